@@ -0,0 +1,233 @@
+// Package benchsuite turns benchmark results into data that can be charted
+// over time. Results come from two places: benchmarks registered with a
+// Suite and run in process, and the text output of go test -bench, which
+// covers the benchmarks in test files that no other package can call:
+//
+//	go test -run '^$' -bench . ./Benchmarking/... ./Testing/... | go run ./Benchmarking/benchsuite/cmd/benchsuite -format csv -append -o bench.csv
+//
+// Either way a Report holds one Result per benchmark run, with ns/op, B/op,
+// allocs/op and any custom metrics, and writes itself as JSON or CSV.
+package benchsuite
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/options"
+)
+
+// Result is one run of one benchmark.
+type Result struct {
+	Package     string             `json:"package,omitempty"`
+	Name        string             `json:"name"` // without the Benchmark prefix and the -procs suffix
+	Procs       int                `json:"procs"`
+	N           int                `json:"n"`
+	NsPerOp     float64            `json:"ns_per_op"`
+	BytesPerOp  int64              `json:"bytes_per_op"`
+	AllocsPerOp int64              `json:"allocs_per_op"`
+	Metrics     map[string]float64 `json:"metrics,omitempty"` // b.ReportMetric values by unit
+}
+
+// Report is a set of results taken together.
+type Report struct {
+	Time    time.Time `json:"time"`
+	Label   string    `json:"label,omitempty"` // a commit or build to tell reports apart
+	GOOS    string    `json:"goos,omitempty"`
+	GOARCH  string    `json:"goarch,omitempty"`
+	CPU     string    `json:"cpu,omitempty"`
+	Results []Result  `json:"results"`
+}
+
+type bench struct {
+	name string
+	fn   func(*testing.B)
+}
+
+// Suite is a set of registered benchmarks. The zero value is ready to use.
+type Suite struct {
+	mu      sync.Mutex
+	benches []bench
+}
+
+// Default is the suite of the package level Register.
+var Default = &Suite{}
+
+// Register adds fn under name, which should not start with Benchmark. fn
+// is an ordinary benchmark function but must not call b.Run: testing only
+// reports the results of leaf benchmarks, so register each case instead.
+func (s *Suite) Register(name string, fn func(*testing.B)) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.benches = append(s.benches, bench{name, fn})
+}
+
+// Register adds fn to Default.
+func Register(name string, fn func(*testing.B)) {
+	Default.Register(name, fn)
+}
+
+// Names returns the registered names in order.
+func (s *Suite) Names() []string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, len(s.benches))
+	for i, b := range s.benches {
+		names[i] = b.name
+	}
+
+	return names
+}
+
+type config struct {
+	filter     *regexp.Regexp
+	iterations int
+	duration   time.Duration
+	count      int
+	label      string
+}
+
+// Option configures Run.
+type Option = options.Option[config]
+
+// WithFilter runs only the benchmarks whose name matches the regular
+// expression pattern, like go test -bench.
+func WithFilter(pattern string) Option {
+	return options.New("WithFilter", func(c *config) error {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		c.filter = re
+		return nil
+	})
+}
+
+// WithIterations runs every benchmark exactly n times, like -benchtime
+// nx. It replaces WithDuration.
+func WithIterations(n int) Option {
+	return options.New("WithIterations", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one iteration")
+		}
+		c.iterations, c.duration = n, 0
+		return nil
+	})
+}
+
+// WithDuration runs every benchmark for about d, like -benchtime d.
+// Defaults to one second. It replaces WithIterations.
+func WithDuration(d time.Duration) Option {
+	return options.New("WithDuration", func(c *config) error {
+		if d <= 0 {
+			return errors.New("duration must be positive")
+		}
+		c.iterations, c.duration = 0, d
+		return nil
+	})
+}
+
+// WithCount runs every benchmark n times, like -count, for a result per
+// run that benchstat can take the spread of. Defaults to 1.
+func WithCount(n int) Option {
+	return options.New("WithCount", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one run")
+		}
+		c.count = n
+		return nil
+	})
+}
+
+// WithLabel sets Report.Label.
+func WithLabel(label string) Option {
+	return options.New("WithLabel", func(c *config) error {
+		c.label = label
+		return nil
+	})
+}
+
+// benchtime guards the testing package's -test.benchtime flag, which is
+// where testing.Benchmark takes its duration from.
+var benchtime sync.Mutex
+
+// Run runs the registered benchmarks in order and returns their results.
+// Suites run one at a time, since the iteration setting is global to the
+// testing package.
+func (s *Suite) Run(opts ...Option) (*Report, error) {
+
+	cfg, err := options.Build(config{duration: time.Second, count: 1}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	benches := slices.Clone(s.benches)
+	s.mu.Unlock()
+
+	benchtime.Lock()
+	defer benchtime.Unlock()
+
+	testing.Init() // registers the flag outside test binaries; a no-op inside
+	f := flag.Lookup("test.benchtime")
+	prev := f.Value.String()
+	defer f.Value.Set(prev)
+
+	value := cfg.duration.String()
+	if cfg.iterations > 0 {
+		value = strconv.Itoa(cfg.iterations) + "x"
+	}
+	if err := f.Value.Set(value); err != nil {
+		return nil, fmt.Errorf("benchsuite: %w", err)
+	}
+
+	rep := &Report{Time: time.Now(), Label: cfg.label, GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+	for _, b := range benches {
+		if cfg.filter != nil && !cfg.filter.MatchString(b.name) {
+			continue
+		}
+		for range cfg.count {
+			rep.Results = append(rep.Results, result(b.name, testing.Benchmark(b.fn)))
+		}
+	}
+
+	return rep, nil
+}
+
+// Run runs Default.
+func Run(opts ...Option) (*Report, error) {
+	return Default.Run(opts...)
+}
+
+func result(name string, r testing.BenchmarkResult) Result {
+
+	res := Result{
+		Name:        name,
+		Procs:       runtime.GOMAXPROCS(0),
+		N:           r.N,
+		BytesPerOp:  r.AllocedBytesPerOp(),
+		AllocsPerOp: r.AllocsPerOp(),
+	}
+	if r.N > 0 {
+		res.NsPerOp = float64(r.T.Nanoseconds()) / float64(r.N)
+	}
+	for unit, v := range r.Extra {
+		if res.Metrics == nil {
+			res.Metrics = make(map[string]float64)
+		}
+		res.Metrics[unit] = v
+	}
+
+	return res
+}
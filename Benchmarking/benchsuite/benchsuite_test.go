@@ -0,0 +1,135 @@
+package benchsuite_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pacx/Benchmarking/benchsuite"
+)
+
+const output = `goos: linux
+goarch: amd64
+pkg: pacx/cache
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkZipfLRU-8       	 5000000	       231.5 ns/op	        62.10 hit%	      16 B/op	       1 allocs/op
+BenchmarkLogs
+    cache_test.go:12: a log line
+BenchmarkLogs-8          	    1000	      1000 ns/op
+PASS
+ok  	pacx/cache	2.345s
+pkg: pacx/Benchmarking/mapkeys
+BenchmarkLookup/key=string/keys=keygen/n=1000     	    2000	         9.142 ns/op
+PASS
+`
+
+func TestParse(t *testing.T) {
+
+	rep, err := benchsuite.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.GOOS != "linux" || rep.GOARCH != "amd64" || rep.CPU != "Intel(R) Xeon(R) Processor" {
+		t.Errorf("Expected the headers but got %+v", rep)
+	}
+	if len(rep.Results) != 3 {
+		t.Fatalf("Expected 3 results but got %+v", rep.Results)
+	}
+
+	r := rep.Results[0]
+	if r.Package != "pacx/cache" || r.Name != "ZipfLRU" || r.Procs != 8 || r.N != 5000000 ||
+		r.NsPerOp != 231.5 || r.BytesPerOp != 16 || r.AllocsPerOp != 1 || r.Metrics["hit%"] != 62.1 {
+		t.Errorf("Expected ZipfLRU with all its values but got %+v", r)
+	}
+	if r := rep.Results[1]; r.Name != "Logs" || r.NsPerOp != 1000 {
+		t.Errorf("Expected Logs after its log lines but got %+v", r)
+	}
+	// no -procs suffix when GOMAXPROCS is 1
+	if r := rep.Results[2]; r.Package != "pacx/Benchmarking/mapkeys" || r.Name != "Lookup/key=string/keys=keygen/n=1000" || r.Procs != 1 {
+		t.Errorf("Expected the sub-benchmark of the second package but got %+v", r)
+	}
+
+	if _, err := benchsuite.Parse(strings.NewReader("BenchmarkBad 10 abc ns/op\n")); err == nil {
+		t.Error("Expected an error for a bad value")
+	}
+}
+
+func TestRunIterations(t *testing.T) {
+
+	var s benchsuite.Suite
+	var calls []int
+	s.Register("Alloc", func(b *testing.B) {
+		calls = append(calls, b.N)
+		for b.Loop() {
+			sink = make([]byte, 64)
+		}
+		b.ReportMetric(42, "things/op")
+	})
+	s.Register("Skipped", func(b *testing.B) {
+		t.Error("Expected the filter to skip this one")
+	})
+
+	rep, err := s.Run(benchsuite.WithIterations(100), benchsuite.WithCount(2), benchsuite.WithFilter("^Alloc$"), benchsuite.WithLabel("abc123"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Label != "abc123" || len(rep.Results) != 2 {
+		t.Fatalf("Expected two labelled runs but got %+v", rep)
+	}
+	for _, r := range rep.Results {
+		if r.Name != "Alloc" || r.N != 100 || r.AllocsPerOp != 1 || r.BytesPerOp != 64 || r.Metrics["things/op"] != 42 {
+			t.Errorf("Expected 100 iterations of one 64 byte allocation but got %+v", r)
+		}
+	}
+
+	if _, err := s.Run(benchsuite.WithFilter("(")); err == nil {
+		t.Error("Expected an error for a bad filter")
+	}
+}
+
+var sink []byte
+
+func TestExport(t *testing.T) {
+
+	rep, err := benchsuite.Parse(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep.Label = "v1"
+
+	var buf bytes.Buffer
+	if err := rep.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var back benchsuite.Report
+	if err := json.Unmarshal(buf.Bytes(), &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Results) != 3 || back.Results[0].Metrics["hit%"] != 62.1 || back.Label != "v1" {
+		t.Errorf("Expected the report back from JSON but got %+v", back)
+	}
+
+	buf.Reset()
+	if err := rep.WriteCSV(&buf, true); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 || rows[0][0] != "time" {
+		t.Fatalf("Expected a header and 3 rows but got %q", rows)
+	}
+	want := []string{"v1", "pacx/cache", "ZipfLRU", "8", "5000000", "231.5", "16", "1", "hit%=62.1"}
+	if got := rows[1][1:]; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %q but got %q", want, got)
+	}
+
+	buf.Reset()
+	rep.WriteCSV(&buf, false)
+	if rows, _ := csv.NewReader(&buf).ReadAll(); len(rows) != 3 {
+		t.Errorf("Expected no header but got %q", rows)
+	}
+}
@@ -0,0 +1,158 @@
+// Command benchsuite writes benchmark results as JSON or CSV. With -in it
+// converts the output of go test -bench; without, it runs the benchmarks
+// registered below.
+//
+//	go test -run '^$' -bench . ./Benchmarking/... | go run ./Benchmarking/benchsuite/cmd/benchsuite -in - -label $(git rev-parse --short HEAD)
+//	go run ./Benchmarking/benchsuite/cmd/benchsuite -bench Lookup -benchtime 100000x -count 5 -format csv -append -o bench.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/Benchmarking/benchsuite"
+	"pacx/Benchmarking/dataset"
+	"pacx/Benchmarking/keygen"
+	"pacx/Benchmarking/mapkeys"
+)
+
+func init() {
+
+	for _, s := range mapkeys.Shapes {
+		keys := s.Keys(100_000)
+		hashes := make([]uint64, len(keys))
+		for i, k := range keys {
+			hashes[i] = mapkeys.Hash64(k)
+		}
+		benchsuite.Register("Lookup/key=string/keys="+s.Name, func(b *testing.B) { lookup(b, keys) })
+		benchsuite.Register("Lookup/key=uint64/keys="+s.Name, func(b *testing.B) { lookup(b, hashes) })
+	}
+	benchsuite.Register("KeygenString", func(b *testing.B) {
+		for i := 0; b.Loop(); i++ {
+			keygen.String(i)
+		}
+	})
+	benchsuite.Register("DatasetGenerate", func(b *testing.B) {
+		for b.Loop() {
+			dataset.Generate(dataset.WithVolume(1000, 500, 10000))
+		}
+	})
+}
+
+var sink int
+
+func lookup[K comparable](b *testing.B, keys []K) {
+
+	m := make(map[K]int, len(keys))
+	for i, k := range keys {
+		m[k] = i
+	}
+
+	i := 0
+	for b.Loop() {
+		sink += m[keys[i]]
+		if i++; i == len(keys) {
+			i = 0
+		}
+	}
+}
+
+func main() {
+
+	in := flag.String("in", "", "go test -bench output to convert, - for stdin; runs the registered benchmarks if empty")
+	bench := flag.String("bench", ".", "run only the registered benchmarks matching this regular expression")
+	benchtime := flag.String("benchtime", "1s", "time per benchmark, or Nx for N iterations")
+	count := flag.Int("count", 1, "runs per benchmark")
+	label := flag.String("label", "", "label for the report, such as a commit")
+	format := flag.String("format", "json", "json or csv")
+	out := flag.String("o", "", "output file; stdout if empty")
+	appendTo := flag.Bool("append", false, "append to -o; for csv the header is only written to an empty file")
+	list := flag.Bool("list", false, "list the registered benchmarks and exit")
+	flag.Parse()
+
+	if *list {
+		fmt.Println(strings.Join(benchsuite.Default.Names(), "\n"))
+		return
+	}
+	if *format != "json" && *format != "csv" {
+		fmt.Fprintf(os.Stderr, "unknown format %q, want json or csv\n", *format)
+		os.Exit(2)
+	}
+
+	rep, err := report(*in, *bench, *benchtime, *count)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *label != "" {
+		rep.Label = *label
+	}
+
+	var w io.Writer = os.Stdout
+	header := true
+	if *out != "" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if *appendTo {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(*out, flags, 0o644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		if info, err := f.Stat(); err == nil && info.Size() > 0 {
+			header = false
+		}
+		w = f
+	}
+
+	if *format == "csv" {
+		err = rep.WriteCSV(w, header)
+	} else {
+		err = rep.WriteJSON(w)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func report(in, bench, benchtime string, count int) (*benchsuite.Report, error) {
+
+	switch in {
+	case "":
+	case "-":
+		return benchsuite.Parse(os.Stdin)
+	default:
+		f, err := os.Open(in)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return benchsuite.Parse(f)
+	}
+
+	opts := []benchsuite.Option{benchsuite.WithFilter(bench), benchsuite.WithCount(count)}
+	if n, ok := strings.CutSuffix(benchtime, "x"); ok {
+		iterations, err := strconv.Atoi(n)
+		if err != nil {
+			return nil, fmt.Errorf("bad -benchtime %q", benchtime)
+		}
+		opts = append(opts, benchsuite.WithIterations(iterations))
+	} else {
+		d, err := time.ParseDuration(benchtime)
+		if err != nil {
+			return nil, fmt.Errorf("bad -benchtime %q", benchtime)
+		}
+		opts = append(opts, benchsuite.WithDuration(d))
+	}
+
+	return benchsuite.Run(opts...)
+}
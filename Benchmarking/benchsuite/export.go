@@ -0,0 +1,59 @@
+package benchsuite
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteJSON writes r as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// CSVHeader is the first row WriteCSV writes.
+var CSVHeader = []string{"time", "label", "package", "name", "procs", "n", "ns_per_op", "bytes_per_op", "allocs_per_op", "metrics"}
+
+// WriteCSV writes a row per result, each with the report's time and label
+// so rows from many reports can share a file. The header row is left out
+// when header is false, for appending to such a file. Custom metrics go in
+// the last column as unit=value pairs separated by semicolons.
+func (r *Report) WriteCSV(w io.Writer, header bool) error {
+
+	cw := csv.NewWriter(w)
+	if header {
+		cw.Write(CSVHeader)
+	}
+
+	at := r.Time.UTC().Format(time.RFC3339)
+	for _, res := range r.Results {
+		var metrics []string
+		for _, unit := range slices.Sorted(maps.Keys(res.Metrics)) {
+			metrics = append(metrics, unit+"="+strconv.FormatFloat(res.Metrics[unit], 'g', -1, 64))
+		}
+		cw.Write([]string{
+			at,
+			r.Label,
+			res.Package,
+			res.Name,
+			strconv.Itoa(res.Procs),
+			strconv.Itoa(res.N),
+			strconv.FormatFloat(res.NsPerOp, 'f', -1, 64),
+			strconv.FormatInt(res.BytesPerOp, 10),
+			strconv.FormatInt(res.AllocsPerOp, 10),
+			strings.Join(metrics, ";"),
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,102 @@
+package benchsuite
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"pacx/File-IO/lines"
+)
+
+// Parse reads the output of go test -bench, for any number of packages,
+// into a report timed now. Lines that are not results or the goos, goarch,
+// pkg and cpu headers, such as test logs and PASS, are skipped.
+func Parse(r io.Reader) (*Report, error) {
+
+	rep := &Report{Time: time.Now()}
+	var pkg string
+
+	line := 0
+	for text, err := range lines.Lines(r) {
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if key, value, ok := strings.Cut(text, ": "); ok {
+			switch key {
+			case "goos":
+				rep.GOOS = value
+			case "goarch":
+				rep.GOARCH = value
+			case "cpu":
+				rep.CPU = value
+			case "pkg":
+				pkg = value
+			}
+		}
+		if !strings.HasPrefix(text, "Benchmark") {
+			continue
+		}
+		res, ok, err := parseLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("benchsuite: line %d: %w", line, err)
+		}
+		if ok {
+			res.Package = pkg
+			rep.Results = append(rep.Results, res)
+		}
+	}
+
+	return rep, nil
+}
+
+// parseLine reads
+//
+//	BenchmarkName/sub-8   1000   1234 ns/op   56 B/op   2 allocs/op   0.5 hit%
+//
+// ok is false for lines that only start like one, such as the name go test
+// prints alone before a benchmark that logs.
+func parseLine(text string) (Result, bool, error) {
+
+	fields := strings.Fields(text)
+	if len(fields) < 4 || len(fields)%2 != 0 {
+		return Result{}, false, nil
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return Result{}, false, nil
+	}
+
+	res := Result{N: n, Procs: 1}
+	res.Name = strings.TrimPrefix(fields[0], "Benchmark")
+	// go test adds -GOMAXPROCS unless it is 1
+	if i := strings.LastIndexByte(res.Name, '-'); i >= 0 {
+		if procs, err := strconv.Atoi(res.Name[i+1:]); err == nil {
+			res.Name, res.Procs = res.Name[:i], procs
+		}
+	}
+
+	for i := 2; i < len(fields); i += 2 {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return Result{}, false, fmt.Errorf("value %q of %s: %w", fields[i], fields[i+1], err)
+		}
+		switch unit := fields[i+1]; unit {
+		case "ns/op":
+			res.NsPerOp = v
+		case "B/op":
+			res.BytesPerOp = int64(v)
+		case "allocs/op":
+			res.AllocsPerOp = int64(v)
+		default:
+			if res.Metrics == nil {
+				res.Metrics = make(map[string]float64)
+			}
+			res.Metrics[unit] = v
+		}
+	}
+
+	return res, true, nil
+}
@@ -0,0 +1,123 @@
+// Command compare runs the map-key experiment through the compare
+// harness: the same workloads on a map keyed by the string, by its FNV-1a
+// hash and by its maphash.
+//
+//	go run ./Benchmarking/compare/cmd/compare -benchtime 200ms
+//	go run ./Benchmarking/compare/cmd/compare -bench lookup -format csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hash/maphash"
+	"os"
+	"testing"
+	"time"
+
+	"pacx/Benchmarking/benchsuite"
+	"pacx/Benchmarking/compare"
+	"pacx/Benchmarking/mapkeys"
+)
+
+// Map is what the variants have in common.
+type Map interface {
+	Set(k string, v int)
+	Get(k string) (int, bool)
+}
+
+type stringMap map[string]int
+
+func (m stringMap) Set(k string, v int) { m[k] = v }
+
+func (m stringMap) Get(k string) (int, bool) {
+	v, ok := m[k]
+	return v, ok
+}
+
+// hashedMap keys by a 64-bit hash of the string. Colliding keys overwrite
+// each other, which the experiment accepts.
+type hashedMap struct {
+	m    map[uint64]int
+	hash func(string) uint64
+}
+
+func (m hashedMap) Set(k string, v int) { m.m[m.hash(k)] = v }
+
+func (m hashedMap) Get(k string) (int, bool) {
+	v, ok := m.m[m.hash(k)]
+	return v, ok
+}
+
+var seed = maphash.MakeSeed()
+
+var sink int
+
+func main() {
+
+	bench := flag.String("bench", ".", "run only workload/variant names matching this regular expression")
+	benchtime := flag.Duration("benchtime", time.Second, "time per benchmark")
+	n := flag.Int("keys", 100_000, "keys per map")
+	format := flag.String("format", "table", "table, json or csv")
+	flag.Parse()
+
+	keys := mapkeys.Keygen(*n)
+	misses := mapkeys.Repeated(*n, 3)
+
+	h := compare.New[Map]()
+	h.Add("string", func() Map { return stringMap{} })
+	h.Add("fnv64", func() Map { return hashedMap{map[uint64]int{}, mapkeys.Hash64} })
+	h.Add("maphash", func() Map {
+		return hashedMap{map[uint64]int{}, func(s string) uint64 { return maphash.String(seed, s) }}
+	})
+
+	fill := func(m Map) {
+		for i, k := range keys {
+			m.Set(k, i)
+		}
+	}
+	h.Workload("insert", func(b *testing.B, m Map) {
+		i := 0
+		for b.Loop() {
+			m.Set(keys[i%len(keys)], i)
+			i++
+		}
+	})
+	h.Workload("lookup", func(b *testing.B, m Map) {
+		b.StopTimer()
+		fill(m)
+		b.StartTimer()
+		i := 0
+		for b.Loop() {
+			v, _ := m.Get(keys[i%len(keys)])
+			sink += v
+			i++
+		}
+	})
+	h.Workload("miss", func(b *testing.B, m Map) {
+		b.StopTimer()
+		fill(m)
+		b.StartTimer()
+		i := 0
+		for b.Loop() {
+			v, _ := m.Get(misses[i%len(misses)])
+			sink += v
+			i++
+		}
+	})
+
+	t, err := h.Run(benchsuite.WithFilter(*bench), benchsuite.WithDuration(*benchtime))
+	if err == nil {
+		switch *format {
+		case "json":
+			err = t.Report.WriteJSON(os.Stdout)
+		case "csv":
+			err = t.Report.WriteCSV(os.Stdout, true)
+		default:
+			err = t.Print(os.Stdout)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,178 @@
+// Package compare runs the same workloads against several implementations
+// of one interface and prints them side by side. It is the string vs
+// uint64 map experiment made general:
+//
+//	h := compare.New[Map]()
+//	h.Add("string", func() Map { return stringMap{} })
+//	h.Add("uint64", func() Map { return hashedMap{} })
+//	h.Workload("lookup", func(b *testing.B, m Map) { ... })
+//	t, err := h.Run(benchsuite.WithDuration(time.Second))
+//	t.Print(os.Stdout)
+//
+// The first implementation added is the baseline the others are compared
+// to. Each workload gets a new instance from the constructor, so state
+// does not leak from one workload to the next.
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"testing"
+	"text/tabwriter"
+
+	"pacx/Benchmarking/benchsuite"
+)
+
+type impl[I any] struct {
+	name  string
+	build func() I
+}
+
+type workload[I any] struct {
+	name string
+	fn   func(b *testing.B, v I)
+}
+
+// Harness holds the implementations and workloads of one comparison.
+type Harness[I any] struct {
+	impls     []impl[I]
+	workloads []workload[I]
+}
+
+// New returns an empty harness for implementations of I.
+func New[I any]() *Harness[I] {
+	return &Harness[I]{}
+}
+
+// Add registers an implementation. build is called once per workload
+// run.
+func (h *Harness[I]) Add(name string, build func() I) {
+	h.impls = append(h.impls, impl[I]{name, build})
+}
+
+// Workload registers a benchmark every implementation runs. fn is an
+// ordinary benchmark body that must not call b.Run.
+func (h *Harness[I]) Workload(name string, fn func(b *testing.B, v I)) {
+	h.workloads = append(h.workloads, workload[I]{name, fn})
+}
+
+func (h *Harness[I]) bench(w workload[I], im impl[I]) func(*testing.B) {
+	return func(b *testing.B) {
+		v := im.build()
+		b.ReportAllocs()
+		b.ResetTimer()
+		w.fn(b, v)
+	}
+}
+
+// Run runs every workload on every implementation with the settings of
+// benchsuite.Suite.Run, one of which can pick a subset by the name
+// workload/implementation.
+func (h *Harness[I]) Run(opts ...benchsuite.Option) (*Table, error) {
+
+	if len(h.impls) == 0 || len(h.workloads) == 0 {
+		return nil, errors.New("compare: need an implementation and a workload")
+	}
+
+	var s benchsuite.Suite
+	for _, w := range h.workloads {
+		for _, im := range h.impls {
+			s.Register(w.name+"/"+im.name, h.bench(w, im))
+		}
+	}
+	rep, err := s.Run(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Table{Report: rep}
+	for _, im := range h.impls {
+		t.Impls = append(t.Impls, im.name)
+	}
+	for _, w := range h.workloads {
+		row := Row{Workload: w.name, Cells: make([]*benchsuite.Result, len(h.impls))}
+		for i, im := range h.impls {
+			// with WithCount the last run wins; the report has them all
+			for j := range rep.Results {
+				if rep.Results[j].Name == w.name+"/"+im.name {
+					row.Cells[i] = &rep.Results[j]
+				}
+			}
+		}
+		if slices.ContainsFunc(row.Cells, func(r *benchsuite.Result) bool { return r != nil }) {
+			t.Rows = append(t.Rows, row)
+		}
+	}
+
+	return t, nil
+}
+
+// Benchmark runs every workload on every implementation as sub-benchmarks
+// of b named workload/implementation, for go test -bench and benchstat.
+func (h *Harness[I]) Benchmark(b *testing.B) {
+
+	for _, w := range h.workloads {
+		for _, im := range h.impls {
+			b.Run(w.name+"/"+im.name, h.bench(w, im))
+		}
+	}
+}
+
+// Table is the outcome of Run.
+type Table struct {
+	Impls  []string
+	Rows   []Row
+	Report *benchsuite.Report // every run, for export
+}
+
+// Row is one workload; a cell is nil where the implementation was filtered
+// out.
+type Row struct {
+	Workload string
+	Cells    []*benchsuite.Result
+}
+
+// Print writes a row per workload with ns/op and allocs/op of every
+// implementation, and how many times faster than the baseline each is.
+func (t *Table) Print(w io.Writer) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprint(tw, "workload\t")
+	for i, name := range t.Impls {
+		fmt.Fprintf(tw, "%s ns/op\tallocs/op\t", name)
+		if i > 0 {
+			fmt.Fprintf(tw, "vs %s\t", t.Impls[0])
+		}
+	}
+	fmt.Fprintln(tw)
+
+	for _, row := range t.Rows {
+		fmt.Fprintf(tw, "%s\t", row.Workload)
+		for i, c := range row.Cells {
+			if c == nil {
+				fmt.Fprint(tw, "-\t-\t")
+			} else {
+				fmt.Fprintf(tw, "%.1f\t%d\t", c.NsPerOp, c.AllocsPerOp)
+			}
+			if i > 0 {
+				fmt.Fprintf(tw, "%s\t", ratio(row.Cells[0], c))
+			}
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
+
+// ratio is how many times faster than base r is, or - if unknown.
+func ratio(base, r *benchsuite.Result) string {
+
+	if base == nil || r == nil || base.NsPerOp == 0 || r.NsPerOp == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.2fx", base.NsPerOp/r.NsPerOp)
+}
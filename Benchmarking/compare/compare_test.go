@@ -0,0 +1,113 @@
+package compare_test
+
+import (
+	"bytes"
+	"container/list"
+	"strings"
+	"testing"
+
+	"pacx/Benchmarking/benchsuite"
+	"pacx/Benchmarking/compare"
+)
+
+type stack interface {
+	Push(v int)
+	Pop() int
+}
+
+type sliceStack struct{ s []int }
+
+func (s *sliceStack) Push(v int) { s.s = append(s.s, v) }
+
+func (s *sliceStack) Pop() int {
+	v := s.s[len(s.s)-1]
+	s.s = s.s[:len(s.s)-1]
+	return v
+}
+
+type listStack struct{ l list.List }
+
+func (s *listStack) Push(v int) { s.l.PushBack(v) }
+
+func (s *listStack) Pop() int { return s.l.Remove(s.l.Back()).(int) }
+
+func harness(built *int) *compare.Harness[stack] {
+
+	h := compare.New[stack]()
+	h.Add("slice", func() stack { *built++; return &sliceStack{} })
+	h.Add("list", func() stack { *built++; return &listStack{} })
+	h.Workload("pushpop", func(b *testing.B, s stack) {
+		for b.Loop() {
+			s.Push(1)
+			s.Pop()
+		}
+	})
+	h.Workload("grow", func(b *testing.B, s stack) {
+		for i := 0; b.Loop(); i++ {
+			s.Push(i)
+		}
+	})
+
+	return h
+}
+
+func TestRun(t *testing.T) {
+
+	var built int
+	table, err := harness(&built).Run(benchsuite.WithIterations(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if built != 4 {
+		t.Errorf("Expected a new stack per workload and implementation but got %d", built)
+	}
+	if len(table.Rows) != 2 || len(table.Report.Results) != 4 {
+		t.Fatalf("Expected 2 workloads of 2 cells but got %+v", table)
+	}
+	for _, row := range table.Rows {
+		for i, c := range row.Cells {
+			if c == nil || c.N != 1000 || c.Name != row.Workload+"/"+table.Impls[i] {
+				t.Errorf("Expected 1000 runs of %s/%s but got %+v", row.Workload, table.Impls[i], c)
+			}
+		}
+	}
+	// a list element per push
+	if c := table.Rows[0].Cells[1]; c.AllocsPerOp < 1 {
+		t.Errorf("Expected list pushes to allocate but got %+v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := table.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); !strings.Contains(out, "vs slice") || !strings.Contains(out, "pushpop") {
+		t.Errorf("Expected a comparison with the baseline but got\n%s", out)
+	}
+}
+
+func TestRunFiltered(t *testing.T) {
+
+	var built int
+	table, err := harness(&built).Run(benchsuite.WithIterations(10), benchsuite.WithFilter("^grow/list$"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 1 || table.Rows[0].Cells[0] != nil || table.Rows[0].Cells[1] == nil {
+		t.Fatalf("Expected only grow on list but got %+v", table.Rows)
+	}
+
+	var buf bytes.Buffer
+	table.Print(&buf)
+	if !strings.Contains(buf.String(), "-") {
+		t.Errorf("Expected dashes for the filtered baseline but got\n%s", buf.String())
+	}
+
+	if _, err := compare.New[stack]().Run(); err == nil {
+		t.Error("Expected an error for an empty harness")
+	}
+}
+
+func BenchmarkStacks(b *testing.B) {
+	var built int
+	harness(&built).Benchmark(b)
+}
@@ -0,0 +1,81 @@
+// Package concat joins a list of strings in each of the usual ways, so
+// they can be benchmarked against each other. Plus is JoinStrings from
+// Testing/concat.go; the others are what to use instead.
+//
+//	go test -run '^$' -bench . -count 10 ./Benchmarking/concat > new.txt
+//	benchstat -col /how new.txt
+package concat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Plus adds the strings with +=, copying everything so far each time.
+func Plus(strs []string) string {
+
+	var result string
+	for _, s := range strs {
+		result += s
+	}
+
+	return result
+}
+
+// Builder appends to a strings.Builder, which grows like append.
+func Builder(strs []string) string {
+
+	var b strings.Builder
+	for _, s := range strs {
+		b.WriteString(s)
+	}
+
+	return b.String()
+}
+
+// BuilderGrow is Builder sized up front, so it allocates once.
+func BuilderGrow(strs []string) string {
+
+	n := 0
+	for _, s := range strs {
+		n += len(s)
+	}
+
+	var b strings.Builder
+	b.Grow(n)
+	for _, s := range strs {
+		b.WriteString(s)
+	}
+
+	return b.String()
+}
+
+// Buffer appends to a bytes.Buffer. Unlike a Builder it copies its bytes
+// once more to make the string.
+func Buffer(strs []string) string {
+
+	var b bytes.Buffer
+	for _, s := range strs {
+		b.WriteString(s)
+	}
+
+	return b.String()
+}
+
+// Join is strings.Join with no separator, which sizes its result first.
+func Join(strs []string) string {
+	return strings.Join(strs, "")
+}
+
+// Sprintf formats the strings with one %s each, boxing every one into an
+// interface on the way.
+func Sprintf(strs []string) string {
+
+	args := make([]any, len(strs))
+	for i, s := range strs {
+		args[i] = s
+	}
+
+	return fmt.Sprintf(strings.Repeat("%s", len(strs)), args...)
+}
@@ -0,0 +1,81 @@
+package concat_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"pacx/Benchmarking/concat"
+)
+
+var ways = []struct {
+	how  string
+	join func([]string) string
+}{
+	{"plus", concat.Plus},
+	{"builder", concat.Builder},
+	{"builder-grow", concat.BuilderGrow},
+	{"buffer", concat.Buffer},
+	{"join", concat.Join},
+	{"sprintf", concat.Sprintf},
+}
+
+// inputs are the strings of BenchmarkJoinStrings in Testing, before and
+// after it appends 700 more, and a few long strings.
+func inputs() []struct {
+	name string
+	strs []string
+} {
+
+	small := []string{"Hello", ",", "world", "!"}
+	many := append(append([]string{}, small...), make([]string, 700)...)
+	for i := len(small); i < len(many); i++ {
+		many[i] = "ababba"
+	}
+	long := make([]string, 16)
+	for i := range long {
+		long[i] = strings.Repeat(string(rune('a'+i)), 4096)
+	}
+
+	return []struct {
+		name string
+		strs []string
+	}{
+		{"small", small},
+		{"many", many},
+		{"long", long},
+	}
+}
+
+func TestWaysAgree(t *testing.T) {
+
+	for _, in := range inputs() {
+		want := strings.Join(in.strs, "")
+		for _, w := range ways {
+			if got := w.join(in.strs); got != want {
+				t.Errorf("Expected %s to join %s into %d bytes but got %d", w.how, in.name, len(want), len(got))
+			}
+		}
+	}
+	for _, w := range ways {
+		if got := w.join(nil); got != "" {
+			t.Errorf("Expected %s of nothing to be empty but got %q", w.how, got)
+		}
+	}
+}
+
+var sink string
+
+func BenchmarkConcat(b *testing.B) {
+
+	for _, in := range inputs() {
+		for _, w := range ways {
+			b.Run(fmt.Sprintf("in=%s/how=%s", in.name, w.how), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					sink = w.join(in.strs)
+				}
+			})
+		}
+	}
+}
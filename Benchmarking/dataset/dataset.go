@@ -0,0 +1,224 @@
+// Package dataset generates fake but plausible customers, products and
+// orders for benchmarks, demos and tests. The same seed and options always
+// give the same data, so fixtures stay consistent between runs and between
+// the places that use them.
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"pacx/Benchmarking/keygen"
+	"pacx/concurrency/orders"
+	"pacx/options"
+)
+
+// Customer places orders.
+type Customer struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	City  string `json:"city"`
+}
+
+// Product is something that can be ordered.
+type Product struct {
+	ID         int    `json:"id"`
+	SKU        string `json:"sku"`
+	Name       string `json:"name"`
+	Category   string `json:"category"`
+	PriceCents int64  `json:"price_cents"`
+}
+
+// Item is one line of an order.
+type Item struct {
+	ProductID  int   `json:"product_id"`
+	Quantity   int   `json:"quantity"`
+	PriceCents int64 `json:"price_cents"` // unit price at the time of the order
+}
+
+// Order uses the statuses of the order demo in concurrency/orders.
+type Order struct {
+	ID         int       `json:"id"`
+	CustomerID int       `json:"customer_id"`
+	Items      []Item    `json:"items"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TotalCents is the sum of the order's lines.
+func (o Order) TotalCents() int64 {
+
+	var total int64
+	for _, it := range o.Items {
+		total += int64(it.Quantity) * it.PriceCents
+	}
+
+	return total
+}
+
+// Dataset is one generated set of fixtures. IDs start at 1 and equal the
+// index plus one, so Customers[o.CustomerID-1] is the order's customer.
+type Dataset struct {
+	Customers []Customer
+	Products  []Product
+	Orders    []Order
+}
+
+type config struct {
+	seed      uint64
+	customers int
+	products  int
+	orders    int
+	maxItems  int
+	start     time.Time
+	span      time.Duration
+}
+
+// Option configures Generate.
+type Option = options.Option[config]
+
+// WithSeed picks the data set. Defaults to 1.
+func WithSeed(seed uint64) Option {
+	return options.New("WithSeed", func(c *config) error {
+		c.seed = seed
+		return nil
+	})
+}
+
+// WithVolume sets how many customers, products and orders to generate.
+// Defaults to 100, 50 and 1000.
+func WithVolume(customers, products, orders int) Option {
+	return options.New("WithVolume", func(c *config) error {
+		if customers < 1 || products < 1 || orders < 0 {
+			return errors.New("need at least one customer and product and no negative orders")
+		}
+		c.customers, c.products, c.orders = customers, products, orders
+		return nil
+	})
+}
+
+// WithMaxItems caps the lines per order. Defaults to 5.
+func WithMaxItems(n int) Option {
+	return options.New("WithMaxItems", func(c *config) error {
+		if n < 1 {
+			return errors.New("max items must be at least 1")
+		}
+		c.maxItems = n
+		return nil
+	})
+}
+
+// WithPeriod spreads the order timestamps over [start, start+span).
+// Defaults to the year 2024.
+func WithPeriod(start time.Time, span time.Duration) Option {
+	return options.New("WithPeriod", func(c *config) error {
+		if span <= 0 {
+			return errors.New("span must be positive")
+		}
+		c.start, c.span = start, span
+		return nil
+	})
+}
+
+var (
+	firstNames = []string{"Aarav", "Maulik", "Priya", "Lena", "Omar", "Sofia", "Kenji", "Amara", "Diego", "Nora", "Ivan", "Zara"}
+	lastNames  = []string{"Shah", "Patel", "Müller", "Haddad", "Rossi", "Tanaka", "Okafor", "García", "Larsen", "Petrov", "Khan"}
+	cities     = []string{"Ahmedabad", "Mumbai", "Berlin", "Cairo", "Milan", "Osaka", "Lagos", "Madrid", "Oslo", "Kyiv"}
+	categories = map[string][]string{
+		"books":       {"Go Programming", "Concurrency Patterns", "Systems Design", "Data Structures"},
+		"electronics": {"USB-C Cable", "Mechanical Keyboard", "Noise Cancelling Headphones", "Monitor Arm"},
+		"kitchen":     {"Chef Knife", "Cast Iron Pan", "Coffee Grinder", "Tea Kettle"},
+		"outdoor":     {"Trail Backpack", "Water Bottle", "Camping Lantern", "Rain Jacket"},
+	}
+	// sorted so map iteration order can't leak into the output
+	categoryNames = []string{"books", "electronics", "kitchen", "outdoor"}
+)
+
+// Generate builds a dataset. Product popularity follows a Zipf
+// distribution, like real shops where a few products sell most.
+func Generate(opts ...Option) (*Dataset, error) {
+
+	cfg, err := options.Build(config{
+		seed:      1,
+		customers: 100,
+		products:  50,
+		orders:    1000,
+		maxItems:  5,
+		start:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		span:      366 * 24 * time.Hour,
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	r := rand.New(rand.NewPCG(cfg.seed, 0x5eed))
+	d := &Dataset{
+		Customers: make([]Customer, cfg.customers),
+		Products:  make([]Product, cfg.products),
+		Orders:    make([]Order, cfg.orders),
+	}
+
+	for i := range d.Customers {
+		first, last := pick(r, firstNames), pick(r, lastNames)
+		d.Customers[i] = Customer{
+			ID:    i + 1,
+			Name:  first + " " + last,
+			Email: fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			City:  pick(r, cities),
+		}
+	}
+
+	for i := range d.Products {
+		category := pick(r, categoryNames)
+		d.Products[i] = Product{
+			ID:         i + 1,
+			SKU:        fmt.Sprintf("%s-%05d", strings.ToUpper(category[:3]), i+1),
+			Name:       pick(r, categories[category]),
+			Category:   category,
+			PriceCents: 199 + r.Int64N(20000),
+		}
+	}
+
+	// one Zipf draw per order line, from keygen so benchmarks and fixtures
+	// share the same notion of skew
+	var popular []uint64
+	if cfg.products > 1 {
+		popular = keygen.Zipf(cfg.seed, 1.1, uint64(cfg.products), cfg.orders*cfg.maxItems)
+	}
+
+	for i := range d.Orders {
+		o := Order{
+			ID:         i + 1,
+			CustomerID: 1 + r.IntN(cfg.customers),
+			Status:     pick(r, append([]string{orders.Pending}, orders.Statuses...)),
+			CreatedAt:  cfg.start.Add(time.Duration(r.Int64N(int64(cfg.span)))).Truncate(time.Second),
+		}
+
+		lines := 1 + r.IntN(cfg.maxItems)
+		seen := make(map[int]bool, lines)
+		for j := 0; j < lines; j++ {
+			idx := 0
+			if popular != nil {
+				idx = int(popular[i*cfg.maxItems+j])
+			}
+			if seen[idx] {
+				continue
+			}
+			seen[idx] = true
+
+			p := d.Products[idx]
+			o.Items = append(o.Items, Item{ProductID: p.ID, Quantity: 1 + r.IntN(3), PriceCents: p.PriceCents})
+		}
+		d.Orders[i] = o
+	}
+
+	return d, nil
+}
+
+func pick[T any](r *rand.Rand, from []T) T {
+	return from[r.IntN(len(from))]
+}
@@ -0,0 +1,74 @@
+package dataset_test
+
+import (
+	"reflect"
+	"testing"
+
+	"pacx/Benchmarking/dataset"
+)
+
+func TestDeterministic(t *testing.T) {
+
+	a, err := dataset.Generate(dataset.WithSeed(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := dataset.Generate(dataset.WithSeed(7))
+	c, _ := dataset.Generate(dataset.WithSeed(8))
+
+	if !reflect.DeepEqual(a, b) {
+		t.Error("Expected the same seed to give the same dataset")
+	}
+	if reflect.DeepEqual(a.Orders, c.Orders) {
+		t.Error("Expected a different seed to give different orders")
+	}
+}
+
+func TestVolumeAndReferences(t *testing.T) {
+
+	d, err := dataset.Generate(dataset.WithVolume(10, 20, 300), dataset.WithMaxItems(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.Customers) != 10 || len(d.Products) != 20 || len(d.Orders) != 300 {
+		t.Fatalf("Expected 10/20/300 but got %d/%d/%d", len(d.Customers), len(d.Products), len(d.Orders))
+	}
+
+	sold := make(map[int]int)
+	for _, o := range d.Orders {
+		if o.CustomerID < 1 || o.CustomerID > 10 {
+			t.Fatalf("Expected a valid customer but got %d", o.CustomerID)
+		}
+		if len(o.Items) == 0 || len(o.Items) > 3 {
+			t.Fatalf("Expected 1 to 3 lines but got %d", len(o.Items))
+		}
+		if o.TotalCents() <= 0 {
+			t.Fatalf("Expected a positive total for order %d", o.ID)
+		}
+		for _, it := range o.Items {
+			if d.Products[it.ProductID-1].PriceCents != it.PriceCents {
+				t.Fatalf("Expected line price to match product %d", it.ProductID)
+			}
+			sold[it.ProductID]++
+		}
+	}
+
+	// Zipf: the first product outsells the last
+	if sold[1] <= sold[20] {
+		t.Errorf("Expected product 1 to be more popular than 20 but got %d vs %d", sold[1], sold[20])
+	}
+}
+
+func TestBadVolume(t *testing.T) {
+
+	if _, err := dataset.Generate(dataset.WithVolume(0, 1, 1)); err == nil {
+		t.Error("Expected an error for zero customers")
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	for b.Loop() {
+		dataset.Generate(dataset.WithVolume(1000, 500, 10000))
+	}
+}
@@ -0,0 +1,71 @@
+// Package hashbench compares the hash functions a string key could be
+// turned into an integer with, as the mapkeys experiments do with FNV: how
+// fast each one is and how many of the generated keys end up on the same
+// hash. The benchmarks are in the tests:
+//
+//	go test -run '^$' -bench . -count 10 ./Benchmarking/hashbench > new.txt
+//	benchstat -col /func new.txt
+//
+// Each sub-benchmark hashes every key once per op and reports MB/s and
+// the collisions it saw, next to the number a perfect hash of the same
+// width would give.
+package hashbench
+
+import (
+	"hash/crc32"
+	"hash/crc64"
+	"hash/maphash"
+	"math"
+	"unsafe"
+
+	"pacx/Benchmarking/mapkeys"
+)
+
+// Func is a string hash.
+type Func struct {
+	Name string
+	Bits int // width of the result; the rest of Sum is zero
+	Sum  func(string) uint64
+}
+
+var (
+	seed  = maphash.MakeSeed()
+	ecma  = crc64.MakeTable(crc64.ECMA)
+	Funcs = []Func{
+		{"fnv32a", 32, func(s string) uint64 { return uint64(mapkeys.Hash32(s)) }},
+		{"fnv64a", 64, mapkeys.Hash64},
+		{"maphash", 64, func(s string) uint64 { return maphash.String(seed, s) }},
+		{"crc32", 32, func(s string) uint64 { return uint64(crc32.ChecksumIEEE(view(s))) }},
+		{"crc64", 64, func(s string) uint64 { return crc64.Checksum(view(s), ecma) }},
+	}
+)
+
+// view is s as bytes without the copy []byte(s) makes, which would
+// otherwise be most of what the crc rows measure. The checksums only read
+// it.
+func view(s string) []byte {
+	return unsafe.Slice(unsafe.StringData(s), len(s))
+}
+
+// Collisions returns how many of keys, which must be distinct, hash to a
+// value an earlier key already had.
+func Collisions(f Func, keys []string) int {
+
+	seen := make(map[uint64]struct{}, len(keys))
+	for _, k := range keys {
+		seen[f.Sum(k)] = struct{}{}
+	}
+
+	return len(keys) - len(seen)
+}
+
+// Expected is the number of collisions among n random values of the given
+// bits: n minus the number of distinct values they are expected to take.
+func Expected(n, bits int) float64 {
+
+	m := math.Ldexp(1, bits)
+	// n*(1-1/m)^n computed without losing everything to rounding at 64 bits
+	distinct := m * -math.Expm1(float64(n)*math.Log1p(-1/m))
+
+	return float64(n) - distinct
+}
@@ -0,0 +1,95 @@
+package hashbench_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"pacx/Benchmarking/hashbench"
+	"pacx/Benchmarking/mapkeys"
+)
+
+var sizes = []int{100_000, 1_000_000}
+
+// sink keeps the hashes from being optimised away.
+var sink uint64
+
+func byName(t *testing.T, name string) hashbench.Func {
+
+	t.Helper()
+
+	for _, f := range hashbench.Funcs {
+		if f.Name == name {
+			return f
+		}
+	}
+	t.Fatalf("Expected a hash called %s but got none", name)
+
+	return hashbench.Func{}
+}
+
+func TestFuncs(t *testing.T) {
+
+	// the published check values of each hash for "123456789"
+	for name, want := range map[string]uint64{
+		"fnv32a": 0xbb86b11c,
+		"fnv64a": 0x06d5573923c6cdfc,
+		"crc32":  0xcbf43926,
+		"crc64":  0x995dc9bbdf1939fa,
+	} {
+		if got := byName(t, name).Sum("123456789"); got != want {
+			t.Errorf("Expected %s to be %#x but got %#x", name, want, got)
+		}
+	}
+
+	h := byName(t, "maphash")
+	if h.Sum("a") != h.Sum("a") || h.Sum("a") == h.Sum("b") {
+		t.Error("Expected maphash to be the same for equal keys and differ for others")
+	}
+}
+
+func TestCollisions(t *testing.T) {
+
+	last := hashbench.Func{Name: "last", Bits: 8, Sum: func(s string) uint64 { return uint64(s[len(s)-1]) }}
+	if n := hashbench.Collisions(last, []string{"a1", "b1", "c1", "a2"}); n != 2 {
+		t.Errorf("Expected 2 collisions but got %d", n)
+	}
+}
+
+func TestExpected(t *testing.T) {
+
+	// about n²/2^(bits+1) while that is small
+	if e := hashbench.Expected(1_000_000, 32); math.Abs(e-116.4) > 0.5 {
+		t.Errorf("Expected about 116.4 collisions of a million 32-bit hashes but got %g", e)
+	}
+	if e := hashbench.Expected(1_000_000, 64); e < 0 || e > 1e-6 {
+		t.Errorf("Expected no collisions of a million 64-bit hashes but got %g", e)
+	}
+}
+
+func BenchmarkHash(b *testing.B) {
+
+	for _, shape := range mapkeys.Shapes {
+		for _, n := range sizes {
+			keys := shape.Keys(n)
+			bytes := 0
+			for _, k := range keys {
+				bytes += len(k)
+			}
+			for _, f := range hashbench.Funcs {
+				collisions := hashbench.Collisions(f, keys)
+				b.Run(fmt.Sprintf("func=%s/keys=%s/n=%d", f.Name, shape.Name, n), func(b *testing.B) {
+					b.SetBytes(int64(bytes))
+					b.ReportAllocs()
+					for b.Loop() {
+						for _, k := range keys {
+							sink += f.Sum(k)
+						}
+					}
+					b.ReportMetric(float64(collisions), "collisions")
+					b.ReportMetric(hashbench.Expected(n, f.Bits), "expected-collisions")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Package keygen produces keys for benchmarks: the long string keys used by
+// the map benchmarks in this directory and skewed key streams that look like
+// real cache traffic.
+package keygen
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// String returns a key in the format "i###i###i###i###i###i###i###i".
+func String(i int) string {
+
+	parts := make([]string, 8)
+	s := strconv.Itoa(i)
+	for j := 0; j < 8; j++ {
+		parts[j] = s
+	}
+
+	return strings.Join(parts, "###")
+}
+
+// Zipf returns count keys in [0, n) drawn from a Zipf distribution with
+// exponent s > 1: key 0 is the most popular, key 1 the next and so on.
+// The same seed always gives the same keys.
+func Zipf(seed uint64, s float64, n uint64, count int) []uint64 {
+
+	r := rand.New(rand.NewPCG(seed, seed))
+	z := rand.NewZipf(r, s, 1, n-1)
+
+	keys := make([]uint64, count)
+	for i := range keys {
+		keys[i] = z.Uint64()
+	}
+
+	return keys
+}
@@ -0,0 +1,37 @@
+package keygen_test
+
+import (
+	"testing"
+
+	"pacx/Benchmarking/keygen"
+)
+
+func TestString(t *testing.T) {
+
+	if got := keygen.String(7); got != "7###7###7###7###7###7###7###7" {
+		t.Errorf("Expected 7###7###7###7###7###7###7###7 but got %s", got)
+	}
+}
+
+func TestZipf(t *testing.T) {
+
+	keys := keygen.Zipf(1, 1.2, 1000, 10000)
+
+	counts := make(map[uint64]int)
+	for _, k := range keys {
+		if k >= 1000 {
+			t.Fatalf("Expected keys below 1000 but got %d", k)
+		}
+		counts[k]++
+	}
+	if counts[0] <= counts[10] || counts[10] <= counts[500] {
+		t.Errorf("Expected popularity to fall with the key but got %d, %d, %d", counts[0], counts[10], counts[500])
+	}
+
+	again := keygen.Zipf(1, 1.2, 1000, 10000)
+	for i := range keys {
+		if keys[i] != again[i] {
+			t.Fatal("Expected the same seed to give the same keys")
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package mapkeys holds the keys of the map-key experiments: is it faster
+// to look up long string keys, or to hash them to an integer once and look
+// that up instead? The benchmarks are in the tests:
+//
+//	go test -run '^$' -bench . -count 10 ./Benchmarking/mapkeys > new.txt
+//	benchstat old.txt new.txt
+//
+// Sub-benchmarks are named key=<type>/keys=<shape>/n=<count>, so
+// benchstat can compare and group them by any of the three.
+package mapkeys
+
+import (
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"pacx/Benchmarking/keygen"
+)
+
+// Shape is a way of generating n distinct keys.
+type Shape struct {
+	Name string
+	Keys func(n int) []string
+}
+
+// Shapes are the key sets of the original experiments.
+var Shapes = []Shape{
+	{"keygen", Keygen},
+	{"repeat10", func(n int) []string { return Repeated(n, 10) }},
+}
+
+// Keygen returns keygen.String(0) to keygen.String(n-1), the keys of the
+// map[string]int vs map[uint64]int experiment.
+func Keygen(n int) []string {
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = keygen.String(i)
+	}
+
+	return keys
+}
+
+// Repeated returns "#i" repeated count times for i in [0, n), the keys of
+// the map[string]int vs map[uint]int experiment. It appends to a nil
+// slice, as that experiment did; see Profiling/growth for what that costs.
+func Repeated(n, count int) []string {
+
+	var keys []string
+	for i := 0; i < n; i++ {
+		keys = append(keys, strings.Repeat("#"+strconv.Itoa(i), count))
+	}
+
+	return keys
+}
+
+// Hash64 is FNV-1a over s. Benchmarking/hashbench compares it with other
+// hashes.
+func Hash64(s string) uint64 {
+
+	h := fnv.New64a()
+	h.Write([]byte(s))
+
+	return h.Sum64()
+}
+
+// Hash32 is 32-bit FNV-1a over s.
+func Hash32(s string) uint32 {
+
+	h := fnv.New32a()
+	h.Write([]byte(s))
+
+	return h.Sum32()
+}
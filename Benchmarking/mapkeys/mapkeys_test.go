@@ -0,0 +1,141 @@
+package mapkeys_test
+
+import (
+	"fmt"
+	"testing"
+
+	"pacx/Benchmarking/mapkeys"
+)
+
+var sizes = []int{1_000, 100_000}
+
+// sink keeps the lookups from being optimised away.
+var sink int
+
+func TestShapesAreDistinct(t *testing.T) {
+
+	for _, s := range mapkeys.Shapes {
+		keys := s.Keys(1000)
+		seen := make(map[string]bool, len(keys))
+		for _, k := range keys {
+			seen[k] = true
+		}
+		if len(keys) != 1000 || len(seen) != 1000 {
+			t.Errorf("Expected 1000 distinct %s keys but got %d of %d", s.Name, len(seen), len(keys))
+		}
+	}
+}
+
+func TestHashes(t *testing.T) {
+
+	// the FNV-1a test vectors for "a"
+	if h := mapkeys.Hash64("a"); h != 0xaf63dc4c8601ec8c {
+		t.Errorf("Expected 0xaf63dc4c8601ec8c but got %#x", h)
+	}
+	if h := mapkeys.Hash32("a"); h != 0xe40c292c {
+		t.Errorf("Expected 0xe40c292c but got %#x", h)
+	}
+}
+
+// build maps keys[i] to i. Keys whose hashes collide keep the last index.
+func build[K comparable](keys []K) map[K]int {
+
+	m := make(map[K]int, len(keys))
+	for i, k := range keys {
+		m[k] = i
+	}
+
+	return m
+}
+
+func convert[K any](keys []string, fn func(string) K) []K {
+
+	out := make([]K, len(keys))
+	for i, k := range keys {
+		out[i] = fn(k)
+	}
+
+	return out
+}
+
+// lookup is one lookup per op, going round keys in order.
+func lookup[K comparable](b *testing.B, keys []K) {
+
+	m := build(keys)
+	b.ReportAllocs()
+
+	i := 0
+	for b.Loop() {
+		sink += m[keys[i]]
+		if i++; i == len(keys) {
+			i = 0
+		}
+	}
+}
+
+// insert is one insert per op into a map that starts empty every round of
+// keys, so growing the map is part of the cost.
+func insert[K comparable](b *testing.B, keys []K) {
+
+	b.ReportAllocs()
+
+	m := make(map[K]int)
+	i := 0
+	for b.Loop() {
+		m[keys[i]] = i
+		if i++; i == len(keys) {
+			i = 0
+			m = make(map[K]int)
+		}
+	}
+}
+
+// each runs fn for every shape and size, named for benchstat.
+func each(b *testing.B, key string, fn func(b *testing.B, keys []string)) {
+
+	for _, s := range mapkeys.Shapes {
+		for _, n := range sizes {
+			keys := s.Keys(n)
+			b.Run(fmt.Sprintf("key=%s/keys=%s/n=%d", key, s.Name, n), func(b *testing.B) {
+				fn(b, keys)
+			})
+		}
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+
+	each(b, "string", func(b *testing.B, keys []string) {
+		lookup(b, keys)
+	})
+	each(b, "uint64", func(b *testing.B, keys []string) {
+		lookup(b, convert(keys, mapkeys.Hash64))
+	})
+	each(b, "uint32", func(b *testing.B, keys []string) {
+		lookup(b, convert(keys, mapkeys.Hash32))
+	})
+	// the uint64 map when the caller only has the string: every lookup
+	// hashes it first
+	each(b, "string-fnv64", func(b *testing.B, keys []string) {
+		m := build(convert(keys, mapkeys.Hash64))
+		b.ReportAllocs()
+
+		i := 0
+		for b.Loop() {
+			sink += m[mapkeys.Hash64(keys[i])]
+			if i++; i == len(keys) {
+				i = 0
+			}
+		}
+	})
+}
+
+func BenchmarkInsert(b *testing.B) {
+
+	each(b, "string", func(b *testing.B, keys []string) {
+		insert(b, keys)
+	})
+	each(b, "uint64", func(b *testing.B, keys []string) {
+		insert(b, convert(keys, mapkeys.Hash64))
+	})
+}
@@ -0,0 +1,172 @@
+// Package microbench times a closure call by call, for when the mean that
+// testing.B reports is not enough: it runs warmup calls first, so caches,
+// the branch predictor and the heap settle, then times every call and
+// reports the distribution along with the allocations:
+//
+//	r, _ := microbench.Run(func() { sink = strconv.Itoa(12345) },
+//		microbench.WithName("itoa"), microbench.WithIterations(100_000))
+//	microbench.Print(os.Stdout, r)
+//
+// Reading the clock costs tens of nanoseconds, which swamps a closure that
+// is faster than that; WithBatch times that many calls at once and reports
+// each sample divided by it.
+package microbench
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"pacx/mazamamaths/stats"
+	"pacx/options"
+)
+
+type config struct {
+	name       string
+	warmup     int
+	iterations int
+	batch      int
+}
+
+// Option configures Run.
+type Option = options.Option[config]
+
+// WithName names the result.
+func WithName(name string) Option {
+	return options.New("WithName", func(c *config) error {
+		c.name = name
+		return nil
+	})
+}
+
+// WithWarmup sets how many calls run before timing starts. Defaults to
+// 1000.
+func WithWarmup(n int) Option {
+	return options.New("WithWarmup", func(c *config) error {
+		if n < 0 {
+			return errors.New("warmup must not be negative")
+		}
+		c.warmup = n
+		return nil
+	})
+}
+
+// WithIterations sets how many samples are timed. Defaults to 10000.
+func WithIterations(n int) Option {
+	return options.New("WithIterations", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one iteration")
+		}
+		c.iterations = n
+		return nil
+	})
+}
+
+// WithBatch makes each sample n calls. Defaults to 1.
+func WithBatch(n int) Option {
+	return options.New("WithBatch", func(c *config) error {
+		if n < 1 {
+			return errors.New("batch must be at least one call")
+		}
+		c.batch = n
+		return nil
+	})
+}
+
+// Result is the distribution of the time one call took, and what the
+// timed calls allocated. Percentiles interpolate between samples, see
+// stats.Percentile.
+type Result struct {
+	Name    string
+	Calls   int           // timed, not counting the warmup
+	Elapsed time.Duration // of the timed calls
+	Min     time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	StdDev  time.Duration
+
+	AllocsPerOp float64
+	BytesPerOp  float64
+	GCs         uint32 // collections during the timed calls
+}
+
+// Mean is the average time of a call.
+func (r Result) Mean() time.Duration {
+	if r.Calls == 0 {
+		return 0
+	}
+	return r.Elapsed / time.Duration(r.Calls)
+}
+
+// Run warms fn up and then times it.
+func Run(fn func(), opts ...Option) (Result, error) {
+
+	cfg, err := options.Build(config{warmup: 1000, iterations: 10_000, batch: 1}, nil, opts...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	for range cfg.warmup {
+		fn()
+	}
+
+	// allocated before the first reading so it does not count
+	samples := make([]time.Duration, cfg.iterations)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	start := time.Now()
+	for i := range samples {
+		t := time.Now()
+		for range cfg.batch {
+			fn()
+		}
+		samples[i] = time.Since(t)
+	}
+	elapsed := time.Since(start)
+
+	runtime.ReadMemStats(&after)
+
+	if cfg.batch > 1 {
+		for i := range samples {
+			samples[i] /= time.Duration(cfg.batch)
+		}
+	}
+	ps := stats.Percentiles(samples, 0, 50, 90, 99, 100)
+
+	calls := cfg.iterations * cfg.batch
+	return Result{
+		Name:        cfg.name,
+		Calls:       calls,
+		Elapsed:     elapsed,
+		Min:         time.Duration(ps[0]),
+		P50:         time.Duration(ps[1]),
+		P90:         time.Duration(ps[2]),
+		P99:         time.Duration(ps[3]),
+		Max:         time.Duration(ps[4]),
+		StdDev:      time.Duration(stats.StdDev(samples)),
+		AllocsPerOp: float64(after.Mallocs-before.Mallocs) / float64(calls),
+		BytesPerOp:  float64(after.TotalAlloc-before.TotalAlloc) / float64(calls),
+		GCs:         after.NumGC - before.NumGC,
+	}, nil
+}
+
+// Print writes a row per result.
+func Print(w io.Writer, results ...Result) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "NAME\tCALLS\tMEAN\tSTDDEV\tMIN\tP50\tP90\tP99\tMAX\tALLOCS/OP\tB/OP\tGCS\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%.1f\t%.0f\t%d\t\n",
+			r.Name, r.Calls, r.Mean(), r.StdDev, r.Min, r.P50, r.P90, r.P99, r.Max, r.AllocsPerOp, r.BytesPerOp, r.GCs)
+	}
+
+	return tw.Flush()
+}
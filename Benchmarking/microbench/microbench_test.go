@@ -0,0 +1,75 @@
+package microbench_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/Benchmarking/microbench"
+)
+
+var sink []byte
+
+func TestRun(t *testing.T) {
+
+	calls := 0
+	r, err := microbench.Run(func() {
+		calls++
+		sink = make([]byte, 64)
+	}, microbench.WithName("alloc"), microbench.WithWarmup(10), microbench.WithIterations(500))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 510 || r.Calls != 500 {
+		t.Errorf("Expected 10 warmup and 500 timed calls but got %d calls, %d timed", calls, r.Calls)
+	}
+	if !(r.Min <= r.P50 && r.P50 <= r.P90 && r.P90 <= r.P99 && r.P99 <= r.Max) {
+		t.Errorf("Expected ordered percentiles but got %v %v %v %v %v", r.Min, r.P50, r.P90, r.P99, r.Max)
+	}
+	if r.AllocsPerOp < 1 || r.AllocsPerOp > 1.1 || r.BytesPerOp < 64 || r.BytesPerOp > 70 {
+		t.Errorf("Expected about one 64 byte allocation a call but got %.2f allocs and %.1f bytes", r.AllocsPerOp, r.BytesPerOp)
+	}
+}
+
+func TestBatch(t *testing.T) {
+
+	r, err := microbench.Run(func() { time.Sleep(time.Millisecond) },
+		microbench.WithWarmup(0), microbench.WithIterations(3), microbench.WithBatch(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Calls != 12 {
+		t.Errorf("Expected 12 calls but got %d", r.Calls)
+	}
+	// each sample is the time of one call, not of the batch
+	if r.P50 < time.Millisecond || r.P50 > 4*time.Millisecond {
+		t.Errorf("Expected about 1ms a call but got %v", r.P50)
+	}
+	if r.Mean() < time.Millisecond {
+		t.Errorf("Expected a mean of at least 1ms but got %v", r.Mean())
+	}
+}
+
+func TestOptions(t *testing.T) {
+
+	for _, opt := range []microbench.Option{
+		microbench.WithWarmup(-1),
+		microbench.WithIterations(0),
+		microbench.WithBatch(0),
+	} {
+		if _, err := microbench.Run(func() {}, opt); err == nil {
+			t.Errorf("Expected %s to be refused but got no error", opt.Name())
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+
+	var b strings.Builder
+	microbench.Print(&b, microbench.Result{Name: "noop", Calls: 10, Elapsed: 100 * time.Nanosecond})
+	if !strings.Contains(b.String(), "P99") || !strings.Contains(b.String(), "noop") {
+		t.Errorf("Expected a header and a row but got\n%s", b.String())
+	}
+}
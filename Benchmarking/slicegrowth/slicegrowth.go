@@ -0,0 +1,69 @@
+// Package slicegrowth measures what appending to a slice that grows costs
+// next to allocating it at its final size, at the sizes the repo's slice
+// examples use: the five element array of basics/try.go and the ten
+// million ints of proff/memory.go, with two sizes in between.
+//
+//	go test -run '^$' -bench . -count 10 ./Benchmarking/slicegrowth > new.txt
+//	benchstat -col /how new.txt
+//
+// The benchmarks are named Fill/how=<way>/n=<size>. Besides the usual
+// ns/op, B/op and allocs/op the append ones report grows/op, the
+// reallocations append made, and copied-B/op, the bytes those moved.
+package slicegrowth
+
+import "unsafe"
+
+// Sizes are the element counts the benchmarks fill.
+var Sizes = []int{5, 1_000, 100_000, 10_000_000}
+
+// Append fills a slice of n ints by appending to nil, letting append
+// grow it.
+func Append(n int) []int {
+
+	var s []int
+	for i := 0; i < n; i++ {
+		s = append(s, i)
+	}
+
+	return s
+}
+
+// Prealloc fills a slice of n ints by appending to one made with capacity
+// n.
+func Prealloc(n int) []int {
+
+	s := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		s = append(s, i)
+	}
+
+	return s
+}
+
+// Make fills a slice of n ints made at length n by index, as
+// allocateMemory in proff/memory.go does.
+func Make(n int) []int {
+
+	s := make([]int, n)
+	for i := range s {
+		s[i] = i
+	}
+
+	return s
+}
+
+// Growth returns how many times appending n ints one by one to nil
+// reallocates, and how many bytes those reallocations copy.
+func Growth(n int) (grows int, copied int64) {
+
+	var s []int
+	for i := 0; i < n; i++ {
+		if len(s) == cap(s) {
+			grows++
+			copied += int64(len(s)) * int64(unsafe.Sizeof(s[0]))
+		}
+		s = append(s, i)
+	}
+
+	return grows, copied
+}
@@ -0,0 +1,115 @@
+package slicegrowth_test
+
+import (
+	"fmt"
+	"testing"
+
+	"pacx/Benchmarking/slicegrowth"
+)
+
+var sink int
+
+func TestFillsAgree(t *testing.T) {
+
+	for _, n := range []int{0, 1, 5, 1000} {
+		a, p, m := slicegrowth.Append(n), slicegrowth.Prealloc(n), slicegrowth.Make(n)
+		if len(a) != n || len(p) != n || len(m) != n {
+			t.Fatalf("Expected %d ints but got %d, %d and %d", n, len(a), len(p), len(m))
+		}
+		for i := range n {
+			if a[i] != i || p[i] != i || m[i] != i {
+				t.Fatalf("Expected %d at %d but got %d, %d and %d", i, i, a[i], p[i], m[i])
+			}
+		}
+		if cap(p) != n {
+			t.Errorf("Expected Prealloc to stay at capacity %d but got %d", n, cap(p))
+		}
+	}
+}
+
+func TestGrowth(t *testing.T) {
+
+	if grows, copied := slicegrowth.Growth(0); grows != 0 || copied != 0 {
+		t.Errorf("Expected nothing for no appends but got %d and %d", grows, copied)
+	}
+	// the first append allocates with nothing to copy; how big depends on
+	// the compiler, which may start with a small buffer on the stack
+	if grows, copied := slicegrowth.Growth(1); grows != 1 || copied != 0 {
+		t.Errorf("Expected one grow copying nothing but got %d and %d", grows, copied)
+	}
+	if grows, copied := slicegrowth.Growth(10_000_000); grows < 30 || copied < 80<<20 {
+		t.Errorf("Expected dozens of grows copying more than the final 80MB but got %d and %d", grows, copied)
+	}
+}
+
+func BenchmarkFill(b *testing.B) {
+
+	fills := []struct {
+		how  string
+		fill func(int) []int
+	}{
+		{"append", slicegrowth.Append},
+		{"prealloc", slicegrowth.Prealloc},
+		{"make", slicegrowth.Make},
+	}
+
+	for _, f := range fills {
+		for _, n := range slicegrowth.Sizes {
+			b.Run(fmt.Sprintf("how=%s/n=%d", f.how, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					sink += len(f.fill(n))
+				}
+				if f.how == "append" {
+					grows, copied := slicegrowth.Growth(n)
+					b.ReportMetric(float64(grows), "grows/op")
+					b.ReportMetric(float64(copied), "copied-B/op")
+				}
+			})
+		}
+	}
+
+	// an array has a constant size, so each size is its own type and its
+	// own case; small ones live on the stack, large ones go to the heap
+	// like make
+	b.Run("how=array/n=5", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var a [5]int
+			for i := range a {
+				a[i] = i
+			}
+			sink += a[4]
+		}
+	})
+	b.Run("how=array/n=1000", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var a [1_000]int
+			for i := range a {
+				a[i] = i
+			}
+			sink += a[999]
+		}
+	})
+	b.Run("how=array/n=100000", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var a [100_000]int
+			for i := range a {
+				a[i] = i
+			}
+			sink += a[99_999]
+		}
+	})
+	b.Run("how=array/n=10000000", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			a := new([10_000_000]int)
+			for i := range a {
+				a[i] = i
+			}
+			sink += a[9_999_999]
+		}
+	})
+}
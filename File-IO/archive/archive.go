@@ -0,0 +1,133 @@
+// Package archive compresses streams with gzip and packs directories into
+// zip files and out again. Unpacking treats the archive as untrusted: no
+// entry can write outside the target directory, whatever its name says or
+// whatever symbolic links are already in the directory, and limits on the
+// unpacked size stop archives that expand without end.
+package archive
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"pacx/options"
+)
+
+var (
+	// ErrUnsafePath is returned for an archive entry whose name would
+	// place it outside the target directory, or that is a symbolic link.
+	ErrUnsafePath = errors.New("archive: unsafe path")
+	// ErrTooLarge is returned when unpacking exceeds WithMaxSize.
+	ErrTooLarge = errors.New("archive: too large")
+)
+
+type config struct {
+	level   int
+	maxSize int64
+	maxEnts int
+}
+
+// Option configures the functions of this package.
+type Option = options.Option[config]
+
+// WithLevel sets the compression level, from gzip.HuffmanOnly to
+// gzip.BestCompression, which zip shares. Defaults to
+// gzip.DefaultCompression.
+func WithLevel(level int) Option {
+	return options.New("WithLevel", func(c *config) error {
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return fmt.Errorf("compression level %d out of range", level)
+		}
+		c.level = level
+		return nil
+	})
+}
+
+// WithMaxSize limits the bytes Decompress writes, or UnzipTo in total.
+// Defaults to 1 GiB; 0 removes the limit.
+func WithMaxSize(n int64) Option {
+	return options.New("WithMaxSize", func(c *config) error {
+		if n < 0 {
+			return errors.New("max size must not be negative")
+		}
+		c.maxSize = n
+		return nil
+	})
+}
+
+// WithMaxEntries limits the entries UnzipTo accepts. Defaults to 100000.
+func WithMaxEntries(n int) Option {
+	return options.New("WithMaxEntries", func(c *config) error {
+		if n < 1 {
+			return errors.New("max entries must be at least 1")
+		}
+		c.maxEnts = n
+		return nil
+	})
+}
+
+func build(opts []Option) (config, error) {
+
+	cfg, err := options.Build(config{level: gzip.DefaultCompression, maxSize: 1 << 30, maxEnts: 100_000}, nil, opts...)
+	if cfg.maxSize == 0 {
+		cfg.maxSize = math.MaxInt64
+	}
+
+	return cfg, err
+}
+
+// Compress writes src to dst gzipped and returns how many bytes it read.
+func Compress(dst io.Writer, src io.Reader, opts ...Option) (int64, error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	zw, err := gzip.NewWriterLevel(dst, cfg.level)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+
+	return n, err
+}
+
+// Decompress writes the gunzipped src to dst and returns how many bytes it
+// wrote. Concatenated gzip streams come out as one, like gunzip does.
+func Decompress(dst io.Writer, src io.Reader, opts ...Option) (int64, error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		return 0, err
+	}
+	defer zr.Close()
+
+	return copyLimited(dst, zr, cfg.maxSize)
+}
+
+// copyLimited copies at most limit bytes, failing with ErrTooLarge if there
+// are more.
+func copyLimited(dst io.Writer, src io.Reader, limit int64) (int64, error) {
+
+	n, err := io.Copy(dst, io.LimitReader(src, limit))
+	if err != nil {
+		return n, err
+	}
+	// one more byte tells a stream of exactly limit from a longer one
+	if m, _ := src.Read(make([]byte, 1)); m > 0 {
+		return n, ErrTooLarge
+	}
+
+	return n, nil
+}
@@ -0,0 +1,213 @@
+package archive_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/File-IO/archive"
+)
+
+func TestGzipRoundTrip(t *testing.T) {
+
+	for _, in := range []string{"", "hello", strings.Repeat("all work and no play ", 10_000)} {
+		var packed, out bytes.Buffer
+		if _, err := archive.Compress(&packed, strings.NewReader(in), archive.WithLevel(gzip.BestCompression)); err != nil {
+			t.Fatal(err)
+		}
+		n, err := archive.Decompress(&out, &packed)
+		if err != nil || n != int64(len(in)) || out.String() != in {
+			t.Errorf("Expected %d bytes back but got %d, %v", len(in), n, err)
+		}
+	}
+}
+
+func TestDecompressMalformed(t *testing.T) {
+
+	var packed bytes.Buffer
+	archive.Compress(&packed, strings.NewReader(strings.Repeat("x", 1000)))
+
+	for _, tc := range []struct {
+		name string
+		in   []byte
+		err  error
+	}{
+		{"not gzip", []byte("plain text"), gzip.ErrHeader},
+		{"empty", nil, io.EOF},
+		{"truncated", packed.Bytes()[:packed.Len()/2], io.ErrUnexpectedEOF},
+	} {
+		if _, err := archive.Decompress(io.Discard, bytes.NewReader(tc.in)); !errors.Is(err, tc.err) {
+			t.Errorf("Expected %v for %s but got %v", tc.err, tc.name, err)
+		}
+	}
+}
+
+func TestDecompressMaxSize(t *testing.T) {
+
+	var packed bytes.Buffer
+	archive.Compress(&packed, bytes.NewReader(make([]byte, 1<<20)))
+
+	if _, err := archive.Decompress(io.Discard, bytes.NewReader(packed.Bytes()), archive.WithMaxSize(1<<20)); err != nil {
+		t.Errorf("Expected exactly the max size to pass but got %v", err)
+	}
+	if _, err := archive.Decompress(io.Discard, bytes.NewReader(packed.Bytes()), archive.WithMaxSize(1<<20-1)); !errors.Is(err, archive.ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge one byte over but got %v", err)
+	}
+}
+
+func TestZipRoundTrip(t *testing.T) {
+
+	src := t.TempDir()
+	mtime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	for name, data := range map[string]string{"a.txt": "alpha", "sub/b.txt": "beta", "sub/deep/c.bin": "\x00\x01"} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(data), 0o640); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mtime, mtime)
+	}
+	os.Mkdir(filepath.Join(src, "empty"), 0o755)
+
+	var buf bytes.Buffer
+	if err := archive.ZipDir(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(t.TempDir(), "out")
+	if err := archive.UnzipTo(dst, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string]string{"a.txt": "alpha", "sub/b.txt": "beta", "sub/deep/c.bin": "\x00\x01"} {
+		p := filepath.Join(dst, filepath.FromSlash(name))
+		got, err := os.ReadFile(p)
+		if err != nil || string(got) != data {
+			t.Errorf("Expected %s to be %q but got %q, %v", name, data, got, err)
+		}
+		fi, _ := os.Stat(p)
+		if !fi.ModTime().Equal(mtime) {
+			t.Errorf("Expected %s to keep its modification time but got %v", name, fi.ModTime())
+		}
+		if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o640 {
+			t.Errorf("Expected %s to keep mode 0640 but got %v", name, fi.Mode())
+		}
+	}
+	if fi, err := os.Stat(filepath.Join(dst, "empty")); err != nil || !fi.IsDir() {
+		t.Errorf("Expected the empty directory to be unpacked but got %v", err)
+	}
+}
+
+// zipOf builds an archive by hand, for entries ZipDir would never write.
+func zipOf(t *testing.T, entries ...*zip.FileHeader) *bytes.Reader {
+
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, h := range entries {
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte("payload"))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestUnzipRejectsUnsafePaths(t *testing.T) {
+
+	link := &zip.FileHeader{Name: "link"}
+	link.SetMode(fs.ModeSymlink | 0o777)
+
+	for _, h := range []*zip.FileHeader{
+		{Name: "../evil"},
+		{Name: "/etc/evil"},
+		{Name: "ok/../../evil"},
+		{Name: `..\evil`},
+		{Name: ""},
+		link,
+	} {
+		dir := t.TempDir()
+		r := zipOf(t, &zip.FileHeader{Name: "fine.txt"}, h)
+		if err := archive.UnzipTo(dir, r, r.Size()); !errors.Is(err, archive.ErrUnsafePath) {
+			t.Errorf("Expected ErrUnsafePath for %q but got %v", h.Name, err)
+		}
+		if des, _ := os.ReadDir(dir); len(des) != 0 {
+			t.Errorf("Expected nothing to be unpacked with %q in the archive but got %d entries", h.Name, len(des))
+		}
+	}
+}
+
+func TestUnzipDoesNotFollowLinksOut(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on Windows")
+	}
+
+	outside := t.TempDir()
+	dir := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := zipOf(t, &zip.FileHeader{Name: "escape/evil"})
+	if err := archive.UnzipTo(dir, r, r.Size()); err == nil {
+		t.Error("Expected an error writing through a link out of the directory but got none")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "evil")); err == nil {
+		t.Error("Expected nothing to be written outside the directory but evil was")
+	}
+}
+
+func TestUnzipLimits(t *testing.T) {
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range []string{"a", "b"} {
+		w, _ := zw.Create(name)
+		w.Write(make([]byte, 600))
+	}
+	zw.Close()
+	r := bytes.NewReader(buf.Bytes())
+
+	if err := archive.UnzipTo(t.TempDir(), r, r.Size(), archive.WithMaxSize(1200)); err != nil {
+		t.Errorf("Expected 1200 bytes to fit but got %v", err)
+	}
+	if err := archive.UnzipTo(t.TempDir(), r, r.Size(), archive.WithMaxSize(1000)); !errors.Is(err, archive.ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge over 1000 bytes but got %v", err)
+	}
+	if err := archive.UnzipTo(t.TempDir(), r, r.Size(), archive.WithMaxEntries(1)); !errors.Is(err, archive.ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge for 2 entries but got %v", err)
+	}
+}
+
+func TestUnzipMalformed(t *testing.T) {
+
+	for _, in := range [][]byte{[]byte("not a zip at all"), nil} {
+		if err := archive.UnzipTo(t.TempDir(), bytes.NewReader(in), int64(len(in))); !errors.Is(err, zip.ErrFormat) {
+			t.Errorf("Expected zip.ErrFormat but got %v", err)
+		}
+	}
+
+	// a damaged payload is caught by the checksum of the entry
+	r := zipOf(t, &zip.FileHeader{Name: "data", Method: zip.Store})
+	data, _ := io.ReadAll(r)
+	i := bytes.Index(data, []byte("payload"))
+	data[i] ^= 0xff
+	if err := archive.UnzipTo(t.TempDir(), bytes.NewReader(data), int64(len(data))); !errors.Is(err, zip.ErrChecksum) {
+		t.Errorf("Expected zip.ErrChecksum for a damaged entry but got %v", err)
+	}
+}
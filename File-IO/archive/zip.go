@@ -0,0 +1,173 @@
+package archive
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ZipDir writes the tree under dir to dst as a zip archive, with paths
+// relative to dir. Regular files keep their permissions and modification
+// times; symbolic links and other special files are skipped.
+func ZipDir(dst io.Writer, dir string, opts ...Option) error {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(dst)
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, cfg.level)
+	})
+
+	err = filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		h, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		h.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			h.Name += "/"
+			_, err := zw.CreateHeader(h)
+			return err
+		}
+		h.Method = zip.Deflate
+
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// UnzipTo unpacks the zip archive in src, of size bytes, into dir, which
+// is created if needed. Existing files are overwritten. It fails with
+// ErrUnsafePath, before writing anything, if any entry is named to land
+// outside dir or is a symbolic link, and with ErrTooLarge once the
+// unpacked files exceed WithMaxSize.
+func UnzipTo(dir string, src io.ReaderAt, size int64, opts ...Option) error {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(src, size)
+	if err != nil {
+		return err
+	}
+	if len(zr.File) > cfg.maxEnts {
+		return fmt.Errorf("%w: %d entries", ErrTooLarge, len(zr.File))
+	}
+	for _, f := range zr.File {
+		if err := checkEntry(f); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	// the root also stops entries from following symbolic links already
+	// in dir out of it
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	left := cfg.maxSize
+	for _, f := range zr.File {
+		name := path.Clean(f.Name)
+		if f.FileInfo().IsDir() {
+			if err := root.MkdirAll(name, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := root.MkdirAll(path.Dir(name), 0o755); err != nil {
+			return err
+		}
+		n, err := unzipFile(root, name, f, left)
+		if err != nil {
+			return err
+		}
+		left -= n
+	}
+
+	return nil
+}
+
+// checkEntry rejects the entries UnzipTo must not unpack.
+func checkEntry(f *zip.File) error {
+
+	name := strings.TrimSuffix(f.Name, "/")
+	if !filepath.IsLocal(name) || strings.Contains(name, `\`) || !fs.ValidPath(path.Clean(name)) {
+		return fmt.Errorf("%w: %q", ErrUnsafePath, f.Name)
+	}
+	if mode := f.Mode(); !mode.IsDir() && !mode.IsRegular() {
+		return fmt.Errorf("%w: %q is a %v", ErrUnsafePath, f.Name, mode.Type())
+	}
+
+	return nil
+}
+
+// unzipFile writes the entry f to name, up to left bytes.
+func unzipFile(root *os.Root, name string, f *zip.File, left int64) (int64, error) {
+
+	rc, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	out, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	n, err := copyLimited(out, rc, left)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, fmt.Errorf("%s: %w", f.Name, err)
+	}
+	if err := root.Chtimes(name, f.Modified, f.Modified); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
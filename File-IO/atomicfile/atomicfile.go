@@ -0,0 +1,123 @@
+// Package atomicfile replaces files so that readers, and a crash, see
+// either the old contents or the new, never half of each. The data goes to
+// a temporary file in the same directory, which is synced and then renamed
+// over the target.
+package atomicfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrClosed is returned by writes to a File that was closed or aborted.
+var ErrClosed = errors.New("atomicfile: file already closed")
+
+// Write replaces the file at path with data, like os.WriteFile but
+// atomically. The file gets perm as it is, without the umask.
+func Write(path string, data []byte, perm fs.FileMode) error {
+
+	f, err := Create(path, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Abort()
+		return err
+	}
+
+	return f.Close()
+}
+
+// File is the streaming form of Write: what is written to it replaces the
+// file at its path on Close. Nothing changes at the path until then, and
+// nothing at all if a write fails or Abort is called first.
+type File struct {
+	path string
+	tmp  *os.File
+	err  error // first failed write, which makes Close abort
+	done bool
+}
+
+// Create starts replacing the file at path, which gets perm when it is
+// committed. The temporary file is created next to it, so path's
+// directory must exist and be writable.
+func Create(path string, perm fs.FileMode) (*File, error) {
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &File{path: path, tmp: tmp}, nil
+}
+
+// Name returns the path the file replaces.
+func (f *File) Name() string {
+	return f.path
+}
+
+func (f *File) Write(p []byte) (int, error) {
+
+	if f.done {
+		return 0, ErrClosed
+	}
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	n, err := f.tmp.Write(p)
+	if err != nil {
+		f.err = err
+	}
+
+	return n, err
+}
+
+// Close syncs what was written and renames it over the target, then syncs
+// the directory so the rename itself survives a crash. If a write failed,
+// Close discards everything instead and returns that error.
+func (f *File) Close() error {
+
+	if f.done {
+		return ErrClosed
+	}
+	if f.err != nil {
+		f.Abort()
+		return f.err
+	}
+	f.done = true
+
+	err := f.tmp.Sync()
+	if cerr := f.tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.tmp.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.tmp.Name())
+		return err
+	}
+
+	return syncDir(filepath.Dir(f.path))
+}
+
+// Abort discards what was written and leaves the target as it was. It
+// does nothing after Close, so it can be deferred.
+func (f *File) Abort() error {
+
+	if f.done {
+		return nil
+	}
+	f.done = true
+	f.tmp.Close()
+
+	return os.Remove(f.tmp.Name())
+}
@@ -0,0 +1,140 @@
+package atomicfile_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"pacx/File-IO/atomicfile"
+)
+
+// entries lists the names in dir, to catch temporary files left behind.
+func entries(t *testing.T, dir string) []string {
+
+	t.Helper()
+
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, de := range des {
+		names = append(names, de.Name())
+	}
+
+	return names
+}
+
+func TestWrite(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	for _, data := range []string{`{"v":1}`, `{"v":2,"more":true}`, ""} {
+		if err := atomicfile.Write(path, []byte(data), 0o640); err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != data {
+			t.Errorf("Expected %q but got %q, %v", data, got, err)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o640 {
+			t.Errorf("Expected mode 0640 but got %v, %v", fi.Mode(), err)
+		}
+	}
+	if names := entries(t, dir); len(names) != 1 {
+		t.Errorf("Expected only the target in the directory but got %v", names)
+	}
+}
+
+func TestFileCommitsOnClose(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.log")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := atomicfile.Create(path, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		fmt.Fprintf(f, "line %d\n", i)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "old" {
+		t.Errorf("Expected the old contents before Close but got %q", got)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "line 0\nline 1\nline 2\n" {
+		t.Errorf("Expected the new contents after Close but got %q", got)
+	}
+	if err := f.Close(); !errors.Is(err, atomicfile.ErrClosed) {
+		t.Errorf("Expected ErrClosed closing twice but got %v", err)
+	}
+	if err := f.Abort(); err != nil {
+		t.Errorf("Expected Abort after Close to do nothing but got %v", err)
+	}
+}
+
+func TestAbortKeepsTheOldFile(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.log")
+	if err := os.WriteFile(path, []byte("old"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := atomicfile.Create(path, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("half of the new"))
+	if err := f.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := os.ReadFile(path); string(got) != "old" {
+		t.Errorf("Expected the old contents after Abort but got %q", got)
+	}
+	if names := entries(t, dir); len(names) != 1 {
+		t.Errorf("Expected the temporary file to be removed but got %v", names)
+	}
+	if _, err := f.Write([]byte("more")); !errors.Is(err, atomicfile.ErrClosed) {
+		t.Errorf("Expected ErrClosed writing after Abort but got %v", err)
+	}
+}
+
+func TestFailedRenameCleansUp(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "taken")
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(path, "keep"), nil, 0o644)
+
+	if err := atomicfile.Write(path, []byte("data"), 0o644); err == nil {
+		t.Error("Expected an error replacing a non-empty directory but got none")
+	}
+	if names := entries(t, dir); len(names) != 1 {
+		t.Errorf("Expected the temporary file to be removed but got %v", names)
+	}
+}
+
+func TestCreateInMissingDirectory(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "missing", "file")
+	if _, err := atomicfile.Create(path, 0o644); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist but got %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package atomicfile
+
+// syncDir does nothing: directories cannot be opened for syncing here, and
+// the rename is as durable as the system makes it.
+func syncDir(string) error {
+	return nil
+}
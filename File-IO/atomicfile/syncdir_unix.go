@@ -0,0 +1,17 @@
+//go:build unix
+
+package atomicfile
+
+import "os"
+
+// syncDir makes the entries of dir durable, such as a file renamed into it.
+func syncDir(dir string) error {
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
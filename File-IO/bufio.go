@@ -3,26 +3,45 @@ package main
 import (
 	"bufio"
 	"fmt"
-	"os"
+	"path/filepath"
+
+	"pacx/File-IO/atomicfile"
+	"pacx/File-IO/tempmgr"
 )
 
 func main() {
 
-	file, err := os.Create("file.txt")
+	// a directory of our own, removed at the end or on ^C
+	tmp, err := tempmgr.New("file-io")
+	if err != nil {
+		fmt.Println("Error in creating temp dir.", err)
+		return
+	}
+	defer tmp.Close()
+
+	// file.txt is only replaced on Close, so a crash never leaves half of it
+	file, err := atomicfile.Create(filepath.Join(tmp.Root(), "file.txt"), 0o644)
 
 	if err != nil {
 		fmt.Println("Error in creating file.", err)
 		return
 	}
 
-	defer file.Close()
+	defer file.Abort()
 
 	writer := bufio.NewWriter(file)
 	write, err := writer.Write([]byte("This is the some stuff that from the bufio writer,hhehe"))
 	if err != nil {
 		return
 	}
-	writer.Flush()
+	if err := writer.Flush(); err != nil {
+		fmt.Println("Error in writing file.", err)
+		return
+	}
+	if err := file.Close(); err != nil {
+		fmt.Println("Error in saving file.", err)
+		return
+	}
 
 	fmt.Println("Wrote", write)
 
@@ -0,0 +1,272 @@
+// Package checksum hashes every file of a directory tree with SHA-256 on a
+// worker pool, writes the sums to a manifest and later verifies the tree
+// against it, reporting files added, removed and changed since.
+//
+// The manifest has the format of sha256sum, so sha256sum -c can check it
+// too:
+//
+//	9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08  docs/readme.md
+package checksum
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"pacx/File-IO/lines"
+	"pacx/concurrency/pool"
+	"pacx/options"
+)
+
+// Entry is the sum of one file. Path is relative to the root and slash
+// separated.
+type Entry struct {
+	Path string
+	Sum  [sha256.Size]byte
+}
+
+// Manifest is the entries of a tree in path order.
+type Manifest []Entry
+
+type config struct {
+	workers int
+	exclude []string
+}
+
+// Option configures Hash and Verify.
+type Option = options.Option[config]
+
+// WithWorkers sets how many files are hashed at once. Defaults to
+// GOMAXPROCS.
+func WithWorkers(n int) Option {
+	return options.New("WithWorkers", func(c *config) error {
+		if n < 1 {
+			return errors.New("workers must be at least 1")
+		}
+		c.workers = n
+		return nil
+	})
+}
+
+// WithExclude skips the files and directories whose relative path matches
+// pattern, in the syntax of path.Match. Typically the manifest itself.
+func WithExclude(pattern string) Option {
+	return options.New("WithExclude", func(c *config) error {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+		c.exclude = append(c.exclude, pattern)
+		return nil
+	})
+}
+
+func (c config) excluded(rel string) bool {
+	return slices.ContainsFunc(c.exclude, func(p string) bool {
+		ok, _ := path.Match(p, rel)
+		return ok
+	})
+}
+
+// Hash sums every regular file under root. Symbolic links and other
+// special files are skipped. It stops at the first file that cannot be
+// read, or when ctx is done.
+func Hash(ctx context.Context, root string, opts ...Option) (Manifest, error) {
+
+	cfg, err := options.Build(config{workers: runtime.GOMAXPROCS(0)}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p, err := pool.New(pool.WithWorkers(cfg.workers), pool.WithQueue(cfg.workers))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var (
+		mu sync.Mutex
+		m  Manifest
+	)
+	walkErr := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel != "." && cfg.excluded(rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return p.Submit(ctx, func() {
+			if ctx.Err() != nil {
+				return
+			}
+			sum, err := hashFile(ctx, name)
+			if err != nil {
+				cancel(err)
+				return
+			}
+			mu.Lock()
+			m = append(m, Entry{Path: rel, Sum: sum})
+			mu.Unlock()
+		})
+	})
+	p.Close()
+
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	if walkErr != nil {
+		return nil, walkErr
+	}
+	slices.SortFunc(m, func(a, b Entry) int { return strings.Compare(a.Path, b.Path) })
+
+	return m, nil
+}
+
+func hashFile(ctx context.Context, name string) ([sha256.Size]byte, error) {
+
+	var sum [sha256.Size]byte
+
+	f, err := os.Open(name)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	buf := make([]byte, 256<<10)
+	for {
+		if err := ctx.Err(); err != nil {
+			return sum, err
+		}
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return sum, err
+		}
+	}
+	h.Sum(sum[:0])
+
+	return sum, nil
+}
+
+// WriteTo writes m in the format of sha256sum.
+func (m Manifest) WriteTo(w io.Writer) (int64, error) {
+
+	var b []byte
+	for _, e := range m {
+		b = hex.AppendEncode(b, e.Sum[:])
+		b = append(b, "  "...)
+		b = append(b, e.Path...)
+		b = append(b, '\n')
+	}
+	n, err := w.Write(b)
+
+	return int64(n), err
+}
+
+// ReadManifest parses what WriteTo wrote, or sha256sum did.
+func ReadManifest(r io.Reader) (Manifest, error) {
+
+	var m Manifest
+	n := 0
+	for line, err := range lines.Lines(r) {
+		if err != nil {
+			return nil, err
+		}
+		n++
+		if line == "" {
+			continue
+		}
+		// sha256sum marks binary mode with a '*' in place of the second space
+		sum, name, ok := strings.Cut(line, " ")
+		if !ok || len(name) < 2 || name[0] != ' ' && name[0] != '*' {
+			return nil, fmt.Errorf("checksum: line %d: want <sum>  <path>", n)
+		}
+		var e Entry
+		if hex.DecodedLen(len(sum)) != len(e.Sum) {
+			return nil, fmt.Errorf("checksum: line %d: sum is not SHA-256", n)
+		}
+		if _, err := hex.Decode(e.Sum[:], []byte(sum)); err != nil {
+			return nil, fmt.Errorf("checksum: line %d: %w", n, err)
+		}
+		e.Path = name[1:]
+		m = append(m, e)
+	}
+	slices.SortFunc(m, func(a, b Entry) int { return strings.Compare(a.Path, b.Path) })
+
+	return m, nil
+}
+
+// Report is how a tree differs from its manifest, each list in path
+// order.
+type Report struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// OK reports whether the tree matches the manifest.
+func (r Report) OK() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Changed) == 0
+}
+
+// Verify hashes root again and compares it with m.
+func Verify(ctx context.Context, root string, m Manifest, opts ...Option) (Report, error) {
+
+	now, err := Hash(ctx, root, opts...)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Diff(m, now), nil
+}
+
+// Diff compares two manifests of the same tree, old and new.
+func Diff(old, new Manifest) Report {
+
+	var r Report
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case j == len(new) || i < len(old) && old[i].Path < new[j].Path:
+			r.Removed = append(r.Removed, old[i].Path)
+			i++
+		case i == len(old) || new[j].Path < old[i].Path:
+			r.Added = append(r.Added, new[j].Path)
+			j++
+		default:
+			if old[i].Sum != new[j].Sum {
+				r.Changed = append(r.Changed, old[i].Path)
+			}
+			i++
+			j++
+		}
+	}
+
+	return r
+}
@@ -0,0 +1,116 @@
+package checksum_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pacx/File-IO/checksum"
+)
+
+// tree writes files, by slash separated path, under a new directory.
+func tree(t *testing.T, files map[string]string) string {
+
+	t.Helper()
+
+	root := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestHash(t *testing.T) {
+
+	root := tree(t, map[string]string{"a.txt": "test", "sub/b.txt": "", "sub/deeper/c": "c"})
+
+	m, err := checksum.Hash(context.Background(), root, checksum.WithWorkers(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	m.WriteTo(&buf)
+	want := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08  a.txt\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  sub/b.txt\n" +
+		"2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6  sub/deeper/c\n"
+	if buf.String() != want {
+		t.Errorf("Expected the manifest\n%s\nbut got\n%s", want, buf.String())
+	}
+
+	back, err := checksum.ReadManifest(&buf)
+	if err != nil || !slices.Equal(back, m) {
+		t.Errorf("Expected the manifest to read back the same but got %v, %v", back, err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+
+	root := tree(t, map[string]string{"keep": "1", "change": "2", "remove": "3", "skip/x": "4"})
+	m, err := checksum.Hash(context.Background(), root, checksum.WithExclude("skip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m) != 3 {
+		t.Fatalf("Expected 3 files outside skip but got %v", m)
+	}
+
+	os.WriteFile(filepath.Join(root, "change"), []byte("two"), 0o644)
+	os.Remove(filepath.Join(root, "remove"))
+	os.WriteFile(filepath.Join(root, "add"), []byte("5"), 0o644)
+	os.WriteFile(filepath.Join(root, "skip", "y"), []byte("6"), 0o644)
+
+	r, err := checksum.Verify(context.Background(), root, m, checksum.WithExclude("skip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.OK() || !slices.Equal(r.Added, []string{"add"}) || !slices.Equal(r.Removed, []string{"remove"}) || !slices.Equal(r.Changed, []string{"change"}) {
+		t.Errorf("Expected add, remove and change to be reported but got %+v", r)
+	}
+}
+
+func TestReadManifestSha256sum(t *testing.T) {
+
+	// what sha256sum -b writes
+	in := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08 *name with spaces\n"
+	m, err := checksum.ReadManifest(strings.NewReader(in))
+	if err != nil || len(m) != 1 || m[0].Path != "name with spaces" {
+		t.Errorf("Expected one entry for the binary mode line but got %v, %v", m, err)
+	}
+
+	for _, bad := range []string{"nothex  a\n", "abcd  a\n", "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08\n"} {
+		if _, err := checksum.ReadManifest(strings.NewReader(bad)); err == nil {
+			t.Errorf("Expected an error for %q but got none", bad)
+		}
+	}
+}
+
+func TestHashCancel(t *testing.T) {
+
+	files := make(map[string]string)
+	for _, c := range "abcdefghijklmnop" {
+		files[string(c)] = strings.Repeat("x", 1<<16)
+	}
+	root := tree(t, files)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := checksum.Hash(ctx, root); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled but got %v", err)
+	}
+	if _, err := checksum.Hash(context.Background(), filepath.Join(root, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for a missing root but got %v", err)
+	}
+}
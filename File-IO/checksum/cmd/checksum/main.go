@@ -0,0 +1,138 @@
+// Command checksum writes a SHA-256 manifest of a directory tree, or with
+// -verify checks the tree against one and lists the files added, removed
+// and changed since. It exits with 1 if there are any.
+//
+//	go run ./File-IO/checksum/cmd/checksum -o SHA256SUMS ./data
+//	go run ./File-IO/checksum/cmd/checksum -verify SHA256SUMS ./data
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+
+	"pacx/File-IO/atomicfile"
+	"pacx/File-IO/checksum"
+)
+
+func main() {
+
+	out := flag.String("o", "", "write the manifest to this file; stdout if empty")
+	verify := flag.String("verify", "", "verify the tree against this manifest instead")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "files hashed at once")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: checksum [-o manifest | -verify manifest] [-workers n] dir")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	root := flag.Arg(0)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := []checksum.Option{checksum.WithWorkers(*workers)}
+	// a manifest inside the tree is not part of it
+	for _, f := range []string{*out, *verify} {
+		if rel, ok := within(root, f); ok {
+			opts = append(opts, checksum.WithExclude(rel))
+		}
+	}
+
+	if *verify != "" {
+		ok, err := check(ctx, root, *verify, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		return
+	}
+
+	m, err := checksum.Hash(ctx, root, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *out == "" {
+		m.WriteTo(os.Stdout)
+		return
+	}
+	f, err := atomicfile.Create(*out, 0o644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	defer f.Abort()
+	if _, err := m.WriteTo(f); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	fmt.Fprintf(os.Stderr, "%d files\n", len(m))
+}
+
+// check verifies root against the manifest at path and prints the
+// differences.
+func check(ctx context.Context, root, path string, opts []checksum.Option) (bool, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	m, err := checksum.ReadManifest(f)
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+
+	r, err := checksum.Verify(ctx, root, m, opts...)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range []struct {
+		label string
+		paths []string
+	}{{"added", r.Added}, {"removed", r.Removed}, {"changed", r.Changed}} {
+		for _, p := range l.paths {
+			fmt.Printf("%-8s %s\n", l.label, p)
+		}
+	}
+	if r.OK() {
+		fmt.Fprintf(os.Stderr, "%d files OK\n", len(m))
+	}
+
+	return r.OK(), nil
+}
+
+// within returns the slash separated path of file relative to root, if
+// file is inside it.
+func within(root, file string) (string, bool) {
+
+	if file == "" {
+		return "", false
+	}
+	absRoot, err1 := filepath.Abs(root)
+	absFile, err2 := filepath.Abs(file)
+	if err1 != nil || err2 != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil || rel == ".." || len(rel) > 2 && rel[:3] == ".."+string(filepath.Separator) {
+		return "", false
+	}
+
+	return filepath.ToSlash(rel), true
+}
@@ -0,0 +1,242 @@
+// Package csvio maps CSV records to structs and back. A column goes to the
+// exported field of the same name, or of the name in its csv tag:
+//
+//	type Trade struct {
+//		Symbol string    `csv:"symbol"`
+//		Price  float64   `csv:"price"`
+//		At     time.Time `csv:"time"`
+//		Note   string    `csv:"-"` // not a column
+//	}
+//
+// Fields can be strings, bools, integers, floats, time.Duration, anything
+// that implements encoding.TextMarshaler and TextUnmarshaler (time.Time
+// does, as RFC 3339), and pointers to those, which an empty cell leaves
+// nil. Embedded structs are not flattened.
+//
+// Readers and Writers handle one record at a time, so files of any size
+// stream through in constant memory.
+package csvio
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"pacx/options"
+)
+
+type config struct {
+	comma  rune
+	strict bool
+}
+
+// Option configures Readers and Writers.
+type Option = options.Option[config]
+
+// WithComma sets the field delimiter. Defaults to ','.
+func WithComma(r rune) Option {
+	return options.New("WithComma", func(c *config) error {
+		if r == '"' || r == '\r' || r == '\n' || r == 0 {
+			return fmt.Errorf("invalid delimiter %q", r)
+		}
+		c.comma = r
+		return nil
+	})
+}
+
+// WithStrict makes a Reader fail on a header with a column no field maps
+// to, or without a column some field maps to. By default those columns
+// are skipped and those fields left zero.
+func WithStrict() Option {
+	return options.New("WithStrict", func(c *config) error {
+		c.strict = true
+		return nil
+	})
+}
+
+func build(opts []Option) (config, error) {
+	return options.Build(config{comma: ','}, nil, opts...)
+}
+
+// FieldError is a cell that does not convert to its field.
+type FieldError struct {
+	Line   int // of the cell, counting from 1; 0 when writing
+	Column string
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("csvio: line %d, column %q: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// field is a struct field and the column it maps to.
+type field struct {
+	column string
+	index  int
+	typ    reflect.Type
+}
+
+// fieldsOf returns the columns of struct type t in field order.
+func fieldsOf(t reflect.Type) ([]field, error) {
+
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("csvio: %v is not a struct", t)
+	}
+
+	var fields []field
+	seen := make(map[string]bool)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		if !supported(f.Type) {
+			return nil, fmt.Errorf("csvio: field %s has unsupported type %v", f.Name, f.Type)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("csvio: column %q is mapped twice", name)
+		}
+		seen[name] = true
+		fields = append(fields, field{column: name, index: i, typ: f.Type})
+	}
+
+	return fields, nil
+}
+
+var (
+	durationType    = reflect.TypeFor[time.Duration]()
+	marshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	unmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+func supported(t reflect.Type) bool {
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		if t.Kind() == reflect.Pointer {
+			return false
+		}
+	}
+	if t.Implements(marshalerType) && reflect.PointerTo(t).Implements(unmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// decode sets v from the text of a cell.
+func decode(v reflect.Value, s string) error {
+
+	if v.Kind() == reflect.Pointer {
+		if s == "" {
+			v.SetZero()
+			return nil
+		}
+		p := reflect.New(v.Type().Elem())
+		if err := decode(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		v.SetInt(int64(d))
+		return err
+	}
+
+	if v.Kind() == reflect.String {
+		v.SetString(s)
+		return nil
+	}
+
+	// numbers are often padded to line up
+	s = strings.TrimSpace(s)
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	}
+
+	return nil
+}
+
+// encode returns the text of v for a cell.
+func encode(v reflect.Value) (string, error) {
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	}
+
+	return "", fmt.Errorf("unsupported type %v", v.Type())
+}
@@ -0,0 +1,174 @@
+package csvio_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/File-IO/csvio"
+)
+
+type trade struct {
+	Symbol string        `csv:"symbol"`
+	Price  float64       `csv:"price"`
+	Qty    int           `csv:"qty"`
+	At     time.Time     `csv:"time"`
+	Hold   time.Duration `csv:"hold"`
+	Limit  *float64      `csv:"limit"`
+	Filled bool
+	Note   string `csv:"-"`
+	secret string
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	limit := 101.5
+	in := []trade{
+		{Symbol: "ACME", Price: 100.25, Qty: 10, At: at, Hold: 90 * time.Second, Limit: &limit, Filled: true},
+		{Symbol: `Quote "Co", Ltd`, Price: -0.5, Qty: -3, At: at.Add(time.Hour)},
+	}
+
+	var buf bytes.Buffer
+	if err := csvio.WriteAll(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(buf.String(), "\n")
+	if header != "symbol,price,qty,time,hold,limit,Filled" {
+		t.Errorf("Expected the header from the tags but got %s", header)
+	}
+
+	out, err := csvio.ReadAll[trade](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != len(in) {
+		t.Fatalf("Expected %d records but got %d", len(in), len(out))
+	}
+	for i := range in {
+		a, b := in[i], out[i]
+		if a.Symbol != b.Symbol || a.Price != b.Price || a.Qty != b.Qty || !a.At.Equal(b.At) || a.Hold != b.Hold || a.Filled != b.Filled {
+			t.Errorf("Expected %+v but got %+v", a, b)
+		}
+		if (a.Limit == nil) != (b.Limit == nil) || a.Limit != nil && *a.Limit != *b.Limit {
+			t.Errorf("Expected limit %v but got %v", a.Limit, b.Limit)
+		}
+	}
+}
+
+func TestReaderMatchesColumnsByName(t *testing.T) {
+
+	in := "qty;extra;symbol\n 7 ;x;ACME\n"
+
+	out, err := csvio.ReadAll[trade](strings.NewReader(in), csvio.WithComma(';'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || out[0].Symbol != "ACME" || out[0].Qty != 7 || out[0].Limit != nil {
+		t.Errorf("Expected ACME with 7 and no limit but got %+v", out)
+	}
+
+	if _, err := csvio.ReadAll[trade](strings.NewReader(in), csvio.WithComma(';'), csvio.WithStrict()); err == nil {
+		t.Error("Expected the strict reader to reject the extra column but got no error")
+	}
+}
+
+func TestFieldError(t *testing.T) {
+
+	in := "symbol,qty\nACME,1\nBETA,lots\nGAMMA,3\n"
+
+	r, err := csvio.NewReader[trade](strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := r.Read(); err != nil || v.Qty != 1 {
+		t.Fatalf("Expected the first record but got %+v, %v", v, err)
+	}
+
+	_, err = r.Read()
+	var fe *csvio.FieldError
+	if !errors.As(err, &fe) || fe.Line != 3 || fe.Column != "qty" || !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("Expected a syntax error in qty on line 3 but got %v", err)
+	}
+
+	// the reader goes on after a bad cell
+	if v, err := r.Read(); err != nil || v.Symbol != "GAMMA" {
+		t.Errorf("Expected GAMMA after the bad record but got %+v, %v", v, err)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end but got %v", err)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, _ := csvio.NewWriter[trade](pw)
+		for i := range 10_000 {
+			w.Write(trade{Symbol: "S" + strconv.Itoa(i), Qty: i})
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+
+	r, err := csvio.NewReader[trade](pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for v, err := range r.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Qty != n {
+			t.Fatalf("Expected record %d but got %d", n, v.Qty)
+		}
+		n++
+		if n == 5000 {
+			break
+		}
+	}
+	pr.Close()
+	if n != 5000 {
+		t.Errorf("Expected to stop after 5000 records but read %d", n)
+	}
+}
+
+func TestBadTypes(t *testing.T) {
+
+	type nested struct {
+		Inner struct{ A int }
+	}
+	type twice struct {
+		A int `csv:"x"`
+		B int `csv:"x"`
+	}
+
+	if _, err := csvio.NewReader[nested](strings.NewReader("Inner\n")); err == nil {
+		t.Error("Expected an error for a struct field but got none")
+	}
+	if _, err := csvio.NewWriter[twice](io.Discard); err == nil {
+		t.Error("Expected an error for a column mapped twice but got none")
+	}
+	if _, err := csvio.NewWriter[int](io.Discard); err == nil {
+		t.Error("Expected an error for a type that is not a struct but got none")
+	}
+	if _, err := csvio.NewReader[trade](strings.NewReader("")); err == nil {
+		t.Error("Expected an error for input without a header but got none")
+	}
+}
+
+func TestEmptyWriteHasHeader(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := csvio.WriteAll[trade](&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "symbol,") {
+		t.Errorf("Expected just the header but got %q", buf.String())
+	}
+}
@@ -0,0 +1,135 @@
+package csvio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"slices"
+)
+
+// Reader reads records into values of struct type T, matching the columns
+// by the header in the first record.
+type Reader[T any] struct {
+	r *csv.Reader
+
+	// cols[i] is the field of the i-th column, nil to skip it
+	cols []*field
+}
+
+// NewReader reads the header from r and returns a Reader for the records
+// that follow.
+func NewReader[T any](r io.Reader, opts ...Option) (*Reader[T], error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldsOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+
+	cr := csv.NewReader(r)
+	cr.Comma = cfg.comma
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("csvio: no header")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cols := make([]*field, len(header))
+	for i, name := range header {
+		j := slices.IndexFunc(fields, func(f field) bool { return f.column == name })
+		if j < 0 {
+			if cfg.strict {
+				return nil, fmt.Errorf("csvio: column %q has no field", name)
+			}
+			continue
+		}
+		if slices.Contains(cols, &fields[j]) {
+			return nil, fmt.Errorf("csvio: column %q appears twice", name)
+		}
+		cols[i] = &fields[j]
+	}
+	if cfg.strict {
+		for i := range fields {
+			if !slices.Contains(cols, &fields[i]) {
+				return nil, fmt.Errorf("csvio: no column %q", fields[i].column)
+			}
+		}
+	}
+
+	return &Reader[T]{r: cr, cols: cols}, nil
+}
+
+// Read returns the next record, or io.EOF after the last one. A cell that
+// does not convert fails with a *FieldError; the Reader can go on to the
+// next record after that.
+func (r *Reader[T]) Read() (T, error) {
+
+	var v T
+
+	rec, err := r.r.Read()
+	if err != nil {
+		return v, err
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	for i, cell := range rec {
+		f := r.cols[i]
+		if f == nil {
+			continue
+		}
+		if err := decode(rv.Field(f.index), cell); err != nil {
+			line, _ := r.r.FieldPos(i)
+			return v, &FieldError{Line: line, Column: f.column, Err: err}
+		}
+	}
+
+	return v, nil
+}
+
+// All yields the remaining records. Unlike Read, it stops at the first
+// error, which it yields with the zero T.
+func (r *Reader[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ReadAll reads every record of r into a slice.
+func ReadAll[T any](r io.Reader, opts ...Option) ([]T, error) {
+
+	cr, err := NewReader[T](r, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []T
+	for v, err := range cr.All() {
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
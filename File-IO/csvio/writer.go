@@ -0,0 +1,95 @@
+package csvio
+
+import (
+	"encoding/csv"
+	"io"
+	"reflect"
+)
+
+// Writer writes values of struct type T as records, after a header of
+// their columns.
+type Writer[T any] struct {
+	w      *csv.Writer
+	fields []field
+	header bool // written yet
+	rec    []string
+}
+
+// NewWriter returns a Writer to w. The header is written with the first
+// record, or by Flush if there is none.
+func NewWriter[T any](w io.Writer, opts ...Option) (*Writer[T], error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldsOf(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = cfg.comma
+
+	return &Writer[T]{w: cw, fields: fields, rec: make([]string, len(fields))}, nil
+}
+
+// Write writes v as one record. Records are buffered; call Flush when
+// done.
+func (w *Writer[T]) Write(v T) error {
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(v)
+	for i, f := range w.fields {
+		s, err := encode(rv.Field(f.index))
+		if err != nil {
+			return &FieldError{Column: f.column, Err: err}
+		}
+		w.rec[i] = s
+	}
+
+	return w.w.Write(w.rec)
+}
+
+// Flush writes any buffered records to the underlying io.Writer.
+func (w *Writer[T]) Flush() error {
+
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+
+	return w.w.Error()
+}
+
+func (w *Writer[T]) writeHeader() error {
+
+	if w.header {
+		return nil
+	}
+	w.header = true
+	for i, f := range w.fields {
+		w.rec[i] = f.column
+	}
+
+	return w.w.Write(w.rec)
+}
+
+// WriteAll writes the header and every value of vs to w.
+func WriteAll[T any](w io.Writer, vs []T, opts ...Option) error {
+
+	cw, err := NewWriter[T](w, opts...)
+	if err != nil {
+		return err
+	}
+	for _, v := range vs {
+		if err := cw.Write(v); err != nil {
+			return err
+		}
+	}
+
+	return cw.Flush()
+}
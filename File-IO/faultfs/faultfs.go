@@ -0,0 +1,293 @@
+// Package faultfs wraps a file system and makes it fail on purpose. Rules
+// match paths with path.Match patterns and pick the operations they apply
+// to; a matching rule fires with its probability and either returns an
+// error such as EIO or ENOSPC, sleeps to simulate a slow disk, or both.
+//
+// The dice come from a seeded generator, so a test that does the same
+// operations in the same order sees the same faults every run.
+package faultfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"pacx/options"
+)
+
+// The errors disks usually fail with, for use in rules.
+var (
+	EIO    error = syscall.EIO
+	ENOSPC error = syscall.ENOSPC
+)
+
+// Op is a set of file operations.
+type Op uint8
+
+const (
+	OpOpen Op = 1 << iota
+	OpRead
+	OpWrite
+	OpSync
+	OpClose
+
+	OpAll = OpOpen | OpRead | OpWrite | OpSync | OpClose
+)
+
+var opNames = [...]string{"open", "read", "write", "sync", "close"}
+
+func (o Op) String() string {
+
+	for i, name := range opNames {
+		if o == 1<<i {
+			return name
+		}
+	}
+
+	return "op"
+}
+
+// Rule describes one fault. Pattern is matched against the slash separated
+// name the file was opened with; an empty pattern matches every file.
+type Rule struct {
+	Pattern string
+	Ops     Op
+	Prob    float64       // chance in [0, 1] that a matching call fails
+	Err     error         // returned when the rule fires, nil to only delay
+	Delay   time.Duration // added before the call when the rule fires
+}
+
+// OpenFileFS is a file system that can also open files for writing, the
+// way os.OpenFile does.
+type OpenFileFS interface {
+	fs.FS
+	OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error)
+}
+
+// Dir is an OpenFileFS rooted at a directory of the host file system.
+type Dir string
+
+func (d Dir) Open(name string) (fs.File, error) {
+	return d.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (d Dir) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	return os.OpenFile(filepath.Join(string(d), filepath.FromSlash(name)), flag, perm)
+}
+
+type config struct {
+	rules []Rule
+	seed  uint64
+}
+
+// Option configures New.
+type Option = options.Option[config]
+
+// WithRule adds a fault. Rules are checked in the order they were added
+// and the first one that fires wins.
+func WithRule(r Rule) Option {
+	return options.New("WithRule", func(c *config) error {
+		if r.Ops == 0 {
+			return errors.New("rule has no operations")
+		}
+		if r.Prob < 0 || r.Prob > 1 {
+			return errors.New("probability must be in [0, 1]")
+		}
+		if r.Err == nil && r.Delay <= 0 {
+			return errors.New("rule needs an error or a delay")
+		}
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return err
+		}
+		c.rules = append(c.rules, r)
+		return nil
+	})
+}
+
+// WithSeed seeds the generator that decides whether a rule fires.
+// Defaults to 1.
+func WithSeed(seed uint64) Option {
+	return options.New("WithSeed", func(c *config) error {
+		c.seed = seed
+		return nil
+	})
+}
+
+// FS is a file system that injects faults into the one it wraps. It is
+// safe for concurrent use, though only a single goroutine gets
+// reproducible faults.
+type FS struct {
+	fsys  fs.FS
+	rules []Rule
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	injected map[Op]int64
+}
+
+// New wraps fsys. If fsys is an OpenFileFS, so is the result.
+func New(fsys fs.FS, opts ...Option) (*FS, error) {
+
+	cfg, err := options.Build(config{seed: 1}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FS{
+		fsys:     fsys,
+		rules:    cfg.rules,
+		rng:      rand.New(rand.NewPCG(cfg.seed, cfg.seed)),
+		injected: make(map[Op]int64),
+	}, nil
+}
+
+// Injected returns how many errors have been injected into op.
+func (f *FS) Injected(op Op) int64 {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.injected[op]
+}
+
+// fault rolls the dice for op on name, sleeps for any delay and returns
+// the error to inject, wrapped in a *fs.PathError.
+func (f *FS) fault(op Op, name string) error {
+
+	var fired *Rule
+
+	f.mu.Lock()
+	for i := range f.rules {
+		r := &f.rules[i]
+		if r.Ops&op == 0 {
+			continue
+		}
+		if r.Pattern != "" {
+			if ok, _ := path.Match(r.Pattern, name); !ok {
+				continue
+			}
+		}
+		// every matching rule draws, so adding a rule for one path does
+		// not shift the faults seen on another
+		if f.rng.Float64() < r.Prob && fired == nil {
+			fired = r
+		}
+	}
+	if fired != nil && fired.Err != nil {
+		f.injected[op]++
+	}
+	f.mu.Unlock()
+
+	if fired == nil {
+		return nil
+	}
+	if fired.Delay > 0 {
+		time.Sleep(fired.Delay)
+	}
+	if fired.Err == nil {
+		return nil
+	}
+
+	return &fs.PathError{Op: op.String(), Path: name, Err: fired.Err}
+}
+
+func (f *FS) Open(name string) (fs.File, error) {
+
+	if err := f.fault(OpOpen, name); err != nil {
+		return nil, err
+	}
+
+	file, err := f.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{File: file, fs: f, name: name}, nil
+}
+
+// OpenFile opens name with flag and perm. The wrapped file system must be
+// an OpenFileFS.
+func (f *FS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+
+	of, ok := f.fsys.(OpenFileFS)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errors.ErrUnsupported}
+	}
+	if err := f.fault(OpOpen, name); err != nil {
+		return nil, err
+	}
+
+	file, err := of.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{File: file, fs: f, name: name}, nil
+}
+
+// File is a file opened through FS. Write and Sync work when the
+// underlying file has them.
+type File struct {
+	fs.File
+	fs   *FS
+	name string
+}
+
+func (f *File) Read(p []byte) (int, error) {
+
+	if err := f.fs.fault(OpRead, f.name); err != nil {
+		return 0, err
+	}
+
+	return f.File.Read(p)
+}
+
+func (f *File) Write(p []byte) (int, error) {
+
+	w, ok := f.File.(io.Writer)
+	if !ok {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: errors.ErrUnsupported}
+	}
+	if err := f.fs.fault(OpWrite, f.name); err != nil {
+		return 0, err
+	}
+
+	return w.Write(p)
+}
+
+func (f *File) Sync() error {
+
+	s, ok := f.File.(interface{ Sync() error })
+	if !ok {
+		return nil
+	}
+	if err := f.fs.fault(OpSync, f.name); err != nil {
+		return err
+	}
+
+	return s.Sync()
+}
+
+// Close always closes the underlying file, so an injected close error
+// does not leak a descriptor.
+func (f *File) Close() error {
+
+	err := f.File.Close()
+	if ferr := f.fs.fault(OpClose, f.name); ferr != nil {
+		return ferr
+	}
+
+	return err
+}
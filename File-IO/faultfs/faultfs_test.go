@@ -0,0 +1,134 @@
+package faultfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"pacx/File-IO/faultfs"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"wal/000001.log": {Data: []byte("record one\n")},
+		"data/kv.db":     {Data: []byte("key=value\n")},
+	}
+}
+
+func TestReadErrorMatchesPattern(t *testing.T) {
+
+	fsys, err := faultfs.New(testFS(), faultfs.WithRule(faultfs.Rule{
+		Pattern: "wal/*.log", Ops: faultfs.OpRead, Prob: 1, Err: faultfs.EIO,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.ReadFile(fsys, "wal/000001.log"); !errors.Is(err, syscall.EIO) {
+		t.Errorf("Expected EIO reading the log but got %v", err)
+	}
+	if data, err := fs.ReadFile(fsys, "data/kv.db"); err != nil || string(data) != "key=value\n" {
+		t.Errorf("Expected the unmatched file to read cleanly but got %q, %v", data, err)
+	}
+	if n := fsys.Injected(faultfs.OpRead); n != 1 {
+		t.Errorf("Expected 1 injected read error but got %d", n)
+	}
+}
+
+func TestSeedIsDeterministic(t *testing.T) {
+
+	pattern := func() []bool {
+		fsys, err := faultfs.New(testFS(), faultfs.WithSeed(42), faultfs.WithRule(faultfs.Rule{
+			Ops: faultfs.OpOpen, Prob: 0.5, Err: faultfs.EIO,
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []bool
+		for i := 0; i < 32; i++ {
+			f, err := fsys.Open("data/kv.db")
+			if err == nil {
+				f.Close()
+			}
+			out = append(out, err != nil)
+		}
+		return out
+	}
+
+	a, b := pattern(), pattern()
+	failed := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Expected the same faults with the same seed but call %d differed", i)
+		}
+		if a[i] {
+			failed++
+		}
+	}
+	if failed == 0 || failed == len(a) {
+		t.Errorf("Expected some but not all opens to fail but got %d of %d", failed, len(a))
+	}
+}
+
+func TestSlowRead(t *testing.T) {
+
+	fsys, err := faultfs.New(testFS(), faultfs.WithRule(faultfs.Rule{
+		Ops: faultfs.OpRead, Prob: 1, Delay: 20 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.Open("data/kv.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	start := time.Now()
+	if _, err := f.Read(make([]byte, 4)); err != nil {
+		t.Fatalf("Expected a delayed read to succeed but got %v", err)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("Expected the read to take at least 20ms but took %v", d)
+	}
+}
+
+func TestWriteNoSpace(t *testing.T) {
+
+	dir := t.TempDir()
+	fsys, err := faultfs.New(faultfs.Dir(dir), faultfs.WithRule(faultfs.Rule{
+		Pattern: "full.txt", Ops: faultfs.OpWrite, Prob: 1, Err: faultfs.ENOSPC,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fsys.OpenFile("full.txt", os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.(io.Writer).Write([]byte("data")); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("Expected ENOSPC but got %v", err)
+	}
+	if data, _ := os.ReadFile(dir + "/full.txt"); len(data) != 0 {
+		t.Errorf("Expected nothing written but found %q", data)
+	}
+}
+
+func TestRejectsBadRule(t *testing.T) {
+
+	if _, err := faultfs.New(testFS(), faultfs.WithRule(faultfs.Rule{Ops: faultfs.OpRead, Prob: 2, Err: faultfs.EIO})); err == nil {
+		t.Error("Expected an error for a probability above 1")
+	}
+	if _, err := faultfs.New(testFS(), faultfs.WithRule(faultfs.Rule{Ops: faultfs.OpRead, Prob: 1})); err == nil {
+		t.Error("Expected an error for a rule with neither error nor delay")
+	}
+}
@@ -0,0 +1,167 @@
+// Package filecopy copies large files in chunks, reporting progress after
+// each one and checking for cancellation between them. The copy goes
+// through atomicfile, so a copy that fails or is cancelled before it is
+// complete leaves the destination as it was.
+package filecopy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"pacx/File-IO/atomicfile"
+	"pacx/options"
+)
+
+// ErrChecksum is returned when the copy does not read back the same as the
+// source.
+var ErrChecksum = errors.New("filecopy: checksum mismatch")
+
+// Progress is how far a copy is.
+type Progress struct {
+	Copied int64 // bytes written so far
+	Total  int64 // size of the source when the copy started
+}
+
+type config struct {
+	chunk    int
+	progress func(Progress)
+	newHash  func() hash.Hash
+}
+
+// Option configures CopyFile.
+type Option = options.Option[config]
+
+// WithChunkSize sets how much is read and written at a time. Defaults to
+// 1 MiB.
+func WithChunkSize(n int) Option {
+	return options.New("WithChunkSize", func(c *config) error {
+		if n <= 0 {
+			return errors.New("chunk size must be positive")
+		}
+		c.chunk = n
+		return nil
+	})
+}
+
+// WithProgress calls fn after every chunk, on the goroutine of CopyFile.
+func WithProgress(fn func(Progress)) Option {
+	return options.New("WithProgress", func(c *config) error {
+		c.progress = fn
+		return nil
+	})
+}
+
+// WithVerify hashes the source while copying and the destination once it
+// is written, and fails with ErrChecksum if they differ. The destination
+// is in place by then, so it is up to the caller what to do with it.
+// newHash is, for example, sha256.New.
+func WithVerify(newHash func() hash.Hash) Option {
+	return options.New("WithVerify", func(c *config) error {
+		c.newHash = newHash
+		return nil
+	})
+}
+
+// CopyFile copies src to dst, which gets the permissions of src, and
+// returns how many bytes it copied. It stops with ctx.Err() between chunks
+// once ctx is done.
+func CopyFile(ctx context.Context, dst, src string, opts ...Option) (int64, error) {
+
+	cfg, err := options.Build(config{chunk: 1 << 20, progress: func(Progress) {}}, nil, opts...)
+	if err != nil {
+		return 0, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	fi, err := in.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !fi.Mode().IsRegular() {
+		return 0, fmt.Errorf("filecopy: %s is not a regular file", src)
+	}
+
+	out, err := atomicfile.Create(dst, fi.Mode().Perm())
+	if err != nil {
+		return 0, err
+	}
+	defer out.Abort()
+
+	var sum hash.Hash
+	var w io.Writer = out
+	if cfg.newHash != nil {
+		sum = cfg.newHash()
+		w = io.MultiWriter(out, sum)
+	}
+
+	p := Progress{Total: fi.Size()}
+	buf := make([]byte, cfg.chunk)
+	for {
+		if err := ctx.Err(); err != nil {
+			return p.Copied, err
+		}
+		n, rerr := io.ReadFull(in, buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return p.Copied, err
+			}
+			p.Copied += int64(n)
+			cfg.progress(p)
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return p.Copied, rerr
+		}
+	}
+
+	if err := out.Close(); err != nil {
+		return p.Copied, err
+	}
+	if sum == nil {
+		return p.Copied, nil
+	}
+
+	return p.Copied, verify(ctx, dst, sum.Sum(nil), cfg)
+}
+
+// verify reads dst back and compares its hash with want.
+func verify(ctx context.Context, dst string, want []byte, cfg config) error {
+
+	f, err := os.Open(dst)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := cfg.newHash()
+	buf := make([]byte, cfg.chunk)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n, err := f.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		return fmt.Errorf("%w: %s has %x, want %x", ErrChecksum, dst, got, want)
+	}
+
+	return nil
+}
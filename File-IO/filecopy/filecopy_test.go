@@ -0,0 +1,140 @@
+package filecopy_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"pacx/File-IO/filecopy"
+)
+
+// source writes size random bytes to a new file.
+func source(t *testing.T, size int) (string, []byte) {
+
+	t.Helper()
+
+	data := make([]byte, size)
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range data {
+		data[i] = byte(r.Uint32())
+	}
+	path := filepath.Join(t.TempDir(), "src.bin")
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, data
+}
+
+func TestCopyFile(t *testing.T) {
+
+	for _, size := range []int{0, 1, 4096, 10_000} {
+		src, data := source(t, size)
+		dst := filepath.Join(t.TempDir(), "dst.bin")
+
+		var seen []filecopy.Progress
+		n, err := filecopy.CopyFile(context.Background(), dst, src,
+			filecopy.WithChunkSize(4096),
+			filecopy.WithProgress(func(p filecopy.Progress) { seen = append(seen, p) }),
+			filecopy.WithVerify(sha256.New))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != int64(size) {
+			t.Errorf("Expected %d bytes copied but got %d", size, n)
+		}
+		if got, _ := os.ReadFile(dst); !bytes.Equal(got, data) {
+			t.Errorf("Expected the copy of %d bytes to match the source but it did not", size)
+		}
+
+		if chunks := (size + 4095) / 4096; len(seen) != chunks {
+			t.Errorf("Expected %d progress reports for %d bytes but got %d", chunks, size, len(seen))
+		}
+		if len(seen) > 0 {
+			if last := seen[len(seen)-1]; last.Copied != int64(size) || last.Total != int64(size) {
+				t.Errorf("Expected the last progress to be %d of %d but got %+v", size, size, last)
+			}
+		}
+	}
+}
+
+func TestCopyFileKeepsPermissions(t *testing.T) {
+
+	src, _ := source(t, 10)
+	dst := filepath.Join(t.TempDir(), "dst.bin")
+	if _, err := filecopy.CopyFile(context.Background(), dst, src); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := os.Stat(src)
+	if got, err := os.Stat(dst); err != nil || got.Mode() != want.Mode() {
+		t.Errorf("Expected mode %v but got %v, %v", want.Mode(), got.Mode(), err)
+	}
+}
+
+func TestCopyFileCancel(t *testing.T) {
+
+	src, _ := source(t, 10_000)
+	dst := filepath.Join(t.TempDir(), "dst.bin")
+	if err := os.WriteFile(dst, []byte("previous"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	n, err := filecopy.CopyFile(ctx, dst, src,
+		filecopy.WithChunkSize(1000),
+		filecopy.WithProgress(func(p filecopy.Progress) {
+			if p.Copied >= 3000 {
+				cancel()
+			}
+		}))
+	if !errors.Is(err, context.Canceled) || n != 3000 {
+		t.Errorf("Expected to stop with context.Canceled after 3000 bytes but got %d, %v", n, err)
+	}
+	if got, _ := os.ReadFile(dst); string(got) != "previous" {
+		t.Errorf("Expected the destination to be untouched but got %d bytes", len(got))
+	}
+	if des, _ := os.ReadDir(filepath.Dir(dst)); len(des) != 1 {
+		t.Errorf("Expected no temporary file to be left but got %d entries", len(des))
+	}
+}
+
+func TestCopyFileChecksumMismatch(t *testing.T) {
+
+	// a different hash each call, as if the copy had been corrupted
+	calls := 0
+	newHash := func() hash.Hash {
+		calls++
+		if calls == 1 {
+			return crc32.NewIEEE()
+		}
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	}
+
+	src, _ := source(t, 100)
+	dst := filepath.Join(t.TempDir(), "dst.bin")
+	if _, err := filecopy.CopyFile(context.Background(), dst, src, filecopy.WithVerify(newHash)); !errors.Is(err, filecopy.ErrChecksum) {
+		t.Errorf("Expected ErrChecksum but got %v", err)
+	}
+}
+
+func TestCopyFileErrors(t *testing.T) {
+
+	dir := t.TempDir()
+	if _, err := filecopy.CopyFile(context.Background(), filepath.Join(dir, "dst"), filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for a missing source but got %v", err)
+	}
+	if _, err := filecopy.CopyFile(context.Background(), filepath.Join(dir, "dst"), dir); err == nil {
+		t.Error("Expected an error copying a directory but got none")
+	}
+	if _, err := filecopy.CopyFile(context.Background(), filepath.Join(dir, "dst"), dir, filecopy.WithChunkSize(0)); err == nil {
+		t.Error("Expected an error for a zero chunk size but got none")
+	}
+}
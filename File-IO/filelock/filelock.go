@@ -0,0 +1,75 @@
+// Package filelock takes advisory locks on files, so processes that agree
+// to lock before they touch a file do not trip over each other. It uses
+// flock on Unix and LockFileEx on Windows. The locks are advisory: they
+// keep out other lockers, not readers and writers that do not lock.
+//
+// A lock belongs to the open file it was taken through, not to the
+// process, so two locks on one path conflict within a process too.
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked is returned by TryLock and TryRLock when the lock is held.
+var ErrLocked = errors.New("filelock: locked by another holder")
+
+// File is a held lock. The file is created if it does not exist and is
+// left in place after Unlock, since removing it would race with the next
+// locker.
+type File struct {
+	f *os.File
+}
+
+// Lock takes an exclusive lock on path, waiting until it is free.
+func Lock(path string) (*File, error) {
+	return lock(path, true, true)
+}
+
+// TryLock is Lock that fails with ErrLocked instead of waiting.
+func TryLock(path string) (*File, error) {
+	return lock(path, true, false)
+}
+
+// RLock takes a shared lock on path, which other shared locks can hold at
+// the same time but not an exclusive one, waiting until it is free.
+func RLock(path string) (*File, error) {
+	return lock(path, false, true)
+}
+
+// TryRLock is RLock that fails with ErrLocked instead of waiting.
+func TryRLock(path string) (*File, error) {
+	return lock(path, false, false)
+}
+
+func lock(path string, exclusive, wait bool) (*File, error) {
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, exclusive, wait); err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "lock", Path: path, Err: err}
+	}
+
+	return &File{f: f}, nil
+}
+
+// Name returns the path of the locked file.
+func (l *File) Name() string {
+	return l.f.Name()
+}
+
+// Unlock releases the lock. Closing the file would too, and the system
+// releases it when the process exits, however it exits.
+func (l *File) Unlock() error {
+
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
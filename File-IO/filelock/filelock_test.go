@@ -0,0 +1,135 @@
+package filelock_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pacx/File-IO/filelock"
+)
+
+func TestTryLock(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "db.lock")
+
+	l, err := filelock.TryLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := filelock.TryLock(path); !errors.Is(err, filelock.ErrLocked) {
+		t.Errorf("Expected ErrLocked while the lock is held but got %v", err)
+	}
+	if _, err := filelock.TryRLock(path); !errors.Is(err, filelock.ErrLocked) {
+		t.Errorf("Expected a shared lock to wait for the exclusive one but got %v", err)
+	}
+	if err := l.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err = filelock.TryLock(path)
+	if err != nil {
+		t.Fatalf("Expected the lock to be free after Unlock but got %v", err)
+	}
+	l.Unlock()
+}
+
+func TestSharedLocks(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "db.lock")
+
+	a, err := filelock.RLock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := filelock.TryRLock(path)
+	if err != nil {
+		t.Fatalf("Expected two shared locks to be held together but got %v", err)
+	}
+	if _, err := filelock.TryLock(path); !errors.Is(err, filelock.ErrLocked) {
+		t.Errorf("Expected ErrLocked for an exclusive lock but got %v", err)
+	}
+	a.Unlock()
+	b.Unlock()
+}
+
+func TestLockWaits(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "db.lock")
+
+	held, err := filelock.Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan *filelock.File)
+	go func() {
+		l, err := filelock.Lock(path)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- l
+	}()
+
+	select {
+	case <-got:
+		t.Fatal("Expected Lock to wait while the lock is held but it returned")
+	case <-time.After(50 * time.Millisecond):
+	}
+	held.Unlock()
+
+	select {
+	case l := <-got:
+		if l != nil {
+			l.Unlock()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Lock to return once the lock was released but it did not")
+	}
+}
+
+// TestBetweenProcesses runs this test binary again to try the lock from
+// another process.
+func TestBetweenProcesses(t *testing.T) {
+
+	if path := os.Getenv("FILELOCK_TRY"); path != "" {
+		l, err := filelock.TryLock(path)
+		if errors.Is(err, filelock.ErrLocked) {
+			os.Exit(3)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		l.Unlock()
+		os.Exit(0)
+	}
+
+	path := filepath.Join(t.TempDir(), "db.lock")
+	try := func() int {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestBetweenProcesses$")
+		cmd.Env = append(os.Environ(), "FILELOCK_TRY="+path)
+		err := cmd.Run()
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			return exit.ExitCode()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return 0
+	}
+
+	l, err := filelock.Lock(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := try(); code != 3 {
+		t.Errorf("Expected the other process to find the file locked but it exited with %d", code)
+	}
+	l.Unlock()
+	if code := try(); code != 0 {
+		t.Errorf("Expected the other process to get the lock but it exited with %d", code)
+	}
+}
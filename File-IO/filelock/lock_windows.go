@@ -0,0 +1,59 @@
+//go:build windows
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32       = syscall.NewLazyDLL("kernel32.dll")
+	procLockFileEx = kernel32.NewProc("LockFileEx")
+	procUnlockFile = kernel32.NewProc("UnlockFileEx")
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+
+	errorLockViolation syscall.Errno = 33
+
+	// the low and high halves of the length to lock
+	allBytes = 0xffffffff
+)
+
+// lockFile locks the whole file, as far as any file can reach.
+func lockFile(f *os.File, exclusive, wait bool) error {
+
+	var flags uint32
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+	if !wait {
+		flags |= lockfileFailImmediately
+	}
+
+	var ol syscall.Overlapped
+	r, _, err := procLockFileEx.Call(f.Fd(), uintptr(flags), 0, allBytes, allBytes, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return ErrLocked
+	}
+
+	return err
+}
+
+func unlockFile(f *os.File) error {
+
+	var ol syscall.Overlapped
+	r, _, err := procUnlockFile.Call(f.Fd(), 0, allBytes, allBytes, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,153 @@
+// Package ioctx makes io.Reader and io.Writer honour context cancellation.
+//
+// Values that support deadlines (net.Conn, os.File on pipes and sockets)
+// are interrupted by moving the deadline into the past when the context is
+// done. Anything else is read or written on a watchdog goroutine so the
+// caller can return as soon as the context ends; the underlying call then
+// finishes in the background.
+package ioctx
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// aLongTimeAgo is a deadline that has always passed.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Reader returns a reader whose Read fails with ctx.Err() once ctx is done,
+// including a Read that is already blocked.
+func Reader(ctx context.Context, r io.Reader) io.Reader {
+	return &reader{ctx: ctx, r: r}
+}
+
+type result struct {
+	data []byte
+	n    int
+	err  error
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	pending  chan result // a watchdog read still in flight
+	leftover []byte      // data a watchdog read returned beyond what fit
+	err      error       // error that came with leftover
+}
+
+func (cr *reader) Read(p []byte) (int, error) {
+
+	if len(cr.leftover) > 0 {
+		n := copy(p, cr.leftover)
+		cr.leftover = cr.leftover[n:]
+		if len(cr.leftover) == 0 {
+			return n, cr.err
+		}
+		return n, nil
+	}
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if d, ok := cr.r.(readDeadliner); ok && cr.pending == nil {
+		stop := context.AfterFunc(cr.ctx, func() { d.SetReadDeadline(aLongTimeAgo) })
+		defer stop()
+
+		n, err := cr.r.Read(p)
+		if err != nil && cr.ctx.Err() != nil {
+			err = cr.ctx.Err()
+		}
+		return n, err
+	}
+
+	// A read that was abandoned on an earlier cancel may still be running;
+	// wait for it instead of starting a second one on the same reader.
+	if cr.pending == nil {
+		buf := make([]byte, len(p))
+		ch := make(chan result, 1)
+		go func() {
+			n, err := cr.r.Read(buf)
+			ch <- result{data: buf[:n], err: err}
+		}()
+		cr.pending = ch
+	}
+
+	select {
+	case res := <-cr.pending:
+		cr.pending = nil
+		n := copy(p, res.data)
+		if n < len(res.data) {
+			cr.leftover, cr.err = res.data[n:], res.err
+			return n, nil
+		}
+		return n, res.err
+	case <-cr.ctx.Done():
+		return 0, cr.ctx.Err()
+	}
+}
+
+// Writer returns a writer whose Write fails with ctx.Err() once ctx is done.
+// When a watchdog write is abandoned the data may still reach w later, so
+// the number of bytes written is reported as 0.
+func Writer(ctx context.Context, w io.Writer) io.Writer {
+	return &writer{ctx: ctx, w: w}
+}
+
+type writer struct {
+	ctx     context.Context
+	w       io.Writer
+	pending chan result
+}
+
+func (cw *writer) Write(p []byte) (int, error) {
+
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	if d, ok := cw.w.(writeDeadliner); ok && cw.pending == nil {
+		stop := context.AfterFunc(cw.ctx, func() { d.SetWriteDeadline(aLongTimeAgo) })
+		defer stop()
+
+		n, err := cw.w.Write(p)
+		if err != nil && cw.ctx.Err() != nil {
+			err = cw.ctx.Err()
+		}
+		return n, err
+	}
+
+	// Writes must stay in order, so an abandoned one has to finish first.
+	if cw.pending != nil {
+		select {
+		case <-cw.pending:
+			cw.pending = nil
+		case <-cw.ctx.Done():
+			return 0, cw.ctx.Err()
+		}
+	}
+
+	// the caller may reuse p as soon as we return
+	buf := append([]byte(nil), p...)
+	ch := make(chan result, 1)
+	go func() {
+		n, err := cw.w.Write(buf)
+		ch <- result{n: n, err: err}
+	}()
+
+	select {
+	case res := <-ch:
+		return res.n, res.err
+	case <-cw.ctx.Done():
+		cw.pending = ch
+		return 0, cw.ctx.Err()
+	}
+}
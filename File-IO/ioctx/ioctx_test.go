@@ -0,0 +1,120 @@
+package ioctx_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/File-IO/ioctx"
+	"pacx/concurrency/ratelimit"
+)
+
+func TestReaderPassesThrough(t *testing.T) {
+
+	r := ioctx.Reader(context.Background(), strings.NewReader("THIS IS FINAL COUTDOWN."))
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "THIS IS FINAL COUTDOWN." {
+		t.Errorf("Expected the full text but got %q, %v", got, err)
+	}
+}
+
+// io.Pipe has no deadlines, so this goes through the watchdog path.
+func TestReaderCancelWatchdog(t *testing.T) {
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := ioctx.Reader(ctx, pr).Read(make([]byte, 8))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+}
+
+// net.Pipe supports deadlines, so the blocked Read itself is interrupted.
+func TestReaderCancelDeadline(t *testing.T) {
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := ioctx.Reader(ctx, c1).Read(make([]byte, 8))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+}
+
+func TestReaderKeepsAbandonedData(t *testing.T) {
+
+	pr, pw := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := ioctx.Reader(ctx, pr)
+
+	// small buffer after a large one: the rest must come back on later reads
+	go pw.Write([]byte("hello"))
+	buf := make([]byte, 2)
+	n, _ := r.Read(buf)
+	rest, _ := io.ReadAll(io.LimitReader(r, 3))
+	cancel()
+
+	if got := string(buf[:n]) + string(rest); got != "hello" {
+		t.Errorf("Expected hello but got %q", got)
+	}
+}
+
+func TestWriterCancel(t *testing.T) {
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// nobody reads the pipe, so the write blocks
+	n, err := ioctx.Writer(ctx, pw).Write([]byte("stuck"))
+	if n != 0 || err != context.DeadlineExceeded {
+		t.Errorf("Expected 0, DeadlineExceeded but got %d, %v", n, err)
+	}
+}
+
+func TestLimitWriter(t *testing.T) {
+
+	var buf bytes.Buffer
+	lim := ratelimit.New(10000, 100) // 10KB/s, 100 byte bursts
+	w := ioctx.LimitWriter(context.Background(), &buf, lim)
+
+	start := time.Now()
+	n, err := w.Write(make([]byte, 600))
+	took := time.Since(start)
+
+	if n != 600 || err != nil {
+		t.Fatalf("Expected 600 bytes written but got %d, %v", n, err)
+	}
+	// the first 100 bytes are free, the other 500 take about 50ms
+	if took < 40*time.Millisecond {
+		t.Errorf("Expected the write to be throttled but it took %v", took)
+	}
+}
+
+func TestLimitReaderCancel(t *testing.T) {
+
+	lim := ratelimit.New(10, 10) // 10 bytes/s
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	r := ioctx.LimitReader(ctx, strings.NewReader(strings.Repeat("x", 100)), lim)
+	_, err := io.ReadAll(r)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package ioctx
+
+import (
+	"context"
+	"io"
+
+	"pacx/concurrency/ratelimit"
+)
+
+// LimitReader is Reader throttled to the limiter's rate in bytes per
+// second. Each Read asks for at most one burst worth of bytes.
+func LimitReader(ctx context.Context, r io.Reader, lim *ratelimit.Limiter) io.Reader {
+	return &limitReader{ctx: ctx, r: Reader(ctx, r), lim: lim}
+}
+
+type limitReader struct {
+	ctx context.Context
+	r   io.Reader
+	lim *ratelimit.Limiter
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+
+	if burst := lr.lim.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := lr.r.Read(p)
+	if n > 0 {
+		// pay for what was actually read
+		if werr := lr.lim.WaitN(lr.ctx, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+
+	return n, err
+}
+
+// LimitWriter is Writer throttled to the limiter's rate in bytes per
+// second. Large writes are split into burst sized chunks.
+func LimitWriter(ctx context.Context, w io.Writer, lim *ratelimit.Limiter) io.Writer {
+	return &limitWriter{ctx: ctx, w: Writer(ctx, w), lim: lim}
+}
+
+type limitWriter struct {
+	ctx context.Context
+	w   io.Writer
+	lim *ratelimit.Limiter
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+
+	written := 0
+	burst := lw.lim.Burst()
+
+	for len(p) > 0 {
+		chunk := p[:min(len(p), burst)]
+		if err := lw.lim.WaitN(lw.ctx, len(chunk)); err != nil {
+			return written, err
+		}
+
+		n, err := lw.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+
+	return written, nil
+}
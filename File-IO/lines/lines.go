@@ -0,0 +1,127 @@
+// Package lines reads text line by line as range-over-func iterators, in
+// place of the usual bufio.Scanner loop:
+//
+//	for line, err := range lines.ReadLines("access.log") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Lines are yielded without their "\n" or "\r\n". An error ends the
+// iteration: it is yielded once, with an empty line.
+package lines
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+
+	"pacx/options"
+)
+
+type config struct {
+	maxLine int
+	buffer  int
+}
+
+// Option configures ReadLines and Lines.
+type Option = options.Option[config]
+
+// WithMaxLineLength sets the longest line that can be read; a longer one
+// ends the iteration with an error wrapping bufio.ErrTooLong. Defaults to
+// bufio.MaxScanTokenSize, 64 KiB.
+func WithMaxLineLength(n int) Option {
+	return options.New("WithMaxLineLength", func(c *config) error {
+		if n <= 0 {
+			return errors.New("max line length must be positive")
+		}
+		c.maxLine = n
+		return nil
+	})
+}
+
+// WithBufferSize sets the size the read buffer starts at. It grows as
+// needed for long lines, up to the max line length. Defaults to 4 KiB.
+func WithBufferSize(n int) Option {
+	return options.New("WithBufferSize", func(c *config) error {
+		if n <= 0 {
+			return errors.New("buffer size must be positive")
+		}
+		c.buffer = n
+		return nil
+	})
+}
+
+func build(opts []Option) (config, error) {
+	return options.Build(config{maxLine: bufio.MaxScanTokenSize, buffer: 4096}, nil, opts...)
+}
+
+// ReadLines yields the lines of the file at path. The file is opened when
+// the iteration starts and closed when it ends, so the sequence can be
+// ranged over more than once.
+func ReadLines(path string, opts ...Option) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+
+		cfg, err := build(opts)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			yield("", err)
+			return
+		}
+		defer f.Close()
+
+		scan(f, path, cfg, yield)
+	}
+}
+
+// Lines yields the lines read from r. Ranging over it again continues
+// where the last iteration stopped reading, which may be past the line
+// it stopped at.
+func Lines(r io.Reader, opts ...Option) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+
+		cfg, err := build(opts)
+		if err != nil {
+			yield("", err)
+			return
+		}
+
+		scan(r, "", cfg, yield)
+	}
+}
+
+func scan(r io.Reader, name string, cfg config, yield func(string, error) bool) {
+
+	sc := bufio.NewScanner(r)
+	// room for the line ending, so the limit is on the line alone
+	sc.Buffer(make([]byte, min(cfg.buffer, cfg.maxLine+2)), cfg.maxLine+2)
+	sc.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, line, err := bufio.ScanLines(data, atEOF)
+		if len(line) > cfg.maxLine {
+			return 0, nil, bufio.ErrTooLong
+		}
+		return advance, line, err
+	})
+
+	n := 0
+	for sc.Scan() {
+		n++
+		if !yield(sc.Text(), nil) {
+			return
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if name != "" {
+			name += ":"
+		}
+		yield("", fmt.Errorf("lines: %sline %d: %w", name, n+1, err))
+	}
+}
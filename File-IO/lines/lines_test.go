@@ -0,0 +1,114 @@
+package lines_test
+
+import (
+	"bufio"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"pacx/File-IO/lines"
+)
+
+// collect ranges over seq and returns the lines and the error it ended
+// with.
+func collect(seq func(func(string, error) bool)) ([]string, error) {
+
+	var out []string
+	for line, err := range seq {
+		if err != nil {
+			return out, err
+		}
+		out = append(out, line)
+	}
+
+	return out, nil
+}
+
+func TestLines(t *testing.T) {
+
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"one", []string{"one"}},
+		{"one\n", []string{"one"}},
+		{"one\ntwo\n", []string{"one", "two"}},
+		{"one\r\ntwo\r\n", []string{"one", "two"}},
+		{"\n\nthree", []string{"", "", "three"}},
+	} {
+		got, err := collect(lines.Lines(strings.NewReader(tc.in)))
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("Expected %q to be %q but got %q, %v", tc.in, tc.want, got, err)
+		}
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+
+	in := "12345\n123456\n1234\n"
+
+	got, err := collect(lines.Lines(strings.NewReader(in), lines.WithMaxLineLength(6), lines.WithBufferSize(1)))
+	if err != nil || len(got) != 3 {
+		t.Errorf("Expected lines up to 6 bytes to be read but got %q, %v", got, err)
+	}
+
+	got, err = collect(lines.Lines(strings.NewReader(in), lines.WithMaxLineLength(5)))
+	if !errors.Is(err, bufio.ErrTooLong) || !strings.Contains(err.Error(), "line 2") || !slices.Equal(got, []string{"12345"}) {
+		t.Errorf("Expected line 2 to be too long after one line but got %q, %v", got, err)
+	}
+
+	// a line at the limit is fine with a "\r\n" too, and at the end
+	got, err = collect(lines.Lines(strings.NewReader("12345\r\n12345"), lines.WithMaxLineLength(5)))
+	if err != nil || len(got) != 2 {
+		t.Errorf("Expected two lines of 5 bytes but got %q, %v", got, err)
+	}
+}
+
+func TestReadLines(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("alpha\nbeta\ngamma\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	seq := lines.ReadLines(path)
+	for range 2 {
+		if got, err := collect(seq); err != nil || !slices.Equal(got, []string{"alpha", "beta", "gamma"}) {
+			t.Errorf("Expected every ranging to read the whole file but got %q, %v", got, err)
+		}
+	}
+
+	for line := range seq {
+		if line != "alpha" {
+			t.Errorf("Expected alpha first but got %q", line)
+		}
+		break
+	}
+
+	if _, err := collect(lines.ReadLines(filepath.Join(t.TempDir(), "missing"))); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist for a missing file but got %v", err)
+	}
+	if _, err := collect(lines.ReadLines(path, lines.WithBufferSize(0))); err == nil {
+		t.Error("Expected an error for a zero buffer size but got none")
+	}
+}
+
+func BenchmarkLines(b *testing.B) {
+
+	in := strings.Repeat("a line of a typical log file, not very long\n", 10_000)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		for _, err := range lines.Lines(strings.NewReader(in)) {
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
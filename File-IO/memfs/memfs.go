@@ -0,0 +1,517 @@
+// Package memfs is a writable file system held in memory, for tests that
+// should not touch the disk. It implements fs.FS with the ReadFile, Stat
+// and ReadDir extensions, and adds the calls that code writing files
+// needs: OpenFile with the os flags, MkdirAll, Remove, Rename and
+// WriteFile.
+//
+// Names are slash separated and unrooted, as io/fs requires. Files opened
+// for writing support Write, Seek, Truncate and Sync; Sync does nothing.
+package memfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type node struct {
+	name    string // base name
+	data    []byte
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (n *node) info() fs.FileInfo {
+	return &fileInfo{name: n.name, size: int64(len(n.data)), mode: n.mode, modTime: n.modTime}
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (fi *fileInfo) Name() string               { return fi.name }
+func (fi *fileInfo) Size() int64                { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode          { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time         { return fi.modTime }
+func (fi *fileInfo) IsDir() bool                { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() any                   { return nil }
+func (fi *fileInfo) Type() fs.FileMode          { return fi.mode.Type() }
+func (fi *fileInfo) Info() (fs.FileInfo, error) { return fi, nil }
+
+// FS is safe for concurrent use. The zero value is not; use New.
+type FS struct {
+	mu    sync.Mutex
+	nodes map[string]*node // by full name; "." is the root
+}
+
+// New returns an empty file system.
+func New() *FS {
+	return &FS{nodes: map[string]*node{
+		".": {name: ".", mode: fs.ModeDir | 0o755, modTime: time.Now()},
+	}}
+}
+
+func pathErr(op, name string, err error) error {
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// parent returns the directory holding name, or an error if it is missing
+// or not a directory. mu must be held.
+func (m *FS) parent(op, name string) error {
+
+	dir, ok := m.nodes[path.Dir(name)]
+	if !ok {
+		return pathErr(op, name, fs.ErrNotExist)
+	}
+	if !dir.mode.IsDir() {
+		return pathErr(op, name, errors.New("not a directory"))
+	}
+
+	return nil
+}
+
+func (m *FS) Open(name string) (fs.File, error) {
+	return m.OpenFile(name, os.O_RDONLY, 0)
+}
+
+// OpenFile is os.OpenFile for the in-memory tree. It understands
+// O_RDONLY, O_WRONLY, O_RDWR, O_APPEND, O_CREATE, O_EXCL and O_TRUNC.
+func (m *FS) OpenFile(name string, flag int, perm fs.FileMode) (fs.File, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, pathErr("open", name, fs.ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.nodes[name]
+	switch {
+	case ok && flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, pathErr("open", name, fs.ErrExist)
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, pathErr("open", name, fs.ErrNotExist)
+	case !ok:
+		if err := m.parent("open", name); err != nil {
+			return nil, err
+		}
+		n = &node{name: path.Base(name), mode: perm.Perm(), modTime: time.Now()}
+		m.nodes[name] = n
+	}
+
+	writable := flag&(os.O_WRONLY|os.O_RDWR) != 0
+	if n.mode.IsDir() && writable {
+		return nil, pathErr("open", name, errors.New("is a directory"))
+	}
+	if writable && flag&os.O_TRUNC != 0 {
+		n.data = nil
+		n.modTime = time.Now()
+	}
+
+	return &File{
+		fs:     m,
+		path:   name,
+		node:   n,
+		read:   flag&os.O_WRONLY == 0,
+		write:  writable,
+		append: flag&os.O_APPEND != 0,
+	}, nil
+}
+
+// Create creates or truncates name, like os.Create.
+func (m *FS) Create(name string) (*File, error) {
+
+	f, err := m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o666)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.(*File), nil
+}
+
+func (m *FS) ReadFile(name string) ([]byte, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, pathErr("read", name, fs.ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, pathErr("read", name, fs.ErrNotExist)
+	}
+	if n.mode.IsDir() {
+		return nil, pathErr("read", name, errors.New("is a directory"))
+	}
+
+	return slices.Clone(n.data), nil
+}
+
+// WriteFile replaces the contents of name, creating it with perm if it
+// does not exist.
+func (m *FS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+
+	f, err := m.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = f.(*File).Write(data)
+
+	return errors.Join(err, f.Close())
+}
+
+func (m *FS) Stat(name string) (fs.FileInfo, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, pathErr("stat", name, fs.ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.nodes[name]
+	if !ok {
+		return nil, pathErr("stat", name, fs.ErrNotExist)
+	}
+
+	return n.info(), nil
+}
+
+func (m *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+
+	if !fs.ValidPath(name) {
+		return nil, pathErr("readdir", name, fs.ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.readDir(name)
+}
+
+// readDir lists name sorted by file name. mu must be held.
+func (m *FS) readDir(name string) ([]fs.DirEntry, error) {
+
+	dir, ok := m.nodes[name]
+	if !ok {
+		return nil, pathErr("readdir", name, fs.ErrNotExist)
+	}
+	if !dir.mode.IsDir() {
+		return nil, pathErr("readdir", name, errors.New("not a directory"))
+	}
+
+	var entries []fs.DirEntry
+	for p, n := range m.nodes {
+		if p != "." && path.Dir(p) == name {
+			entries = append(entries, n.info().(*fileInfo))
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// MkdirAll creates name and any missing parents.
+func (m *FS) MkdirAll(name string, perm fs.FileMode) error {
+
+	if !fs.ValidPath(name) {
+		return pathErr("mkdir", name, fs.ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var missing []string
+	for p := name; p != "."; p = path.Dir(p) {
+		if n, ok := m.nodes[p]; ok {
+			if !n.mode.IsDir() {
+				return pathErr("mkdir", p, errors.New("not a directory"))
+			}
+			break
+		}
+		missing = append(missing, p)
+	}
+
+	now := time.Now()
+	for _, p := range missing {
+		m.nodes[p] = &node{name: path.Base(p), mode: fs.ModeDir | perm.Perm(), modTime: now}
+	}
+
+	return nil
+}
+
+// Remove deletes a file or an empty directory.
+func (m *FS) Remove(name string) error {
+
+	if !fs.ValidPath(name) || name == "." {
+		return pathErr("remove", name, fs.ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.nodes[name]
+	if !ok {
+		return pathErr("remove", name, fs.ErrNotExist)
+	}
+	if n.mode.IsDir() {
+		if entries, _ := m.readDir(name); len(entries) > 0 {
+			return pathErr("remove", name, errors.New("directory not empty"))
+		}
+	}
+	delete(m.nodes, name)
+
+	return nil
+}
+
+// Rename moves oldname to newname, replacing newname if it is a file, the
+// way os.Rename does on Unix. Directories are moved with their contents.
+func (m *FS) Rename(oldname, newname string) error {
+
+	if !fs.ValidPath(oldname) || !fs.ValidPath(newname) || oldname == "." || newname == "." {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.nodes[oldname]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrNotExist}
+	}
+	if err := m.parent("rename", newname); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: errors.Unwrap(err)}
+	}
+	if old, ok := m.nodes[newname]; ok && old.mode.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrExist}
+	}
+	if n.mode.IsDir() && strings.HasPrefix(newname, oldname+"/") {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: fs.ErrInvalid}
+	}
+
+	delete(m.nodes, oldname)
+	n.name = path.Base(newname)
+	m.nodes[newname] = n
+
+	if n.mode.IsDir() {
+		for p, c := range m.nodes {
+			if rest, ok := strings.CutPrefix(p, oldname+"/"); ok {
+				delete(m.nodes, p)
+				m.nodes[newname+"/"+rest] = c
+			}
+		}
+	}
+
+	return nil
+}
+
+// File is an open file. Handles to the same name share its contents, so a
+// write through one is seen by a read through another.
+type File struct {
+	fs     *FS
+	path   string
+	node   *node
+	offset int64
+	read   bool
+	write  bool
+	append bool
+	closed bool
+	dirPos int
+}
+
+func (f *File) check(op string, allowed bool) error {
+
+	if f.closed {
+		return pathErr(op, f.path, fs.ErrClosed)
+	}
+	if !allowed {
+		return pathErr(op, f.path, fs.ErrPermission)
+	}
+
+	return nil
+}
+
+func (f *File) Stat() (fs.FileInfo, error) {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("stat", true); err != nil {
+		return nil, err
+	}
+
+	return f.node.info(), nil
+}
+
+func (f *File) Read(p []byte) (int, error) {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("read", f.read && !f.node.mode.IsDir()); err != nil {
+		return 0, err
+	}
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset += int64(n)
+
+	return n, nil
+}
+
+func (f *File) ReadAt(p []byte, off int64) (int, error) {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("read", f.read && !f.node.mode.IsDir()); err != nil {
+		return 0, err
+	}
+	if off < 0 {
+		return 0, pathErr("read", f.path, fs.ErrInvalid)
+	}
+	if off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+
+	return n, nil
+}
+
+func (f *File) Write(p []byte) (int, error) {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("write", f.write); err != nil {
+		return 0, err
+	}
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+
+	end := f.offset + int64(len(p))
+	if end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	copy(f.node.data[f.offset:], p)
+	f.offset = end
+	f.node.modTime = time.Now()
+
+	return len(p), nil
+}
+
+func (f *File) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("seek", true); err != nil {
+		return 0, err
+	}
+
+	switch whence {
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += int64(len(f.node.data))
+	}
+	if offset < 0 {
+		return 0, pathErr("seek", f.path, fs.ErrInvalid)
+	}
+	f.offset = offset
+
+	return offset, nil
+}
+
+// Truncate changes the size of the file, padding with zeros when it
+// grows.
+func (f *File) Truncate(size int64) error {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("truncate", f.write); err != nil {
+		return err
+	}
+	if size < 0 {
+		return pathErr("truncate", f.path, fs.ErrInvalid)
+	}
+	if size <= int64(len(f.node.data)) {
+		f.node.data = f.node.data[:size]
+	} else {
+		f.node.data = append(f.node.data, make([]byte, size-int64(len(f.node.data)))...)
+	}
+	f.node.modTime = time.Now()
+
+	return nil
+}
+
+func (f *File) Sync() error {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	return f.check("sync", true)
+}
+
+// ReadDir reads the entries of a directory opened with Open, as
+// fs.ReadDirFile describes.
+func (f *File) ReadDir(count int) ([]fs.DirEntry, error) {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if err := f.check("readdir", true); err != nil {
+		return nil, err
+	}
+	entries, err := f.fs.readDir(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries = entries[min(f.dirPos, len(entries)):]
+	if count > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		entries = entries[:min(count, len(entries))]
+	}
+	f.dirPos += len(entries)
+
+	return entries, nil
+}
+
+func (f *File) Close() error {
+
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return pathErr("close", f.path, fs.ErrClosed)
+	}
+	f.closed = true
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package memfs_test
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"pacx/File-IO/memfs"
+)
+
+func TestConformsToFS(t *testing.T) {
+
+	m := memfs.New()
+	if err := m.MkdirAll("a/b", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"top.txt", "a/one.txt", "a/b/two.txt"} {
+		if err := m.WriteFile(name, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fstest.TestFS(m, "top.txt", "a/one.txt", "a/b/two.txt"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestOpenFileFlags(t *testing.T) {
+
+	m := memfs.New()
+
+	if _, err := m.OpenFile("log.txt", os.O_WRONLY, 0); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected ErrNotExist without O_CREATE but got %v", err)
+	}
+
+	f, err := m.OpenFile("log.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := f.(*memfs.File)
+	w.WriteString("one\n")
+	w.Seek(0, io.SeekStart)
+	w.WriteString("two\n")
+	if _, err := w.Read(make([]byte, 1)); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("Expected reading a write-only file to fail but got %v", err)
+	}
+	w.Close()
+
+	if data, _ := m.ReadFile("log.txt"); string(data) != "one\ntwo\n" {
+		t.Errorf("Expected appended lines but got %q", data)
+	}
+	if _, err := m.OpenFile("log.txt", os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected ErrExist with O_EXCL but got %v", err)
+	}
+
+	f, _ = m.OpenFile("log.txt", os.O_RDWR|os.O_TRUNC, 0)
+	f.Close()
+	if info, _ := m.Stat("log.txt"); info.Size() != 0 {
+		t.Errorf("Expected O_TRUNC to empty the file but size is %d", info.Size())
+	}
+
+	if _, err := m.OpenFile("missing/x.txt", os.O_CREATE|os.O_WRONLY, 0o644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected a missing parent to fail but got %v", err)
+	}
+}
+
+func TestRenameAndRemove(t *testing.T) {
+
+	m := memfs.New()
+	m.MkdirAll("dir/sub", 0o755)
+	m.WriteFile("dir/sub/f.txt", []byte("x"), 0o644)
+	m.WriteFile("target.txt", []byte("old"), 0o644)
+	m.WriteFile("tmp.txt", []byte("new"), 0o644)
+
+	if err := m.Rename("tmp.txt", "target.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := m.ReadFile("target.txt"); string(data) != "new" {
+		t.Errorf("Expected rename to replace the target but got %q", data)
+	}
+	if _, err := m.Stat("tmp.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected the old name to be gone but got %v", err)
+	}
+
+	if err := m.Rename("dir", "moved"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := m.ReadFile("moved/sub/f.txt"); err != nil || string(data) != "x" {
+		t.Errorf("Expected the directory contents to move but got %q, %v", data, err)
+	}
+
+	if err := m.Remove("moved/sub"); err == nil {
+		t.Error("Expected removing a non-empty directory to fail")
+	}
+	m.Remove("moved/sub/f.txt")
+	if err := m.Remove("moved/sub"); err != nil {
+		t.Errorf("Expected removing an empty directory to succeed but got %v", err)
+	}
+}
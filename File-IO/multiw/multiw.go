@@ -0,0 +1,78 @@
+// Package multiw duplicates writes to several sinks like io.MultiWriter,
+// except that only the primary sink can fail a write. Errors from the
+// secondary sinks are counted and kept for inspection instead, so a broken
+// log stream does not take the log file down with it.
+package multiw
+
+import (
+	"io"
+	"sync"
+)
+
+// SinkStats describes one sink.
+type SinkStats struct {
+	Writes  int64 // calls to Write
+	Errors  int64 // calls that failed or wrote short
+	LastErr error // most recent failure, nil if there never was one
+}
+
+// MultiWriter is safe for concurrent use; writes reach every sink in the
+// same order.
+type MultiWriter struct {
+	mu    sync.Mutex
+	sinks []io.Writer
+	stats []SinkStats
+}
+
+// Writer returns a writer that writes to primary and then to each
+// secondary. Write returns whatever primary returned.
+func Writer(primary io.Writer, secondaries ...io.Writer) *MultiWriter {
+
+	sinks := append([]io.Writer{primary}, secondaries...)
+
+	return &MultiWriter{
+		sinks: sinks,
+		stats: make([]SinkStats, len(sinks)),
+	}
+}
+
+func (m *MultiWriter) Write(p []byte) (int, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, err := m.write(0, p)
+	for i := 1; i < len(m.sinks); i++ {
+		m.write(i, p)
+	}
+
+	return n, err
+}
+
+// write sends p to sink i and records the outcome. mu must be held.
+func (m *MultiWriter) write(i int, p []byte) (int, error) {
+
+	n, err := m.sinks[i].Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	s := &m.stats[i]
+	s.Writes++
+	if err != nil {
+		s.Errors++
+		s.LastErr = err
+	}
+
+	return n, err
+}
+
+// Stats returns a snapshot of the counters, primary first and then the
+// secondaries in the order they were given.
+func (m *MultiWriter) Stats() []SinkStats {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]SinkStats(nil), m.stats...)
+}
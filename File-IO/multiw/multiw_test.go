@@ -0,0 +1,64 @@
+package multiw_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"pacx/File-IO/multiw"
+)
+
+var errBroken = errors.New("sink broken")
+
+type brokenWriter struct{}
+
+func (brokenWriter) Write(p []byte) (int, error) { return 0, errBroken }
+
+type shortWriter struct{}
+
+func (shortWriter) Write(p []byte) (int, error) { return len(p) / 2, nil }
+
+func TestSecondaryFailureIsolated(t *testing.T) {
+
+	var primary, good bytes.Buffer
+	w := multiw.Writer(&primary, brokenWriter{}, &good, shortWriter{})
+
+	for i := 0; i < 3; i++ {
+		if _, err := fmt.Fprintf(w, "line %d\n", i); err != nil {
+			t.Fatalf("Expected the primary write to succeed but got %v", err)
+		}
+	}
+
+	if primary.String() != good.String() || good.Len() == 0 {
+		t.Errorf("Expected both healthy sinks to match but got %q and %q", primary.String(), good.String())
+	}
+
+	stats := w.Stats()
+	expected := []int64{0, 3, 0, 3}
+	for i, s := range stats {
+		if s.Writes != 3 || s.Errors != expected[i] {
+			t.Errorf("Expected sink %d to have 3 writes and %d errors but got %+v", i, expected[i], s)
+		}
+	}
+	if stats[1].LastErr != errBroken {
+		t.Errorf("Expected %v but got %v", errBroken, stats[1].LastErr)
+	}
+	if stats[3].LastErr != io.ErrShortWrite {
+		t.Errorf("Expected %v but got %v", io.ErrShortWrite, stats[3].LastErr)
+	}
+}
+
+func TestPrimaryFailureReturned(t *testing.T) {
+
+	var secondary bytes.Buffer
+	w := multiw.Writer(brokenWriter{}, &secondary)
+
+	if _, err := w.Write([]byte("x")); err != errBroken {
+		t.Errorf("Expected %v but got %v", errBroken, err)
+	}
+	if secondary.String() != "x" {
+		t.Errorf("Expected the secondary to still get the write but got %q", secondary.String())
+	}
+}
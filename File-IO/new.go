@@ -3,11 +3,25 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+
+	"pacx/File-IO/atomicfile"
+	"pacx/File-IO/tempmgr"
 )
 
 func main() {
 
-	file, err := os.Create("file.txt")
+	// a directory of our own, removed at the end or on ^C
+	tmp, err := tempmgr.New("file-io")
+	if err != nil {
+		fmt.Println("Error in creating temp dir", err)
+		return
+	}
+	defer tmp.Close()
+	path := filepath.Join(tmp.Root(), "file.txt")
+
+	// like os.Create, but a crash leaves the old file.txt or the new one
+	err = atomicfile.Write(path, nil, 0o644)
 	if err != nil {
 		fmt.Println("Error in creating file", err)
 		return
@@ -15,13 +29,10 @@ func main() {
 	fmt.Println("File Created Sucessfully")
 
 	// checking the file existence
-	_, err = os.Stat("file.txt")
+	_, err = os.Stat(path)
 	if os.IsExist(err) {
 		fmt.Println("No file Found")
 	} else {
 		fmt.Println("file is here.")
 	}
-
-	defer file.Close()
-
 }
@@ -3,10 +3,20 @@ package main
 import (
 	"fmt"
 	"os"
+
+	"pacx/File-IO/filelock"
 )
 
 func main() {
 
+	// wait for any other run that is still writing file.txt
+	lock, err := filelock.Lock("file.txt.lock")
+	if err != nil {
+		fmt.Println("lock failed", err)
+		return
+	}
+	defer lock.Unlock()
+
 	file, err := os.OpenFile("file.txt", os.O_APPEND|os.O_WRONLY, 644) // ONLY O_WRONLY is for overwrite
 
 	if err != nil {
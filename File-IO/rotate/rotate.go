@@ -0,0 +1,269 @@
+// Package rotate is an io.Writer for log files that starts a new file once
+// the current one grows too large or too old. The old files are kept as
+// backups next to it, numbered from the newest, and optionally gzipped:
+//
+//	app.log      being written
+//	app.log.1    the previous file
+//	app.log.2.gz older, compressed
+//
+// A log record is never split between files: rotation happens before a
+// write that would not fit, not during it.
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"pacx/options"
+)
+
+type config struct {
+	maxSize  int64
+	maxAge   time.Duration
+	backups  int
+	compress bool
+}
+
+// Option configures New.
+type Option = options.Option[config]
+
+// WithMaxSize rotates before a write that would take the file past n
+// bytes. A single write larger than n gets a file to itself. Defaults to
+// 100 MiB; 0 turns size rotation off.
+func WithMaxSize(n int64) Option {
+	return options.New("WithMaxSize", func(c *config) error {
+		if n < 0 {
+			return errors.New("max size must not be negative")
+		}
+		c.maxSize = n
+		return nil
+	})
+}
+
+// WithMaxAge rotates before the first write once the file is d old,
+// counted from when this Writer started it or opened it. By default files
+// are not rotated by age.
+func WithMaxAge(d time.Duration) Option {
+	return options.New("WithMaxAge", func(c *config) error {
+		if d < 0 {
+			return errors.New("max age must not be negative")
+		}
+		c.maxAge = d
+		return nil
+	})
+}
+
+// WithBackups keeps n old files and deletes older ones. Defaults to 5.
+func WithBackups(n int) Option {
+	return options.New("WithBackups", func(c *config) error {
+		if n < 0 {
+			return errors.New("backups must not be negative")
+		}
+		c.backups = n
+		return nil
+	})
+}
+
+// WithCompress gzips the backups. The compression runs inside the Write
+// that rotates, so that write takes longer.
+func WithCompress() Option {
+	return options.New("WithCompress", func(c *config) error {
+		c.compress = true
+		return nil
+	})
+}
+
+// Writer writes to a file that it rotates. It is safe for concurrent use.
+type Writer struct {
+	path string
+	cfg  config
+	now  func() time.Time
+
+	mu      sync.Mutex
+	f       *os.File
+	size    int64
+	started time.Time
+}
+
+// New opens the file at path for appending, creating it if needed.
+func New(path string, opts ...Option) (*Writer, error) {
+
+	cfg, err := options.Build(config{maxSize: 100 << 20, backups: 5}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{path: path, cfg: cfg, now: time.Now}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *Writer) open() error {
+
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f, w.size, w.started = f, fi.Size(), w.now()
+
+	return nil
+}
+
+// Write writes p to the current file, first rotating if p would take it
+// past the max size or the file is past the max age.
+func (w *Writer) Write(p []byte) (int, error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.due(len(p)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+func (w *Writer) due(n int) bool {
+
+	if w.size == 0 {
+		return false
+	}
+	if w.cfg.maxSize > 0 && w.size+int64(n) > w.cfg.maxSize {
+		return true
+	}
+
+	return w.cfg.maxAge > 0 && w.now().Sub(w.started) >= w.cfg.maxAge
+}
+
+// Rotate starts a new file now, whatever its size and age.
+func (w *Writer) Rotate() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return os.ErrClosed
+	}
+
+	return w.rotate()
+}
+
+// rotate shifts the backups up by one, moves the current file to the
+// first and opens a new one. mu must be held.
+func (w *Writer) rotate() error {
+
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	w.f = nil
+
+	if err := w.shift(); err != nil {
+		// keep writing to the old file rather than losing the logs
+		if oerr := w.open(); oerr != nil {
+			return errors.Join(err, oerr)
+		}
+		return err
+	}
+
+	return w.open()
+}
+
+func (w *Writer) shift() error {
+
+	if w.cfg.backups == 0 {
+		return os.Remove(w.path)
+	}
+
+	// drop the oldest, then move each one up
+	for _, ext := range []string{"", ".gz"} {
+		if err := os.Remove(w.backup(w.cfg.backups) + ext); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	for i := w.cfg.backups - 1; i >= 1; i-- {
+		for _, ext := range []string{"", ".gz"} {
+			err := os.Rename(w.backup(i)+ext, w.backup(i+1)+ext)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(w.path, w.backup(1)); err != nil {
+		return err
+	}
+	if w.cfg.compress {
+		return gzipFile(w.backup(1))
+	}
+
+	return nil
+}
+
+func (w *Writer) backup(i int) string {
+	return fmt.Sprintf("%s.%d", w.path, i)
+}
+
+// gzipFile replaces path with path.gz.
+func gzipFile(path string) error {
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	in.Close()
+
+	return os.Remove(path)
+}
+
+// Close closes the current file. Writes after Close fail with
+// os.ErrClosed.
+func (w *Writer) Close() error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	err := w.f.Close()
+	w.f = nil
+
+	return err
+}
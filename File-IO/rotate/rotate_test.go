@@ -0,0 +1,196 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// files returns the names in dir, sorted.
+func files(t *testing.T, dir string) []string {
+
+	t.Helper()
+
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, de := range des {
+		names = append(names, de.Name())
+	}
+
+	return names
+}
+
+func read(t *testing.T, path string) string {
+
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestRotateBySize(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := New(path, WithMaxSize(10), WithBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddd\n", "eeee\n", "ffff\n", "gggg\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := files(t, dir); !slices.Equal(got, []string{"app.log", "app.log.1", "app.log.2"}) {
+		t.Fatalf("Expected the log and 2 backups but got %v", got)
+	}
+	for name, want := range map[string]string{"app.log": "gggg\n", "app.log.1": "eeee\nffff\n", "app.log.2": "cccc\ndddd\n"} {
+		if got := read(t, filepath.Join(dir, name)); got != want {
+			t.Errorf("Expected %s to hold %q but got %q", name, want, got)
+		}
+	}
+}
+
+func TestLargeWriteGetsItsOwnFile(t *testing.T) {
+
+	dir := t.TempDir()
+	w, err := New(filepath.Join(dir, "app.log"), WithMaxSize(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("ab"))
+	w.Write([]byte("longer than the limit"))
+	w.Write([]byte("cd"))
+
+	for name, want := range map[string]string{"app.log": "cd", "app.log.1": "longer than the limit", "app.log.2": "ab"} {
+		if got := read(t, filepath.Join(dir, name)); got != want {
+			t.Errorf("Expected %s to hold %q but got %q", name, want, got)
+		}
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := New(path, WithMaxSize(0), WithMaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	now := time.Now()
+	w.now = func() time.Time { return now }
+	w.started = now
+
+	w.Write([]byte("first\n"))
+	now = now.Add(59 * time.Minute)
+	w.Write([]byte("second\n"))
+	now = now.Add(time.Minute)
+	w.Write([]byte("third\n"))
+
+	if got := read(t, path+".1"); got != "first\nsecond\n" {
+		t.Errorf("Expected the first hour in the backup but got %q", got)
+	}
+	if got := read(t, path); got != "third\n" {
+		t.Errorf("Expected a new file after an hour but got %q", got)
+	}
+}
+
+func TestCompress(t *testing.T) {
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := New(path, WithCompress(), WithBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	for _, s := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		w.Write([]byte(s))
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := files(t, dir); !slices.Equal(got, []string{"app.log", "app.log.1.gz", "app.log.2.gz"}) {
+		t.Fatalf("Expected 2 compressed backups but got %v", got)
+	}
+	if got := read(t, path+".1.gz"); got != "four\n" {
+		t.Errorf("Expected the newest backup to hold four but got %q", got)
+	}
+	if got := read(t, path+".2.gz"); got != "three\n" {
+		t.Errorf("Expected the older backup to hold three but got %q", got)
+	}
+}
+
+func TestAppendsToExistingFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	os.WriteFile(path, []byte("12345678"), 0o644)
+
+	w, err := New(path, WithMaxSize(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("9"))
+	w.Write([]byte("abc"))
+	w.Close()
+
+	if got := read(t, path+".1"); got != "123456789" {
+		t.Errorf("Expected the existing file to be counted and appended to but got %q", got)
+	}
+	if _, err := w.Write([]byte("late")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected os.ErrClosed after Close but got %v", err)
+	}
+}
+
+func TestNoBackups(t *testing.T) {
+
+	dir := t.TempDir()
+	w, err := New(filepath.Join(dir, "app.log"), WithMaxSize(3), WithBackups(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("abc"))
+	w.Write([]byte("def"))
+
+	if got := files(t, dir); !slices.Equal(got, []string{"app.log"}) {
+		t.Errorf("Expected only the log without backups but got %v", got)
+	}
+}
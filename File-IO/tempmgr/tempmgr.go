@@ -0,0 +1,218 @@
+// Package tempmgr keeps the temporary files and directories of a program
+// in one place and removes them when it is done, including when it is
+// interrupted. Everything a TempManager creates lives in a directory of
+// its own under the system temp directory, named after its namespace:
+//
+//	m, err := tempmgr.New("orderservice")
+//	if err != nil {
+//		return err
+//	}
+//	defer m.Close()
+//	f, err := m.File("orders-*.json")
+//
+// Nothing can clean up after a process that is killed outright; Sweep
+// removes what such processes left behind.
+package tempmgr
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"pacx/options"
+)
+
+type config struct {
+	parent  string
+	signals []os.Signal
+}
+
+// Option configures New.
+type Option = options.Option[config]
+
+// WithParent creates the manager's directory in dir instead of
+// os.TempDir().
+func WithParent(dir string) Option {
+	return options.New("WithParent", func(c *config) error {
+		c.parent = dir
+		return nil
+	})
+}
+
+// WithSignals sets the signals that make the manager clean up. Defaults
+// to os.Interrupt and SIGTERM; none leaves signals alone, so only Close
+// cleans up.
+func WithSignals(sigs ...os.Signal) Option {
+	return options.New("WithSignals", func(c *config) error {
+		c.signals = sigs
+		return nil
+	})
+}
+
+// TempManager creates temporary files and directories and removes them
+// all on Close. It is safe for concurrent use.
+type TempManager struct {
+	dir  string
+	sigs chan os.Signal
+	stop chan struct{}
+
+	mu     sync.Mutex
+	files  map[string]*os.File // created by File and still open
+	closed bool
+}
+
+// New creates the manager's directory, namespace followed by a random
+// suffix. On one of its signals the manager removes it, then lets the
+// signal do what it would have done without the manager, which for the
+// defaults is to end the process.
+func New(namespace string, opts ...Option) (*TempManager, error) {
+
+	cfg, err := options.Build(config{signals: []os.Signal{os.Interrupt, syscall.SIGTERM}}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" || strings.ContainsAny(namespace, `/\*?[`) {
+		return nil, errors.New("tempmgr: namespace must be a plain name")
+	}
+
+	dir, err := os.MkdirTemp(cfg.parent, namespace+"-*")
+	if err != nil {
+		return nil, err
+	}
+	m := &TempManager{dir: dir, files: make(map[string]*os.File), stop: make(chan struct{})}
+
+	if len(cfg.signals) > 0 {
+		m.sigs = make(chan os.Signal, 1)
+		signal.Notify(m.sigs, cfg.signals...)
+		go m.watch()
+	}
+
+	return m, nil
+}
+
+func (m *TempManager) watch() {
+
+	select {
+	case sig := <-m.sigs:
+		m.Close()
+		// with the handler gone, the signal has its usual effect
+		if p, err := os.FindProcess(os.Getpid()); err == nil {
+			p.Signal(sig)
+		}
+	case <-m.stop:
+	}
+}
+
+// Root returns the manager's directory.
+func (m *TempManager) Root() string {
+	return m.dir
+}
+
+// File creates a file in the manager's directory, named by pattern as in
+// os.CreateTemp. Close closes it if the caller has not.
+func (m *TempManager) File(pattern string) (*os.File, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil, os.ErrClosed
+	}
+	f, err := os.CreateTemp(m.dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	m.files[f.Name()] = f
+
+	return f, nil
+}
+
+// Dir creates a directory in the manager's directory, named by pattern as
+// in os.MkdirTemp.
+func (m *TempManager) Dir(pattern string) (string, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return "", os.ErrClosed
+	}
+
+	return os.MkdirTemp(m.dir, pattern)
+}
+
+// Remove deletes a file or directory the manager created before Close
+// does.
+func (m *TempManager) Remove(path string) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rel, err := filepath.Rel(m.dir, path)
+	if err != nil || rel == "." || !filepath.IsLocal(rel) {
+		return errors.New("tempmgr: " + path + " is not managed")
+	}
+	if f, ok := m.files[path]; ok {
+		f.Close()
+		delete(m.files, path)
+	}
+
+	return os.RemoveAll(path)
+}
+
+// Close closes the files still open and removes the manager's directory
+// with everything in it. Calling it again does nothing.
+func (m *TempManager) Close() error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if m.sigs != nil {
+		signal.Stop(m.sigs)
+		close(m.stop)
+	}
+
+	// open files cannot be removed on Windows
+	for _, f := range m.files {
+		f.Close()
+	}
+	m.files = nil
+
+	return os.RemoveAll(m.dir)
+}
+
+// Sweep removes the directories of namespace in parent, or os.TempDir()
+// if parent is empty, that have not been modified for olderThan. They are
+// left by processes that ended without closing their manager.
+func Sweep(parent, namespace string, olderThan time.Duration) error {
+
+	if parent == "" {
+		parent = os.TempDir()
+	}
+	dirs, err := filepath.Glob(filepath.Join(parent, namespace+"-*"))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, d := range dirs {
+		fi, err := os.Lstat(d)
+		if err != nil || !fi.IsDir() || time.Since(fi.ModTime()) < olderThan {
+			continue
+		}
+		if err := os.RemoveAll(d); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
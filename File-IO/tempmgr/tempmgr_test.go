@@ -0,0 +1,152 @@
+package tempmgr_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/File-IO/tempmgr"
+)
+
+func TestClose(t *testing.T) {
+
+	parent := t.TempDir()
+	m, err := tempmgr.New("demo", tempmgr.WithParent(parent), tempmgr.WithSignals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(filepath.Base(m.Root()), "demo-") || filepath.Dir(m.Root()) != parent {
+		t.Errorf("Expected a demo-* directory in %s but got %s", parent, m.Root())
+	}
+
+	f, err := m.File("data-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("left open on purpose")
+	dir, err := m.Dir("work-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nested"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(m.Root()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the directory to be removed but got %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Errorf("Expected a second Close to do nothing but got %v", err)
+	}
+	if _, err := m.File("late"); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected os.ErrClosed after Close but got %v", err)
+	}
+}
+
+func TestRemove(t *testing.T) {
+
+	m, err := tempmgr.New("demo", tempmgr.WithParent(t.TempDir()), tempmgr.WithSignals())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	f, _ := m.File("x")
+	if err := m.Remove(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(f.Name()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the file to be removed but got %v", err)
+	}
+	for _, p := range []string{m.Root(), filepath.Join(m.Root(), ".."), t.TempDir()} {
+		if err := m.Remove(p); err == nil {
+			t.Errorf("Expected Remove of %s to be refused but got no error", p)
+		}
+	}
+}
+
+func TestNamespace(t *testing.T) {
+
+	for _, ns := range []string{"", "a/b", "a*", "a?"} {
+		if _, err := tempmgr.New(ns, tempmgr.WithParent(t.TempDir())); err == nil {
+			t.Errorf("Expected namespace %q to be refused but got no error", ns)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+
+	parent := t.TempDir()
+	old, _ := tempmgr.New("demo", tempmgr.WithParent(parent), tempmgr.WithSignals())
+	fresh, _ := tempmgr.New("demo", tempmgr.WithParent(parent), tempmgr.WithSignals())
+	other, _ := tempmgr.New("other", tempmgr.WithParent(parent), tempmgr.WithSignals())
+	defer fresh.Close()
+	defer other.Close()
+
+	long := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(old.Root(), long, long)
+	os.Chtimes(other.Root(), long, long)
+
+	if err := tempmgr.Sweep(parent, "demo", 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for root, want := range map[string]bool{old.Root(): false, fresh.Root(): true, other.Root(): true} {
+		if _, err := os.Stat(root); (err == nil) != want {
+			t.Errorf("Expected %s to exist: %v but got %v", root, want, err)
+		}
+	}
+}
+
+// TestSignal runs this test binary again with a manager and interrupts it.
+func TestSignal(t *testing.T) {
+
+	if parent := os.Getenv("TEMPMGR_CHILD"); parent != "" {
+		m, err := tempmgr.New("child", tempmgr.WithParent(parent))
+		if err != nil {
+			os.Exit(1)
+		}
+		m.File("data")
+		fmt.Println(m.Root())
+		time.Sleep(time.Minute)
+		os.Exit(0)
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot send os.Interrupt to a process on Windows")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSignal$")
+	cmd.Env = append(os.Environ(), "TEMPMGR_CHILD="+t.TempDir())
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	root, err := bufio.NewReader(out).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	root = strings.TrimSpace(root)
+
+	cmd.Process.Signal(os.Interrupt)
+	err = cmd.Wait()
+
+	var exit *exec.ExitError
+	if !errors.As(err, &exit) || exit.Success() {
+		t.Errorf("Expected the interrupt to end the process but got %v", err)
+	}
+	if _, err := os.Stat(root); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected %s to be removed on the interrupt but got %v", root, err)
+	}
+}
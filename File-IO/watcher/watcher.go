@@ -0,0 +1,355 @@
+// Package watcher reports files being created, modified and removed. It
+// polls with os.Stat instead of using the notification APIs of the system,
+// so it works the same everywhere, on network file systems too, at the
+// cost of noticing changes only once per interval.
+//
+// A file counts as modified when its size, mode or modification time
+// changes; directories are only ever created or removed. Two writes
+// within one interval are one event, and a write that keeps the size
+// within the resolution of the file system's timestamps can go unnoticed.
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"pacx/options"
+)
+
+// Op is what happened to a file.
+type Op uint8
+
+const (
+	Create Op = iota + 1
+	Write
+	Remove
+)
+
+func (o Op) String() string {
+
+	switch o {
+	case Create:
+		return "CREATE"
+	case Write:
+		return "WRITE"
+	case Remove:
+		return "REMOVE"
+	}
+
+	return fmt.Sprintf("Op(%d)", uint8(o))
+}
+
+// Event is one change. Info is the file as it is now, nil for Remove.
+type Event struct {
+	Path string
+	Op   Op
+	Info fs.FileInfo
+}
+
+func (e Event) String() string {
+	return e.Op.String() + " " + e.Path
+}
+
+type config struct {
+	interval  time.Duration
+	recursive bool
+	buffer    int
+	onError   func(error)
+}
+
+// Option configures Watch.
+type Option = options.Option[config]
+
+// WithInterval sets how often the files are polled. Defaults to a second.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithRecursive watches the whole tree under each directory instead of
+// only its entries.
+func WithRecursive() Option {
+	return options.New("WithRecursive", func(c *config) error {
+		c.recursive = true
+		return nil
+	})
+}
+
+// WithBuffer sets how many events the channel holds. Defaults to 64.
+// Polling waits while it is full, so nothing is dropped.
+func WithBuffer(n int) Option {
+	return options.New("WithBuffer", func(c *config) error {
+		if n < 0 {
+			return errors.New("buffer must not be negative")
+		}
+		c.buffer = n
+		return nil
+	})
+}
+
+// WithOnError calls fn with the errors of polling, such as a directory
+// that cannot be read. By default they are dropped; the files that could
+// not be seen keep their last known state until they can be again.
+func WithOnError(fn func(error)) Option {
+	return options.New("WithOnError", func(c *config) error {
+		c.onError = fn
+		return nil
+	})
+}
+
+// state is what a poll compares.
+type state struct {
+	info fs.FileInfo
+}
+
+// changed reports whether the file was modified. Directories are not:
+// their changes are reported as the events of their entries.
+func (s state) changed(o state) bool {
+
+	if s.info.IsDir() && o.info.IsDir() {
+		return false
+	}
+
+	return s.info.Size() != o.info.Size() || s.info.Mode() != o.info.Mode() || !s.info.ModTime().Equal(o.info.ModTime())
+}
+
+// Watcher polls a set of paths. A path that is a file is watched itself,
+// a directory together with its entries; a path that does not exist yet
+// is reported when it is created.
+type Watcher struct {
+	cfg    config
+	events chan Event
+	done   chan struct{}
+
+	mu    sync.Mutex
+	roots map[string]bool
+	files map[string]state
+}
+
+// Watch starts watching paths until ctx is done, after which the events
+// channel is closed. Changes are reported relative to the files as they
+// are when Watch returns.
+func Watch(ctx context.Context, paths []string, opts ...Option) (*Watcher, error) {
+
+	cfg, err := options.Build(config{interval: time.Second, buffer: 64, onError: func(error) {}}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Watcher{
+		cfg:    cfg,
+		events: make(chan Event, cfg.buffer),
+		done:   make(chan struct{}),
+		roots:  make(map[string]bool),
+		files:  make(map[string]state),
+	}
+	for _, p := range paths {
+		w.add(p)
+	}
+	go w.run(ctx)
+
+	return w, nil
+}
+
+// Events returns the channel the changes are delivered on, in path order
+// within each poll.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Done is closed once the watcher has stopped and the events channel is
+// closed.
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Add starts watching path, without events for what is already there.
+func (w *Watcher) Add(path string) {
+	w.add(path)
+}
+
+// Remove stops watching path, which must have been passed to Watch or Add.
+// Files still under another watched path stay watched.
+func (w *Watcher) Remove(path string) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.roots, filepath.Clean(path))
+	for p := range w.files {
+		if !w.watched(p) {
+			delete(w.files, p)
+		}
+	}
+}
+
+// watched reports whether a scan of the roots would include p. mu must be
+// held.
+func (w *Watcher) watched(p string) bool {
+
+	for root := range w.roots {
+		if p == root || w.cfg.recursive && under(p, root) || filepath.Dir(p) == root {
+			return true
+		}
+	}
+
+	return false
+}
+
+// under reports whether p is below dir.
+func under(p, dir string) bool {
+
+	rel, err := filepath.Rel(dir, p)
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func (w *Watcher) add(path string) {
+
+	w.mu.Lock()
+	w.roots[filepath.Clean(path)] = true
+	now, errs := w.scan(w.files)
+	for p, s := range now {
+		if _, ok := w.files[p]; !ok {
+			w.files[p] = s
+		}
+	}
+	w.mu.Unlock()
+
+	// outside the lock, so onError may call Add or Remove
+	for _, err := range errs {
+		w.cfg.onError(err)
+	}
+}
+
+func (w *Watcher) run(ctx context.Context) {
+
+	defer close(w.done)
+	defer close(w.events)
+
+	t := time.NewTicker(w.cfg.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		events, errs := w.poll()
+		for _, err := range errs {
+			w.cfg.onError(err)
+		}
+		for _, e := range events {
+			select {
+			case w.events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// poll rescans the roots and returns the differences to the last scan
+// and the errors that came up.
+func (w *Watcher) poll() ([]Event, []error) {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now, errs := w.scan(w.files)
+
+	var events []Event
+	for p, s := range now {
+		old, ok := w.files[p]
+		switch {
+		case !ok:
+			events = append(events, Event{Path: p, Op: Create, Info: s.info})
+		case s.changed(old):
+			events = append(events, Event{Path: p, Op: Write, Info: s.info})
+		}
+	}
+	for p := range w.files {
+		if _, ok := now[p]; !ok {
+			events = append(events, Event{Path: p, Op: Remove})
+		}
+	}
+	slices.SortFunc(events, func(a, b Event) int { return strings.Compare(a.Path, b.Path) })
+	w.files = now
+
+	return events, errs
+}
+
+// scan stats every watched file. A file that cannot be seen for any
+// reason but not existing keeps its state from last, so an unreadable
+// directory does not look like its entries were removed. The errors are
+// returned for onError, which must not be called with mu held. mu must be
+// held.
+func (w *Watcher) scan(last map[string]state) (map[string]state, []error) {
+
+	now := make(map[string]state, len(last))
+	var errs []error
+	for root := range w.roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			w.keep(now, last, root, err, &errs)
+			continue
+		}
+		now[root] = state{info}
+		if info.IsDir() {
+			w.scanDir(now, last, root, &errs)
+		}
+	}
+
+	return now, errs
+}
+
+func (w *Watcher) scanDir(now, last map[string]state, dir string, errs *[]error) {
+
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		w.keep(now, last, dir, err, errs)
+		return
+	}
+	for _, de := range des {
+		p := filepath.Join(dir, de.Name())
+		info, err := de.Info()
+		if err != nil {
+			w.keep(now, last, p, err, errs)
+			continue
+		}
+		now[p] = state{info}
+		if w.cfg.recursive && info.IsDir() {
+			w.scanDir(now, last, p, errs)
+		}
+	}
+}
+
+// keep copies the last state of path and, for a directory, everything
+// under it, unless err says it is gone. Other errors go to errs.
+func (w *Watcher) keep(now, last map[string]state, path string, err error, errs *[]error) {
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	*errs = append(*errs, err)
+
+	for p, s := range last {
+		if p == path || under(p, path) {
+			now[p] = s
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package watcher_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pacx/File-IO/watcher"
+)
+
+func watch(t *testing.T, paths []string, opts ...watcher.Option) *watcher.Watcher {
+
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := watcher.Watch(ctx, paths, append([]watcher.Option{watcher.WithInterval(5 * time.Millisecond)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		<-w.Done()
+	})
+
+	return w
+}
+
+// expect waits for the next event and checks it.
+func expect(t *testing.T, w *watcher.Watcher, op watcher.Op, path string) {
+
+	t.Helper()
+
+	select {
+	case e := <-w.Events():
+		if e.Op != op || e.Path != path {
+			t.Fatalf("Expected %s %s but got %s", op, path, e)
+		}
+		if (e.Info == nil) != (op == watcher.Remove) {
+			t.Errorf("Expected Info only when the file exists but got %v for %s", e.Info, e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Expected %s %s but got nothing", op, path)
+	}
+}
+
+// quiet checks that no event arrives for a few polls.
+func quiet(t *testing.T, w *watcher.Watcher) {
+
+	t.Helper()
+
+	select {
+	case e := <-w.Events():
+		t.Fatalf("Expected no event but got %s", e)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func write(t *testing.T, path, data string) {
+
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirectory(t *testing.T) {
+
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old")
+	write(t, old, "there before")
+
+	w := watch(t, []string{dir})
+	quiet(t, w)
+
+	f := filepath.Join(dir, "a.txt")
+	write(t, f, "one")
+	expect(t, w, watcher.Create, f)
+
+	write(t, f, "one two")
+	expect(t, w, watcher.Write, f)
+
+	if err := os.Remove(f); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, watcher.Remove, f)
+
+	if err := os.Remove(old); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, w, watcher.Remove, old)
+}
+
+func TestFileNotThereYet(t *testing.T) {
+
+	f := filepath.Join(t.TempDir(), "later.log")
+	w := watch(t, []string{f})
+
+	write(t, f, "now")
+	expect(t, w, watcher.Create, f)
+	os.Remove(f)
+	expect(t, w, watcher.Remove, f)
+}
+
+func TestRecursive(t *testing.T) {
+
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	flat := watch(t, []string{dir})
+	deep := watch(t, []string{dir}, watcher.WithRecursive())
+
+	f := filepath.Join(sub, "deep.txt")
+	write(t, f, "x")
+
+	expect(t, deep, watcher.Create, f)
+	quiet(t, flat)
+
+	if err := os.RemoveAll(sub); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, deep, watcher.Remove, sub)
+	expect(t, deep, watcher.Remove, f)
+}
+
+func TestAddAndRemove(t *testing.T) {
+
+	a, b := t.TempDir(), t.TempDir()
+	write(t, filepath.Join(b, "existing"), "")
+
+	w := watch(t, []string{a})
+	w.Add(b)
+	quiet(t, w)
+
+	write(t, filepath.Join(b, "new"), "")
+	expect(t, w, watcher.Create, filepath.Join(b, "new"))
+
+	w.Remove(b)
+	write(t, filepath.Join(b, "unseen"), "")
+	quiet(t, w)
+
+	write(t, filepath.Join(a, "seen"), "")
+	expect(t, w, watcher.Create, filepath.Join(a, "seen"))
+}
+
+func TestOnErrorMayRemove(t *testing.T) {
+
+	dir := t.TempDir()
+	f := filepath.Join(dir, "file")
+	write(t, f, "")
+	bad := filepath.Join(f, "under") // not a directory, not missing either
+
+	var w *watcher.Watcher
+	ready, removed := make(chan struct{}), make(chan struct{})
+	w = watch(t, []string{dir}, watcher.WithOnError(func(error) {
+		<-ready
+		w.Remove(bad)
+		select {
+		case <-removed:
+		default:
+			close(removed)
+		}
+	}))
+	close(ready)
+
+	go w.Add(bad)
+	select {
+	case <-removed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected onError to remove the path but it did not return")
+	}
+
+	write(t, filepath.Join(dir, "after"), "")
+	expect(t, w, watcher.Create, filepath.Join(dir, "after"))
+}
+
+func TestStopsWithContext(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w, err := watcher.Watch(ctx, []string{t.TempDir()}, watcher.WithInterval(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	select {
+	case <-w.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the watcher to stop once the context is done but it did not")
+	}
+	if _, ok := <-w.Events(); ok {
+		t.Error("Expected the events channel to be closed but it was not")
+	}
+}
+
+func TestOptions(t *testing.T) {
+
+	for _, opt := range []watcher.Option{watcher.WithInterval(0), watcher.WithBuffer(-1)} {
+		if _, err := watcher.Watch(context.Background(), nil, opt); err == nil {
+			t.Errorf("Expected %s to fail but got no error", opt.Name())
+		}
+	}
+}
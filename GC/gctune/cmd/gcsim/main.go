@@ -0,0 +1,149 @@
+// Command gcsim runs the memHog workload of GC/main.go, scaled up, under
+// fixed GC settings and under the gctune tuner, and prints what each cost
+// in time, collections, GC CPU, pauses and peak heap. Every setting runs
+// in a fresh copy of the process, so one run's heap does not carry over
+// into the next.
+//
+//	go run ./GC/gctune/cmd/gcsim
+//	go run ./GC/gctune/cmd/gcsim -live 2000000 -limit 128
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"time"
+
+	"pacx/GC/gctune"
+	"pacx/GC/memmon"
+)
+
+var (
+	run    = flag.String("run", "", "run one setting and print its row")
+	live   = flag.Int("live", 1000000, "10-byte slices kept live")
+	rounds = flag.Int("rounds", 120, "rounds, each replacing a quarter of the live slices")
+	limit  = flag.Int64("limit", 256, "memory limit of the tuned run, in MB")
+)
+
+// settings are the runs to compare, by name.
+var settings = []string{"gogc=100", "gogc=400", "tuned"}
+
+func main() {
+
+	flag.Parse()
+
+	if *run != "" {
+		if err := runOne(*run); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("%-10s %10s %6s %8s %10s %12s %6s\n", "setting", "time", "GCs", "GC CPU", "paused", "peak heap", "GOGC")
+	for _, s := range settings {
+		cmd := exec.Command(self, "-run", s,
+			"-live", strconv.Itoa(*live), "-rounds", strconv.Itoa(*rounds), "-limit", strconv.FormatInt(*limit, 10))
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Println(s, err)
+		}
+	}
+}
+
+func runOne(setting string) error {
+
+	var tuner *gctune.Tuner
+	switch setting {
+	case "gogc=100":
+		debug.SetGCPercent(100)
+	case "gogc=400":
+		debug.SetGCPercent(400)
+	case "tuned":
+		var err error
+		tuner, err = gctune.Start(
+			gctune.WithInterval(50*time.Millisecond),
+			gctune.WithMemoryLimit(*limit<<20),
+			gctune.WithOnStep(func(s gctune.Step) { fmt.Fprintln(os.Stderr, s) }),
+		)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown setting %q", setting)
+	}
+
+	mon, err := memmon.Start(memmon.WithInterval(10*time.Millisecond), memmon.WithHistory(1<<20))
+	if err != nil {
+		return err
+	}
+
+	before := memmon.Read()
+	gcBefore, cpuBefore := cpu()
+	start := time.Now()
+
+	memHog(*live, *rounds)
+
+	elapsed := time.Since(start)
+	gcAfter, cpuAfter := cpu()
+	after := memmon.Read()
+	mon.Close()
+
+	gogc := debug.SetGCPercent(-1)
+	if tuner != nil {
+		gogc = tuner.Percent()
+		tuner.Close()
+	}
+
+	var peak uint64
+	for _, s := range mon.History() {
+		peak = max(peak, s.HeapAlloc)
+	}
+
+	fmt.Printf("%-10s %10v %6d %7.1f%% %10v %9d MB %6d\n", setting,
+		elapsed.Round(time.Millisecond),
+		after.NumGC-before.NumGC,
+		100*(gcAfter-gcBefore)/(cpuAfter-cpuBefore),
+		(after.PauseTotal - before.PauseTotal).Round(time.Microsecond),
+		peak>>20,
+		gogc)
+
+	return nil
+}
+
+// memHog is the loop of GC/main.go at scale: it keeps n small slices live
+// and replaces a quarter of them every round, so each round leaves a
+// quarter of the live heap as garbage.
+func memHog(n, rounds int) {
+
+	var memHog [][]byte
+
+	for i := 0; i < n; i++ {
+		memHog = append(memHog, make([]byte, 10))
+	}
+
+	for r := 0; r < rounds; r++ {
+		for i := r % 4; i < n; i += 4 {
+			memHog[i] = make([]byte, 10)
+		}
+	}
+}
+
+// cpu returns the CPU seconds used by the collector and in total.
+func cpu() (gc, total float64) {
+
+	s := []metrics.Sample{{Name: "/cpu/classes/gc/total:cpu-seconds"}, {Name: "/cpu/classes/total:cpu-seconds"}}
+	metrics.Read(s)
+
+	return s[0].Value.Float64(), s[1].Value.Float64()
+}
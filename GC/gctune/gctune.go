@@ -0,0 +1,334 @@
+// Package gctune adjusts GOGC while a program runs. Every interval a Tuner
+// looks at how much CPU the collector took since the last look: above a
+// target it doubles the GC percent, so the heap may grow further between
+// collections and they run less often; well below it, it takes a quarter
+// off, giving memory back. The percent stays within a range, and with a
+// memory limit it is never raised past what the live heap leaves room for:
+//
+//	t, err := gctune.Start(
+//		gctune.WithGCCPUTarget(0.05),
+//		gctune.WithMemoryLimit(512<<20),
+//	)
+//	defer t.Close()
+//
+// The collector's CPU time includes its stop-the-world pauses; each Step
+// also records the pause time on its own. GC/gctune/cmd/gcsim compares
+// the tuner with fixed settings on the memHog workload of GC/main.go.
+package gctune
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"slices"
+	"sync"
+	"time"
+
+	"pacx/GC/memmon"
+	"pacx/options"
+	"pacx/sync/syncx"
+)
+
+// Step is one change of the GC percent and what led to it.
+type Step struct {
+	Time   time.Time
+	From   int
+	To     int
+	GCs    uint32        // collections in the interval
+	GCCPU  float64       // share of the CPU time the collector took
+	Pause  time.Duration // stop-the-world time in the interval
+	Live   uint64        // heap bytes marked live by the last collection
+	Reason string
+}
+
+func (s Step) String() string {
+	return fmt.Sprintf("gctune: GOGC %d -> %d: %s (%d collections, %v paused, %d KB live)",
+		s.From, s.To, s.Reason, s.GCs, s.Pause, s.Live/1024)
+}
+
+type config struct {
+	interval time.Duration
+	lo, hi   int
+	target   float64
+	limit    int64
+	history  int
+	onStep   func(Step)
+}
+
+// Option configures a Tuner.
+type Option = options.Option[config]
+
+// WithInterval sets how often the tuner looks. Defaults to a second.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithPercentRange bounds the GC percent. Defaults to 25..800.
+func WithPercentRange(lo, hi int) Option {
+	return options.New("WithPercentRange", func(c *config) error {
+		if lo < 1 || hi < lo {
+			return fmt.Errorf("bad range %d..%d", lo, hi)
+		}
+		c.lo, c.hi = lo, hi
+		return nil
+	})
+}
+
+// WithGCCPUTarget sets the share of the CPU time the collector should take,
+// between 0 and 1. Defaults to 0.05.
+func WithGCCPUTarget(share float64) Option {
+	return options.New("WithGCCPUTarget", func(c *config) error {
+		if !(share > 0 && share < 1) {
+			return fmt.Errorf("target %g outside (0, 1)", share)
+		}
+		c.target = share
+		return nil
+	})
+}
+
+// WithMemoryLimit sets the runtime's soft memory limit to bytes while the
+// tuner runs, and keeps the GC percent low enough that the heap goal of
+// the live heap stays under it.
+func WithMemoryLimit(bytes int64) Option {
+	return options.New("WithMemoryLimit", func(c *config) error {
+		if bytes <= 0 {
+			return errors.New("memory limit must be positive")
+		}
+		c.limit = bytes
+		return nil
+	})
+}
+
+// WithHistory sets how many steps Steps keeps. Defaults to 100.
+func WithHistory(n int) Option {
+	return options.New("WithHistory", func(c *config) error {
+		if n < 0 {
+			return errors.New("history must not be negative")
+		}
+		c.history = n
+		return nil
+	})
+}
+
+// WithOnStep is called after every change, on the tuner's goroutine.
+func WithOnStep(fn func(Step)) Option {
+	return options.New("WithOnStep", func(c *config) error {
+		c.onStep = fn
+		return nil
+	})
+}
+
+// reading is what the tuner reads every interval; all but live are
+// cumulative.
+type reading struct {
+	at     time.Time
+	gcs    uint32
+	pause  time.Duration
+	gcCPU  float64 // seconds
+	allCPU float64 // seconds
+	live   uint64
+}
+
+var cpuMetrics = []string{"/cpu/classes/gc/total:cpu-seconds", "/cpu/classes/total:cpu-seconds", "/gc/heap/live:bytes"}
+
+func read() reading {
+
+	s := memmon.Read()
+	ms := make([]metrics.Sample, len(cpuMetrics))
+	for i, name := range cpuMetrics {
+		ms[i].Name = name
+	}
+	metrics.Read(ms)
+
+	return reading{
+		at:     s.Time,
+		gcs:    s.NumGC,
+		pause:  s.PauseTotal,
+		gcCPU:  ms[0].Value.Float64(),
+		allCPU: ms[1].Value.Float64(),
+		live:   ms[2].Value.Uint64(),
+	}
+}
+
+// Tuner adjusts the GC percent in the background until Close.
+type Tuner struct {
+	cfg  config
+	read func() reading
+	set  func(int)
+	bg   *syncx.Background
+
+	prevPercent int
+	prevLimit   int64
+
+	mu      sync.Mutex
+	percent int
+	last    reading
+	steps   []Step
+}
+
+// Start starts tuning from the current GOGC, moved into the range if it is
+// outside it. Close puts back the settings Start found.
+func Start(opts ...Option) (*Tuner, error) {
+
+	cfg, err := options.Build(config{
+		interval: time.Second,
+		lo:       25,
+		hi:       800,
+		target:   0.05,
+		history:  100,
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tuner{
+		cfg:         cfg,
+		read:        read,
+		set:         func(p int) { debug.SetGCPercent(p) },
+		prevPercent: debug.SetGCPercent(100),
+		prevLimit:   debug.SetMemoryLimit(-1), // a negative limit only reads it
+	}
+	if cfg.limit > 0 {
+		debug.SetMemoryLimit(cfg.limit)
+	}
+	// with the collector off, start where GOGC does by default
+	current := t.prevPercent
+	if current < 0 {
+		current = 100
+	}
+	t.percent = min(max(current, cfg.lo), cfg.hi)
+	t.set(t.percent)
+	t.last = t.read()
+	t.bg = syncx.Go(t.loop)
+
+	return t, nil
+}
+
+// loop tunes until quit and then puts back the settings Start found.
+func (t *Tuner) loop(quit <-chan struct{}) {
+
+	defer debug.SetMemoryLimit(t.prevLimit)
+	defer debug.SetGCPercent(t.prevPercent)
+
+	ticker := time.NewTicker(t.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s, ok := t.step(); ok && t.cfg.onStep != nil {
+				t.cfg.onStep(s)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// step reads the runtime and changes the GC percent if it should.
+func (t *Tuner) step() (Step, bool) {
+
+	r := t.read()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev := t.last
+	t.last = r
+	if r.gcs == prev.gcs {
+		// the collector did not run, so there is nothing to judge
+		return Step{}, false
+	}
+
+	s := Step{
+		Time:  r.at,
+		From:  t.percent,
+		GCs:   r.gcs - prev.gcs,
+		Pause: r.pause - prev.pause,
+		Live:  r.live,
+	}
+	if cpu := r.allCPU - prev.allCPU; cpu > 0 {
+		s.GCCPU = (r.gcCPU - prev.gcCPU) / cpu
+	}
+	s.To, s.Reason = t.cfg.decide(t.percent, s.GCCPU, s.Live)
+	if s.To == s.From {
+		return Step{}, false
+	}
+
+	t.percent = s.To
+	t.set(s.To)
+	if t.cfg.history > 0 {
+		if len(t.steps) == t.cfg.history {
+			t.steps = slices.Delete(t.steps, 0, 1)
+		}
+		t.steps = append(t.steps, s)
+	}
+
+	return s, true
+}
+
+// decide returns the GC percent to use next, and why.
+func (c config) decide(percent int, gcCPU float64, live uint64) (int, string) {
+
+	switch {
+	case gcCPU > c.target:
+		next := min(2*percent, c.hi)
+		why := fmt.Sprintf("gc took %.1f%% of the cpu, above %.1f%%", 100*gcCPU, 100*c.target)
+		if ceiling, ok := c.ceiling(live); ok && next > ceiling {
+			next = max(ceiling, percent)
+			why += ", raised as far as the memory limit allows"
+		}
+		return next, why
+
+	case gcCPU < c.target/4:
+		next := max(percent*3/4, c.lo)
+		return next, fmt.Sprintf("gc took %.1f%% of the cpu, below %.1f%%", 100*gcCPU, 100*c.target/4)
+	}
+
+	return percent, ""
+}
+
+// ceiling returns the highest GC percent whose heap goal for live stays
+// under the memory limit, if there is a limit.
+func (c config) ceiling(live uint64) (int, bool) {
+
+	if c.limit <= 0 || live == 0 {
+		return 0, false
+	}
+	room := float64(c.limit)/float64(live) - 1
+
+	return int(math.Min(100*room, math.MaxInt32)), true
+}
+
+// Percent returns the GC percent the tuner last set.
+func (t *Tuner) Percent() int {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.percent
+}
+
+// Steps returns the changes made so far, oldest first.
+func (t *Tuner) Steps() []Step {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return slices.Clone(t.steps)
+}
+
+// Close stops tuning and restores the GC percent and memory limit Start
+// found.
+func (t *Tuner) Close() {
+
+	t.bg.Stop()
+}
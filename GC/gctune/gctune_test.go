@@ -0,0 +1,138 @@
+package gctune
+
+import (
+	"runtime/debug"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecide(t *testing.T) {
+
+	cfg := config{lo: 25, hi: 800, target: 0.05}
+	limited := cfg
+	limited.limit = 300 << 20
+
+	tests := []struct {
+		cfg     config
+		percent int
+		gcCPU   float64
+		live    uint64
+		want    int
+		why     string
+	}{
+		{cfg, 100, 0.20, 1 << 20, 200, "above 5.0%"},
+		{cfg, 600, 0.20, 1 << 20, 800, "above"},
+		{cfg, 800, 0.20, 1 << 20, 800, "above"},
+		{cfg, 100, 0.03, 1 << 20, 100, ""},
+		{cfg, 100, 0.01, 1 << 20, 75, "below 1.2%"},
+		{cfg, 30, 0.00, 1 << 20, 25, "below"},
+		{limited, 100, 0.20, 100 << 20, 200, "above 5.0%"},
+		{limited, 100, 0.20, 200 << 20, 100, "memory limit"},
+		{limited, 100, 0.20, 120 << 20, 150, "memory limit"},
+		{limited, 100, 0.20, 0, 200, "above 5.0%"},
+	}
+
+	for _, tt := range tests {
+		got, why := tt.cfg.decide(tt.percent, tt.gcCPU, tt.live)
+		if got != tt.want || !strings.Contains(why, tt.why) {
+			t.Errorf("Expected %d at %.2f with %d MB live to become %d (%q) but got %d (%q)",
+				tt.percent, tt.gcCPU, tt.live>>20, tt.want, tt.why, got, why)
+		}
+	}
+}
+
+func TestStep(t *testing.T) {
+
+	// every reading adds a second of CPU time, of which gc is the given share
+	var (
+		set   []int
+		now   reading
+		share float64
+		gcs   uint32
+	)
+	tn := &Tuner{
+		cfg:     config{lo: 25, hi: 800, target: 0.05, history: 2},
+		percent: 100,
+		set:     func(p int) { set = append(set, p) },
+		read: func() reading {
+			now.at = now.at.Add(time.Second)
+			now.gcs += gcs
+			now.pause += time.Duration(gcs) * time.Millisecond
+			now.allCPU++
+			now.gcCPU += share
+			now.live = 10 << 20
+			return now
+		},
+	}
+
+	for _, tt := range []struct {
+		gcs   uint32
+		share float64
+		want  int
+	}{
+		{5, 0.20, 200},
+		{5, 0.20, 400},
+		{0, 0.20, 400}, // no collections, no change
+		{5, 0.03, 400},
+		{5, 0.01, 300},
+	} {
+		gcs, share = tt.gcs, tt.share
+		tn.step()
+		if tn.Percent() != tt.want {
+			t.Errorf("Expected %d after %d collections at %.2f but got %d", tt.want, tt.gcs, tt.share, tn.Percent())
+		}
+	}
+
+	if want := []int{200, 400, 300}; !slices.Equal(set, want) {
+		t.Errorf("Expected the percent set to %v but got %v", want, set)
+	}
+	steps := tn.Steps()
+	if len(steps) != 2 || steps[1].From != 400 || steps[1].To != 300 || steps[1].GCs != 5 || steps[1].Pause != 5*time.Millisecond {
+		t.Errorf("Expected the last two steps kept but got %+v", steps)
+	}
+}
+
+func TestStartRestores(t *testing.T) {
+
+	defer debug.SetGCPercent(debug.SetGCPercent(1000))
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+
+	tn, err := Start(WithInterval(time.Hour), WithPercentRange(50, 400), WithMemoryLimit(1<<30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tn.Percent(); got != 400 {
+		t.Errorf("Expected GOGC 1000 moved into the range but got %d", got)
+	}
+	if got := debug.SetMemoryLimit(-1); got != 1<<30 {
+		t.Errorf("Expected the memory limit set but got %d", got)
+	}
+
+	tn.Close()
+	tn.Close()
+	if got := debug.SetGCPercent(1000); got != 1000 {
+		t.Errorf("Expected GOGC restored to 1000 but got %d", got)
+	}
+	if got := debug.SetMemoryLimit(-1); got == 1<<30 {
+		t.Errorf("Expected the memory limit restored but got %d", got)
+	}
+}
+
+func TestOptions(t *testing.T) {
+
+	for _, o := range []Option{
+		WithInterval(0),
+		WithPercentRange(0, 100),
+		WithPercentRange(200, 100),
+		WithGCCPUTarget(0),
+		WithGCCPUTarget(1),
+		WithMemoryLimit(0),
+		WithHistory(-1),
+	} {
+		if _, err := Start(o); err == nil {
+			t.Errorf("Expected %s to reject its argument", o.Name())
+		}
+	}
+}
@@ -5,12 +5,24 @@ import (
 	"runtime"
 	"runtime/debug"
 	"time"
+
+	"pacx/GC/memmon"
 )
 
 func main() {
 
 	debug.SetGCPercent(100) // default
 
+	mon, err := memmon.Start(
+		memmon.WithInterval(100*time.Millisecond),
+		memmon.WithHeapThreshold(32<<20),
+		memmon.WithGCThreshold(2),
+		memmon.WithOnAlert(func(a memmon.Alert) { fmt.Println(a) }),
+	)
+	if err != nil {
+		panic(err)
+	}
+
 	printmemstat("Start of the Program")
 
 	var memHog [][]byte
@@ -38,18 +50,25 @@ func main() {
 
 	printmemstat("After Sleep (GC May Have Run)")
 
+	mon.Close()
+	fmt.Println("Sampled every 100ms:")
+	for _, s := range mon.History() {
+		fmt.Println(s.Time.Format("15:04:05.000"), s)
+	}
+
 	fmt.Println("End of program")
 
 }
 
 func printmemstat(msg string) {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
+
+	s := memmon.Read()
 
 	fmt.Println("------------------------------------------------")
 	fmt.Println(msg)
-	fmt.Printf("HeapAlloc: %v KB\n", m.HeapAlloc/1024)
-	fmt.Printf("HeapSys: %v KB\n", m.HeapSys/1024)
-	fmt.Printf("NumGC: %v\n", m.NumGC)
-	fmt.Println("------------------------------------------------\n")
+	fmt.Printf("HeapAlloc: %v KB\n", s.HeapAlloc/1024)
+	fmt.Printf("HeapSys: %v KB\n", s.HeapSys/1024)
+	fmt.Printf("NumGC: %v\n", s.NumGC)
+	fmt.Println("------------------------------------------------")
+	fmt.Println()
 }
@@ -0,0 +1,291 @@
+// Package memmon watches the heap in the background, the way GC/main.go
+// prints it between steps. A Monitor reads runtime.MemStats every
+// interval, keeps the latest samples, and calls back when HeapAlloc goes
+// above a threshold or the GC runs more often than a threshold between
+// two samples:
+//
+//	mon, err := memmon.Start(
+//		memmon.WithInterval(100*time.Millisecond),
+//		memmon.WithHeapThreshold(64<<20),
+//		memmon.WithGCThreshold(5),
+//		memmon.WithOnAlert(func(a memmon.Alert) { log.Print(a) }),
+//	)
+//	defer mon.Close()
+//
+// ReadMemStats stops the world for a moment, so an interval much below a
+// millisecond costs more than it shows.
+package memmon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"pacx/options"
+	"pacx/sync/syncx"
+)
+
+// Sample is the part of runtime.MemStats the monitor keeps.
+type Sample struct {
+	Time        time.Time
+	HeapAlloc   uint64 // bytes of live and not yet swept heap objects
+	HeapSys     uint64 // bytes of heap obtained from the OS
+	HeapObjects uint64
+	TotalAlloc  uint64 // bytes allocated ever
+	Sys         uint64 // bytes obtained from the OS in total
+	NumGC       uint32
+	PauseTotal  time.Duration
+}
+
+func (s Sample) String() string {
+	return fmt.Sprintf("HeapAlloc: %d KB, HeapSys: %d KB, NumGC: %d", s.HeapAlloc/1024, s.HeapSys/1024, s.NumGC)
+}
+
+func fromMemStats(m *runtime.MemStats, at time.Time) Sample {
+	return Sample{
+		Time:        at,
+		HeapAlloc:   m.HeapAlloc,
+		HeapSys:     m.HeapSys,
+		HeapObjects: m.HeapObjects,
+		TotalAlloc:  m.TotalAlloc,
+		Sys:         m.Sys,
+		NumGC:       m.NumGC,
+		PauseTotal:  time.Duration(m.PauseTotalNs),
+	}
+}
+
+// Read returns a sample of the memory statistics now.
+func Read() Sample {
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	return fromMemStats(&m, time.Now())
+}
+
+// Kind is what an Alert is about.
+type Kind int
+
+const (
+	Heap Kind = iota // HeapAlloc went above its threshold
+	GC               // NumGC grew by at least its threshold in one interval
+)
+
+func (k Kind) String() string {
+
+	switch k {
+	case Heap:
+		return "heap"
+	case GC:
+		return "gc"
+	}
+
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// Alert is one threshold crossed.
+type Alert struct {
+	Kind      Kind
+	Value     uint64 // HeapAlloc, or the collections since the previous sample
+	Threshold uint64
+	Sample    Sample
+}
+
+func (a Alert) String() string {
+
+	if a.Kind == GC {
+		return fmt.Sprintf("memmon: %d collections in one interval, threshold %d (%s)", a.Value, a.Threshold, a.Sample)
+	}
+
+	return fmt.Sprintf("memmon: HeapAlloc %d KB above %d KB (%s)", a.Value/1024, a.Threshold/1024, a.Sample)
+}
+
+type config struct {
+	interval time.Duration
+	history  int
+	heap     uint64
+	gc       uint32
+	onAlert  func(Alert)
+}
+
+// Option configures a Monitor.
+type Option = options.Option[config]
+
+// WithInterval sets how often the statistics are read. Defaults to a
+// second.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithHistory sets how many samples History keeps. Defaults to 60.
+func WithHistory(n int) Option {
+	return options.New("WithHistory", func(c *config) error {
+		if n < 1 {
+			return errors.New("history must be at least 1")
+		}
+		c.history = n
+		return nil
+	})
+}
+
+// WithHeapThreshold alerts when HeapAlloc goes above bytes. It alerts
+// once per crossing: HeapAlloc has to come back down to bytes or below
+// before it alerts again.
+func WithHeapThreshold(bytes uint64) Option {
+	return options.New("WithHeapThreshold", func(c *config) error {
+		if bytes == 0 {
+			return errors.New("heap threshold must be positive")
+		}
+		c.heap = bytes
+		return nil
+	})
+}
+
+// WithGCThreshold alerts when the GC ran n times or more between two
+// samples, a sign that the program allocates faster than GOGC expects.
+func WithGCThreshold(n uint32) Option {
+	return options.New("WithGCThreshold", func(c *config) error {
+		if n == 0 {
+			return errors.New("gc threshold must be positive")
+		}
+		c.gc = n
+		return nil
+	})
+}
+
+// WithOnAlert is called for every alert, on the monitor's goroutine. By
+// default alerts are logged.
+func WithOnAlert(fn func(Alert)) Option {
+	return options.New("WithOnAlert", func(c *config) error {
+		c.onAlert = fn
+		return nil
+	})
+}
+
+// Monitor samples the memory statistics in the background until Close.
+type Monitor struct {
+	cfg  config
+	read func() Sample
+	bg   *syncx.Background
+
+	mu      sync.Mutex
+	samples []Sample // oldest first
+	above   bool     // HeapAlloc was above the heap threshold
+}
+
+// Start takes a first sample and starts monitoring.
+func Start(opts ...Option) (*Monitor, error) {
+
+	cfg, err := build(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := newMonitor(cfg, Read)
+	// a heap already above its threshold alerts now
+	for _, a := range m.sample() {
+		cfg.onAlert(a)
+	}
+	m.bg = syncx.Go(m.loop)
+
+	return m, nil
+}
+
+func build(opts ...Option) (config, error) {
+
+	cfg, err := options.Build(config{interval: time.Second, history: 60}, nil, opts...)
+	if err != nil {
+		return config{}, err
+	}
+	if cfg.onAlert == nil {
+		cfg.onAlert = func(a Alert) { log.Print(a) }
+	}
+
+	return cfg, nil
+}
+
+func newMonitor(cfg config, read func() Sample) *Monitor {
+	return &Monitor{cfg: cfg, read: read}
+}
+
+func (m *Monitor) loop(quit <-chan struct{}) {
+
+	ticker := time.NewTicker(m.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, a := range m.sample() {
+				m.cfg.onAlert(a)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// sample reads the statistics, adds them to the history and returns the
+// thresholds they crossed.
+func (m *Monitor) sample() []Alert {
+
+	s := m.read()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var alerts []Alert
+	if n := len(m.samples); n > 0 && m.cfg.gc > 0 {
+		if grew := s.NumGC - m.samples[n-1].NumGC; grew >= m.cfg.gc {
+			alerts = append(alerts, Alert{Kind: GC, Value: uint64(grew), Threshold: uint64(m.cfg.gc), Sample: s})
+		}
+	}
+	if m.cfg.heap > 0 {
+		above := s.HeapAlloc > m.cfg.heap
+		if above && !m.above {
+			alerts = append(alerts, Alert{Kind: Heap, Value: s.HeapAlloc, Threshold: m.cfg.heap, Sample: s})
+		}
+		m.above = above
+	}
+
+	if len(m.samples) == m.cfg.history {
+		m.samples = slices.Delete(m.samples, 0, 1)
+	}
+	m.samples = append(m.samples, s)
+
+	return alerts
+}
+
+// History returns the samples kept, oldest first.
+func (m *Monitor) History() []Sample {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.samples)
+}
+
+// Last returns the newest sample.
+func (m *Monitor) Last() Sample {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.samples[len(m.samples)-1]
+}
+
+// Close stops monitoring. The history stays readable.
+func (m *Monitor) Close() {
+
+	m.bg.Stop()
+}
@@ -0,0 +1,159 @@
+package memmon
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// scripted returns a monitor that reads the given heap sizes and GC counts
+// in turn, one per sample, and never samples on its own.
+func scripted(t *testing.T, heap []uint64, gcs []uint32, opts ...Option) *Monitor {
+
+	t.Helper()
+
+	cfg, err := build(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	return newMonitor(cfg, func() Sample {
+		s := Sample{Time: time.Unix(int64(i), 0), HeapAlloc: heap[i], NumGC: gcs[i]}
+		i++
+		return s
+	})
+}
+
+func kinds(alerts []Alert) []Kind {
+
+	var out []Kind
+	for _, a := range alerts {
+		out = append(out, a.Kind)
+	}
+
+	return out
+}
+
+func TestHeapThreshold(t *testing.T) {
+
+	heap := []uint64{10, 200, 300, 100, 50, 101, 400}
+	m := scripted(t, heap, make([]uint32, len(heap)), WithHeapThreshold(100))
+
+	var got []int
+	for i := range heap {
+		for _, a := range m.sample() {
+			if a.Kind != Heap || a.Value != heap[i] || a.Threshold != 100 {
+				t.Errorf("Expected a heap alert for %d but got %+v", heap[i], a)
+			}
+			got = append(got, i)
+		}
+	}
+	if want := []int{1, 5}; !slices.Equal(got, want) {
+		t.Errorf("Expected alerts at samples %v but got %v", want, got)
+	}
+}
+
+func TestGCThreshold(t *testing.T) {
+
+	gcs := []uint32{0, 1, 4, 4, 10}
+	m := scripted(t, make([]uint64, len(gcs)), gcs, WithGCThreshold(3))
+
+	var got []uint64
+	for range gcs {
+		for _, a := range m.sample() {
+			got = append(got, a.Value)
+		}
+	}
+	if want := []uint64{3, 6}; !slices.Equal(got, want) {
+		t.Errorf("Expected alerts for %v collections but got %v", want, got)
+	}
+}
+
+func TestBoth(t *testing.T) {
+
+	m := scripted(t, []uint64{0, 500}, []uint32{0, 9}, WithHeapThreshold(100), WithGCThreshold(1))
+	m.sample()
+	if got := kinds(m.sample()); !slices.Equal(got, []Kind{GC, Heap}) {
+		t.Errorf("Expected a GC and a heap alert but got %v", got)
+	}
+}
+
+func TestHistory(t *testing.T) {
+
+	heap := []uint64{1, 2, 3, 4, 5}
+	m := scripted(t, heap, make([]uint32, len(heap)), WithHistory(3))
+	for range heap {
+		m.sample()
+	}
+
+	var got []uint64
+	for _, s := range m.History() {
+		got = append(got, s.HeapAlloc)
+	}
+	if want := []uint64{3, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("Expected the newest samples %v but got %v", want, got)
+	}
+	if last := m.Last(); last.HeapAlloc != 5 {
+		t.Errorf("Expected the last sample to be 5 but got %d", last.HeapAlloc)
+	}
+}
+
+func TestStart(t *testing.T) {
+
+	alerts := make(chan Alert, 16)
+	m, err := Start(
+		WithInterval(time.Millisecond),
+		WithGCThreshold(1),
+		WithOnAlert(func(a Alert) {
+			select {
+			case alerts <- a:
+			default:
+			}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	runtime.GC()
+	select {
+	case a := <-alerts:
+		if a.Kind != GC || a.Value < 1 {
+			t.Errorf("Expected a GC alert but got %+v", a)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an alert for the forced collection")
+	}
+
+	m.Close()
+	m.Close()
+	h := m.History()
+	if len(h) < 2 || h[len(h)-1].NumGC <= h[0].NumGC || h[len(h)-1].Time.Before(h[0].Time) {
+		t.Errorf("Expected samples before and after the collection but got %v", h)
+	}
+}
+
+func TestOptions(t *testing.T) {
+
+	for _, o := range []Option{WithInterval(0), WithHistory(0), WithHeapThreshold(0), WithGCThreshold(0)} {
+		if _, err := Start(o); err == nil {
+			t.Errorf("Expected %s to reject its argument", o.Name())
+		}
+	}
+}
+
+func TestStartAbove(t *testing.T) {
+
+	var got []Alert
+	m, err := Start(WithInterval(time.Hour), WithHeapThreshold(1), WithOnAlert(func(a Alert) { got = append(got, a) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	if len(got) != 1 || got[0].Kind != Heap || got[0].Sample != m.Last() {
+		t.Errorf("Expected a heap alert from the first sample but got %v", got)
+	}
+}
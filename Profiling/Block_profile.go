@@ -1,10 +1,6 @@
 package main
 
 import (
-	"fmt"
-	"os"
-	"runtime"
-	"runtime/pprof"
 	"sync"
 	"time"
 )
@@ -17,18 +13,8 @@ func slowFunction() {
 	time.Sleep(3 * time.Second) // Simulate delay
 }
 
+// go run ./Profiling/profiler/cmd/profile -block Block_profile
 func main() {
-	// Enable block profiling
-	runtime.SetBlockProfileRate(1)
-
-	// Create profile file
-	f, err := os.Create("block.prof")
-	if err != nil {
-		fmt.Println("Could not create block profile:", err)
-		return
-	}
-	defer f.Close()
-
 	// Start multiple goroutines
 	var wg sync.WaitGroup
 	wg.Add(3)
@@ -47,7 +33,4 @@ func main() {
 	}()
 
 	wg.Wait()
-
-	// Write block profile data to file
-	pprof.Lookup("block").WriteTo(f, 0)
 }
@@ -1,10 +1,6 @@
 package main
 
 import (
-	"fmt"
-	"os"
-	"runtime"
-	"runtime/pprof"
 	"time"
 )
 
@@ -17,21 +13,8 @@ func blockedChannel() {
 	<-ch // Blocks here
 }
 
+// go run ./Profiling/profiler/cmd/profile -block channel_block
 func main() {
-	// Enable block profiling
-	runtime.SetBlockProfileRate(1)
-
-	// Create profile file
-	f, err := os.Create("blockk.prof")
-	if err != nil {
-		fmt.Println("Could not create block profile:", err)
-		return
-	}
-	defer f.Close()
-
 	// Run the function
 	blockedChannel()
-
-	// Write block profile data to file
-	pprof.Lookup("block").WriteTo(f, 0)
 }
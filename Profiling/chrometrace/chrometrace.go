@@ -0,0 +1,188 @@
+// Package chrometrace records what pools and pipelines did and writes it
+// in the Trace Event Format, so the timeline opens in chrome://tracing or
+// ui.perfetto.dev next to a go tool trace of the same run.
+//
+//	rec := chrometrace.New()
+//	p, _ := pool.New(pool.WithObserver(rec.Pool("resize")))
+//	stage := pipeline.Map("decode", decode, pipeline.WithRecorder(rec.Pipeline("ingest")))
+//	...
+//	rec.WriteTo(f)
+//
+// Every pool and pipeline is a process in the viewer. A pool has a thread
+// per worker with a slice per job, and the time each job spent queued is
+// drawn as an async slice above them. A pipeline has a row of async slices
+// per stage, since items of a stage overlap when it has several workers.
+package chrometrace
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"pacx/concurrency/pipeline"
+	"pacx/concurrency/pool"
+)
+
+// event is one entry of the traceEvents array. Times are microseconds
+// since the recorder was created.
+type event struct {
+	Name string         `json:"name"`
+	Cat  string         `json:"cat,omitempty"`
+	Ph   string         `json:"ph"`
+	Ts   float64        `json:"ts"`
+	Dur  float64        `json:"dur,omitempty"`
+	Pid  int            `json:"pid"`
+	Tid  int            `json:"tid"`
+	ID   uint64         `json:"id,omitempty"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// Recorder collects events from any number of pools and pipelines. It is
+// safe for concurrent use.
+type Recorder struct {
+	start time.Time
+
+	mu     sync.Mutex
+	events []event
+	procs  int
+	nextID uint64
+}
+
+// New returns a recorder whose timeline starts now.
+func New() *Recorder {
+	return &Recorder{start: time.Now(), events: []event{}}
+}
+
+func (r *Recorder) micros(t time.Time) float64 {
+	return float64(t.Sub(r.start).Nanoseconds()) / 1e3
+}
+
+// process adds a named process to the timeline and returns its pid.
+func (r *Recorder) process(name string) int {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.procs++
+	r.events = append(r.events, event{
+		Name: "process_name", Ph: "M", Pid: r.procs,
+		Args: map[string]any{"name": name},
+	})
+
+	return r.procs
+}
+
+// Pool returns an observer to pass to pool.WithObserver. name labels the
+// pool in the viewer.
+func (r *Recorder) Pool(name string) pool.Observer {
+	return &poolLane{r: r, pid: r.process("pool " + name), named: make(map[int]bool)}
+}
+
+type poolLane struct {
+	r     *Recorder
+	pid   int
+	named map[int]bool // workers with a thread_name event, guarded by r.mu
+}
+
+func (l *poolLane) ObserveJob(e pool.JobEvent) {
+
+	r := l.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tid := e.Worker + 1 // tid 0 holds the queue slices
+	if !l.named[e.Worker] {
+		l.named[e.Worker] = true
+		r.events = append(r.events, event{
+			Name: "thread_name", Ph: "M", Pid: l.pid, Tid: tid,
+			Args: map[string]any{"name": "worker " + strconv.Itoa(e.Worker)},
+		})
+	}
+
+	r.nextID++
+	queued := map[string]any{"job": e.ID}
+	r.events = append(r.events,
+		event{Name: "queued", Cat: "queue", Ph: "b", Ts: r.micros(e.Enqueued), Pid: l.pid, ID: r.nextID, Args: queued},
+		event{Name: "queued", Cat: "queue", Ph: "e", Ts: r.micros(e.Started), Pid: l.pid, ID: r.nextID},
+		event{
+			Name: "job", Cat: "job", Ph: "X",
+			Ts: r.micros(e.Started), Dur: micros(e.Finished.Sub(e.Started)),
+			Pid: l.pid, Tid: tid,
+			Args: map[string]any{"job": e.ID, "wait_us": micros(e.Started.Sub(e.Enqueued))},
+		},
+	)
+}
+
+// Pipeline returns a recorder to pass to pipeline.WithRecorder on every
+// stage of one pipeline. name labels the pipeline in the viewer.
+func (r *Recorder) Pipeline(name string) pipeline.Recorder {
+	return &pipelineLane{r: r, pid: r.process("pipeline " + name), stages: make(map[string]int)}
+}
+
+type pipelineLane struct {
+	r      *Recorder
+	pid    int
+	stages map[string]int // tid per stage, guarded by r.mu
+}
+
+// Record is called after the item was handed on, so the item started
+// latency plus wait ago.
+func (l *pipelineLane) Record(stage string, latency, wait time.Duration, emitted bool) {
+
+	now := time.Now()
+	started := now.Add(-latency - wait)
+
+	r := l.r
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tid, ok := l.stages[stage]
+	if !ok {
+		tid = len(l.stages)
+		l.stages[stage] = tid
+		r.events = append(r.events, event{
+			Name: "thread_name", Ph: "M", Pid: l.pid, Tid: tid,
+			Args: map[string]any{"name": stage},
+		})
+	}
+
+	r.nextID++
+	args := map[string]any{"emitted": emitted, "wait_us": micros(wait)}
+	r.events = append(r.events,
+		event{Name: stage, Cat: "stage", Ph: "b", Ts: r.micros(started), Pid: l.pid, Tid: tid, ID: r.nextID, Args: args},
+		event{Name: stage, Cat: "stage", Ph: "e", Ts: r.micros(now), Pid: l.pid, Tid: tid, ID: r.nextID},
+	)
+}
+
+// Len returns the number of events recorded so far.
+func (r *Recorder) Len() int {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.events)
+}
+
+// WriteTo writes the events as a JSON object trace.
+func (r *Recorder) WriteTo(w io.Writer) (int64, error) {
+
+	r.mu.Lock()
+	data, err := json.Marshal(struct {
+		TraceEvents     []event `json:"traceEvents"`
+		DisplayTimeUnit string  `json:"displayTimeUnit"`
+	}{r.events, "ms"})
+	r.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+
+	return int64(n), err
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d.Nanoseconds()) / 1e3
+}
@@ -0,0 +1,117 @@
+package chrometrace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"pacx/Profiling/chrometrace"
+	"pacx/concurrency/pipeline"
+	"pacx/concurrency/pool"
+)
+
+type traceFile struct {
+	TraceEvents []struct {
+		Name string         `json:"name"`
+		Ph   string         `json:"ph"`
+		Ts   float64        `json:"ts"`
+		Dur  float64        `json:"dur"`
+		Pid  int            `json:"pid"`
+		Tid  int            `json:"tid"`
+		Args map[string]any `json:"args"`
+	} `json:"traceEvents"`
+}
+
+func decode(t *testing.T, rec *chrometrace.Recorder) traceFile {
+
+	var buf bytes.Buffer
+	if _, err := rec.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var tf traceFile
+	if err := json.Unmarshal(buf.Bytes(), &tf); err != nil {
+		t.Fatalf("Expected valid JSON but got %v", err)
+	}
+
+	return tf
+}
+
+func TestPoolTimeline(t *testing.T) {
+
+	rec := chrometrace.New()
+	p, err := pool.New(pool.WithWorkers(2), pool.WithObserver(rec.Pool("test")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		p.Submit(context.Background(), func() { time.Sleep(time.Millisecond) })
+	}
+	p.Close()
+
+	jobs, begins, ends := 0, 0, 0
+	workers := make(map[int]bool)
+	for _, e := range decode(t, rec).TraceEvents {
+		switch e.Ph {
+		case "X":
+			jobs++
+			workers[e.Tid] = true
+			if e.Dur < 1000 {
+				t.Errorf("Expected a job to last at least 1000us but got %v", e.Dur)
+			}
+		case "b":
+			begins++
+		case "e":
+			ends++
+		}
+	}
+
+	if jobs != 6 || begins != 6 || ends != 6 {
+		t.Errorf("Expected 6 jobs with 6 queue slices but got %d jobs, %d begins, %d ends", jobs, begins, ends)
+	}
+	for tid := range workers {
+		if tid < 1 || tid > 2 {
+			t.Errorf("Expected worker threads 1 and 2 but got %d", tid)
+		}
+	}
+}
+
+func TestPipelineTimeline(t *testing.T) {
+
+	rec := chrometrace.New()
+	lane := rec.Pipeline("numbers")
+
+	ctx := context.Background()
+	double := pipeline.Map("double", func(v int) int { return v * 2 }, pipeline.WithRecorder(lane))
+	odd := pipeline.Filter("odd", func(v int) bool { return v%4 == 2 }, pipeline.WithRecorder(lane))
+	pipeline.Collect(ctx, pipeline.Then(double, odd)(ctx, pipeline.Source(ctx, []int{1, 2, 3, 4})))
+
+	stages := make(map[string]int)
+	threads := make(map[string]bool)
+	for _, e := range decode(t, rec).TraceEvents {
+		switch e.Ph {
+		case "b":
+			stages[e.Name]++
+		case "M":
+			if e.Name == "thread_name" {
+				threads[e.Args["name"].(string)] = true
+			}
+		}
+	}
+
+	if stages["double"] != 4 || stages["odd"] != 4 {
+		t.Errorf("Expected 4 items through each stage but got %v", stages)
+	}
+	if !threads["double"] || !threads["odd"] {
+		t.Errorf("Expected a named row per stage but got %v", threads)
+	}
+}
+
+func TestEmptyTraceIsValid(t *testing.T) {
+
+	if tf := decode(t, chrometrace.New()); tf.TraceEvents == nil {
+		t.Error("Expected an empty traceEvents array, not null")
+	}
+}
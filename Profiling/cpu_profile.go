@@ -1,24 +1,12 @@
 package main
 
 import (
-	"fmt"
-	"os"
-	"runtime/pprof"
 	"time"
 )
 
+// go run ./Profiling/profiler/cmd/profile -cpu cpu_profile
 func main() {
 
-	f, err := os.Create("cpu.pprof")
-	if err != nil {
-		fmt.Println("Error creating CPU profile: ", err)
-	}
-
-	defer f.Close()
-
-	pprof.StartCPUProfile(f)
-	defer pprof.StopCPUProfile()
-
 	slower()
 
 	time.Sleep(time.Second)
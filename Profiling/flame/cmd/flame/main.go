@@ -0,0 +1,141 @@
+// Command flame writes a folded stack file and an SVG flamegraph next to
+// each CPU profile it is given. Directories are searched for the files the
+// profiler package writes: cpu.pprof in a run directory and
+// cpu-<timestamp>-<program>.pprof from continuous profiling.
+//
+//	go run ./Profiling/flame/cmd/flame                # everything under ./profiles
+//	go run ./Profiling/flame/cmd/flame cpu.prof       # one file
+//	go run ./Profiling/flame/cmd/flame -index 0 dir   # samples instead of time
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"pacx/Profiling/flame"
+)
+
+func main() {
+
+	index := flag.Int("index", -1, "sample value to graph, -1 for the profile's default")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: flame [-index n] [profile or directory ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"profiles"}
+	}
+
+	var files []string
+	for _, arg := range args {
+		found, err := find(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		files = append(files, found...)
+	}
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "flame: no CPU profiles found")
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, f := range files {
+		if err := convert(f, *index); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", f, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// find returns path if it is a file, or the CPU profiles under it if it is
+// a directory.
+func find(path string) ([]string, error) {
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if !d.IsDir() && (name == "cpu.pprof" || strings.HasPrefix(name, "cpu-") && strings.HasSuffix(name, ".pprof")) {
+			files = append(files, p)
+		}
+		return nil
+	})
+
+	return files, err
+}
+
+// convert writes <profile>.folded and <profile>.svg, without the profile's
+// extension.
+func convert(path string, index int) error {
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	p, err := flame.Parse(in)
+	in.Close()
+	if err != nil {
+		return err
+	}
+
+	if index < 0 {
+		index = p.Default
+	}
+	stacks, err := p.Fold(index)
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	if err := write(base+".folded", func(f *os.File) error {
+		return flame.WriteFolded(f, stacks)
+	}); err != nil {
+		return err
+	}
+	title := fmt.Sprintf("%s (%s)", filepath.Base(path), p.Types[index])
+	if err := write(base+".svg", func(f *os.File) error {
+		return flame.WriteSVG(f, stacks, title)
+	}); err != nil {
+		return err
+	}
+
+	fmt.Println(base + ".svg")
+
+	return nil
+}
+
+func write(path string, fn func(f *os.File) error) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
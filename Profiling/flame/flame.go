@@ -0,0 +1,247 @@
+// Package flame turns pprof profiles into flamegraphs without go tool
+// pprof or the FlameGraph perl scripts. It reads the gzipped protobuf the
+// runtime writes, folds the samples into one line per unique stack
+//
+//	main.main;main.work;runtime.mallocgc 42
+//
+// and renders the folded stacks as a self-contained SVG.
+package flame
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"pacx/File-IO/lines"
+)
+
+// Profile is the part of a pprof profile a flamegraph is drawn from.
+type Profile struct {
+	Types   []string // sample value types, e.g. "samples", "cpu"
+	Default int      // index into Types that pprof shows by default
+	Samples []Sample
+}
+
+// Sample is one stack and its values, one per type.
+type Sample struct {
+	Stack  []string // function names, root first
+	Values []int64
+}
+
+// Parse reads a profile as written by runtime/pprof, gzipped or not.
+func Parse(r io.Reader) (*Profile, error) {
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		strs      []string
+		typeIdx   []uint64
+		rawSample [][]byte
+		rawLoc    [][]byte
+		funcs     = make(map[uint64]uint64) // function id -> name index
+		defIdx    uint64
+		hasDefIdx bool
+	)
+	err = fields(data, func(f field) error {
+		switch f.tag {
+		case profileStringTable:
+			strs = append(strs, string(f.data))
+		case profileSampleType:
+			return fields(f.data, func(v field) error {
+				if v.tag == valueTypeType {
+					typeIdx = append(typeIdx, v.num)
+				}
+				return nil
+			})
+		case profileSample:
+			rawSample = append(rawSample, f.data)
+		case profileLocation:
+			rawLoc = append(rawLoc, f.data)
+		case profileFunction:
+			var id, name uint64
+			err := fields(f.data, func(v field) error {
+				switch v.tag {
+				case functionID:
+					id = v.num
+				case functionName:
+					name = v.num
+				}
+				return nil
+			})
+			funcs[id] = name
+			return err
+		case profileDefaultIndex:
+			defIdx, hasDefIdx = f.num, true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	str := func(i uint64) string {
+		if i < uint64(len(strs)) {
+			return strs[i]
+		}
+		return "?"
+	}
+
+	// a location holds one line per inlined call, innermost first
+	locs := make(map[uint64][]string)
+	for _, b := range rawLoc {
+		var id uint64
+		var names []string
+		err := fields(b, func(v field) error {
+			switch v.tag {
+			case locationID:
+				id = v.num
+			case locationLine:
+				return fields(v.data, func(l field) error {
+					if l.tag == lineFunction {
+						names = append(names, str(funcs[l.num]))
+					}
+					return nil
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		locs[id] = names
+	}
+
+	p := &Profile{Default: len(typeIdx) - 1}
+	for _, i := range typeIdx {
+		p.Types = append(p.Types, str(i))
+	}
+	if hasDefIdx {
+		for i, t := range typeIdx {
+			if t == defIdx {
+				p.Default = i
+			}
+		}
+	}
+
+	for _, b := range rawSample {
+		var ids, vals []uint64
+		err := fields(b, func(v field) error {
+			var err error
+			switch v.tag {
+			case sampleLocation:
+				ids, err = varints(ids, v)
+			case sampleValue:
+				vals, err = varints(vals, v)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// ids run leaf first; the stack runs root first
+		s := Sample{Values: make([]int64, len(vals))}
+		for i := len(ids) - 1; i >= 0; i-- {
+			names := locs[ids[i]]
+			for j := len(names) - 1; j >= 0; j-- {
+				s.Stack = append(s.Stack, names[j])
+			}
+		}
+		for i, v := range vals {
+			s.Values[i] = int64(v)
+		}
+		p.Samples = append(p.Samples, s)
+	}
+
+	return p, nil
+}
+
+// Stack is a unique stack and the total of one sample value over it.
+type Stack struct {
+	Frames []string // root first
+	Value  int64
+}
+
+// Fold merges the samples with the same stack, summing the value at
+// index, and returns the stacks sorted by frames. Stacks that add up to
+// zero are dropped.
+func (p *Profile) Fold(index int) ([]Stack, error) {
+
+	if index < 0 || index >= len(p.Types) {
+		return nil, fmt.Errorf("flame: no sample type %d", index)
+	}
+
+	totals := make(map[string]int64)
+	for _, s := range p.Samples {
+		if index < len(s.Values) {
+			totals[strings.Join(s.Stack, ";")] += s.Values[index]
+		}
+	}
+
+	stacks := make([]Stack, 0, len(totals))
+	for key, v := range totals {
+		if v != 0 {
+			stacks = append(stacks, Stack{Frames: strings.Split(key, ";"), Value: v})
+		}
+	}
+	slices.SortFunc(stacks, func(a, b Stack) int {
+		return slices.Compare(a.Frames, b.Frames)
+	})
+
+	return stacks, nil
+}
+
+// WriteFolded writes stacks in the folded format flamegraph.pl and
+// speedscope read.
+func WriteFolded(w io.Writer, stacks []Stack) error {
+
+	bw := bufio.NewWriter(w)
+	for _, s := range stacks {
+		fmt.Fprintf(bw, "%s %d\n", strings.Join(s.Frames, ";"), s.Value)
+	}
+
+	return bw.Flush()
+}
+
+// ReadFolded parses the output of WriteFolded.
+func ReadFolded(r io.Reader) ([]Stack, error) {
+
+	var stacks []Stack
+
+	for line, err := range lines.Lines(r, lines.WithMaxLineLength(1<<20)) {
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			return nil, errors.New("flame: folded line without a value")
+		}
+		var v int64
+		if _, err := fmt.Sscan(line[i+1:], &v); err != nil {
+			return nil, fmt.Errorf("flame: bad value in %q", line)
+		}
+		stacks = append(stacks, Stack{Frames: strings.Split(line[:i], ";"), Value: v})
+	}
+
+	return stacks, nil
+}
@@ -0,0 +1,107 @@
+package flame_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"testing"
+
+	"pacx/Profiling/flame"
+)
+
+// goroutineProfile returns a real profile whose stacks include the
+// running test.
+func goroutineProfile(t *testing.T) *flame.Profile {
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := flame.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Expected the profile to parse but got %v", err)
+	}
+
+	return p
+}
+
+func TestParseRuntimeProfile(t *testing.T) {
+
+	p := goroutineProfile(t)
+
+	if len(p.Types) != 1 || p.Types[0] != "goroutine" {
+		t.Fatalf("Expected a single goroutine value type but got %v", p.Types)
+	}
+
+	stacks, err := p.Fold(p.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, s := range stacks {
+		i := slices.Index(s.Frames, "testing.tRunner")
+		if i >= 0 && slices.Contains(s.Frames[i:], "pacx/Profiling/flame_test.TestParseRuntimeProfile") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a stack from tRunner down to this test in %v", stacks)
+	}
+}
+
+func TestFoldedRoundTrip(t *testing.T) {
+
+	in := []flame.Stack{
+		{Frames: []string{"main.main", "main.a"}, Value: 3},
+		{Frames: []string{"main.main", "main.b"}, Value: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := flame.WriteFolded(&buf, in); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "main.main;main.a 3\nmain.main;main.b 1\n"; buf.String() != expected {
+		t.Errorf("Expected %q but got %q", expected, buf.String())
+	}
+
+	out, err := flame.ReadFolded(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 2 || !slices.Equal(out[0].Frames, in[0].Frames) || out[1].Value != 1 {
+		t.Errorf("Expected %v back but got %v", in, out)
+	}
+}
+
+func TestSVGIsWellFormed(t *testing.T) {
+
+	stacks := []flame.Stack{
+		{Frames: []string{"main.main", "main.<work>"}, Value: 3},
+		{Frames: []string{"main.main"}, Value: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := flame.WriteSVG(&buf, stacks, "cpu & more"); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := xml.NewDecoder(&buf)
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Expected valid XML but got %v", err)
+		}
+	}
+
+	var out bytes.Buffer
+	flame.WriteSVG(&out, stacks, "")
+	if !strings.Contains(out.String(), "main.&lt;work&gt; (3, 75.00%)") {
+		t.Errorf("Expected the escaped frame with its share in the SVG")
+	}
+}
@@ -0,0 +1,111 @@
+package flame
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The parts of profile.proto that a flamegraph needs. Field numbers are
+// from github.com/google/pprof/proto/profile.proto.
+const (
+	profileSampleType   = 1
+	profileSample       = 2
+	profileLocation     = 4
+	profileFunction     = 5
+	profileStringTable  = 6
+	profileDefaultIndex = 14
+
+	valueTypeType = 1
+
+	sampleLocation = 1
+	sampleValue    = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunction = 1
+
+	functionID   = 1
+	functionName = 2
+)
+
+var errTruncated = errors.New("flame: truncated profile")
+
+// field is one decoded protobuf field. Varints are in num, length
+// delimited fields in data.
+type field struct {
+	tag  int
+	wire int
+	num  uint64
+	data []byte
+}
+
+// fields calls fn for each top level field in b.
+func fields(b []byte, fn func(f field) error) error {
+
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+
+		f := field{tag: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case 0: // varint
+			f.num, n = binary.Uvarint(b)
+			if n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case 1: // fixed64
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.num = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case 2: // length delimited
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return errTruncated
+			}
+			f.data = b[n : n+int(l)]
+			b = b[n+int(l):]
+		case 5: // fixed32
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.num = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		default:
+			return errors.New("flame: unsupported wire type")
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// varints appends the values of a repeated integer field, which may be
+// packed or sent one value per field.
+func varints(dst []uint64, f field) ([]uint64, error) {
+
+	if f.wire == 0 {
+		return append(dst, f.num), nil
+	}
+
+	b := f.data
+	for len(b) > 0 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			return dst, errTruncated
+		}
+		dst = append(dst, v)
+		b = b[n:]
+	}
+
+	return dst, nil
+}
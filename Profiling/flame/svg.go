@@ -0,0 +1,142 @@
+package flame
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	svgWidth    = 1200
+	frameHeight = 16
+	padTop      = 30
+	padSide     = 10
+	minWidth    = 0.1 // frames narrower than this many pixels are skipped
+	charWidth   = 7   // rough width of a character at font size 12
+)
+
+// node is a frame in the merged call tree.
+type node struct {
+	name     string
+	value    int64
+	children []*node
+}
+
+func (n *node) child(name string) *node {
+
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+
+	c := &node{name: name}
+	n.children = append(n.children, c)
+
+	return c
+}
+
+func tree(stacks []Stack) (*node, int) {
+
+	root := &node{name: "all"}
+	depth := 0
+	for _, s := range stacks {
+		if s.Value <= 0 {
+			continue
+		}
+		root.value += s.Value
+		n := root
+		for _, f := range s.Frames {
+			n = n.child(f)
+			n.value += s.Value
+		}
+		depth = max(depth, len(s.Frames))
+	}
+
+	var sortChildren func(n *node)
+	sortChildren = func(n *node) {
+		slices.SortFunc(n.children, func(a, b *node) int {
+			return strings.Compare(a.name, b.name)
+		})
+		for _, c := range n.children {
+			sortChildren(c)
+		}
+	}
+	sortChildren(root)
+
+	return root, depth + 1
+}
+
+// WriteSVG draws stacks as a flamegraph with the root at the bottom and
+// children sorted by name, the way flamegraph.pl lays them out. Hovering a
+// frame shows its name and share of the total.
+func WriteSVG(w io.Writer, stacks []Stack, title string) error {
+
+	root, depth := tree(stacks)
+	height := padTop + depth*frameHeight + padSide
+	scale := 0.0
+	if root.value > 0 {
+		scale = float64(svgWidth-2*padSide) / float64(root.value)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<?xml version="1.0" standalone="no"?>
+<svg version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg">
+<style>text { font-family: monospace; font-size: 12px; } g:hover rect { stroke: black; }</style>
+<rect x="0" y="0" width="100%%" height="100%%" fill="#eeeeee"/>
+<text x="%d" y="20" text-anchor="middle" font-size="16">%s</text>
+`, svgWidth, height, svgWidth, height, svgWidth/2, html.EscapeString(title))
+
+	var draw func(n *node, x float64, level int)
+	draw = func(n *node, x float64, level int) {
+		width := float64(n.value) * scale
+		if width < minWidth {
+			return
+		}
+		y := height - padSide - (level+1)*frameHeight
+		pct := 100 * float64(n.value) / float64(root.value)
+		name := html.EscapeString(n.name)
+
+		fmt.Fprintf(bw, `<g><title>%s (%d, %.2f%%)</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2"/>`,
+			name, n.value, pct, x, y, width, frameHeight-1, color(n.name))
+		if chars := int(width / charWidth); chars >= 3 {
+			label := n.name
+			if len(label) > chars {
+				label = label[:chars-2] + ".."
+			}
+			fmt.Fprintf(bw, `<text x="%.1f" y="%d">%s</text>`, x+3, y+frameHeight-4, html.EscapeString(label))
+		}
+		bw.WriteString("</g>\n")
+
+		for _, c := range n.children {
+			draw(c, x, level+1)
+			x += float64(c.value) * scale
+		}
+	}
+	if root.value > 0 {
+		draw(root, padSide, 0)
+	}
+
+	bw.WriteString("</svg>\n")
+
+	return bw.Flush()
+}
+
+// color picks a warm colour from the frame name so the same function has
+// the same colour in every graph.
+func color(name string) string {
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	v := h.Sum32()
+
+	r := 205 + v%50
+	g := (v >> 8) % 230
+	b := (v >> 16) % 55
+
+	return fmt.Sprintf("rgb(%d,%d,%d)", r, g, b)
+}
@@ -2,22 +2,15 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"runtime/pprof"
 	"sync"
 	"time"
+
+	"pacx/sync/syncx"
 )
 
+// go run ./Profiling/profiler/cmd/profile -goroutine goroutine
 func main() {
 
-	f, err := os.Create("goroutine.prof")
-	if err != nil {
-		fmt.Println("issues in creating file")
-		return
-	}
-
-	defer f.Close()
-
 	var wg sync.WaitGroup
 	wg.Add(3)
 
@@ -25,8 +18,9 @@ func main() {
 	go goroutineFunction(&wg)
 	go goroutineFunction(&wg)
 
-	wg.Wait()
-	pprof.Lookup("goroutine").WriteTo(f, 0)
+	if !syncx.WaitTimeout(&wg, 5*time.Second) {
+		fmt.Println("goroutines did not finish, profiling them anyway")
+	}
 
 }
 
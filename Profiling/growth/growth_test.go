@@ -0,0 +1,87 @@
+package growth_test
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"pacx/Profiling/growth"
+)
+
+// mapkeys.Repeated with a tracked result
+func prepareKeys(site *growth.Site, hint, nKeys, repeatCount int) []string {
+
+	keys := growth.Make[string](site, hint)
+	for i := 0; i < nKeys; i++ {
+		keys.Append(strings.Repeat("#"+strconv.Itoa(i), repeatCount))
+	}
+
+	return keys.Done()
+}
+
+func TestAdviseHintForGrowingSlice(t *testing.T) {
+
+	var r growth.Registry
+	site := r.At("prepareKeys")
+	for i := 0; i < 3; i++ {
+		if keys := prepareKeys(site, 0, 1000, 2); len(keys) != 1000 || keys[999] != "#999#999" {
+			t.Fatalf("Expected 1000 keys ending in #999#999 but got %d", len(keys))
+		}
+	}
+
+	a := r.Report()
+	if len(a) != 1 {
+		t.Fatalf("Expected one site but got %+v", a)
+	}
+	if a[0].Uses != 3 || a[0].Grows < 3*10 || a[0].Suggest != 1000 || a[0].MaxLen != 1000 {
+		t.Errorf("Expected 3 uses with many reallocations and a hint of 1000 but got %+v", a[0])
+	}
+	// every reallocation copies the strings appended so far
+	if a[0].Copied < 3*1000*16 {
+		t.Errorf("Expected at least one copy of the final slice per use but got %d bytes", a[0].Copied)
+	}
+}
+
+func TestHintedSliceNeedsNoAdvice(t *testing.T) {
+
+	var r growth.Registry
+	prepareKeys(r.At("hinted"), 1000, 1000, 1)
+
+	a := r.Report()
+	if a[0].Grows != 0 || a[0].Copied != 0 || a[0].Suggest != 0 {
+		t.Errorf("Expected no reallocations and no suggestion but got %+v", a[0])
+	}
+
+	var buf bytes.Buffer
+	growth.Write(&buf, a)
+	if !strings.Contains(buf.String(), "hinted: ok") {
+		t.Errorf("Expected the site reported as ok but got %q", buf.String())
+	}
+}
+
+func TestConcurrentUses(t *testing.T) {
+
+	var r growth.Registry
+	site := r.At("data")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := growth.Make[int](site, 0)
+			for j := 0; j < 100*(i+1); j++ {
+				s.Append(j * j)
+			}
+			s.Done()
+		}()
+	}
+	wg.Wait()
+
+	a := r.Report()[0]
+	if a.Uses != 8 || a.MaxLen != 800 || a.MeanLen != 450 {
+		t.Errorf("Expected 8 uses with max 800 and mean 450 but got %+v", a)
+	}
+}
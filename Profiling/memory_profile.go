@@ -1,25 +1,12 @@
 package main
 
-import (
-	"fmt"
-	"os"
-	"runtime/pprof"
-)
+var sink []byte // keeps the allocation live until the heap is written
 
 func allocateMemory() {
-	_ = make([]byte, 50*1024*1024) // taking 10 mb
+	sink = make([]byte, 50*1024*1024) // taking 50 mb
 }
-func main() {
-
-	f, err := os.Create("mem.prof")
-	if err != nil {
-		fmt.Println("Error in creation.")
-	}
-	defer f.Close()
 
+// go run ./Profiling/profiler/cmd/profile -heap memory_profile
+func main() {
 	allocateMemory()
-
-	// Write memory profile
-	pprof.WriteHeapProfile(f)
-
 }
@@ -0,0 +1,254 @@
+// Command profile runs an example program with profiling turned on, so
+// the examples themselves only contain the workload.
+//
+//	go run ./Profiling/profiler/cmd/profile -cpu cpu_profile
+//	go run ./Profiling/profiler/cmd/profile -trace -block -mutex proff/trace
+//	go run ./Profiling/profiler/cmd/profile -heap -duration 10s path/to/example.go -- args
+//
+// The example is a single file with a main function. It is compiled with
+// an overlay that renames its main to exampleMain and adds a main that
+// starts the profiler, runs the example and writes the profiles when the
+// example returns, when -duration has passed or on an interrupt,
+// whichever comes first. Profiles land in a timestamped directory under
+// -dir, as with profiler.Start.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// roots are searched, in order, for an example given by bare name.
+var roots = []string{"Profiling", "proff", "."}
+
+// kinds maps each flag to the profiler.Kind constant it selects.
+var kinds = []struct{ flag, kind, usage string }{
+	{"cpu", "CPU", "collect a CPU profile"},
+	{"heap", "Heap", "write a heap profile at the end"},
+	{"allocs", "Allocs", "write an allocation profile at the end"},
+	{"block", "Block", "collect a block profile"},
+	{"mutex", "Mutex", "collect a mutex contention profile"},
+	{"goroutine", "Goroutine", "write a goroutine profile at the end"},
+	{"trace", "Trace", "collect an execution trace"},
+}
+
+func main() {
+
+	selected := make([]*bool, len(kinds))
+	for i, k := range kinds {
+		selected[i] = flag.Bool(k.flag, false, k.usage)
+	}
+	duration := flag.Duration("duration", 0, "stop profiling after this long, 0 to wait for the example")
+	dir := flag.String("dir", "profiles", "directory for the run directories")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: profile [flags] example [args ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var chosen []string
+	for i, k := range kinds {
+		if *selected[i] {
+			chosen = append(chosen, k.kind)
+		}
+	}
+	if len(chosen) == 0 {
+		chosen = []string{"CPU", "Heap"}
+	}
+
+	if err := run(flag.Arg(0), flag.Args()[1:], chosen, *duration, *dir); err != nil {
+		var exit *exec.ExitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.ExitCode())
+		}
+		fmt.Fprintln(os.Stderr, "profile:", err)
+		os.Exit(1)
+	}
+}
+
+func run(name string, args, chosen []string, d time.Duration, dir string) error {
+
+	example, err := resolve(name)
+	if err != nil {
+		return err
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return err
+	}
+
+	src, err := rename(example)
+	if err != nil {
+		return err
+	}
+	var hook bytes.Buffer
+	err = hookTmpl.Execute(&hook, struct {
+		Dir      string
+		Kinds    []string
+		Duration int64
+	}{dir, chosen, int64(d)})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.MkdirTemp("", "profile")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	hookPath := filepath.Join(filepath.Dir(example), "zz_profile_main.go")
+	if _, err := os.Stat(hookPath); err == nil {
+		return fmt.Errorf("%s already exists", hookPath)
+	}
+	overlay := map[string]map[string]string{"Replace": {
+		example:  filepath.Join(tmp, "example.go"),
+		hookPath: filepath.Join(tmp, "hook.go"),
+	}}
+	data, err := json.Marshal(overlay)
+	if err != nil {
+		return err
+	}
+	for path, content := range map[string][]byte{
+		"example.go":   src,
+		"hook.go":      hook.Bytes(),
+		"overlay.json": data,
+	} {
+		if err := os.WriteFile(filepath.Join(tmp, path), content, 0o644); err != nil {
+			return err
+		}
+	}
+
+	cmd := exec.Command("go", append([]string{"run", "-overlay", filepath.Join(tmp, "overlay.json"), example, hookPath}, args...)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// the example writes its profiles on an interrupt; stay alive until it
+	// has, instead of dying with it
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	return cmd.Run()
+}
+
+// resolve finds the example's file: a path to a .go file, a path without
+// the extension, or a bare name looked up in roots.
+func resolve(name string) (string, error) {
+
+	candidates := []string{name, name + ".go"}
+	if !strings.ContainsRune(name, filepath.Separator) {
+		for _, root := range roots {
+			candidates = append(candidates, filepath.Join(root, name+".go"))
+		}
+	}
+
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() && strings.HasSuffix(c, ".go") {
+			return filepath.Abs(c)
+		}
+	}
+
+	return "", fmt.Errorf("no example %q in %s", name, strings.Join(roots, ", "))
+}
+
+// rename returns the source of the example with its main function
+// renamed to exampleMain.
+func rename(path string) ([]byte, error) {
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	if f.Name.Name != "main" {
+		return nil, fmt.Errorf("%s is package %s, not main", path, f.Name.Name)
+	}
+
+	found := false
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == "main" {
+			fn.Name.Name = "exampleMain"
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("%s has no main function", path)
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+var hookTmpl = template.Must(template.New("hook").Parse(`// Code generated by the profile command. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"pacx/Profiling/profiler"
+)
+
+func main() {
+
+	p, err := profiler.Start(
+		profiler.WithDir({{printf "%q" .Dir}}),
+		profiler.WithProfiles({{range .Kinds}}profiler.{{.}}, {{end}}),
+	)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "profile:", err)
+		os.Exit(1)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		exampleMain()
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	var timeout <-chan time.Time
+	if d := time.Duration({{.Duration}}); d > 0 {
+		timeout = time.After(d)
+	}
+
+	select {
+	case <-done:
+	case <-interrupt:
+	case <-timeout:
+	}
+
+	if err := p.Stop(); err != nil {
+		fmt.Fprintln(os.Stderr, "profile:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "profile: wrote", p.Dir())
+}
+`))
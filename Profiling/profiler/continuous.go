@@ -0,0 +1,211 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"sort"
+	"strings"
+	"time"
+
+	"pacx/options"
+)
+
+// WithInterval sets how often Run takes a snapshot. Defaults to a minute.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithCPUDuration sets how long each of Run's CPU profiles samples for.
+// Defaults to ten seconds, or to the interval if that is shorter; a
+// duration set here may not exceed the interval.
+func WithCPUDuration(d time.Duration) Option {
+	return options.New("WithCPUDuration", func(c *config) error {
+		if d <= 0 {
+			return errors.New("cpu duration must be positive")
+		}
+		c.cpuDuration = d
+		return nil
+	})
+}
+
+// WithRetention keeps the newest n files of each profile kind and deletes
+// older ones. Defaults to 60.
+func WithRetention(n int) Option {
+	return options.New("WithRetention", func(c *config) error {
+		if n < 1 {
+			return errors.New("must retain at least one file")
+		}
+		c.retain = n
+		return nil
+	})
+}
+
+// Run profiles the program continuously until ctx is done, for soaking a
+// demo overnight. Every interval it writes one file per selected kind into
+// the WithDir directory, named
+//
+//	<kind>-<20060102-150405.000>-<program>[-<revision>].pprof
+//
+// and deletes the oldest files beyond WithRetention. The CPU profile covers
+// the first WithCPUDuration of each interval. Trace is not supported. Run
+// returns ctx.Err().
+func Run(ctx context.Context, opts ...Option) error {
+
+	cfg, err := options.Build(defaults(), validate, opts...)
+	if err != nil {
+		return err
+	}
+	if cfg.cpuDuration == 0 {
+		cfg.cpuDuration = min(10*time.Second, cfg.interval)
+	}
+
+	kinds := make(map[Kind]bool)
+	for _, k := range cfg.kinds {
+		if k == Trace {
+			return errors.New("profiler: Run does not support Trace")
+		}
+		kinds[k] = true
+	}
+	if err := os.MkdirAll(cfg.dir, 0o755); err != nil {
+		return err
+	}
+
+	if kinds[Block] {
+		runtime.SetBlockProfileRate(cfg.blockRate)
+		defer runtime.SetBlockProfileRate(0)
+	}
+	if kinds[Mutex] {
+		defer runtime.SetMutexProfileFraction(runtime.SetMutexProfileFraction(cfg.mutexFraction))
+	}
+
+	tag := buildTag()
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		stamp := time.Now().Format("20060102-150405.000")
+		name := func(k Kind) string {
+			return filepath.Join(cfg.dir, k.String()+"-"+stamp+"-"+tag+".pprof")
+		}
+
+		var written []string
+		if kinds[CPU] {
+			if err := sampleCPU(ctx, name(CPU), cfg.cpuDuration); err != nil {
+				return err
+			}
+			written = append(written, name(CPU))
+		}
+		// a CPU profile cut short is still worth keeping; skip the rest
+		if ctx.Err() == nil {
+			for _, k := range []Kind{Heap, Allocs, Block, Mutex, Goroutine} {
+				if kinds[k] {
+					if err := snapshot(k, name(k)); err != nil {
+						return err
+					}
+					written = append(written, name(k))
+				}
+			}
+		}
+		if cfg.uploadURL != "" {
+			for _, path := range written {
+				Upload(ctx, cfg.uploadURL, path)
+			}
+		}
+		for k := range kinds {
+			if err := rotate(cfg.dir, k, cfg.retain); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// sampleCPU writes a CPU profile covering d, or less if ctx ends first.
+func sampleCPU(ctx context.Context, path string, d time.Duration) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := pprof.StartCPUProfile(f); err != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(d):
+	case <-ctx.Done():
+	}
+	pprof.StopCPUProfile()
+
+	return nil
+}
+
+func snapshot(k Kind, path string) error {
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return pprof.Lookup(k.String()).WriteTo(f, 0)
+}
+
+// rotate deletes all but the newest keep files of kind k. The timestamp in
+// the name makes name order the same as age order.
+func rotate(dir string, k Kind, keep int) error {
+
+	files, err := filepath.Glob(filepath.Join(dir, k.String()+"-*.pprof"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var errs []error
+	for len(files) > keep {
+		errs = append(errs, os.Remove(files[0]))
+		files = files[1:]
+	}
+
+	return errors.Join(errs...)
+}
+
+// buildTag names the binary and, when built from a VCS checkout, the
+// revision, so profiles from different builds are told apart.
+func buildTag() string {
+
+	tag := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+				tag += "-" + s.Value[:7]
+			}
+		}
+	}
+
+	// keep it a single safe path element
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, tag)
+}
@@ -0,0 +1,75 @@
+package profiler_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/Profiling/profiler"
+)
+
+func TestRunRotates(t *testing.T) {
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
+	defer cancel()
+
+	err := profiler.Run(ctx,
+		profiler.WithDir(dir),
+		profiler.WithProfiles(profiler.CPU, profiler.Heap, profiler.Goroutine),
+		profiler.WithInterval(300*time.Millisecond),
+		profiler.WithCPUDuration(50*time.Millisecond),
+		profiler.WithRetention(2),
+	)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded but got %v", err)
+	}
+
+	for _, kind := range []string{"cpu", "heap", "goroutine"} {
+		files, _ := filepath.Glob(filepath.Join(dir, kind+"-*.pprof"))
+		if len(files) != 2 {
+			t.Errorf("Expected 2 %s files after rotation but got %v", kind, files)
+		}
+		for _, f := range files {
+			if !strings.Contains(filepath.Base(f), "profiler") {
+				t.Errorf("Expected the program name in %s", f)
+			}
+			if info, err := os.Stat(f); err != nil || info.Size() == 0 {
+				t.Errorf("Expected %s to be non-empty", f)
+			}
+		}
+	}
+}
+
+func TestRunRejectsTrace(t *testing.T) {
+
+	err := profiler.Run(context.Background(), profiler.WithDir(t.TempDir()), profiler.WithProfiles(profiler.Trace))
+	if err == nil {
+		t.Error("Expected Trace to be refused")
+	}
+}
+
+func TestRunCPUDurationFitsInterval(t *testing.T) {
+
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	// the default ten seconds is cut down to the interval
+	err := profiler.Run(ctx, profiler.WithDir(dir), profiler.WithInterval(100*time.Millisecond))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded but got %v", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "cpu-*.pprof")); len(files) == 0 {
+		t.Error("Expected CPU profiles with only the interval set")
+	}
+
+	err = profiler.Run(context.Background(), profiler.WithDir(dir),
+		profiler.WithInterval(100*time.Millisecond), profiler.WithCPUDuration(200*time.Millisecond))
+	if err == nil || err == context.DeadlineExceeded {
+		t.Errorf("Expected a cpu duration longer than the interval to be refused but got %v", err)
+	}
+}
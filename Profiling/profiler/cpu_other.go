@@ -0,0 +1,13 @@
+//go:build !unix
+
+package profiler
+
+import (
+	"errors"
+	"time"
+)
+
+// cpuTime is not available here; WithCPUThreshold makes Watch fail.
+func cpuTime() (time.Duration, error) {
+	return 0, errors.New("process CPU time not supported on this platform")
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package profiler
+
+import (
+	"syscall"
+	"time"
+)
+
+// cpuTime returns the user and system CPU time the process has used.
+func cpuTime() (time.Duration, error) {
+
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, err
+	}
+
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano()), nil
+}
@@ -0,0 +1,168 @@
+package profiler
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"pacx/options"
+)
+
+// WithProfileDir also serves the profiles Run and Watch wrote to dir, so
+// they can be fetched off the machine:
+//
+//	GET /debug/profiles/               the files, newest first, as JSON
+//	GET /debug/profiles/{name}         one file
+//	GET /debug/profiles/latest/{kind}  the newest file of a kind, e.g. cpu
+func WithProfileDir(dir string) ServerOption {
+	return options.New("WithProfileDir", func(c *serverConfig) error {
+		if dir == "" {
+			return errors.New("empty directory")
+		}
+		c.dir = dir
+		return nil
+	})
+}
+
+// ProfileFile is a profile written by Run or Watch.
+type ProfileFile struct {
+	Name string    `json:"name"`
+	Kind string    `json:"kind"`
+	Size int64     `json:"size"`
+	Time time.Time `json:"time"`
+}
+
+// Recent returns the profiles in dir, newest first.
+func Recent(dir string) ([]ProfileFile, error) {
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*-*.pprof"))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]ProfileFile, 0, len(paths))
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() {
+			continue // removed by rotation in between
+		}
+		kind, _, _ := strings.Cut(info.Name(), "-")
+		files = append(files, ProfileFile{Name: info.Name(), Kind: kind, Size: info.Size(), Time: info.ModTime()})
+	}
+	// names start with the timestamp after the kind, so within a kind the
+	// name orders them too
+	slices.SortFunc(files, func(a, b ProfileFile) int {
+		if c := b.Time.Compare(a.Time); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Name, a.Name)
+	})
+
+	return files, nil
+}
+
+func profilesHandler(dir string) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		files, err := Recent(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		name := strings.TrimPrefix(r.URL.Path, "/debug/profiles/")
+		if name == "" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(files)
+			return
+		}
+		if kind, ok := strings.CutPrefix(name, "latest/"); ok {
+			i := slices.IndexFunc(files, func(f ProfileFile) bool { return f.Kind == kind })
+			if i < 0 {
+				http.NotFound(w, r)
+				return
+			}
+			name = files[i].Name
+		}
+
+		// only hand out files that are listed, never a path of the client's
+		if !slices.ContainsFunc(files, func(f ProfileFile) bool { return f.Name == name }) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		http.ServeFile(w, r, filepath.Join(dir, name))
+	})
+}
+
+// Upload POSTs the profile at path to url, with its file name in the
+// X-Profile-Name header. Any status but 2xx is an error.
+func Upload(ctx context.Context, url, path string) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Profile-Name", filepath.Base(path))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("profiler: upload of %s: %s", filepath.Base(path), resp.Status)
+	}
+
+	return nil
+}
+
+// WithUploadURL makes Run POST every file it writes to url, see Upload. A
+// failed upload does not stop Run; the file stays on disk until rotated.
+func WithUploadURL(url string) Option {
+	return options.New("WithUploadURL", func(c *config) error {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("not an http url: %q", url)
+		}
+		c.uploadURL = url
+		return nil
+	})
+}
+
+// WithCaptureUpload makes Watch POST every capture to url, see Upload.
+// The outcome is in Capture.UploadErr.
+func WithCaptureUpload(url string) TriggerOption {
+	return options.New("WithCaptureUpload", func(c *triggerConfig) error {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("not an http url: %q", url)
+		}
+		c.uploadURL = url
+		return nil
+	})
+}
@@ -0,0 +1,118 @@
+package profiler_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/Profiling/profiler"
+)
+
+func TestProfileDownloads(t *testing.T) {
+
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Minute)
+	for name, mtime := range map[string]time.Time{
+		"cpu-20240101-000000.000-demo.pprof":  old,
+		"cpu-20240101-000100.000-demo.pprof":  old.Add(time.Second),
+		"heap-20240101-000100.000-demo.pprof": old.Add(2 * time.Second),
+	} {
+		path := filepath.Join(dir, name)
+		os.WriteFile(path, []byte(name), 0o644)
+		os.Chtimes(path, mtime, mtime)
+	}
+	os.WriteFile(filepath.Join(t.TempDir(), "secret"), []byte("no"), 0o644)
+
+	h, err := profiler.Handler(profiler.WithProfileDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+
+	var files []profiler.ProfileFile
+	if err := json.NewDecoder(get("/debug/profiles/").Body).Decode(&files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[0].Kind != "heap" || files[1].Name != "cpu-20240101-000100.000-demo.pprof" {
+		t.Errorf("Expected three files, newest first, but got %+v", files)
+	}
+
+	if rec := get("/debug/profiles/latest/cpu"); rec.Code != 200 || rec.Body.String() != "cpu-20240101-000100.000-demo.pprof" {
+		t.Errorf("Expected the newest cpu profile but got %d %q", rec.Code, rec.Body)
+	}
+	if rec := get("/debug/profiles/cpu-20240101-000000.000-demo.pprof"); rec.Code != 200 {
+		t.Errorf("Expected the older cpu profile by name but got %d", rec.Code)
+	}
+	for _, path := range []string{"/debug/profiles/latest/mutex", "/debug/profiles/..%2fsecret", "/debug/profiles/nope.pprof"} {
+		if rec := get(path); rec.Code != 404 {
+			t.Errorf("Expected 404 for %s but got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestWatchUploads(t *testing.T) {
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.Header.Get("X-Profile-Name")] = len(body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	captured := make(chan profiler.Capture, 1)
+	done := make(chan error)
+	go func() {
+		done <- profiler.Watch(ctx,
+			profiler.WithCaptureDir(t.TempDir()),
+			profiler.WithHeapThreshold(1),
+			profiler.WithSampleInterval(10*time.Millisecond),
+			profiler.WithCooldown(time.Hour),
+			profiler.WithCaptureUpload(srv.URL),
+			profiler.WithOnCapture(func(c profiler.Capture) { captured <- c }),
+		)
+	}()
+
+	c := <-captured
+	cancel()
+	<-done
+	if c.Err != nil || c.UploadErr != nil {
+		t.Fatalf("Expected a capture and an upload but got %v and %v", c.Err, c.UploadErr)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if n, ok := received[filepath.Base(c.Path)]; !ok || n == 0 {
+		t.Errorf("Expected %s to be uploaded but got %v", filepath.Base(c.Path), received)
+	}
+}
+
+func TestUploadRejected(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "full", http.StatusInsufficientStorage)
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cpu-x.pprof")
+	os.WriteFile(path, []byte("p"), 0o644)
+
+	if err := profiler.Upload(context.Background(), srv.URL, path); err == nil {
+		t.Error("Expected an error for a 507 response")
+	}
+}
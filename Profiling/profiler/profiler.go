@@ -0,0 +1,270 @@
+// Package profiler is the boilerplate of the Profiling/ and proff/ demos in
+// one place: it starts the selected profiles, sets the runtime rates they
+// need and, on Stop, writes every profile into a fresh timestamped
+// directory.
+//
+//	p, err := profiler.Start(profiler.WithProfiles(profiler.CPU, profiler.Block))
+//	if err != nil { ... }
+//	defer p.Stop()
+package profiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"time"
+
+	"pacx/options"
+)
+
+// Kind selects a profile.
+type Kind int
+
+const (
+	CPU       Kind = iota // sampled for the whole run
+	Heap                  // live objects at Stop
+	Allocs                // all allocations since the program started
+	Block                 // time spent blocked on channels and sync primitives
+	Mutex                 // contended mutexes
+	Goroutine             // stacks of all goroutines at Stop
+	Trace                 // execution trace for go tool trace
+)
+
+// file is where each kind is written inside the run directory.
+var file = map[Kind]string{
+	CPU:       "cpu.pprof",
+	Heap:      "heap.pprof",
+	Allocs:    "allocs.pprof",
+	Block:     "block.pprof",
+	Mutex:     "mutex.pprof",
+	Goroutine: "goroutine.pprof",
+	Trace:     "trace.out",
+}
+
+func (k Kind) String() string {
+	if name, ok := file[k]; ok {
+		return name[:len(name)-len(filepath.Ext(name))]
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// config holds the settings of Start and Run; Start ignores the fields
+// that only apply to continuous profiling.
+type config struct {
+	dir           string
+	kinds         []Kind
+	blockRate     int
+	mutexFraction int
+
+	// Run
+	interval    time.Duration
+	cpuDuration time.Duration // 0 until Run fits the default to the interval
+	retain      int
+	uploadURL   string
+}
+
+func defaults() config {
+	return config{
+		dir:           "profiles",
+		kinds:         []Kind{CPU, Heap},
+		blockRate:     1,
+		mutexFraction: 1,
+		interval:      time.Minute,
+		retain:        60,
+	}
+}
+
+// Option configures Start and Run.
+type Option = options.Option[config]
+
+// WithDir sets the directory the timestamped run directories are created
+// in. Defaults to "profiles".
+func WithDir(dir string) Option {
+	return options.New("WithDir", func(c *config) error {
+		if dir == "" {
+			return errors.New("empty directory")
+		}
+		c.dir = dir
+		return nil
+	})
+}
+
+// WithProfiles selects the profiles to collect. Defaults to CPU and Heap.
+func WithProfiles(kinds ...Kind) Option {
+	return options.New("WithProfiles", func(c *config) error {
+		for _, k := range kinds {
+			if _, ok := file[k]; !ok {
+				return fmt.Errorf("unknown profile %v", k)
+			}
+		}
+		c.kinds = kinds
+		return nil
+	})
+}
+
+// WithBlockRate sets runtime.SetBlockProfileRate while Block is collected.
+// Defaults to 1, recording every blocking event.
+func WithBlockRate(rate int) Option {
+	return options.New("WithBlockRate", func(c *config) error {
+		if rate < 1 {
+			return errors.New("block rate must be positive")
+		}
+		c.blockRate = rate
+		return nil
+	})
+}
+
+// WithMutexFraction sets runtime.SetMutexProfileFraction while Mutex is
+// collected. Defaults to 1, recording every contention event.
+func WithMutexFraction(fraction int) Option {
+	return options.New("WithMutexFraction", func(c *config) error {
+		if fraction < 1 {
+			return errors.New("mutex fraction must be positive")
+		}
+		c.mutexFraction = fraction
+		return nil
+	})
+}
+
+func validate(c config) error {
+	if len(c.kinds) == 0 {
+		return errors.New("no profiles selected")
+	}
+	if c.cpuDuration > c.interval {
+		return errors.New("cpu duration longer than the interval")
+	}
+	return nil
+}
+
+// Profiler is a running profiling session.
+type Profiler struct {
+	cfg       config
+	dir       string
+	kinds     map[Kind]bool
+	cpu       *os.File
+	trace     *os.File
+	prevMutex int
+	stopped   bool
+}
+
+// Start creates the run directory and starts the CPU profile and trace if
+// they were selected.
+func Start(opts ...Option) (*Profiler, error) {
+
+	cfg, err := options.Build(defaults(), validate, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(cfg.dir, time.Now().Format("20060102-150405.000"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	p := &Profiler{cfg: cfg, dir: dir, kinds: make(map[Kind]bool)}
+	for _, k := range cfg.kinds {
+		p.kinds[k] = true
+	}
+
+	if p.kinds[CPU] {
+		if p.cpu, err = p.create(CPU); err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(p.cpu); err != nil {
+			p.cpu.Close()
+			return nil, err
+		}
+	}
+	if p.kinds[Trace] {
+		if p.trace, err = p.create(Trace); err != nil {
+			p.abort()
+			return nil, err
+		}
+		if err := trace.Start(p.trace); err != nil {
+			p.trace.Close()
+			p.trace = nil
+			p.abort()
+			return nil, err
+		}
+	}
+	if p.kinds[Block] {
+		runtime.SetBlockProfileRate(cfg.blockRate)
+	}
+	if p.kinds[Mutex] {
+		p.prevMutex = runtime.SetMutexProfileFraction(cfg.mutexFraction)
+	}
+
+	return p, nil
+}
+
+func (p *Profiler) create(k Kind) (*os.File, error) {
+	return os.Create(filepath.Join(p.dir, file[k]))
+}
+
+// abort undoes a partial Start.
+func (p *Profiler) abort() {
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		p.cpu.Close()
+	}
+}
+
+// Dir returns the directory this run writes to.
+func (p *Profiler) Dir() string {
+	return p.dir
+}
+
+// Stop ends the CPU profile and trace, writes the snapshot profiles and
+// restores the runtime rates. Calling it again does nothing.
+func (p *Profiler) Stop() error {
+
+	if p.stopped {
+		return nil
+	}
+	p.stopped = true
+
+	var errs []error
+
+	if p.cpu != nil {
+		pprof.StopCPUProfile()
+		errs = append(errs, p.cpu.Close())
+	}
+	if p.trace != nil {
+		trace.Stop()
+		errs = append(errs, p.trace.Close())
+	}
+
+	if p.kinds[Heap] {
+		runtime.GC() // so the heap profile shows what is live right now
+	}
+	for _, k := range []Kind{Heap, Allocs, Block, Mutex, Goroutine} {
+		if p.kinds[k] {
+			errs = append(errs, p.write(k))
+		}
+	}
+
+	if p.kinds[Block] {
+		runtime.SetBlockProfileRate(0)
+	}
+	if p.kinds[Mutex] {
+		runtime.SetMutexProfileFraction(p.prevMutex)
+	}
+
+	return errors.Join(errs...)
+}
+
+// write saves the named runtime/pprof profile of kind k.
+func (p *Profiler) write(k Kind) error {
+
+	f, err := p.create(k)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return pprof.Lookup(k.String()).WriteTo(f, 0)
+}
@@ -0,0 +1,79 @@
+package profiler_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/Profiling/profiler"
+)
+
+func TestWritesAllProfiles(t *testing.T) {
+
+	p, err := profiler.Start(
+		profiler.WithDir(t.TempDir()),
+		profiler.WithProfiles(profiler.CPU, profiler.Heap, profiler.Allocs, profiler.Block,
+			profiler.Mutex, profiler.Goroutine, profiler.Trace),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// something for every profile to see
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				mu.Lock()
+				_ = make([]byte, 1024)
+				time.Sleep(10 * time.Microsecond)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := p.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Stop(); err != nil {
+		t.Errorf("Expected a second Stop to do nothing but got %v", err)
+	}
+
+	for _, name := range []string{"cpu.pprof", "heap.pprof", "allocs.pprof", "block.pprof",
+		"mutex.pprof", "goroutine.pprof", "trace.out"} {
+		info, err := os.Stat(filepath.Join(p.Dir(), name))
+		if err != nil || info.Size() == 0 {
+			t.Errorf("Expected a non-empty %s but got %v", name, err)
+		}
+	}
+}
+
+func TestOneCPUProfileAtATime(t *testing.T) {
+
+	dir := t.TempDir()
+	p, err := profiler.Start(profiler.WithDir(dir), profiler.WithProfiles(profiler.CPU))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Stop()
+
+	if _, err := profiler.Start(profiler.WithDir(dir), profiler.WithProfiles(profiler.CPU)); err == nil {
+		t.Error("Expected a second CPU profile to be refused")
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+
+	if _, err := profiler.Start(profiler.WithProfiles()); err == nil {
+		t.Error("Expected an error when no profile is selected")
+	}
+	if _, err := profiler.Start(profiler.WithProfiles(profiler.Kind(42))); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
@@ -0,0 +1,143 @@
+package profiler
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+
+	"pacx/options"
+)
+
+type serverConfig struct {
+	user, password string
+	dir            string
+}
+
+// ServerOption configures Handler and Serve.
+type ServerOption = options.Option[serverConfig]
+
+// WithBasicAuth requires HTTP basic auth with the given credentials.
+func WithBasicAuth(user, password string) ServerOption {
+	return options.New("WithBasicAuth", func(c *serverConfig) error {
+		if user == "" || password == "" {
+			return errors.New("user and password must not be empty")
+		}
+		c.user, c.password = user, password
+		return nil
+	})
+}
+
+// Handler returns the net/http/pprof endpoints under /debug/pprof/, and
+// with WithProfileDir the saved profiles under /debug/profiles/, for
+// mounting on a server the program already runs.
+func Handler(opts ...ServerOption) (http.Handler, error) {
+
+	cfg, err := options.Build(serverConfig{}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(cfg), nil
+}
+
+func handler(cfg serverConfig) http.Handler {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	if cfg.dir != "" {
+		mux.Handle("/debug/profiles/", profilesHandler(cfg.dir))
+	}
+
+	if cfg.user == "" {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user), []byte(cfg.user)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(password), []byte(cfg.password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="pprof"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Serve runs the pprof endpoints on addr until ctx is done. Without basic
+// auth it only listens on loopback addresses, since the profiles expose
+// the program's internals.
+//
+//	go profiler.Serve(ctx, "localhost:6060")
+//	go tool pprof http://localhost:6060/debug/pprof/heap
+func Serve(ctx context.Context, addr string, opts ...ServerOption) error {
+
+	cfg, err := options.Build(serverConfig{}, nil, opts...)
+	if err != nil {
+		return err
+	}
+	if cfg.user == "" && !loopback(addr) {
+		return errors.New("profiler: refusing to serve pprof on " + addr + " without basic auth")
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{Handler: handler(cfg)}
+	stop := context.AfterFunc(ctx, func() { srv.Close() })
+	defer stop()
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// ServeFromEnv is the opt-in switch for demos: it calls Serve when
+// PPROF_ADDR is set, with basic auth from PPROF_USER and PPROF_PASSWORD and
+// the profiles in PPROF_DIR if those are set too, and otherwise returns nil
+// right away.
+func ServeFromEnv(ctx context.Context) error {
+
+	addr := os.Getenv("PPROF_ADDR")
+	if addr == "" {
+		return nil
+	}
+
+	var opts []ServerOption
+	if user := os.Getenv("PPROF_USER"); user != "" {
+		opts = append(opts, WithBasicAuth(user, os.Getenv("PPROF_PASSWORD")))
+	}
+	if dir := os.Getenv("PPROF_DIR"); dir != "" {
+		opts = append(opts, WithProfileDir(dir))
+	}
+
+	return Serve(ctx, addr, opts...)
+}
+
+func loopback(addr string) bool {
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
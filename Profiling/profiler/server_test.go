@@ -0,0 +1,92 @@
+package profiler_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pacx/Profiling/profiler"
+)
+
+func TestHandlerBasicAuth(t *testing.T) {
+
+	h, err := profiler.Handler(profiler.WithBasicAuth("admin", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		user, password string
+		code           int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"admin", "wrong", http.StatusUnauthorized},
+		{"admin", "secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "/debug/pprof/", nil)
+		if tc.user != "" {
+			req.SetBasicAuth(tc.user, tc.password)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tc.code {
+			t.Errorf("Expected %d for %s:%s but got %d", tc.code, tc.user, tc.password, rec.Code)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+
+	// grab a free port
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- profiler.Serve(ctx, addr) }()
+
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		resp, err = http.Get("http://" + addr + "/debug/pprof/goroutine?debug=1")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 but got %d", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected a clean shutdown but got %v", err)
+	}
+}
+
+func TestServeRefusesPublicWithoutAuth(t *testing.T) {
+
+	if err := profiler.Serve(context.Background(), ":0"); err == nil {
+		t.Error("Expected serving on all interfaces without auth to be refused")
+	}
+}
+
+func TestServeFromEnvIsOptIn(t *testing.T) {
+
+	t.Setenv("PPROF_ADDR", "")
+	if err := profiler.ServeFromEnv(context.Background()); err != nil {
+		t.Errorf("Expected nil without PPROF_ADDR but got %v", err)
+	}
+}
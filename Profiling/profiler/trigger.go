@@ -0,0 +1,260 @@
+package profiler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"pacx/options"
+)
+
+type triggerConfig struct {
+	dir         string
+	interval    time.Duration
+	cpuPercent  float64
+	heapBytes   uint64
+	cpuDuration time.Duration
+	cooldown    time.Duration
+	retain      int
+	uploadURL   string
+	onCapture   func(Capture)
+}
+
+// TriggerOption configures Watch.
+type TriggerOption = options.Option[triggerConfig]
+
+// WithCPUThreshold captures a CPU profile when the process used more than
+// percent of one core over a sampling interval, as top counts it: 250 is
+// two and a half cores busy.
+func WithCPUThreshold(percent float64) TriggerOption {
+	return options.New("WithCPUThreshold", func(c *triggerConfig) error {
+		if percent <= 0 {
+			return errors.New("cpu threshold must be positive")
+		}
+		c.cpuPercent = percent
+		return nil
+	})
+}
+
+// WithHeapThreshold captures a heap profile when HeapAlloc is above bytes.
+func WithHeapThreshold(bytes uint64) TriggerOption {
+	return options.New("WithHeapThreshold", func(c *triggerConfig) error {
+		if bytes == 0 {
+			return errors.New("heap threshold must be positive")
+		}
+		c.heapBytes = bytes
+		return nil
+	})
+}
+
+// WithCaptureDir sets where captured profiles are written. Defaults to
+// "profiles".
+func WithCaptureDir(dir string) TriggerOption {
+	return options.New("WithCaptureDir", func(c *triggerConfig) error {
+		if dir == "" {
+			return errors.New("empty directory")
+		}
+		c.dir = dir
+		return nil
+	})
+}
+
+// WithSampleInterval sets how often CPU use and the heap are checked.
+// Defaults to a second. Reading HeapAlloc stops the world briefly, so keep
+// it well above a millisecond.
+func WithSampleInterval(d time.Duration) TriggerOption {
+	return options.New("WithSampleInterval", func(c *triggerConfig) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithCaptureCPU sets how long a triggered CPU profile samples for.
+// Defaults to ten seconds.
+func WithCaptureCPU(d time.Duration) TriggerOption {
+	return options.New("WithCaptureCPU", func(c *triggerConfig) error {
+		if d <= 0 {
+			return errors.New("cpu duration must be positive")
+		}
+		c.cpuDuration = d
+		return nil
+	})
+}
+
+// WithCooldown sets how long after a capture of one kind the next one of
+// that kind may start, so a program stuck above a threshold does not
+// write a profile every interval. Defaults to five minutes.
+func WithCooldown(d time.Duration) TriggerOption {
+	return options.New("WithCooldown", func(c *triggerConfig) error {
+		if d < 0 {
+			return errors.New("cooldown must not be negative")
+		}
+		c.cooldown = d
+		return nil
+	})
+}
+
+// WithCaptureRetention keeps the newest n captures of each kind. Defaults
+// to 10.
+func WithCaptureRetention(n int) TriggerOption {
+	return options.New("WithCaptureRetention", func(c *triggerConfig) error {
+		if n < 1 {
+			return errors.New("must retain at least one file")
+		}
+		c.retain = n
+		return nil
+	})
+}
+
+// WithOnCapture is called after every capture, for logging or alerting.
+// CPU captures finish on a goroutine of their own, so fn may be called
+// concurrently.
+func WithOnCapture(fn func(Capture)) TriggerOption {
+	return options.New("WithOnCapture", func(c *triggerConfig) error {
+		c.onCapture = fn
+		return nil
+	})
+}
+
+// Capture is one profile written because a threshold was crossed.
+type Capture struct {
+	Kind  Kind    // CPU or Heap
+	Path  string  // empty if Err is set
+	Value float64 // what crossed the threshold: CPU percent or heap bytes
+	Time  time.Time
+	Err   error
+
+	UploadErr error // with WithCaptureUpload, the outcome of the upload
+}
+
+// Watch samples the process's CPU use and HeapAlloc every interval until
+// ctx is done and captures a profile when one is above its threshold: a
+// CPU profile of the next WithCaptureCPU, or a heap profile at once. Files
+// are named like Run's, so the two can share a directory. At least one
+// threshold must be set. Watch returns ctx.Err().
+//
+// A CPU profile cannot be taken while another one runs, including one of
+// Start or Run; such a capture is reported with an error.
+func Watch(ctx context.Context, opts ...TriggerOption) error {
+
+	cfg, err := options.Build(triggerConfig{
+		dir:         "profiles",
+		interval:    time.Second,
+		cpuDuration: 10 * time.Second,
+		cooldown:    5 * time.Minute,
+		retain:      10,
+	}, func(c triggerConfig) error {
+		if c.cpuPercent == 0 && c.heapBytes == 0 {
+			return errors.New("no threshold set")
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(cfg.dir, 0o755); err != nil {
+		return err
+	}
+
+	var lastCPU time.Duration
+	if cfg.cpuPercent > 0 {
+		if lastCPU, err = cpuTime(); err != nil {
+			return err
+		}
+	}
+	lastWall := time.Now()
+
+	tag := buildTag()
+	name := func(k Kind, at time.Time) string {
+		return filepath.Join(cfg.dir, k.String()+"-"+at.Format("20060102-150405.000")+"-"+tag+".pprof")
+	}
+	report := func(c Capture) {
+		if c.Err == nil && cfg.uploadURL != "" {
+			c.UploadErr = Upload(ctx, cfg.uploadURL, c.Path)
+		}
+		if c.Err == nil {
+			c.Err = rotate(cfg.dir, c.Kind, cfg.retain)
+		}
+		if cfg.onCapture != nil {
+			cfg.onCapture(c)
+		}
+	}
+
+	// a kind may capture again once its next has passed
+	var (
+		mu      sync.Mutex // guards the CPU state, shared with the capture
+		nextCPU time.Time
+		running bool // a CPU capture is in progress
+		wg      sync.WaitGroup
+
+		nextHeap time.Time
+	)
+	defer wg.Wait()
+
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		now := time.Now()
+
+		if cfg.cpuPercent > 0 {
+			used, err := cpuTime()
+			if err != nil {
+				return err
+			}
+			percent := 100 * float64(used-lastCPU) / float64(now.Sub(lastWall))
+			lastCPU, lastWall = used, now
+
+			mu.Lock()
+			fire := percent > cfg.cpuPercent && !running && !now.Before(nextCPU)
+			if fire {
+				running = true
+			}
+			mu.Unlock()
+
+			if fire {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c := Capture{Kind: CPU, Path: name(CPU, now), Value: percent, Time: now}
+					if c.Err = sampleCPU(ctx, c.Path, cfg.cpuDuration); c.Err != nil {
+						c.Path = ""
+						os.Remove(name(CPU, now))
+					}
+					// the cooldown starts once the profile is done
+					mu.Lock()
+					running = false
+					nextCPU = time.Now().Add(cfg.cooldown)
+					mu.Unlock()
+					report(c)
+				}()
+			}
+		}
+
+		if cfg.heapBytes > 0 && !now.Before(nextHeap) {
+			var m runtime.MemStats
+			runtime.ReadMemStats(&m)
+			if m.HeapAlloc > cfg.heapBytes {
+				c := Capture{Kind: Heap, Path: name(Heap, now), Value: float64(m.HeapAlloc), Time: now}
+				if c.Err = snapshot(Heap, c.Path); c.Err != nil {
+					c.Path = ""
+				}
+				nextHeap = time.Now().Add(cfg.cooldown)
+				report(c)
+			}
+		}
+	}
+}
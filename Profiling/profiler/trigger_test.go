@@ -0,0 +1,95 @@
+package profiler_test
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/Profiling/profiler"
+)
+
+func TestWatchHeapCooldown(t *testing.T) {
+
+	var mu sync.Mutex
+	var captures []profiler.Capture
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	err := profiler.Watch(ctx,
+		profiler.WithCaptureDir(t.TempDir()),
+		profiler.WithHeapThreshold(1), // always above
+		profiler.WithSampleInterval(10*time.Millisecond),
+		profiler.WithCooldown(time.Hour),
+		profiler.WithOnCapture(func(c profiler.Capture) {
+			mu.Lock()
+			captures = append(captures, c)
+			mu.Unlock()
+		}),
+	)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded but got %v", err)
+	}
+
+	if len(captures) != 1 {
+		t.Fatalf("Expected one capture within the cooldown but got %d", len(captures))
+	}
+	c := captures[0]
+	if c.Kind != profiler.Heap || c.Err != nil || c.Value < 1 {
+		t.Errorf("Expected a heap capture but got %+v", c)
+	}
+	if info, err := os.Stat(c.Path); err != nil || info.Size() == 0 {
+		t.Errorf("Expected a non-empty profile at %s", c.Path)
+	}
+}
+
+func TestWatchCPU(t *testing.T) {
+
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		t.Skip("no process CPU time")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// keep a core busy until the test ends
+	go func() {
+		for ctx.Err() == nil {
+		}
+	}()
+
+	captured := make(chan profiler.Capture, 1)
+	done := make(chan error)
+	go func() {
+		done <- profiler.Watch(ctx,
+			profiler.WithCaptureDir(t.TempDir()),
+			profiler.WithCPUThreshold(20),
+			profiler.WithSampleInterval(20*time.Millisecond),
+			profiler.WithCaptureCPU(50*time.Millisecond),
+			profiler.WithCooldown(time.Hour),
+			profiler.WithOnCapture(func(c profiler.Capture) { captured <- c }),
+		)
+	}()
+
+	select {
+	case c := <-captured:
+		if c.Kind != profiler.CPU || c.Err != nil || c.Value <= 20 {
+			t.Errorf("Expected a CPU capture above 20%% but got %+v", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("Expected a CPU capture while a core is busy")
+	}
+
+	cancel()
+	<-done
+}
+
+func TestWatchNeedsThreshold(t *testing.T) {
+
+	if err := profiler.Watch(context.Background(), profiler.WithCaptureDir(t.TempDir())); err == nil {
+		t.Error("Expected an error without thresholds")
+	}
+}
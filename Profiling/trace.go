@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-	"os"
-	"runtime/trace"
 	"sync"
 	"time"
 )
@@ -17,19 +15,8 @@ func worker(id int, wg *sync.WaitGroup, ch chan int) {
 	}
 }
 
+// go run ./Profiling/profiler/cmd/profile -trace Profiling/trace
 func main() {
-	// Create a trace file
-	f, err := os.Create("trace.out")
-	if err != nil {
-		fmt.Println("Failed to create trace file:", err)
-		return
-	}
-	defer f.Close()
-
-	// Start tracing
-	trace.Start(f)
-	defer trace.Stop()
-
 	// Simulate workload
 	var wg sync.WaitGroup
 	ch := make(chan int, 10)
@@ -49,5 +36,4 @@ func main() {
 
 	wg.Wait()
 	close(ch)
-	fmt.Println("Tracing complete. Run 'go tool trace trace.out' to analyze.")
 }
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Label keys used by the pools, pipelines and orders, so CPU profiles can
+// be cut the same way everywhere:
+//
+//	go tool pprof -tagfocus=stage=square cpu.pprof
+//	go tool pprof -tags cpu.pprof
+const (
+	OrderLabel  = "order"
+	WorkerLabel = "worker"
+	StageLabel  = "stage"
+)
+
+// WithLabels returns a context carrying the pprof labels of ctx plus kv,
+// given as key, value pairs. The labels only show up in profiles once a
+// goroutine runs under them with Do.
+func WithLabels(ctx context.Context, kv ...string) context.Context {
+	return pprof.WithLabels(ctx, pprof.Labels(kv...))
+}
+
+// Do runs fn with the labels of ctx plus kv set on the current goroutine,
+// so samples taken while it runs carry them. Goroutines fn starts inherit
+// them too. The previous labels are restored when fn returns.
+func Do(ctx context.Context, fn func(context.Context), kv ...string) {
+	pprof.Do(ctx, pprof.Labels(kv...), fn)
+}
+
+// Labels returns the pprof labels carried by ctx.
+func Labels(ctx context.Context) map[string]string {
+
+	out := make(map[string]string)
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		out[key] = value
+		return true
+	})
+
+	return out
+}
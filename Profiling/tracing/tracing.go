@@ -0,0 +1,46 @@
+// Package tracing names the work that shows up in go tool trace. The
+// runtime/trace calls in proff/trace.go only mark where a trace starts and
+// stops; a task groups everything done for one request or order across
+// goroutines, and a region marks a span of one goroutine inside it, such
+// as a pipeline stage handling one item.
+//
+// Both are close to free while no trace is being recorded, so they can be
+// left in hot paths.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"runtime/trace"
+)
+
+// WithTask starts a task called name and returns a context carrying it.
+// Regions and logs using that context, on any goroutine, belong to the
+// task. Call end once the work is done.
+func WithTask(ctx context.Context, name string) (_ context.Context, end func()) {
+
+	ctx, task := trace.NewTask(ctx, name)
+
+	return ctx, task.End
+}
+
+// Region runs fn as a region called name of the task in ctx.
+func Region(ctx context.Context, name string, fn func()) {
+
+	if !trace.IsEnabled() {
+		fn()
+		return
+	}
+
+	trace.WithRegion(ctx, name, fn)
+}
+
+// Logf records a message under category for the task in ctx.
+func Logf(ctx context.Context, category, format string, args ...any) {
+
+	if !trace.IsEnabled() {
+		return
+	}
+
+	trace.Log(ctx, category, fmt.Sprintf(format, args...))
+}
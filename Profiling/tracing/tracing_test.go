@@ -0,0 +1,65 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+	"testing"
+
+	"pacx/Profiling/tracing"
+)
+
+func TestRegionRunsWithoutTrace(t *testing.T) {
+
+	ran := false
+	tracing.Region(context.Background(), "untraced", func() { ran = true })
+
+	if !ran {
+		t.Error("Expected fn to run while tracing is off")
+	}
+}
+
+func TestNamesInTrace(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("tracing already running: %v", err)
+	}
+
+	ctx, end := tracing.WithTask(context.Background(), "order-task")
+	ran := false
+	tracing.Region(ctx, "ship-region", func() { ran = true })
+	tracing.Logf(ctx, "order", "shipped %d", 7)
+	end()
+
+	trace.Stop()
+
+	if !ran {
+		t.Error("Expected fn to run while tracing")
+	}
+	// the names are stored as plain strings in the trace
+	for _, name := range []string{"order-task", "ship-region", "shipped 7"} {
+		if !bytes.Contains(buf.Bytes(), []byte(name)) {
+			t.Errorf("Expected %q in the trace", name)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+
+	ctx := tracing.WithLabels(context.Background(), tracing.OrderLabel, "42")
+
+	var profile bytes.Buffer
+	tracing.Do(ctx, func(ctx context.Context) {
+		if got := tracing.Labels(ctx); got[tracing.OrderLabel] != "42" || got[tracing.StageLabel] != "square" {
+			t.Errorf("Expected order 42 and stage square but got %v", got)
+		}
+		pprof.Lookup("goroutine").WriteTo(&profile, 1)
+	}, tracing.StageLabel, "square")
+
+	// the goroutine profile lists the labels set on each goroutine
+	if !bytes.Contains(profile.Bytes(), []byte(`"order":"42"`)) || !bytes.Contains(profile.Bytes(), []byte(`"stage":"square"`)) {
+		t.Errorf("Expected both labels on the goroutine but got\n%s", profile.String())
+	}
+}
@@ -31,6 +31,7 @@ func main() {
 	//fmt.Println(d)
 
 	// with interface
+	// (for arrays too large to unmarshal whole, see jsonx.Array)
 	b := []byte(`{"Name":"Wednesday","Age":6,"Parents":["Gomez","Morticia"]}`)
 
 	var f interface{}
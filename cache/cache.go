@@ -0,0 +1,314 @@
+// Package cache is an in-memory LRU cache where every entry has a cost and
+// the cache is bounded by total cost rather than entry count. Optionally a
+// TinyLFU filter decides whether a new entry is worth evicting others for,
+// which keeps one-off keys from flushing the popular ones out.
+package cache
+
+import (
+	"errors"
+	"hash/maphash"
+	"iter"
+	"sync"
+	"time"
+
+	"pacx/invariant"
+	"pacx/options"
+)
+
+type entry[K comparable, V any] struct {
+	key        K
+	val        V
+	cost       int64
+	prev, next *entry[K, V]
+}
+
+// Stats counts what happened to the cache so far.
+type Stats struct {
+	Hits       int64
+	Misses     int64
+	Evictions  int64 // entries pushed out to make room
+	Rejections int64 // Sets refused by admission or because the entry is too big
+}
+
+// HitRate returns Hits / (Hits + Misses), or 0 before the first Get.
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Cache is safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	mu      sync.Mutex
+	items   map[K]*entry[K, V]
+	head    entry[K, V] // sentinel: head.next is most recent, head.prev least
+	cost    int64
+	maxCost int64
+
+	hash   func(K) uint64 // seeded per cache; tests fix it
+	sketch *sketch        // nil without TinyLFU
+	stats  Stats
+}
+
+func defaults() config {
+	return config{
+		maxCost:       1024,
+		ttl:           time.Minute,
+		flushInterval: time.Second,
+		flushBatch:    100,
+	}
+}
+
+func validate(c config) error {
+	if c.earlyRefresh > c.ttl {
+		return errors.New("early refresh window longer than ttl")
+	}
+	return nil
+}
+
+// New returns an empty cache. WithMaxCost defaults to 1024.
+func New[K comparable, V any](opts ...Option) (*Cache[K, V], error) {
+
+	cfg, err := options.Build(defaults(), validate, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCache[K, V](cfg), nil
+}
+
+func newCache[K comparable, V any](cfg config) *Cache[K, V] {
+
+	c := &Cache[K, V]{
+		items:   make(map[K]*entry[K, V]),
+		maxCost: cfg.maxCost,
+	}
+	seed := maphash.MakeSeed()
+	c.hash = func(k K) uint64 { return maphash.Comparable(seed, k) }
+	c.head.next, c.head.prev = &c.head, &c.head
+	if cfg.counters > 0 {
+		c.sketch = newSketch(cfg.counters)
+	}
+
+	return c
+}
+
+// Get returns the value for k and marks it as recently used.
+func (c *Cache[K, V]) Get(k K) (V, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sketch != nil {
+		c.sketch.increment(c.hash(k))
+	}
+
+	e, ok := c.items[k]
+	if !ok {
+		c.stats.Misses++
+		var zero V
+		return zero, false
+	}
+	c.stats.Hits++
+	c.moveToFront(e)
+
+	return e.val, true
+}
+
+// peek returns the value for k without counting a hit or a miss and
+// without touching the recency order or the admission sketch.
+func (c *Cache[K, V]) peek(k K) (V, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[k]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	return e.val, true
+}
+
+// Set stores v under k with the given cost and reports whether it was
+// admitted. An existing entry is always updated in place.
+func (c *Cache[K, V]) Set(k K, v V, cost int64) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cost < 0 {
+		cost = 0
+	}
+	if cost > c.maxCost {
+		c.stats.Rejections++
+		return false
+	}
+
+	if e, ok := c.items[k]; ok {
+		c.cost += cost - e.cost
+		e.val, e.cost = v, cost
+		c.moveToFront(e)
+		c.evict(e)
+		return true
+	}
+
+	if c.sketch != nil && c.cost+cost > c.maxCost && !c.admit(k, cost) {
+		c.stats.Rejections++
+		return false
+	}
+
+	e := &entry[K, V]{key: k, val: v, cost: cost}
+	c.items[k] = e
+	c.cost += cost
+	c.pushFront(e)
+	c.evict(e)
+
+	return true
+}
+
+// admit compares the candidate against the entries that would have to go
+// to make room for it. mu must be held.
+func (c *Cache[K, V]) admit(k K, cost int64) bool {
+
+	freq := c.sketch.estimate(c.hash(k))
+	need := c.cost + cost - c.maxCost
+
+	for e := c.head.prev; need > 0 && e != &c.head; e = e.prev {
+		if c.sketch.estimate(c.hash(e.key)) >= freq {
+			return false
+		}
+		need -= e.cost
+	}
+
+	return true
+}
+
+// evict drops least recently used entries until the cost fits, never
+// evicting keep. mu must be held.
+func (c *Cache[K, V]) evict(keep *entry[K, V]) {
+
+	for c.cost > c.maxCost {
+		e := c.head.prev
+		if e == keep {
+			e = e.prev
+		}
+		c.remove(e)
+		c.stats.Evictions++
+	}
+
+	invariant.Checkf(c.cost >= 0 && c.cost <= c.maxCost,
+		"cache: cost %d outside [0, %d]", c.cost, c.maxCost)
+}
+
+// Delete removes k and reports whether it was present.
+func (c *Cache[K, V]) Delete(k K) bool {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[k]
+	if ok {
+		c.remove(e)
+	}
+
+	return ok
+}
+
+// Len returns the number of entries.
+func (c *Cache[K, V]) Len() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+// Cost returns the total cost of the entries.
+func (c *Cache[K, V]) Cost() int64 {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cost
+}
+
+// MaxCost returns the total cost the cache may hold.
+func (c *Cache[K, V]) MaxCost() int64 {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.maxCost
+}
+
+// SetMaxCost changes the capacity, evicting the least recently used
+// entries at once if the cache no longer fits.
+func (c *Cache[K, V]) SetMaxCost(n int64) error {
+
+	if n < 1 {
+		return errors.New("cache: max cost must be positive")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxCost = n
+	c.evict(nil)
+
+	return nil
+}
+
+// Stats returns the counters collected so far.
+func (c *Cache[K, V]) Stats() Stats {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+// All yields the entries from most to least recently used. It iterates over
+// a copy, so the cache can be used inside the loop; recency is not touched.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+
+		c.mu.Lock()
+		entries := make([]*entry[K, V], 0, len(c.items))
+		for e := c.head.next; e != &c.head; e = e.next {
+			entries = append(entries, &entry[K, V]{key: e.key, val: e.val})
+		}
+		c.mu.Unlock()
+
+		for _, e := range entries {
+			if !yield(e.key, e.val) {
+				return
+			}
+		}
+	}
+}
+
+func (c *Cache[K, V]) pushFront(e *entry[K, V]) {
+	e.prev, e.next = &c.head, c.head.next
+	c.head.next.prev = e
+	c.head.next = e
+}
+
+func (c *Cache[K, V]) unlink(e *entry[K, V]) {
+	e.prev.next = e.next
+	e.next.prev = e.prev
+	e.prev, e.next = nil, nil
+}
+
+func (c *Cache[K, V]) moveToFront(e *entry[K, V]) {
+	c.unlink(e)
+	c.pushFront(e)
+}
+
+func (c *Cache[K, V]) remove(e *entry[K, V]) {
+	c.unlink(e)
+	delete(c.items, e.key)
+	c.cost -= e.cost
+}
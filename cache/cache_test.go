@@ -0,0 +1,138 @@
+package cache_test
+
+import (
+	"fmt"
+	"testing"
+
+	"pacx/Benchmarking/keygen"
+	"pacx/cache"
+)
+
+func newCache[K comparable, V any](t testing.TB, opts ...cache.Option) *cache.Cache[K, V] {
+
+	c, err := cache.New[K, V](opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+func TestLRU(t *testing.T) {
+
+	c := newCache[string, int](t, cache.WithMaxCost(2))
+
+	c.Set("a", 1, 1)
+	c.Set("b", 2, 1)
+	c.Get("a") // b is now least recently used
+	c.Set("c", 3, 1)
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Expected a=1 but got %d, %v", v, ok)
+	}
+
+	var keys []string
+	for k := range c.All() {
+		keys = append(keys, k)
+	}
+	if fmt.Sprint(keys) != "[a c]" {
+		t.Errorf("Expected [a c] but got %v", keys)
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.Hits != 2 || s.Misses != 1 {
+		t.Errorf("Expected 1 eviction, 2 hits, 1 miss but got %+v", s)
+	}
+}
+
+func TestCost(t *testing.T) {
+
+	c := newCache[string, string](t, cache.WithMaxCost(10))
+
+	c.Set("small", "x", 2)
+	c.Set("medium", "y", 4)
+	c.Set("large", "z", 7) // both others have to go
+
+	if c.Len() != 1 || c.Cost() != 7 {
+		t.Errorf("Expected 1 entry costing 7 but got %d costing %d", c.Len(), c.Cost())
+	}
+	if c.Set("huge", "w", 11) {
+		t.Error("Expected an entry above max cost to be rejected")
+	}
+
+	// growing an existing entry evicts others, not itself
+	c.Set("a", "1", 3)
+	c.Set("a", "2", 9)
+	if v, ok := c.Get("a"); !ok || v != "2" || c.Cost() != 9 {
+		t.Errorf("Expected a=2 at cost 9 but got %q, %v at cost %d", v, ok, c.Cost())
+	}
+}
+
+func TestDelete(t *testing.T) {
+
+	c := newCache[string, int](t)
+	c.Set("a", 1, 5)
+
+	if !c.Delete("a") || c.Delete("a") {
+		t.Error("Expected the first delete to succeed and the second to fail")
+	}
+	if c.Len() != 0 || c.Cost() != 0 {
+		t.Errorf("Expected an empty cache but got %d entries costing %d", c.Len(), c.Cost())
+	}
+}
+
+func TestSetMaxCost(t *testing.T) {
+
+	c := newCache[int, int](t, cache.WithMaxCost(4))
+	for i := 0; i < 4; i++ {
+		c.Set(i, i, 1)
+	}
+
+	if err := c.SetMaxCost(2); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 2 || c.Cost() != 2 {
+		t.Errorf("Expected 2 entries after shrinking but got %d with cost %d", c.Len(), c.Cost())
+	}
+	if _, ok := c.Get(3); !ok {
+		t.Error("Expected the most recent entry to survive the shrink")
+	}
+	if err := c.SetMaxCost(0); err == nil {
+		t.Error("Expected an error for a zero capacity")
+	}
+}
+
+func TestBadOption(t *testing.T) {
+
+	if _, err := cache.New[string, int](cache.WithMaxCost(0)); err == nil {
+		t.Error("Expected an error for zero max cost")
+	}
+}
+
+// benchZipf replays a Zipf workload through a cache that loads on miss and
+// reports the hit rate next to the timings.
+func benchZipf(b *testing.B, opts ...cache.Option) {
+
+	keys := keygen.Zipf(42, 1.1, 100_000, 1<<16)
+	c := newCache[uint64, uint64](b, opts...)
+
+	i := 0
+	for b.Loop() {
+		k := keys[i%len(keys)]
+		if _, ok := c.Get(k); !ok {
+			c.Set(k, k, 1)
+		}
+		i++
+	}
+
+	b.ReportMetric(c.Stats().HitRate()*100, "hit%")
+}
+
+func BenchmarkZipfLRU(b *testing.B) {
+	benchZipf(b, cache.WithMaxCost(1000))
+}
+
+func BenchmarkZipfTinyLFU(b *testing.B) {
+	benchZipf(b, cache.WithMaxCost(1000), cache.WithTinyLFU(1000))
+}
@@ -0,0 +1,110 @@
+// Command httploader puts a cache.Loader in front of a slow HTTP backend.
+// A burst of concurrent requests for the same user costs one backend call,
+// an unknown user is remembered as missing for a while, and once the value
+// expires callers keep getting the stale copy while it is refreshed.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pacx/Benchmarking/dataset"
+	"pacx/cache"
+)
+
+var errNotFound = errors.New("user not found")
+
+func main() {
+
+	var backendCalls atomic.Int32
+
+	data, err := dataset.Generate(dataset.WithSeed(42))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendCalls.Add(1)
+		time.Sleep(50 * time.Millisecond) // a slow database behind it
+
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id < 1 || id > len(data.Customers) {
+			http.NotFound(w, r)
+			return
+		}
+		c := data.Customers[id-1]
+		fmt.Fprintf(w, "%s <%s> from %s at %s", c.Name, c.Email, c.City, time.Now().Format("15:04:05.000"))
+	}))
+	defer backend.Close()
+
+	load := func(ctx context.Context, id string) (string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL+"?id="+id, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusNotFound {
+			return "", errNotFound
+		}
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	users, err := cache.NewLoader(load,
+		cache.WithTTL(300*time.Millisecond),
+		cache.WithEarlyRefresh(100*time.Millisecond),
+		cache.WithStaleWhileRevalidate(time.Second),
+		cache.WithNegativeTTL(time.Second),
+	)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	ctx := context.Background()
+
+	// stampede: 100 requests, one backend call
+	var wg sync.WaitGroup
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		go func() {
+			defer wg.Done()
+			users.Get(ctx, "1")
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("100 concurrent gets -> %d backend call(s)\n", backendCalls.Load())
+
+	// negative caching: the 404 is remembered
+	for i := 0; i < 5; i++ {
+		users.Get(ctx, "ghost")
+	}
+	_, err = users.Get(ctx, "ghost")
+	fmt.Printf("ghost: %v, backend calls now %d\n", err, backendCalls.Load())
+
+	// stale while revalidate: served instantly, refreshed behind the scenes
+	time.Sleep(400 * time.Millisecond)
+	start := time.Now()
+	v, _ := users.Get(ctx, "1")
+	fmt.Printf("after expiry got %q in %v\n", v, time.Since(start).Round(time.Millisecond))
+
+	time.Sleep(100 * time.Millisecond)
+	v, _ = users.Get(ctx, "1")
+	fmt.Printf("after refresh got %q\n", v)
+
+	fmt.Printf("backend calls: %d, cache %+v\n", backendCalls.Load(), users.Stats())
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"pacx/concurrency/singleflight"
+	"pacx/options"
+)
+
+// LoadFunc fetches the value for a key from wherever it really lives.
+type LoadFunc[K comparable, V any] func(ctx context.Context, k K) (V, error)
+
+// record is what the Loader keeps per key. A failed load is cached as a
+// record with err set.
+type record[V any] struct {
+	val       V
+	err       error
+	refreshAt time.Time // early refresh starts here
+	expires   time.Time
+	staleTill time.Time // served while revalidating until here
+}
+
+// outcome is how a shared load ended for Get: with a record, or with the
+// *singleflight.PanicError of a load that panicked.
+type outcome[V any] struct {
+	r     record[V]
+	panic any
+}
+
+// Loader is a read-through cache: Get returns the cached value or loads it,
+// with at most one load per key in flight at any time.
+type Loader[K comparable, V any] struct {
+	cfg   config
+	load  LoadFunc[K, V]
+	cache *Cache[K, record[V]]
+	group singleflight.Group[record[V]]
+	now   func() time.Time
+}
+
+// NewLoader returns a Loader calling load on misses. It takes the Cache
+// options plus WithTTL, WithNegativeTTL, WithStaleWhileRevalidate and
+// WithEarlyRefresh.
+func NewLoader[K comparable, V any](load LoadFunc[K, V], opts ...Option) (*Loader[K, V], error) {
+
+	cfg, err := options.Build(defaults(), validate, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Loader[K, V]{
+		cfg:   cfg,
+		load:  load,
+		cache: newCache[K, record[V]](cfg),
+		now:   time.Now,
+	}, nil
+}
+
+// Get returns the value for k. A fresh value is returned as is; one that is
+// due for early refresh or within its stale window is returned while a
+// background load replaces it; anything else is loaded before returning.
+// Concurrent misses on the same key share one load. The load does not
+// end with the caller that started it: a caller whose ctx is done stops
+// waiting with ctx.Err(), and the load carries on for the others and still
+// fills the cache. If load panics, Get panics with a
+// *singleflight.PanicError.
+func (l *Loader[K, V]) Get(ctx context.Context, k K) (V, error) {
+
+	now := l.now()
+
+	if r, ok := l.cache.Get(k); ok {
+		switch {
+		case now.Before(r.refreshAt):
+			return r.val, r.err
+		case now.Before(r.staleTill):
+			l.refresh(ctx, k)
+			return r.val, r.err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		var zero V
+		return zero, err
+	}
+
+	shared := context.WithoutCancel(ctx)
+	done := make(chan outcome[V], 1)
+	go func() {
+		// a panicking load goes back to the caller rather than crash here
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome[V]{panic: p}
+			}
+		}()
+		r, _, _ := l.group.Do(l.key(k), func() (record[V], error) {
+			return l.fetch(shared, k), nil
+		})
+		done <- outcome[V]{r: r}
+	}()
+
+	select {
+	case o := <-done:
+		if o.panic != nil {
+			panic(o.panic)
+		}
+		return o.r.val, o.r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// refresh reloads k in the background unless a load is already running.
+// The load outlives the request that triggered it.
+func (l *Loader[K, V]) refresh(ctx context.Context, k K) {
+
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		// nobody waits for a refresh, so a panicking load is dropped and
+		// the old value stays until it goes stale
+		defer func() { _ = recover() }()
+		l.group.Do(l.key(k), func() (record[V], error) {
+			return l.fetch(ctx, k), nil
+		})
+	}()
+}
+
+// fetch loads k and stores the outcome. A failed reload does not replace a
+// good value that can still be served.
+func (l *Loader[K, V]) fetch(ctx context.Context, k K) record[V] {
+
+	v, err := l.load(ctx, k)
+	now := l.now()
+
+	if err != nil {
+		if old, ok := l.cache.peek(k); ok && old.err == nil && now.Before(old.staleTill) {
+			return old
+		}
+
+		r := record[V]{err: err, refreshAt: now.Add(l.cfg.negativeTTL)}
+		r.expires, r.staleTill = r.refreshAt, r.refreshAt
+		if l.cfg.negativeTTL > 0 {
+			l.cache.Set(k, r, 1)
+		} else {
+			l.cache.Delete(k)
+		}
+		return r
+	}
+
+	return l.store(k, v, now)
+}
+
+// store caches v as freshly loaded at now.
+func (l *Loader[K, V]) store(k K, v V, now time.Time) record[V] {
+
+	r := record[V]{val: v, expires: now.Add(l.cfg.ttl)}
+	r.refreshAt = r.expires
+	if l.cfg.earlyRefresh > 0 {
+		r.refreshAt = r.expires.Add(-rand.N(l.cfg.earlyRefresh))
+	}
+	r.staleTill = r.expires.Add(l.cfg.stale)
+	l.cache.Set(k, r, 1)
+
+	return r
+}
+
+// key turns k into a singleflight key; %#v keeps keys of different types
+// and values apart.
+func (l *Loader[K, V]) key(k K) string {
+	return fmt.Sprintf("%#v", k)
+}
+
+// Invalidate drops k so the next Get loads it again.
+func (l *Loader[K, V]) Invalidate(k K) {
+	l.cache.Delete(k)
+	l.group.Forget(l.key(k))
+}
+
+// Stats returns the statistics of the underlying cache.
+func (l *Loader[K, V]) Stats() Stats {
+	return l.cache.Stats()
+}
@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/concurrency/singleflight"
+)
+
+// clock is a fake time source that tests move by hand.
+type clock struct{ nanos atomic.Int64 }
+
+func (c *clock) now() time.Time          { return time.Unix(0, c.nanos.Load()) }
+func (c *clock) advance(d time.Duration) { c.nanos.Add(int64(d)) }
+
+func newTestLoader[V any](t *testing.T, load LoadFunc[string, V], opts ...Option) (*Loader[string, V], *clock) {
+
+	l, err := NewLoader(load, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &clock{}
+	l.now = c.now
+
+	return l, c
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the condition to become true")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// settle waits for the load of k in flight, if any, to finish and its
+// outcome to be stored. A load that started has entered the singleflight
+// group, so joining it is enough.
+func settle[V any](l *Loader[string, V], k string) {
+
+	defer func() { _ = recover() }() // a panicking load panics here too
+	l.group.Do(l.key(k), func() (record[V], error) { return record[V]{}, nil })
+}
+
+// joined reports how many callers wait on a load someone else started.
+func joined() int {
+
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	n := 0
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(g, "[select") && strings.Contains(g, "singleflight.(*Group[...]).DoContext(") {
+			n++
+		}
+	}
+
+	return n
+}
+
+var errNotFound = errors.New("not found")
+
+func TestLoaderNegativeTTL(t *testing.T) {
+
+	var loads atomic.Int32
+	l, clk := newTestLoader(t, func(ctx context.Context, k string) (int, error) {
+		loads.Add(1)
+		return 0, errNotFound
+	}, WithNegativeTTL(time.Second))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if _, err := l.Get(ctx, "missing"); err != errNotFound {
+			t.Fatalf("Expected %v but got %v", errNotFound, err)
+		}
+	}
+	if got := loads.Load(); got != 1 {
+		t.Errorf("Expected the error to be cached after 1 load but got %d", got)
+	}
+
+	clk.advance(2 * time.Second)
+	l.Get(ctx, "missing")
+	if got := loads.Load(); got != 2 {
+		t.Errorf("Expected a reload after the negative ttl but got %d loads", got)
+	}
+}
+
+func TestLoaderNoNegativeCaching(t *testing.T) {
+
+	var loads atomic.Int32
+	l, _ := newTestLoader(t, func(ctx context.Context, k string) (int, error) {
+		loads.Add(1)
+		return 0, errNotFound
+	})
+
+	l.Get(context.Background(), "missing")
+	l.Get(context.Background(), "missing")
+	if got := loads.Load(); got != 2 {
+		t.Errorf("Expected every Get to load but got %d loads", got)
+	}
+}
+
+func TestLoaderStaleWhileRevalidate(t *testing.T) {
+
+	var loads atomic.Int32
+	loaded := make(chan struct{}, 1)
+	l, clk := newTestLoader(t, func(ctx context.Context, k string) (int32, error) {
+		defer func() { loaded <- struct{}{} }()
+		return loads.Add(1), nil
+	}, WithTTL(time.Second), WithStaleWhileRevalidate(time.Second))
+
+	ctx := context.Background()
+	l.Get(ctx, "k")
+	<-loaded
+	clk.advance(1500 * time.Millisecond)
+
+	if v, _ := l.Get(ctx, "k"); v != 1 {
+		t.Errorf("Expected the stale value 1 but got %d", v)
+	}
+	<-loaded
+	settle(l, "k")
+	if v, _ := l.Get(ctx, "k"); v != 2 {
+		t.Errorf("Expected the revalidated value 2 but got %d", v)
+	}
+
+	// past the stale window the Get waits for a fresh value
+	clk.advance(3 * time.Second)
+	if v, _ := l.Get(ctx, "k"); v != 3 {
+		t.Errorf("Expected a synchronous reload returning 3 but got %d", v)
+	}
+}
+
+func TestLoaderFailedRefreshKeepsValue(t *testing.T) {
+
+	var fail atomic.Bool
+	loaded := make(chan struct{}, 1)
+	l, clk := newTestLoader(t, func(ctx context.Context, k string) (string, error) {
+		defer func() { loaded <- struct{}{} }()
+		if fail.Load() {
+			return "", errNotFound
+		}
+		return "good", nil
+	}, WithTTL(time.Second), WithStaleWhileRevalidate(time.Minute), WithNegativeTTL(time.Second))
+
+	ctx := context.Background()
+	l.Get(ctx, "k")
+	<-loaded
+	fail.Store(true)
+	clk.advance(2 * time.Second)
+
+	l.Get(ctx, "k") // triggers the failing refresh
+	<-loaded
+	settle(l, "k")
+
+	if v, err := l.Get(ctx, "k"); v != "good" || err != nil {
+		t.Errorf("Expected the old value to survive a failed refresh but got %q, %v", v, err)
+	}
+}
+
+func TestLoaderStampede(t *testing.T) {
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	l, _ := newTestLoader(t, func(ctx context.Context, k string) (string, error) {
+		loads.Add(1)
+		<-release
+		return "v", nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(50)
+	for i := 0; i < 50; i++ {
+		go func() {
+			defer wg.Done()
+			if v, err := l.Get(context.Background(), "hot"); v != "v" || err != nil {
+				t.Errorf("Expected v, nil but got %q, %v", v, err)
+			}
+		}()
+	}
+
+	waitFor(t, func() bool { return joined() == 49 })
+	close(release)
+	wg.Wait()
+
+	if got := loads.Load(); got != 1 {
+		t.Errorf("Expected 1 load for 50 concurrent misses but got %d", got)
+	}
+}
+
+func TestLoaderEarlyRefreshJitter(t *testing.T) {
+
+	var loads atomic.Int32
+	loaded := make(chan struct{}, 1)
+	l, clk := newTestLoader(t, func(ctx context.Context, k string) (int32, error) {
+		defer func() { loaded <- struct{}{} }()
+		return loads.Add(1), nil
+	}, WithTTL(10*time.Second), WithEarlyRefresh(5*time.Second))
+
+	seen := make(map[time.Time]bool)
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		r := l.fetch(context.Background(), k)
+		<-loaded
+		if r.refreshAt.Before(r.expires.Add(-5*time.Second)) || r.refreshAt.After(r.expires) {
+			t.Errorf("Expected refresh within 5s before expiry but got %v before", r.expires.Sub(r.refreshAt))
+		}
+		seen[r.refreshAt] = true
+	}
+	if len(seen) < 2 {
+		t.Error("Expected refresh times to be jittered")
+	}
+
+	// once due, the value is served while it is reloaded in the background
+	ctx := context.Background()
+	v, _ := l.Get(ctx, "a")
+	r, _ := l.cache.peek("a")
+	clk.advance(r.refreshAt.Sub(clk.now()))
+	if got, _ := l.Get(ctx, "a"); got != v {
+		t.Errorf("Expected %d while refreshing early but got %d", v, got)
+	}
+	<-loaded
+	settle(l, "a")
+	if got, _ := l.Get(ctx, "a"); got != loads.Load() {
+		t.Errorf("Expected the refreshed value %d but got %d", loads.Load(), got)
+	}
+}
+
+func TestLoaderCancelledCaller(t *testing.T) {
+
+	var loads atomic.Int32
+	release := make(chan struct{})
+	l, _ := newTestLoader(t, func(ctx context.Context, k string) (int, error) {
+		loads.Add(1)
+		<-release
+		return 7, ctx.Err()
+	})
+
+	// the first caller starts the load and gives up while it runs
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := l.Get(ctx, "k")
+		first <- err
+	}()
+	waitFor(t, func() bool { return loads.Load() == 1 })
+
+	second := make(chan int)
+	go func() {
+		v, _ := l.Get(context.Background(), "k")
+		second <- v
+	}()
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled caller to get its own error but got %v", err)
+	}
+
+	close(release)
+	if v := <-second; v != 7 {
+		t.Errorf("Expected the other caller to get the value but got %d", v)
+	}
+	if v, err := l.Get(context.Background(), "k"); v != 7 || err != nil || loads.Load() != 1 {
+		t.Errorf("Expected the value cached after one load but got %d, %v after %d loads", v, err, loads.Load())
+	}
+
+	// a caller that already gave up does not start a load
+	l.Invalidate("k")
+	if _, err := l.Get(ctx, "k"); !errors.Is(err, context.Canceled) || loads.Load() != 1 {
+		t.Errorf("Expected no load for a cancelled caller but got %v after %d loads", err, loads.Load())
+	}
+}
+
+func TestLoaderPanic(t *testing.T) {
+
+	var boom atomic.Bool
+	loaded := make(chan struct{}, 1)
+	l, clk := newTestLoader(t, func(ctx context.Context, k string) (int, error) {
+		defer func() { loaded <- struct{}{} }()
+		if boom.Load() {
+			panic("boom")
+		}
+		return 1, nil
+	}, WithTTL(time.Second), WithStaleWhileRevalidate(time.Minute))
+
+	get := func(k string) (v int, p any) {
+		defer func() { p = recover() }()
+		v, _ = l.Get(context.Background(), k)
+		return v, nil
+	}
+
+	boom.Store(true)
+	_, p := get("k")
+	<-loaded
+	if pe, ok := p.(*singleflight.PanicError); !ok || pe.Value != "boom" {
+		t.Fatalf("Expected Get to panic with the load's panic but got %v", p)
+	}
+
+	// a refresh that panics keeps the stale value
+	boom.Store(false)
+	get("k")
+	<-loaded
+	boom.Store(true)
+	clk.advance(2 * time.Second)
+	if v, p := get("k"); v != 1 || p != nil {
+		t.Errorf("Expected the stale value during the refresh but got %d, %v", v, p)
+	}
+	<-loaded
+	settle(l, "k")
+	if v, p := get("k"); v != 1 || p != nil {
+		t.Errorf("Expected the stale value after a panicking refresh but got %d, %v", v, p)
+	}
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"errors"
+	"time"
+
+	"pacx/options"
+)
+
+// config holds the settings of Cache and Loader; New ignores the loader
+// fields.
+type config struct {
+	maxCost  int64
+	counters int // 0 disables TinyLFU admission
+
+	// Loader
+	ttl          time.Duration
+	negativeTTL  time.Duration
+	stale        time.Duration
+	earlyRefresh time.Duration
+
+	// WriteBehind
+	flushInterval time.Duration
+	flushBatch    int
+	onFlushError  func(error)
+}
+
+// Option configures a Cache.
+type Option = options.Option[config]
+
+// WithMaxCost sets the total cost the cache may hold. With Set's cost at 1
+// it is simply the number of entries.
+func WithMaxCost(n int64) Option {
+	return options.New("WithMaxCost", func(c *config) error {
+		if n < 1 {
+			return errors.New("max cost must be positive")
+		}
+		c.maxCost = n
+		return nil
+	})
+}
+
+// WithTinyLFU puts a TinyLFU admission filter in front of the LRU: a new
+// entry only gets in if it was requested more often recently than the
+// entries it would evict. counters should be around the expected number of
+// distinct hot keys, typically the entry capacity.
+func WithTinyLFU(counters int) Option {
+	return options.New("WithTinyLFU", func(c *config) error {
+		if counters < 1 {
+			return errors.New("need at least one counter")
+		}
+		c.counters = counters
+		return nil
+	})
+}
+
+// WithTTL sets how long a loaded value is fresh. Defaults to one minute.
+func WithTTL(d time.Duration) Option {
+	return options.New("WithTTL", func(c *config) error {
+		if d <= 0 {
+			return errors.New("ttl must be positive")
+		}
+		c.ttl = d
+		return nil
+	})
+}
+
+// WithNegativeTTL caches load errors for d so a failing or missing key
+// does not hit the backend on every Get. Off by default.
+func WithNegativeTTL(d time.Duration) Option {
+	return options.New("WithNegativeTTL", func(c *config) error {
+		if d < 0 {
+			return errors.New("negative ttl cannot be negative")
+		}
+		c.negativeTTL = d
+		return nil
+	})
+}
+
+// WithStaleWhileRevalidate keeps serving a value for up to d after it
+// expired while a background load replaces it. Off by default.
+func WithStaleWhileRevalidate(d time.Duration) Option {
+	return options.New("WithStaleWhileRevalidate", func(c *config) error {
+		if d < 0 {
+			return errors.New("stale window cannot be negative")
+		}
+		c.stale = d
+		return nil
+	})
+}
+
+// WithEarlyRefresh starts a background reload at a random moment within d
+// before a value expires. The jitter spreads the reloads of keys that were
+// loaded together so they do not all expire, and stampede, at once.
+func WithEarlyRefresh(d time.Duration) Option {
+	return options.New("WithEarlyRefresh", func(c *config) error {
+		if d < 0 {
+			return errors.New("early refresh window cannot be negative")
+		}
+		c.earlyRefresh = d
+		return nil
+	})
+}
+
+// WithFlushInterval sets how often WriteBehind writes pending changes to
+// the repository. Defaults to one second.
+func WithFlushInterval(d time.Duration) Option {
+	return options.New("WithFlushInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("flush interval must be positive")
+		}
+		c.flushInterval = d
+		return nil
+	})
+}
+
+// WithFlushBatch makes WriteBehind flush early once n changes are pending.
+// Defaults to 100.
+func WithFlushBatch(n int) Option {
+	return options.New("WithFlushBatch", func(c *config) error {
+		if n < 1 {
+			return errors.New("flush batch must be at least 1")
+		}
+		c.flushBatch = n
+		return nil
+	})
+}
+
+// WithOnFlushError is called for every failed background write. The change
+// stays pending and is retried on the next flush.
+func WithOnFlushError(fn func(error)) Option {
+	return options.New("WithOnFlushError", func(c *config) error {
+		c.onFlushError = fn
+		return nil
+	})
+}
@@ -0,0 +1,289 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by a Repository for a key it does not have.
+var ErrNotFound = errors.New("cache: not found")
+
+// Repository is the storage a cache can sit in front of.
+type Repository[K comparable, V any] interface {
+	Get(ctx context.Context, k K) (V, error)
+	Put(ctx context.Context, k K, v V) error
+	Delete(ctx context.Context, k K) error
+}
+
+type writeMode int
+
+const (
+	readThrough writeMode = iota
+	writeThrough
+	writeBehind
+)
+
+// Cached is a Repository decorated with a Loader. Reads always go through
+// the cache; how writes reach the repository depends on the constructor.
+//
+// A load that was already running when a write happened can still store
+// the older value; it is replaced at the latest when it expires.
+type Cached[K comparable, V any] struct {
+	repo   Repository[K, V]
+	loader *Loader[K, V]
+	mode   writeMode
+	cfg    config
+
+	// write-behind
+	mu      sync.Mutex
+	pending map[K]change[V]
+	seq     uint64
+	flushMu sync.Mutex
+	kick    chan struct{}
+	quit    chan struct{}
+	done    chan struct{}
+	closed  bool
+}
+
+// change is a write waiting to be flushed.
+type change[V any] struct {
+	val     V
+	deleted bool
+	seq     uint64
+}
+
+// ReadThrough caches reads; writes go to repo and drop the cached value.
+func ReadThrough[K comparable, V any](repo Repository[K, V], opts ...Option) (*Cached[K, V], error) {
+	return newCached(repo, readThrough, opts)
+}
+
+// WriteThrough caches reads; writes go to repo and, once it accepted them,
+// into the cache.
+func WriteThrough[K comparable, V any](repo Repository[K, V], opts ...Option) (*Cached[K, V], error) {
+	return newCached(repo, writeThrough, opts)
+}
+
+// WriteBehind applies writes to the cache right away and flushes them to
+// repo in the background, every WithFlushInterval or once WithFlushBatch
+// changes are pending. Only the last write to a key is flushed. Close must
+// be called to flush what is left.
+func WriteBehind[K comparable, V any](repo Repository[K, V], opts ...Option) (*Cached[K, V], error) {
+	return newCached(repo, writeBehind, opts)
+}
+
+func newCached[K comparable, V any](repo Repository[K, V], mode writeMode, opts []Option) (*Cached[K, V], error) {
+
+	loader, err := NewLoader(repo.Get, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cached[K, V]{repo: repo, loader: loader, mode: mode, cfg: loader.cfg}
+
+	if mode == writeBehind {
+		c.pending = make(map[K]change[V])
+		c.kick = make(chan struct{}, 1)
+		c.quit = make(chan struct{})
+		c.done = make(chan struct{})
+		go c.flushLoop()
+	}
+
+	return c, nil
+}
+
+// Get returns the value for k, loading it from the repository on a miss.
+func (c *Cached[K, V]) Get(ctx context.Context, k K) (V, error) {
+
+	if c.mode == writeBehind {
+		c.mu.Lock()
+		ch, ok := c.pending[k]
+		c.mu.Unlock()
+
+		if ok {
+			if ch.deleted {
+				var zero V
+				return zero, ErrNotFound
+			}
+			return ch.val, nil
+		}
+	}
+
+	return c.loader.Get(ctx, k)
+}
+
+// Put stores v under k.
+func (c *Cached[K, V]) Put(ctx context.Context, k K, v V) error {
+
+	switch c.mode {
+	case readThrough:
+		if err := c.repo.Put(ctx, k, v); err != nil {
+			return err
+		}
+		c.loader.Invalidate(k)
+	case writeThrough:
+		if err := c.repo.Put(ctx, k, v); err != nil {
+			c.loader.Invalidate(k)
+			return err
+		}
+		c.loader.store(k, v, c.loader.now())
+	case writeBehind:
+		// cache v only once it will be flushed
+		if err := c.enqueue(k, change[V]{val: v}); err != nil {
+			return err
+		}
+		c.loader.store(k, v, c.loader.now())
+	}
+
+	return nil
+}
+
+// Delete removes k.
+func (c *Cached[K, V]) Delete(ctx context.Context, k K) error {
+
+	if c.mode == writeBehind {
+		// the tombstone goes first, so a Get in between finds it rather
+		// than loading the row it deletes
+		if err := c.enqueue(k, change[V]{deleted: true}); err != nil {
+			return err
+		}
+		c.loader.Invalidate(k)
+		return nil
+	}
+
+	err := c.repo.Delete(ctx, k)
+	c.loader.Invalidate(k)
+
+	return err
+}
+
+func (c *Cached[K, V]) enqueue(k K, ch change[V]) error {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return errors.New("cache: write-behind repository is closed")
+	}
+	c.seq++
+	ch.seq = c.seq
+	c.pending[k] = ch
+	full := len(c.pending) >= c.cfg.flushBatch
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.kick <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Pending returns the number of changes not yet flushed.
+func (c *Cached[K, V]) Pending() int {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.pending)
+}
+
+// Flush writes the pending changes to the repository now. A change that
+// fails stays pending. It is a no-op outside write-behind mode.
+func (c *Cached[K, V]) Flush(ctx context.Context) error {
+
+	if c.mode != writeBehind {
+		return nil
+	}
+
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	batch := make(map[K]change[V], len(c.pending))
+	for k, ch := range c.pending {
+		batch[k] = ch
+	}
+	c.mu.Unlock()
+
+	var errs []error
+	for k, ch := range batch {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		var err error
+		if ch.deleted {
+			// drop what a load that was already running cached meanwhile
+			if err = c.repo.Delete(ctx, k); err == nil {
+				c.loader.Invalidate(k)
+			}
+		} else {
+			err = c.repo.Put(ctx, k, ch.val)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			if c.cfg.onFlushError != nil {
+				c.cfg.onFlushError(err)
+			}
+			continue
+		}
+
+		// a newer write to k may have come in meanwhile; leave that one
+		c.mu.Lock()
+		if c.pending[k].seq == ch.seq {
+			delete(c.pending, k)
+		}
+		c.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Cached[K, V]) flushLoop() {
+
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.cfg.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.kick:
+		case <-c.quit:
+			return
+		}
+		c.Flush(context.Background())
+	}
+}
+
+// Close stops the background flushing and flushes what is still pending.
+// It returns the errors of that last flush.
+func (c *Cached[K, V]) Close() error {
+
+	if c.mode != writeBehind {
+		return nil
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.quit)
+	<-c.done
+
+	return c.Flush(context.Background())
+}
+
+// Stats returns the statistics of the underlying cache.
+func (c *Cached[K, V]) Stats() Stats {
+	return c.loader.Stats()
+}
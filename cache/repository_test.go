@@ -0,0 +1,280 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/cache"
+)
+
+// memRepo is the reference Repository the decorators are checked against.
+type memRepo struct {
+	mu   sync.Mutex
+	data map[string]int
+	gets atomic.Int32
+	fail atomic.Bool
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{data: make(map[string]int)}
+}
+
+var errDown = errors.New("repository down")
+
+func (r *memRepo) Get(ctx context.Context, k string) (int, error) {
+
+	r.gets.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	v, ok := r.data[k]
+	if !ok {
+		return 0, cache.ErrNotFound
+	}
+
+	return v, nil
+}
+
+func (r *memRepo) Put(ctx context.Context, k string, v int) error {
+
+	if r.fail.Load() {
+		return errDown
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data[k] = v
+
+	return nil
+}
+
+func (r *memRepo) Delete(ctx context.Context, k string) error {
+
+	if r.fail.Load() {
+		return errDown
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.data, k)
+
+	return nil
+}
+
+func (r *memRepo) snapshot() map[string]int {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]int, len(r.data))
+	for k, v := range r.data {
+		out[k] = v
+	}
+
+	return out
+}
+
+// testContract is the behaviour every Repository has to show.
+func testContract(t *testing.T, repo cache.Repository[string, int]) {
+
+	ctx := context.Background()
+
+	if _, err := repo.Get(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key but got %v", err)
+	}
+
+	repo.Put(ctx, "a", 1)
+	if v, err := repo.Get(ctx, "a"); v != 1 || err != nil {
+		t.Errorf("Expected 1, nil but got %d, %v", v, err)
+	}
+
+	repo.Put(ctx, "a", 2)
+	if v, _ := repo.Get(ctx, "a"); v != 2 {
+		t.Errorf("Expected the overwrite to be visible but got %d", v)
+	}
+
+	if err := repo.Delete(ctx, "a"); err != nil {
+		t.Errorf("Expected delete to succeed but got %v", err)
+	}
+	if _, err := repo.Get(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete but got %v", err)
+	}
+	if err := repo.Delete(ctx, "never"); err != nil {
+		t.Errorf("Expected deleting a missing key to succeed but got %v", err)
+	}
+}
+
+type decorator func(cache.Repository[string, int], ...cache.Option) (*cache.Cached[string, int], error)
+
+var decorators = map[string]decorator{
+	"ReadThrough":  cache.ReadThrough[string, int],
+	"WriteThrough": cache.WriteThrough[string, int],
+	"WriteBehind":  cache.WriteBehind[string, int],
+}
+
+func TestRepositoryContract(t *testing.T) {
+
+	t.Run("memRepo", func(t *testing.T) { testContract(t, newMemRepo()) })
+
+	for name, decorate := range decorators {
+		t.Run(name, func(t *testing.T) {
+
+			base := newMemRepo()
+			c, err := decorate(base, cache.WithNegativeTTL(time.Minute))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			testContract(t, c)
+			c.Put(context.Background(), "kept", 7)
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// after Close the repository holds exactly what the cache showed
+			if got := base.snapshot(); len(got) != 1 || got["kept"] != 7 {
+				t.Errorf("Expected the repository to hold kept=7 but got %v", got)
+			}
+		})
+	}
+}
+
+func TestReadThroughCachesReads(t *testing.T) {
+
+	base := newMemRepo()
+	base.data["a"] = 1
+	c, _ := cache.ReadThrough[string, int](base)
+
+	for i := 0; i < 5; i++ {
+		c.Get(context.Background(), "a")
+	}
+	if got := base.gets.Load(); got != 1 {
+		t.Errorf("Expected 1 repository read but got %d", got)
+	}
+
+	c.Put(context.Background(), "a", 2)
+	if v, _ := c.Get(context.Background(), "a"); v != 2 || base.gets.Load() != 2 {
+		t.Errorf("Expected the write to invalidate and reload 2 but got %d after %d reads", v, base.gets.Load())
+	}
+}
+
+func TestWriteThroughFailureKeepsCacheConsistent(t *testing.T) {
+
+	base := newMemRepo()
+	c, _ := cache.WriteThrough[string, int](base)
+	ctx := context.Background()
+
+	c.Put(ctx, "a", 1)
+	base.fail.Store(true)
+	if err := c.Put(ctx, "a", 2); err != errDown {
+		t.Fatalf("Expected %v but got %v", errDown, err)
+	}
+	if v, _ := c.Get(ctx, "a"); v != 1 {
+		t.Errorf("Expected the cache to keep the stored 1 but got %d", v)
+	}
+}
+
+func TestWriteBehindBatches(t *testing.T) {
+
+	base := newMemRepo()
+	c, _ := cache.WriteBehind[string, int](base, cache.WithFlushInterval(time.Hour), cache.WithFlushBatch(3))
+	defer c.Close()
+	ctx := context.Background()
+
+	c.Put(ctx, "a", 1)
+	c.Put(ctx, "a", 2) // coalesced with the first
+	c.Put(ctx, "b", 1)
+	if v, _ := c.Get(ctx, "a"); v != 2 || len(base.snapshot()) != 0 {
+		t.Errorf("Expected the write to be cached but not flushed, got %d and %v", v, base.snapshot())
+	}
+
+	c.Put(ctx, "c", 1) // third pending key triggers a flush
+	deadline := time.Now().Add(time.Second)
+	for c.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := base.snapshot(); len(got) != 3 || got["a"] != 2 {
+		t.Errorf("Expected a=2, b, c flushed but got %v", got)
+	}
+}
+
+func TestWriteBehindRetriesFailedFlush(t *testing.T) {
+
+	var failures atomic.Int32
+	base := newMemRepo()
+	c, _ := cache.WriteBehind[string, int](base,
+		cache.WithFlushInterval(time.Hour),
+		cache.WithOnFlushError(func(error) { failures.Add(1) }))
+	ctx := context.Background()
+
+	base.fail.Store(true)
+	c.Put(ctx, "a", 1)
+	if err := c.Flush(ctx); err == nil || c.Pending() != 1 || failures.Load() != 1 {
+		t.Fatalf("Expected a failed flush to keep the change, got %v with %d pending", err, c.Pending())
+	}
+
+	base.fail.Store(false)
+	if err := c.Close(); err != nil || base.snapshot()["a"] != 1 {
+		t.Errorf("Expected Close to flush a=1 but got %v, %v", err, base.snapshot())
+	}
+	if err := c.Put(ctx, "b", 1); err == nil {
+		t.Error("Expected Put after Close to fail")
+	}
+	if _, err := c.Get(ctx, "b"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected a refused Put not to be cached but got %v", err)
+	}
+	c.Put(ctx, "a", 2)
+	if v, _ := c.Get(ctx, "a"); v != 1 {
+		t.Errorf("Expected a=1 as persisted but got %d", v)
+	}
+}
+
+// gatedRepo reports every Get on started and holds it until release is
+// closed.
+type gatedRepo struct {
+	*memRepo
+	started chan struct{}
+	release chan struct{}
+}
+
+func (r *gatedRepo) Get(ctx context.Context, k string) (int, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return r.memRepo.Get(ctx, k)
+}
+
+func TestWriteBehindDeleteRacesGet(t *testing.T) {
+
+	base := &gatedRepo{memRepo: newMemRepo(), started: make(chan struct{}, 1), release: make(chan struct{})}
+	c, _ := cache.WriteBehind[string, int](base, cache.WithFlushInterval(time.Hour), cache.WithTTL(time.Hour))
+	defer c.Close()
+	ctx := context.Background()
+	base.Put(ctx, "a", 1)
+
+	// a load of the row is running while it is deleted
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		c.Get(ctx, "a")
+	}()
+	<-base.started
+	if err := c.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected the pending delete to hide the row but got %v", err)
+	}
+	close(base.release)
+	<-loaded
+
+	if err := c.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected a deleted key to stay deleted but got %d, %v", v, err)
+	}
+}
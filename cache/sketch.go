@@ -0,0 +1,82 @@
+package cache
+
+import "math/bits"
+
+// sketch is a count-min sketch of 4-bit counters used by TinyLFU to
+// estimate how often a key has been seen recently. Every sampleSize
+// increments all counters are halved so old popularity fades.
+type sketch struct {
+	rows       [4][]uint8 // two counters per byte
+	mask       uint64
+	additions  int
+	sampleSize int
+}
+
+func newSketch(counters int) *sketch {
+
+	n := max(64, 1<<bits.Len(uint(counters-1))) // power of two
+	s := &sketch{mask: uint64(n - 1), sampleSize: 10 * counters}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, n/2)
+	}
+
+	return s
+}
+
+// rowSeeds give every row a hash of its own. Rows derived from one hash,
+// by offsets or by double hashing, put keys that collide in one row
+// together in the others far more often than chance, and the sketch then
+// counts little better than a single row.
+var rowSeeds = [4]uint64{0x9e3779b97f4a7c15, 0xc2b2ae3d27d4eb4f, 0x165667b19e3779f9, 0xd6e8feb86659fd93}
+
+// index returns the counter position of h in row i.
+func (s *sketch) index(h uint64, i int) uint64 {
+	return mix(h^rowSeeds[i]) & s.mask
+}
+
+// mix is the splitmix64 finalizer.
+func mix(h uint64) uint64 {
+	h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+	h = (h ^ h>>27) * 0x94d049bb133111eb
+	return h ^ h>>31
+}
+
+func (s *sketch) get(row int, idx uint64) uint8 {
+	return s.rows[row][idx/2] >> ((idx & 1) * 4) & 0x0f
+}
+
+func (s *sketch) increment(h uint64) {
+
+	for i := range s.rows {
+		idx := s.index(h, i)
+		if s.get(i, idx) < 15 {
+			s.rows[i][idx/2] += 1 << ((idx & 1) * 4)
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+func (s *sketch) estimate(h uint64) uint8 {
+
+	est := uint8(15)
+	for i := range s.rows {
+		est = min(est, s.get(i, s.index(h, i)))
+	}
+
+	return est
+}
+
+// reset halves every counter.
+func (s *sketch) reset() {
+
+	for _, row := range s.rows {
+		for j := range row {
+			row[j] = row[j] >> 1 & 0x77
+		}
+	}
+	s.additions /= 2
+}
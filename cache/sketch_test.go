@@ -0,0 +1,65 @@
+package cache
+
+import "testing"
+
+// TestTinyLFUProtectsHotKeys runs one scenario under many fixed hash
+// seeds: three keys read often, then a scan of one-off keys. Every key of
+// the scan must be refused admission under every seed.
+func TestTinyLFUProtectsHotKeys(t *testing.T) {
+
+	for seed := uint64(1); seed <= 300; seed++ {
+		cfg := defaults()
+		cfg.maxCost, cfg.counters = 3, 16
+		c := newCache[int, int](cfg)
+		c.hash = func(k int) uint64 { return mix(uint64(k) + seed*0x9e3779b97f4a7c15) }
+
+		for k := 0; k < 3; k++ {
+			c.Set(k, k, 1)
+			for i := 0; i < 5; i++ {
+				c.Get(k)
+			}
+		}
+
+		// a scan of one-off keys must not displace the hot ones
+		admitted := 0
+		for k := 100; k < 200; k++ {
+			c.Get(k)
+			if c.Set(k, k, 1) {
+				admitted++
+			}
+		}
+
+		if admitted != 0 {
+			t.Errorf("Expected no scan key admitted with seed %d but got %d", seed, admitted)
+		}
+		for k := 0; k < 3; k++ {
+			if _, ok := c.items[k]; !ok {
+				t.Errorf("Expected hot key %d to survive the scan with seed %d", k, seed)
+			}
+		}
+	}
+}
+
+func TestSketchRowsIndependent(t *testing.T) {
+
+	// two keys that share a counter in one row should rarely share it in
+	// the others, about one in 64 for rows of 64 counters
+	s := newSketch(64)
+	together := 0
+	pairs := 0
+	for a := uint64(0); a < 2000; a++ {
+		for b := a + 1; b < 2000; b++ {
+			if s.index(a, 0) != s.index(b, 0) {
+				continue
+			}
+			pairs++
+			if s.index(a, 1) == s.index(b, 1) {
+				together++
+			}
+		}
+	}
+
+	if pairs == 0 || together*10 > pairs {
+		t.Errorf("Expected few of the %d pairs colliding in row 0 to collide in row 1 but %d did", pairs, together)
+	}
+}
@@ -0,0 +1,221 @@
+// Package actor runs each piece of state on its own goroutine and only
+// talks to it through a mailbox channel, so the state needs no locks.
+package actor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"pacx/options"
+)
+
+// ErrStopped is returned when sending to an actor that is stopping or gone.
+var ErrStopped = errors.New("actor: stopped")
+
+// Handler processes one message. It is only ever called from the actor's
+// goroutine, one message at a time.
+type Handler[M any] func(msg M)
+
+// Ref is the handle used to talk to a running actor.
+type Ref[M any] struct {
+	mailbox    chan M
+	newHandler func() Handler[M]
+	cfg        config
+
+	mu       sync.RWMutex
+	stopped  bool
+	stopping chan struct{}  // closed when stopped is set
+	sends    sync.WaitGroup // Sends past the stopped check
+	done     chan struct{}
+
+	restarts int
+	err      error // set when the actor gave up after too many panics
+}
+
+type config struct {
+	mailbox     int
+	maxRestarts int
+	onPanic     func(recovered any, restarts int)
+}
+
+// Option configures an actor.
+type Option = options.Option[config]
+
+// WithMailbox sets the mailbox buffer. Zero makes Send wait for the actor.
+func WithMailbox(n int) Option {
+	return options.New("WithMailbox", func(c *config) error {
+		if n < 0 {
+			return errors.New("mailbox size must not be negative")
+		}
+		c.mailbox = n
+		return nil
+	})
+}
+
+// WithMaxRestarts stops the actor after n restarts. A negative n restarts
+// forever.
+func WithMaxRestarts(n int) Option {
+	return options.New("WithMaxRestarts", func(c *config) error {
+		c.maxRestarts = n
+		return nil
+	})
+}
+
+// WithOnPanic is called with every recovered panic, before the restart.
+func WithOnPanic(fn func(recovered any, restarts int)) Option {
+	return options.New("WithOnPanic", func(c *config) error {
+		c.onPanic = fn
+		return nil
+	})
+}
+
+// Spawn starts an actor. newHandler builds the handler and its state; it is
+// called again after every panic so a restarted actor starts clean. By
+// default the mailbox holds 16 messages and the actor is restarted up to 3
+// times.
+func Spawn[M any](newHandler func() Handler[M], opts ...Option) (*Ref[M], error) {
+
+	cfg, err := options.Build(config{mailbox: 16, maxRestarts: 3}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Ref[M]{
+		mailbox:    make(chan M, cfg.mailbox),
+		newHandler: newHandler,
+		cfg:        cfg,
+		stopping:   make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go r.supervise()
+
+	return r, nil
+}
+
+// Send delivers msg to the mailbox, waiting for room until ctx is done.
+func (r *Ref[M]) Send(ctx context.Context, msg M) error {
+
+	// the lock only covers the check: holding it while waiting for room
+	// would block a restart, and the restart is what makes room
+	r.mu.RLock()
+	if r.stopped {
+		r.mu.RUnlock()
+		return ErrStopped
+	}
+	r.sends.Add(1)
+	r.mu.RUnlock()
+	defer r.sends.Done()
+
+	select {
+	case r.mailbox <- msg:
+		return nil
+	case <-r.stopping:
+		return ErrStopped
+	case <-r.done:
+		return ErrStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop stops accepting messages, lets the actor handle what is already in
+// its mailbox and waits for it to exit. It returns the reason the actor gave
+// up, if it did.
+func (r *Ref[M]) Stop() error {
+
+	r.shut()
+	<-r.done
+
+	return r.Err()
+}
+
+// shut stops accepting messages and closes the mailbox once no Send can
+// still put one in.
+func (r *Ref[M]) shut() {
+
+	r.mu.Lock()
+	if r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	close(r.stopping)
+	r.mu.Unlock()
+
+	r.sends.Wait()
+	close(r.mailbox)
+}
+
+// Done is closed once the actor has exited.
+func (r *Ref[M]) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns the error that made the actor give up after too many
+// restarts, or nil.
+func (r *Ref[M]) Err() error {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.err
+}
+
+// Restarts returns how many times the actor was restarted after a panic.
+func (r *Ref[M]) Restarts() int {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.restarts
+}
+
+func (r *Ref[M]) supervise() {
+
+	defer close(r.done)
+
+	for {
+		recovered, panicked := r.run(r.newHandler())
+		if !panicked {
+			return // mailbox closed and drained
+		}
+
+		r.mu.Lock()
+		r.restarts++
+		restarts := r.restarts
+		giveUp := r.cfg.maxRestarts >= 0 && restarts > r.cfg.maxRestarts
+		if giveUp {
+			r.err = fmt.Errorf("actor: gave up after %d restarts: %v", restarts-1, recovered)
+		}
+		r.mu.Unlock()
+		if giveUp {
+			r.shut()
+		}
+
+		if r.cfg.onPanic != nil {
+			r.cfg.onPanic(recovered, restarts)
+		}
+		if giveUp {
+			return
+		}
+	}
+}
+
+// run handles messages until the mailbox is closed or the handler panics.
+func (r *Ref[M]) run(h Handler[M]) (recovered any, panicked bool) {
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			recovered, panicked = rec, true
+		}
+	}()
+
+	for msg := range r.mailbox {
+		h(msg)
+	}
+
+	return nil, false
+}
@@ -0,0 +1,207 @@
+package actor_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/concurrency/actor"
+)
+
+type add struct {
+	n     int
+	reply chan int // receives the running total when set
+}
+
+func counter() actor.Handler[add] {
+
+	total := 0
+
+	return func(msg add) {
+		if msg.n < 0 {
+			panic("negative")
+		}
+		total += msg.n
+		if msg.reply != nil {
+			msg.reply <- total
+		}
+	}
+}
+
+func TestSendAndStop(t *testing.T) {
+
+	ref, err := actor.Spawn(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			ref.Send(ctx, add{n: 1})
+		}()
+	}
+	wg.Wait()
+
+	reply := make(chan int, 1)
+	ref.Send(ctx, add{reply: reply})
+	if got := <-reply; got != 10 {
+		t.Errorf("Expected total 10 but got %d", got)
+	}
+
+	if err := ref.Stop(); err != nil {
+		t.Errorf("Expected a clean stop but got %v", err)
+	}
+	if err := ref.Send(ctx, add{n: 1}); !errors.Is(err, actor.ErrStopped) {
+		t.Errorf("Expected ErrStopped but got %v", err)
+	}
+}
+
+func TestStopDrainsMailbox(t *testing.T) {
+
+	var handled int
+	ref, _ := actor.Spawn(func() actor.Handler[int] {
+		return func(int) { handled++ }
+	}, actor.WithMailbox(100))
+
+	for i := 0; i < 100; i++ {
+		ref.Send(context.Background(), i)
+	}
+	ref.Stop()
+
+	if handled != 100 {
+		t.Errorf("Expected all 100 queued messages handled but got %d", handled)
+	}
+}
+
+func TestRestartOnPanic(t *testing.T) {
+
+	var panics []int
+	ref, _ := actor.Spawn(counter, actor.WithOnPanic(func(_ any, restarts int) {
+		panics = append(panics, restarts)
+	}))
+
+	ctx := context.Background()
+	ref.Send(ctx, add{n: 5})
+	ref.Send(ctx, add{n: -1}) // crashes, state is rebuilt
+
+	reply := make(chan int, 1)
+	ref.Send(ctx, add{n: 2, reply: reply})
+	if got := <-reply; got != 2 {
+		t.Errorf("Expected fresh state after restart (2) but got %d", got)
+	}
+	if ref.Restarts() != 1 {
+		t.Errorf("Expected 1 restart but got %d", ref.Restarts())
+	}
+
+	ref.Stop()
+	if len(panics) != 1 {
+		t.Errorf("Expected OnPanic once but got %v", panics)
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+
+	ref, _ := actor.Spawn(counter, actor.WithMaxRestarts(1))
+
+	ctx := context.Background()
+	ref.Send(ctx, add{n: -1})
+	ref.Send(ctx, add{n: -1})
+	<-ref.Done()
+
+	if ref.Err() == nil {
+		t.Error("Expected an error after exceeding max restarts")
+	}
+	if err := ref.Send(ctx, add{n: 1}); !errors.Is(err, actor.ErrStopped) {
+		t.Errorf("Expected ErrStopped after giving up but got %v", err)
+	}
+	if err := ref.Stop(); err == nil {
+		t.Error("Expected Stop to report why the actor gave up")
+	}
+}
+
+func TestSendDuringRestart(t *testing.T) {
+
+	release := make(chan struct{})
+	ref, _ := actor.Spawn(func() actor.Handler[int] {
+		return func(n int) {
+			if n < 0 {
+				<-release
+				panic("negative")
+			}
+		}
+	}, actor.WithMailbox(1))
+	defer ref.Stop()
+
+	ctx := context.Background()
+	ref.Send(ctx, -1) // the handler holds this one until release
+	ref.Send(ctx, 1)  // fills the mailbox
+
+	sent := make(chan error)
+	go func() { sent <- ref.Send(ctx, 2) }()
+	deadline := time.Now().Add(time.Second)
+	for !sendBlocked() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the Send to block on the full mailbox")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Errorf("Expected the pending Send to deliver but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the pending Send to return once the actor restarted")
+	}
+}
+
+// sendBlocked reports whether a goroutine is parked in Ref.Send, waiting
+// for room in a mailbox.
+func sendBlocked() bool {
+
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	for _, g := range strings.Split(string(buf), "\n\n") {
+		if strings.Contains(g, "[select") && strings.Contains(g, "actor.(*Ref[...]).Send(") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestStopWithPendingSend(t *testing.T) {
+
+	release := make(chan struct{})
+	ref, _ := actor.Spawn(func() actor.Handler[int] {
+		return func(int) { <-release }
+	}, actor.WithMailbox(1))
+
+	ctx := context.Background()
+	ref.Send(ctx, 1)
+	ref.Send(ctx, 2)
+
+	sent := make(chan error)
+	go func() { sent <- ref.Send(ctx, 3) }()
+	stopped := make(chan error)
+	go func() { stopped <- ref.Stop() }()
+
+	// the pending Send is refused once Stop starts, not delivered late
+	if err := <-sent; !errors.Is(err, actor.ErrStopped) {
+		t.Errorf("Expected ErrStopped for the pending Send but got %v", err)
+	}
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Errorf("Expected a clean stop but got %v", err)
+	}
+}
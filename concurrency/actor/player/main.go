@@ -0,0 +1,108 @@
+// The Player from basics/atomic.go as an actor: health is a plain int owned
+// by the actor goroutine, so there are no atomics and reads can't race with
+// damage.
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"pacx/concurrency/actor"
+)
+
+type message interface{ isMessage() }
+
+type takeDamage struct{ value int }
+
+type getHealth struct{ reply chan<- int }
+
+func (takeDamage) isMessage() {}
+func (getHealth) isMessage()  {}
+
+func newPlayer() actor.Handler[message] {
+
+	health := 100
+
+	return func(msg message) {
+		switch m := msg.(type) {
+		case takeDamage:
+			health -= m.value
+		case getHealth:
+			m.reply <- health
+		}
+	}
+}
+
+func health(ctx context.Context, p *actor.Ref[message]) (int, error) {
+
+	reply := make(chan int, 1)
+	if err := p.Send(ctx, getHealth{reply: reply}); err != nil {
+		return 0, err
+	}
+
+	select {
+	case h := <-reply:
+		return h, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func startUIloop(ctx context.Context, p *actor.Ref[message]) {
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		h, err := health(ctx, p)
+		if err != nil {
+			return
+		}
+		fmt.Printf("Player health: %d\n", h)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func gameOver(ctx context.Context, p *actor.Ref[message]) {
+
+	ticker := time.NewTicker(300 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		p.Send(ctx, takeDamage{value: rand.Intn(20)})
+
+		h, err := health(ctx, p)
+		if err != nil {
+			return
+		}
+		if h <= 0 {
+			fmt.Println("Game Over: Player has died.")
+			return
+		}
+		<-ticker.C
+	}
+}
+
+func main() {
+
+	player, err := actor.Spawn(newPlayer)
+	if err != nil {
+		fmt.Println("spawn failed:", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go startUIloop(ctx, player)
+	gameOver(ctx, player)
+
+	cancel()
+	player.Stop()
+}
@@ -0,0 +1,291 @@
+// Package circuitbreaker stops calling a downstream that keeps failing and
+// gives it time to recover before letting traffic through again.
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"pacx/invariant"
+)
+
+// State is the position of the breaker.
+type State int
+
+const (
+	Closed   State = iota // calls go through, outcomes are recorded
+	Open                  // calls are rejected until OpenTimeout passes
+	HalfOpen              // a few probe calls decide whether to close again
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+var (
+	// ErrOpen is returned while the breaker is open.
+	ErrOpen = errors.New("circuitbreaker: breaker is open")
+
+	// ErrTooManyRequests is returned in half-open state once all probe
+	// slots are taken.
+	ErrTooManyRequests = errors.New("circuitbreaker: too many requests in half-open state")
+)
+
+// Settings configures a Breaker. Zero fields fall back to the defaults.
+type Settings struct {
+	// FailureRate in (0, 1] trips the breaker when reached over the window.
+	FailureRate float64
+
+	// Window is how many of the most recent calls the rate is computed on.
+	Window int
+
+	// MinRequests is the number of calls the window needs before the rate
+	// is trusted; one early failure should not open the breaker.
+	MinRequests int
+
+	// OpenTimeout is how long the breaker stays open before probing.
+	OpenTimeout time.Duration
+
+	// HalfOpenRequests is the number of probe calls allowed in half-open
+	// state. All of them have to succeed to close the breaker.
+	HalfOpenRequests int
+
+	// OnStateChange is called after every transition, outside the lock.
+	OnStateChange func(from, to State)
+}
+
+const (
+	defaultFailureRate      = 0.5
+	defaultWindow           = 20
+	defaultMinRequests      = 10
+	defaultOpenTimeout      = 5 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// Breaker is safe for concurrent use.
+type Breaker struct {
+	settings Settings
+	now      func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64 // bumped on every transition so stale results are dropped
+	openedAt   time.Time
+
+	// closed state: ring buffer of the last Window outcomes
+	outcomes []bool
+	next     int
+	filled   int
+	failures int
+
+	// half-open state
+	probes    int
+	successes int
+}
+
+// New returns a closed breaker.
+func New(s Settings) *Breaker {
+
+	if s.FailureRate <= 0 || s.FailureRate > 1 {
+		s.FailureRate = defaultFailureRate
+	}
+	if s.Window <= 0 {
+		s.Window = defaultWindow
+	}
+	if s.MinRequests <= 0 {
+		s.MinRequests = min(defaultMinRequests, s.Window)
+	}
+	if s.MinRequests > s.Window {
+		s.MinRequests = s.Window
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = defaultOpenTimeout
+	}
+	if s.HalfOpenRequests <= 0 {
+		s.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &Breaker{
+		settings: s,
+		now:      time.Now,
+		outcomes: make([]bool, s.Window),
+	}
+}
+
+// Execute runs fn if the breaker allows it and records the outcome. A nil
+// error counts as success.
+func (b *Breaker) Execute(fn func() error) error {
+
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// a panicking call is a failed call
+		if r := recover(); r != nil {
+			done(false)
+			panic(r)
+		}
+	}()
+
+	err = fn()
+	done(err == nil)
+
+	return err
+}
+
+// Allow asks for permission to make a call. On success the caller must
+// report the outcome through done exactly once.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+
+	b.mu.Lock()
+
+	var change *transition
+
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		change = b.setState(HalfOpen)
+	}
+
+	switch b.state {
+	case Open:
+		b.mu.Unlock()
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.settings.HalfOpenRequests {
+			b.mu.Unlock()
+			b.notify(change)
+			return nil, ErrTooManyRequests
+		}
+		b.probes++
+	}
+
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(change)
+
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.record(generation, success) })
+	}, nil
+}
+
+// State returns the current state, moving from open to half-open if the
+// timeout already passed.
+func (b *Breaker) State() State {
+
+	b.mu.Lock()
+	var change *transition
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.settings.OpenTimeout {
+		change = b.setState(HalfOpen)
+	}
+	state := b.state
+	b.mu.Unlock()
+
+	b.notify(change)
+
+	return state
+}
+
+// Counts reports the outcomes currently held in the closed-state window.
+func (b *Breaker) Counts() (requests, failures int) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.filled, b.failures
+}
+
+func (b *Breaker) record(generation uint64, success bool) {
+
+	b.mu.Lock()
+
+	// the breaker moved on while this call was in flight
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	var change *transition
+
+	switch b.state {
+	case Closed:
+		b.push(success)
+		if b.filled >= b.settings.MinRequests &&
+			float64(b.failures)/float64(b.filled) >= b.settings.FailureRate {
+			change = b.setState(Open)
+		}
+	case HalfOpen:
+		if !success {
+			change = b.setState(Open)
+			break
+		}
+		b.successes++
+		invariant.Check(b.successes <= b.probes, "circuitbreaker: more half-open successes than probes")
+		if b.successes >= b.settings.HalfOpenRequests {
+			change = b.setState(Closed)
+		}
+	}
+
+	b.mu.Unlock()
+	b.notify(change)
+}
+
+// push adds an outcome to the ring buffer, evicting the oldest one.
+func (b *Breaker) push(success bool) {
+
+	if b.filled == len(b.outcomes) {
+		if !b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.filled++
+	}
+
+	b.outcomes[b.next] = success
+	if !success {
+		b.failures++
+	}
+	b.next = (b.next + 1) % len(b.outcomes)
+
+	invariant.Checkf(b.failures >= 0 && b.failures <= b.filled && b.filled <= len(b.outcomes),
+		"circuitbreaker: window out of sync (failures %d, filled %d, size %d)", b.failures, b.filled, len(b.outcomes))
+}
+
+type transition struct {
+	from, to State
+}
+
+// setState must be called with mu held. The returned transition is handed to
+// notify once the lock is released.
+func (b *Breaker) setState(to State) *transition {
+
+	from := b.state
+	invariant.Check(from != to, "circuitbreaker: transition to the current state")
+	b.state = to
+	b.generation++
+
+	b.next, b.filled, b.failures = 0, 0, 0
+	b.probes, b.successes = 0, 0
+
+	if to == Open {
+		b.openedAt = b.now()
+	}
+
+	return &transition{from: from, to: to}
+}
+
+func (b *Breaker) notify(t *transition) {
+	if t != nil && b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(t.from, t.to)
+	}
+}
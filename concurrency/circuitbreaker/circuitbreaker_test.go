@@ -0,0 +1,205 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/concurrency/circuitbreaker"
+)
+
+var errDown = errors.New("downstream failed")
+
+func TestTransitions(t *testing.T) {
+
+	var changes []string
+
+	b := circuitbreaker.New(circuitbreaker.Settings{
+		FailureRate: 0.5,
+		Window:      4,
+		MinRequests: 4,
+		OpenTimeout: 20 * time.Millisecond,
+		OnStateChange: func(from, to circuitbreaker.State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+
+	fail := func() error { return errDown }
+	ok := func() error { return nil }
+
+	// two failures out of four trips it
+	for _, fn := range []func() error{ok, fail, ok, fail} {
+		b.Execute(fn)
+	}
+	if got := b.State(); got != circuitbreaker.Open {
+		t.Fatalf("Expected open but got %s", got)
+	}
+	if err := b.Execute(ok); err != circuitbreaker.ErrOpen {
+		t.Errorf("Expected ErrOpen but got %v", err)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if got := b.State(); got != circuitbreaker.HalfOpen {
+		t.Fatalf("Expected half-open but got %s", got)
+	}
+
+	// a failed probe opens it again, a good one closes it
+	b.Execute(fail)
+	if got := b.State(); got != circuitbreaker.Open {
+		t.Fatalf("Expected open after failed probe but got %s", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := b.Execute(ok); err != nil {
+		t.Fatalf("Expected probe to run but got %v", err)
+	}
+	if got := b.State(); got != circuitbreaker.Closed {
+		t.Fatalf("Expected closed but got %s", got)
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %v but got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected %v but got %v", expected, changes)
+			break
+		}
+	}
+}
+
+func TestMinRequests(t *testing.T) {
+
+	b := circuitbreaker.New(circuitbreaker.Settings{Window: 10, MinRequests: 5})
+
+	for i := 0; i < 4; i++ {
+		b.Execute(func() error { return errDown })
+	}
+	if got := b.State(); got != circuitbreaker.Closed {
+		t.Errorf("Expected closed below MinRequests but got %s", got)
+	}
+}
+
+func TestHalfOpenLimit(t *testing.T) {
+
+	b := circuitbreaker.New(circuitbreaker.Settings{
+		Window:           2,
+		MinRequests:      2,
+		OpenTimeout:      10 * time.Millisecond,
+		HalfOpenRequests: 1,
+	})
+
+	b.Execute(func() error { return errDown })
+	b.Execute(func() error { return errDown })
+	time.Sleep(20 * time.Millisecond)
+
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Expected the first probe to be allowed but got %v", err)
+	}
+	if _, err := b.Allow(); err != circuitbreaker.ErrTooManyRequests {
+		t.Errorf("Expected ErrTooManyRequests but got %v", err)
+	}
+	done(true)
+	done(false) // only the first report counts
+
+	if got := b.State(); got != circuitbreaker.Closed {
+		t.Errorf("Expected closed but got %s", got)
+	}
+}
+
+// flaky is a downstream that goes through an outage and then recovers.
+type flaky struct {
+	down  atomic.Bool
+	calls atomic.Int64
+}
+
+func (f *flaky) call() error {
+	f.calls.Add(1)
+	time.Sleep(time.Millisecond)
+	if f.down.Load() {
+		return errDown
+	}
+	return nil
+}
+
+// TestStressWorkerPool runs the breaker inside the jobs/results worker pool
+// from concurrency/patterns/worker-pool.go and checks that the outage is
+// absorbed by the breaker instead of the downstream.
+func TestStressWorkerPool(t *testing.T) {
+
+	const (
+		totaljobs   = 2000
+		totalworker = 8
+	)
+
+	var opened atomic.Int64
+
+	b := circuitbreaker.New(circuitbreaker.Settings{
+		FailureRate: 0.5,
+		Window:      20,
+		MinRequests: 10,
+		OpenTimeout: 5 * time.Millisecond,
+		OnStateChange: func(from, to circuitbreaker.State) {
+			if to == circuitbreaker.Open {
+				opened.Add(1)
+			}
+		},
+	})
+
+	downstream := &flaky{}
+	downstream.down.Store(true)
+
+	jobs := make(chan int, totaljobs)
+	results := make(chan error, totaljobs)
+
+	var wg sync.WaitGroup
+	wg.Add(totalworker)
+
+	for w := 1; w <= totalworker; w++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				if j == totaljobs/2 {
+					downstream.down.Store(false) // outage is over
+				}
+				results <- b.Execute(downstream.call)
+			}
+		}()
+	}
+
+	for j := 1; j <= totaljobs; j++ {
+		jobs <- j
+	}
+	close(jobs)
+
+	wg.Wait()
+	close(results)
+
+	var rejected int
+	for err := range results {
+		if errors.Is(err, circuitbreaker.ErrOpen) || errors.Is(err, circuitbreaker.ErrTooManyRequests) {
+			rejected++
+		}
+	}
+
+	if opened.Load() == 0 {
+		t.Fatal("Expected the breaker to open during the outage")
+	}
+	if rejected == 0 || downstream.calls.Load() >= totaljobs {
+		t.Errorf("Expected the breaker to shield the downstream, calls=%d rejected=%d", downstream.calls.Load(), rejected)
+	}
+
+	// once recovered, a few probe windows later traffic flows again
+	deadline := time.Now().Add(time.Second)
+	for b.State() != circuitbreaker.Closed && time.Now().Before(deadline) {
+		b.Execute(downstream.call)
+		time.Sleep(time.Millisecond)
+	}
+	if got := b.State(); got != circuitbreaker.Closed {
+		t.Errorf("Expected closed after recovery but got %s", got)
+	}
+}
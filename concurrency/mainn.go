@@ -5,19 +5,24 @@ import (
 	"math/rand"
 	"sync"
 	"time"
+
+	"pacx/concurrency/orders"
 )
 
-type Order struct {
-	ID     int
-	Status string
-}
+// the same types cmd/orderdemo sends between processes
+type Order = orders.Order
+
+var (
+	pending  = orders.Pending
+	statuses = orders.Statuses
+)
 
 func generateOrders(count int) []*Order {
 
 	orders := make([]*Order, count)
 
 	for i := 0; i < count; i++ {
-		orders[i] = &Order{ID: i + 1, Status: "pending"}
+		orders[i] = &Order{ID: i + 1, Status: pending}
 	}
 
 	return orders
@@ -41,7 +46,7 @@ func updateOrderStatus(orders []*Order) {
 
 		time.Sleep(time.Duration(rand.Intn(300)) * time.Millisecond)
 
-		status := []string{"Processing", "Shipped", "Delivered"}[rand.Intn(3)]
+		status := statuses[rand.Intn(len(statuses))]
 
 		order.Status = status
 
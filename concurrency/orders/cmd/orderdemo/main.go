@@ -0,0 +1,221 @@
+// Command orderdemo runs the order demo from concurrency/mainn.go as three
+// OS processes instead of three goroutines:
+//
+//	generator --orders--> processor --status updates--> reporter
+//
+// Each arrow is a Unix socket (or TCP with -network tcp). Started without
+// -role it launches the three stages as child processes of itself.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"pacx/concurrency/orders"
+)
+
+func main() {
+
+	role := flag.String("role", "", "generator, processor or reporter; empty runs all three as processes")
+	network := flag.String("network", "unix", "unix or tcp")
+	in := flag.String("in", "", "address this stage listens on")
+	out := flag.String("out", "", "address of the next stage")
+	count := flag.Int("n", 10, "number of orders")
+	flag.Parse()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var err error
+	switch *role {
+	case "":
+		err = runAll(ctx, *network, *count)
+	case "generator":
+		err = generator(ctx, *network, *out, *count)
+	case "processor":
+		err = processor(ctx, *network, *in, *out)
+	case "reporter":
+		err = reporter(ctx, *network, *in)
+	default:
+		err = fmt.Errorf("unknown role %q", *role)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *role, err)
+		os.Exit(1)
+	}
+}
+
+func runAll(ctx context.Context, network string, count int) error {
+
+	procAddr, reportAddr := "localhost:7071", "localhost:7072"
+	if network == "unix" {
+		dir, err := os.MkdirTemp("", "orderdemo")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		procAddr, reportAddr = filepath.Join(dir, "processor.sock"), filepath.Join(dir, "reporter.sock")
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	stages := [][]string{
+		{"-role", "reporter", "-in", reportAddr},
+		{"-role", "processor", "-in", procAddr, "-out", reportAddr},
+		{"-role", "generator", "-out", procAddr, "-n", fmt.Sprint(count)},
+	}
+
+	var cmds []*exec.Cmd
+	for _, args := range stages {
+		cmd := exec.CommandContext(ctx, self, append(args, "-network", network)...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	var errs []error
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("stages failed: %v", errs)
+	}
+
+	fmt.Println("All the orders completed  Exiting!")
+	return nil
+}
+
+func generator(ctx context.Context, network, out string, count int) error {
+
+	s, err := orders.Dial(ctx, network, out)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	for _, o := range orders.Generate(count) {
+		if err := s.Send(ctx, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func processor(ctx context.Context, network, in, out string) error {
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, network, in)
+	if err != nil {
+		return err
+	}
+
+	s, err := orders.Dial(ctx, network, out)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	// the Sender is not safe for concurrent use
+	var mu sync.Mutex
+	update := func(o orders.Order) error {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Printf("Updated order %d status :  %s\n", o.ID, o.Status)
+		return s.Send(ctx, o)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 1)
+
+	err = orders.Receive(ctx, ln, func(o orders.Order) error {
+		fmt.Printf("Processing order %d\n", o.ID)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := orders.Process(ctx, o, update); err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+		}()
+		return nil
+	})
+	wg.Wait()
+
+	select {
+	case perr := <-errs:
+		return perr
+	default:
+		return err
+	}
+}
+
+func reporter(ctx context.Context, network, in string) error {
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, network, in)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	status := make(map[int]string)
+
+	report := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		ids := make([]int, 0, len(status))
+		for id := range status {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		fmt.Println("\n---- Order Status Report ----")
+		for _, id := range ids {
+			fmt.Printf("Order %d : %s\n", id, status[id])
+		}
+		fmt.Println("---------------------------------")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = orders.Receive(ctx, ln, func(o orders.Order) error {
+		mu.Lock()
+		status[o.ID] = o.Status
+		mu.Unlock()
+		return nil
+	})
+	close(done)
+	report()
+
+	return err
+}
@@ -0,0 +1,161 @@
+// Package orders holds the domain of the order demo in
+// concurrency/mainn.go, so the same types can be used in process and by
+// the multi-process version in cmd/orderdemo, where generator, processor
+// and reporter talk over Unix or TCP sockets.
+package orders
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"pacx/Profiling/tracing"
+	"pacx/validate"
+)
+
+// Order statuses, in the order an order goes through them.
+const (
+	Pending    = "pending"
+	Processing = "Processing"
+	Shipped    = "Shipped"
+	Delivered  = "Delivered"
+)
+
+// Statuses are the statuses after Pending.
+var Statuses = []string{Processing, Shipped, Delivered}
+
+// Order is one order and its current status.
+type Order struct {
+	ID     int    `json:"id" validate:"min=1"`
+	Status string `json:"status" validate:"required,regexp=^(pending|Processing|Shipped|Delivered)$"`
+}
+
+// Generate returns count pending orders numbered from 1.
+func Generate(count int) []Order {
+
+	orders := make([]Order, count)
+	for i := range orders {
+		orders[i] = Order{ID: i + 1, Status: Pending}
+	}
+
+	return orders
+}
+
+// Process moves o through the remaining statuses, taking a random while for
+// each step, and calls update after every step. In go tool trace each order
+// is a task with one region per status; in CPU profiles the work carries
+// the pprof label order=<id>.
+func Process(ctx context.Context, o Order, update func(Order) error) error {
+
+	ctx, end := tracing.WithTask(ctx, "order")
+	defer end()
+	tracing.Logf(ctx, "order", "id %d", o.ID)
+
+	var err error
+	tracing.Do(ctx, func(ctx context.Context) {
+		for _, status := range Statuses {
+			tracing.Region(ctx, status, func() {
+				select {
+				case <-time.After(time.Duration(rand.Intn(300)) * time.Millisecond):
+				case <-ctx.Done():
+					err = ctx.Err()
+					return
+				}
+
+				o.Status = status
+				err = update(o)
+			})
+			if err != nil {
+				return
+			}
+		}
+	}, tracing.OrderLabel, strconv.Itoa(o.ID))
+
+	return err
+}
+
+// Sender writes orders to the next stage, one JSON object per line.
+type Sender struct {
+	conn net.Conn
+	enc  *json.Encoder
+}
+
+// Dial connects to the next stage, retrying until ctx is done since the
+// other process may not be listening yet.
+func Dial(ctx context.Context, network, addr string) (*Sender, error) {
+
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, network, addr)
+		if err == nil {
+			return &Sender{conn: conn, enc: json.NewEncoder(conn)}, nil
+		}
+
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, errors.Join(ctx.Err(), err)
+		}
+	}
+}
+
+// Send writes one order.
+func (s *Sender) Send(ctx context.Context, o Order) error {
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+
+	return s.enc.Encode(o)
+}
+
+// Close tells the next stage there are no more orders.
+func (s *Sender) Close() error {
+	return s.conn.Close()
+}
+
+// Receive accepts one connection from the previous stage on ln and calls
+// fn for every order until that stage closes its Sender. It returns early
+// with ctx.Err(), fn's error, or the validate.Errors of an order that
+// breaks its validate tags.
+func Receive(ctx context.Context, ln net.Listener, fn func(Order) error) error {
+
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	defer conn.Close()
+
+	stopConn := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopConn()
+
+	sc := bufio.NewScanner(conn)
+	for sc.Scan() {
+		var o Order
+		if err := json.Unmarshal(sc.Bytes(), &o); err != nil {
+			return err
+		}
+		if err := validate.Struct(o); err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return sc.Err()
+}
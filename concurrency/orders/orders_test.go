@@ -0,0 +1,124 @@
+package orders_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pacx/concurrency/orders"
+	"pacx/validate"
+)
+
+func listen(t *testing.T, network string) net.Listener {
+
+	addr := "127.0.0.1:0"
+	if network == "unix" {
+		dir, err := os.MkdirTemp("", "o")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		addr = filepath.Join(dir, "s")
+	}
+
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	return ln
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	for _, network := range []string{"unix", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+
+			ln := listen(t, network)
+			ctx := context.Background()
+
+			got := make(chan []orders.Order)
+			go func() {
+				var received []orders.Order
+				err := orders.Receive(ctx, ln, func(o orders.Order) error {
+					received = append(received, o)
+					return nil
+				})
+				if err != nil {
+					t.Error(err)
+				}
+				got <- received
+			}()
+
+			s, err := orders.Dial(ctx, network, ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sent := orders.Generate(3)
+			for _, o := range sent {
+				s.Send(ctx, o)
+			}
+			s.Close()
+
+			received := <-got
+			if len(received) != 3 || received[2] != sent[2] {
+				t.Errorf("Expected %v but got %v", sent, received)
+			}
+		})
+	}
+}
+
+func TestReceiveInvalid(t *testing.T) {
+
+	ln := listen(t, "tcp")
+	ctx := context.Background()
+
+	done := make(chan error)
+	go func() {
+		done <- orders.Receive(ctx, ln, func(orders.Order) error { return nil })
+	}()
+
+	s, err := orders.Dial(ctx, "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Send(ctx, orders.Order{ID: 1, Status: orders.Pending})
+	s.Send(ctx, orders.Order{ID: 0, Status: "lost"})
+	s.Close()
+
+	err = <-done
+	var errs validate.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Errorf("Expected the bad ID and status reported but got %v", err)
+	}
+}
+
+func TestReceiveCancel(t *testing.T) {
+
+	ln := listen(t, "tcp")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := orders.Receive(ctx, ln, func(orders.Order) error { return nil })
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded while nobody connects but got %v", err)
+	}
+}
+
+func TestProcess(t *testing.T) {
+
+	var statuses []string
+	err := orders.Process(context.Background(), orders.Order{ID: 1, Status: orders.Pending}, func(o orders.Order) error {
+		statuses = append(statuses, o.Status)
+		return nil
+	})
+
+	if err != nil || len(statuses) != 3 || statuses[2] != orders.Delivered {
+		t.Errorf("Expected %v but got %v, %v", orders.Statuses, statuses, err)
+	}
+}
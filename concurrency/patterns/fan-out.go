@@ -31,7 +31,8 @@ func main() {
 	}
 	close(jobs)
 
-	// receiving the results
+	// receiving the results, in whatever order the workers finish; see
+	// pipeline.OrderedMap for a fan-out that keeps the job order
 	for k := 0; k <= 5; k++ {
 		fmt.Println("Results :", <-results)
 	}
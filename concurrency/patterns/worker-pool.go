@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"sync"
+
+	"pacx/Profiling/profiler"
 )
 
 const (
@@ -12,6 +16,13 @@ const (
 
 func main() {
 
+	// PPROF_ADDR=localhost:6060 to look at the pool live with go tool pprof
+	go func() {
+		if err := profiler.ServeFromEnv(context.Background()); err != nil {
+			log.Print(err)
+		}
+	}()
+
 	jobs := make(chan int, totaljobs)
 
 	results := make(chan int, totaljobs)
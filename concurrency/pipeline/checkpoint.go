@@ -0,0 +1,399 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"pacx/options"
+)
+
+// Checkpoints stores the last processed offset of each stage under a key.
+// A key-value store fits by keeping the offset as the value; MemCheckpoints
+// and FileCheckpoints are the ones this package has.
+type Checkpoints interface {
+	// Load returns the offset saved under key; ok is false if there is
+	// none.
+	Load(ctx context.Context, key string) (offset int64, ok bool, err error)
+	Save(ctx context.Context, key string, offset int64) error
+}
+
+// MemCheckpoints keeps offsets in memory, which is enough to resume a
+// pipeline within one process and for tests. The zero value is ready to
+// use.
+type MemCheckpoints struct {
+	mu      sync.Mutex
+	offsets map[string]int64
+}
+
+func (m *MemCheckpoints) Load(_ context.Context, key string) (int64, bool, error) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	off, ok := m.offsets[key]
+
+	return off, ok, nil
+}
+
+func (m *MemCheckpoints) Save(_ context.Context, key string, offset int64) error {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.offsets == nil {
+		m.offsets = make(map[string]int64)
+	}
+	m.offsets[key] = offset
+
+	return nil
+}
+
+// FileCheckpoints keeps offsets in a JSON file. Every save rewrites the
+// file to a temporary one and renames it over the old, so a crash leaves
+// either the old offsets or the new ones.
+type FileCheckpoints struct {
+	path string
+
+	mu      sync.Mutex
+	offsets map[string]int64
+}
+
+// OpenFileCheckpoints reads the offsets in the file at path, which need
+// not exist yet.
+func OpenFileCheckpoints(path string) (*FileCheckpoints, error) {
+
+	f := &FileCheckpoints{path: path, offsets: make(map[string]int64)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &f.offsets); err != nil {
+		return nil, fmt.Errorf("pipeline: checkpoints in %s: %w", path, err)
+	}
+
+	return f, nil
+}
+
+// Path returns the file the offsets are kept in.
+func (f *FileCheckpoints) Path() string {
+	return f.path
+}
+
+func (f *FileCheckpoints) Load(_ context.Context, key string) (int64, bool, error) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	off, ok := f.offsets[key]
+
+	return off, ok, nil
+}
+
+func (f *FileCheckpoints) Save(_ context.Context, key string, offset int64) error {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.offsets[key] = offset
+	data, err := json.Marshal(f.offsets)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Item is a value with its offset in the source of a checkpointed
+// pipeline.
+type Item[T any] struct {
+	Offset int64
+	Value  T
+	// Replayed is true if the stage may have processed this offset before
+	// a restart. Stages with side effects should then make them idempotent,
+	// for instance with Ledger.Key.
+	Replayed bool
+}
+
+type ledgerConfig struct {
+	every int64
+}
+
+// LedgerOption configures a Ledger.
+type LedgerOption = options.Option[ledgerConfig]
+
+// WithSaveEvery saves a stage's offset once it has moved n items past the
+// last saved one. Defaults to 100; Flush saves the rest.
+func WithSaveEvery(n int) LedgerOption {
+	return options.New("WithSaveEvery", func(c *ledgerConfig) error {
+		if n < 1 {
+			return errors.New("need at least one item")
+		}
+		c.every = int64(n)
+		return nil
+	})
+}
+
+// mark is the progress of one stage.
+type mark struct {
+	loaded  int64              // saved before this run; -1 if none
+	done    int64              // every offset up to this one is done
+	pending map[int64]struct{} // done offsets above done
+	saved   int64
+}
+
+// Ledger tracks the progress of the stages of one pipeline through a
+// CheckpointedSource, so it can restart where it stopped.
+//
+// An offset is done for a stage when the stage function has returned for
+// it, or when an earlier stage dropped it. A stage's checkpoint is the
+// highest offset up to which everything is done, so an item that was
+// finished by one stage and was still in the channel to the next when the
+// process died is not skipped. After a restart the source resumes one past
+// the lowest checkpoint of all stages: nothing is lost, but stages further
+// ahead see some items again, marked Replayed.
+type Ledger struct {
+	cp     Checkpoints
+	name   string
+	stages []string
+	every  int64
+	from   int64
+
+	mu    sync.Mutex
+	marks []mark
+
+	saveMu sync.Mutex
+	err    error
+}
+
+// NewLedger loads the checkpoints of the stages of the pipeline called
+// name, which are saved under name/stage. stages must be listed in the
+// order items go through them.
+func NewLedger(ctx context.Context, cp Checkpoints, name string, stages []string, opts ...LedgerOption) (*Ledger, error) {
+
+	cfg, err := options.Build(ledgerConfig{every: 100}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(stages) == 0 {
+		return nil, errors.New("pipeline: ledger needs at least one stage")
+	}
+
+	l := &Ledger{cp: cp, name: name, stages: slices.Clone(stages), every: cfg.every}
+
+	lowest := int64(-1)
+	for i, s := range stages {
+		if slices.Index(stages, s) != i {
+			return nil, fmt.Errorf("pipeline: stage %s listed twice", s)
+		}
+		off, ok, err := cp.Load(ctx, l.key(s))
+		if err != nil {
+			return nil, fmt.Errorf("pipeline: loading checkpoint of %s: %w", s, err)
+		}
+		if !ok {
+			off = -1
+		}
+		if i == 0 || off < lowest {
+			lowest = off
+		}
+		l.marks = append(l.marks, mark{loaded: off, saved: off})
+	}
+
+	l.from = lowest + 1
+	for i := range l.marks {
+		l.marks[i].done = lowest
+		l.marks[i].pending = make(map[int64]struct{})
+	}
+
+	return l, nil
+}
+
+func (l *Ledger) key(stage string) string {
+	return l.name + "/" + stage
+}
+
+// From is the offset the source resumes at.
+func (l *Ledger) From() int64 {
+	return l.from
+}
+
+// Key returns a key for the item at offset that is the same every time
+// the pipeline runs, for deduplicating side effects.
+func (l *Ledger) Key(offset int64) string {
+	return l.name + "/" + strconv.FormatInt(offset, 10)
+}
+
+// Checkpoint returns the highest offset up to which stage is done, or -1.
+func (l *Ledger) Checkpoint(stage string) int64 {
+
+	i := l.index(stage)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.marks[i].done
+}
+
+// index panics for a stage the ledger does not know, which is a mistake in
+// the pipeline setup like an invalid option.
+func (l *Ledger) index(stage string) int {
+
+	i := slices.Index(l.stages, stage)
+	if i < 0 {
+		panic("pipeline: stage " + stage + " is not in ledger " + l.name)
+	}
+
+	return i
+}
+
+// finish marks offset done for stage i, and for every later stage if the
+// item was dropped, and saves the stages that moved far enough.
+func (l *Ledger) finish(i int, offset int64, kept bool) {
+
+	last := i
+	if !kept {
+		last = len(l.marks) - 1
+	}
+
+	var save []int
+	l.mu.Lock()
+	for j := i; j <= last; j++ {
+		m := &l.marks[j]
+		m.pending[offset] = struct{}{}
+		for {
+			if _, ok := m.pending[m.done+1]; !ok {
+				break
+			}
+			delete(m.pending, m.done+1)
+			m.done++
+		}
+		if m.done-m.saved >= l.every {
+			save = append(save, j)
+		}
+	}
+	l.mu.Unlock()
+
+	for _, j := range save {
+		if err := l.save(context.Background(), j); err != nil {
+			l.saveMu.Lock()
+			l.err = err
+			l.saveMu.Unlock()
+		}
+	}
+}
+
+// save stores the checkpoint of stage i if it moved since the last save.
+func (l *Ledger) save(ctx context.Context, i int) error {
+
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	l.mu.Lock()
+	done, saved := l.marks[i].done, l.marks[i].saved
+	l.mu.Unlock()
+
+	// a restart that replayed from below this stage's checkpoint must not
+	// move it back
+	if done <= saved || done <= l.marks[i].loaded {
+		return nil
+	}
+	if err := l.cp.Save(ctx, l.key(l.stages[i]), done); err != nil {
+		return fmt.Errorf("pipeline: saving checkpoint of %s: %w", l.stages[i], err)
+	}
+
+	l.mu.Lock()
+	l.marks[i].saved = done
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Flush saves the checkpoint of every stage now. Call it once the
+// pipeline is done or cancelled, so a restart does not replay what ran
+// since the last automatic save.
+func (l *Ledger) Flush(ctx context.Context) error {
+
+	var errs []error
+	for i := range l.stages {
+		errs = append(errs, l.save(ctx, i))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Err returns the error of the last automatic save that failed, if any.
+// Such a failure only means a restart replays more.
+func (l *Ledger) Err() error {
+
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	return l.err
+}
+
+// CheckpointedSource sends items from l.From() on, each with its offset.
+func CheckpointedSource[T any](ctx context.Context, l *Ledger, items []T) <-chan Item[T] {
+
+	out := make(chan Item[T])
+
+	go func() {
+		defer close(out)
+
+		for off := l.from; off < int64(len(items)); off++ {
+			select {
+			case out <- Item[T]{Offset: off, Value: items[off]}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Checkpointed is FilterMap over the items of a CheckpointedSource, with
+// its progress kept in l under name. An item fn drops, or panics on with
+// WithPanics, is done for this stage and all later ones.
+func Checkpointed[In, Out any](l *Ledger, name string, fn func(Item[In]) (Out, bool), opts ...Option) Stage[Item[In], Item[Out]] {
+
+	i := l.index(name)
+	loaded := l.marks[i].loaded
+
+	return FilterMap(name, func(it Item[In]) (out Item[Out], keep bool) {
+		// deferred so a panic still counts as done, as a dropped item
+		defer func() { l.finish(i, it.Offset, keep) }()
+
+		it.Replayed = it.Offset <= loaded
+		v, keep := fn(it)
+
+		return Item[Out]{Offset: it.Offset, Value: v}, keep
+	}, opts...)
+}
@@ -0,0 +1,186 @@
+package pipeline_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"pacx/concurrency/pipeline"
+	"pacx/runtime/panics"
+)
+
+func TestCheckpointedRunToEnd(t *testing.T) {
+
+	ctx := context.Background()
+	var cp pipeline.MemCheckpoints
+
+	l, err := pipeline.NewLedger(ctx, &cp, "job", []string{"even", "square"}, pipeline.WithSaveEvery(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	even := pipeline.Checkpointed(l, "even", func(it pipeline.Item[int]) (int, bool) {
+		return it.Value, it.Value%2 == 0
+	}, pipeline.WithWorkers(4))
+	square := pipeline.Checkpointed(l, "square", func(it pipeline.Item[int]) (int, bool) {
+		return it.Value * it.Value, true
+	}, pipeline.WithWorkers(4))
+
+	got := pipeline.Collect(ctx, pipeline.Then(even, square)(ctx, pipeline.CheckpointedSource(ctx, l, items)))
+	if len(got) != 500 {
+		t.Fatalf("Expected 500 items but got %d", len(got))
+	}
+	// the odd items square never saw are done for it too
+	for _, s := range []string{"even", "square"} {
+		if c := l.Checkpoint(s); c != 999 {
+			t.Errorf("Expected %s done up to 999 but got %d", s, c)
+		}
+	}
+	if err := l.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if off, ok, _ := cp.Load(ctx, "job/square"); !ok || off != 999 {
+		t.Errorf("Expected 999 saved for square but got %d, %v", off, ok)
+	}
+
+	l, err = pipeline.NewLedger(ctx, &cp, "job", []string{"even", "square"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.From() != 1000 {
+		t.Errorf("Expected nothing left to do but got from %d", l.From())
+	}
+}
+
+func TestCheckpointedResumeAfterCrash(t *testing.T) {
+
+	cp, err := pipeline.OpenFileCheckpoints(filepath.Join(t.TempDir(), "offsets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := make([]string, 200)
+	for i := range items {
+		items[i] = string(rune('a' + i%26))
+	}
+
+	// the side effect of the last stage, deduplicated by key
+	var mu sync.Mutex
+	written := make(map[string]int)
+
+	run := func(crashAt int) *pipeline.Ledger {
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		l, err := pipeline.NewLedger(ctx, cp, "upper", []string{"parse", "write"}, pipeline.WithSaveEvery(5))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parse := pipeline.Checkpointed(l, "parse", func(it pipeline.Item[string]) (string, bool) {
+			return it.Value, true
+		}, pipeline.WithWorkers(3), pipeline.WithBuffer(8))
+		write := pipeline.Checkpointed(l, "write", func(it pipeline.Item[string]) (struct{}, bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			written[l.Key(it.Offset)]++
+			if len(written) == crashAt {
+				cancel()
+			}
+			return struct{}{}, true
+		}, pipeline.WithWorkers(2))
+
+		pipeline.Collect(ctx, pipeline.Then(parse, write)(ctx, pipeline.CheckpointedSource(ctx, l, items)))
+		if err := l.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		return l
+	}
+
+	first := run(80)
+	if c := first.Checkpoint("write"); c < 0 || c >= 200 {
+		t.Fatalf("Expected the crash to stop write part way but got %d", c)
+	}
+
+	second := run(-1)
+	if second.From() > first.Checkpoint("write")+1 {
+		t.Errorf("Expected to resume at most one past %d but got %d", first.Checkpoint("write"), second.From())
+	}
+	if len(written) != 200 {
+		t.Errorf("Expected every item written but got %d", len(written))
+	}
+	if c := second.Checkpoint("write"); c != 199 {
+		t.Errorf("Expected write done up to 199 but got %d", c)
+	}
+
+	reopened, err := pipeline.OpenFileCheckpoints(cp.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off, ok, _ := reopened.Load(context.Background(), "upper/parse"); !ok || off != 199 {
+		t.Errorf("Expected 199 in the file for parse but got %d, %v", off, ok)
+	}
+}
+
+func TestCheckpointedReplayedAndPanics(t *testing.T) {
+
+	ctx := context.Background()
+	var cp pipeline.MemCheckpoints
+	cp.Save(ctx, "p/a", 4)
+	cp.Save(ctx, "p/b", 1)
+
+	l, err := pipeline.NewLedger(ctx, &cp, "p", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.From() != 2 {
+		t.Fatalf("Expected to resume past the lower checkpoint at 2 but got %d", l.From())
+	}
+
+	var mu sync.Mutex
+	replayed := make(map[int64]bool)
+	reg, _ := panics.New()
+	a := pipeline.Checkpointed(l, "a", func(it pipeline.Item[int]) (int, bool) {
+		mu.Lock()
+		replayed[it.Offset] = it.Replayed
+		mu.Unlock()
+		if it.Value == 7 {
+			panic("bad item")
+		}
+		return it.Value, true
+	}, pipeline.WithPanics(reg))
+	b := pipeline.Checkpointed(l, "b", func(it pipeline.Item[int]) (int, bool) {
+		return it.Value, true
+	})
+
+	got := pipeline.Collect(ctx, pipeline.Then(a, b)(ctx, pipeline.CheckpointedSource(ctx, l, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})))
+	if len(got) != 7 {
+		t.Errorf("Expected 2 to 9 without 7 but got %v", got)
+	}
+	if !replayed[2] || !replayed[4] || replayed[5] {
+		t.Errorf("Expected 2 to 4 replayed for a but got %v", replayed)
+	}
+	if reg.Count("a") != 1 || l.Checkpoint("b") != 9 {
+		t.Errorf("Expected one panic and b done past it but got %d and %d", reg.Count("a"), l.Checkpoint("b"))
+	}
+}
+
+func TestLedgerUnknownStage(t *testing.T) {
+
+	l, err := pipeline.NewLedger(context.Background(), &pipeline.MemCheckpoints{}, "p", []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for a stage the ledger does not know")
+		}
+	}()
+	pipeline.Checkpointed(l, "b", func(it pipeline.Item[int]) (int, bool) { return it.Value, true })
+}
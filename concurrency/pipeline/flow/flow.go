@@ -0,0 +1,142 @@
+// Package flow describes a pipeline as iterator combinators and runs it
+// either as plain sequential iteration or on the channel engine of package
+// pipeline, so the two execution models can be compared on the same code.
+//
+//	f := flow.From(slices.Values(work))
+//	evens := flow.Filter(f, "filter", func(i int) bool { return i%2 == 0 })
+//	squares := flow.Map(evens, "square", square, pipeline.WithWorkers(4))
+//	for v := range flow.Take(squares, 10).Seq(ctx, flow.Concurrent) { ... }
+package flow
+
+import (
+	"context"
+	"iter"
+	"slices"
+
+	"pacx/concurrency/pipeline"
+	"pacx/iterx"
+)
+
+// Mode selects how a Flow is executed.
+type Mode int
+
+const (
+	// Sequential runs every step inline on the ranging goroutine.
+	Sequential Mode = iota
+
+	// Concurrent runs every Map and Filter as a pipeline stage on its own
+	// goroutines connected by channels. With more than one worker per stage
+	// the output order is not preserved.
+	Concurrent
+)
+
+// Flow is a lazily evaluated sequence of T. Nothing runs until Seq is
+// ranged over.
+type Flow[T any] struct {
+	seq func(ctx context.Context, mode Mode) iter.Seq[T]
+}
+
+// From starts a flow from any iterator.
+func From[T any](seq iter.Seq[T]) Flow[T] {
+	return Flow[T]{seq: func(context.Context, Mode) iter.Seq[T] { return seq }}
+}
+
+// Seq returns the iterator for the given mode. Stopping the range early or
+// cancelling ctx stops the stage goroutines of a concurrent flow.
+func (f Flow[T]) Seq(ctx context.Context, mode Mode) iter.Seq[T] {
+	return f.seq(ctx, mode)
+}
+
+// Collect runs the flow and returns all its values.
+func (f Flow[T]) Collect(ctx context.Context, mode Mode) []T {
+	return slices.Collect(f.Seq(ctx, mode))
+}
+
+// Map applies fn to every value. opts configure the concurrent stage.
+func Map[In, Out any](f Flow[In], name string, fn func(In) Out, opts ...pipeline.Option) Flow[Out] {
+	return stage(f, pipeline.Map(name, fn, opts...), func(yield func(Out) bool, v In) bool {
+		return yield(fn(v))
+	})
+}
+
+// Filter keeps the values for which keep returns true.
+func Filter[T any](f Flow[T], name string, keep func(T) bool, opts ...pipeline.Option) Flow[T] {
+	return stage(f, pipeline.Filter(name, keep, opts...), func(yield func(T) bool, v T) bool {
+		return !keep(v) || yield(v)
+	})
+}
+
+// stage builds a flow step from its channel form and its inline form.
+func stage[In, Out any](f Flow[In], concurrent pipeline.Stage[In, Out], inline func(yield func(Out) bool, v In) bool) Flow[Out] {
+	return Flow[Out]{seq: func(ctx context.Context, mode Mode) iter.Seq[Out] {
+		if mode == Sequential {
+			return func(yield func(Out) bool) {
+				for v := range f.Seq(ctx, mode) {
+					if !inline(yield, v) {
+						return
+					}
+				}
+			}
+		}
+
+		return func(yield func(Out) bool) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+
+			in := iterx.ToChan(ctx, f.Seq(ctx, mode), 0)
+			for v := range iterx.FromChan(ctx, concurrent(ctx, in)) {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}}
+}
+
+// Take stops after n values. In concurrent mode the upstream stages are
+// cancelled once the n-th value has been delivered.
+func Take[T any](f Flow[T], n int) Flow[T] {
+	return Flow[T]{seq: func(ctx context.Context, mode Mode) iter.Seq[T] {
+		return func(yield func(T) bool) {
+			if n <= 0 {
+				return
+			}
+			taken := 0
+			for v := range f.Seq(ctx, mode) {
+				if !yield(v) {
+					return
+				}
+				taken++
+				if taken == n {
+					return
+				}
+			}
+		}
+	}}
+}
+
+// Chunk groups values into slices of size n; the last one may be shorter.
+func Chunk[T any](f Flow[T], n int) Flow[[]T] {
+
+	if n < 1 {
+		panic("flow: chunk size must be at least 1")
+	}
+
+	return Flow[[]T]{seq: func(ctx context.Context, mode Mode) iter.Seq[[]T] {
+		return func(yield func([]T) bool) {
+			batch := make([]T, 0, n)
+			for v := range f.Seq(ctx, mode) {
+				batch = append(batch, v)
+				if len(batch) == n {
+					if !yield(batch) {
+						return
+					}
+					batch = make([]T, 0, n)
+				}
+			}
+			if len(batch) > 0 {
+				yield(batch)
+			}
+		}
+	}}
+}
@@ -0,0 +1,117 @@
+package flow_test
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"pacx/concurrency/pipeline"
+	"pacx/concurrency/pipeline/flow"
+)
+
+func demo(work []int, opts ...pipeline.Option) flow.Flow[int] {
+
+	f := flow.From(slices.Values(work))
+	f = flow.Filter(f, "filter", func(i int) bool { return i%2 == 0 }, opts...)
+	f = flow.Map(f, "square", func(i int) int { return i * i }, opts...)
+
+	return flow.Map(f, "half", func(i int) int { return i / 2 }, opts...)
+}
+
+func TestModesAgree(t *testing.T) {
+
+	ctx := context.Background()
+	work := []int{0, 1, 2, 3, 4, 5, 6, 7, 8}
+	expected := []int{0, 2, 8, 18, 32}
+
+	for _, mode := range []flow.Mode{flow.Sequential, flow.Concurrent} {
+		if got := demo(work).Collect(ctx, mode); !slices.Equal(got, expected) {
+			t.Errorf("mode %d: Expected %v but got %v", mode, expected, got)
+		}
+	}
+
+	// with several workers per stage only the contents match
+	got := demo(work, pipeline.WithWorkers(3)).Collect(ctx, flow.Concurrent)
+	slices.Sort(got)
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %v in any order but got %v", expected, got)
+	}
+}
+
+func TestTakeAndChunk(t *testing.T) {
+
+	ctx := context.Background()
+	naturals := flow.From(func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	})
+
+	for _, mode := range []flow.Mode{flow.Sequential, flow.Concurrent} {
+		doubled := flow.Map(naturals, "double", func(i int) int { return 2 * i })
+		chunks := flow.Chunk(flow.Take(doubled, 5), 2).Collect(ctx, mode)
+
+		if len(chunks) != 3 || !slices.Equal(chunks[2], []int{8}) {
+			t.Errorf("mode %d: Expected [[0 2] [4 6] [8]] but got %v", mode, chunks)
+		}
+	}
+}
+
+func TestConcurrentTakeStopsStages(t *testing.T) {
+
+	before := runtime.NumGoroutine()
+
+	endless := flow.From(func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	})
+	f := flow.Map(endless, "inc", func(i int) int { return i + 1 }, pipeline.WithWorkers(4))
+	flow.Take(f, 3).Collect(context.Background(), flow.Concurrent)
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected stage goroutines to exit, %d running vs %d before", n, before)
+	}
+}
+
+func benchmarkFlow(b *testing.B, mode flow.Mode, cost time.Duration, workers int) {
+
+	work := make([]int, 1000)
+	for i := range work {
+		work[i] = i
+	}
+
+	f := flow.From(slices.Values(work))
+	f = flow.Map(f, "work", func(i int) int {
+		for start := time.Now(); time.Since(start) < cost; {
+		}
+		return i
+	}, pipeline.WithWorkers(workers))
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for range f.Seq(ctx, mode) {
+		}
+	}
+}
+
+func BenchmarkSequentialCheap(b *testing.B) {
+	benchmarkFlow(b, flow.Sequential, 0, 1)
+}
+
+func BenchmarkConcurrentCheap(b *testing.B) {
+	benchmarkFlow(b, flow.Concurrent, 0, 4)
+}
+
+func BenchmarkSequentialCostly(b *testing.B) {
+	benchmarkFlow(b, flow.Sequential, 10*time.Microsecond, 1)
+}
+
+func BenchmarkConcurrentCostly(b *testing.B) {
+	benchmarkFlow(b, flow.Concurrent, 10*time.Microsecond, 4)
+}
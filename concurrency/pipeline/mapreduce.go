@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+)
+
+// MapReduce maps every input on parallelism workers and folds the results
+// into one value with reduceFn, starting from the zero Out. It is Source,
+// a fanned-out Map and a fan-in into a single reducer, so reduceFn never
+// runs concurrently with itself but sees the mapped values in no
+// particular order. If ctx is done first it returns ctx.Err() and the
+// partial result.
+func MapReduce[In, Mid, Out any](ctx context.Context, inputs []In, mapFn func(In) Mid, reduceFn func(acc Out, v Mid) Out, parallelism int) (Out, error) {
+
+	var acc Out
+	if parallelism < 1 {
+		return acc, errors.New("pipeline: parallelism must be at least 1")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // stops the workers if we return early
+
+	mapped := Map("map", mapFn, WithWorkers(parallelism))(ctx, Source(ctx, inputs))
+
+	for {
+		select {
+		case v, ok := <-mapped:
+			if !ok {
+				// the stage also closes on cancellation
+				return acc, ctx.Err()
+			}
+			acc = reduceFn(acc, v)
+		case <-ctx.Done():
+			return acc, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Recorder receives one call per item an instrumented stage processed.
+// latency is the time spent in the stage function; wait is how long the
+// result sat waiting for the next stage to take it, which is the queue wait
+// seen by that stage. emitted is false for filtered out items.
+type Recorder interface {
+	Record(stage string, latency, wait time.Duration, emitted bool)
+}
+
+// StageStats are the totals for one stage.
+type StageStats struct {
+	Stage      string
+	Processed  int64
+	Emitted    int64
+	Latency    time.Duration // sum over all items
+	MaxLatency time.Duration
+	Wait       time.Duration // sum over emitted items
+	Elapsed    time.Duration // first to last item
+}
+
+// Throughput is processed items per second.
+func (s StageStats) Throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Processed) / s.Elapsed.Seconds()
+}
+
+// AvgLatency is the mean processing time per item.
+func (s StageStats) AvgLatency() time.Duration {
+	if s.Processed == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Processed)
+}
+
+// AvgWait is the mean queue wait per emitted item.
+func (s StageStats) AvgWait() time.Duration {
+	if s.Emitted == 0 {
+		return 0
+	}
+	return s.Wait / time.Duration(s.Emitted)
+}
+
+// Metrics is a Recorder that keeps per stage counters in memory.
+type Metrics struct {
+	mu     sync.Mutex
+	stages map[string]*stageState
+}
+
+type stageState struct {
+	StageStats
+	first, last time.Time
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{stages: make(map[string]*stageState)}
+}
+
+// Record implements Recorder.
+func (m *Metrics) Record(stage string, latency, wait time.Duration, emitted bool) {
+
+	now := time.Now()
+	started := now.Add(-latency - wait)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.stages[stage]
+	if !ok {
+		s = &stageState{StageStats: StageStats{Stage: stage}, first: started}
+		m.stages[stage] = s
+	}
+	if started.Before(s.first) {
+		s.first = started
+	}
+
+	s.Processed++
+	s.Latency += latency
+	s.MaxLatency = max(s.MaxLatency, latency)
+	if emitted {
+		s.Emitted++
+		s.Wait += wait
+	}
+	s.last = now
+}
+
+// Snapshot returns the stages ordered by when they started their first
+// item, which for a linear pipeline is the order of the stages.
+func (m *Metrics) Snapshot() []StageStats {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := slices.SortedFunc(maps.Values(m.stages), func(a, b *stageState) int {
+		return a.first.Compare(b.first)
+	})
+
+	out := make([]StageStats, len(states))
+	for i, s := range states {
+		out[i] = s.StageStats
+		out[i].Elapsed = s.last.Sub(s.first)
+	}
+
+	return out
+}
+
+// Print writes the snapshot as a table.
+func (m *Metrics) Print(w io.Writer) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tIN\tOUT\tITEMS/S\tAVG LATENCY\tMAX LATENCY\tAVG WAIT")
+
+	for _, s := range m.Snapshot() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f\t%v\t%v\t%v\n",
+			s.Stage, s.Processed, s.Emitted, s.Throughput(), s.AvgLatency(), s.MaxLatency, s.AvgWait())
+	}
+
+	return tw.Flush()
+}
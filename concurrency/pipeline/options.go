@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"pacx/Profiling/tracing"
+	"pacx/options"
+	"pacx/runtime/panics"
+)
+
+type config struct {
+	workers  int
+	buffer   int
+	recorder Recorder
+	panics   *panics.Registry
+}
+
+// Option configures a stage.
+type Option = options.Option[config]
+
+func mustConfigure(opts []Option) config {
+
+	cfg, err := options.Build(config{workers: 1}, nil, opts...)
+	if err != nil {
+		panic("pipeline: " + err.Error())
+	}
+
+	return cfg
+}
+
+// WithWorkers runs the stage on n goroutines.
+func WithWorkers(n int) Option {
+	return options.New("WithWorkers", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one worker")
+		}
+		c.workers = n
+		return nil
+	})
+}
+
+// WithBuffer sets the size of the stage's output channel.
+func WithBuffer(n int) Option {
+	return options.New("WithBuffer", func(c *config) error {
+		if n < 0 {
+			return errors.New("buffer must not be negative")
+		}
+		c.buffer = n
+		return nil
+	})
+}
+
+// WithRecorder reports every processed item to r.
+func WithRecorder(r Recorder) Option {
+	return options.New("WithRecorder", func(c *config) error {
+		c.recorder = r
+		return nil
+	})
+}
+
+// WithPanics recovers a panic in the stage function, records it in r under
+// the stage name and drops the item, instead of crashing the process.
+func WithPanics(r *panics.Registry) Option {
+	return options.New("WithPanics", func(c *config) error {
+		if r == nil {
+			return errors.New("nil panic registry")
+		}
+		c.panics = r
+		return nil
+	})
+}
+
+// runWorkers starts n copies of work and calls done once all have returned.
+// The workers carry the stage name as a pprof label on top of the labels
+// of ctx.
+func runWorkers(ctx context.Context, name string, n int, work func(), done func()) {
+
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			tracing.Do(ctx, func(context.Context) { work() }, tracing.StageLabel, name)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		done()
+	}()
+}
@@ -0,0 +1,124 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"pacx/Profiling/tracing"
+)
+
+// OrderedMap is Map that emits results in input order however many workers
+// it runs on.
+func OrderedMap[In, Out any](name string, fn func(In) Out, opts ...Option) Stage[In, Out] {
+	return OrderedFilterMap(name, func(v In) (Out, bool) { return fn(v), true }, opts...)
+}
+
+// OrderedFilterMap is FilterMap that keeps input order. A dispatcher queues
+// one result slot per item in arrival order and hands the item to the
+// workers; the stage emits the slots front to back, waiting for the oldest
+// one if a later item finishes first. At most 2*workers+buffer items are in
+// flight, so one slow item stalls the stage rather than growing a reorder
+// buffer without bound.
+//
+// A Recorder sees the time an item waited to be reordered as part of its
+// wait.
+func OrderedFilterMap[In, Out any](name string, fn func(In) (Out, bool), opts ...Option) Stage[In, Out] {
+
+	cfg := mustConfigure(opts)
+
+	type result struct {
+		v        Out
+		keep     bool
+		latency  time.Duration
+		finished time.Time
+	}
+	type job struct {
+		v    In
+		slot chan result
+	}
+
+	return func(ctx context.Context, in <-chan In) <-chan Out {
+
+		jobs := make(chan job)
+		slots := make(chan chan result, 2*cfg.workers+cfg.buffer)
+		out := make(chan Out, cfg.buffer)
+
+		go func() {
+			defer close(jobs)
+			defer close(slots)
+
+			for {
+				var v In
+				select {
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+					v = item
+				case <-ctx.Done():
+					return
+				}
+
+				// one buffered slot per item, so a worker never blocks on it
+				slot := make(chan result, 1)
+				select {
+				case slots <- slot:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case jobs <- job{v: v, slot: slot}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		work := func() {
+			for j := range jobs {
+				start := time.Now()
+				var r Out
+				var keep bool
+				tracing.Region(ctx, name, func() {
+					if cfg.panics != nil {
+						defer cfg.panics.Recover(name)
+					}
+					r, keep = fn(j.v)
+				})
+				j.slot <- result{v: r, keep: keep, latency: time.Since(start), finished: time.Now()}
+			}
+		}
+		runWorkers(ctx, name, cfg.workers, work, func() {})
+
+		go func() {
+			defer close(out)
+
+			for slot := range slots {
+				var res result
+				select {
+				case res = <-slot:
+				case <-ctx.Done():
+					return
+				}
+
+				if res.keep {
+					select {
+					case out <- res.v:
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				if cfg.recorder != nil {
+					var wait time.Duration
+					if res.keep {
+						wait = time.Since(res.finished)
+					}
+					cfg.recorder.Record(name, res.latency, wait, res.keep)
+				}
+			}
+		}()
+
+		return out
+	}
+}
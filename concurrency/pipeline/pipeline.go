@@ -0,0 +1,141 @@
+// Package pipeline is the generator -> filter -> square -> half chain from
+// concurrency/patterns/pipeline.go as reusable, typed, cancellable stages.
+//
+// Each call of a stage function is a region named after the stage, so the
+// stages can be told apart in go tool trace, and every worker runs with
+// the pprof label stage=<name>, so they can be told apart in CPU profiles.
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"pacx/Profiling/tracing"
+)
+
+// Stage reads from in and returns the channel it writes to. The returned
+// channel is closed once in is drained or ctx is done.
+type Stage[In, Out any] func(ctx context.Context, in <-chan In) <-chan Out
+
+// Source sends items on the returned channel, like workGenerator.
+func Source[T any](ctx context.Context, items []T) <-chan T {
+
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+
+		for _, v := range items {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Collect drains in into a slice. It returns early with what it has if ctx
+// is done first.
+func Collect[T any](ctx context.Context, in <-chan T) []T {
+
+	var out []T
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return out
+			}
+			out = append(out, v)
+		case <-ctx.Done():
+			return out
+		}
+	}
+}
+
+// Then joins two stages into one.
+func Then[A, B, C any](first Stage[A, B], second Stage[B, C]) Stage[A, C] {
+	return func(ctx context.Context, in <-chan A) <-chan C {
+		return second(ctx, first(ctx, in))
+	}
+}
+
+// Map applies fn to every item.
+//
+// Like every stage constructor in this package it panics if an option is
+// invalid, since that is a programming error in static pipeline setup.
+func Map[In, Out any](name string, fn func(In) Out, opts ...Option) Stage[In, Out] {
+	return FilterMap(name, func(v In) (Out, bool) { return fn(v), true }, opts...)
+}
+
+// Filter keeps the items for which keep returns true.
+func Filter[T any](name string, keep func(T) bool, opts ...Option) Stage[T, T] {
+	return FilterMap(name, func(v T) (T, bool) { return v, keep(v) }, opts...)
+}
+
+// FilterMap applies fn to every item and forwards the results for which it
+// returns true. With more than one worker the output order is not the
+// input order.
+func FilterMap[In, Out any](name string, fn func(In) (Out, bool), opts ...Option) Stage[In, Out] {
+
+	cfg := mustConfigure(opts)
+
+	return func(ctx context.Context, in <-chan In) <-chan Out {
+
+		out := make(chan Out, cfg.buffer)
+
+		work := func() {
+			for {
+				var v In
+				select {
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+					v = item
+				case <-ctx.Done():
+					return
+				}
+
+				var start time.Time
+				if cfg.recorder != nil {
+					start = time.Now()
+				}
+
+				var r Out
+				var keep bool
+				tracing.Region(ctx, name, func() {
+					if cfg.panics != nil {
+						defer cfg.panics.Recover(name)
+					}
+					r, keep = fn(v)
+				})
+
+				var latency, wait time.Duration
+				if cfg.recorder != nil {
+					latency = time.Since(start)
+				}
+
+				if keep {
+					sent := time.Now()
+					select {
+					case out <- r:
+					case <-ctx.Done():
+						return
+					}
+					wait = time.Since(sent)
+				}
+
+				if cfg.recorder != nil {
+					cfg.recorder.Record(name, latency, wait, keep)
+				}
+			}
+		}
+
+		runWorkers(ctx, name, cfg.workers, work, func() { close(out) })
+
+		return out
+	}
+}
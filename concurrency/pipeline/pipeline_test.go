@@ -0,0 +1,308 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/concurrency/pipeline"
+	"pacx/runtime/panics"
+)
+
+// the stages of concurrency/patterns/pipeline.go
+func demo(opts ...pipeline.Option) pipeline.Stage[int, int] {
+
+	filter := pipeline.Filter("filter", func(i int) bool { return i%2 == 0 }, opts...)
+	square := pipeline.Map("square", func(i int) int { return i * i }, opts...)
+	half := pipeline.Map("half", func(i int) int { return i / 2 }, opts...)
+
+	return pipeline.Then(pipeline.Then(filter, square), half)
+}
+
+func TestDemoPipeline(t *testing.T) {
+
+	ctx := context.Background()
+	in := pipeline.Source(ctx, []int{0, 1, 2, 3, 4, 5, 6, 7, 8})
+
+	got := pipeline.Collect(ctx, demo()(ctx, in))
+
+	expected := []int{0, 2, 8, 18, 32}
+	if !slices.Equal(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+}
+
+func TestCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	endless := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case endless <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	out := demo(pipeline.WithWorkers(3))(ctx, endless)
+	<-out
+	cancel()
+
+	select {
+	case <-drained(out):
+	case <-time.After(time.Second):
+		t.Fatal("Expected the output to close after cancel")
+	}
+}
+
+func drained[T any](ch <-chan T) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		for range ch {
+		}
+		close(done)
+	}()
+	return done
+}
+
+func TestMetrics(t *testing.T) {
+
+	ctx := context.Background()
+	m := pipeline.NewMetrics()
+
+	slow := pipeline.Map("slow", func(i int) int {
+		time.Sleep(time.Millisecond)
+		return i
+	}, pipeline.WithRecorder(m))
+	stage := pipeline.Then(demo(pipeline.WithRecorder(m)), slow)
+
+	pipeline.Collect(ctx, stage(ctx, pipeline.Source(ctx, []int{0, 1, 2, 3, 4, 5, 6, 7, 8})))
+
+	stats := m.Snapshot()
+	names := make([]string, len(stats))
+	for i, s := range stats {
+		names[i] = s.Stage
+	}
+	if !slices.Equal(names, []string{"filter", "square", "half", "slow"}) {
+		t.Fatalf("Expected stages in order but got %v", names)
+	}
+
+	if f := stats[0]; f.Processed != 9 || f.Emitted != 5 {
+		t.Errorf("Expected filter 9 in / 5 out but got %d / %d", f.Processed, f.Emitted)
+	}
+	if s := stats[3]; s.AvgLatency() < time.Millisecond || s.Throughput() <= 0 {
+		t.Errorf("Expected slow stage latency >= 1ms but got %v (%.0f/s)", s.AvgLatency(), s.Throughput())
+	}
+	// half feeds the slow stage, so its results wait in the queue
+	if h := stats[2]; h.AvgWait() <= 0 {
+		t.Errorf("Expected half to see queue wait but got %v", h.AvgWait())
+	}
+
+	var buf bytes.Buffer
+	m.Print(&buf)
+	if !strings.Contains(buf.String(), "square") {
+		t.Errorf("Expected the table to list stages but got\n%s", buf.String())
+	}
+}
+
+func TestInvalidOptionPanics(t *testing.T) {
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for zero workers")
+		}
+	}()
+	pipeline.Map("x", func(i int) int { return i }, pipeline.WithWorkers(0))
+}
+
+func TestWithPanicsDropsAndCounts(t *testing.T) {
+
+	reg, _ := panics.New()
+	square := pipeline.Map("square", func(i int) int {
+		if i%3 == 0 {
+			panic("multiple of three")
+		}
+		return i * i
+	}, pipeline.WithWorkers(2), pipeline.WithPanics(reg))
+
+	ctx := context.Background()
+	got := pipeline.Collect(ctx, square(ctx, pipeline.Source(ctx, []int{1, 2, 3, 4, 5, 6})))
+	slices.Sort(got)
+
+	if expected := []int{1, 4, 16, 25}; !slices.Equal(got, expected) {
+		t.Errorf("Expected %v but got %v", expected, got)
+	}
+	if n := reg.Count("square"); n != 2 {
+		t.Errorf("Expected 2 panics in square but got %d", n)
+	}
+}
+
+func TestStageLabel(t *testing.T) {
+
+	square := pipeline.Map("square", func(i int) []byte {
+		var buf bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&buf, 1)
+		return buf.Bytes()
+	})
+
+	ctx := context.Background()
+	got := pipeline.Collect(ctx, square(ctx, pipeline.Source(ctx, []int{1})))
+
+	if len(got) != 1 || !bytes.Contains(got[0], []byte(`"stage":"square"`)) {
+		t.Errorf("Expected the stage worker to carry the stage label")
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+
+	words := strings.Fields("the quick brown fox jumps over the lazy dog the end")
+
+	counts, err := pipeline.MapReduce(context.Background(), words,
+		func(w string) string { return strings.ToUpper(w) },
+		func(acc map[string]int, w string) map[string]int {
+			if acc == nil {
+				acc = make(map[string]int)
+			}
+			acc[w]++
+			return acc
+		}, 4)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["THE"] != 3 || counts["FOX"] != 1 || len(counts) != 9 {
+		t.Errorf("Expected THE=3, FOX=1 over 9 words but got %v", counts)
+	}
+
+	sum, _ := pipeline.MapReduce(context.Background(), []int{1, 2, 3, 4},
+		func(i int) int { return i * i },
+		func(acc, v int) int { return acc + v }, 2)
+	if sum != 30 {
+		t.Errorf("Expected 30 but got %d", sum)
+	}
+}
+
+func TestMapReduceCancel(t *testing.T) {
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	inputs := make([]int, 1000)
+	n, err := pipeline.MapReduce(ctx, inputs,
+		func(i int) int { time.Sleep(time.Millisecond); return 1 },
+		func(acc, v int) int { return acc + v }, 2)
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+	if n >= len(inputs) {
+		t.Errorf("Expected a partial result but got %d", n)
+	}
+
+	if _, err := pipeline.MapReduce(context.Background(), inputs, func(i int) int { return i },
+		func(acc, v int) int { return acc }, 0); err == nil {
+		t.Error("Expected an error for zero parallelism")
+	}
+}
+
+func TestOrderedMap(t *testing.T) {
+
+	input := make([]int, 200)
+	for i := range input {
+		input[i] = i
+	}
+
+	// later items finish first, so only reassembly keeps the order
+	slow := pipeline.OrderedMap("slow", func(i int) int {
+		time.Sleep(time.Duration(len(input)-i) * 10 * time.Microsecond)
+		return i * 2
+	}, pipeline.WithWorkers(8))
+
+	ctx := context.Background()
+	got := pipeline.Collect(ctx, slow(ctx, pipeline.Source(ctx, input)))
+
+	if len(got) != len(input) {
+		t.Fatalf("Expected %d results but got %d", len(input), len(got))
+	}
+	for i, v := range got {
+		if v != i*2 {
+			t.Fatalf("Expected %d at position %d but got %d", i*2, i, v)
+		}
+	}
+}
+
+func TestOrderedFilterMap(t *testing.T) {
+
+	odd := pipeline.OrderedFilterMap("odd", func(i int) (string, bool) {
+		return strings.Repeat("x", i), i%2 == 1
+	}, pipeline.WithWorkers(3))
+
+	ctx := context.Background()
+	got := pipeline.Collect(ctx, odd(ctx, pipeline.Source(ctx, []int{1, 2, 3, 4, 5})))
+
+	if !slices.Equal(got, []string{"x", "xxx", "xxxxx"}) {
+		t.Errorf("Expected [x xxx xxxxx] but got %v", got)
+	}
+}
+
+func TestOrderedCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stage := pipeline.OrderedMap("forever", func(i int) int { return i }, pipeline.WithWorkers(4))
+	out := stage(ctx, pipeline.Source(ctx, make([]int, 1<<20)))
+	<-out
+	cancel()
+
+	select {
+	case <-drained(out):
+	case <-time.After(time.Second):
+		t.Fatal("Expected the ordered stage to close after cancel")
+	}
+}
+
+// benchmarkFanOut runs n items through a stage whose cost varies per item,
+// so the unordered stage can emit out of order and the ordered one has to
+// hold results back.
+func benchmarkFanOut(b *testing.B, ordered bool) {
+
+	const n = 1000
+
+	input := make([]int, n)
+	for i := range input {
+		input[i] = i
+	}
+	// busy work rather than Sleep, whose timer resolution would dominate
+	work := func(i int) int {
+		sum := 0
+		for j := 0; j < (i%7)*500; j++ {
+			sum += j
+		}
+		return i + sum%2
+	}
+
+	stage := pipeline.Map("work", work, pipeline.WithWorkers(8))
+	if ordered {
+		stage = pipeline.OrderedMap("work", work, pipeline.WithWorkers(8))
+	}
+
+	ctx := context.Background()
+	for b.Loop() {
+		pipeline.Collect(ctx, stage(ctx, pipeline.Source(ctx, input)))
+	}
+}
+
+func BenchmarkFanOutUnordered(b *testing.B) {
+	benchmarkFanOut(b, false)
+}
+
+func BenchmarkFanOutOrdered(b *testing.B) {
+	benchmarkFanOut(b, true)
+}
@@ -0,0 +1,474 @@
+// Package registry keeps named pipelines that are built from a Spec, can
+// be listed while they run and can be replaced without losing items:
+//
+//	r, _ := registry.New(registry.WithSink(store))
+//	r.Deploy(ctx, spec)         // version 1
+//	r.Submit(ctx, "ingest", item)
+//	r.Deploy(ctx, changed)      // version 2 takes new items, 1 drains
+//
+// A redeploy swaps the input of the name to the new version atomically:
+// every Submit either went to the old version or goes to the new one. The
+// old version then gets no more items and runs until it has passed on the
+// ones it had, so while it drains both versions hand items to the sink and
+// their output interleaves.
+//
+// Flow control is the pipeline's own: stage buffers bound what waits
+// between stages, Submit blocks while the first stage is busy, and a
+// Spec's rate limits how fast Submit accepts items.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pacx/concurrency/pipeline"
+	"pacx/concurrency/ratelimit"
+	"pacx/options"
+	"pacx/runtime/panics"
+	"pacx/runtime/scenario"
+)
+
+// ErrNotFound is returned for a pipeline name that is not deployed.
+var ErrNotFound = errors.New("registry: no such pipeline")
+
+type config struct {
+	sink   func(pipeline string, item any)
+	ops    map[string]scenario.Builder
+	panics *panics.Registry
+}
+
+// Option configures a Registry.
+type Option = options.Option[config]
+
+// WithSink is called with every item that made it through a pipeline. It
+// is called from one goroutine per running version, so it must be safe
+// for concurrent use. Defaults to dropping the items.
+func WithSink(fn func(pipeline string, item any)) Option {
+	return options.New("WithSink", func(c *config) error {
+		c.sink = fn
+		return nil
+	})
+}
+
+// WithOp adds an op kind for specs, or replaces a built-in one, like
+// scenario.WithOp.
+func WithOp(name string, build scenario.Builder) Option {
+	return options.New("WithOp", func(c *config) error {
+		if name == "" || build == nil {
+			return errors.New("op needs a name and a builder")
+		}
+		c.ops[name] = build
+		return nil
+	})
+}
+
+// WithPanics records panics of ops in r under pipeline/stage. Defaults to
+// a registry of the Registry's own; a panicking item is dropped either
+// way.
+func WithPanics(r *panics.Registry) Option {
+	return options.New("WithPanics", func(c *config) error {
+		if r == nil {
+			return errors.New("nil panic registry")
+		}
+		c.panics = r
+		return nil
+	})
+}
+
+// Info is what a deployed pipeline is and has done.
+type Info struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Deployed  time.Time `json:"deployed"`
+	Spec      Spec      `json:"spec"`
+	Submitted int64     `json:"submitted"` // by the current version
+	Processed int64     `json:"processed"`
+	Dropped   int64     `json:"dropped"` // failed or panicked in an op
+	InFlight  int64     `json:"in_flight"`
+	Draining  int       `json:"draining"` // older versions still passing on items
+}
+
+// version is one deployment of a spec.
+type version struct {
+	n        int
+	spec     Spec
+	deployed time.Time
+	in       chan any
+	limiter  *ratelimit.Limiter // nil without a rate
+	cancel   context.CancelFunc
+	done     chan struct{} // closed once the last item left
+
+	retired context.Context // done once the version takes no more items
+	retire  context.CancelFunc
+	sends   sync.WaitGroup // Submits handing the version an item
+
+	submitted, processed, dropped atomic.Int64
+}
+
+type entry struct {
+	mu       sync.RWMutex // held briefly, for reading by Submit, for writing by swaps
+	cur      *version     // nil before the first deploy and once removed
+	removed  bool         // taken out of the registry; a Deploy needs a new entry
+	versions int
+	draining atomic.Int32
+}
+
+// Registry holds the deployed pipelines. It is safe for concurrent use.
+type Registry struct {
+	cfg config
+
+	mu    sync.Mutex
+	pipes map[string]*entry
+}
+
+// New returns an empty registry.
+func New(opts ...Option) (*Registry, error) {
+
+	cfg := config{ops: make(map[string]scenario.Builder)}
+	cfg, err := options.Build(cfg, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.panics == nil {
+		cfg.panics, _ = panics.New()
+	}
+
+	return &Registry{cfg: cfg, pipes: make(map[string]*entry)}, nil
+}
+
+// start builds and starts a version of spec.
+func (r *Registry) start(spec Spec) (*version, error) {
+
+	if err := spec.validate(); err != nil {
+		return nil, fmt.Errorf("registry: pipeline %s: %w", spec.Name, err)
+	}
+
+	fns := make([]scenario.OpFunc, len(spec.Stages))
+	for i, st := range spec.Stages {
+		build, ok := r.cfg.ops[st.Op.Kind]
+		if !ok {
+			build, ok = scenario.Builtin(st.Op.Kind)
+		}
+		if !ok {
+			return nil, fmt.Errorf("registry: pipeline %s: stage %s: unknown op kind %q", spec.Name, st.Name, st.Op.Kind)
+		}
+		fn, err := build(st.Op)
+		if err != nil {
+			return nil, fmt.Errorf("registry: pipeline %s: stage %s: %w", spec.Name, st.Name, err)
+		}
+		fns[i] = fn
+	}
+
+	// cancelled only by a Remove or Close that runs out of time
+	ctx, cancel := context.WithCancel(context.Background())
+	v := &version{
+		spec:     spec,
+		deployed: time.Now(),
+		in:       make(chan any),
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	v.retired, v.retire = context.WithCancel(context.Background())
+	if spec.Rate > 0 {
+		v.limiter = ratelimit.New(spec.Rate, max(1, int(spec.Rate/10)))
+	}
+
+	var chain pipeline.Stage[any, any]
+	for i, st := range spec.Stages {
+		s := r.stage(ctx, v, spec.Name+"/"+st.Name, fns[i], st)
+		if chain == nil {
+			chain = s
+		} else {
+			chain = pipeline.Then(chain, s)
+		}
+	}
+	out := chain(ctx, v.in)
+
+	go func() {
+		defer close(v.done)
+		defer cancel()
+
+		for item := range out {
+			if r.cfg.sink != nil {
+				r.cfg.sink(spec.Name, item)
+			}
+			v.processed.Add(1)
+		}
+	}()
+
+	return v, nil
+}
+
+func (r *Registry) stage(ctx context.Context, v *version, name string, fn scenario.OpFunc, st StageSpec) pipeline.Stage[any, any] {
+
+	// ops get a random source they don't share with other goroutines
+	rngs := sync.Pool{New: func() any { return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())) }}
+
+	return pipeline.FilterMap(name, func(item any) (_ any, keep bool) {
+		// deferred so a panic, which WithPanics turns into a drop, counts
+		defer func() {
+			if !keep {
+				v.dropped.Add(1)
+			}
+		}()
+
+		rng := rngs.Get().(*rand.Rand)
+		defer rngs.Put(rng)
+
+		return item, fn(ctx, rng) == nil
+	}, pipeline.WithWorkers(st.Workers), pipeline.WithBuffer(st.Buffer), pipeline.WithPanics(r.cfg.panics))
+}
+
+// Deploy starts spec under spec.Name. If a version is already deployed
+// there, new items go to spec from now on and Deploy waits for the old one
+// to drain. If ctx is done first it returns ctx.Err(), but the old version
+// keeps draining and spec stays deployed. A spec that does not build leaves
+// the deployed version alone.
+func (r *Registry) Deploy(ctx context.Context, spec Spec) error {
+
+	v, err := r.start(spec)
+	if err != nil {
+		return err
+	}
+
+	// look e up under r.mu and swap under e.mu, never holding both
+	var (
+		e   *entry
+		old *version
+	)
+	for e == nil {
+		r.mu.Lock()
+		e = r.pipes[spec.Name]
+		if e == nil {
+			e = &entry{}
+			r.pipes[spec.Name] = e
+		}
+		r.mu.Unlock()
+
+		e.mu.Lock()
+		if e.removed {
+			// a Remove took e out in between; deploy under a new entry
+			e.mu.Unlock()
+			e = nil
+			continue
+		}
+		old = e.cur
+		e.versions++
+		v.n = e.versions
+		e.cur = v
+		e.mu.Unlock()
+	}
+
+	if old == nil {
+		return nil
+	}
+
+	old.shut()
+	e.draining.Add(1)
+	go func() {
+		<-old.done
+		e.draining.Add(-1)
+	}()
+
+	select {
+	case <-old.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Submit hands item to the pipeline called name, waiting for its rate and
+// for its first stage to take the item, or for ctx. An item still waiting
+// when the pipeline is redeployed goes to the new version.
+func (r *Registry) Submit(ctx context.Context, name string, item any) error {
+
+	r.mu.Lock()
+	e, ok := r.pipes[name]
+	r.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+
+	for {
+		e.mu.RLock()
+		v := e.cur
+		if v != nil {
+			v.sends.Add(1)
+		}
+		e.mu.RUnlock()
+		if v == nil {
+			return ErrNotFound
+		}
+
+		err := v.submit(ctx, item)
+		v.sends.Done()
+		if err != errRetired {
+			return err
+		}
+		// a Deploy or Remove retired v while it waited; try what replaced it
+	}
+}
+
+// errRetired is returned by submit for a version that stopped taking items.
+var errRetired = errors.New("registry: version retired")
+
+// submit hands item to v, or returns errRetired if v is retired first.
+func (v *version) submit(ctx context.Context, item any) error {
+
+	if v.limiter != nil {
+		wait, cancel := context.WithCancel(ctx)
+		stop := context.AfterFunc(v.retired, cancel)
+		err := v.limiter.Wait(wait)
+		stop()
+		cancel()
+		if err != nil {
+			if ctx.Err() == nil {
+				return errRetired
+			}
+			return err
+		}
+	}
+
+	v.submitted.Add(1)
+	select {
+	case v.in <- item:
+		return nil
+	case <-v.retired.Done():
+		v.submitted.Add(-1)
+		return errRetired
+	case <-ctx.Done():
+		v.submitted.Add(-1)
+		return ctx.Err()
+	}
+}
+
+// shut retires v and, once no Submit is handing it an item, closes its
+// input so it drains.
+func (v *version) shut() {
+
+	v.retire()
+	v.sends.Wait()
+	close(v.in)
+}
+
+// Remove stops the pipeline called name from taking items and waits for
+// it to drain. If ctx is done first it stops the pipeline, dropping what
+// it still had, and returns ctx.Err().
+func (r *Registry) Remove(ctx context.Context, name string) error {
+
+	r.mu.Lock()
+	e, ok := r.pipes[name]
+	if !ok {
+		r.mu.Unlock()
+		return ErrNotFound
+	}
+	delete(r.pipes, name)
+	r.mu.Unlock()
+
+	e.mu.Lock()
+	v := e.cur
+	e.cur = nil
+	e.removed = true
+	e.mu.Unlock()
+	if v == nil {
+		// removed before its first Deploy got to set it
+		return ErrNotFound
+	}
+
+	v.shut()
+	select {
+	case <-v.done:
+		return nil
+	case <-ctx.Done():
+		v.cancel()
+		return ctx.Err()
+	}
+}
+
+// Close removes every pipeline, see Remove.
+func (r *Registry) Close(ctx context.Context) error {
+
+	var errs []error
+	for _, info := range r.List() {
+		if err := r.Remove(ctx, info.Name); err != nil && !errors.Is(err, ErrNotFound) {
+			errs = append(errs, fmt.Errorf("%s: %w", info.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Get returns the current version of the pipeline called name.
+func (r *Registry) Get(name string) (Info, bool) {
+
+	r.mu.Lock()
+	e, ok := r.pipes[name]
+	r.mu.Unlock()
+	if !ok {
+		return Info{}, false
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	v := e.cur
+	if v == nil {
+		return Info{}, false
+	}
+	info := Info{
+		Name:      v.spec.Name,
+		Version:   v.n,
+		Deployed:  v.deployed,
+		Spec:      v.spec,
+		Submitted: v.submitted.Load(),
+		Processed: v.processed.Load(),
+		Dropped:   v.dropped.Load(),
+		Draining:  int(e.draining.Load()),
+	}
+	info.InFlight = info.Submitted - info.Processed - info.Dropped
+
+	return info, true
+}
+
+// List returns every deployed pipeline by name.
+func (r *Registry) List() []Info {
+
+	r.mu.Lock()
+	names := make([]string, 0, len(r.pipes))
+	for name := range r.pipes {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	slices.Sort(names)
+
+	out := make([]Info, 0, len(names))
+	for _, name := range names {
+		if info, ok := r.Get(name); ok {
+			out = append(out, info)
+		}
+	}
+
+	return out
+}
+
+// ServeHTTP lists the pipelines as JSON.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.List())
+}
@@ -0,0 +1,412 @@
+package registry_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand/v2"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/concurrency/pipeline/registry"
+	"pacx/runtime/scenario"
+)
+
+const specV1 = `{"name": "ingest", "stages": [
+  {"name": "parse", "workers": 2, "buffer": 4, "op": {"kind": "cpu", "work": 1000}},
+  {"name": "store", "workers": 2, "op": {"kind": "sleep", "latency": "1ms"}}
+]}`
+
+func parse(t *testing.T, s string) registry.Spec {
+
+	spec, err := registry.ParseSpec(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return spec
+}
+
+// sink counts every item it is handed.
+type sink struct {
+	mu   sync.Mutex
+	seen map[int]int
+}
+
+func (s *sink) add(_ string, item any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen[item.(int)]++
+}
+
+// eventually polls cond until it holds or a second has passed.
+func eventually(t *testing.T, cond func() bool) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the condition to hold within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// took reports whether the pipeline called name took n items, the
+// last of which may still wait for its first stage.
+func took(r *registry.Registry, name string, n int64) func() bool {
+	return func() bool {
+		info, _ := r.Get(name)
+		return info.Submitted >= n
+	}
+}
+
+func TestRedeployKeepsItems(t *testing.T) {
+
+	ctx := context.Background()
+	s := &sink{seen: make(map[int]int)}
+	r, _ := registry.New(registry.WithSink(s.add))
+	defer r.Close(ctx)
+
+	if err := r.Deploy(ctx, parse(t, specV1)); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 500
+	submitted := make(chan error, 1)
+	go func() {
+		for i := 0; i < n; i++ {
+			if err := r.Submit(ctx, "ingest", i); err != nil {
+				submitted <- err
+				return
+			}
+		}
+		submitted <- nil
+	}()
+
+	// swap while items are in flight, to a version with a different shape
+	eventually(t, took(r, "ingest", n/10))
+	v2 := parse(t, specV1)
+	v2.Stages = v2.Stages[1:]
+	v2.Stages[0].Workers = 4
+	if err := r.Deploy(ctx, v2); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+
+	info, ok := r.Get("ingest")
+	if !ok || info.Version != 2 || len(info.Spec.Stages) != 1 || info.Draining != 0 {
+		t.Errorf("Expected version 2 with one stage and nothing draining but got %+v", info)
+	}
+	if info.Submitted == 0 || info.Submitted == n {
+		t.Errorf("Expected the items to be split between the versions but version 2 got %d", info.Submitted)
+	}
+
+	if err := r.Remove(ctx, "ingest"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.seen) != n {
+		t.Fatalf("Expected all %d items but got %d", n, len(s.seen))
+	}
+	for item, count := range s.seen {
+		if count != 1 {
+			t.Errorf("Expected item %d once but got it %d times", item, count)
+		}
+	}
+	if err := r.Submit(ctx, "ingest", 0); !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Remove but got %v", err)
+	}
+}
+
+func TestDropsAndPanics(t *testing.T) {
+
+	ctx := context.Background()
+	s := &sink{seen: make(map[int]int)}
+	r, _ := registry.New(
+		registry.WithSink(s.add),
+		registry.WithOp("flaky", func(op scenario.Op) (scenario.OpFunc, error) {
+			var calls int64
+			var mu sync.Mutex
+			return func(context.Context, *rand.Rand) error {
+				mu.Lock()
+				calls++
+				c := calls
+				mu.Unlock()
+				switch c % 3 {
+				case 1:
+					return errors.New("failed")
+				case 2:
+					panic("boom")
+				}
+				return nil
+			}, nil
+		}),
+	)
+
+	spec := registry.Spec{Name: "f", Stages: []registry.StageSpec{{Op: scenario.Op{Kind: "flaky"}}}}
+	if err := r.Deploy(ctx, spec); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 30; i++ {
+		r.Submit(ctx, "f", i)
+	}
+
+	var info registry.Info
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if info, _ = r.Get("f"); info.InFlight == 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if info.Submitted != 30 || info.Dropped != 20 || info.Processed != 10 || info.InFlight != 0 {
+		t.Errorf("Expected 10 processed and 20 dropped but got %+v", info)
+	}
+	if info.Spec.Stages[0].Name != "stage1" || info.Spec.Stages[0].Workers != 1 {
+		t.Errorf("Expected the stage defaults to be filled in but got %+v", info.Spec.Stages[0])
+	}
+	r.Close(ctx)
+}
+
+func TestBadSpecs(t *testing.T) {
+
+	ctx := context.Background()
+	r, _ := registry.New()
+	defer r.Close(ctx)
+
+	if err := r.Deploy(ctx, parse(t, specV1)); err != nil {
+		t.Fatal(err)
+	}
+	bad := parse(t, specV1)
+	bad.Stages[0].Op.Kind = "teleport"
+	if err := r.Deploy(ctx, bad); err == nil {
+		t.Error("Expected an error for an unknown op")
+	}
+	if info, _ := r.Get("ingest"); info.Version != 1 {
+		t.Errorf("Expected a failed deploy to keep version 1 but got %d", info.Version)
+	}
+
+	for _, s := range []string{
+		`{"name": "x"}`,
+		`{"stages": [{"op": {"kind": "cpu"}}]}`,
+		`{"name": "x", "stages": [{"op": {}}]}`,
+		`{"name": "x", "stages": [{"op": {"kind": "cpu"}, "workerz": 2}]}`,
+	} {
+		if _, err := registry.ParseSpec(strings.NewReader(s)); err == nil {
+			t.Errorf("Expected an error for %s", s)
+		}
+	}
+}
+
+func TestListAndServe(t *testing.T) {
+
+	ctx := context.Background()
+	r, _ := registry.New()
+	defer r.Close(ctx)
+
+	for _, name := range []string{"b", "a"} {
+		spec := parse(t, specV1)
+		spec.Name = name
+		if err := r.Deploy(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var list []registry.Info
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "a" || list[1].Name != "b" || list[0].Version != 1 {
+		t.Errorf("Expected a and b at version 1 but got %+v", list)
+	}
+}
+
+func TestDeployBehindBlockedSubmit(t *testing.T) {
+
+	ctx := context.Background()
+	gate := make(chan struct{})
+	r, _ := registry.New(registry.WithOp("gate", func(scenario.Op) (scenario.OpFunc, error) {
+		return func(context.Context, *rand.Rand) error {
+			<-gate
+			return nil
+		}, nil
+	}))
+	defer r.Close(ctx)
+
+	slow := registry.Spec{Name: "slow", Stages: []registry.StageSpec{{Op: scenario.Op{Kind: "gate"}}}}
+	other := parse(t, specV1)
+	for _, spec := range []registry.Spec{slow, other} {
+		if err := r.Deploy(ctx, spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the worker holds the first item, so the second Submit waits
+	if err := r.Submit(ctx, "slow", 1); err != nil {
+		t.Fatal(err)
+	}
+	submitted := make(chan error, 1)
+	go func() { submitted <- r.Submit(ctx, "slow", 2) }()
+	eventually(t, took(r, "slow", 2))
+
+	// the redeploy waits for that Submit; other pipelines must not
+	deployed := make(chan error, 1)
+	go func() { deployed <- r.Deploy(ctx, slow) }()
+	eventually(t, func() bool {
+		info, _ := r.Get("slow")
+		return info.Version == 2
+	})
+
+	got := make(chan bool, 1)
+	go func() {
+		_, ok := r.Get(other.Name)
+		got <- ok
+	}()
+	select {
+	case ok := <-got:
+		if !ok {
+			t.Errorf("Expected %s deployed", other.Name)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected Get of another pipeline not to wait for the redeploy")
+	}
+
+	close(gate)
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-deployed; err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := r.Get("slow"); info.Version != 2 {
+		t.Errorf("Expected version 2 but got %d", info.Version)
+	}
+}
+
+func TestRemoveDuringDeploy(t *testing.T) {
+
+	ctx := context.Background()
+	r, _ := registry.New()
+	defer r.Close(ctx)
+
+	for i := 0; i < 50; i++ {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := r.Deploy(ctx, parse(t, specV1)); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := r.Remove(ctx, "ingest"); err != nil && !errors.Is(err, registry.ErrNotFound) {
+				t.Error(err)
+			}
+		}()
+		wg.Wait()
+
+		// whichever ran last decides, but a deployed pipeline must work
+		if _, ok := r.Get("ingest"); ok {
+			if err := r.Submit(ctx, "ingest", i); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.Remove(ctx, "ingest")
+	}
+}
+
+func TestRemoveDeadlineWithBlockedStage(t *testing.T) {
+
+	gate := make(chan struct{})
+	defer close(gate)
+	r, _ := registry.New(registry.WithOp("gate", func(scenario.Op) (scenario.OpFunc, error) {
+		return func(ctx context.Context, _ *rand.Rand) error {
+			select {
+			case <-gate:
+			case <-ctx.Done():
+			}
+			return nil
+		}, nil
+	}))
+
+	ctx := context.Background()
+	slow := registry.Spec{Name: "slow", Stages: []registry.StageSpec{{Op: scenario.Op{Kind: "gate"}}}}
+	if err := r.Deploy(ctx, slow); err != nil {
+		t.Fatal(err)
+	}
+
+	// the worker holds the first item, so the second Submit waits
+	if err := r.Submit(ctx, "slow", 1); err != nil {
+		t.Fatal(err)
+	}
+	submitted := make(chan error, 1)
+	go func() { submitted <- r.Submit(ctx, "slow", 2) }()
+	eventually(t, took(r, "slow", 2))
+
+	removed := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		removed <- r.Remove(ctx, "slow")
+	}()
+	select {
+	case err := <-removed:
+		if err != context.DeadlineExceeded {
+			t.Errorf("Expected DeadlineExceeded but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Remove to give up at its deadline")
+	}
+	if err := <-submitted; !errors.Is(err, registry.ErrNotFound) {
+		t.Errorf("Expected the waiting Submit to get ErrNotFound but got %v", err)
+	}
+}
+
+func TestRedeployMovesWaitingSubmit(t *testing.T) {
+
+	gate := make(chan struct{})
+	s := &sink{seen: make(map[int]int)}
+	r, _ := registry.New(registry.WithSink(s.add), registry.WithOp("gate", func(scenario.Op) (scenario.OpFunc, error) {
+		return func(context.Context, *rand.Rand) error {
+			<-gate
+			return nil
+		}, nil
+	}))
+
+	ctx := context.Background()
+	slow := registry.Spec{Name: "slow", Rate: 1000, Stages: []registry.StageSpec{{Op: scenario.Op{Kind: "gate"}}}}
+	if err := r.Deploy(ctx, slow); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Submit(ctx, "slow", 1); err != nil {
+		t.Fatal(err)
+	}
+	submitted := make(chan error, 1)
+	go func() { submitted <- r.Submit(ctx, "slow", 2) }()
+	eventually(t, took(r, "slow", 2))
+
+	deployed := make(chan error, 1)
+	go func() { deployed <- r.Deploy(ctx, slow) }()
+	close(gate)
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-deployed; err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if s.seen[1] != 1 || s.seen[2] != 1 {
+		t.Errorf("Expected both items once but got %v", s.seen)
+	}
+}
@@ -0,0 +1,82 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"pacx/runtime/scenario"
+)
+
+// Spec describes a pipeline in the op language of scenario files:
+//
+//	{"name": "ingest", "rate": 500, "stages": [
+//	  {"name": "parse", "workers": 4, "buffer": 16, "op": {"kind": "cpu", "work": 2000}},
+//	  {"name": "store", "workers": 2, "op": {"kind": "sleep", "latency": "1ms", "jitter": "1ms"}}
+//	]}
+//
+// Every item goes through the stages in order; a stage whose op fails
+// drops the item.
+type Spec struct {
+	Name   string      `json:"name"`
+	Rate   float64     `json:"rate"` // items accepted per second; 0 is unlimited
+	Stages []StageSpec `json:"stages"`
+}
+
+// StageSpec is one stage of a pipeline.
+type StageSpec struct {
+	Name    string      `json:"name"`
+	Workers int         `json:"workers"` // defaults to 1
+	Buffer  int         `json:"buffer"`  // items waiting for the next stage
+	Op      scenario.Op `json:"op"`
+}
+
+// ParseSpec reads a spec in JSON. Unknown fields are errors.
+func ParseSpec(r io.Reader) (Spec, error) {
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var s Spec
+	if err := dec.Decode(&s); err != nil {
+		return Spec{}, fmt.Errorf("registry: %w", err)
+	}
+	if err := s.validate(); err != nil {
+		return Spec{}, fmt.Errorf("registry: pipeline %s: %w", s.Name, err)
+	}
+
+	return s, nil
+}
+
+func (s *Spec) validate() error {
+
+	if s.Name == "" {
+		return errors.New("no name")
+	}
+	if s.Rate < 0 {
+		return errors.New("rate must not be negative")
+	}
+	if len(s.Stages) == 0 {
+		return errors.New("no stages")
+	}
+	for i := range s.Stages {
+		st := &s.Stages[i]
+		if st.Name == "" {
+			st.Name = fmt.Sprintf("stage%d", i+1)
+		}
+		if st.Workers == 0 {
+			st.Workers = 1
+		}
+		switch {
+		case st.Workers < 0:
+			return fmt.Errorf("stage %s: workers must not be negative", st.Name)
+		case st.Buffer < 0:
+			return fmt.Errorf("stage %s: buffer must not be negative", st.Name)
+		case st.Op.Kind == "":
+			return fmt.Errorf("stage %s: op needs a kind", st.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package pool
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"pacx/Profiling/tracing"
+)
+
+// JobEvent is the life of one job, from Submit to its return.
+type JobEvent struct {
+	ID       uint64 // unique per pool, in submission order
+	Worker   int    // which of the pool's workers ran it
+	Enqueued time.Time
+	Started  time.Time
+	Finished time.Time
+}
+
+// Observer receives a JobEvent for every job a pool ran. It is called on
+// the worker that ran the job, so it must be safe for concurrent use and
+// should be quick.
+type Observer interface {
+	ObserveJob(JobEvent)
+}
+
+// task is a queued job with what the observer and the profile labels
+// need to know about it.
+type task struct {
+	fn       func()
+	ctx      context.Context // only kept for profile labels
+	id       uint64
+	enqueued time.Time
+}
+
+// tracker stamps jobs for an observer and carries their submit context
+// for profile labels. With neither it only carries the job through.
+type tracker struct {
+	obs    Observer
+	labels bool
+	next   atomic.Uint64
+}
+
+func (t *tracker) task(ctx context.Context, fn func()) task {
+
+	tk := task{fn: fn}
+	if t.labels {
+		tk.ctx = ctx
+	}
+	if t.obs != nil {
+		tk.id, tk.enqueued = t.next.Add(1), time.Now()
+	}
+
+	return tk
+}
+
+func (t *tracker) run(worker int, tk task) {
+
+	if t.labels {
+		tracing.Do(tk.ctx, func(context.Context) { t.observe(worker, tk) }, tracing.WorkerLabel, strconv.Itoa(worker))
+		return
+	}
+
+	t.observe(worker, tk)
+}
+
+func (t *tracker) observe(worker int, tk task) {
+
+	if t.obs == nil {
+		tk.fn()
+		return
+	}
+
+	e := JobEvent{ID: tk.id, Worker: worker, Enqueued: tk.enqueued, Started: time.Now()}
+	tk.fn()
+	e.Finished = time.Now()
+	t.obs.ObserveJob(e)
+}
@@ -0,0 +1,197 @@
+package pool
+
+import (
+	"errors"
+	"runtime"
+	"time"
+
+	"pacx/concurrency/quota"
+	"pacx/options"
+)
+
+// config holds the settings of all three pools; each constructor only reads
+// the fields that apply to it.
+type config struct {
+	workers int            // Pool, PriorityPool
+	queue   int            // Pool, ScalingPool
+	aging   time.Duration  // PriorityPool
+	obs     Observer       // all
+	labels  bool           // all
+	quota   *quota.Manager // all
+
+	// ScalingPool
+	min, max      int
+	interval      time.Duration
+	cooldown      time.Duration
+	upDepth       int
+	targetLatency time.Duration
+	onScale       func(from, to int, reason string)
+	onSample      func(Sample)
+}
+
+// Option configures a pool.
+type Option = options.Option[config]
+
+func defaults() config {
+	return config{
+		workers:  runtime.GOMAXPROCS(0),
+		queue:    64,
+		min:      1,
+		max:      runtime.GOMAXPROCS(0),
+		interval: 100 * time.Millisecond,
+		upDepth:  1,
+	}
+}
+
+func validate(c config) error {
+	if c.max < c.min {
+		return errors.New("max workers below min workers")
+	}
+	return nil
+}
+
+func build(opts []Option) (config, error) {
+	return options.Build(defaults(), validate, opts...)
+}
+
+// WithWorkers sets the number of workers of a Pool or PriorityPool.
+func WithWorkers(n int) Option {
+	return options.New("WithWorkers", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one worker")
+		}
+		c.workers = n
+		return nil
+	})
+}
+
+// WithQueue sets the job buffer of a Pool or ScalingPool. Zero makes Submit
+// wait for a free worker.
+func WithQueue(n int) Option {
+	return options.New("WithQueue", func(c *config) error {
+		if n < 0 {
+			return errors.New("queue size must not be negative")
+		}
+		c.queue = n
+		return nil
+	})
+}
+
+// WithAging makes a waiting PriorityPool job gain one priority level per d.
+func WithAging(d time.Duration) Option {
+	return options.New("WithAging", func(c *config) error {
+		if d < 0 {
+			return errors.New("aging must not be negative")
+		}
+		c.aging = d
+		return nil
+	})
+}
+
+// WithObserver reports every job any kind of pool ran to o.
+func WithObserver(o Observer) Option {
+	return options.New("WithObserver", func(c *config) error {
+		c.obs = o
+		return nil
+	})
+}
+
+// WithProfileLabels runs every job with the pprof label worker=<n> on top
+// of the labels of the context it was submitted with, so CPU profiles can
+// be cut per worker or per whatever the submitter labelled, such as the
+// order. PriorityPool.Submit takes no context, so its jobs only get the
+// worker label; SubmitContext does.
+func WithProfileLabels() Option {
+	return options.New("WithProfileLabels", func(c *config) error {
+		c.labels = true
+		return nil
+	})
+}
+
+// WithQuota makes every job submitted with a tenant in its context, see
+// quota.With, hold that tenant's quota from Submit until it returns, with
+// the memory of quota.WithMemory. Pool and ScalingPool wait for the quota
+// like they wait for room in the queue; PriorityPool.SubmitContext never
+// blocks and refuses the job instead. Jobs without a tenant are not
+// limited.
+func WithQuota(m *quota.Manager) Option {
+	return options.New("WithQuota", func(c *config) error {
+		if m == nil {
+			return errors.New("nil quota manager")
+		}
+		c.quota = m
+		return nil
+	})
+}
+
+// WithBounds sets the worker range of a ScalingPool.
+func WithBounds(min, max int) Option {
+	return options.New("WithBounds", func(c *config) error {
+		if min < 1 {
+			return errors.New("need at least one worker")
+		}
+		c.min, c.max = min, max
+		return nil
+	})
+}
+
+// WithInterval sets how often a ScalingPool samples its load.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithCooldown sets the minimum time between two ScalingPool resizes.
+func WithCooldown(d time.Duration) Option {
+	return options.New("WithCooldown", func(c *config) error {
+		if d < 0 {
+			return errors.New("cooldown must not be negative")
+		}
+		c.cooldown = d
+		return nil
+	})
+}
+
+// WithUpDepth grows a ScalingPool once n jobs wait per worker.
+func WithUpDepth(n int) Option {
+	return options.New("WithUpDepth", func(c *config) error {
+		if n < 1 {
+			return errors.New("depth must be at least 1")
+		}
+		c.upDepth = n
+		return nil
+	})
+}
+
+// WithTargetLatency grows a ScalingPool while jobs wait and the average
+// processing time is above d.
+func WithTargetLatency(d time.Duration) Option {
+	return options.New("WithTargetLatency", func(c *config) error {
+		if d < 0 {
+			return errors.New("latency must not be negative")
+		}
+		c.targetLatency = d
+		return nil
+	})
+}
+
+// WithOnScale is called after every ScalingPool resize.
+func WithOnScale(fn func(from, to int, reason string)) Option {
+	return options.New("WithOnScale", func(c *config) error {
+		c.onScale = fn
+		return nil
+	})
+}
+
+// WithOnSample is called with every ScalingPool sample, for metrics.
+func WithOnSample(fn func(Sample)) Option {
+	return options.New("WithOnSample", func(c *config) error {
+		c.onSample = fn
+		return nil
+	})
+}
@@ -0,0 +1,142 @@
+// Package pool is the worker pool from concurrency/patterns/worker-pool.go
+// turned into reusable types: Pool feeds workers from one shared FIFO
+// channel and PriorityPool dispatches from a heap so urgent jobs go first.
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"pacx/concurrency/qos"
+	"pacx/concurrency/quota"
+)
+
+var (
+	// ErrClosed is returned when submitting to a pool that was closed.
+	ErrClosed = errors.New("pool: closed")
+	// ErrFull is returned when submitting qos.Low work to a full queue.
+	ErrFull = errors.New("pool: queue full")
+)
+
+// Pool runs jobs on a fixed number of workers in submission order.
+type Pool struct {
+	jobs  chan task
+	wg    sync.WaitGroup
+	tr    tracker
+	quota *quota.Manager
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New starts the workers reading from one buffered channel. By default
+// there is a worker per GOMAXPROCS and room for 64 queued jobs.
+func New(opts ...Option) (*Pool, error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pool{jobs: make(chan task, cfg.queue), quota: cfg.quota}
+	p.tr.obs, p.tr.labels = cfg.obs, cfg.labels
+
+	p.wg.Add(cfg.workers)
+	for w := 0; w < cfg.workers; w++ {
+		go func() {
+			defer p.wg.Done()
+			for t := range p.jobs {
+				p.tr.run(w, t)
+			}
+		}()
+	}
+
+	return p, nil
+}
+
+// Submit queues job, blocking while the buffer is full. It gives up with
+// ctx.Err() if ctx is done first, and at once with ErrFull if ctx carries
+// qos.Low.
+func (p *Pool) Submit(ctx context.Context, job func()) error {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	return send(ctx, p.quota, p.jobs, &p.tr, job)
+}
+
+// send queues job on jobs for Pool and ScalingPool.
+func send(ctx context.Context, m *quota.Manager, jobs chan<- task, tr *tracker, job func()) (err error) {
+
+	job, release, err := admit(ctx, m, true, job)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+
+	t := tr.task(ctx, job)
+	if qos.From(ctx) <= qos.Low {
+		select {
+		case jobs <- t:
+			return nil
+		default:
+			return ErrFull
+		}
+	}
+
+	select {
+	case jobs <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// admit takes the quota of the tenant in ctx, waiting for it if wait is
+// set, and returns job wrapped to give it back once done. release gives
+// it back for a job that is not queued after all.
+func admit(ctx context.Context, m *quota.Manager, wait bool, job func()) (wrapped func(), release func(), err error) {
+
+	tenant := quota.Tenant(ctx)
+	if m == nil || tenant == "" {
+		return job, func() {}, nil
+	}
+
+	if wait {
+		release, err = m.Acquire(ctx, tenant, quota.Memory(ctx))
+	} else {
+		release, err = m.TryAcquire(tenant, quota.Memory(ctx))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return func() {
+		defer release()
+		job()
+	}, release, nil
+}
+
+// Close stops accepting jobs and waits for the queued ones to finish.
+func (p *Pool) Close() {
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.jobs)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
@@ -0,0 +1,572 @@
+package pool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime/pprof"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/Profiling/tracing"
+	"pacx/concurrency/qos"
+	"pacx/concurrency/quota"
+)
+
+func TestPool(t *testing.T) {
+
+	p, err := New(WithWorkers(4), WithQueue(8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var done atomic.Int64
+	for i := 0; i < 100; i++ {
+		if err := p.Submit(context.Background(), func() { done.Add(1) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+
+	if got := done.Load(); got != 100 {
+		t.Errorf("Expected 100 jobs done but got %d", got)
+	}
+	if err := p.Submit(context.Background(), func() {}); err != ErrClosed {
+		t.Errorf("Expected ErrClosed but got %v", err)
+	}
+}
+
+func TestSubmitCancel(t *testing.T) {
+
+	p, _ := New(WithWorkers(1), WithQueue(0))
+	defer p.Close()
+
+	gate := make(chan struct{})
+	defer close(gate)
+	p.Submit(context.Background(), func() { <-gate })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// the only worker is busy and there is no buffer
+	if err := p.Submit(ctx, func() {}); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+}
+
+func TestSubmitLowIsShed(t *testing.T) {
+
+	for _, p := range []interface {
+		Submit(context.Context, func()) error
+		Close()
+	}{
+		must(New(WithWorkers(1), WithQueue(0))),
+		must(NewScaling(WithBounds(1, 1), WithQueue(0))),
+	} {
+		gate := make(chan struct{})
+		p.Submit(context.Background(), func() { <-gate })
+
+		if err := p.Submit(qos.With(context.Background(), qos.Low), func() {}); err != ErrFull {
+			t.Errorf("Expected ErrFull for low work while the only worker is busy but got %v", err)
+		}
+
+		close(gate)
+		p.Close()
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestQuota(t *testing.T) {
+
+	m, _ := quota.New(quota.WithLimits("small", quota.Limits{Concurrency: 1}))
+	p, _ := New(WithWorkers(4), WithQuota(m))
+
+	var running, most atomic.Int64
+	ctx := quota.With(context.Background(), "small")
+	for i := 0; i < 20; i++ {
+		err := p.Submit(ctx, func() {
+			n := running.Add(1)
+			if n > most.Load() {
+				most.Store(n)
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+
+	if most.Load() != 1 {
+		t.Errorf("Expected one job of the tenant at a time but got %d", most.Load())
+	}
+	if u := m.Usage(); u[0].Admitted != 20 || u[0].InFlight != 0 {
+		t.Errorf("Expected 20 admitted and all released but got %+v", u)
+	}
+
+	pp, _ := NewPriority(WithWorkers(1), WithQuota(m))
+	defer pp.Close()
+	gate := make(chan struct{})
+	defer close(gate)
+	if err := pp.SubmitContext(ctx, func() { <-gate }); err != nil {
+		t.Fatal(err)
+	}
+	if err := pp.SubmitContext(ctx, func() {}); !errors.Is(err, quota.ErrExceeded) {
+		t.Errorf("Expected the priority pool to refuse instead of waiting but got %v", err)
+	}
+}
+
+func TestQueuePushQuota(t *testing.T) {
+
+	m, _ := quota.New(quota.WithLimits("small", quota.Limits{Concurrency: 1}))
+	q := NewQueue[string](0)
+	ctx := quota.With(context.Background(), "small")
+
+	release, err := q.PushQuota(ctx, m, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.PushQuota(ctx, m, "b"); !errors.Is(err, quota.ErrExceeded) || q.Len() != 1 {
+		t.Errorf("Expected b refused and left out but got %v with %d queued", err, q.Len())
+	}
+	if _, err := q.PushQuota(context.Background(), m, "untenanted"); err != nil {
+		t.Errorf("Expected work without a tenant to be pushed but got %v", err)
+	}
+
+	release()
+	if _, err := q.PushQuota(ctx, m, "c"); err != nil {
+		t.Errorf("Expected c to fit once a was released but got %v", err)
+	}
+	if q.Len() != 3 {
+		t.Errorf("Expected 3 queued but got %d", q.Len())
+	}
+}
+
+func TestQueueOrder(t *testing.T) {
+
+	q := NewQueue[string](0)
+	q.Push("low", 1)
+	q.Push("high-a", 5)
+	q.Push("mid", 3)
+	q.Push("high-b", 5)
+
+	expected := []string{"high-a", "high-b", "mid", "low"}
+	for _, e := range expected {
+		got, _, ok := q.Pop()
+		if !ok || got != e {
+			t.Fatalf("Expected %s but got %s", e, got)
+		}
+	}
+	if _, _, ok := q.Pop(); ok {
+		t.Error("Expected empty queue")
+	}
+}
+
+func TestQueueIterators(t *testing.T) {
+
+	q := NewQueue[string](0)
+	q.Push("b", 2)
+	q.Push("c", 1)
+	q.Push("a", 3)
+
+	var peeked []string
+	for v := range q.All() {
+		peeked = append(peeked, v)
+	}
+	if q.Len() != 3 {
+		t.Fatalf("Expected All to leave the queue alone, len %d", q.Len())
+	}
+
+	var drained []string
+	for v, prio := range q.Drain() {
+		drained = append(drained, v)
+		if prio == 2 {
+			break
+		}
+	}
+
+	if !slices.Equal(peeked, []string{"a", "b", "c"}) || !slices.Equal(drained, []string{"a", "b"}) || q.Len() != 1 {
+		t.Errorf("Expected a b c / a b with one left but got %v / %v, len %d", peeked, drained, q.Len())
+	}
+}
+
+func TestQueueAging(t *testing.T) {
+
+	now := time.Unix(0, 0)
+	q := NewQueue[string](time.Second)
+	q.now = func() time.Time { return now }
+
+	q.Push("old-low", 1)
+	now = now.Add(3 * time.Second)
+	q.Push("new-mid", 3)  // old-low has aged to 4 by now
+	q.Push("new-high", 5) // still ahead
+
+	expected := []string{"new-high", "old-low", "new-mid"}
+	for _, e := range expected {
+		if got, _, _ := q.Pop(); got != e {
+			t.Fatalf("Expected %s but got %s", e, got)
+		}
+	}
+}
+
+func TestPriorityPool(t *testing.T) {
+
+	p, _ := NewPriority(WithWorkers(1))
+
+	// park the only worker so the rest of the jobs pile up in the heap
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(0, func() {
+		close(started)
+		<-gate
+	})
+	<-started
+
+	var mu sync.Mutex
+	var order []int
+	for _, prio := range []int{1, 9, 5, 7, 3} {
+		p.Submit(prio, func() {
+			mu.Lock()
+			order = append(order, prio)
+			mu.Unlock()
+		})
+	}
+
+	close(gate)
+	p.Close()
+
+	expected := []int{9, 7, 5, 3, 1}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Fatalf("Expected %v but got %v", expected, order)
+		}
+	}
+}
+
+func TestPrioritySubmitContext(t *testing.T) {
+
+	p, _ := NewPriority(WithWorkers(1))
+
+	gate := make(chan struct{})
+	started := make(chan struct{})
+	p.Submit(0, func() {
+		close(started)
+		<-gate
+	})
+	<-started
+
+	var mu sync.Mutex
+	var order []qos.Level
+	for _, l := range []qos.Level{qos.Low, qos.Critical, qos.Normal, qos.High} {
+		p.SubmitContext(qos.With(context.Background(), l), func() {
+			mu.Lock()
+			order = append(order, l)
+			mu.Unlock()
+		})
+	}
+	// no level is Normal, after the Normal job submitted before it
+	p.SubmitContext(context.Background(), func() {
+		mu.Lock()
+		order = append(order, -9)
+		mu.Unlock()
+	})
+
+	close(gate)
+	p.Close()
+
+	expected := []qos.Level{qos.Critical, qos.High, qos.Normal, -9, qos.Low}
+	if !slices.Equal(order, expected) {
+		t.Errorf("Expected %v but got %v", expected, order)
+	}
+
+	q := NewQueue[string](0)
+	q.PushContext(context.Background(), "normal")
+	q.PushContext(qos.With(context.Background(), qos.High), "high")
+	if v, prio, _ := q.Pop(); v != "high" || prio != int(qos.High) {
+		t.Errorf("Expected the high item first but got %s at %d", v, prio)
+	}
+}
+
+func BenchmarkFIFO(b *testing.B) {
+
+	p, _ := New(WithWorkers(4))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+
+	b.ReportAllocs()
+	for b.Loop() {
+		wg.Add(1)
+		p.Submit(ctx, func() { wg.Done() })
+	}
+	wg.Wait()
+	p.Close()
+}
+
+func BenchmarkPriority(b *testing.B) {
+
+	p, _ := NewPriority(WithWorkers(4))
+	var wg sync.WaitGroup
+
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		wg.Add(1)
+		p.Submit(i%10, func() { wg.Done() })
+		i++
+	}
+	wg.Wait()
+	p.Close()
+}
+
+func BenchmarkPriorityAging(b *testing.B) {
+
+	p, _ := NewPriority(WithWorkers(4), WithAging(time.Millisecond))
+	var wg sync.WaitGroup
+
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		wg.Add(1)
+		p.Submit(i%10, func() { wg.Done() })
+		i++
+	}
+	wg.Wait()
+	p.Close()
+}
+
+func TestScalingPool(t *testing.T) {
+
+	var mu sync.Mutex
+	var peak int
+
+	p, err := NewScaling(
+		WithBounds(1, 4),
+		WithQueue(256),
+		WithInterval(2*time.Millisecond),
+		WithOnScale(func(from, to int, reason string) {
+			mu.Lock()
+			peak = max(peak, to)
+			mu.Unlock()
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	wg.Add(200)
+	for i := 0; i < 200; i++ {
+		p.Submit(ctx, func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+		})
+	}
+	wg.Wait()
+
+	mu.Lock()
+	grew := peak
+	mu.Unlock()
+	if grew < 2 {
+		t.Errorf("Expected the pool to grow under load, peak %d", grew)
+	}
+
+	// with nothing to do it falls back to Min
+	deadline := time.Now().Add(time.Second)
+	for p.Workers() > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := p.Workers(); got != 1 {
+		t.Errorf("Expected the pool to shrink to 1 but got %d", got)
+	}
+
+	p.Close()
+	if err := p.Submit(ctx, func() {}); err != ErrClosed {
+		t.Errorf("Expected ErrClosed but got %v", err)
+	}
+}
+
+func TestScalingCooldown(t *testing.T) {
+
+	var moves atomic.Int64
+
+	p, _ := NewScaling(
+		WithBounds(1, 8),
+		WithQueue(256),
+		WithInterval(time.Millisecond),
+		WithCooldown(time.Hour),
+		WithOnScale(func(from, to int, reason string) { moves.Add(1) }),
+	)
+	defer p.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		p.Submit(ctx, func() { time.Sleep(time.Millisecond) })
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if got := moves.Load(); got != 1 {
+		t.Errorf("Expected a single resize inside the cooldown but got %d", got)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+
+	if _, err := New(WithWorkers(0)); err == nil {
+		t.Error("Expected an error for zero workers")
+	}
+	if _, err := NewScaling(WithBounds(4, 2)); err == nil {
+		t.Error("Expected an error for max below min")
+	}
+}
+
+type jobLog struct {
+	mu     sync.Mutex
+	events []JobEvent
+}
+
+func (l *jobLog) ObserveJob(e JobEvent) {
+	l.mu.Lock()
+	l.events = append(l.events, e)
+	l.mu.Unlock()
+}
+
+func TestObserver(t *testing.T) {
+
+	var log jobLog
+	p, _ := NewPriority(WithWorkers(2), WithObserver(&log))
+	for i := 0; i < 10; i++ {
+		p.Submit(i, func() {})
+	}
+	p.Close()
+
+	if len(log.events) != 10 {
+		t.Fatalf("Expected 10 events but got %d", len(log.events))
+	}
+	ids := make(map[uint64]bool)
+	for _, e := range log.events {
+		ids[e.ID] = true
+		if e.Worker < 0 || e.Worker > 1 {
+			t.Errorf("Expected worker 0 or 1 but got %d", e.Worker)
+		}
+		if e.Started.Before(e.Enqueued) || e.Finished.Before(e.Started) {
+			t.Errorf("Expected enqueued <= started <= finished but got %+v", e)
+		}
+	}
+	if len(ids) != 10 {
+		t.Errorf("Expected 10 distinct ids but got %d", len(ids))
+	}
+}
+
+func TestScalingSetBounds(t *testing.T) {
+
+	p, _ := NewScaling(WithBounds(1, 2), WithInterval(time.Hour))
+	defer p.Close()
+
+	if err := p.SetBounds(3, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Workers(); got != 3 {
+		t.Errorf("Expected the pool to grow to the new minimum of 3 but got %d", got)
+	}
+	if err := p.SetBounds(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Workers(); got != 2 {
+		t.Errorf("Expected the pool to shrink to the new maximum of 2 but got %d", got)
+	}
+	if err := p.SetBounds(4, 3); err == nil {
+		t.Error("Expected an error for max below min")
+	}
+	if lo, hi := p.Bounds(); lo != 1 || hi != 2 {
+		t.Errorf("Expected bounds 1..2 to stay but got %d..%d", lo, hi)
+	}
+}
+
+func TestScalingSetBoundsDuringClose(t *testing.T) {
+
+	p, _ := NewScaling(WithBounds(4, 4), WithInterval(time.Hour))
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(4)
+	for range 4 {
+		p.Submit(context.Background(), func() {
+			started.Done()
+			<-release
+		})
+	}
+	started.Wait()
+
+	// the shrink waits for idle workers, and Close starts before any is
+	done := make(chan struct{})
+	go func() {
+		p.SetBounds(1, 1)
+		done <- struct{}{}
+	}()
+	waitState(t, func() bool { return p.Workers() == 1 })
+	go func() {
+		p.Close()
+		done <- struct{}{}
+	}()
+	waitState(t, func() bool {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return p.closed
+	})
+	close(release)
+
+	for range 2 {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected SetBounds and Close to return")
+		}
+	}
+}
+
+// waitState polls the pool until cond holds or a second has passed.
+func waitState(t *testing.T, cond func() bool) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the pool to get there within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestProfileLabels(t *testing.T) {
+
+	p, err := New(WithWorkers(1), WithProfileLabels())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx := tracing.WithLabels(context.Background(), tracing.OrderLabel, "7")
+	profile := make(chan []byte)
+	p.Submit(ctx, func() {
+		var buf bytes.Buffer
+		pprof.Lookup("goroutine").WriteTo(&buf, 1)
+		profile <- buf.Bytes()
+	})
+
+	got := <-profile
+	if !bytes.Contains(got, []byte(`"order":"7"`)) || !bytes.Contains(got, []byte(`"worker":"0"`)) {
+		t.Errorf("Expected the job to run with the order and worker labels but got\n%s", got)
+	}
+}
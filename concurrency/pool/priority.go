@@ -0,0 +1,121 @@
+package pool
+
+import (
+	"context"
+	"sync"
+
+	"pacx/concurrency/qos"
+	"pacx/concurrency/quota"
+	"pacx/invariant"
+)
+
+// PriorityPool runs jobs on a fixed number of workers, always picking the
+// highest priority job that is waiting.
+type PriorityPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  *Queue[task]
+	closed bool
+	wg     sync.WaitGroup
+	tr     tracker
+	quota  *quota.Manager
+}
+
+// NewPriority starts the workers. Without WithAging jobs run in strict
+// priority order; with it a waiting job gains one level per interval.
+func NewPriority(opts ...Option) (*PriorityPool, error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &PriorityPool{queue: NewQueue[task](cfg.aging), quota: cfg.quota}
+	p.cond = sync.NewCond(&p.mu)
+	p.tr.obs, p.tr.labels = cfg.obs, cfg.labels
+
+	p.wg.Add(cfg.workers)
+	for w := 0; w < cfg.workers; w++ {
+		go p.work(w)
+	}
+
+	return p, nil
+}
+
+// Submit queues job with the given priority. It never blocks.
+func (p *PriorityPool) Submit(priority int, job func()) error {
+	return p.submit(context.Background(), priority, job)
+}
+
+// SubmitContext queues job with the qos level of ctx as its priority, so
+// qos.Critical work runs before qos.High and so on. With
+// WithProfileLabels the job also runs with the labels of ctx, and with
+// WithQuota it is refused if its tenant is over quota. It never blocks.
+func (p *PriorityPool) SubmitContext(ctx context.Context, job func()) error {
+
+	job, release, err := admit(ctx, p.quota, false, job)
+	if err != nil {
+		return err
+	}
+	if err := p.submit(ctx, int(qos.From(ctx)), job); err != nil {
+		release()
+		return err
+	}
+
+	return nil
+}
+
+func (p *PriorityPool) submit(ctx context.Context, priority int, job func()) error {
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrClosed
+	}
+	p.queue.Push(p.tr.task(ctx, job), priority)
+	p.mu.Unlock()
+
+	p.cond.Signal()
+
+	return nil
+}
+
+// Len returns the number of jobs waiting for a worker.
+func (p *PriorityPool) Len() int {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.queue.Len()
+}
+
+// Close stops accepting jobs and waits for the queued ones to finish.
+func (p *PriorityPool) Close() {
+
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	p.cond.Broadcast()
+	p.wg.Wait()
+}
+
+func (p *PriorityPool) work(worker int) {
+
+	defer p.wg.Done()
+
+	for {
+		p.mu.Lock()
+		for p.queue.Len() == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		t, _, ok := p.queue.Pop()
+		p.mu.Unlock()
+
+		if !ok { // closed and drained
+			invariant.Check(p.closed, "pool: worker woke up to an empty queue of an open pool")
+			return
+		}
+		p.tr.run(worker, t)
+	}
+}
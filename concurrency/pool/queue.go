@@ -0,0 +1,151 @@
+package pool
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"iter"
+	"slices"
+	"time"
+
+	"pacx/concurrency/qos"
+	"pacx/concurrency/quota"
+	"pacx/invariant"
+)
+
+// Queue is a priority queue: higher priority values come out first and
+// equal priorities come out in insertion order. It is not safe for
+// concurrent use on its own.
+//
+// With a non zero aging interval a waiting item gains one priority level
+// per interval, so a steady stream of urgent items can't starve the rest.
+// Because every item ages at the same rate this is the same as ordering by
+// priority*aging - enqueueTime, which never changes after insertion and so
+// keeps the heap valid without re-sorting.
+type Queue[T any] struct {
+	aging time.Duration
+	now   func() time.Time
+	seq   uint64
+	items items[T]
+}
+
+// NewQueue returns an empty queue. aging <= 0 disables aging.
+func NewQueue[T any](aging time.Duration) *Queue[T] {
+	return &Queue[T]{aging: aging, now: time.Now}
+}
+
+type item[T any] struct {
+	value    T
+	priority int
+	rank     int64 // ordering key, larger first
+	seq      uint64
+}
+
+// Push adds v with the given priority.
+func (q *Queue[T]) Push(v T, priority int) {
+
+	rank := int64(priority)
+	if q.aging > 0 {
+		rank = int64(priority)*int64(q.aging) - q.now().UnixNano()
+	}
+
+	q.seq++
+	heap.Push(&q.items, item[T]{value: v, priority: priority, rank: rank, seq: q.seq})
+}
+
+// PushContext adds v with the qos level of ctx as its priority.
+func (q *Queue[T]) PushContext(ctx context.Context, v T) {
+	q.Push(v, int(qos.From(ctx)))
+}
+
+// PushQuota is PushContext for work of the tenant in ctx: it takes that
+// tenant's quota from m without waiting, as PriorityPool.SubmitContext
+// does, and pushes nothing if the tenant is over it. release gives the
+// quota back once the work v stands for is done. Without a tenant in ctx
+// or with a nil m it only pushes.
+func (q *Queue[T]) PushQuota(ctx context.Context, m *quota.Manager, v T) (release func(), err error) {
+
+	release = func() {}
+	if tenant := quota.Tenant(ctx); m != nil && tenant != "" {
+		if release, err = m.TryAcquire(tenant, quota.Memory(ctx)); err != nil {
+			return nil, err
+		}
+	}
+	q.PushContext(ctx, v)
+
+	return release, nil
+}
+
+// Pop removes the next item. ok is false when the queue is empty.
+func (q *Queue[T]) Pop() (v T, priority int, ok bool) {
+
+	if len(q.items) == 0 {
+		return v, 0, false
+	}
+	it := heap.Pop(&q.items).(item[T])
+	invariant.Check(len(q.items) == 0 || !q.items.before(q.items[0], it), "pool: queue popped out of order")
+
+	return it.value, it.priority, true
+}
+
+// Len returns the number of queued items.
+func (q *Queue[T]) Len() int {
+	return len(q.items)
+}
+
+// All yields the queued values with their priority in the order Pop would
+// return them, without removing anything. It works on a sorted copy, so the
+// queue may be changed while ranging.
+func (q *Queue[T]) All() iter.Seq2[T, int] {
+	return func(yield func(T, int) bool) {
+		sorted := slices.Clone(q.items)
+		slices.SortFunc(sorted, func(a, b item[T]) int {
+			// larger ranks first, as before orders them
+			return cmp.Or(cmp.Compare(b.rank, a.rank), cmp.Compare(a.seq, b.seq))
+		})
+		for _, it := range sorted {
+			if !yield(it.value, it.priority) {
+				return
+			}
+		}
+	}
+}
+
+// Drain pops and yields values until the queue is empty or the consumer
+// stops ranging.
+func (q *Queue[T]) Drain() iter.Seq2[T, int] {
+	return func(yield func(T, int) bool) {
+		for q.Len() > 0 {
+			v, prio, _ := q.Pop()
+			if !yield(v, prio) {
+				return
+			}
+		}
+	}
+}
+
+type items[T any] []item[T]
+
+func (h items[T]) Len() int { return len(h) }
+
+func (h items[T]) Less(i, j int) bool { return h.before(h[i], h[j]) }
+
+func (items[T]) before(a, b item[T]) bool {
+	if a.rank != b.rank {
+		return a.rank > b.rank
+	}
+	return a.seq < b.seq
+}
+
+func (h items[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *items[T]) Push(x any) { *h = append(*h, x.(item[T])) }
+
+func (h *items[T]) Pop() any {
+	old := *h
+	n := len(old)
+	it := old[n-1]
+	old[n-1] = item[T]{} // drop the reference to the job
+	*h = old[:n-1]
+	return it
+}
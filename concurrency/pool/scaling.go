@@ -0,0 +1,256 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sample is what the controller saw during one interval.
+type Sample struct {
+	Workers    int
+	Busy       int
+	QueueDepth int
+	Completed  int64
+	AvgLatency time.Duration
+}
+
+// ScalingPool is a worker pool that grows while jobs pile up and shrinks
+// back when it is idle, so long running services don't need a fixed
+// totalworker constant.
+type ScalingPool struct {
+	cfg     config
+	jobs    chan task
+	retire  chan struct{}
+	stop    chan struct{}
+	workers sync.WaitGroup
+	control sync.WaitGroup
+	moves   sync.WaitGroup // resizes in progress, which Close waits for
+
+	mu       sync.RWMutex
+	closed   bool
+	size     int
+	min, max int // bounds, copied from cfg so SetBounds can change them
+	lastMove time.Time
+
+	busy      atomic.Int64
+	completed atomic.Int64
+	latency   atomic.Int64 // summed nanoseconds since the last sample
+
+	tr      tracker
+	spawned atomic.Int64 // workers ever started, to number the next one
+}
+
+// NewScaling starts the minimum number of workers and the controller. By
+// default it scales between 1 and GOMAXPROCS workers, sampling every 100ms.
+func NewScaling(opts ...Option) (*ScalingPool, error) {
+
+	cfg, err := build(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ScalingPool{
+		cfg:    cfg,
+		jobs:   make(chan task, cfg.queue),
+		retire: make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+	p.tr.obs, p.tr.labels = cfg.obs, cfg.labels
+	p.min, p.max = cfg.min, cfg.max
+
+	for i := 0; i < cfg.min; i++ {
+		p.spawn()
+	}
+	p.size = cfg.min
+
+	p.control.Add(1)
+	go p.controller()
+
+	return p, nil
+}
+
+// Submit queues job, blocking while the buffer is full or until ctx is
+// done. Like Pool.Submit it refuses qos.Low work with ErrFull rather than
+// wait.
+func (p *ScalingPool) Submit(ctx context.Context, job func()) error {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	return send(ctx, p.cfg.quota, p.jobs, &p.tr, job)
+}
+
+// Workers returns the current number of workers.
+func (p *ScalingPool) Workers() int {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.size
+}
+
+// Close stops the controller, runs the queued jobs and waits for workers.
+func (p *ScalingPool) Close() {
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.stop)
+	p.mu.Unlock()
+
+	p.control.Wait()
+	p.moves.Wait()
+	close(p.jobs)
+	p.workers.Wait()
+}
+
+// Bounds returns the current worker range.
+func (p *ScalingPool) Bounds() (min, max int) {
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.min, p.max
+}
+
+// SetBounds changes the worker range and moves the pool into it at once,
+// ignoring the cooldown. Shrinking waits for workers to finish their
+// current job.
+func (p *ScalingPool) SetBounds(min, max int) error {
+
+	if min < 1 {
+		return errors.New("pool: need at least one worker")
+	}
+	if max < min {
+		return errors.New("pool: max workers below min workers")
+	}
+
+	p.mu.Lock()
+	p.min, p.max = min, max
+	size := p.size
+	p.mu.Unlock()
+
+	p.move(size, "bounds", false)
+
+	return nil
+}
+
+func (p *ScalingPool) spawn() {
+
+	worker := int(p.spawned.Add(1) - 1)
+
+	p.workers.Add(1)
+	go func() {
+		defer p.workers.Done()
+		for {
+			select {
+			case t, ok := <-p.jobs:
+				if !ok {
+					return
+				}
+				p.busy.Add(1)
+				start := time.Now()
+				p.tr.run(worker, t)
+				p.latency.Add(int64(time.Since(start)))
+				p.completed.Add(1)
+				p.busy.Add(-1)
+			case <-p.retire:
+				return
+			}
+		}
+	}()
+}
+
+func (p *ScalingPool) controller() {
+
+	defer p.control.Done()
+
+	ticker := time.NewTicker(p.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.evaluate()
+		}
+	}
+}
+
+func (p *ScalingPool) evaluate() {
+
+	completed := p.completed.Swap(0)
+	latency := p.latency.Swap(0)
+
+	s := Sample{
+		Workers:    p.Workers(),
+		Busy:       int(p.busy.Load()),
+		QueueDepth: len(p.jobs),
+		Completed:  completed,
+	}
+	if completed > 0 {
+		s.AvgLatency = time.Duration(latency / completed)
+	}
+	if p.cfg.onSample != nil {
+		p.cfg.onSample(s)
+	}
+
+	switch {
+	case s.QueueDepth >= p.cfg.upDepth*s.Workers:
+		p.resize(s.Workers+1, "queue depth")
+	case p.cfg.targetLatency > 0 && s.QueueDepth > 0 && s.AvgLatency > p.cfg.targetLatency:
+		p.resize(s.Workers+1, "latency")
+	case s.QueueDepth == 0 && s.Busy < s.Workers:
+		p.resize(s.Workers-1, "idle")
+	}
+}
+
+func (p *ScalingPool) resize(to int, reason string) {
+	p.move(to, reason, true)
+}
+
+// move resizes to the nearest size within the bounds. With cooldown set it
+// does nothing if the last move was too recent.
+func (p *ScalingPool) move(to int, reason string, cooldown bool) {
+
+	p.mu.Lock()
+	to = min(max(to, p.min), p.max)
+	from := p.size
+	if to == from || p.closed || cooldown && time.Since(p.lastMove) < p.cfg.cooldown {
+		p.mu.Unlock()
+		return
+	}
+	p.size = to
+	p.lastMove = time.Now()
+	p.moves.Add(1)
+	p.mu.Unlock()
+	defer p.moves.Done()
+
+	for i := from; i < to; i++ {
+		p.spawn()
+	}
+	for i := to; i < from; i++ {
+		// an idle worker picks this up between jobs; once Close has begun
+		// the workers exit on the closed queue instead
+		select {
+		case p.retire <- struct{}{}:
+		case <-p.stop:
+			return
+		}
+	}
+
+	if p.cfg.onScale != nil {
+		p.cfg.onScale(from, to, reason)
+	}
+}
@@ -0,0 +1,96 @@
+// Package qos carries how urgent a piece of work is in its context, so one
+// decision at the edge of a request reaches every subsystem the request
+// touches:
+//
+//	ctx = qos.With(ctx, qos.High)
+//
+// The convention is to set the level once, where work enters the process,
+// and only read it further down. Nothing in the repo raises a level on its
+// own. A context without a level is Normal, so code that never heard of
+// qos keeps its behaviour.
+//
+// What the levels mean to each subsystem:
+//
+//   - pool.PriorityPool.SubmitContext uses the level as the priority.
+//   - pool.Pool and pool.ScalingPool refuse Low work with ErrFull instead
+//     of waiting for room in a full queue.
+//   - pool.Queue.PushContext uses the level as the priority.
+//   - ratelimit.Limiter.Wait lets Critical work through at once, still
+//     spending its tokens, and makes Low work leave half the burst for
+//     everyone else.
+package qos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Level is how urgent work is. Higher is more urgent.
+type Level int
+
+const (
+	Low      Level = -1 // background work that may be shed or delayed
+	Normal   Level = 0  // the default
+	High     Level = 1  // a user is waiting
+	Critical Level = 2  // must not wait for anything it can avoid
+)
+
+var names = map[Level]string{Low: "low", Normal: "normal", High: "high", Critical: "critical"}
+
+func (l Level) String() string {
+
+	if s, ok := names[l]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Parse reads a level written by String, in any case.
+func Parse(s string) (Level, error) {
+
+	for l, name := range names {
+		if strings.EqualFold(s, name) {
+			return l, nil
+		}
+	}
+
+	return Normal, fmt.Errorf("qos: unknown level %q", s)
+}
+
+type key struct{}
+
+// With returns a copy of ctx carrying level.
+func With(ctx context.Context, level Level) context.Context {
+	return context.WithValue(ctx, key{}, level)
+}
+
+// From returns the level ctx carries, or Normal.
+func From(ctx context.Context) Level {
+
+	if l, ok := ctx.Value(key{}).(Level); ok {
+		return l
+	}
+
+	return Normal
+}
+
+// Header is the request header Handler reads the level from.
+const Header = "X-QoS"
+
+// Handler sets the level of every request's context from its X-QoS
+// header. Requests without one, or with one it can't read, are Normal.
+// Put it in front only of handlers whose clients may be trusted to say
+// how urgent they are.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h := r.Header.Get(Header); h != "" {
+			if l, err := Parse(h); err == nil {
+				r = r.WithContext(With(r.Context(), l))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
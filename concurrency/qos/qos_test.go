@@ -0,0 +1,62 @@
+package qos_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pacx/concurrency/qos"
+)
+
+func TestWithFrom(t *testing.T) {
+
+	ctx := context.Background()
+	if l := qos.From(ctx); l != qos.Normal {
+		t.Errorf("Expected Normal without a level but got %v", l)
+	}
+
+	ctx = qos.With(ctx, qos.High)
+	child, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if l := qos.From(child); l != qos.High {
+		t.Errorf("Expected the level to reach child contexts but got %v", l)
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	for _, l := range []qos.Level{qos.Low, qos.Normal, qos.High, qos.Critical} {
+		if got, err := qos.Parse(l.String()); err != nil || got != l {
+			t.Errorf("Expected %v back but got %v, %v", l, got, err)
+		}
+	}
+	if l, err := qos.Parse("HIGH"); err != nil || l != qos.High {
+		t.Errorf("Expected High in any case but got %v, %v", l, err)
+	}
+	if _, err := qos.Parse("urgent"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+	if s := qos.Level(7).String(); s != "Level(7)" {
+		t.Errorf("Expected Level(7) but got %s", s)
+	}
+}
+
+func TestHandler(t *testing.T) {
+
+	var got qos.Level
+	h := qos.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = qos.From(r.Context())
+	}))
+
+	for header, want := range map[string]qos.Level{"": qos.Normal, "critical": qos.Critical, "low": qos.Low, "bogus": qos.Normal} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			r.Header.Set(qos.Header, header)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		if got != want {
+			t.Errorf("Expected %v for %q but got %v", want, header, got)
+		}
+	}
+}
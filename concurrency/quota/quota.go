@@ -0,0 +1,405 @@
+// Package quota keeps tenants of one process from starving each other. Each
+// tenant has budgets for how much of its work may run at once, how fast it
+// may start new work and how much memory its running work may claim:
+//
+//	m, _ := quota.New(
+//		quota.WithDefaultLimits(quota.Limits{Concurrency: 4}),
+//		quota.WithLimits("batch", quota.Limits{Concurrency: 1, Rate: 10}),
+//		quota.WithMetrics(reg),
+//	)
+//	release, err := m.Acquire(ctx, "batch", 64<<20)
+//	defer release()
+//
+// The pools enforce it for work submitted with a tenant in its context, see
+// pool.WithQuota and With, and pool.Queue.PushQuota for work queued by
+// hand. With WithMetrics every tenant's usage is kept in
+// a metrics.Registry, so it reaches a collector with metrics.PushEvery like
+// any other metric.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"pacx/concurrency/ratelimit"
+	"pacx/metrics"
+	"pacx/options"
+)
+
+// ErrExceeded is wrapped by the errors of work refused for being over a
+// budget.
+var ErrExceeded = errors.New("quota: exceeded")
+
+// Limits are the budgets of one tenant. A zero field is unlimited.
+type Limits struct {
+	Concurrency int     // work holding the quota at once
+	Rate        float64 // acquisitions per second
+	Burst       int     // acquisitions above Rate in one go; defaults to 1
+	Memory      int64   // bytes claimed by the work holding the quota
+}
+
+// Usage is what a tenant holds and has been given or refused.
+type Usage struct {
+	Tenant   string
+	Limits   Limits
+	InFlight int
+	Memory   int64
+	Admitted int64
+	Rejected int64 // refused by TryAcquire or by an Acquire larger than the memory budget
+}
+
+type tenant struct {
+	name    string
+	limits  Limits
+	limiter *ratelimit.Limiter // nil without a rate
+
+	inFlight           int
+	memory             int64
+	admitted, rejected int64
+	changed            chan struct{} // closed and replaced on every release
+
+	// nil without WithMetrics
+	inFlightG, memoryG *metrics.Gauge
+	admittedC          *metrics.Counter
+	rejectedC          *metrics.Counter
+}
+
+type config struct {
+	def     Limits
+	tenants map[string]Limits
+	reg     *metrics.Registry
+}
+
+// Option configures a Manager.
+type Option = options.Option[config]
+
+func checkLimits(l Limits) error {
+	if l.Concurrency < 0 || l.Rate < 0 || l.Burst < 0 || l.Memory < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// WithDefaultLimits sets the limits of tenants without their own.
+// Defaults to unlimited.
+func WithDefaultLimits(l Limits) Option {
+	return options.New("WithDefaultLimits", func(c *config) error {
+		if err := checkLimits(l); err != nil {
+			return err
+		}
+		c.def = l
+		return nil
+	})
+}
+
+// WithLimits sets the limits of one tenant.
+func WithLimits(tenant string, l Limits) Option {
+	return options.New("WithLimits", func(c *config) error {
+		if err := checkLimits(l); err != nil {
+			return err
+		}
+		c.tenants[tenant] = l
+		return nil
+	})
+}
+
+// WithMetrics keeps every tenant's usage in r as the gauges
+// quota.<tenant>.inflight and quota.<tenant>.memory and the counters
+// quota.<tenant>.admitted and quota.<tenant>.rejected.
+func WithMetrics(r *metrics.Registry) Option {
+	return options.New("WithMetrics", func(c *config) error {
+		if r == nil {
+			return errors.New("nil registry")
+		}
+		c.reg = r
+		return nil
+	})
+}
+
+// Manager holds the budgets and usage of every tenant. It is safe for
+// concurrent use.
+type Manager struct {
+	reg *metrics.Registry
+
+	mu      sync.Mutex
+	def     Limits
+	limits  map[string]Limits
+	tenants map[string]*tenant
+}
+
+// New returns a manager. Tenants come into being on first use.
+func New(opts ...Option) (*Manager, error) {
+
+	cfg, err := options.Build(config{tenants: make(map[string]Limits)}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Manager{
+		reg:     cfg.reg,
+		def:     cfg.def,
+		limits:  cfg.tenants,
+		tenants: make(map[string]*tenant),
+	}, nil
+}
+
+// get returns the tenant called name, creating it. mu must be held.
+func (m *Manager) get(name string) *tenant {
+
+	if t, ok := m.tenants[name]; ok {
+		return t
+	}
+
+	t := &tenant{name: name, changed: make(chan struct{})}
+	if m.reg != nil {
+		prefix := "quota." + name + "."
+		t.inFlightG = m.reg.Gauge(prefix + "inflight")
+		t.memoryG = m.reg.Gauge(prefix + "memory")
+		t.admittedC = m.reg.Counter(prefix + "admitted")
+		t.rejectedC = m.reg.Counter(prefix + "rejected")
+	}
+	l, ok := m.limits[name]
+	if !ok {
+		l = m.def
+	}
+	t.setLimits(l)
+	m.tenants[name] = t
+
+	return t
+}
+
+func (t *tenant) setLimits(l Limits) {
+
+	t.limits = l
+	switch {
+	case l.Rate <= 0:
+		t.limiter = nil
+	case t.limiter == nil || t.limiter.Burst() != max(l.Burst, 1):
+		t.limiter = ratelimit.New(l.Rate, l.Burst)
+	default:
+		t.limiter.SetRate(l.Rate)
+	}
+}
+
+// SetLimits changes the limits of tenant from now on. Work that already
+// holds the quota keeps it.
+func (m *Manager) SetLimits(tenant string, l Limits) error {
+
+	if err := checkLimits(l); err != nil {
+		return fmt.Errorf("quota: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limits[tenant] = l
+	t := m.get(tenant)
+	t.setLimits(l)
+	t.wake() // a raised limit may let waiters in
+
+	return nil
+}
+
+// fits reports whether one more piece of work of mem bytes is within the
+// budgets. mu must be held.
+func (t *tenant) fits(mem int64) bool {
+
+	if t.limits.Concurrency > 0 && t.inFlight >= t.limits.Concurrency {
+		return false
+	}
+	if t.limits.Memory > 0 && t.memory+mem > t.limits.Memory {
+		return false
+	}
+
+	return true
+}
+
+// take books the slot and memory of the work. mu must be held.
+func (t *tenant) take(mem int64) {
+
+	t.inFlight++
+	t.memory += mem
+	if t.inFlightG != nil {
+		t.inFlightG.Set(float64(t.inFlight))
+		t.memoryG.Set(float64(t.memory))
+	}
+}
+
+// admit counts work that got through every limit. mu must be held.
+func (t *tenant) admit() {
+
+	t.admitted++
+	if t.admittedC != nil {
+		t.admittedC.Add(1)
+	}
+}
+
+// reject counts refused work. mu must be held.
+func (t *tenant) reject() {
+
+	t.rejected++
+	if t.rejectedC != nil {
+		t.rejectedC.Add(1)
+	}
+}
+
+// wake lets waiters check again. mu must be held.
+func (t *tenant) wake() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// release returns the func that gives the quota of mem bytes back; calling
+// it more than once does nothing.
+func (m *Manager) release(t *tenant, mem int64) func() {
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+
+			t.inFlight--
+			t.memory -= mem
+			if t.inFlightG != nil {
+				t.inFlightG.Set(float64(t.inFlight))
+				t.memoryG.Set(float64(t.memory))
+			}
+			t.wake()
+		})
+	}
+}
+
+// Acquire waits until tenant may start work claiming mem bytes, then books
+// it until release is called. It waits for a free slot and memory first
+// and then for the rate, and gives up with ctx.Err() if ctx is done first.
+// Work larger than the whole memory budget can never fit and is refused
+// with an error wrapping ErrExceeded. A negative mem is an error.
+func (m *Manager) Acquire(ctx context.Context, tenant string, mem int64) (release func(), err error) {
+
+	if mem < 0 {
+		return nil, fmt.Errorf("quota: %s claims a negative %d bytes", tenant, mem)
+	}
+
+	for {
+		m.mu.Lock()
+		t := m.get(tenant)
+		if t.limits.Memory > 0 && mem > t.limits.Memory {
+			t.reject()
+			m.mu.Unlock()
+			return nil, fmt.Errorf("quota: %s needs %d bytes of a %d byte budget: %w", tenant, mem, t.limits.Memory, ErrExceeded)
+		}
+		if t.fits(mem) {
+			t.take(mem)
+			limiter := t.limiter
+			m.mu.Unlock()
+
+			// the slot is held while waiting for the rate, but the work
+			// only counts as admitted once it gets through
+			release = m.release(t, mem)
+			if limiter != nil {
+				if err := limiter.Wait(ctx); err != nil {
+					release()
+					return nil, err
+				}
+			}
+			m.mu.Lock()
+			t.admit()
+			m.mu.Unlock()
+			return release, nil
+		}
+		changed := t.changed
+		m.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryAcquire is Acquire without waiting: work that does not fit right now
+// is refused with an error wrapping ErrExceeded.
+func (m *Manager) TryAcquire(tenant string, mem int64) (release func(), err error) {
+
+	if mem < 0 {
+		return nil, fmt.Errorf("quota: %s claims a negative %d bytes", tenant, mem)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t := m.get(tenant)
+	var over string
+	switch {
+	case t.limits.Concurrency > 0 && t.inFlight >= t.limits.Concurrency:
+		over = "concurrency"
+	case t.limits.Memory > 0 && t.memory+mem > t.limits.Memory:
+		over = "memory"
+	case t.limiter != nil && !t.limiter.Allow():
+		over = "rate"
+	}
+	if over != "" {
+		t.reject()
+		return nil, fmt.Errorf("quota: %s over its %s limit: %w", tenant, over, ErrExceeded)
+	}
+	t.take(mem)
+	t.admit()
+
+	return m.release(t, mem), nil
+}
+
+// Usage returns the usage of every tenant seen so far, by name.
+func (m *Manager) Usage() []Usage {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	out := make([]Usage, 0, len(m.tenants))
+	for _, name := range slices.Sorted(maps.Keys(m.tenants)) {
+		t := m.tenants[name]
+		out = append(out, Usage{
+			Tenant:   name,
+			Limits:   t.limits,
+			InFlight: t.inFlight,
+			Memory:   t.memory,
+			Admitted: t.admitted,
+			Rejected: t.rejected,
+		})
+	}
+
+	return out
+}
+
+type tenantKey struct{}
+type memoryKey struct{}
+
+// With returns a copy of ctx for work of tenant. The pools take the quota
+// of the tenant in the context they are given.
+func With(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// Tenant returns the tenant ctx carries, or "".
+func Tenant(ctx context.Context) string {
+	s, _ := ctx.Value(tenantKey{}).(string)
+	return s
+}
+
+// WithMemory returns a copy of ctx saying the work it is for claims bytes
+// of its tenant's memory budget.
+func WithMemory(ctx context.Context, bytes int64) context.Context {
+	return context.WithValue(ctx, memoryKey{}, bytes)
+}
+
+// Memory returns the bytes ctx claims, or 0.
+func Memory(ctx context.Context) int64 {
+	b, _ := ctx.Value(memoryKey{}).(int64)
+	return b
+}
@@ -0,0 +1,220 @@
+package quota_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/concurrency/quota"
+	"pacx/metrics"
+)
+
+func TestConcurrency(t *testing.T) {
+
+	m, _ := quota.New(quota.WithLimits("a", quota.Limits{Concurrency: 2}))
+	ctx := context.Background()
+
+	r1, _ := m.Acquire(ctx, "a", 0)
+	r2, _ := m.Acquire(ctx, "a", 0)
+	if _, err := m.TryAcquire("a", 0); !errors.Is(err, quota.ErrExceeded) {
+		t.Errorf("Expected a third to be refused but got %v", err)
+	}
+	// other tenants have the unlimited default
+	for i := 0; i < 10; i++ {
+		if _, err := m.TryAcquire("b", 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := make(chan struct{})
+	go func() {
+		release, err := m.Acquire(ctx, "a", 0)
+		if err == nil {
+			release()
+		}
+		close(got)
+	}()
+	select {
+	case <-got:
+		t.Fatal("Expected Acquire to wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	r1()
+	r1() // a second release gives nothing back
+	select {
+	case <-got:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the release to let the waiter in")
+	}
+	r2()
+
+	u := m.Usage()
+	if len(u) != 2 || u[0].Tenant != "a" || u[0].InFlight != 0 || u[0].Admitted != 3 || u[0].Rejected != 1 || u[1].Admitted != 10 {
+		t.Errorf("Expected a with 3 admitted and 1 rejected and b with 10 but got %+v", u)
+	}
+}
+
+func TestMemory(t *testing.T) {
+
+	m, _ := quota.New(quota.WithDefaultLimits(quota.Limits{Memory: 100}))
+	ctx := context.Background()
+
+	release, err := m.Acquire(ctx, "a", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.TryAcquire("a", 50); !errors.Is(err, quota.ErrExceeded) {
+		t.Errorf("Expected 60+50 of 100 bytes to be refused but got %v", err)
+	}
+	if _, err := m.Acquire(ctx, "a", 101); !errors.Is(err, quota.ErrExceeded) {
+		t.Errorf("Expected more than the budget to be refused without waiting but got %v", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Acquire(short, "a", 50); err != context.DeadlineExceeded {
+		t.Errorf("Expected to give up waiting for memory but got %v", err)
+	}
+
+	release()
+	if r, err := m.TryAcquire("a", 100); err != nil {
+		t.Errorf("Expected the whole budget after the release but got %v", err)
+	} else {
+		r()
+	}
+
+	if _, err := m.TryAcquire("a", -100); err == nil {
+		t.Error("Expected an error for negative memory")
+	}
+	if _, err := m.Acquire(ctx, "a", -100); err == nil {
+		t.Error("Expected an error for negative memory")
+	}
+	if u := m.Usage(); u[0].Memory != 0 || u[0].InFlight != 0 {
+		t.Errorf("Expected nothing booked for negative memory but got %+v", u[0])
+	}
+}
+
+func TestRate(t *testing.T) {
+
+	m, _ := quota.New(quota.WithLimits("a", quota.Limits{Rate: 100, Burst: 2}))
+
+	for i := 0; i < 2; i++ {
+		r, err := m.TryAcquire("a", 0)
+		if err != nil {
+			t.Fatalf("Expected burst %d to be allowed but got %v", i, err)
+		}
+		r()
+	}
+	if _, err := m.TryAcquire("a", 0); !errors.Is(err, quota.ErrExceeded) {
+		t.Errorf("Expected the rate to refuse but got %v", err)
+	}
+
+	start := time.Now()
+	r, err := m.Acquire(context.Background(), "a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r()
+	if took := time.Since(start); took < 5*time.Millisecond {
+		t.Errorf("Expected to wait for a token at 100/s but took %v", took)
+	}
+}
+
+func TestRateCancelled(t *testing.T) {
+
+	m, _ := quota.New(quota.WithLimits("a", quota.Limits{Rate: 0.1, Burst: 1}))
+
+	r, err := m.TryAcquire("a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Acquire(ctx, "a", 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the rate wait to run out but got %v", err)
+	}
+	if u := m.Usage(); u[0].Admitted != 1 || u[0].InFlight != 0 {
+		t.Errorf("Expected only the first to be admitted and nothing in flight but got %+v", u[0])
+	}
+}
+
+// waitingCtx closes waiting once Acquire waits on it.
+type waitingCtx struct {
+	context.Context
+	once    sync.Once
+	waiting chan struct{}
+}
+
+func (c *waitingCtx) Done() <-chan struct{} {
+	c.once.Do(func() { close(c.waiting) })
+	return c.Context.Done()
+}
+
+func TestSetLimitsWakesWaiters(t *testing.T) {
+
+	m, _ := quota.New(quota.WithLimits("a", quota.Limits{Concurrency: 1}))
+	m.TryAcquire("a", 0)
+
+	ctx := &waitingCtx{Context: context.Background(), waiting: make(chan struct{})}
+	got := make(chan error, 1)
+	go func() {
+		_, err := m.Acquire(ctx, "a", 0)
+		got <- err
+	}()
+
+	<-ctx.waiting
+	if err := m.SetLimits("a", quota.Limits{Concurrency: 2}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the raised limit to let the waiter in")
+	}
+
+	if err := m.SetLimits("a", quota.Limits{Memory: -1}); err == nil {
+		t.Error("Expected an error for a negative limit")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+
+	var reg metrics.Registry
+	m, _ := quota.New(quota.WithLimits("a", quota.Limits{Concurrency: 1}), quota.WithMetrics(&reg))
+
+	release, _ := m.TryAcquire("a", 42)
+	m.TryAcquire("a", 0)
+
+	s := reg.Snapshot("test")
+	if s.Gauges["quota.a.inflight"] != 1 || s.Gauges["quota.a.memory"] != 42 || s.Counters["quota.a.admitted"] != 1 || s.Counters["quota.a.rejected"] != 1 {
+		t.Errorf("Expected the usage of a in the registry but got %+v", s)
+	}
+
+	release()
+	if s := reg.Snapshot("test"); s.Gauges["quota.a.inflight"] != 0 || s.Gauges["quota.a.memory"] != 0 {
+		t.Errorf("Expected the release in the registry but got %+v", s)
+	}
+}
+
+func TestContext(t *testing.T) {
+
+	ctx := quota.WithMemory(quota.With(context.Background(), "a"), 10)
+	if quota.Tenant(ctx) != "a" || quota.Memory(ctx) != 10 {
+		t.Errorf("Expected tenant a with 10 bytes but got %q and %d", quota.Tenant(ctx), quota.Memory(ctx))
+	}
+	if quota.Tenant(context.Background()) != "" {
+		t.Error("Expected no tenant")
+	}
+
+	if _, err := quota.New(quota.WithDefaultLimits(quota.Limits{Concurrency: -1})); err == nil {
+		t.Error("Expected an error for a negative limit")
+	}
+}
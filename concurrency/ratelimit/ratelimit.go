@@ -0,0 +1,175 @@
+// Package ratelimit is a token bucket: tokens refill at a steady rate up to
+// a burst size and every event spends one (or n) of them.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"pacx/concurrency/qos"
+)
+
+// Limiter is safe for concurrent use.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64 // may go negative while reservations are waiting
+	last   time.Time
+	now    func() time.Time
+}
+
+// New returns a limiter refilling rate tokens per second with room for
+// burst tokens. It starts full.
+func New(rate float64, burst int) *Limiter {
+
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// advance refills the bucket up to now. mu must be held.
+func (l *Limiter) advance() time.Time {
+
+	now := l.now()
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens = min(l.burst, l.tokens+elapsed*l.rate)
+	}
+	l.last = now
+
+	return now
+}
+
+// Allow is AllowN(1).
+func (l *Limiter) Allow() bool {
+	return l.AllowN(1)
+}
+
+// AllowN spends n tokens if they are all available right now.
+func (l *Limiter) AllowN(n int) bool {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance()
+	if l.tokens < float64(n) {
+		return false
+	}
+	l.tokens -= float64(n)
+
+	return true
+}
+
+// Wait is WaitN(ctx, 1).
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN spends n tokens, sleeping until the bucket has refilled enough.
+// n may be larger than the burst; the caller then simply waits longer. If
+// ctx is done first the tokens are given back and ctx.Err() is returned.
+//
+// The qos level of ctx changes how long it waits: qos.Critical does not
+// wait at all, though it still spends the tokens and so delays everyone
+// else, and qos.Low waits until the bucket would keep half its burst
+// after spending.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	wait, ok := l.reserve(qos.From(ctx), n)
+	if !ok {
+		l.refund(n)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.refund(n)
+		return ctx.Err()
+	}
+}
+
+// reserve spends n tokens and returns how long a caller at level has to
+// wait for them, or false if the bucket does not refill at all.
+func (l *Limiter) reserve(level qos.Level, n int) (time.Duration, bool) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance()
+	l.tokens -= float64(n)
+	if level >= qos.Critical {
+		return 0, true
+	}
+
+	deficit := -l.tokens
+	if level <= qos.Low {
+		deficit += l.burst / 2
+	}
+	if deficit <= 0 {
+		return 0, true
+	}
+	if l.rate <= 0 {
+		return 0, false
+	}
+
+	return time.Duration(deficit / l.rate * float64(time.Second)), true
+}
+
+func (l *Limiter) refund(n int) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance()
+	l.tokens = min(l.burst, l.tokens+float64(n))
+}
+
+// SetRate changes the refill rate from now on.
+func (l *Limiter) SetRate(rate float64) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance()
+	l.rate = rate
+}
+
+// Rate returns the refill rate in tokens per second.
+func (l *Limiter) Rate() float64 {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate
+}
+
+// Burst returns the bucket size.
+func (l *Limiter) Burst() int {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.burst)
+}
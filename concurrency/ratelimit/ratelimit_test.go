@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pacx/concurrency/qos"
+)
+
+func fakeClock(l *Limiter) *time.Time {
+	now := time.Unix(0, 0)
+	l.last = now
+	l.now = func() time.Time { return now }
+	return &now
+}
+
+func TestAllow(t *testing.T) {
+
+	l := New(10, 3)
+	now := fakeClock(l)
+
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("Expected burst token %d to be allowed", i)
+		}
+	}
+	if l.Allow() {
+		t.Fatal("Expected the empty bucket to refuse")
+	}
+
+	*now = now.Add(100 * time.Millisecond) // one token at 10/s
+	if !l.Allow() || l.Allow() {
+		t.Error("Expected exactly one refilled token")
+	}
+
+	*now = now.Add(time.Hour)
+	if l.AllowN(4) {
+		t.Error("Expected refill to stop at burst")
+	}
+}
+
+func TestWaitN(t *testing.T) {
+
+	l := New(1000, 10)
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := l.WaitN(ctx, 60); err != nil { // 10 from the burst, 50 at 1000/s
+		t.Fatal(err)
+	}
+	if took := time.Since(start); took < 40*time.Millisecond {
+		t.Errorf("Expected to wait about 50ms but took %v", took)
+	}
+}
+
+func TestWaitCancelRefunds(t *testing.T) {
+
+	l := New(1, 1)
+	now := fakeClock(l)
+	l.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.WaitN(ctx, 5); err != context.DeadlineExceeded {
+		t.Fatalf("Expected DeadlineExceeded but got %v", err)
+	}
+
+	*now = now.Add(time.Second)
+	if !l.Allow() {
+		t.Error("Expected the cancelled reservation to be refunded")
+	}
+}
+
+func TestWaitQoS(t *testing.T) {
+
+	l := New(100, 10)
+	now := fakeClock(l)
+	l.AllowN(5)
+
+	// Normal only needs the token it spends
+	if wait, _ := l.reserve(qos.Normal, 1); wait != 0 {
+		t.Errorf("Expected no wait with tokens left but got %v", wait)
+	}
+
+	// Low leaves 5 of the 10: with 4 left it waits for 2 at 100/s
+	if wait, _ := l.reserve(qos.Low, 1); wait != 20*time.Millisecond {
+		t.Errorf("Expected low work to wait 20ms but got %v", wait)
+	}
+
+	// once they have refilled, Low goes through as well
+	*now = now.Add(40 * time.Millisecond)
+	if err := l.Wait(qos.With(context.Background(), qos.Low)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Critical goes straight through an empty bucket and leaves the debt
+	l.AllowN(6) // all that is left
+	if err := l.WaitN(qos.With(context.Background(), qos.Critical), 50); err != nil {
+		t.Fatal(err)
+	}
+	if l.Allow() {
+		t.Error("Expected the critical tokens to be spent")
+	}
+	if wait, _ := l.reserve(qos.Normal, 1); wait != 510*time.Millisecond {
+		t.Errorf("Expected normal work to wait 510ms behind the debt but got %v", wait)
+	}
+}
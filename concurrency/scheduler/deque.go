@@ -0,0 +1,70 @@
+package scheduler
+
+import "sync"
+
+// deque is a worker's local task list. The owner pushes and pops at the
+// bottom (LIFO, cache warm) while thieves take from the top (FIFO, the
+// oldest and usually largest pieces of work).
+type deque struct {
+	mu    sync.Mutex
+	tasks []func()
+	head  int
+}
+
+func (d *deque) push(task func()) {
+	d.mu.Lock()
+	d.tasks = append(d.tasks, task)
+	d.mu.Unlock()
+}
+
+func (d *deque) pop() func() {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.tasks) == d.head {
+		return nil
+	}
+	last := len(d.tasks) - 1
+	task := d.tasks[last]
+	d.tasks[last] = nil
+	d.tasks = d.tasks[:last]
+	d.reset()
+
+	return task
+}
+
+func (d *deque) steal() func() {
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.tasks) == d.head {
+		return nil
+	}
+	task := d.tasks[d.head]
+	d.tasks[d.head] = nil
+	d.head++
+	d.reset()
+
+	return task
+}
+
+// reset reclaims the stolen prefix once it is more than half of tasks,
+// so a deque that is pushed to while it is stolen from, and never runs
+// empty, does not grow without bound.
+func (d *deque) reset() {
+	if d.head <= len(d.tasks)/2 {
+		return
+	}
+	n := copy(d.tasks, d.tasks[d.head:])
+	clear(d.tasks[n:])
+	d.tasks = d.tasks[:n]
+	d.head = 0
+}
+
+func (d *deque) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.tasks) - d.head
+}
@@ -0,0 +1,35 @@
+package scheduler
+
+import "testing"
+
+func TestDequeReclaimsStolen(t *testing.T) {
+
+	var d deque
+	for range 8 {
+		d.push(func() {})
+	}
+
+	// the owner keeps pushing while thieves keep taking, so it never runs
+	// empty
+	for i := 0; i < 100_000; i++ {
+		d.push(func() {})
+		if d.steal() == nil {
+			t.Fatal("Expected a task to steal")
+		}
+	}
+
+	if n := d.len(); n != 8 {
+		t.Errorf("Expected 8 tasks left but got %d", n)
+	}
+	if c := cap(d.tasks); c > 64 {
+		t.Errorf("Expected the stolen tasks to be reclaimed but the deque holds room for %d", c)
+	}
+	for range 8 {
+		if d.pop() == nil {
+			t.Fatal("Expected a task to pop")
+		}
+	}
+	if d.pop() != nil || d.steal() != nil {
+		t.Error("Expected the deque to be empty")
+	}
+}
@@ -0,0 +1,193 @@
+// Package scheduler is a work-stealing alternative to the single shared
+// channel in concurrency/patterns/worker-pool.go. Each worker owns a deque;
+// submissions are spread across them and an idle worker steals from the
+// others instead of waiting, so one slow task can't hold up the jobs queued
+// behind it.
+package scheduler
+
+import (
+	"errors"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"pacx/options"
+)
+
+// ErrClosed is returned when submitting to a closed scheduler.
+var ErrClosed = errors.New("scheduler: closed")
+
+type config struct {
+	workers int
+}
+
+// Option configures a Scheduler.
+type Option = options.Option[config]
+
+// WithWorkers sets the number of workers, each with its own deque.
+func WithWorkers(n int) Option {
+	return options.New("WithWorkers", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one worker")
+		}
+		c.workers = n
+		return nil
+	})
+}
+
+// Scheduler runs tasks on a fixed set of workers.
+type Scheduler struct {
+	deques []*deque
+	next   atomic.Uint64 // round robin cursor for Submit
+	wake   chan struct{}
+	quit   chan struct{}
+	wg     sync.WaitGroup
+
+	// Submit holds mu for reading across the check and the push, so a
+	// task it accepts is queued before Close lets the workers finish
+	mu     sync.RWMutex
+	closed bool
+
+	executed atomic.Int64
+	stolen   atomic.Int64
+}
+
+// Stats counts what the workers did so far.
+type Stats struct {
+	Executed int64 // tasks run
+	Stolen   int64 // tasks run by a worker other than the one they were queued on
+}
+
+// New starts the workers, one per GOMAXPROCS unless WithWorkers says
+// otherwise.
+func New(opts ...Option) (*Scheduler, error) {
+
+	cfg, err := options.Build(config{workers: runtime.GOMAXPROCS(0)}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	workers := cfg.workers
+
+	s := &Scheduler{
+		deques: make([]*deque, workers),
+		wake:   make(chan struct{}, workers),
+		quit:   make(chan struct{}),
+	}
+	for i := range s.deques {
+		s.deques[i] = &deque{}
+	}
+
+	s.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go s.work(i)
+	}
+
+	return s, nil
+}
+
+// Submit queues task on the next worker's deque. It never blocks.
+func (s *Scheduler) Submit(task func()) error {
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrClosed
+	}
+	i := s.next.Add(1) % uint64(len(s.deques))
+	s.deques[i].push(task)
+	s.mu.RUnlock()
+
+	// a buffered token per worker is enough to make sure someone looks
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Pending returns the number of tasks waiting in the deques.
+func (s *Scheduler) Pending() int {
+
+	n := 0
+	for _, d := range s.deques {
+		n += d.len()
+	}
+
+	return n
+}
+
+// Stats returns the counters collected so far.
+func (s *Scheduler) Stats() Stats {
+	return Stats{Executed: s.executed.Load(), Stolen: s.stolen.Load()}
+}
+
+// Close stops accepting tasks, runs everything already queued and waits for
+// the workers to exit.
+func (s *Scheduler) Close() {
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) work(id int) {
+
+	defer s.wg.Done()
+
+	own := s.deques[id]
+
+	for {
+		if task := own.pop(); task != nil {
+			s.run(task)
+			continue
+		}
+		if task := s.trySteal(id); task != nil {
+			s.stolen.Add(1)
+			s.run(task)
+			continue
+		}
+
+		select {
+		case <-s.wake:
+		case <-s.quit:
+			// Submit is refused from now on, so one more empty scan means
+			// there is nothing left for this worker.
+			if own.len() == 0 && s.Pending() == 0 {
+				return
+			}
+		}
+	}
+}
+
+// trySteal walks the other deques starting at a random victim.
+func (s *Scheduler) trySteal(id int) func() {
+
+	n := len(s.deques)
+	start := rand.IntN(n)
+
+	for i := 0; i < n; i++ {
+		victim := (start + i) % n
+		if victim == id {
+			continue
+		}
+		if task := s.deques[victim].steal(); task != nil {
+			return task
+		}
+	}
+
+	return nil
+}
+
+func (s *Scheduler) run(task func()) {
+	task()
+	s.executed.Add(1)
+}
@@ -0,0 +1,166 @@
+package scheduler_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/concurrency/pool"
+	"pacx/concurrency/scheduler"
+)
+
+func TestRunsEverything(t *testing.T) {
+
+	s, _ := scheduler.New(scheduler.WithWorkers(4))
+
+	var done atomic.Int64
+	for i := 0; i < 1000; i++ {
+		s.Submit(func() { done.Add(1) })
+	}
+	s.Close()
+
+	if got := done.Load(); got != 1000 {
+		t.Errorf("Expected 1000 tasks done but got %d", got)
+	}
+	if got := s.Stats().Executed; got != 1000 {
+		t.Errorf("Expected 1000 executed but got %d", got)
+	}
+	if err := s.Submit(func() {}); err != scheduler.ErrClosed {
+		t.Errorf("Expected ErrClosed but got %v", err)
+	}
+}
+
+func TestSubmitDuringClose(t *testing.T) {
+
+	for range 20 {
+		s, _ := scheduler.New(scheduler.WithWorkers(2))
+
+		var accepted, ran atomic.Int64
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for s.Submit(func() { ran.Add(1) }) == nil {
+					accepted.Add(1)
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		s.Close()
+		wg.Wait()
+
+		if ran.Load() != accepted.Load() {
+			t.Fatalf("Expected every accepted task to run but %d of %d did", ran.Load(), accepted.Load())
+		}
+	}
+}
+
+func TestStealsFromBusyWorker(t *testing.T) {
+
+	s, _ := scheduler.New(scheduler.WithWorkers(2))
+
+	// Park one worker on a slow task, then queue more work. Round robin
+	// puts half of it behind the slow task; the idle worker has to steal
+	// those for the batch to finish.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var wg sync.WaitGroup
+	wg.Add(20)
+	for i := 0; i < 20; i++ {
+		s.Submit(wg.Done)
+	}
+	wg.Wait()
+	close(release)
+	s.Close()
+
+	if s.Stats().Stolen == 0 {
+		t.Error("Expected the idle worker to steal tasks")
+	}
+}
+
+// skewed returns task costs where every workers-th task is expensive, so
+// round robin placement alone would leave the workers unbalanced.
+func skewed(n, workers int) []time.Duration {
+
+	costs := make([]time.Duration, n)
+	for i := range costs {
+		costs[i] = 20 * time.Microsecond
+		if i%workers == 0 {
+			costs[i] = 500 * time.Microsecond
+		}
+	}
+
+	return costs
+}
+
+func spin(d time.Duration) {
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
+
+const benchWorkers = 4
+
+func BenchmarkWorkStealing(b *testing.B) {
+
+	costs := skewed(256, benchWorkers)
+
+	for b.Loop() {
+		s, _ := scheduler.New(scheduler.WithWorkers(benchWorkers))
+		for _, c := range costs {
+			s.Submit(func() { spin(c) })
+		}
+		s.Close()
+	}
+}
+
+func BenchmarkSharedChannel(b *testing.B) {
+
+	costs := skewed(256, benchWorkers)
+	ctx := context.Background()
+
+	for b.Loop() {
+		p, _ := pool.New(pool.WithWorkers(benchWorkers), pool.WithQueue(len(costs)))
+		for _, c := range costs {
+			p.Submit(ctx, func() { spin(c) })
+		}
+		p.Close()
+	}
+}
+
+func BenchmarkWorkStealingTiny(b *testing.B) {
+
+	s, _ := scheduler.New(scheduler.WithWorkers(benchWorkers))
+	var wg sync.WaitGroup
+
+	b.ReportAllocs()
+	for b.Loop() {
+		wg.Add(1)
+		s.Submit(wg.Done)
+	}
+	wg.Wait()
+	s.Close()
+}
+
+func BenchmarkSharedChannelTiny(b *testing.B) {
+
+	p, _ := pool.New(pool.WithWorkers(benchWorkers))
+	ctx := context.Background()
+	var wg sync.WaitGroup
+
+	b.ReportAllocs()
+	for b.Loop() {
+		wg.Add(1)
+		p.Submit(ctx, wg.Done)
+	}
+	wg.Wait()
+	p.Close()
+}
@@ -0,0 +1,14 @@
+package singleflight
+
+// Waiting reports how many callers have joined the call in flight for key.
+func (g *Group[T]) Waiting(key string) int {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.calls[key]; ok {
+		return c.dups
+	}
+
+	return 0
+}
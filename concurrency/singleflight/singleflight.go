@@ -0,0 +1,116 @@
+// Package singleflight collapses concurrent calls for the same key into a
+// single execution whose result is shared by every caller.
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is what every caller of a call whose fn panicked panics with.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: fn panicked: %v\n\n%s", p.Value, p.Stack)
+}
+
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+type call[T any] struct {
+	done  chan struct{}
+	val   T
+	err   error
+	panic *PanicError
+	dups  int
+}
+
+// Group deduplicates calls by key. The zero value is ready to use.
+type Group[T any] struct {
+	mu    sync.Mutex
+	calls map[string]*call[T]
+}
+
+// Do runs fn unless a call for key is already in flight, in which case it
+// waits for that one and returns its result. shared reports whether the
+// result went to more than one caller. If fn panics, every caller panics
+// with a *PanicError.
+func (g *Group[T]) Do(key string, fn func() (T, error)) (v T, err error, shared bool) {
+	return g.DoContext(context.Background(), key, fn)
+}
+
+// DoContext is Do where a caller waiting on someone else's call can give up
+// when ctx is done. The call itself keeps running for the others; the
+// caller that started it always waits for fn to return.
+func (g *Group[T]) DoContext(ctx context.Context, key string, fn func() (T, error)) (v T, err error, shared bool) {
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*call[T])
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return v, ctx.Err(), true
+		}
+		if c.panic != nil {
+			panic(c.panic)
+		}
+		return c.val, c.err, true
+	}
+
+	c := &call[T]{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.run(key, c, fn)
+	if c.panic != nil {
+		panic(c.panic)
+	}
+
+	g.mu.Lock()
+	shared = c.dups > 0
+	g.mu.Unlock()
+
+	return c.val, c.err, shared
+}
+
+func (g *Group[T]) run(key string, c *call[T], fn func() (T, error)) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			c.panic = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+
+		g.mu.Lock()
+		if g.calls[key] == c {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+
+	c.val, c.err = fn()
+}
+
+// Forget makes the next Do for key start a fresh call even if one is still
+// in flight. Callers already waiting keep waiting for the old one.
+func (g *Group[T]) Forget(key string) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.calls, key)
+}
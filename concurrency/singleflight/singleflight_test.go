@@ -0,0 +1,187 @@
+package singleflight_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/concurrency/singleflight"
+)
+
+// joined waits until n callers are waiting on the call in flight for key.
+func joined[T any](t *testing.T, g *singleflight.Group[T], key string, n int) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for g.Waiting(key) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d callers to join but got %d", n, g.Waiting(key))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDo(t *testing.T) {
+
+	var g singleflight.Group[int]
+
+	v, err, shared := g.Do("k", func() (int, error) { return 42, nil })
+	if v != 42 || err != nil || shared {
+		t.Errorf("Expected 42, nil, false but got %d, %v, %v", v, err, shared)
+	}
+
+	errBoom := errors.New("boom")
+	if _, err, _ := g.Do("k", func() (int, error) { return 0, errBoom }); err != errBoom {
+		t.Errorf("Expected %v but got %v", errBoom, err)
+	}
+}
+
+func TestDoDeduplicates(t *testing.T) {
+
+	const callers = 10
+
+	var (
+		g       singleflight.Group[string]
+		calls   atomic.Int32
+		sharedN atomic.Int32
+		wg      sync.WaitGroup
+	)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	fn := func() (string, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		return "value", nil
+	}
+
+	wg.Add(callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("k", fn)
+			if v != "value" || err != nil {
+				t.Errorf("Expected value, nil but got %q, %v", v, err)
+			}
+			if shared {
+				sharedN.Add(1)
+			}
+		}()
+	}
+
+	<-started
+	joined(t, &g, "k", callers-1)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected fn to run once but ran %d times", got)
+	}
+	if got := sharedN.Load(); got != callers {
+		t.Errorf("Expected all %d callers to see a shared result but got %d", callers, got)
+	}
+}
+
+func TestDoPanic(t *testing.T) {
+
+	var (
+		g  singleflight.Group[int]
+		wg sync.WaitGroup
+	)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	fn := func() (int, error) {
+		close(started)
+		<-release
+		panic("kaboom")
+	}
+
+	panics := make(chan any, 3)
+	do := func() {
+		defer wg.Done()
+		defer func() { panics <- recover() }()
+		g.Do("k", fn)
+	}
+
+	wg.Add(1)
+	go do()
+	<-started
+	wg.Add(2)
+	go do()
+	go do()
+	joined(t, &g, "k", 2)
+	close(release)
+	wg.Wait()
+	close(panics)
+
+	for p := range panics {
+		pe, ok := p.(*singleflight.PanicError)
+		if !ok || pe.Value != "kaboom" {
+			t.Errorf("Expected a *PanicError with kaboom but got %v", p)
+		}
+	}
+
+	// the key is free again after a panic
+	if v, _, _ := g.Do("k", func() (int, error) { return 1, nil }); v != 1 {
+		t.Errorf("Expected 1 but got %d", v)
+	}
+}
+
+func TestForget(t *testing.T) {
+
+	var g singleflight.Group[int]
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go g.Do("k", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	g.Forget("k")
+	v, _, shared := g.Do("k", func() (int, error) { return 2, nil })
+	close(release)
+
+	if v != 2 || shared {
+		t.Errorf("Expected a fresh call returning 2 but got %d, shared=%v", v, shared)
+	}
+}
+
+func TestDoContextWaiterGivesUp(t *testing.T) {
+
+	var g singleflight.Group[int]
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	result := make(chan int)
+	go func() {
+		v, _, _ := g.Do("k", func() (int, error) {
+			close(started)
+			<-release
+			return 7, nil
+		})
+		result <- v
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err, _ := g.DoContext(ctx, "k", nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+
+	close(release)
+	if v := <-result; v != 7 {
+		t.Errorf("Expected the original call to finish with 7 but got %d", v)
+	}
+}
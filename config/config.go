@@ -0,0 +1,628 @@
+// Package config loads a program's settings into a struct from, in rising
+// order of precedence, the defaults in its tags, a JSON file, environment
+// variables and command-line flags:
+//
+//	type settings struct {
+//		Addr    string        `config:"addr" default:"localhost:8080" usage:"address to serve on"`
+//		Workers int           `default:"8" usage:"most jobs at once"`
+//		Timeout time.Duration `default:"10s"`
+//		DB      struct {
+//			DSN string `config:"dsn" usage:"database to connect to"`
+//		}
+//	}
+//
+//	var s settings
+//	_, err := config.Load(&s, os.Args[1:], config.WithEnvPrefix("APP"), config.WithFileFlag("config"))
+//
+// Every field is a setting, named by its config tag or else its name in
+// kebab case. Nested structs are groups whose settings are prefixed with
+// the group name and a dot, so DB.DSN above is:
+//
+//	flag      -db.dsn
+//	file      {"db": {"dsn": "..."}}
+//	env       APP_DB_DSN
+//
+// Settings can be strings, bools, integers, floats, time.Durations,
+// anything implementing encoding.TextUnmarshaler, and slices of those,
+// written comma separated in flags and the environment. A field tagged
+// config:"-" is left alone.
+//
+// Once loaded, the struct is checked against its validate tags, as with
+// validate.Struct, and then by its Validate() error method if it has one:
+//
+//	Workers int `default:"8" validate:"min=1"`
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"pacx/options"
+	"pacx/validate"
+)
+
+type config struct {
+	name      string
+	file      string
+	fileFlag  string
+	envPrefix string
+	getenv    func(string) string
+	output    io.Writer
+}
+
+// Option configures Load.
+type Option = options.Option[config]
+
+// WithName names the program in the flag usage message. Defaults to the
+// base name of os.Args[0].
+func WithName(name string) Option {
+	return options.New("WithName", func(c *config) error {
+		c.name = name
+		return nil
+	})
+}
+
+// WithFile reads settings from the JSON file at path. The file must exist.
+func WithFile(path string) Option {
+	return options.New("WithFile", func(c *config) error {
+		c.file = path
+		return nil
+	})
+}
+
+// WithFileFlag adds a flag of this name that gives the JSON file to read,
+// in place of the one from WithFile if it is set.
+func WithFileFlag(name string) Option {
+	return options.New("WithFileFlag", func(c *config) error {
+		if name == "" {
+			return errors.New("flag name must not be empty")
+		}
+		c.fileFlag = name
+		return nil
+	})
+}
+
+// WithEnvPrefix reads settings from environment variables named prefix,
+// an underscore and the setting in upper case with dashes and dots as
+// underscores: APP_DATA_DIR for data-dir. Without it the environment is
+// not read. An empty variable counts as unset.
+func WithEnvPrefix(prefix string) Option {
+	return options.New("WithEnvPrefix", func(c *config) error {
+		if prefix == "" {
+			return errors.New("prefix must not be empty")
+		}
+		c.envPrefix = prefix
+		return nil
+	})
+}
+
+// WithGetenv reads the environment through getenv instead of os.Getenv.
+func WithGetenv(getenv func(string) string) Option {
+	return options.New("WithGetenv", func(c *config) error {
+		if getenv == nil {
+			return errors.New("getenv must not be nil")
+		}
+		c.getenv = getenv
+		return nil
+	})
+}
+
+// WithOutput sets where the flag usage message and flag errors go.
+// Defaults to standard error.
+func WithOutput(w io.Writer) Option {
+	return options.New("WithOutput", func(c *config) error {
+		c.output = w
+		return nil
+	})
+}
+
+// Load fills the struct dst points to and returns the arguments left
+// after the flags. A -h or -help flag prints the usage and returns
+// flag.ErrHelp. Problems with the file and the environment are all
+// reported together.
+func Load(dst any, args []string, opts ...Option) ([]string, error) {
+
+	cfg, err := options.Build(config{
+		name:   filepath.Base(os.Args[0]),
+		getenv: os.Getenv,
+		output: os.Stderr,
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config: want a pointer to a struct, got %T", dst)
+	}
+	v = v.Elem()
+	fields, err := fieldsOf(v.Type(), "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.name == cfg.fileFlag {
+			return nil, fmt.Errorf("config: %s: duplicate setting", f.name)
+		}
+	}
+
+	// the flags are parsed first, as one of them may name the file, but
+	// applied last so they win
+	fs := flag.NewFlagSet(cfg.name, flag.ContinueOnError)
+	fs.SetOutput(cfg.output)
+	fs.Usage = func() { usage(fs) }
+	values := make([]*flagValue, len(fields))
+	for i, f := range fields {
+		values[i] = &flagValue{f: f}
+		fs.Var(values[i], f.name, f.usage)
+	}
+	file := cfg.file
+	if cfg.fileFlag != "" {
+		fs.StringVar(&file, cfg.fileFlag, cfg.file, "JSON file to read the settings from")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	for _, f := range fields {
+		if f.def == "" {
+			continue
+		}
+		if err := set(f.in(v), f.def); err != nil {
+			return nil, fmt.Errorf("config: default of %s: %w", f.name, err)
+		}
+	}
+
+	var errs []error
+	if file != "" {
+		errs = append(errs, loadFile(v, fields, file)...)
+	}
+	if cfg.envPrefix != "" {
+		for _, f := range fields {
+			name := envName(cfg.envPrefix, f.name)
+			s := cfg.getenv(name)
+			if s == "" {
+				continue
+			}
+			if err := set(f.in(v), s); err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %w", name, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	for _, fv := range values {
+		if fv.value.IsValid() {
+			fv.f.in(v).Set(fv.value)
+		}
+	}
+
+	if err := check(dst, v.Type(), fields); err != nil {
+		return nil, err
+	}
+	if val, ok := dst.(interface{ Validate() error }); ok {
+		if err := val.Validate(); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	}
+
+	return fs.Args(), nil
+}
+
+// check runs validate.Struct on dst, naming the settings that break their
+// rules as flags do rather than by their Go path.
+func check(dst any, t reflect.Type, fields []field) error {
+
+	err := validate.Struct(dst)
+	var verrs validate.Errors
+	if !errors.As(err, &verrs) {
+		if err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+		return nil
+	}
+
+	names := make(map[string]string, len(fields))
+	for _, f := range fields {
+		names[goPath(t, f.index)] = f.name
+	}
+	errs := make([]error, len(verrs))
+	for i, e := range verrs {
+		name, ok := names[e.Path]
+		if !ok {
+			name = e.Path
+		}
+		errs[i] = fmt.Errorf("config: %s: %w", name, e.Err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// goPath returns the path validate gives the field at index in t, where
+// the fields of embedded structs are named as promoted.
+func goPath(t reflect.Type, index []int) string {
+
+	var names []string
+	for _, i := range index {
+		sf := t.Field(i)
+		if !sf.Anonymous {
+			names = append(names, sf.Name)
+		}
+		t = sf.Type
+	}
+
+	return strings.Join(names, ".")
+}
+
+// field is one setting: a leaf of the struct.
+type field struct {
+	name  string // dotted for nested structs
+	index []int
+	typ   reflect.Type
+	def   string
+	usage string
+}
+
+// in returns the field within the struct v.
+func (f field) in(v reflect.Value) reflect.Value {
+	return v.FieldByIndex(f.index)
+}
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// fieldsOf returns the settings of struct type t, their names after
+// prefix and their indexes after index.
+func fieldsOf(t reflect.Type, prefix string, index []int) ([]field, error) {
+
+	var fields []field
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("config")
+		if !sf.IsExported() || tag == "-" {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = kebab(sf.Name)
+		}
+		name = prefix + name
+		idx := append(index[:len(index):len(index)], i)
+
+		if sf.Type.Kind() == reflect.Struct && !isText(sf.Type) {
+			nested, err := fieldsOf(sf.Type, name+".", idx)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+			continue
+		}
+		if !settable(sf.Type) {
+			return nil, fmt.Errorf("config: %s: unsupported type %v", name, sf.Type)
+		}
+		fields = append(fields, field{
+			name:  name,
+			index: idx,
+			typ:   sf.Type,
+			def:   sf.Tag.Get("default"),
+			usage: sf.Tag.Get("usage"),
+		})
+	}
+
+	// a tag can name a setting like a nested one, A.B and "a.b"
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if seen[f.name] {
+			return nil, fmt.Errorf("config: %s: duplicate setting", f.name)
+		}
+		seen[f.name] = true
+	}
+
+	return fields, nil
+}
+
+// kebab turns a Go name into a setting name: DataDir to data-dir, HTTPAddr
+// to http-addr.
+func kebab(name string) string {
+
+	var b strings.Builder
+	rs := []rune(name)
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			next := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && next {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+
+	return b.String()
+}
+
+func envName(prefix, name string) string {
+	return prefix + "_" + strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+func isText(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func settable(t reflect.Type) bool {
+
+	if isText(t) || t == durationType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() != reflect.Slice && settable(t.Elem())
+	}
+
+	return false
+}
+
+// set parses s into v, the way a flag or environment variable is written.
+func set(v reflect.Value, s string) error {
+
+	if isText(v.Type()) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		parts := strings.Split(s, ",")
+		if s == "" {
+			parts = nil
+		}
+		sv := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := set(sv.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	}
+
+	return nil
+}
+
+// loadFile sets the fields found in the JSON file at path. Keys that are
+// not settings are errors, to catch misspellings.
+func loadFile(v reflect.Value, fields []field, path string) []error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("config: %w", err)}
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return []error{fmt.Errorf("config: %s: %w", path, err)}
+	}
+
+	byName := make(map[string]field, len(fields))
+	for _, f := range fields {
+		byName[f.name] = f
+	}
+
+	var errs []error
+	var walk func(doc map[string]json.RawMessage, prefix string)
+	walk = func(doc map[string]json.RawMessage, prefix string) {
+		for _, key := range slices.Sorted(maps.Keys(doc)) {
+			raw := doc[key]
+			name := prefix + key
+			f, ok := byName[name]
+			if !ok {
+				var group map[string]json.RawMessage
+				if isGroup(fields, name) && json.Unmarshal(raw, &group) == nil {
+					walk(group, name+".")
+					continue
+				}
+				errs = append(errs, fmt.Errorf("config: %s: unknown setting %q", path, name))
+				continue
+			}
+			if err := setJSON(f.in(v), raw); err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: %s: %w", path, name, err))
+			}
+		}
+	}
+	walk(doc, "")
+
+	return errs
+}
+
+// isGroup reports whether name is a nested struct holding settings.
+func isGroup(fields []field, name string) bool {
+
+	for _, f := range fields {
+		if strings.HasPrefix(f.name, name+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setJSON sets v from a JSON value. Strings are parsed as in flags, so a
+// duration is written "1m30s", and arrays element by element; anything
+// else is decoded as usual. Null leaves v as it is.
+func setJSON(v reflect.Value, raw json.RawMessage) error {
+
+	raw = bytes.TrimSpace(raw)
+	switch {
+	case bytes.Equal(raw, []byte("null")):
+		return nil
+	case len(raw) > 0 && raw[0] == '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		return set(v, s)
+	case len(raw) > 0 && raw[0] == '[' && v.Kind() == reflect.Slice:
+		var elems []json.RawMessage
+		if err := json.Unmarshal(raw, &elems); err != nil {
+			return err
+		}
+		sv := reflect.MakeSlice(v.Type(), len(elems), len(elems))
+		for i, e := range elems {
+			if err := setJSON(sv.Index(i), e); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		v.Set(sv)
+		return nil
+	}
+
+	nv := reflect.New(v.Type())
+	if err := json.Unmarshal(raw, nv.Interface()); err != nil {
+		return err
+	}
+	v.Set(nv.Elem())
+
+	return nil
+}
+
+// flagValue is a setting as a flag. The value is parsed when the flag is
+// set, so bad ones fail with the usage message, but only stored in the
+// struct after the file and the environment.
+type flagValue struct {
+	f     field
+	value reflect.Value
+}
+
+func (fv *flagValue) String() string {
+
+	if fv == nil {
+		return ""
+	}
+
+	return fv.f.def
+}
+
+func (fv *flagValue) Set(s string) error {
+
+	v := reflect.New(fv.f.typ).Elem()
+	if err := set(v, s); err != nil {
+		return err
+	}
+	fv.value = v
+
+	return nil
+}
+
+func (fv *flagValue) IsBoolFlag() bool {
+	return fv.f.typ.Kind() == reflect.Bool
+}
+
+// typeName is what the usage message shows the flag takes.
+func (fv *flagValue) typeName() string {
+
+	t := fv.f.typ
+	switch {
+	case isText(t):
+		return "value"
+	case t == durationType:
+		return "duration"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return ""
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "list"
+	}
+
+	return "string"
+}
+
+// usage is flag.PrintDefaults with the type of each setting, where the
+// flag package can only say "value" for a flag.Value of its own.
+func usage(fs *flag.FlagSet) {
+
+	w := fs.Output()
+	fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+	fs.VisitAll(func(f *flag.Flag) {
+		name, text := flag.UnquoteUsage(f)
+		def := f.DefValue
+		if fv, ok := f.Value.(*flagValue); ok {
+			if !strings.Contains(f.Usage, "`") {
+				name = fv.typeName()
+			}
+			if fv.f.typ.Kind() == reflect.String {
+				def = strconv.Quote(def)
+			}
+		}
+		line := "  -" + f.Name
+		if name != "" {
+			line += " " + name
+		}
+		line += "\n    \t" + strings.ReplaceAll(text, "\n", "\n    \t")
+		if f.DefValue != "" {
+			line += " (default " + def + ")"
+		}
+		fmt.Fprintln(w, line)
+	})
+}
@@ -0,0 +1,212 @@
+package config_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/config"
+	"pacx/validate"
+)
+
+type settings struct {
+	Addr    string        `config:"addr" default:"localhost:8080" usage:"address to serve on" validate:"required"`
+	DataDir string        `default:"data"`
+	Workers int           `default:"8"`
+	Rate    float64       `default:"100"`
+	Verbose bool          `usage:"log more"`
+	Timeout time.Duration `default:"10s"`
+	Tags    []string      `default:"a,b"`
+	Retries []time.Duration
+	Allow   netip.Addr
+	DB      struct {
+		DSN      string `config:"dsn"`
+		MaxConns uint16 `default:"4" validate:"min=1,max=64"`
+	}
+	Internal string `config:"-"`
+	private  int
+}
+
+func (s *settings) Validate() error {
+
+	if s.Workers < 1 {
+		return errors.New("need at least one worker")
+	}
+
+	return nil
+}
+
+func env(vars map[string]string) config.Option {
+	return config.WithGetenv(func(name string) string { return vars[name] })
+}
+
+func writeFile(t *testing.T, content string) string {
+
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestDefaults(t *testing.T) {
+
+	var s settings
+	rest, err := config.Load(&s, []string{"serve", "-x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr != "localhost:8080" || s.DataDir != "data" || s.Workers != 8 || s.Rate != 100 || s.Timeout != 10*time.Second || s.DB.MaxConns != 4 {
+		t.Errorf("Expected the defaults but got %+v", s)
+	}
+	if !slices.Equal(s.Tags, []string{"a", "b"}) {
+		t.Errorf("Expected default tags [a b] but got %v", s.Tags)
+	}
+	if !slices.Equal(rest, []string{"serve", "-x"}) {
+		t.Errorf("Expected the arguments after the flags but got %v", rest)
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+
+	path := writeFile(t, `{
+		"addr": "file:1",
+		"data-dir": "from-file",
+		"workers": 2,
+		"timeout": "1m",
+		"retries": ["1s", "2s"],
+		"allow": "10.0.0.1",
+		"db": {"dsn": "file-dsn", "max-conns": 16}
+	}`)
+	vars := map[string]string{
+		"APP_DATA_DIR":  "from-env",
+		"APP_WORKERS":   "3",
+		"APP_DB_DSN":    "env-dsn",
+		"APP_TAGS":      "x, y, z",
+		"APP_VERBOSE":   "true",
+		"APP_DB_UNUSED": "ignored",
+	}
+
+	var s settings
+	_, err := config.Load(&s, []string{"-workers", "5", "-db.max-conns=32", "-config", path},
+		config.WithEnvPrefix("APP"), env(vars), config.WithFileFlag("config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch {
+	case s.Addr != "file:1":
+		t.Errorf("Expected addr from the file but got %s", s.Addr)
+	case s.DataDir != "from-env":
+		t.Errorf("Expected data-dir from the environment over the file but got %s", s.DataDir)
+	case s.Workers != 5:
+		t.Errorf("Expected workers from the flag over both but got %d", s.Workers)
+	case s.DB.DSN != "env-dsn" || s.DB.MaxConns != 32:
+		t.Errorf("Expected the nested settings from the environment and flag but got %+v", s.DB)
+	case s.Timeout != time.Minute || !s.Verbose || s.Rate != 100:
+		t.Errorf("Expected timeout 1m, verbose and rate 100 but got %v, %v, %v", s.Timeout, s.Verbose, s.Rate)
+	case s.Allow != netip.MustParseAddr("10.0.0.1"):
+		t.Errorf("Expected the address decoded as text but got %v", s.Allow)
+	}
+	if !slices.Equal(s.Tags, []string{"x", "y", "z"}) || !slices.Equal(s.Retries, []time.Duration{time.Second, 2 * time.Second}) {
+		t.Errorf("Expected tags [x y z] and retries [1s 2s] but got %v and %v", s.Tags, s.Retries)
+	}
+}
+
+func TestErrors(t *testing.T) {
+
+	path := writeFile(t, `{"workers": "many", "wrokers": 1, "db": {"dns": "x"}}`)
+
+	var s settings
+	_, err := config.Load(&s, nil, config.WithFile(path),
+		config.WithEnvPrefix("APP"), env(map[string]string{"APP_TIMEOUT": "soon"}))
+	if err == nil {
+		t.Fatal("Expected errors")
+	}
+	for _, want := range []string{`workers: strconv.ParseInt`, `unknown setting "wrokers"`, `unknown setting "db.dns"`, `APP_TIMEOUT`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %s but got %v", want, err)
+		}
+	}
+
+	if _, err := config.Load(&s, nil, config.WithFile(filepath.Join(t.TempDir(), "missing.json"))); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing file to be an error but got %v", err)
+	}
+	if _, err := config.Load(&s, []string{"-workers", "x"}, config.WithOutput(io.Discard)); err == nil {
+		t.Error("Expected a bad flag to be an error")
+	}
+	if _, err := config.Load(&s, []string{"-h"}, config.WithOutput(io.Discard)); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("Expected flag.ErrHelp but got %v", err)
+	}
+	if _, err := config.Load(s, nil); err == nil {
+		t.Error("Expected an error for a struct that is not a pointer")
+	}
+
+	var bad struct{ Ch chan int }
+	if _, err := config.Load(&bad, nil); err == nil || !strings.Contains(err.Error(), "unsupported type") {
+		t.Errorf("Expected an unsupported type error but got %v", err)
+	}
+}
+
+func TestDuplicateSettings(t *testing.T) {
+
+	var file struct{ Config string }
+	_, err := config.Load(&file, nil, config.WithFileFlag("config"))
+	if err == nil || err.Error() != "config: config: duplicate setting" {
+		t.Errorf("Expected the file flag to clash with the setting but got %v", err)
+	}
+
+	var nested struct {
+		A  struct{ B int }
+		AB int `config:"a.b"`
+	}
+	_, err = config.Load(&nested, nil)
+	if err == nil || err.Error() != "config: a.b: duplicate setting" {
+		t.Errorf("Expected a.b to clash with A.B but got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	var s settings
+	_, err := config.Load(&s, []string{"-workers", "0"})
+	if err == nil || !strings.Contains(err.Error(), "need at least one worker") {
+		t.Errorf("Expected the Validate error but got %v", err)
+	}
+
+	_, err = config.Load(&s, []string{"-addr", "", "-db.max-conns", "100"})
+	if !errors.Is(err, validate.ErrRequired) || !errors.Is(err, validate.ErrOutOfRange) {
+		t.Fatalf("Expected the validate tags checked but got %v", err)
+	}
+	for _, want := range []string{"config: addr: is required", "config: db.max-conns: out of range"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %s but got %v", want, err)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+
+	var out strings.Builder
+	var s settings
+	config.Load(&s, []string{"-h"}, config.WithName("app"), config.WithOutput(&out), config.WithFileFlag("config"))
+
+	for _, want := range []string{"Usage of app", "-addr string", `address to serve on (default "localhost:8080")`, "-timeout duration", "-db.max-conns uint", "-tags list", "-allow value", "-verbose\n", "-config string"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the usage to contain %q but got\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "internal") || strings.Contains(out.String(), "private") {
+		t.Errorf("Expected skipped fields left out of the usage but got\n%s", out.String())
+	}
+}
@@ -0,0 +1,312 @@
+// Package di is a small dependency injection container. Constructors are
+// registered with Provide, and what each needs, its parameters, is found
+// by type among the results of the others:
+//
+//	c := di.New()
+//	c.Provide(loadSettings) // func() (settings, error)
+//	c.Provide(openDB)       // func(settings, *di.Lifecycle) (*sql.DB, error)
+//	c.Provide(newServer)    // func(*sql.DB, settings) *server
+//
+//	srv, err := di.Resolve[*server](c)
+//	err = c.Start(ctx)
+//	defer c.Stop(ctx)
+//
+// Every type is built once, when first needed, and then shared. A
+// constructor that takes a *Lifecycle can hook the start and stop of what
+// it built, and values that are Starters or Stoppers are hooked on their
+// own. Start runs the hooks in the order the values were built, so a
+// value's dependencies start before it, and Stop runs them in reverse.
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// The errors resolving a type wraps.
+var (
+	ErrNotProvided = errors.New("no constructor")
+	ErrCycle       = errors.New("dependency cycle")
+)
+
+// Starter is a value Start starts.
+type Starter interface {
+	Start(context.Context) error
+}
+
+// Stopper is a value Stop stops.
+type Stopper interface {
+	Stop(context.Context) error
+}
+
+// Hook is what to do when the container starts and stops. Either may be
+// nil.
+type Hook struct {
+	Start func(context.Context) error
+	Stop  func(context.Context) error
+}
+
+// Lifecycle holds the hooks of a container. Constructors that need one
+// take a *Lifecycle parameter.
+type Lifecycle struct {
+	hooks []hook
+}
+
+type hook struct {
+	Hook
+	running bool // started, or without a Start
+}
+
+// Append adds h after the hooks of the values built so far. A hook
+// without a Start counts as running at once, so Stop undoes what its
+// constructor did even if Start never ran.
+func (l *Lifecycle) Append(h Hook) {
+	l.hooks = append(l.hooks, hook{Hook: h, running: h.Start == nil})
+}
+
+// Container builds values from their constructors. It is safe for
+// concurrent use, but constructors must not call back into it: what they
+// need they take as parameters.
+type Container struct {
+	mu        sync.Mutex
+	providers map[reflect.Type]provider
+	values    map[reflect.Type]reflect.Value
+	building  []reflect.Type // the types being built, outermost first
+	lc        Lifecycle
+}
+
+type provider struct {
+	fn      reflect.Value
+	in      []reflect.Type
+	withErr bool // returns an error too
+}
+
+var (
+	errorType     = reflect.TypeFor[error]()
+	lifecycleType = reflect.TypeFor[*Lifecycle]()
+)
+
+// New returns an empty container, which provides only its *Lifecycle.
+func New() *Container {
+
+	c := &Container{
+		providers: make(map[reflect.Type]provider),
+		values:    make(map[reflect.Type]reflect.Value),
+	}
+	c.values[lifecycleType] = reflect.ValueOf(&c.lc)
+
+	return c
+}
+
+// Provide registers constructor, a func returning a value of the type it
+// provides, and optionally an error. Its parameters are resolved when the
+// type is first needed. A type has one constructor.
+func (c *Container) Provide(constructor any) error {
+
+	fn := reflect.ValueOf(constructor)
+	if fn.Kind() != reflect.Func || fn.IsNil() {
+		return fmt.Errorf("di: want a constructor func, got %T", constructor)
+	}
+	t := fn.Type()
+	if t.IsVariadic() {
+		return fmt.Errorf("di: constructor %v is variadic", t)
+	}
+	withErr := t.NumOut() == 2 && t.Out(1) == errorType
+	if t.NumOut() != 1 && !withErr || t.Out(0) == errorType {
+		return fmt.Errorf("di: constructor %v must return a value, or a value and an error", t)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := t.Out(0)
+	if _, ok := c.providers[out]; ok || out == lifecycleType {
+		return fmt.Errorf("di: %v is already provided", out)
+	}
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	c.providers[out] = provider{fn: fn, in: in, withErr: withErr}
+
+	return nil
+}
+
+// Resolve returns the T of c, building it and what it needs first if that
+// has not been done yet.
+func Resolve[T any](c *Container) (T, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, err := c.resolve(reflect.TypeFor[T]())
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	t, _ := v.Interface().(T) // not ok only for a nil interface
+
+	return t, nil
+}
+
+// Invoke calls fn with its parameters resolved. If fn's last result is an
+// error, Invoke returns it.
+func (c *Container) Invoke(fn any) error {
+
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() || v.Type().IsVariadic() {
+		return fmt.Errorf("di: want a func to invoke, got %T", fn)
+	}
+	t := v.Type()
+
+	c.mu.Lock()
+	args := make([]reflect.Value, t.NumIn())
+	for i := range args {
+		arg, err := c.resolve(t.In(i))
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		args[i] = arg
+	}
+	c.mu.Unlock()
+
+	out := v.Call(args)
+	if n := len(out); n > 0 && t.Out(n-1) == errorType && !out[n-1].IsNil() {
+		return out[n-1].Interface().(error)
+	}
+
+	return nil
+}
+
+// resolve returns the value of type t, building it if need be.
+func (c *Container) resolve(t reflect.Type) (reflect.Value, error) {
+
+	if v, ok := c.values[t]; ok {
+		return v, nil
+	}
+	p, ok := c.providers[t]
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("di: %w for %v%s", ErrNotProvided, t, c.neededBy())
+	}
+	if i := slices.Index(c.building, t); i >= 0 {
+		var path []string
+		for _, b := range c.building[i:] {
+			path = append(path, b.String())
+		}
+		path = append(path, t.String())
+		return reflect.Value{}, fmt.Errorf("di: %w: %s", ErrCycle, strings.Join(path, " -> "))
+	}
+
+	c.building = append(c.building, t)
+	defer func() { c.building = c.building[:len(c.building)-1] }()
+
+	args := make([]reflect.Value, len(p.in))
+	for i, in := range p.in {
+		arg, err := c.resolve(in)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		args[i] = arg
+	}
+	out := p.fn.Call(args)
+	if p.withErr && !out[1].IsNil() {
+		return reflect.Value{}, fmt.Errorf("di: building %v: %w", t, out[1].Interface().(error))
+	}
+
+	v := out[0]
+	c.values[t] = v
+	c.hook(v)
+
+	return v, nil
+}
+
+// neededBy names the type being built, if any, for errors.
+func (c *Container) neededBy() string {
+
+	if len(c.building) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(" (needed by %v)", c.building[len(c.building)-1])
+}
+
+// hook appends the Start and Stop of v, if it has them.
+func (c *Container) hook(v reflect.Value) {
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return
+		}
+	}
+
+	var h Hook
+	if s, ok := v.Interface().(Starter); ok {
+		h.Start = s.Start
+	}
+	if s, ok := v.Interface().(Stopper); ok {
+		h.Stop = s.Stop
+	}
+	if h.Start != nil || h.Stop != nil {
+		c.lc.Append(h)
+	}
+}
+
+// Start runs the Start hooks that have not run yet, in the order they were
+// appended. If one fails, the hooks running are stopped again, and the
+// errors returned.
+func (c *Container) Start(ctx context.Context) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.lc.hooks {
+		h := &c.lc.hooks[i]
+		if h.running {
+			continue
+		}
+		if h.Start != nil {
+			if err := h.Start(ctx); err != nil {
+				return errors.Join(fmt.Errorf("di: starting: %w", err), c.stop(ctx))
+			}
+		}
+		h.running = true
+	}
+
+	return nil
+}
+
+// Stop runs the Stop hooks of what is running, in reverse order, and
+// returns their errors joined. Every hook runs even if one fails.
+func (c *Container) Stop(ctx context.Context) error {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stop(ctx)
+}
+
+func (c *Container) stop(ctx context.Context) error {
+
+	var errs []error
+	for i := len(c.lc.hooks) - 1; i >= 0; i-- {
+		h := &c.lc.hooks[i]
+		if !h.running {
+			continue
+		}
+		h.running = false
+		if h.Stop != nil {
+			if err := h.Stop(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,227 @@
+package di_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"pacx/di"
+)
+
+type settings struct{ dsn string }
+
+type db struct{ dsn string }
+
+type repo struct{ db *db }
+
+type server struct {
+	repo *repo
+	db   *db
+	log  *[]string
+}
+
+func (s *server) Start(context.Context) error {
+	*s.log = append(*s.log, "start server")
+	return nil
+}
+
+func (s *server) Stop(context.Context) error {
+	*s.log = append(*s.log, "stop server")
+	return nil
+}
+
+// assemble provides the types of the tests, logging the lifecycle to log.
+func assemble(t *testing.T, log *[]string) *di.Container {
+
+	t.Helper()
+
+	c := di.New()
+	for _, fn := range []any{
+		func() settings { return settings{dsn: "mem"} },
+		func(s settings, lc *di.Lifecycle) (*db, error) {
+			*log = append(*log, "open db")
+			lc.Append(di.Hook{
+				Start: func(context.Context) error { *log = append(*log, "start db"); return nil },
+				Stop:  func(context.Context) error { *log = append(*log, "stop db"); return nil },
+			})
+			return &db{dsn: s.dsn}, nil
+		},
+		func(d *db) *repo { return &repo{db: d} },
+		func(r *repo, d *db) *server { return &server{repo: r, db: d, log: log} },
+	} {
+		if err := c.Provide(fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return c
+}
+
+func TestResolve(t *testing.T) {
+
+	var log []string
+	c := assemble(t, &log)
+
+	s, err := di.Resolve[*server](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.db.dsn != "mem" || s.repo.db != s.db {
+		t.Errorf("Expected one db shared by the repo and the server but got %+v", s)
+	}
+	s2, _ := di.Resolve[*server](c)
+	if s2 != s || !slices.Equal(log, []string{"open db"}) {
+		t.Errorf("Expected everything built once but got %v", log)
+	}
+
+	var got *repo
+	if err := c.Invoke(func(r *repo) { got = r }); err != nil || got != s.repo {
+		t.Errorf("Expected Invoke to get the same repo but got %v, %v", got, err)
+	}
+	if err := c.Invoke(func(*repo) error { return io.EOF }); err != io.EOF {
+		t.Errorf("Expected Invoke to return the func's error but got %v", err)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+
+	var log []string
+	c := assemble(t, &log)
+	if _, err := di.Resolve[*server](c); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"open db", "start db", "start server", "stop server", "stop db"}
+	if !slices.Equal(log, want) {
+		t.Errorf("Expected %v but got %v", want, log)
+	}
+}
+
+func TestStartFails(t *testing.T) {
+
+	var log []string
+	hook := func(name string, err error) di.Hook {
+		return di.Hook{
+			Start: func(context.Context) error { log = append(log, "start "+name); return err },
+			Stop:  func(context.Context) error { log = append(log, "stop "+name); return nil },
+		}
+	}
+
+	c := di.New()
+	c.Provide(func(lc *di.Lifecycle) int {
+		lc.Append(hook("a", nil))
+		lc.Append(di.Hook{Stop: func(context.Context) error { log = append(log, "close b"); return nil }})
+		lc.Append(hook("c", errors.New("no")))
+		lc.Append(hook("d", nil))
+		return 1
+	})
+	if _, err := di.Resolve[int](c); err != nil {
+		t.Fatal(err)
+	}
+
+	err := c.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "di: starting: no") {
+		t.Errorf("Expected the start error but got %v", err)
+	}
+	want := []string{"start a", "start c", "close b", "stop a"}
+	if !slices.Equal(log, want) {
+		t.Errorf("Expected %v but got %v", want, log)
+	}
+}
+
+func TestStopErrors(t *testing.T) {
+
+	c := di.New()
+	c.Provide(func(lc *di.Lifecycle) int {
+		for i := range 3 {
+			lc.Append(di.Hook{Stop: func(context.Context) error { return fmt.Errorf("stop %d", i) }})
+		}
+		return 1
+	})
+	di.Resolve[int](c)
+
+	err := c.Stop(context.Background())
+	if err == nil || err.Error() != "stop 2\nstop 1\nstop 0" {
+		t.Errorf("Expected every stop error, last first, but got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+
+	type a struct{}
+	type b struct{}
+	c := di.New()
+	c.Provide(func(b) a { return a{} })
+	c.Provide(func(a) b { return b{} })
+	c.Provide(func(*repo) string { return "" })
+	c.Provide(func() (int, error) { return 0, io.ErrUnexpectedEOF })
+
+	_, err := di.Resolve[a](c)
+	if !errors.Is(err, di.ErrCycle) || !strings.Contains(err.Error(), "di_test.a -> di_test.b -> di_test.a") {
+		t.Errorf("Expected the cycle named but got %v", err)
+	}
+	_, err = di.Resolve[string](c)
+	if !errors.Is(err, di.ErrNotProvided) || !strings.Contains(err.Error(), "*di_test.repo (needed by string)") {
+		t.Errorf("Expected the missing type named but got %v", err)
+	}
+	if _, err := di.Resolve[int](c); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected the constructor's error but got %v", err)
+	}
+	if err := c.Invoke(func(float64) {}); !errors.Is(err, di.ErrNotProvided) {
+		t.Errorf("Expected Invoke to fail for a missing type but got %v", err)
+	}
+}
+
+func TestProvideErrors(t *testing.T) {
+
+	c := di.New()
+	c.Provide(func() int { return 1 })
+
+	for _, fn := range []any{
+		nil,
+		42,
+		(func() int)(nil),
+		func() {},
+		func() error { return nil },
+		func() (int, int) { return 1, 2 },
+		func(...int) string { return "" },
+		func() int { return 2 },
+		func() *di.Lifecycle { return nil },
+	} {
+		if err := c.Provide(fn); err == nil {
+			t.Errorf("Expected an error for %T", fn)
+		}
+	}
+}
+
+func TestInterface(t *testing.T) {
+
+	c := di.New()
+	c.Provide(func() io.Writer { return &strings.Builder{} })
+
+	w, err := di.Resolve[io.Writer](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.(*strings.Builder); !ok {
+		t.Errorf("Expected the builder as an io.Writer but got %T", w)
+	}
+}
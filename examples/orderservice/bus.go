@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"pacx/concurrency/orders"
+	"pacx/concurrency/scheduler"
+	"pacx/di"
+)
+
+// bus is the event bus: every status change of an order is published
+// under the new status, and each subscriber of that status gets the order
+// as a task on the scheduler, so a slow subscriber holds up neither the
+// order nor the other subscribers. Subscribers of one status run in no
+// particular order and may overlap.
+type bus struct {
+	sched *scheduler.Scheduler
+	log   *slog.Logger
+
+	mu   sync.RWMutex
+	subs map[string][]func(orders.Order)
+}
+
+// newBus starts the scheduler the subscribers run on, which is closed,
+// after running the events still queued, when the container stops.
+func newBus(log *slog.Logger, lc *di.Lifecycle) (*bus, error) {
+
+	sched, err := scheduler.New(scheduler.WithWorkers(2))
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(di.Hook{Stop: func(context.Context) error {
+		sched.Close()
+		return nil
+	}})
+
+	return &bus{sched: sched, log: log, subs: make(map[string][]func(orders.Order))}, nil
+}
+
+// Subscribe calls fn with every order published under status; "*" gets
+// every order.
+func (b *bus) Subscribe(status string, fn func(orders.Order)) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs[status] = append(b.subs[status], fn)
+}
+
+// Publish hands o to the subscribers of its status.
+func (b *bus) Publish(o orders.Order) {
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, topic := range []string{o.Status, "*"} {
+		for _, fn := range b.subs[topic] {
+			if err := b.sched.Submit(func() { fn(o) }); err != nil {
+				b.log.Warn("event dropped", "order", o.ID, "status", o.Status, "err", err)
+			}
+		}
+	}
+}
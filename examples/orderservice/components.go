@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"pacx/cache"
+	"pacx/concurrency/orders"
+	"pacx/concurrency/pool"
+	"pacx/concurrency/ratelimit"
+	"pacx/di"
+	"pacx/metrics"
+)
+
+// assemble registers the constructors the service is built from with a
+// di.Container. Those that open something append a hook to close it, and
+// the container stops them in the reverse of the order they were built
+// in: the service lets the running orders finish, the events still queued
+// are delivered, and then every order is saved.
+func assemble(cfg settings, log *slog.Logger, level *slog.LevelVar) (*di.Container, error) {
+
+	c := di.New()
+	err := errors.Join(
+		c.Provide(func() settings { return cfg }),
+		c.Provide(func() *slog.Logger { return log }),
+		c.Provide(func() *slog.LevelVar { return level }),
+		c.Provide(func() *metrics.Registry { return &metrics.Registry{} }),
+		c.Provide(func(cfg settings) *ratelimit.Limiter { return ratelimit.New(cfg.Rate, max(1, int(cfg.Rate/10))) }),
+		c.Provide(func(cfg settings) (*store, error) { return openStore(cfg.DataDir) }),
+		c.Provide(newOrderCache),
+		c.Provide(newPool),
+		c.Provide(newBus),
+		c.Provide(newAuditLog),
+		c.Provide(newService),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newOrderCache puts a write-behind cache in front of the store, which is
+// flushed a last time when the container stops.
+func newOrderCache(cfg settings, st *store, log *slog.Logger, lc *di.Lifecycle) (*cache.Cached[int, orders.Order], error) {
+
+	c, err := cache.WriteBehind(st,
+		cache.WithMaxCost(10_000),
+		cache.WithFlushInterval(cfg.Flush),
+		cache.WithOnFlushError(func(err error) { log.Error("saving orders", "err", err) }))
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(di.Hook{Stop: func(context.Context) error { return c.Close() }})
+
+	return c, nil
+}
+
+// newPool starts the worker pool the orders are processed on. The service
+// closes it as it stops, letting the orders finish; the hook closes it if
+// the service was never built.
+func newPool(cfg settings, reg *metrics.Registry, log *slog.Logger, lc *di.Lifecycle) (*pool.ScalingPool, error) {
+
+	workers := reg.Gauge("pool.workers")
+	queued := reg.Gauge("pool.queued")
+	p, err := pool.NewScaling(
+		pool.WithBounds(1, cfg.Workers),
+		pool.WithOnSample(func(smp pool.Sample) {
+			workers.Set(float64(smp.Workers))
+			queued.Set(float64(smp.QueueDepth))
+		}),
+		pool.WithOnScale(func(from, to int, reason string) {
+			log.Debug("pool resized", "from", from, "to", to, "reason", reason)
+		}))
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(di.Hook{Stop: func(context.Context) error {
+		p.Close()
+		return nil
+	}})
+
+	return p, nil
+}
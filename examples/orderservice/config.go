@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"pacx/config"
+)
+
+// settings are what the service is started with. Every flag can also be
+// set from the environment as ORDERSERVICE_<NAME>, with dashes as
+// underscores, or from the JSON file given with -config; a flag on the
+// command line wins over the environment, which wins over the file.
+type settings struct {
+	Addr     string        `default:"localhost:8080" usage:"address to serve the API on"`
+	Admin    string        `config:"admin-addr" default:"localhost:6060" usage:"loopback address to serve the live settings and profiles on"`
+	DataDir  string        `default:"orderservice-data" usage:"directory the orders are kept in"`
+	Workers  int           `default:"8" usage:"most orders processed at once" validate:"min=1"`
+	Rate     float64       `default:"100" usage:"orders accepted per second"`
+	LogLevel string        `default:"info" usage:"debug, info, warn or error"`
+	LogFile  string        `usage:"write the log to this file, rotated at 10 MiB, instead of standard error"`
+	Audit    string        `config:"audit-log" usage:"write every order status change to this file as a JSON line, rotated daily"`
+	Metrics  string        `config:"metrics-socket" usage:"push metrics to the collector on this Unix socket"`
+	Flush    time.Duration `default:"1s" usage:"how often order changes are written to disk" validate:"min=1"`
+	Shutdown time.Duration `config:"shutdown-timeout" default:"10s" usage:"how long to let running orders finish on shutdown"`
+}
+
+// Validate checks what the validate tags cannot say.
+func (s *settings) Validate() error {
+
+	if s.Rate <= 0 {
+		return errors.New("rate must be positive")
+	}
+	// the admin endpoints have no auth, so they stay on this machine
+	if !loopback(s.Admin) {
+		return fmt.Errorf("admin address %s is not a loopback address", s.Admin)
+	}
+
+	return nil
+}
+
+func loopback(addr string) bool {
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
+
+func loadConfig(args []string, getenv func(string) string) (settings, error) {
+
+	var s settings
+	_, err := config.Load(&s, args,
+		config.WithName("orderservice"),
+		config.WithEnvPrefix("ORDERSERVICE"),
+		config.WithGetenv(getenv),
+		config.WithFileFlag("config"))
+	if err != nil {
+		return settings{}, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,161 @@
+// Command orderservice is the order demo of concurrency/orders as a
+// long-running service, and the reference for how the packages of this
+// module fit together:
+//
+//	config       package config: flags, ORDERSERVICE_* environment variables
+//	             or a -config JSON file
+//	assembly     package di builds the components, then starts and stops
+//	             them in order
+//	logging      log/slog, its level a live setting
+//	metrics      package metrics, served on /metrics and optionally pushed
+//	HTTP API     net/http, with qos levels from the X-QoS header
+//	worker pool  pool.ScalingPool processes the orders
+//	scheduler    scheduler.Scheduler runs the event subscribers
+//	event bus    bus, publishing every status change
+//	persistence  store, a JSON file behind a write-behind cache.Cached
+//	log files    rotate, for -log-file and the -audit-log of status changes
+//	             written as JSON lines by jsonl.Writer
+//	profiling    profiler.Handler on /debug/pprof/ of -admin-addr
+//	live tuning  package tune on /tune/ of -admin-addr
+//
+// Interrupting it shuts down gracefully: the API stops taking requests,
+// queued and running orders get -shutdown-timeout to finish and every order
+// is saved. Orders stopped halfway are picked up again on the next start.
+//
+//	go run ./examples/orderservice -addr localhost:8080
+//	curl -X POST localhost:8080/orders
+//	curl localhost:8080/orders/1
+//	curl localhost:8080/metrics
+//	curl -X PUT 'localhost:6060/tune/log_level?value=debug'
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"pacx/File-IO/rotate"
+	"pacx/di"
+)
+
+func main() {
+
+	cfg, err := loadConfig(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, cfg, os.Stderr, nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run serves until ctx is done and then shuts down. ready, if not nil, is
+// called with the addresses the API and the admin endpoints listen on once
+// they do.
+func run(ctx context.Context, cfg settings, logOut io.Writer, ready func(api, admin string)) error {
+
+	if cfg.LogFile != "" {
+		f, err := rotate.New(cfg.LogFile, rotate.WithMaxSize(10<<20))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		logOut = f
+	}
+
+	level := new(slog.LevelVar)
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return fmt.Errorf("log level: %w", err)
+	}
+	log := slog.New(slog.NewTextHandler(logOut, &slog.HandlerOptions{Level: level}))
+
+	c, err := assemble(cfg, log, level)
+	if err != nil {
+		return err
+	}
+	s, err := di.Resolve[*service](c)
+	if err != nil {
+		return errors.Join(err, c.Stop(context.Background()))
+	}
+	if err := c.Start(context.Background()); err != nil {
+		return err
+	}
+
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "tcp", cfg.Addr)
+	if err != nil {
+		c.Stop(context.Background())
+		return err
+	}
+	adminLn, err := lc.Listen(ctx, "tcp", cfg.Admin)
+	if err != nil {
+		ln.Close()
+		c.Stop(context.Background())
+		return err
+	}
+	admin, err := s.adminHandler()
+	if err != nil {
+		ln.Close()
+		adminLn.Close()
+		c.Stop(context.Background())
+		return err
+	}
+	srv := &http.Server{Handler: s.handler()}
+	adminSrv := &http.Server{Handler: admin}
+
+	serveErr := make(chan error, 2)
+	go func() { serveErr <- srv.Serve(ln) }()
+	go func() { serveErr <- adminSrv.Serve(adminLn) }()
+
+	pushCtx, stopPush := context.WithCancel(context.Background())
+	defer stopPush()
+	if cfg.Metrics != "" {
+		go s.pushMetrics(pushCtx, cfg.Metrics)
+	}
+
+	log.Info("serving", "addr", ln.Addr().String(), "admin", adminLn.Addr().String(), "data", s.store.path)
+	if ready != nil {
+		ready(ln.Addr().String(), adminLn.Addr().String())
+	}
+
+	select {
+	case <-ctx.Done():
+	case err = <-serveErr:
+		log.Error("serving", "err", err)
+	}
+
+	log.Info("shutting down", "timeout", cfg.Shutdown)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown)
+	defer cancel()
+
+	errs := []error{err}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping the API: %w", err))
+	}
+	if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping the admin endpoints: %w", err))
+	}
+	if err := c.Stop(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("stopping the orders: %w", err))
+	}
+	log.Info("stopped")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/concurrency/orders"
+	"pacx/jsonx/jsonl"
+)
+
+// start runs the service on free ports until the returned stop is called,
+// which returns what run did.
+func start(t *testing.T, dir string, args ...string) (base, admin string, stop func() error) {
+
+	t.Helper()
+
+	cfg, err := loadConfig(append([]string{"-addr", "localhost:0", "-admin-addr", "localhost:0", "-data-dir", dir, "-flush", "10ms"}, args...), func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	addrs := make(chan [2]string, 1)
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, cfg, io.Discard, func(api, admin string) { addrs <- [2]string{api, admin} }) }()
+
+	select {
+	case a := <-addrs:
+		base, admin = "http://"+a[0], "http://"+a[1]
+	case err := <-done:
+		cancel()
+		t.Fatalf("Expected the service to start but got %v", err)
+	}
+
+	return base, admin, func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func create(t *testing.T, base string) orders.Order {
+
+	t.Helper()
+
+	resp, err := http.Post(base+"/orders", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected 202 for a new order but got %s", resp.Status)
+	}
+
+	var o orders.Order
+	if err := json.NewDecoder(resp.Body).Decode(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	return o
+}
+
+func get(t *testing.T, url string) (int, string) {
+
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	return resp.StatusCode, string(body)
+}
+
+// waitDelivered polls the order until it is delivered.
+func waitDelivered(t *testing.T, base string, id int) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		_, body := get(t, fmt.Sprintf("%s/orders/%d", base, id))
+		var o orders.Order
+		if json.Unmarshal([]byte(body), &o) == nil && o.Status == orders.Delivered {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("Expected order %d to be delivered within 5s but it was not", id)
+}
+
+func TestOrderService(t *testing.T) {
+
+	dir := t.TempDir()
+	base, admin, stop := start(t, dir, "-audit-log", filepath.Join(dir, "audit.log"))
+
+	var ids []int
+	for range 3 {
+		ids = append(ids, create(t, base).ID)
+	}
+	if ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("Expected order IDs 1 to 3 but got %v", ids)
+	}
+	for _, id := range ids {
+		waitDelivered(t, base, id)
+	}
+
+	if code, _ := get(t, base+"/orders/42"); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown order but got %d", code)
+	}
+	if code, _ := get(t, base+"/healthz"); code != http.StatusOK {
+		t.Errorf("Expected /healthz to be 200 but got %d", code)
+	}
+	if code, _ := get(t, admin+"/debug/pprof/"); code != http.StatusOK {
+		t.Errorf("Expected the pprof index to be 200 but got %d", code)
+	}
+	if code, body := get(t, admin+"/tune/workers"); code != http.StatusOK || !strings.Contains(body, "1..8") {
+		t.Errorf("Expected the workers knob to be 1..8 but got %d %s", code, body)
+	}
+	for _, path := range []string{"/debug/pprof/", "/tune/workers"} {
+		if code, _ := get(t, base+path); code != http.StatusNotFound {
+			t.Errorf("Expected %s not to be served with the API but got %d", path, code)
+		}
+	}
+
+	// the bus delivers the counts on the scheduler, so they may lag a bit
+	deadline := time.Now().Add(time.Second)
+	for {
+		_, body := get(t, base+"/metrics")
+		if strings.Contains(body, "orders.created 3\n") && strings.Contains(body, "orders.delivered 3\n") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 3 orders created and delivered in the metrics but got\n%s", body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Expected a clean shutdown but got %v", err)
+	}
+
+	audit, err := os.Open(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+	events, err := jsonl.NewReader[auditEntry](audit, jsonl.WithDisallowUnknownFields())
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := 0
+	for e, err := range events.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("Expected JSON lines in the audit log but got %v", err)
+		}
+		if e.Status == orders.Delivered {
+			delivered++
+		}
+	}
+	if delivered != 3 {
+		t.Errorf("Expected 3 deliveries in the audit log but got %d", delivered)
+	}
+
+	s, err := openStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := s.All()
+	if len(all) != 3 {
+		t.Fatalf("Expected 3 saved orders but got %d", len(all))
+	}
+	for _, o := range all {
+		if o.Status != orders.Delivered {
+			t.Errorf("Expected order %d to be saved as delivered but got %s", o.ID, o.Status)
+		}
+	}
+}
+
+func TestOrderServiceResumes(t *testing.T) {
+
+	dir := t.TempDir()
+
+	// no time to finish on shutdown, so the orders stop halfway
+	base, _, stop := start(t, dir, "-shutdown-timeout", "1ns")
+	for range 2 {
+		create(t, base)
+	}
+	if err := stop(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the shutdown to run out of time but got %v", err)
+	}
+
+	s, err := openStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all := s.All(); len(all) != 2 || all[0].Status == orders.Delivered {
+		t.Fatalf("Expected 2 undelivered orders to be saved but got %v", all)
+	}
+
+	base, _, stop = start(t, dir)
+	waitDelivered(t, base, 1)
+	waitDelivered(t, base, 2)
+	if o := create(t, base); o.ID != 3 {
+		t.Errorf("Expected the next order to get ID 3 but got %d", o.ID)
+	}
+	if err := stop(); err != nil {
+		t.Fatalf("Expected a clean shutdown but got %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+
+	env := map[string]string{"ORDERSERVICE_WORKERS": "3", "ORDERSERVICE_LOG_LEVEL": "debug"}
+	cfg, err := loadConfig([]string{"-workers", "5"}, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Workers != 5 || cfg.LogLevel != "debug" {
+		t.Errorf("Expected the flag to win and the environment to fill in but got workers %d, log level %s", cfg.Workers, cfg.LogLevel)
+	}
+
+	file := filepath.Join(t.TempDir(), "orderservice.json")
+	os.WriteFile(file, []byte(`{"workers": 2, "rate": 50, "flush": "5s"}`), 0o644)
+	cfg, err = loadConfig([]string{"-config", file}, func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Workers != 3 || cfg.Rate != 50 || cfg.Flush != 5*time.Second {
+		t.Errorf("Expected the environment to win over the file but got %+v", cfg)
+	}
+
+	if _, err := loadConfig([]string{"-admin-addr", ":6060"}, func(k string) string { return env[k] }); err == nil {
+		t.Error("Expected an error for admin endpoints off loopback but got none")
+	}
+
+	env["ORDERSERVICE_WORKERS"] = "0"
+	if _, err := loadConfig(nil, func(k string) string { return env[k] }); err == nil {
+		t.Error("Expected an error for no workers but got none")
+	}
+
+	env["ORDERSERVICE_RATE"] = "fast"
+	if _, err := loadConfig(nil, func(k string) string { return env[k] }); err == nil {
+		t.Error("Expected an error for a rate that is not a number but got none")
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pacx/File-IO/rotate"
+	"pacx/Profiling/profiler"
+	"pacx/cache"
+	"pacx/concurrency/orders"
+	"pacx/concurrency/pool"
+	"pacx/concurrency/qos"
+	"pacx/concurrency/ratelimit"
+	"pacx/di"
+	"pacx/jsonx/jsonl"
+	"pacx/metrics"
+	"pacx/reflect/diff"
+	"pacx/tune"
+)
+
+// service takes orders over HTTP and moves each through its statuses on
+// the worker pool. Every status change is saved and then published on the
+// bus, whose subscribers count and log it. It is the last component
+// assemble builds, so it starts after everything it uses and stops first.
+type service struct {
+	log     *slog.Logger
+	reg     *metrics.Registry
+	store   *store
+	orders  *cache.Cached[int, orders.Order]
+	pool    *pool.ScalingPool
+	bus     *bus
+	limiter *ratelimit.Limiter
+	knobs   *tune.Registry
+	nextID  atomic.Int64
+
+	// work is cancelled when shutdown runs out of time, which stops the
+	// orders still being processed
+	work       context.Context
+	cancelWork context.CancelFunc
+}
+
+// newService subscribes the service to the bus, and the audit log too
+// when there is one, and registers the live settings.
+func newService(log *slog.Logger, level *slog.LevelVar, reg *metrics.Registry, limiter *ratelimit.Limiter,
+	st *store, cached *cache.Cached[int, orders.Order], p *pool.ScalingPool, b *bus, audit *auditLog) (*service, error) {
+
+	knobs, err := tune.New(tune.WithLogger(slog.NewLogLogger(log.Handler(), slog.LevelInfo)))
+	if err != nil {
+		return nil, err
+	}
+	err = knobs.Register(
+		tune.LogLevel(level),
+		tune.PoolBounds("workers", p),
+		tune.RateLimit("rate", limiter),
+		tune.GCPercent(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &service{log: log, reg: reg, store: st, orders: cached, pool: p, bus: b, limiter: limiter, knobs: knobs}
+	s.work, s.cancelWork = context.WithCancel(context.Background())
+
+	s.bus.Subscribe("*", func(o orders.Order) {
+		s.reg.Counter("orders." + strings.ToLower(o.Status)).Add(1)
+	})
+	s.bus.Subscribe(orders.Delivered, func(o orders.Order) {
+		log.Info("order delivered", "order", o.ID)
+	})
+	if audit != nil {
+		s.bus.Subscribe("*", audit.write)
+	}
+
+	return s, nil
+}
+
+// Start resumes the stored orders, before the API serves, so new orders
+// are numbered after them.
+func (s *service) Start(context.Context) error {
+
+	if err := s.resume(); err != nil {
+		s.log.Error("resuming orders", "err", err)
+	}
+
+	return nil
+}
+
+// resume processes the stored orders that were not delivered when the
+// service last stopped. They go through every status again from the
+// first.
+func (s *service) resume() error {
+
+	for _, o := range s.store.All() {
+		s.nextID.Store(max(s.nextID.Load(), int64(o.ID)))
+		if o.Status == orders.Delivered {
+			continue
+		}
+		s.log.Info("resuming order", "order", o.ID, "status", o.Status)
+		if err := s.pool.Submit(context.Background(), s.job(o)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// auditEntry is one line of the audit log.
+type auditEntry struct {
+	Time   time.Time `json:"time"`
+	Order  int       `json:"order"`
+	Status string    `json:"status"`
+}
+
+// auditLog is the -audit-log: every status change as a JSON line, in a
+// file rotated daily.
+type auditLog struct {
+	log    *slog.Logger
+	events *jsonl.Writer[auditEntry]
+}
+
+// newAuditLog opens the audit log, or returns nil without -audit-log.
+func newAuditLog(cfg settings, log *slog.Logger, b *bus, lc *di.Lifecycle) (*auditLog, error) {
+
+	if cfg.Audit == "" {
+		return nil, nil
+	}
+	f, err := rotate.New(cfg.Audit, rotate.WithMaxSize(0), rotate.WithMaxAge(24*time.Hour), rotate.WithBackups(30))
+	if err != nil {
+		return nil, err
+	}
+	lc.Append(di.Hook{Stop: func(context.Context) error {
+		// the events still queued are written before the file is closed
+		b.sched.Close()
+		return f.Close()
+	}})
+
+	return &auditLog{log: log, events: jsonl.NewWriter[auditEntry](f)}, nil
+}
+
+// write appends the status change of o to the audit log. The bus may run
+// subscribers at once; the jsonl.Writer keeps their lines whole.
+func (a *auditLog) write(o orders.Order) {
+
+	if err := a.events.Write(auditEntry{time.Now().UTC(), o.ID, o.Status}); err != nil {
+		a.log.Error("writing the audit log", "err", err)
+	}
+}
+
+// create stores a new pending order and queues it for processing.
+func (s *service) create(ctx context.Context) (orders.Order, error) {
+
+	o := orders.Order{ID: int(s.nextID.Add(1)), Status: orders.Pending}
+	if err := s.orders.Put(ctx, o.ID, o); err != nil {
+		return orders.Order{}, err
+	}
+	if err := s.pool.Submit(ctx, s.job(o)); err != nil {
+		s.orders.Delete(context.Background(), o.ID)
+		return orders.Order{}, err
+	}
+	s.reg.Counter("orders.created").Add(1)
+	s.bus.Publish(o)
+
+	return o, nil
+}
+
+func (s *service) job(o orders.Order) func() {
+	return func() {
+		prev := o
+		err := orders.Process(s.work, o, func(o orders.Order) error {
+			// saved before it is published, so subscribers that read
+			// the order back see this status or a later one
+			if err := s.orders.Put(s.work, o.ID, o); err != nil {
+				return err
+			}
+			if changes, _ := diff.Structs(prev, o); len(changes) > 0 {
+				s.log.Debug("order changed", "order", o.ID, "changes", changes)
+			}
+			prev = o
+			s.bus.Publish(o)
+			return nil
+		})
+		if err != nil {
+			s.reg.Counter("orders.interrupted").Add(1)
+			s.log.Warn("order interrupted", "order", o.ID, "err", err)
+		}
+	}
+}
+
+// Stop lets the queued and running orders finish, or stops them once ctx
+// is done. The components stopped after it then deliver the last events
+// and save every order.
+func (s *service) Stop(ctx context.Context) error {
+
+	drained := make(chan struct{})
+	go func() {
+		s.pool.Close()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.cancelWork()
+		<-drained
+	}
+	s.cancelWork()
+
+	return err
+}
+
+// handler is the HTTP API next to the metrics:
+//
+//	POST /orders          create an order
+//	GET  /orders/{id}     one order
+//	GET  /metrics         counters and gauges, one per line
+//	GET  /healthz         200 while serving
+//
+// A request's X-QoS header sets its qos level: Low ones are refused when
+// the pool's queue is full instead of waiting for room.
+func (s *service) handler() http.Handler {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders", s.handleCreate)
+	mux.HandleFunc("GET /orders/{id}", s.handleGet)
+	mux.HandleFunc("GET /metrics", s.handleMetrics)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok\n"))
+	})
+
+	requests := s.reg.Counter("http.requests")
+
+	return qos.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+// adminHandler is what changes or exposes the running process, served
+// apart from the API on -admin-addr:
+//
+//	/tune/           live settings, see package tune
+//	/debug/pprof/    profiles, see package profiler
+func (s *service) adminHandler() (http.Handler, error) {
+
+	pprof, err := profiler.Handler()
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/tune/", http.StripPrefix("/tune", s.knobs))
+	mux.Handle("/debug/", pprof)
+
+	return mux, nil
+}
+
+func (s *service) handleCreate(w http.ResponseWriter, r *http.Request) {
+
+	if !s.limiter.Allow() {
+		s.reg.Counter("orders.limited").Add(1)
+		http.Error(w, "too many orders", http.StatusTooManyRequests)
+		return
+	}
+
+	o, err := s.create(r.Context())
+	switch {
+	case errors.Is(err, pool.ErrFull), errors.Is(err, pool.ErrClosed):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Location", "/orders/"+strconv.Itoa(o.ID))
+	writeJSON(w, http.StatusAccepted, o)
+}
+
+func (s *service) handleGet(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "order id must be a number", http.StatusBadRequest)
+		return
+	}
+
+	o, err := s.orders.Get(r.Context(), id)
+	switch {
+	case errors.Is(err, cache.ErrNotFound):
+		http.Error(w, "no such order", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, o)
+}
+
+func (s *service) handleMetrics(w http.ResponseWriter, r *http.Request) {
+
+	s.reg.Gauge("scheduler.pending").Set(float64(s.bus.sched.Pending()))
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(s.reg.Snapshot(metrics.DefaultSource()).AppendLines(nil))
+}
+
+// pushMetrics sends the metrics to the collector at path until ctx is
+// done.
+func (s *service) pushMetrics(ctx context.Context, path string) {
+	metrics.PushEvery(ctx, path, time.Second, s.reg, metrics.DefaultSource())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
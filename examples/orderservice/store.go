@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"pacx/cache"
+	"pacx/concurrency/orders"
+	"pacx/validate"
+)
+
+// store is the key-value persistence of the orders: a cache.Repository
+// kept in one JSON file, which every write replaces by renaming a
+// temporary file over it, so a crash leaves the old orders or the new.
+// The service puts a write-behind cache in front, so writes are batched.
+type store struct {
+	path string
+
+	mu     sync.Mutex
+	orders map[int]orders.Order
+}
+
+func openStore(dir string) (*store, error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &store{path: filepath.Join(dir, "orders.json"), orders: make(map[int]orders.Order)}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []orders.Order
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("orders in %s: %w", s.path, err)
+	}
+	for i, o := range list {
+		if err := validate.Struct(o); err != nil {
+			return nil, fmt.Errorf("order %d in %s: %w", i, s.path, err)
+		}
+		s.orders[o.ID] = o
+	}
+
+	return s, nil
+}
+
+func (s *store) Get(_ context.Context, id int) (orders.Order, error) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return orders.Order{}, cache.ErrNotFound
+	}
+
+	return o, nil
+}
+
+func (s *store) Put(_ context.Context, id int, o orders.Order) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, had := s.orders[id]
+	s.orders[id] = o
+	if err := s.write(); err != nil {
+		if had {
+			s.orders[id] = old
+		} else {
+			delete(s.orders, id)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *store) Delete(_ context.Context, id int) error {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return nil
+	}
+	delete(s.orders, id)
+	if err := s.write(); err != nil {
+		s.orders[id] = o
+		return err
+	}
+
+	return nil
+}
+
+// All returns the stored orders by ID.
+func (s *store) All() []orders.Order {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]orders.Order, 0, len(s.orders))
+	for _, id := range slices.Sorted(maps.Keys(s.orders)) {
+		out = append(out, s.orders[id])
+	}
+
+	return out
+}
+
+// write replaces the file with the orders. mu must be held.
+func (s *store) write() error {
+
+	list := make([]orders.Order, 0, len(s.orders))
+	for _, id := range slices.Sorted(maps.Keys(s.orders)) {
+		list = append(list, s.orders[id])
+	}
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,10 @@
+//go:build !production
+
+package invariant
+
+// Production reports whether failures are logged rather than panicking.
+const Production = false
+
+func fail(msg string) {
+	panic(Violation{Msg: msg})
+}
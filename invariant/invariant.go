@@ -0,0 +1,32 @@
+// Package invariant documents and enforces internal invariants.
+//
+// By default a failed Check panics so bugs surface in tests and demos.
+// Building with -tags production turns failures into log lines instead,
+// rate limited per message so a hot path can't flood the output.
+package invariant
+
+import "fmt"
+
+// Violation is the panic value of a failed Check in dev builds.
+type Violation struct {
+	Msg string
+}
+
+func (v Violation) Error() string {
+	return "invariant violated: " + v.Msg
+}
+
+// Check reports a violation with msg when cond is false.
+func Check(cond bool, msg string) {
+	if !cond {
+		fail(msg)
+	}
+}
+
+// Checkf is Check with a formatted message. The message is only built when
+// cond is false.
+func Checkf(cond bool, format string, args ...any) {
+	if !cond {
+		fail(fmt.Sprintf(format, args...))
+	}
+}
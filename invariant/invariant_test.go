@@ -0,0 +1,26 @@
+//go:build !production
+
+package invariant_test
+
+import (
+	"testing"
+
+	"pacx/invariant"
+)
+
+func TestCheckPanics(t *testing.T) {
+
+	defer func() {
+		r := recover()
+		v, ok := r.(invariant.Violation)
+		if !ok {
+			t.Fatalf("Expected a Violation panic but got %v", r)
+		}
+		if v.Msg != "len 3 > cap 2" {
+			t.Errorf("Expected formatted message but got %q", v.Msg)
+		}
+	}()
+
+	invariant.Check(true, "never reported")
+	invariant.Checkf(3 <= 2, "len %d > cap %d", 3, 2)
+}
@@ -0,0 +1,54 @@
+//go:build production
+
+package invariant
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Production reports whether failures are logged rather than panicking.
+const Production = true
+
+// Interval is the minimum time between two log lines for the same message.
+var Interval = 10 * time.Second
+
+// Logf is where violations are written.
+var Logf = log.Printf
+
+type seen struct {
+	last       time.Time
+	suppressed int
+}
+
+var (
+	mu      sync.Mutex
+	reports = make(map[string]*seen)
+)
+
+func fail(msg string) {
+
+	now := time.Now()
+
+	mu.Lock()
+	s, ok := reports[msg]
+	if !ok {
+		s = &seen{}
+		reports[msg] = s
+	}
+	if ok && now.Sub(s.last) < Interval {
+		s.suppressed++
+		mu.Unlock()
+		return
+	}
+	suppressed := s.suppressed
+	s.last, s.suppressed = now, 0
+	mu.Unlock()
+
+	if suppressed > 0 {
+		Logf("invariant violated: %s (%d more since last report)", msg, suppressed)
+		return
+	}
+	Logf("invariant violated: %s", msg)
+}
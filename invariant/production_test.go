@@ -0,0 +1,36 @@
+//go:build production
+
+package invariant
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCheckRateLimited(t *testing.T) {
+
+	var lines []string
+	Logf = func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	Interval = time.Hour
+
+	for i := 0; i < 5; i++ {
+		Check(false, "queue length negative")
+	}
+	Check(false, "other")
+
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines but got %d: %v", len(lines), lines)
+	}
+
+	// once the interval is over the suppressed count is reported
+	reports["queue length negative"].last = time.Now().Add(-2 * time.Hour)
+	Check(false, "queue length negative")
+
+	expected := "invariant violated: queue length negative (4 more since last report)"
+	if lines[2] != expected {
+		t.Errorf("Expected %q but got %q", expected, lines[2])
+	}
+}
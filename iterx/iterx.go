@@ -0,0 +1,85 @@
+// Package iterx bridges channels and range-over-func iterators, the style
+// explored with maps.All in map-func.go.
+package iterx
+
+import (
+	"context"
+	"iter"
+)
+
+// FromChan yields values received from ch until it is closed, ctx is done
+// or the consumer stops ranging. Nothing is left running afterwards: the
+// sender side still owns ch and is responsible for closing it.
+func FromChan[T any](ctx context.Context, ch <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok := <-ch:
+				if !ok || !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ToChan runs seq on a new goroutine and sends its values on the returned
+// channel, which is closed when seq ends. Cancelling ctx stops seq at the
+// next value, so an abandoned channel doesn't leak the goroutine.
+func ToChan[T any](ctx context.Context, seq iter.Seq[T], buffer int) <-chan T {
+
+	out := make(chan T, buffer)
+
+	go func() {
+		defer close(out)
+
+		for v := range seq {
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// ToChan2 is ToChan for two-value sequences, sending them as pairs.
+func ToChan2[K, V any](ctx context.Context, seq iter.Seq2[K, V], buffer int) <-chan Pair[K, V] {
+
+	out := make(chan Pair[K, V], buffer)
+
+	go func() {
+		defer close(out)
+
+		for k, v := range seq {
+			select {
+			case out <- Pair[K, V]{Key: k, Value: v}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// Pair carries one element of an iter.Seq2 over a channel.
+type Pair[K, V any] struct {
+	Key   K
+	Value V
+}
+
+// FromChan2 is FromChan for channels of pairs.
+func FromChan2[K, V any](ctx context.Context, ch <-chan Pair[K, V]) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for p := range FromChan(ctx, ch) {
+			if !yield(p.Key, p.Value) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package iterx_test
+
+import (
+	"context"
+	"maps"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+
+	"pacx/iterx"
+)
+
+func TestRoundTrip(t *testing.T) {
+
+	ctx := context.Background()
+
+	ch := iterx.ToChan(ctx, slices.Values([]int{1, 2, 3}), 0)
+	got := slices.Collect(iterx.FromChan(ctx, ch))
+
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("Expected [1 2 3] but got %v", got)
+	}
+
+	m := map[string]int{"a": 1, "b": 2}
+	pairs := iterx.ToChan2(ctx, maps.All(m), 1)
+	if back := maps.Collect(iterx.FromChan2(ctx, pairs)); !maps.Equal(back, m) {
+		t.Errorf("Expected %v but got %v", m, back)
+	}
+}
+
+func TestFromChanCancel(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan int) // never closed
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range iterx.FromChan(ctx, ch) {
+		}
+	}()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the range to end on cancel")
+	}
+}
+
+func TestToChanCancelStopsProducer(t *testing.T) {
+
+	before := runtime.NumGoroutine()
+
+	endless := func(yield func(int) bool) {
+		for i := 0; yield(i); i++ {
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := iterx.ToChan(ctx, endless, 0)
+	<-ch
+	<-ch
+	cancel()
+
+	// the producer notices on its next send and closes the channel
+	for range ch {
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("Expected the producer goroutine to exit, %d running vs %d before", n, before)
+	}
+}
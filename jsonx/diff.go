@@ -0,0 +1,208 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math/big"
+	"slices"
+)
+
+// Op is the kind of a Change.
+type Op int
+
+const (
+	Added Op = iota
+	Removed
+	Changed
+)
+
+func (op Op) String() string {
+
+	switch op {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// Change is one difference between two documents. Path is in JSON terms,
+// as in items[2].name, and empty for the document itself. Old is nil when
+// the value was added and New when it was removed.
+type Change struct {
+	Op       Op
+	Path     string
+	Old, New any
+}
+
+// String is the change on one line, as in
+//
+//	~ items[0].qty: 1 -> 2
+//	+ items[1]: {"qty":5}
+//	- note: "gift"
+func (c Change) String() string {
+
+	path := c.Path
+	if path == "" {
+		path = "(root)"
+	}
+	switch c.Op {
+	case Added:
+		return "+ " + path + ": " + compact(c.New)
+	case Removed:
+		return "- " + path + ": " + compact(c.Old)
+	}
+
+	return "~ " + path + ": " + compact(c.Old) + " -> " + compact(c.New)
+}
+
+// Diff compares two JSON documents and returns their differences, in path
+// order, with objects compared key by key and arrays index by index.
+// Numbers are compared by value, so 1, 1.0 and 1e0 are the same.
+func Diff(a, b []byte) ([]Change, error) {
+
+	va, err := decodeTree(a)
+	if err != nil {
+		return nil, fmt.Errorf("jsonx: first document: %w", err)
+	}
+	vb, err := decodeTree(b)
+	if err != nil {
+		return nil, fmt.Errorf("jsonx: second document: %w", err)
+	}
+
+	return DiffValues(va, vb), nil
+}
+
+// DiffValues is Diff for values already decoded into interface values, as
+// json.Unmarshal does with an any: maps of string to any, slices of any,
+// strings, float64s or json.Numbers, bools and nil. Other values are
+// compared with ==, or reported changed if they are not comparable.
+func DiffValues(a, b any) []Change {
+
+	var changes []Change
+	diff(a, b, "", &changes)
+
+	return changes
+}
+
+func diff(a, b any, path string, changes *[]Change) {
+
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := slices.Collect(maps.Keys(a))
+		for k := range b {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			va, ina := a[k]
+			vb, inb := b[k]
+			p := join(path, k)
+			switch {
+			case !inb:
+				*changes = append(*changes, Change{Op: Removed, Path: p, Old: va})
+			case !ina:
+				*changes = append(*changes, Change{Op: Added, Path: p, New: vb})
+			default:
+				diff(va, vb, p, changes)
+			}
+		}
+		return
+
+	case []any:
+		b, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := range max(len(a), len(b)) {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(b):
+				*changes = append(*changes, Change{Op: Removed, Path: p, Old: a[i]})
+			case i >= len(a):
+				*changes = append(*changes, Change{Op: Added, Path: p, New: b[i]})
+			default:
+				diff(a[i], b[i], p, changes)
+			}
+		}
+		return
+	}
+
+	if !same(a, b) {
+		*changes = append(*changes, Change{Op: Changed, Path: path, Old: a, New: b})
+	}
+}
+
+// same reports whether two leaves are equal, numbers by value.
+func same(a, b any) bool {
+
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x.Cmp(y) == 0
+	}
+	switch a.(type) {
+	case map[string]any, []any:
+		return false
+	}
+	defer func() { recover() }() // not comparable
+
+	return a == b
+}
+
+// number returns v as an exact number if it is one json.Unmarshal makes.
+func number(v any) (*big.Rat, bool) {
+
+	switch v := v.(type) {
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(v) == nil {
+			return nil, false // not finite
+		}
+		return r, true
+	case json.Number:
+		return new(big.Rat).SetString(string(v))
+	}
+
+	return nil, false
+}
+
+// decodeTree decodes a whole document, keeping numbers exact.
+func decodeTree(data []byte) (any, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	// More misses a stray closing bracket; only EOF means nothing follows
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("more than one value")
+	}
+
+	return v, nil
+}
+
+func compact(v any) string {
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+
+	return string(b)
+}
@@ -0,0 +1,113 @@
+package jsonx_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"pacx/jsonx"
+)
+
+func render(changes []jsonx.Change) string {
+
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+func TestDiff(t *testing.T) {
+
+	a := `{"id": 1, "status": "paid", "note": "gift", "items": [{"sku": "A", "qty": 1}, {"sku": "B", "qty": 2}], "total": 10}`
+	b := `{"id": 1.0, "status": "shipped", "items": [{"sku": "A", "qty": 3}], "tags": ["x"], "total": 1e1}`
+
+	changes, err := jsonx.Diff([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `~ items[0].qty: 1 -> 3
+- items[1]: {"qty":2,"sku":"B"}
+- note: "gift"
+~ status: "paid" -> "shipped"
++ tags: ["x"]
+`
+	if got := render(changes); got != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, got)
+	}
+	if changes[1].Op != jsonx.Removed || changes[4].Op != jsonx.Added || changes[0].Op != jsonx.Changed {
+		t.Errorf("Expected the ops changed, removed, added but got %v, %v, %v", changes[0].Op, changes[1].Op, changes[4].Op)
+	}
+}
+
+func TestDiffSame(t *testing.T) {
+
+	a := `{"a": [1, {"b": null}], "c": true, "d": 12345678901234567890}`
+	b := "{\n  \"d\": 12345678901234567890,\n  \"c\": true,\n  \"a\": [1.0, {\"b\": null}]\n}"
+
+	changes, err := jsonx.Diff([]byte(a), []byte(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes but got\n%s", render(changes))
+	}
+
+	// exact, where float64 would round both to the same value
+	changes, _ = jsonx.Diff([]byte(`12345678901234567890`), []byte(`12345678901234567891`))
+	if len(changes) != 1 {
+		t.Errorf("Expected large numbers compared exactly but got %v", changes)
+	}
+}
+
+func TestDiffTypes(t *testing.T) {
+
+	changes, err := jsonx.Diff([]byte(`{"a": [1], "b": "1", "c": null}`), []byte(`{"a": {"0": 1}, "b": 1, "c": false}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `~ a: [1] -> {"0":1}
+~ b: "1" -> 1
+~ c: null -> false
+`
+	if got := render(changes); got != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, got)
+	}
+
+	changes, _ = jsonx.Diff([]byte(`[1]`), []byte(`"x"`))
+	if len(changes) != 1 || changes[0].String() != `~ (root): [1] -> "x"` {
+		t.Errorf("Expected the root changed but got %v", changes)
+	}
+}
+
+func TestDiffInvalid(t *testing.T) {
+
+	if _, err := jsonx.Diff([]byte(`{}`), []byte(`{`)); err == nil || !strings.Contains(err.Error(), "second document") {
+		t.Errorf("Expected an error for the second document but got %v", err)
+	}
+	if _, err := jsonx.Diff([]byte(`{} {}`), []byte(`{}`)); err == nil || !strings.Contains(err.Error(), "first document") {
+		t.Errorf("Expected an error for the first document but got %v", err)
+	}
+	if _, err := jsonx.Diff([]byte(`{"a":1}]`), []byte(`{"a":1}`)); err == nil || !strings.Contains(err.Error(), "first document") {
+		t.Errorf("Expected an error for the stray bracket but got %v", err)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+
+	var a, b any
+	json.Unmarshal([]byte(`{"items": [1, 2, 3]}`), &a)
+	json.Unmarshal([]byte(`{"items": [1, 2]}`), &b)
+
+	changes := jsonx.DiffValues(a, b)
+	if len(changes) != 1 || changes[0].Path != "items[2]" || changes[0].Old != 3.0 {
+		t.Errorf("Expected items[2] removed but got %v", changes)
+	}
+	if changes := jsonx.DiffValues([]int{1}, []int{1}); len(changes) != 1 {
+		t.Errorf("Expected values that are not comparable to be changed but got %v", changes)
+	}
+}
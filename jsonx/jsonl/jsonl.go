@@ -0,0 +1,269 @@
+// Package jsonl reads and writes JSON Lines: one JSON value per line, as
+// in append-only logs where a record is added with a single write.
+//
+//	w := jsonl.NewWriter[Event](f)
+//	w.Write(Event{Order: 1, Status: "pending"})
+//
+//	r, _ := jsonl.NewReader[Event](f)
+//	for e, err := range r.All(ctx) {
+//		var rerr *jsonl.RecordError
+//		if errors.As(err, &rerr) {
+//			continue // a bad line, skip it
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// A line that does not decode is a *RecordError, after which reading goes
+// on with the next line, so one torn or corrupt record does not lose the
+// rest of the log.
+package jsonl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+
+	"pacx/options"
+)
+
+type config struct {
+	maxLine      int
+	useNumber    bool
+	strictFields bool
+}
+
+// Option configures NewReader.
+type Option = options.Option[config]
+
+// WithMaxLineLength sets the longest line that is decoded; a longer one is
+// skipped with a *RecordError wrapping bufio.ErrTooLong. Defaults to 1 MiB.
+func WithMaxLineLength(n int) Option {
+	return options.New("WithMaxLineLength", func(c *config) error {
+		if n <= 0 {
+			return errors.New("max line length must be positive")
+		}
+		c.maxLine = n
+		return nil
+	})
+}
+
+// WithUseNumber decodes numbers into interface values as json.Number, see
+// json.Decoder.UseNumber.
+func WithUseNumber() Option {
+	return options.New("WithUseNumber", func(c *config) error {
+		c.useNumber = true
+		return nil
+	})
+}
+
+// WithDisallowUnknownFields fails a record with object keys that match no
+// field of T, see json.Decoder.DisallowUnknownFields.
+func WithDisallowUnknownFields() Option {
+	return options.New("WithDisallowUnknownFields", func(c *config) error {
+		c.strictFields = true
+		return nil
+	})
+}
+
+// RecordError is a record that could not be read or written.
+type RecordError struct {
+	Line int // counting from 1
+	Err  error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("jsonl: line %d: %v", e.Line, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads values of type T, one per line. Blank lines are skipped.
+type Reader[T any] struct {
+	r    *bufio.Reader
+	cfg  config
+	line int
+	buf  []byte
+}
+
+// NewReader returns a Reader of the lines of r.
+func NewReader[T any](r io.Reader, opts ...Option) (*Reader[T], error) {
+
+	cfg, err := options.Build(config{maxLine: 1 << 20}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader[T]{r: bufio.NewReader(r), cfg: cfg}, nil
+}
+
+// Read returns the next record, or io.EOF after the last one. A line that
+// does not decode fails with a *RecordError; the Reader can go on to the
+// next line after that. Any other error is from the underlying reader.
+func (r *Reader[T]) Read() (T, error) {
+
+	var v, zero T
+
+	for {
+		line, tooLong, err := r.readLine()
+		if err != nil {
+			return zero, err
+		}
+		r.line++
+		if tooLong {
+			return zero, &RecordError{Line: r.line, Err: bufio.ErrTooLong}
+		}
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := r.decode(line, &v); err != nil {
+			return zero, &RecordError{Line: r.line, Err: err}
+		}
+		return v, nil
+	}
+}
+
+// All yields the remaining records. A *RecordError is yielded with the zero
+// T and the iteration goes on, so the loop decides whether to skip the
+// line or stop. Any other error ends it, as does ctx being done, which is
+// checked before each line.
+func (r *Reader[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+
+		var zero T
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			v, err := r.Read()
+			if err == io.EOF {
+				return
+			}
+			var rerr *RecordError
+			if err != nil && !errors.As(err, &rerr) {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// readLine returns the next line without its "\n". One longer than the max
+// line length is read past and reported as tooLong instead. The last line
+// may lack the "\n".
+func (r *Reader[T]) readLine() ([]byte, bool, error) {
+
+	r.buf = r.buf[:0]
+	tooLong := false
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		if !tooLong {
+			r.buf = append(r.buf, chunk...)
+			if len(bytes.TrimSuffix(r.buf, []byte("\n"))) > r.cfg.maxLine {
+				tooLong = true
+			}
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && (len(r.buf) > 0 || tooLong):
+			// the last line, without a newline
+		case err != nil:
+			return nil, false, err
+		}
+		return bytes.TrimSuffix(r.buf, []byte("\n")), tooLong, nil
+	}
+}
+
+// decode decodes line into v, which must be all of it.
+func (r *Reader[T]) decode(line []byte, v *T) error {
+
+	dec := json.NewDecoder(bytes.NewReader(line))
+	if r.cfg.useNumber {
+		dec.UseNumber()
+	}
+	if r.cfg.strictFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("more than one value on the line")
+	}
+
+	return nil
+}
+
+// Writer writes values of type T, one per line. Each record goes to the
+// underlying io.Writer in a single Write, so records from concurrent
+// writers to a file opened with os.O_APPEND do not interleave. A Writer is
+// safe for concurrent use.
+type Writer[T any] struct {
+	mu   sync.Mutex
+	w    io.Writer
+	line int
+	buf  bytes.Buffer
+	enc  *json.Encoder
+}
+
+// NewWriter returns a Writer to w.
+func NewWriter[T any](w io.Writer) *Writer[T] {
+
+	jw := &Writer[T]{w: w}
+	jw.enc = json.NewEncoder(&jw.buf)
+
+	return jw
+}
+
+// Write writes v as one line. A value that does not encode fails with a
+// *RecordError and nothing is written.
+func (w *Writer[T]) Write(v T) error {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Reset()
+	// Encode ends the value with the newline
+	if err := w.enc.Encode(v); err != nil {
+		return &RecordError{Line: w.line + 1, Err: err}
+	}
+	if _, err := w.w.Write(w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.line++
+
+	return nil
+}
+
+// WriteAll writes the values of seq until it ends, a write fails or ctx is
+// done, which is checked before each value.
+func (w *Writer[T]) WriteAll(ctx context.Context, seq iter.Seq[T]) error {
+
+	for v := range seq {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := w.Write(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package jsonl_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"pacx/jsonx/jsonl"
+)
+
+type event struct {
+	Order  int    `json:"order"`
+	Status string `json:"status"`
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	in := []event{{1, "pending"}, {1, "paid"}, {2, "pending <new>"}}
+
+	var buf bytes.Buffer
+	w := jsonl.NewWriter[event](&buf)
+	if err := w.WriteAll(context.Background(), slices.Values(in)); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != len(in) {
+		t.Errorf("Expected %d lines but got %d in\n%s", len(in), n, buf.String())
+	}
+
+	r, err := jsonl.NewReader[event](&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []event
+	for e, err := range r.All(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, e)
+	}
+	if !slices.Equal(in, out) {
+		t.Errorf("Expected %v but got %v", in, out)
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+
+	input := "{\"order\":1}\n" +
+		"\n" +
+		"{\"order\":2\n" + // torn
+		"  {\"order\":3}\r\n" +
+		"{\"order\":4} {\"order\":5}\n" +
+		"{\"order\":\"six\"}\n" +
+		"{\"order\":7}" // no final newline
+
+	r, err := jsonl.NewReader[event](strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []int
+	var bad []int
+	for e, err := range r.All(context.Background()) {
+		var rerr *jsonl.RecordError
+		if errors.As(err, &rerr) {
+			bad = append(bad, rerr.Line)
+			if e != (event{}) {
+				t.Errorf("Expected the zero event with an error but got %v", e)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e.Order)
+	}
+	if !slices.Equal(got, []int{1, 3, 7}) {
+		t.Errorf("Expected orders [1 3 7] but got %v", got)
+	}
+	if !slices.Equal(bad, []int{3, 5, 6}) {
+		t.Errorf("Expected bad lines [3 5 6] but got %v", bad)
+	}
+}
+
+func TestReadEOF(t *testing.T) {
+
+	r, _ := jsonl.NewReader[event](strings.NewReader("{\"order\":1}\n\n"))
+	if e, err := r.Read(); err != nil || e.Order != 1 {
+		t.Fatalf("Expected order 1 but got %v, %v", e, err)
+	}
+	for range 2 {
+		if _, err := r.Read(); err != io.EOF {
+			t.Errorf("Expected io.EOF but got %v", err)
+		}
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+
+	long := `{"order":1,"status":"` + strings.Repeat("x", 10_000) + `"}`
+	input := long + "\n{\"order\":2}\n" + long
+
+	r, err := jsonl.NewReader[event](strings.NewReader(input), jsonl.WithMaxLineLength(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []int
+	var orders []int
+	for e, err := range r.All(context.Background()) {
+		var rerr *jsonl.RecordError
+		switch {
+		case errors.As(err, &rerr) && errors.Is(err, bufio.ErrTooLong):
+			lines = append(lines, rerr.Line)
+		case err != nil:
+			t.Fatal(err)
+		default:
+			orders = append(orders, e.Order)
+		}
+	}
+	if !slices.Equal(lines, []int{1, 3}) || !slices.Equal(orders, []int{2}) {
+		t.Errorf("Expected lines 1 and 3 too long and order 2 read but got %v and %v", lines, orders)
+	}
+
+	if _, err := jsonl.NewReader[event](nil, jsonl.WithMaxLineLength(0)); err == nil {
+		t.Error("Expected an error for a max line length of 0")
+	}
+}
+
+func TestDisallowUnknownFields(t *testing.T) {
+
+	r, _ := jsonl.NewReader[event](strings.NewReader(`{"order":1,"extra":true}`), jsonl.WithDisallowUnknownFields())
+	var rerr *jsonl.RecordError
+	if _, err := r.Read(); !errors.As(err, &rerr) || rerr.Line != 1 {
+		t.Errorf("Expected a record error on line 1 but got %v", err)
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+
+	r, _ := jsonl.NewReader[map[string]any](strings.NewReader(`{"id":12345678901234567890}`), jsonl.WithUseNumber())
+	m, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, ok := m["id"].(json.Number); !ok || n != "12345678901234567890" {
+		t.Errorf("Expected the number kept exact but got %v", m["id"])
+	}
+}
+
+func TestAllContext(t *testing.T) {
+
+	r, _ := jsonl.NewReader[event](strings.NewReader("{}\n{}\n{}\n"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	n := 0
+	var last error
+	for _, err := range r.All(ctx) {
+		if err != nil {
+			last = err
+			break
+		}
+		n++
+		cancel()
+	}
+	if n != 1 || !errors.Is(last, context.Canceled) {
+		t.Errorf("Expected 1 record then context.Canceled but got %d and %v", n, last)
+	}
+
+	w := jsonl.NewWriter[event](io.Discard)
+	if err := w.WriteAll(ctx, slices.Values([]event{{}})); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled from WriteAll but got %v", err)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+
+	var buf bytes.Buffer
+	w := jsonl.NewWriter[any](&buf)
+	w.Write(1)
+	err := w.Write(func() {})
+	var rerr *jsonl.RecordError
+	if !errors.As(err, &rerr) || rerr.Line != 2 {
+		t.Errorf("Expected a record error on line 2 but got %v", err)
+	}
+	if buf.String() != "1\n" {
+		t.Errorf("Expected only the first record written but got %q", buf.String())
+	}
+	if err := w.Write(2); err != nil || buf.String() != "1\n2\n" {
+		t.Errorf("Expected the writer to go on after the error but got %v, %q", err, buf.String())
+	}
+}
+
+// lineWriter fails the test unless every Write is whole lines.
+type lineWriter struct {
+	t   *testing.T
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+
+	if !bytes.HasSuffix(p, []byte("\n")) || bytes.Count(p, []byte("\n")) != 1 {
+		w.t.Errorf("Expected one line per write but got %q", p)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buf.Write(p)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+
+	lw := &lineWriter{t: t}
+	w := jsonl.NewWriter[event](lw)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			for range 100 {
+				if err := w.Write(event{Order: i, Status: "pending"}); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	r, _ := jsonl.NewReader[event](&lw.buf, jsonl.WithDisallowUnknownFields())
+	n := 0
+	for _, err := range r.All(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+	}
+	if n != 800 {
+		t.Errorf("Expected 800 records but got %d", n)
+	}
+}
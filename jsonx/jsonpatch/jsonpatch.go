@@ -0,0 +1,398 @@
+// Package jsonpatch updates JSON documents with the two standard kinds of
+// patch: JSON Patch, RFC 6902, a list of operations such as
+//
+//	[{"op": "replace", "path": "/status", "value": "shipped"},
+//	 {"op": "add", "path": "/items/-", "value": {"sku": "B-2"}}]
+//
+// and JSON Merge Patch, RFC 7396, an object of the members to set, with
+// null for those to remove:
+//
+//	{"status": "shipped", "note": null}
+//
+// Patches apply to a copy: a patch that fails leaves nothing half done.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pacx/jsonx"
+)
+
+// The errors an OpError wraps.
+var (
+	ErrInvalid    = errors.New("invalid operation")
+	ErrNoPath     = errors.New("path not found")
+	ErrTestFailed = errors.New("test failed")
+)
+
+// Operation is one step of a JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// OpError is a JSON Patch operation that could not be applied.
+type OpError struct {
+	Index int // in the patch, counting from 0
+	Op    Operation
+	Err   error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("jsonpatch: operation %d, %s %q: %v", e.Index, e.Op.Op, e.Op.Path, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Apply applies patch to doc: a JSON Patch if it is an array, a JSON Merge
+// Patch otherwise. A merge patch that is itself an array, which replaces
+// the whole document, needs Merge.
+func Apply(doc, patch []byte) ([]byte, error) {
+
+	if p := bytes.TrimSpace(patch); len(p) > 0 && p[0] == '[' {
+		return Patch(doc, patch)
+	}
+
+	return Merge(doc, patch)
+}
+
+// Patch applies the JSON Patch operations in patch to doc, in order. The
+// first that fails is returned as an *OpError.
+func Patch(doc, patch []byte) ([]byte, error) {
+
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("jsonpatch: patch: %w", err)
+	}
+	tree, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: document: %w", err)
+	}
+
+	for i, op := range ops {
+		if tree, err = apply(tree, op); err != nil {
+			return nil, &OpError{Index: i, Op: op, Err: err}
+		}
+	}
+
+	return encode(tree)
+}
+
+// Merge applies the JSON Merge Patch patch to doc.
+func Merge(doc, patch []byte) ([]byte, error) {
+
+	tree, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("jsonpatch: patch: %w", err)
+	}
+
+	return encode(merge(tree, p))
+}
+
+// merge is the MergePatch function of RFC 7396.
+func merge(target, patch any) any {
+
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any, len(p))
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+
+	return t
+}
+
+// apply runs one operation on tree and returns the result. Containers are
+// changed in place; Patch works on its own decoded copy.
+func apply(tree any, op Operation) (any, error) {
+
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: no value", ErrInvalid)
+		}
+		if value, err = decode(op.Value); err != nil {
+			return nil, err
+		}
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = get(tree, from); err != nil {
+			return nil, fmt.Errorf("from %q: %w", op.From, err)
+		}
+		if op.Op == "copy" {
+			value = clone(value)
+			break
+		}
+		if op.From == op.Path {
+			return tree, nil
+		}
+		if strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move %q into itself", ErrInvalid, op.From)
+		}
+		if tree, err = remove(tree, from); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalid, op.Op)
+	}
+
+	switch op.Op {
+	case "add", "move", "copy":
+		return add(tree, path, value)
+	case "remove":
+		return remove(tree, path)
+	case "replace":
+		if _, err := get(tree, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return value, nil
+		}
+		if tree, err = remove(tree, path); err != nil {
+			return nil, err
+		}
+		return add(tree, path, value)
+	}
+
+	// test
+	got, err := get(tree, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(jsonx.DiffValues(got, value)) > 0 {
+		return nil, ErrTestFailed
+	}
+
+	return tree, nil
+}
+
+// parsePointer splits a JSON Pointer, RFC 6901, into its reference tokens.
+// The empty pointer is the whole document.
+func parsePointer(p string) ([]string, error) {
+
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer %q does not start with /", ErrInvalid, p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// get returns the value at path.
+func get(tree any, path []string) (any, error) {
+
+	for _, tok := range path {
+		switch n := tree.(type) {
+		case map[string]any:
+			v, ok := n[tok]
+			if !ok {
+				return nil, ErrNoPath
+			}
+			tree = v
+		case []any:
+			i, err := index(tok, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			tree = n[i]
+		default:
+			return nil, ErrNoPath
+		}
+	}
+
+	return tree, nil
+}
+
+// add puts value at path: a member of an object is set, an element of an
+// array inserted before the one at the index, or appended for "-".
+func add(tree any, path []string, value any) (any, error) {
+
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return edit(tree, path, func(parent any, tok string) (any, error) {
+		switch n := parent.(type) {
+		case map[string]any:
+			n[tok] = value
+			return n, nil
+		case []any:
+			if tok == "-" {
+				return append(n, value), nil
+			}
+			i, err := index(tok, len(n))
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:i], append([]any{value}, n[i:]...)...), nil
+		}
+		return nil, ErrNoPath
+	})
+}
+
+// remove takes out the value at path, which must exist.
+func remove(tree any, path []string) (any, error) {
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalid)
+	}
+
+	return edit(tree, path, func(parent any, tok string) (any, error) {
+		switch n := parent.(type) {
+		case map[string]any:
+			if _, ok := n[tok]; !ok {
+				return nil, ErrNoPath
+			}
+			delete(n, tok)
+			return n, nil
+		case []any:
+			i, err := index(tok, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:i], n[i+1:]...), nil
+		}
+		return nil, ErrNoPath
+	})
+}
+
+// edit walks to the container path ends in and replaces it with what fn
+// makes of it and the last token, writing a changed array back into its
+// parent.
+func edit(tree any, path []string, fn func(parent any, tok string) (any, error)) (any, error) {
+
+	if len(path) == 1 {
+		return fn(tree, path[0])
+	}
+
+	switch n := tree.(type) {
+	case map[string]any:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, ErrNoPath
+		}
+		child, err := edit(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = child
+		return n, nil
+	case []any:
+		i, err := index(path[0], len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		child, err := edit(n[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = child
+		return n, nil
+	}
+
+	return nil, ErrNoPath
+}
+
+// index parses an array index no greater than last. Leading zeros are not
+// allowed.
+func index(tok string, last int) (int, error) {
+
+	if tok == "" || len(tok) > 1 && tok[0] == '0' || strings.TrimLeft(tok, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: bad array index %q", ErrNoPath, tok)
+	}
+	i, err := strconv.Atoi(tok)
+	if err != nil || i > last {
+		return 0, fmt.Errorf("%w: array index %s out of range", ErrNoPath, tok)
+	}
+
+	return i, nil
+}
+
+// clone copies the objects and arrays of v, so a copied value does not
+// share them with the original.
+func clone(v any) any {
+
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = clone(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = clone(e)
+		}
+		return s
+	}
+
+	return v
+}
+
+// decode reads one JSON value, keeping numbers as they were written.
+func decode(data []byte) (any, error) {
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("more than one value")
+	}
+
+	return v, nil
+}
+
+// encode writes v compactly, without escaping HTML characters.
+func encode(v any) ([]byte, error) {
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,208 @@
+package jsonpatch_test
+
+import (
+	"errors"
+	"testing"
+
+	"pacx/jsonx"
+	"pacx/jsonx/jsonpatch"
+)
+
+func sameJSON(t *testing.T, want, got string) {
+
+	t.Helper()
+
+	changes, err := jsonx.Diff([]byte(want), []byte(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) > 0 {
+		t.Errorf("Expected %s but got %s, differing in %v", want, got, changes)
+	}
+}
+
+// The examples of RFC 6902, appendix A.
+func TestPatchRFC(t *testing.T) {
+
+	tests := []struct {
+		name, doc, patch, want string
+		err                    error // want is ignored if set
+	}{
+		{"A.1 add object member", `{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			`{"baz": "qux", "foo": "bar"}`, nil},
+		{"A.2 add array element", `{"foo": ["bar", "baz"]}`,
+			`[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			`{"foo": ["bar", "qux", "baz"]}`, nil},
+		{"A.3 remove object member", `{"baz": "qux", "foo": "bar"}`,
+			`[{"op": "remove", "path": "/baz"}]`,
+			`{"foo": "bar"}`, nil},
+		{"A.4 remove array element", `{"foo": ["bar", "qux", "baz"]}`,
+			`[{"op": "remove", "path": "/foo/1"}]`,
+			`{"foo": ["bar", "baz"]}`, nil},
+		{"A.5 replace", `{"baz": "qux", "foo": "bar"}`,
+			`[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			`{"baz": "boo", "foo": "bar"}`, nil},
+		{"A.6 move value", `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			`[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			`{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`, nil},
+		{"A.7 move array element", `{"foo": ["all", "grass", "cows", "eat"]}`,
+			`[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			`{"foo": ["all", "cows", "eat", "grass"]}`, nil},
+		{"A.8 test", `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			`[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			`{"baz": "qux", "foo": ["a", 2, "c"]}`, nil},
+		{"A.9 test error", `{"baz": "qux"}`,
+			`[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			``, jsonpatch.ErrTestFailed},
+		{"A.10 add nested member", `{"foo": "bar"}`,
+			`[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			`{"foo": "bar", "child": {"grandchild": {}}}`, nil},
+		{"A.11 ignore unknown members", `{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			`{"foo": "bar", "baz": "qux"}`, nil},
+		{"A.12 add to nonexistent target", `{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			``, jsonpatch.ErrNoPath},
+		{"A.13 duplicate op", `{"foo": "bar"}`,
+			`[{"op": "add", "path": "/baz", "value": "qux", "op": "remove"}]`,
+			``, jsonpatch.ErrNoPath},
+		{"A.14 escapes", `{"/": 9, "~1": 10}`,
+			`[{"op": "test", "path": "/~01", "value": 10}]`,
+			`{"/": 9, "~1": 10}`, nil},
+		{"A.15 string against number", `{"/": 9, "~1": 10}`,
+			`[{"op": "test", "path": "/~01", "value": "10"}]`,
+			``, jsonpatch.ErrTestFailed},
+		{"A.16 add array value", `{"foo": ["bar"]}`,
+			`[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			`{"foo": ["bar", ["abc", "def"]]}`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.Apply([]byte(tt.doc), []byte(tt.patch))
+			if tt.err != nil {
+				var oerr *jsonpatch.OpError
+				if !errors.Is(err, tt.err) || !errors.As(err, &oerr) {
+					t.Errorf("Expected an *OpError wrapping %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameJSON(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestPatch(t *testing.T) {
+
+	tests := []struct {
+		name, doc, patch, want string
+		err                    error
+	}{
+		{"replace root", `{"a": 1}`, `[{"op": "replace", "path": "", "value": [1]}]`, `[1]`, nil},
+		{"add root", `{"a": 1}`, `[{"op": "add", "path": "", "value": "x"}]`, `"x"`, nil},
+		{"copy is deep", `{"a": {"b": 1}}`,
+			`[{"op": "copy", "from": "/a", "path": "/c"}, {"op": "replace", "path": "/c/b", "value": 2}]`,
+			`{"a": {"b": 1}, "c": {"b": 2}}`, nil},
+		{"append", `[1, 2]`, `[{"op": "add", "path": "/2", "value": 3}]`, `[1, 2, 3]`, nil},
+		{"numbers kept", `{"n": 12345678901234567890}`, `[{"op": "add", "path": "/m", "value": 1.50}]`,
+			`{"m": 1.50, "n": 12345678901234567890}`, nil},
+		{"nested array", `{"a": [[1], [2]]}`, `[{"op": "add", "path": "/a/1/0", "value": 0}]`, `{"a": [[1], [0, 2]]}`, nil},
+		{"move to same place", `{"a": 1}`, `[{"op": "move", "from": "/a", "path": "/a"}]`, `{"a": 1}`, nil},
+		{"move into itself", `{"a": {"b": 1}}`, `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`, ``, jsonpatch.ErrInvalid},
+		{"unknown op", `{}`, `[{"op": "frob", "path": "/a"}]`, ``, jsonpatch.ErrInvalid},
+		{"no value", `{}`, `[{"op": "add", "path": "/a"}]`, ``, jsonpatch.ErrInvalid},
+		{"bad pointer", `{}`, `[{"op": "add", "path": "a", "value": 1}]`, ``, jsonpatch.ErrInvalid},
+		{"index past end", `[1]`, `[{"op": "add", "path": "/2", "value": 1}]`, ``, jsonpatch.ErrNoPath},
+		{"leading zero", `[1, 2]`, `[{"op": "remove", "path": "/01"}]`, ``, jsonpatch.ErrNoPath},
+		{"remove dash", `[1]`, `[{"op": "remove", "path": "/-"}]`, ``, jsonpatch.ErrNoPath},
+		{"replace missing", `{}`, `[{"op": "replace", "path": "/a", "value": 1}]`, ``, jsonpatch.ErrNoPath},
+		{"remove root", `{}`, `[{"op": "remove", "path": ""}]`, ``, jsonpatch.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := jsonpatch.Patch([]byte(tt.doc), []byte(tt.patch))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("Expected %v but got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			sameJSON(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestPatchFailsWhole(t *testing.T) {
+
+	patch := `[{"op": "add", "path": "/a", "value": 1}, {"op": "remove", "path": "/missing"}]`
+	got, err := jsonpatch.Patch([]byte(`{}`), []byte(patch))
+
+	var oerr *jsonpatch.OpError
+	if !errors.As(err, &oerr) || oerr.Index != 1 || oerr.Op.Path != "/missing" {
+		t.Fatalf("Expected the second operation to fail but got %v", err)
+	}
+	if got != nil {
+		t.Errorf("Expected no document from a failed patch but got %s", got)
+	}
+}
+
+// The examples of RFC 7396, appendix A.
+func TestMergeRFC(t *testing.T) {
+
+	tests := []struct{ doc, patch, want string }{
+		{`{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+		{`{"a": "b"}`, `{"a": null}`, `{}`},
+		{`{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+		{`{"a": ["b"]}`, `{"a": "c"}`, `{"a": "c"}`},
+		{`{"a": "c"}`, `{"a": ["b"]}`, `{"a": ["b"]}`},
+		{`{"a": {"b": "c"}}`, `{"a": {"b": "d", "c": null}}`, `{"a": {"b": "d"}}`},
+		{`{"a": [{"b": "c"}]}`, `{"a": [1]}`, `{"a": [1]}`},
+		{`["a", "b"]`, `["c", "d"]`, `["c", "d"]`},
+		{`{"a": "b"}`, `["c"]`, `["c"]`},
+		{`{"a": "foo"}`, `null`, `null`},
+		{`{"a": "foo"}`, `"bar"`, `"bar"`},
+		{`{"e": null}`, `{"a": 1}`, `{"e": null, "a": 1}`},
+		{`[1, 2]`, `{"a": "b", "c": null}`, `{"a": "b"}`},
+		{`{}`, `{"a": {"bb": {"ccc": null}}}`, `{"a": {"bb": {}}}`},
+	}
+
+	for _, tt := range tests {
+		got, err := jsonpatch.Merge([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Fatalf("%s with %s: %v", tt.doc, tt.patch, err)
+		}
+		sameJSON(t, tt.want, string(got))
+	}
+}
+
+func TestApplyMerge(t *testing.T) {
+
+	got, err := jsonpatch.Apply([]byte(`{"id": 7, "status": "paid", "note": "<gift>"}`), []byte(` {"status": "shipped", "note": null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != `{"id":7,"status":"shipped"}` {
+		t.Errorf("Expected the merge patch applied but got %s", got)
+	}
+
+	got, _ = jsonpatch.Apply([]byte(`{"note": "a"}`), []byte(`{"note": "<b> & c"}`))
+	if string(got) != `{"note":"<b> & c"}` {
+		t.Errorf("Expected HTML characters left alone but got %s", got)
+	}
+
+	if _, err := jsonpatch.Apply([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Error("Expected an error for a bad document")
+	}
+	if _, err := jsonpatch.Apply([]byte(`{}`), []byte(`[{]`)); err == nil {
+		t.Error("Expected an error for a bad patch")
+	}
+}
@@ -0,0 +1,174 @@
+// Package jsonx adds to encoding/json what basics/json.go shows it lacks:
+// reading documents too large to unmarshal whole.
+package jsonx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+
+	"pacx/options"
+)
+
+type config struct {
+	path         []string
+	useNumber    bool
+	strictFields bool
+}
+
+// Option configures Array.
+type Option = options.Option[config]
+
+// WithPath finds the array under these object keys, as in
+// {"data": {"items": [...]}} for WithPath("data", "items"), instead of
+// expecting it at the top. The values skipped on the way are read one at
+// a time, so a large one before the array costs its size in memory once.
+func WithPath(keys ...string) Option {
+	return options.New("WithPath", func(c *config) error {
+		c.path = keys
+		return nil
+	})
+}
+
+// WithUseNumber decodes numbers into interface values as json.Number, see
+// json.Decoder.UseNumber.
+func WithUseNumber() Option {
+	return options.New("WithUseNumber", func(c *config) error {
+		c.useNumber = true
+		return nil
+	})
+}
+
+// WithDisallowUnknownFields fails on object keys that match no field of
+// T, see json.Decoder.DisallowUnknownFields.
+func WithDisallowUnknownFields() Option {
+	return options.New("WithDisallowUnknownFields", func(c *config) error {
+		c.strictFields = true
+		return nil
+	})
+}
+
+// Array yields the elements of the JSON array in r one at a time, decoded
+// into T, so memory stays bounded by the largest element rather than the
+// document. An error ends the iteration: it is yielded once, with the
+// zero T. What follows the array in r is not read.
+func Array[T any](r io.Reader, opts ...Option) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+
+		var zero T
+
+		cfg, err := options.Build(config{}, nil, opts...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		dec := json.NewDecoder(r)
+		if cfg.useNumber {
+			dec.UseNumber()
+		}
+		if cfg.strictFields {
+			dec.DisallowUnknownFields()
+		}
+
+		if err := descend(dec, cfg.path); err != nil {
+			yield(zero, err)
+			return
+		}
+		if err := expect(dec, '[', "an array"); err != nil {
+			yield(zero, err)
+			return
+		}
+		for i := 0; dec.More(); i++ {
+			var v T
+			if err := dec.Decode(&v); err != nil {
+				yield(zero, fmt.Errorf("jsonx: element %d: %w", i, err))
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		// the closing bracket, which also catches a truncated array
+		if _, err := dec.Token(); err != nil {
+			yield(zero, unexpectedEOF(err))
+		}
+	}
+}
+
+// descend reads up to the value of the last key in path.
+func descend(dec *json.Decoder, path []string) error {
+
+	for depth, key := range path {
+		if err := expect(dec, '{', fmt.Sprintf("an object around %q", key)); err != nil {
+			return err
+		}
+		for {
+			if !dec.More() {
+				return fmt.Errorf("jsonx: no key %q at %v", key, path[:depth])
+			}
+			tok, err := dec.Token()
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if tok == key {
+				break
+			}
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return unexpectedEOF(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// expect reads a delimiter token, failing with what was wanted instead.
+func expect(dec *json.Decoder, delim json.Delim, want string) error {
+
+	tok, err := dec.Token()
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+	if tok != delim {
+		return fmt.Errorf("jsonx: want %s, got %v", want, describe(tok))
+	}
+
+	return nil
+}
+
+func describe(tok json.Token) string {
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			return "an object"
+		}
+		if tok == '[' {
+			return "an array"
+		}
+		return fmt.Sprintf("%q", tok.String())
+	case string:
+		return fmt.Sprintf("string %q", tok)
+	case float64, json.Number:
+		return fmt.Sprintf("number %v", tok)
+	case nil:
+		return "null"
+	}
+
+	return fmt.Sprint(tok)
+}
+
+// unexpectedEOF turns the io.EOF of a document that ends too early into
+// io.ErrUnexpectedEOF, since the iteration had not finished.
+func unexpectedEOF(err error) error {
+
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("jsonx: %w", io.ErrUnexpectedEOF)
+	}
+
+	return err
+}
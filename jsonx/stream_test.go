@@ -0,0 +1,211 @@
+package jsonx_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"testing"
+
+	"pacx/jsonx"
+)
+
+type item struct {
+	ID   int      `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// collect ranges over seq and returns the values and the error it ended
+// with.
+func collect[T any](seq func(func(T, error) bool)) ([]T, error) {
+
+	var out []T
+	for v, err := range seq {
+		if err != nil {
+			return out, err
+		}
+		out = append(out, v)
+	}
+
+	return out, nil
+}
+
+func TestArray(t *testing.T) {
+
+	in := ` [ {"id": 1, "name": "a"}, {"id": 2, "tags": ["x"]} ,{"id":3} ] trailing garbage`
+
+	got, err := collect(jsonx.Array[item](strings.NewReader(in)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Name != "a" || got[1].Tags[0] != "x" || got[2].ID != 3 {
+		t.Errorf("Expected the 3 items but got %+v", got)
+	}
+
+	if got, err := collect(jsonx.Array[int](strings.NewReader(`[]`))); err != nil || len(got) != 0 {
+		t.Errorf("Expected nothing for an empty array but got %v, %v", got, err)
+	}
+}
+
+func TestArrayWithPath(t *testing.T) {
+
+	in := `{"meta": {"skip": [1, 2, {"deep": true}]}, "data": {"count": 2, "items": [10, 20]}, "after": 1}`
+
+	got, err := collect(jsonx.Array[int](strings.NewReader(in), jsonx.WithPath("data", "items")))
+	if err != nil || len(got) != 2 || got[0] != 10 || got[1] != 20 {
+		t.Errorf("Expected [10 20] but got %v, %v", got, err)
+	}
+
+	if _, err := collect(jsonx.Array[int](strings.NewReader(in), jsonx.WithPath("data", "missing"))); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("Expected an error naming the missing key but got %v", err)
+	}
+	if _, err := collect(jsonx.Array[int](strings.NewReader(in), jsonx.WithPath("data", "count"))); err == nil || !strings.Contains(err.Error(), "number 2") {
+		t.Errorf("Expected an error for a number where the array should be but got %v", err)
+	}
+}
+
+func TestArrayErrors(t *testing.T) {
+
+	for _, tc := range []struct {
+		in   string
+		n    int // elements before the error
+		want string
+	}{
+		{`{"id": 1}`, 0, "want an array, got an object"},
+		{`"text"`, 0, `got string "text"`},
+		{`null`, 0, "got null"},
+		{``, 0, io.ErrUnexpectedEOF.Error()},
+		{`[{"id": 1}, {"id": 2}`, 2, "unexpected end"},
+		{`[{"id": 1}, {"id": "two"}]`, 1, "element 1"},
+		{`[{"id": 1} {"id": 2}]`, 1, "invalid character"},
+	} {
+		got, err := collect(jsonx.Array[item](strings.NewReader(tc.in)))
+		if err == nil || !strings.Contains(err.Error(), tc.want) || len(got) != tc.n {
+			t.Errorf("Expected %d items and an error with %q for %s but got %d, %v", tc.n, tc.want, tc.in, len(got), err)
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if _, err := collect(jsonx.Array[item](strings.NewReader(`[{"id": "x"}]`))); !errors.As(err, &typeErr) {
+		t.Errorf("Expected a *json.UnmarshalTypeError but got %v", err)
+	}
+	if _, err := collect(jsonx.Array[item](strings.NewReader(`[{"id": 1, "extra": 2}]`), jsonx.WithDisallowUnknownFields())); err == nil {
+		t.Error("Expected the unknown field to be refused but got no error")
+	}
+}
+
+func TestArrayUseNumber(t *testing.T) {
+
+	got, err := collect(jsonx.Array[any](strings.NewReader(`[12345678901234567890]`), jsonx.WithUseNumber()))
+	if err != nil || len(got) != 1 || got[0] != json.Number("12345678901234567890") {
+		t.Errorf("Expected the exact number but got %v, %v", got, err)
+	}
+}
+
+func TestArrayStopsEarly(t *testing.T) {
+
+	r := &items{n: 1 << 30} // far too large to read whole
+	for v, err := range jsonx.Array[item](r) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.ID == 9 {
+			break
+		}
+	}
+	if r.i > 100 {
+		t.Errorf("Expected to read about 10 items but %d were generated", r.i)
+	}
+}
+
+// items generates the array [{"id":0,...},{"id":1,...},...] of n items
+// as it is read, so a benchmark measures the decoder and not a document
+// held in memory.
+type items struct {
+	n, i int
+	buf  []byte
+}
+
+func (r *items) Read(p []byte) (int, error) {
+
+	for len(r.buf) < len(p) && r.i <= r.n {
+		switch {
+		case r.i == r.n:
+			r.buf = append(r.buf, ']')
+		default:
+			if r.i == 0 {
+				r.buf = append(r.buf, '[')
+			} else {
+				r.buf = append(r.buf, ',')
+			}
+			r.buf = append(r.buf, `{"id":`...)
+			r.buf = strconv.AppendInt(r.buf, int64(r.i), 10)
+			r.buf = append(r.buf, `,"name":"item number `...)
+			r.buf = strconv.AppendInt(r.buf, int64(r.i), 10)
+			r.buf = append(r.buf, `","tags":["alpha","beta"]}`...)
+		}
+		r.i++
+	}
+	if len(r.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+
+	return n, nil
+}
+
+// heapBytes is the memory in heap objects, live or not yet collected.
+func heapBytes() uint64 {
+
+	s := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(s)
+
+	return s[0].Value.Uint64()
+}
+
+// BenchmarkArray shows that streaming keeps memory bounded while
+// unmarshalling the whole array does not: peak-heap-MB stays flat for
+// how=stream as items grows a hundredfold.
+func BenchmarkArray(b *testing.B) {
+
+	for _, n := range []int{1_000, 100_000} {
+		b.Run(fmt.Sprintf("items=%d/how=stream", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for b.Loop() {
+				runtime.GC()
+				i := 0
+				for _, err := range jsonx.Array[item](&items{n: n}) {
+					if err != nil {
+						b.Fatal(err)
+					}
+					if i++; i%1000 == 0 {
+						peak = max(peak, heapBytes())
+					}
+				}
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+		b.Run(fmt.Sprintf("items=%d/how=unmarshal", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var peak uint64
+			for b.Loop() {
+				runtime.GC()
+				data, _ := io.ReadAll(&items{n: n})
+				var all []item
+				if err := json.Unmarshal(data, &all); err != nil {
+					b.Fatal(err)
+				}
+				peak = max(peak, heapBytes())
+				runtime.KeepAlive(all)
+			}
+			b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MB")
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package jsonx
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"pacx/validate"
+)
+
+// FieldError is one problem with one field. Path is in JSON terms, as in
+// items[2].name, and empty for the document itself.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is every problem UnmarshalStrict found, sorted by path.
+type Errors []*FieldError
+
+func (es Errors) Error() string {
+
+	var b strings.Builder
+	b.WriteString("jsonx: ")
+	for i, e := range es {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(e.Error())
+	}
+
+	return b.String()
+}
+
+func (es Errors) Unwrap() []error {
+
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+
+	return errs
+}
+
+// The rule violations a FieldError wraps. All but ErrUnknownField are
+// those of package validate.
+var (
+	ErrRequired     = validate.ErrRequired
+	ErrUnknownField = errors.New("unknown field")
+	ErrOutOfRange   = validate.ErrOutOfRange
+	ErrPattern      = validate.ErrPattern
+)
+
+// UnmarshalStrict is json.Unmarshal that also checks the document against
+// v's type. Keys that match no field are errors, and so are fields whose
+// validate tag breaks. The tags are those of package validate, except that
+// required means the key must be present and not null, as in
+//
+//	type Order struct {
+//		ID    int    `json:"id" validate:"required,min=1"`
+//		Email string `json:"email" validate:"regexp=^[^@]+@[^@]+$"`
+//	}
+//
+// Every problem is reported, not only the first, as an Errors. Nested
+// structs, and those in pointers, slices, arrays and maps, are checked
+// too. A malformed document or a malformed tag is a plain error.
+func UnmarshalStrict(data []byte, v any) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errors.New("jsonx: UnmarshalStrict needs a non-nil pointer")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+	// More misses a stray closing bracket; only EOF means nothing follows
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("jsonx: data after the top-level value")
+	}
+
+	var errs Errors
+	err := json.Unmarshal(data, v)
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		errs = append(errs, &FieldError{Path: typeErr.Field, Err: fmt.Errorf("cannot be %s", typeErr.Value)})
+	case err != nil:
+		return err
+	}
+
+	if err := check(tree, rv.Elem(), "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		slices.SortStableFunc(errs, func(a, b *FieldError) int { return strings.Compare(a.Path, b.Path) })
+		return errs
+	}
+
+	return nil
+}
+
+type structField struct {
+	index  []int // for FieldByIndex, through embedded structs
+	name   string
+	tagged bool // named by its json tag
+	rule   validate.Rule
+}
+
+var fieldCache sync.Map // reflect.Type to []structField or error
+
+// fieldsOf returns the JSON fields of struct type t, with embedded
+// structs flattened the way encoding/json does: of fields with the same
+// name the shallowest wins, then the one named by its tag; otherwise none
+// does. A struct embedded inside itself is walked only once.
+func fieldsOf(t reflect.Type) ([]structField, error) {
+
+	if c, ok := fieldCache.Load(t); ok {
+		if err, ok := c.(error); ok {
+			return nil, err
+		}
+		return c.([]structField), nil
+	}
+
+	var all []structField
+	inside := make(map[reflect.Type]bool)
+	var walk func(t reflect.Type, index []int) error
+	walk = func(t reflect.Type, index []int) error {
+		if inside[t] {
+			return nil
+		}
+		inside[t] = true
+		defer delete(inside, t)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			idx := append(index[:len(index):len(index)], i)
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				if err := walk(ft, idx); err != nil {
+					return err
+				}
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = f.Name
+			}
+			r, err := validate.ParseRule(f.Tag.Get("validate"))
+			if err != nil {
+				return fmt.Errorf("jsonx: field %s of %v: %w", f.Name, t, err)
+			}
+			all = append(all, structField{index: idx, name: name, tagged: tagged, rule: r})
+		}
+		return nil
+	}
+	if err := walk(t, nil); err != nil {
+		fieldCache.Store(t, err)
+		return nil, err
+	}
+
+	fields := make([]structField, 0, len(all))
+	for _, f := range all {
+		dominant := true
+		for _, g := range all {
+			if g.name != f.name || slices.Equal(g.index, f.index) {
+				continue
+			}
+			if len(g.index) < len(f.index) || len(g.index) == len(f.index) && (g.tagged || !f.tagged) {
+				dominant = false
+			}
+		}
+		if dominant {
+			fields = append(fields, f)
+		}
+	}
+	fieldCache.Store(t, fields)
+
+	return fields, nil
+}
+
+// check walks the decoded document alongside v, which it was decoded
+// into, and reports unknown and missing keys and the values that break
+// their rules. Keys that are absent or null are only checked for being
+// required. Values of the wrong shape were reported by json.Unmarshal.
+func check(tree any, v reflect.Value, path string, errs *Errors) error {
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		// a null document is missing every required field; a null further
+		// down was checked against the rule of its own key
+		obj, ok := tree.(map[string]any)
+		if !ok && (tree != nil || path != "") {
+			return nil
+		}
+		fields, err := fieldsOf(v.Type())
+		if err != nil {
+			return err
+		}
+		present := make(map[int]bool, len(obj))
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			// encoding/json matches keys without regard to case
+			i := findField(fields, key)
+			if i < 0 {
+				*errs = append(*errs, &FieldError{Path: join(path, key), Err: ErrUnknownField})
+				continue
+			}
+			if obj[key] == nil {
+				continue
+			}
+			present[i] = true
+			f := fields[i]
+			fv, err := v.FieldByIndexErr(f.index)
+			if err != nil {
+				continue // through a nil embedded pointer
+			}
+			p := join(path, f.name)
+			if err := f.rule.Check(fv); err != nil {
+				*errs = append(*errs, &FieldError{Path: p, Err: err})
+			}
+			if err := check(obj[key], fv, p, errs); err != nil {
+				return err
+			}
+		}
+		for i, f := range fields {
+			if f.rule.Required && !present[i] {
+				*errs = append(*errs, &FieldError{Path: join(path, f.name), Err: ErrRequired})
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		arr, _ := tree.([]any)
+		for i := range min(len(arr), v.Len()) {
+			if err := check(arr[i], v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		obj, _ := tree.(map[string]any)
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			ev := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			if !ev.IsValid() {
+				continue
+			}
+			if err := check(obj[key], ev, join(path, key), errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findField returns the field key decodes into, preferring an exact match
+// as encoding/json does, or -1.
+func findField(fields []structField, key string) int {
+
+	fold := -1
+	for i, f := range fields {
+		if f.name == key {
+			return i
+		}
+		if fold < 0 && strings.EqualFold(f.name, key) {
+			fold = i
+		}
+	}
+
+	return fold
+}
+
+func join(path, key string) string {
+
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
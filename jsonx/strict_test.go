@@ -0,0 +1,191 @@
+package jsonx_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"pacx/jsonx"
+)
+
+type Base struct {
+	Version int `json:"version" validate:"required,min=1"`
+}
+
+type line struct {
+	SKU string `json:"sku" validate:"required,regexp=^[A-Z]{3}-[0-9]+$"`
+	Qty int    `json:"qty" validate:"min=1,max=100"`
+}
+
+type order struct {
+	Base
+	ID       int               `json:"id" validate:"required"`
+	Email    string            `json:"email" validate:"regexp=^[^@,]+@[^@,]+$"`
+	Lines    []line            `json:"lines" validate:"min=1"`
+	Shipping *line             `json:"shipping"`
+	Labels   map[string]string `json:"labels" validate:"max=2"`
+	Note     string            `json:"-"`
+}
+
+func TestUnmarshalStrictValid(t *testing.T) {
+
+	in := `{"version": 2, "ID": 7, "email": "a@b.c", "lines": [{"sku": "ABC-1", "qty": 3}], "labels": {"x": "y"}}`
+
+	var o order
+	if err := jsonx.UnmarshalStrict([]byte(in), &o); err != nil {
+		t.Fatal(err)
+	}
+	if o.Version != 2 || o.ID != 7 || o.Lines[0].Qty != 3 {
+		t.Errorf("Expected the order to be decoded but got %+v", o)
+	}
+}
+
+func TestUnmarshalStrictErrors(t *testing.T) {
+
+	in := `{
+		"id": null,
+		"email": "nobody",
+		"lines": [{"sku": "ABC-1", "qty": 0}, {"qty": 500, "colour": "red"}],
+		"shipping": {"sku": "bad", "qty": 1},
+		"labels": {"a": "1", "b": "2", "c": "3"},
+		"extra": true
+	}`
+
+	var o order
+	err := jsonx.UnmarshalStrict([]byte(in), &o)
+
+	var errs jsonx.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected jsonx.Errors but got %v", err)
+	}
+	want := []struct {
+		path string
+		err  error
+	}{
+		{"email", jsonx.ErrPattern},
+		{"extra", jsonx.ErrUnknownField},
+		{"id", jsonx.ErrRequired},
+		{"labels", jsonx.ErrOutOfRange},
+		{"lines[0].qty", jsonx.ErrOutOfRange},
+		{"lines[1].colour", jsonx.ErrUnknownField},
+		{"lines[1].qty", jsonx.ErrOutOfRange},
+		{"lines[1].sku", jsonx.ErrRequired},
+		{"shipping.sku", jsonx.ErrPattern},
+		{"version", jsonx.ErrRequired},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors but got %d: %v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Path != w.path || !errors.Is(errs[i], w.err) {
+			t.Errorf("Expected %s to fail with %v but got %v", w.path, w.err, errs[i])
+		}
+	}
+	if !errors.Is(err, jsonx.ErrUnknownField) {
+		t.Error("Expected errors.Is to see through Errors but it did not")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "lines[1].qty: out of range: 500 is over the max 100") {
+		t.Errorf("Expected a readable message but got %s", msg)
+	}
+}
+
+func TestUnmarshalStrictTypeError(t *testing.T) {
+
+	var o order
+	err := jsonx.UnmarshalStrict([]byte(`{"version": 1, "id": "seven"}`), &o)
+
+	var errs jsonx.Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Path != "id" {
+		t.Errorf("Expected one error for id but got %v", err)
+	}
+}
+
+func TestUnmarshalStrictBadInput(t *testing.T) {
+
+	type badTag struct {
+		N int `validate:"min=one"`
+	}
+	type badRule struct {
+		N int `validate:"positive"`
+	}
+
+	var o order
+	for _, tc := range []struct {
+		in string
+		v  any
+	}{
+		{`{"version": `, &o},
+		{`{} {}`, &o},
+		{`{}]`, &o},
+		{`{}`, o},
+		{`{"N": 1}`, &badTag{}},
+		{`{"N": 1}`, &badRule{}},
+	} {
+		err := jsonx.UnmarshalStrict([]byte(tc.in), tc.v)
+		var errs jsonx.Errors
+		if err == nil || errors.As(err, &errs) {
+			t.Errorf("Expected a plain error for %s into %T but got %v", tc.in, tc.v, err)
+		}
+	}
+}
+
+func TestUnmarshalStrictEmbedded(t *testing.T) {
+
+	type self struct {
+		*self
+		X int `validate:"min=1"`
+	}
+	var s self
+	if err := jsonx.UnmarshalStrict([]byte(`{"X": 1}`), &s); err != nil || s.X != 1 {
+		t.Errorf("Expected a self-embedded struct to decode but got %+v, %v", s, err)
+	}
+
+	type deep struct {
+		Name string `json:"name" validate:"required,min=5"`
+	}
+	type tied struct {
+		ID int `json:"id"`
+	}
+	type outer struct {
+		deep
+		tied
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}
+	var o outer
+	if err := jsonx.UnmarshalStrict([]byte(`{"name": "ab", "id": 1}`), &o); err != nil {
+		t.Errorf("Expected the shallower fields to win over the embedded rules but got %v", err)
+	}
+	if o.Name != "ab" || o.ID != 1 || o.deep.Name != "" {
+		t.Errorf("Expected the shallower fields set but got %+v", o)
+	}
+
+	type left struct{ Dup int }
+	type right struct{ Dup int }
+	type tie struct {
+		left
+		right
+	}
+	err := jsonx.UnmarshalStrict([]byte(`{"Dup": 1}`), &tie{})
+	if !errors.Is(err, jsonx.ErrUnknownField) {
+		t.Errorf("Expected an equally deep name to be dropped but got %v", err)
+	}
+}
+
+func TestUnmarshalStrictNull(t *testing.T) {
+
+	var o order
+	err := jsonx.UnmarshalStrict([]byte("null"), &o)
+	var errs jsonx.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Path != "id" || errs[1].Path != "version" ||
+		!errors.Is(errs[0], jsonx.ErrRequired) || !errors.Is(errs[1], jsonx.ErrRequired) {
+		t.Errorf("Expected id and version required but got %v", err)
+	}
+
+	var opt struct {
+		Note string `json:"note"`
+	}
+	if err := jsonx.UnmarshalStrict([]byte("null"), &opt); err != nil {
+		t.Errorf("Expected null to be fine without required fields but got %v", err)
+	}
+}
@@ -0,0 +1,422 @@
+// Package antipattern flags the concurrency pitfalls that show up across the
+// demos in this repo: an if around cond.Wait, wg.Add inside the goroutine it
+// counts, loop variables captured by goroutines before Go 1.22, time.Sleep
+// used to wait for goroutines and unguarded struct writes from goroutines.
+package antipattern
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"go/version"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `report concurrency anti-patterns
+
+The checks are:
+  - sync.Cond.Wait guarded by an if instead of a for loop (autofix: if -> for)
+  - sync.WaitGroup.Add called inside the goroutine it is counting
+  - loop variables captured by go/defer closures in files older than go1.22
+    (autofix: shadow the variable at the top of the loop body)
+  - time.Sleep used after go statements as a way to wait for them
+  - struct fields of captured variables written from a goroutine without
+    holding a lock`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "antipattern",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	nodes := []ast.Node{
+		(*ast.IfStmt)(nil),
+		(*ast.GoStmt)(nil),
+		(*ast.BlockStmt)(nil),
+		(*ast.ForStmt)(nil),
+		(*ast.RangeStmt)(nil),
+	}
+
+	insp.Preorder(nodes, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.IfStmt:
+			checkCondWait(pass, n)
+		case *ast.GoStmt:
+			checkWaitGroupAdd(pass, n)
+			checkSharedWrites(pass, n)
+		case *ast.BlockStmt:
+			checkSleepSync(pass, n)
+		case *ast.ForStmt:
+			checkLoopCapture(pass, n, n.Body, loopVars(n.Init))
+		case *ast.RangeStmt:
+			checkLoopCapture(pass, n, n.Body, rangeVars(n))
+		}
+	})
+
+	return nil, nil
+}
+
+// checkCondWait reports `if cond { c.Wait() }`. Wait can return without the
+// condition being true (spurious or stolen wakeups), so it must sit in a loop.
+func checkCondWait(pass *analysis.Pass, stmt *ast.IfStmt) {
+
+	var wait *ast.CallExpr
+	for _, s := range stmt.Body.List {
+		if call := exprCall(s); call != nil && isMethod(pass, call, "sync", "Cond", "Wait") {
+			wait = call
+			break
+		}
+	}
+	if wait == nil {
+		return
+	}
+
+	diag := analysis.Diagnostic{
+		Pos:     stmt.Pos(),
+		End:     stmt.Body.Lbrace,
+		Message: "sync.Cond.Wait guarded by if; re-check the condition in a for loop",
+	}
+
+	// Swapping the keyword is only safe when there is nothing that a for
+	// statement can't express: no init statement and no else branch.
+	if stmt.Init == nil && stmt.Else == nil {
+		diag.SuggestedFixes = []analysis.SuggestedFix{{
+			Message: "Replace if with for",
+			TextEdits: []analysis.TextEdit{{
+				Pos:     stmt.If,
+				End:     stmt.If + token.Pos(len("if")),
+				NewText: []byte("for"),
+			}},
+		}}
+	}
+
+	pass.Report(diag)
+}
+
+// checkWaitGroupAdd reports wg.Add inside the goroutine literal. The parent
+// may reach wg.Wait before the goroutine gets scheduled and runs Add.
+func checkWaitGroupAdd(pass *analysis.Pass, stmt *ast.GoStmt) {
+
+	lit, ok := stmt.Call.Fun.(*ast.FuncLit)
+	if !ok {
+		return
+	}
+
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false
+		}
+		call, ok := n.(*ast.CallExpr)
+		if ok && isMethod(pass, call, "sync", "WaitGroup", "Add") {
+			pass.Reportf(call.Pos(), "sync.WaitGroup.Add called inside the goroutine; call Add before the go statement")
+		}
+		return true
+	})
+}
+
+// checkSleepSync reports a time.Sleep that follows go statements in the same
+// block with nothing else in between that could be waiting on them.
+func checkSleepSync(pass *analysis.Pass, block *ast.BlockStmt) {
+
+	launched := false
+
+	for _, s := range block.List {
+		switch s := s.(type) {
+		case *ast.GoStmt:
+			launched = true
+			continue
+		case *ast.ExprStmt:
+			if call := exprCall(s); call != nil && launched && isFunc(pass, call, "time", "Sleep") {
+				pass.Reportf(call.Pos(), "time.Sleep used to wait for goroutines; use sync.WaitGroup or a channel")
+				return
+			}
+		}
+
+		if launched && blocksOnSync(pass, s) {
+			return
+		}
+	}
+}
+
+// blocksOnSync reports whether s contains something that could be the real
+// synchronisation point: a channel receive, a select or a Wait call.
+func blocksOnSync(pass *analysis.Pass, s ast.Stmt) bool {
+
+	found := false
+	ast.Inspect(s, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.UnaryExpr:
+			found = found || n.Op == token.ARROW
+		case *ast.SelectStmt:
+			found = true
+		case *ast.RangeStmt:
+			if t := pass.TypesInfo.TypeOf(n.X); t != nil {
+				if _, ok := t.Underlying().(*types.Chan); ok {
+					found = true
+				}
+			}
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "Wait" {
+				found = true
+			}
+		}
+		return !found
+	})
+
+	return found
+}
+
+// checkLoopCapture reports loop variables referenced from go/defer closures
+// in files whose language version still shares one variable per loop.
+func checkLoopCapture(pass *analysis.Pass, loop ast.Node, body *ast.BlockStmt, vars []*ast.Ident) {
+
+	if len(vars) == 0 || !sharedLoopVars(pass, loop) {
+		return
+	}
+
+	objs := make(map[types.Object]*ast.Ident, len(vars))
+	for _, id := range vars {
+		if obj := pass.TypesInfo.Defs[id]; obj != nil {
+			objs[obj] = id
+		}
+	}
+
+	reported := make(map[types.Object]bool)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		var call *ast.CallExpr
+		switch n := n.(type) {
+		case *ast.GoStmt:
+			call = n.Call
+		case *ast.DeferStmt:
+			call = n.Call
+		default:
+			return true
+		}
+
+		lit, ok := call.Fun.(*ast.FuncLit)
+		if !ok {
+			return true
+		}
+
+		ast.Inspect(lit.Body, func(n ast.Node) bool {
+			id, ok := n.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			obj := pass.TypesInfo.Uses[id]
+			def, captured := objs[obj]
+			if !captured || reported[obj] {
+				return true
+			}
+			reported[obj] = true
+
+			shadow := def.Name + " := " + def.Name
+			pass.Report(analysis.Diagnostic{
+				Pos:     id.Pos(),
+				End:     id.End(),
+				Message: "loop variable " + def.Name + " captured by func literal",
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: "Shadow " + def.Name + " inside the loop body",
+					TextEdits: []analysis.TextEdit{{
+						Pos:     body.Lbrace + 1,
+						End:     body.Lbrace + 1,
+						NewText: []byte("\n" + shadow),
+					}},
+				}},
+			})
+			return true
+		})
+
+		return false
+	})
+}
+
+// sharedLoopVars reports whether the file holding n predates go1.22, where a
+// single variable is reused across all iterations.
+func sharedLoopVars(pass *analysis.Pass, n ast.Node) bool {
+
+	for _, f := range pass.Files {
+		if f.FileStart <= n.Pos() && n.Pos() <= f.FileEnd {
+			v := pass.TypesInfo.FileVersions[f]
+			return v != "" && version.Compare(v, "go1.22") < 0
+		}
+	}
+
+	return false
+}
+
+func loopVars(init ast.Stmt) []*ast.Ident {
+
+	assign, ok := init.(*ast.AssignStmt)
+	if !ok || assign.Tok != token.DEFINE {
+		return nil
+	}
+
+	var ids []*ast.Ident
+	for _, lhs := range assign.Lhs {
+		if id, ok := lhs.(*ast.Ident); ok && id.Name != "_" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func rangeVars(stmt *ast.RangeStmt) []*ast.Ident {
+
+	if stmt.Tok != token.DEFINE {
+		return nil
+	}
+
+	var ids []*ast.Ident
+	for _, e := range []ast.Expr{stmt.Key, stmt.Value} {
+		if id, ok := e.(*ast.Ident); ok && id.Name != "_" {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// checkSharedWrites reports assignments to fields of variables that were
+// captured from outside a goroutine literal when the literal never locks.
+func checkSharedWrites(pass *analysis.Pass, stmt *ast.GoStmt) {
+
+	lit, ok := stmt.Call.Fun.(*ast.FuncLit)
+	if !ok || locks(pass, lit.Body) {
+		return
+	}
+
+	ast.Inspect(lit.Body, func(n ast.Node) bool {
+		var lhs []ast.Expr
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			if n.Tok == token.DEFINE {
+				return true
+			}
+			lhs = n.Lhs
+		case *ast.IncDecStmt:
+			lhs = []ast.Expr{n.X}
+		default:
+			return true
+		}
+
+		for _, e := range lhs {
+			sel, ok := e.(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			root := rootIdent(sel)
+			if root == nil {
+				continue
+			}
+			obj, ok := pass.TypesInfo.Uses[root].(*types.Var)
+			if !ok || declaredWithin(obj, lit) {
+				continue
+			}
+			pass.Reportf(sel.Pos(), "write to %s.%s from a goroutine without holding a lock", root.Name, sel.Sel.Name)
+		}
+
+		return true
+	})
+}
+
+func locks(pass *analysis.Pass, body *ast.BlockStmt) bool {
+
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if ok && (isMethod(pass, call, "sync", "Mutex", "Lock") || isMethod(pass, call, "sync", "RWMutex", "Lock")) {
+			found = true
+		}
+		return !found
+	})
+
+	return found
+}
+
+func rootIdent(e ast.Expr) *ast.Ident {
+
+	for {
+		switch x := e.(type) {
+		case *ast.Ident:
+			return x
+		case *ast.SelectorExpr:
+			e = x.X
+		case *ast.IndexExpr:
+			e = x.X
+		case *ast.StarExpr:
+			e = x.X
+		case *ast.ParenExpr:
+			e = x.X
+		default:
+			return nil
+		}
+	}
+}
+
+func declaredWithin(obj types.Object, lit *ast.FuncLit) bool {
+	return lit.Pos() <= obj.Pos() && obj.Pos() < lit.End()
+}
+
+func exprCall(s ast.Stmt) *ast.CallExpr {
+
+	es, ok := s.(*ast.ExprStmt)
+	if !ok {
+		return nil
+	}
+	call, _ := es.X.(*ast.CallExpr)
+
+	return call
+}
+
+// isMethod reports whether call invokes pkg.Type.name on a value or pointer.
+func isMethod(pass *analysis.Pass, call *ast.CallExpr, pkg, typ, name string) bool {
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok {
+		return false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return false
+	}
+
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == pkg && named.Obj().Name() == typ
+}
+
+// isFunc reports whether call invokes the package level function pkg.name.
+func isFunc(pass *analysis.Pass, call *ast.CallExpr, pkg, name string) bool {
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == pkg && fn.Name() == name
+}
@@ -0,0 +1,13 @@
+package antipattern_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"pacx/lint/antipattern"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), antipattern.Analyzer, "a", "legacy")
+}
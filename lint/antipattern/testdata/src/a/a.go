@@ -0,0 +1,93 @@
+package a
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	queue []int
+	mu    sync.Mutex
+	cond  = sync.NewCond(&mu)
+)
+
+func consumer() {
+	mu.Lock()
+	if len(queue) == 0 { // want "sync.Cond.Wait guarded by if"
+		cond.Wait()
+	}
+	queue = queue[1:]
+	mu.Unlock()
+}
+
+func consumerLoop() {
+	mu.Lock()
+	for len(queue) == 0 {
+		cond.Wait()
+	}
+	queue = queue[1:]
+	mu.Unlock()
+}
+
+func addInside() {
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		go func() {
+			wg.Add(1) // want "sync.WaitGroup.Add called inside the goroutine"
+			defer wg.Done()
+		}()
+	}
+	wg.Wait()
+}
+
+func sleepSync() {
+	go func() {}()
+	go func() {}()
+	time.Sleep(time.Second) // want "time.Sleep used to wait for goroutines"
+}
+
+func sleepAfterWait() {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+	}()
+	wg.Wait()
+	time.Sleep(time.Second)
+}
+
+func sleepAfterReceive(ch chan int, xs []int) {
+	go func() { ch <- 1 }()
+	if len(xs) > 0 {
+		<-ch
+		for range xs {
+		}
+	}
+	time.Sleep(time.Second)
+}
+
+type Order struct {
+	ID     int
+	Status string
+}
+
+func unguarded(o *Order) {
+	go func() {
+		o.Status = "Shipped" // want "write to o.Status from a goroutine without holding a lock"
+	}()
+}
+
+func guarded(o *Order) {
+	go func() {
+		mu.Lock()
+		o.Status = "Shipped"
+		mu.Unlock()
+	}()
+}
+
+func local() {
+	go func() {
+		o := &Order{}
+		o.Status = "pending"
+	}()
+}
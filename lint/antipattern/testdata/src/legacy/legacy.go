@@ -0,0 +1,15 @@
+//go:build go1.21
+
+package legacy
+
+import "fmt"
+
+func printers() []func() {
+	var fns []func()
+	for _, v := range []int{1, 2, 3} {
+		defer func() {
+			fmt.Println(v) // want "loop variable v captured by func literal"
+		}()
+	}
+	return fns
+}
@@ -0,0 +1,18 @@
+// Command gomasterlint runs the repo's own analyzers.
+//
+//	go run pacx/lint/cmd/gomasterlint ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/multichecker"
+
+	"pacx/lint/antipattern"
+	"pacx/lint/ctxprop"
+)
+
+func main() {
+	multichecker.Main(
+		antipattern.Analyzer,
+		ctxprop.Analyzer,
+	)
+}
@@ -0,0 +1,240 @@
+// Package ctxprop flags exported functions that can block without giving the
+// caller a way to cancel them through a context.Context.
+package ctxprop
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const doc = `report exported functions that block without accepting a context
+
+A function is considered blocking when its own body (not the goroutines it
+starts) sends or receives on a channel, ranges over a channel, selects
+without a default case, sleeps, waits on a WaitGroup or Cond, or does
+network or stream IO. Such functions should take a context.Context so the
+caller can cancel them.
+
+Package main, test files, methods named Close or Stop (which follow the
+io.Closer shutdown convention) and methods named Read, Write, ReadFrom or
+WriteTo (which implement the io interfaces and so cannot take a context)
+are not checked. Writes to a hash.Hash do not block.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "ctxprop",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+
+	if pass.Pkg.Name() == "main" {
+		return nil, nil
+	}
+
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	insp.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		fn := n.(*ast.FuncDecl)
+
+		if fn.Body == nil || !fn.Name.IsExported() || exempt(fn) {
+			return
+		}
+		if strings.HasSuffix(pass.Fset.File(fn.Pos()).Name(), "_test.go") {
+			return
+		}
+		if fn.Recv != nil && !exportedRecv(fn.Recv) {
+			return
+		}
+		if takesContext(pass, fn) {
+			return
+		}
+
+		if what := blocking(pass, fn.Body); what != "" {
+			pass.Reportf(fn.Name.Pos(), "exported function %s blocks (%s) but does not accept a context.Context", fn.Name.Name, what)
+		}
+	})
+
+	return nil, nil
+}
+
+// exemptMethods have signatures fixed by the interfaces they implement.
+var exemptMethods = map[string]bool{
+	"Close": true, "Stop": true,
+	"Read": true, "Write": true, "ReadFrom": true, "WriteTo": true,
+}
+
+func exempt(fn *ast.FuncDecl) bool {
+	return fn.Recv != nil && exemptMethods[fn.Name.Name]
+}
+
+func exportedRecv(recv *ast.FieldList) bool {
+
+	t := recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.IsExported()
+		default:
+			return false
+		}
+	}
+}
+
+func takesContext(pass *analysis.Pass, fn *ast.FuncDecl) bool {
+
+	for _, field := range fn.Type.Params.List {
+		if isContext(pass.TypesInfo.TypeOf(field.Type)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isContext(t types.Type) bool {
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+
+	return obj.Pkg() != nil && obj.Pkg().Path() == "context" && obj.Name() == "Context"
+}
+
+// blocking returns a short description of the first blocking operation in
+// body, or "" if there is none. Function literals are skipped: they either
+// run on another goroutine or are the caller's business.
+func blocking(pass *analysis.Pass, body *ast.BlockStmt) string {
+
+	var what string
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		if what != "" {
+			return false
+		}
+
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.SendStmt:
+			what = "channel send"
+		case *ast.UnaryExpr:
+			if n.Op.String() == "<-" {
+				what = "channel receive"
+			}
+		case *ast.RangeStmt:
+			if t := pass.TypesInfo.TypeOf(n.X); t != nil {
+				if _, ok := t.Underlying().(*types.Chan); ok {
+					what = "range over channel"
+				}
+			}
+		case *ast.SelectStmt:
+			if !hasDefault(n) {
+				what = "select"
+			}
+			// the cases themselves are covered by the select
+			return false
+		case *ast.CallExpr:
+			what = blockingCall(pass, n)
+		}
+
+		return true
+	})
+
+	return what
+}
+
+func hasDefault(s *ast.SelectStmt) bool {
+
+	for _, c := range s.Body.List {
+		if c.(*ast.CommClause).Comm == nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ioMethods are the stream and connection methods treated as blocking when
+// called on a value from one of the ioPackages.
+var ioMethods = map[string]bool{
+	"Read": true, "Write": true, "ReadFrom": true, "WriteTo": true,
+	"ReadString": true, "ReadBytes": true, "ReadLine": true, "Accept": true,
+}
+
+var ioPackages = map[string]bool{
+	"io": true, "os": true, "bufio": true, "net": true, "net/http": true,
+}
+
+func blockingCall(pass *analysis.Pass, call *ast.CallExpr) string {
+
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil {
+		return ""
+	}
+
+	pkg := fn.Pkg().Path()
+	recv := fn.Type().(*types.Signature).Recv()
+
+	switch {
+	case recv == nil && pkg == "time" && fn.Name() == "Sleep":
+		return "time.Sleep"
+	case recv == nil && (pkg == "net" || pkg == "net/http") && isNetEntry(fn.Name()):
+		return pkg + "." + fn.Name()
+	case recv != nil && pkg == "sync" && fn.Name() == "Wait":
+		return "sync " + recvName(recv) + ".Wait"
+	case recv != nil && ioPackages[pkg] && ioMethods[fn.Name()] && !isHash(pass.TypesInfo.TypeOf(sel.X)):
+		return "IO " + fn.Name()
+	}
+
+	return ""
+}
+
+// isHash reports whether t is one of the hash package's interfaces, whose
+// Write comes from io.Writer but only feeds the hash.
+func isHash(t types.Type) bool {
+
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+
+	return obj.Pkg() != nil && obj.Pkg().Path() == "hash"
+}
+
+func isNetEntry(name string) bool {
+	return strings.HasPrefix(name, "Dial") || strings.HasPrefix(name, "Listen") ||
+		name == "Get" || name == "Post" || name == "Head" || name == "PostForm"
+}
+
+func recvName(recv *types.Var) string {
+
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj().Name()
+	}
+
+	return t.String()
+}
@@ -0,0 +1,110 @@
+package a
+
+import (
+	"context"
+	"hash"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+func Send(ch chan<- int, v int) { // want "exported function Send blocks \\(channel send\\)"
+	ch <- v
+}
+
+func SendContext(ctx context.Context, ch chan<- int, v int) error {
+	select {
+	case ch <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TrySend(ch chan<- int, v int) bool {
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func Drain(ch <-chan int) (n int) { // want "range over channel"
+	for range ch {
+		n++
+	}
+	return n
+}
+
+func Nap() { // want "time.Sleep"
+	time.Sleep(time.Second)
+}
+
+func Background(ch chan<- int) {
+	go func() {
+		ch <- 1
+	}()
+}
+
+func WaitAll(wg *sync.WaitGroup) { // want "sync WaitGroup.Wait"
+	wg.Wait()
+}
+
+func Copy(dst io.Writer, src io.Reader) { // want "IO Read"
+	buf := make([]byte, 512)
+	n, _ := src.Read(buf)
+	dst.Write(buf[:n])
+}
+
+func Connect(addr string) (net.Conn, error) { // want "net.Dial"
+	return net.Dial("tcp", addr)
+}
+
+func helper(ch chan int) {
+	ch <- 1
+}
+
+type Queue struct {
+	ch chan int
+}
+
+func (q *Queue) Pop() int { // want "exported function Pop blocks"
+	return <-q.ch
+}
+
+func (q *Queue) Close() {
+	for range q.ch {
+	}
+}
+
+type queue struct {
+	ch chan int
+}
+
+func (q *queue) Pop() int {
+	return <-q.ch
+}
+
+func Checksum(h hash.Hash64, b []byte) uint64 {
+	h.Write(b)
+	return h.Sum64()
+}
+
+// Logger implements io.Writer, which has no room for a context.
+type Logger struct {
+	w io.Writer
+}
+
+func (l *Logger) Write(p []byte) (int, error) {
+	return l.w.Write(p)
+}
+
+func (l *Logger) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(l.w, r)
+}
+
+func (l *Logger) Flush(p []byte) { // want "IO Write"
+	l.w.Write(p)
+}
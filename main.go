@@ -38,7 +38,7 @@ func main() {
 	// result := mm.Add(2, 3)
 	// fmt.Println(result)
 	//
-	check := mazamamaths.Add2(4, 2)
+	check := mazamamaths.Sub(4, 2)
 	fmt.Println(check)
 
 	//mm.PublicFunction()
@@ -75,6 +75,7 @@ func main() {
 		},
 	}
 
+	// deepcopy.Copy(m1) in reflect/deepcopy copies the inner maps as well.
 	m1_copy := maps.Clone(m1)
 
 	m1_copy["M1"]["Key1"] = 10000   // this will side effect
@@ -0,0 +1,101 @@
+package mazamamaths
+
+import "errors"
+
+// ErrOverflow is returned by Checked for a result that does not fit.
+var ErrOverflow = errors.New("mazamamaths: integer overflow")
+
+// Checked turns the (value, ok) of the checked functions into an error,
+// so they can be used where one is returned:
+//
+//	total, err := mazamamaths.Checked(mazamamaths.AddChecked(a, b))
+func Checked[T Integer](v T, ok bool) (T, error) {
+	if !ok {
+		return v, ErrOverflow
+	}
+	return v, nil
+}
+
+// signed reports whether T is a signed integer type.
+func signed[T Integer]() bool {
+	var zero T
+	return zero-1 < zero
+}
+
+// AddChecked returns x + y and whether it fit in T. If it did not, the
+// value is the wrapped-around sum, as + gives.
+func AddChecked[T Integer](x, y T) (T, bool) {
+
+	s := x + y
+
+	// adding a non-negative y can only go up, a negative one only down
+	return s, (s >= x) == (y >= 0)
+}
+
+// SubChecked returns x - y and whether it fit in T.
+func SubChecked[T Integer](x, y T) (T, bool) {
+
+	d := x - y
+
+	return d, (d <= x) == (y >= 0)
+}
+
+// MulChecked returns x * y and whether it fit in T.
+func MulChecked[T Integer](x, y T) (T, bool) {
+
+	if x == 0 || y == 0 {
+		return 0, true
+	}
+	p := x * y
+
+	// the division check misses -1 * min, as min / -1 wraps back to min
+	var one T = 1
+	if signed[T]() && y == 0-one {
+		return p, p != x
+	}
+
+	return p, p/y == x
+}
+
+// DivChecked returns x / y and whether it is defined and fits in T: y
+// must not be zero, and for a signed T the smallest value divided by -1
+// overflows.
+func DivChecked[T Integer](x, y T) (T, bool) {
+
+	if y == 0 {
+		return 0, false
+	}
+	var one T = 1
+	if signed[T]() && y == 0-one {
+		return NegChecked(x)
+	}
+
+	return x / y, true
+}
+
+// NegChecked returns -x and whether it fit in T. Only zero can be negated
+// in an unsigned type.
+func NegChecked[T Integer](x T) (T, bool) {
+
+	n := 0 - x
+	if !signed[T]() {
+		return n, x == 0
+	}
+
+	// only the smallest value is its own negation besides zero
+	return n, x == 0 || n != x
+}
+
+// SumChecked returns the sum of xs and whether it fit in T along the way.
+func SumChecked[T Integer](xs ...T) (T, bool) {
+
+	var total T
+	for _, x := range xs {
+		var ok bool
+		if total, ok = AddChecked(total, x); !ok {
+			return total, false
+		}
+	}
+
+	return total, true
+}
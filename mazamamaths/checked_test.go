@@ -0,0 +1,185 @@
+package mazamamaths_test
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	"pacx/mazamamaths"
+)
+
+// exhaustive checks every pair of a small type against the same
+// arithmetic in int, which cannot overflow at that size.
+func exhaustive[T int8 | uint8](t *testing.T, lo, hi int) {
+
+	fits := func(v int) bool { return v >= lo && v <= hi }
+
+	for a := lo; a <= hi; a++ {
+		for b := lo; b <= hi; b++ {
+			x, y := T(a), T(b)
+
+			if v, ok := mazamamaths.AddChecked(x, y); ok != fits(a+b) || v != T(a+b) {
+				t.Fatalf("Expected AddChecked(%d, %d) to be %d, %v but got %d, %v", a, b, T(a+b), fits(a+b), v, ok)
+			}
+			if v, ok := mazamamaths.SubChecked(x, y); ok != fits(a-b) || v != T(a-b) {
+				t.Fatalf("Expected SubChecked(%d, %d) to be %d, %v but got %d, %v", a, b, T(a-b), fits(a-b), v, ok)
+			}
+			if v, ok := mazamamaths.MulChecked(x, y); ok != fits(a*b) || v != T(a*b) {
+				t.Fatalf("Expected MulChecked(%d, %d) to be %d, %v but got %d, %v", a, b, T(a*b), fits(a*b), v, ok)
+			}
+			if b == 0 {
+				if _, ok := mazamamaths.DivChecked(x, y); ok {
+					t.Fatalf("Expected DivChecked(%d, 0) to fail but it did not", a)
+				}
+			} else if v, ok := mazamamaths.DivChecked(x, y); ok != fits(a/b) || (ok && v != T(a/b)) {
+				t.Fatalf("Expected DivChecked(%d, %d) to be %d, %v but got %d, %v", a, b, T(a/b), fits(a/b), v, ok)
+			}
+		}
+		if v, ok := mazamamaths.NegChecked(T(a)); ok != fits(-a) || v != T(-a) {
+			t.Fatalf("Expected NegChecked(%d) to be %d, %v but got %d, %v", a, T(-a), fits(-a), v, ok)
+		}
+	}
+}
+
+func TestCheckedExhaustive(t *testing.T) {
+	exhaustive[int8](t, math.MinInt8, math.MaxInt8)
+	exhaustive[uint8](t, 0, math.MaxUint8)
+}
+
+// oracle is what big says about x op y in a type of the given range.
+func oracle(x, y *big.Int, op string, lo, hi *big.Int) (*big.Int, bool) {
+
+	r := new(big.Int)
+	switch op {
+	case "+":
+		r.Add(x, y)
+	case "-":
+		r.Sub(x, y)
+	case "*":
+		r.Mul(x, y)
+	case "/":
+		if y.Sign() == 0 {
+			return r, false
+		}
+		r.Quo(x, y) // truncates like Go's /
+	}
+
+	return r, r.Cmp(lo) >= 0 && r.Cmp(hi) <= 0
+}
+
+var (
+	minInt64  = big.NewInt(math.MinInt64)
+	maxInt64  = big.NewInt(math.MaxInt64)
+	maxUint64 = new(big.Int).SetUint64(math.MaxUint64)
+)
+
+func checkInt64(t *testing.T, x, y int64) {
+
+	t.Helper()
+
+	bx, by := big.NewInt(x), big.NewInt(y)
+	for _, c := range []struct {
+		op string
+		fn func(int64, int64) (int64, bool)
+	}{
+		{"+", mazamamaths.AddChecked[int64]},
+		{"-", mazamamaths.SubChecked[int64]},
+		{"*", mazamamaths.MulChecked[int64]},
+		{"/", mazamamaths.DivChecked[int64]},
+	} {
+		want, fits := oracle(bx, by, c.op, minInt64, maxInt64)
+		got, ok := c.fn(x, y)
+		if ok != fits || (ok && got != want.Int64()) {
+			t.Errorf("Expected %d %s %d to be %s (fits: %v) but got %d, %v", x, c.op, y, want, fits, got, ok)
+		}
+	}
+}
+
+func checkUint64(t *testing.T, x, y uint64) {
+
+	t.Helper()
+
+	bx, by := new(big.Int).SetUint64(x), new(big.Int).SetUint64(y)
+	for _, c := range []struct {
+		op string
+		fn func(uint64, uint64) (uint64, bool)
+	}{
+		{"+", mazamamaths.AddChecked[uint64]},
+		{"-", mazamamaths.SubChecked[uint64]},
+		{"*", mazamamaths.MulChecked[uint64]},
+		{"/", mazamamaths.DivChecked[uint64]},
+	} {
+		want, fits := oracle(bx, by, c.op, new(big.Int), maxUint64)
+		got, ok := c.fn(x, y)
+		if ok != fits || (ok && got != want.Uint64()) {
+			t.Errorf("Expected %d %s %d to be %s (fits: %v) but got %d, %v", x, c.op, y, want, fits, got, ok)
+		}
+	}
+}
+
+func TestCheckedBoundaries(t *testing.T) {
+
+	signed := []int64{math.MinInt64, math.MinInt64 + 1, math.MinInt32, -2, -1, 0, 1, 2, math.MaxInt32, 1 << 32, math.MaxInt64 - 1, math.MaxInt64}
+	for _, x := range signed {
+		for _, y := range signed {
+			checkInt64(t, x, y)
+		}
+	}
+
+	unsigned := []uint64{0, 1, 2, math.MaxUint32, 1 << 32, math.MaxInt64, math.MaxUint64 - 1, math.MaxUint64}
+	for _, x := range unsigned {
+		for _, y := range unsigned {
+			checkUint64(t, x, y)
+		}
+	}
+
+	// int is int64 or int32 wide; its limits behave the same either way
+	if _, ok := mazamamaths.AddChecked(math.MaxInt, 1); ok {
+		t.Error("Expected MaxInt + 1 to overflow but it did not")
+	}
+	if _, ok := mazamamaths.MulChecked(math.MinInt, -1); ok {
+		t.Error("Expected MinInt * -1 to overflow but it did not")
+	}
+	if v, ok := mazamamaths.SubChecked(math.MinInt+1, 1); !ok || v != math.MinInt {
+		t.Errorf("Expected MinInt+1 - 1 to be MinInt but got %d, %v", v, ok)
+	}
+}
+
+func TestSumChecked(t *testing.T) {
+
+	if v, ok := mazamamaths.SumChecked(1, 2, 3); !ok || v != 6 {
+		t.Errorf("Expected 6 but got %d, %v", v, ok)
+	}
+	if _, ok := mazamamaths.SumChecked[int64](math.MaxInt64, 1, -1); ok {
+		t.Error("Expected an overflow along the way to be reported but it was not")
+	}
+	if v, ok := mazamamaths.SumChecked[uint64](); !ok || v != 0 {
+		t.Errorf("Expected 0 for no numbers but got %d, %v", v, ok)
+	}
+}
+
+func TestChecked(t *testing.T) {
+
+	if _, err := mazamamaths.Checked(mazamamaths.AddChecked[uint64](math.MaxUint64, 1)); !errors.Is(err, mazamamaths.ErrOverflow) {
+		t.Errorf("Expected ErrOverflow but got %v", err)
+	}
+	if v, err := mazamamaths.Checked(mazamamaths.MulChecked(6, 7)); err != nil || v != 42 {
+		t.Errorf("Expected 42 but got %d, %v", v, err)
+	}
+}
+
+func FuzzCheckedInt64(f *testing.F) {
+
+	f.Add(int64(math.MinInt64), int64(-1))
+	f.Add(int64(math.MaxInt64), int64(2))
+	f.Add(int64(1<<32), int64(1<<31))
+	f.Fuzz(checkInt64)
+}
+
+func FuzzCheckedUint64(f *testing.F) {
+
+	f.Add(uint64(math.MaxUint64), uint64(1))
+	f.Add(uint64(1<<32), uint64(1<<32))
+	f.Fuzz(checkUint64)
+}
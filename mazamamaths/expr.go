@@ -0,0 +1,355 @@
+package mazamamaths
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The errors of Parse and Eval wrap one of these in an *ExprError.
+var (
+	ErrSyntax         = errors.New("syntax error")
+	ErrUndefined      = errors.New("undefined variable")
+	ErrDivisionByZero = errors.New("division by zero")
+)
+
+// maxDepth bounds the nesting of an expression, so a long run of
+// parentheses is an error rather than a stack overflow.
+const maxDepth = 1000
+
+// ExprError is what went wrong in an expression, and where.
+type ExprError struct {
+	Expr   string
+	Pos    int    // byte offset into Expr
+	Err    error  // ErrSyntax, ErrUndefined or ErrDivisionByZero
+	Detail string // what was found, e.g. `unexpected ")"`
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("mazamamaths: %q at column %d: %v: %s", e.Expr, e.Pos+1, e.Err, e.Detail)
+}
+
+func (e *ExprError) Unwrap() error {
+	return e.Err
+}
+
+// Expr is a parsed expression, to evaluate with different variables.
+type Expr struct {
+	src  string
+	root node
+	vars []string
+}
+
+// Parse parses an arithmetic expression: integer and decimal literals,
+// with an optional exponent as in 1.5e3, variables named like Go
+// identifiers, + and - as binary and unary operators, * and /, and
+// parentheses. The operators are left-associative and * and / bind
+// tighter than + and -.
+func Parse(expr string) (*Expr, error) {
+
+	p := &parser{src: expr}
+	p.next()
+
+	root, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, p.errorf("unexpected %s", p.tok)
+	}
+
+	return &Expr{src: expr, root: root, vars: p.vars}, nil
+}
+
+// Eval parses expr and evaluates it with vars bound, see Parse and
+// Expr.Eval.
+func Eval(expr string, vars map[string]float64) (float64, error) {
+
+	e, err := Parse(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	return e.Eval(vars)
+}
+
+// Eval evaluates e with vars bound. Every variable e uses must be in
+// vars; division by zero is an error rather than an infinity.
+func (e *Expr) Eval(vars map[string]float64) (float64, error) {
+	return e.root.eval(e.src, vars)
+}
+
+// Vars returns the variables e uses, in the order they first appear.
+func (e *Expr) Vars() []string {
+	return append([]string(nil), e.vars...)
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type node interface {
+	eval(src string, vars map[string]float64) (float64, error)
+}
+
+type number float64
+
+func (n number) eval(string, map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type variable struct {
+	name string
+	pos  int
+}
+
+func (v variable) eval(src string, vars map[string]float64) (float64, error) {
+
+	x, ok := vars[v.name]
+	if !ok {
+		return 0, &ExprError{Expr: src, Pos: v.pos, Err: ErrUndefined, Detail: v.name}
+	}
+
+	return x, nil
+}
+
+type negate struct {
+	x node
+}
+
+func (n negate) eval(src string, vars map[string]float64) (float64, error) {
+	x, err := n.x.eval(src, vars)
+	return -x, err
+}
+
+type binary struct {
+	op   byte
+	pos  int
+	l, r node
+}
+
+func (b binary) eval(src string, vars map[string]float64) (float64, error) {
+
+	l, err := b.l.eval(src, vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := b.r.eval(src, vars)
+	if err != nil {
+		return 0, err
+	}
+
+	switch b.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	}
+	if r == 0 {
+		return 0, &ExprError{Expr: src, Pos: b.pos, Err: ErrDivisionByZero, Detail: fmt.Sprintf("%g / 0", l)}
+	}
+
+	return l / r, nil
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokNumber
+	tokIdent
+	tokOp      // one of + - * / ( )
+	tokInvalid // any other character
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokInvalid:
+		r, _ := utf8.DecodeRuneInString(t.text)
+		return fmt.Sprintf("character %q", r)
+	}
+	return strconv.Quote(t.text)
+}
+
+type parser struct {
+	src  string
+	pos  int // of the next byte to scan
+	tok  token
+	vars []string
+}
+
+func (p *parser) errorf(format string, args ...any) *ExprError {
+	return &ExprError{Expr: p.src, Pos: p.tok.pos, Err: ErrSyntax, Detail: fmt.Sprintf(format, args...)}
+}
+
+// next scans the next token into p.tok.
+func (p *parser) next() {
+
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+	start := p.pos
+	if start == len(p.src) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.src[start]
+	r, size := utf8.DecodeRuneInString(p.src[start:])
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		p.scanNumber()
+	case r == '_' || unicode.IsLetter(r):
+		for p.pos < len(p.src) {
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			p.pos += size
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos], pos: start}
+	case c == '+' || c == '-' || c == '*' || c == '/' || c == '(' || c == ')':
+		p.pos++
+		p.tok = token{kind: tokOp, text: p.src[start:p.pos], pos: start}
+	default:
+		p.pos += size
+		p.tok = token{kind: tokInvalid, text: p.src[start:p.pos], pos: start}
+	}
+}
+
+func (p *parser) scanNumber() {
+
+	start := p.pos
+	digits := func() {
+		for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+			p.pos++
+		}
+	}
+
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	p.tok = token{kind: tokNumber, text: p.src[start:p.pos], pos: start}
+}
+
+// expr := term (("+" | "-") term)*
+func (p *parser) expr(depth int) (node, error) {
+
+	if depth > maxDepth {
+		return nil, p.errorf("nested more than %d deep", maxDepth)
+	}
+
+	l, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && (p.tok.text == "+" || p.tok.text == "-") {
+		op := p.tok
+		p.next()
+		r, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op: op.text[0], pos: op.pos, l: l, r: r}
+	}
+
+	return l, nil
+}
+
+// term := unary (("*" | "/") unary)*
+func (p *parser) term(depth int) (node, error) {
+
+	l, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOp && (p.tok.text == "*" || p.tok.text == "/") {
+		op := p.tok
+		p.next()
+		r, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		l = binary{op: op.text[0], pos: op.pos, l: l, r: r}
+	}
+
+	return l, nil
+}
+
+// unary := ("-" | "+") unary | primary
+func (p *parser) unary(depth int) (node, error) {
+
+	if p.tok.kind == tokOp && (p.tok.text == "-" || p.tok.text == "+") {
+		if depth > maxDepth {
+			return nil, p.errorf("nested more than %d deep", maxDepth)
+		}
+		neg := p.tok.text == "-"
+		p.next()
+		x, err := p.unary(depth + 1)
+		if err != nil || !neg {
+			return x, err
+		}
+		return negate{x}, nil
+	}
+
+	return p.primary(depth)
+}
+
+// primary := number | identifier | "(" expr ")"
+func (p *parser) primary(depth int) (node, error) {
+
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf("malformed number %s", tok)
+		}
+		p.next()
+		return number(v), nil
+
+	case tok.kind == tokIdent:
+		p.next()
+		if !slices.Contains(p.vars, tok.text) {
+			p.vars = append(p.vars, tok.text)
+		}
+		return variable{name: tok.text, pos: tok.pos}, nil
+
+	case tok.kind == tokOp && tok.text == "(":
+		p.next()
+		x, err := p.expr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokOp || p.tok.text != ")" {
+			return nil, &ExprError{Expr: p.src, Pos: p.tok.pos, Err: ErrSyntax,
+				Detail: fmt.Sprintf("unexpected %s, want \")\" to close the \"(\" at column %d", p.tok, tok.pos+1)}
+		}
+		p.next()
+		return x, nil
+	}
+
+	return nil, p.errorf("unexpected %s, want a number, a variable or \"(\"", tok)
+}
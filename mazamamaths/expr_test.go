@@ -0,0 +1,143 @@
+package mazamamaths_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"pacx/mazamamaths"
+)
+
+func TestEval(t *testing.T) {
+
+	vars := map[string]float64{"x": 3, "y": -2, "rate_2": 0.5, "π": 3.14}
+
+	for _, tc := range []struct {
+		expr string
+		want float64
+	}{
+		{"42", 42},
+		{"1.5", 1.5},
+		{".5", 0.5},
+		{"2.", 2},
+		{"1e3", 1000},
+		{"2.5E-1", 0.25},
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"24 / 4 / 2", 3},
+		{"7 / 2", 3.5},
+		{"-3", -3},
+		{"--3", 3},
+		{"+3", 3},
+		{"2 * -3", -6},
+		{"-(1 + 2) * 2", -6},
+		{"x * y", -6},
+		{"x - -y", 1},
+		{"rate_2 * 100", 50},
+		{"2 * π", 6.28},
+		{" \t( ( x ) )\n", 3},
+	} {
+		got, err := mazamamaths.Eval(tc.expr, vars)
+		if err != nil {
+			t.Errorf("Expected %q to be %g but got %v", tc.expr, tc.want, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("Expected %q to be %g but got %g", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+
+	for _, tc := range []struct {
+		expr   string
+		err    error
+		pos    int
+		detail string
+	}{
+		{"", mazamamaths.ErrSyntax, 0, "end of expression"},
+		{"   ", mazamamaths.ErrSyntax, 3, "end of expression"},
+		{"1 +", mazamamaths.ErrSyntax, 3, "end of expression"},
+		{"1 + * 2", mazamamaths.ErrSyntax, 4, `"*"`},
+		{"1 2", mazamamaths.ErrSyntax, 2, `"2"`},
+		{"(1 + 2", mazamamaths.ErrSyntax, 6, "column 1"},
+		{"1 + 2)", mazamamaths.ErrSyntax, 5, `")"`},
+		{"()", mazamamaths.ErrSyntax, 1, `")"`},
+		{"1 % 2", mazamamaths.ErrSyntax, 2, `'%'`},
+		{"(1 ^ 2)", mazamamaths.ErrSyntax, 3, `'^'`},
+		{"x €", mazamamaths.ErrSyntax, 2, `'€'`},
+		{".", mazamamaths.ErrSyntax, 0, "malformed number"},
+		{"1e", mazamamaths.ErrSyntax, 0, "malformed number"},
+		{"1.2.3", mazamamaths.ErrSyntax, 3, `".3"`},
+		{strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000), mazamamaths.ErrSyntax, 1001, "nested"},
+		{strings.Repeat("-", 2000) + "1", mazamamaths.ErrSyntax, 1001, "nested"},
+		{"x + z", mazamamaths.ErrUndefined, 4, "z"},
+		{"1 / (x - 3)", mazamamaths.ErrDivisionByZero, 2, "1 / 0"},
+	} {
+		_, err := mazamamaths.Eval(tc.expr, map[string]float64{"x": 3})
+		var e *mazamamaths.ExprError
+		if !errors.As(err, &e) || !errors.Is(err, tc.err) {
+			t.Errorf("Expected %q to fail with %v but got %v", tc.expr, tc.err, err)
+			continue
+		}
+		if e.Pos != tc.pos || !strings.Contains(e.Detail, tc.detail) {
+			t.Errorf("Expected %q to fail at %d with %s but got %d with %s", tc.expr, tc.pos, tc.detail, e.Pos, e.Detail)
+		}
+	}
+}
+
+func TestExprError(t *testing.T) {
+
+	_, err := mazamamaths.Eval("1 + * 2", nil)
+	if want := `mazamamaths: "1 + * 2" at column 5: syntax error: unexpected "*", want a number, a variable or "("`; err == nil || err.Error() != want {
+		t.Errorf("Expected the error %s but got %v", want, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+
+	e, err := mazamamaths.Parse("a * x + b - x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars := e.Vars(); !slices.Equal(vars, []string{"a", "x", "b"}) {
+		t.Errorf("Expected the variables a, x, b but got %v", vars)
+	}
+
+	for x, want := range map[float64]float64{0: 1, 1: 2, 2: 3} {
+		got, err := e.Eval(map[string]float64{"a": 2, "b": 1, "x": x})
+		if err != nil || got != want {
+			t.Errorf("Expected %g at x = %g but got %g, %v", want, x, got, err)
+		}
+	}
+	if _, err := e.Eval(map[string]float64{"a": 2, "x": 1}); !errors.Is(err, mazamamaths.ErrUndefined) {
+		t.Errorf("Expected b to be undefined but got %v", err)
+	}
+}
+
+// FuzzEval checks that any input is either evaluated or rejected with an
+// *ExprError pointing into it, and never panics.
+func FuzzEval(f *testing.F) {
+
+	for _, s := range []string{"1 + 2 * 3", "-(x / y)", "((1))", "1e-3 - .5", "x €", "1 / 0", "(("} {
+		f.Add(s)
+	}
+
+	vars := map[string]float64{"x": 1, "y": 0}
+	f.Fuzz(func(t *testing.T, s string) {
+		_, err := mazamamaths.Eval(s, vars)
+		if err == nil {
+			return
+		}
+		var e *mazamamaths.ExprError
+		if !errors.As(err, &e) {
+			t.Fatalf("Expected an *ExprError for %q but got %T: %v", s, err, err)
+		}
+		if e.Pos < 0 || e.Pos > len(s) || e.Expr != s {
+			t.Fatalf("Expected the error for %q to point into it but got %d in %q", s, e.Pos, e.Expr)
+		}
+	})
+}
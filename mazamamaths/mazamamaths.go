@@ -1,9 +1,99 @@
+// Package mazamamaths has arithmetic helpers that work on any number
+// type. Integer operations wrap around on overflow like the operators they
+// stand for.
 package mazamamaths
 
-func Add(x, y int) int {
+// Signed is any signed integer type.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is any unsigned integer type.
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Integer is any integer type.
+type Integer interface {
+	Signed | Unsigned
+}
+
+// Float is any floating-point type.
+type Float interface {
+	~float32 | ~float64
+}
+
+// Number is any integer or floating-point type.
+type Number interface {
+	Integer | Float
+}
+
+// Add returns x + y.
+func Add[T Number](x, y T) T {
 	return x + y
 }
 
-func mutiply(x, y int) int {
+// Sub returns x - y.
+func Sub[T Number](x, y T) T {
+	return x - y
+}
+
+// Mul returns x * y.
+func Mul[T Number](x, y T) T {
 	return x * y
 }
+
+// Abs returns the absolute value of x. Like -x, it is negative for the
+// most negative value of a signed type, which has no positive
+// counterpart. Abs of -0.0 is 0.0 and of NaN is NaN, as with math.Abs,
+// and an unsigned x is its own.
+func Abs[T Number](x T) T {
+
+	// 0 - x rather than -x turns -0.0 into 0.0
+	if x <= 0 {
+		return 0 - x
+	}
+
+	return x
+}
+
+// Min returns the smallest of its arguments, or NaN if any is.
+func Min[T Number](x T, rest ...T) T {
+
+	for _, y := range rest {
+		x = min(x, y)
+	}
+
+	return x
+}
+
+// Max returns the largest of its arguments, or NaN if any is.
+func Max[T Number](x T, rest ...T) T {
+
+	for _, y := range rest {
+		x = max(x, y)
+	}
+
+	return x
+}
+
+// Clamp returns x limited to [lo, hi]. It panics if lo > hi.
+func Clamp[T Number](x, lo, hi T) T {
+
+	if lo > hi {
+		panic("mazamamaths: Clamp with lo > hi")
+	}
+
+	return min(max(x, lo), hi)
+}
+
+// Sum returns the sum of xs, 0 for none.
+func Sum[T Number](xs ...T) T {
+
+	var total T
+	for _, x := range xs {
+		total += x
+	}
+
+	return total
+}
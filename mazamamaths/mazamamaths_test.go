@@ -1,8 +1,11 @@
 package mazamamaths_test
 
 import (
+	"math"
+	"math/big"
 	"pacx/mazamamaths"
 	"testing"
+	"testing/quick"
 )
 
 type addTestCase struct {
@@ -37,7 +40,119 @@ func TestAdd(t *testing.T) {
 
 func FuzzTestAdd(f *testing.F) {
 	f.Fuzz(func(t *testing.T, a, b int) {
-		mazamamaths.Add(a, b)
+		if got, want := mazamamaths.Add(a, b), int(rippleAdd(uint64(a), uint64(b))); got != want {
+			t.Errorf("Expected %d + %d to be %d but got %d", a, b, want, got)
+		}
+	})
+}
+
+// rippleAdd adds like a circuit does, with XOR for the sum bits and AND
+// for the carries, so it shares nothing with the + it checks.
+func rippleAdd(a, b uint64) uint64 {
+
+	for b != 0 {
+		carry := a & b
+		a ^= b
+		b = carry << 1
+	}
+
+	return a
+}
+
+// wrap64 is x cut to 64 bits, as int64 arithmetic wraps around.
+func wrap64(x *big.Int) int64 {
+
+	m := new(big.Int).And(x, new(big.Int).SetUint64(math.MaxUint64))
+
+	return int64(m.Uint64())
+}
+
+// adders are the functions that claim to add. Each is checked against
+// both references, so one that does something else, as Add2 once did by
+// subtracting, fails at once.
+var adders = []struct {
+	name string
+	fn   func(a, b int64) int64
+}{
+	{"Add", mazamamaths.Add[int64]},
+	{"Add2", func(a, b int64) int64 { return int64(mazamamaths.Add2(int(a), int(b))) }},
+	{"Sum", func(a, b int64) int64 { return mazamamaths.Sum(a, b) }},
+	{"Sub of the negation", func(a, b int64) int64 { return mazamamaths.Sub(a, 0-b) }},
+}
+
+func FuzzAddDifferential(f *testing.F) {
+
+	f.Add(int64(4), int64(2))
+	f.Add(int64(math.MaxInt64), int64(1))
+	f.Add(int64(math.MinInt64), int64(-1))
+	f.Fuzz(func(t *testing.T, a, b int64) {
+		want := wrap64(new(big.Int).Add(big.NewInt(a), big.NewInt(b)))
+		ripple := int64(rippleAdd(uint64(a), uint64(b)))
+		if want != ripple {
+			t.Fatalf("Expected the references to agree on %d + %d but got %d and %d", a, b, want, ripple)
+		}
+		for _, ad := range adders {
+			if got := ad.fn(a, b); got != want {
+				t.Errorf("Expected %s(%d, %d) to be %d but got %d", ad.name, a, b, want, got)
+			}
+		}
+	})
+}
+
+func FuzzMulDifferential(f *testing.F) {
+
+	f.Add(int64(math.MinInt64), int64(-1))
+	f.Fuzz(func(t *testing.T, a, b int64) {
+		want := wrap64(new(big.Int).Mul(big.NewInt(a), big.NewInt(b)))
+		if got := mazamamaths.Mul(a, b); got != want {
+			t.Errorf("Expected %d * %d to be %d but got %d", a, b, want, got)
+		}
+	})
+}
+
+// quickCheck fails t with the arguments quick found if prop does not
+// hold for them.
+func quickCheck(t *testing.T, name string, prop any) {
+
+	t.Helper()
+
+	if err := quick.Check(prop, &quick.Config{MaxCount: 10_000}); err != nil {
+		t.Errorf("Expected %s to hold but it does not: %v", name, err)
+	}
+}
+
+func TestAddProperties(t *testing.T) {
+
+	add := mazamamaths.Add[int64]
+	quickCheck(t, "commutativity", func(a, b int64) bool { return add(a, b) == add(b, a) })
+	quickCheck(t, "associativity", func(a, b, c int64) bool { return add(add(a, b), c) == add(a, add(b, c)) })
+	quickCheck(t, "identity", func(a int64) bool { return add(a, 0) == a && add(0, a) == a })
+	quickCheck(t, "inverse", func(a, b int64) bool { return mazamamaths.Sub(add(a, b), b) == a })
+	quickCheck(t, "unsigned commutativity", func(a, b uint8) bool { return mazamamaths.Add(a, b) == mazamamaths.Add(b, a) })
+
+	// floats round, so only the properties that survive rounding
+	quickCheck(t, "float commutativity", func(a, b float64) bool { return mazamamaths.Add(a, b) == mazamamaths.Add(b, a) })
+	quickCheck(t, "float identity", func(a float64) bool { return mazamamaths.Add(a, 0) == a })
+}
+
+func TestMulProperties(t *testing.T) {
+
+	mul, add := mazamamaths.Mul[int32], mazamamaths.Add[int32]
+	quickCheck(t, "commutativity", func(a, b int32) bool { return mul(a, b) == mul(b, a) })
+	quickCheck(t, "associativity", func(a, b, c int32) bool { return mul(mul(a, b), c) == mul(a, mul(b, c)) })
+	quickCheck(t, "identity", func(a int32) bool { return mul(a, 1) == a })
+	quickCheck(t, "zero", func(a int32) bool { return mul(a, 0) == 0 })
+	quickCheck(t, "distributivity", func(a, b, c int32) bool { return mul(a, add(b, c)) == add(mul(a, b), mul(a, c)) })
+}
+
+func TestMinMaxProperties(t *testing.T) {
+
+	quickCheck(t, "Min ≤ Max", func(a, b, c int) bool { return mazamamaths.Min(a, b, c) <= mazamamaths.Max(a, b, c) })
+	quickCheck(t, "Min is an argument", func(a, b int) bool { m := mazamamaths.Min(a, b); return m == a || m == b })
+	quickCheck(t, "Clamp is idempotent", func(x, lo, hi int) bool {
+		lo, hi = min(lo, hi), max(lo, hi)
+		once := mazamamaths.Clamp(x, lo, hi)
+		return mazamamaths.Clamp(once, lo, hi) == once
 	})
 }
 
@@ -46,3 +161,176 @@ func BenchmarkAdd(b *testing.B) {
 		mazamamaths.Add(1, 1)
 	}
 }
+
+func TestAddTypes(t *testing.T) {
+
+	if got := mazamamaths.Add(1.5, 2.25); got != 3.75 {
+		t.Errorf("Expected 3.75 but got %g", got)
+	}
+	if got := mazamamaths.Add[uint8](250, 10); got != 4 {
+		t.Errorf("Expected uint8 addition to wrap to 4 but got %d", got)
+	}
+
+	type celsius float64
+	if got := mazamamaths.Add(celsius(20), 1.5); got != 21.5 {
+		t.Errorf("Expected 21.5 but got %g", got)
+	}
+}
+
+func TestSubMul(t *testing.T) {
+
+	if got := mazamamaths.Sub(4, 2); got != 2 {
+		t.Errorf("Expected 2 but got %d", got)
+	}
+	if got := mazamamaths.Sub[uint](0, 1); got != math.MaxUint {
+		t.Errorf("Expected uint subtraction to wrap to %d but got %d", uint(math.MaxUint), got)
+	}
+	if got := mazamamaths.Mul(-3, 7); got != -21 {
+		t.Errorf("Expected -21 but got %d", got)
+	}
+	if got := mazamamaths.Mul[float32](0.5, 3); got != 1.5 {
+		t.Errorf("Expected 1.5 but got %g", got)
+	}
+}
+
+func TestAbs(t *testing.T) {
+
+	for _, tc := range []struct{ in, want int }{{-5, 5}, {5, 5}, {0, 0}} {
+		if got := mazamamaths.Abs(tc.in); got != tc.want {
+			t.Errorf("Expected Abs(%d) to be %d but got %d", tc.in, tc.want, got)
+		}
+	}
+	if got := mazamamaths.Abs(int8(math.MinInt8)); got != math.MinInt8 {
+		t.Errorf("Expected Abs of the most negative int8 to stay %d but got %d", math.MinInt8, got)
+	}
+	if got := mazamamaths.Abs(math.Copysign(0, -1)); math.Signbit(got) {
+		t.Errorf("Expected Abs(-0) to be +0 but got %g", got)
+	}
+	if got := mazamamaths.Abs(math.Inf(-1)); !math.IsInf(got, 1) {
+		t.Errorf("Expected +Inf but got %g", got)
+	}
+	if got := mazamamaths.Abs(math.NaN()); !math.IsNaN(got) {
+		t.Errorf("Expected NaN but got %g", got)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+
+	if got := mazamamaths.Min(3, 1, 2); got != 1 {
+		t.Errorf("Expected 1 but got %d", got)
+	}
+	if got := mazamamaths.Max(3, 1, 2); got != 3 {
+		t.Errorf("Expected 3 but got %d", got)
+	}
+	if got := mazamamaths.Min(7); got != 7 {
+		t.Errorf("Expected the only argument 7 but got %d", got)
+	}
+	if got := mazamamaths.Max(1.0, math.NaN(), 2.0); !math.IsNaN(got) {
+		t.Errorf("Expected NaN to win but got %g", got)
+	}
+}
+
+func TestClamp(t *testing.T) {
+
+	for _, tc := range []struct{ x, lo, hi, want int }{
+		{5, 0, 10, 5},
+		{-5, 0, 10, 0},
+		{15, 0, 10, 10},
+		{3, 3, 3, 3},
+	} {
+		if got := mazamamaths.Clamp(tc.x, tc.lo, tc.hi); got != tc.want {
+			t.Errorf("Expected Clamp(%d, %d, %d) to be %d but got %d", tc.x, tc.lo, tc.hi, tc.want, got)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Clamp with lo > hi to panic but it did not")
+		}
+	}()
+	mazamamaths.Clamp(1, 2, 1)
+}
+
+func TestSum(t *testing.T) {
+
+	if got := mazamamaths.Sum[int](); got != 0 {
+		t.Errorf("Expected 0 for no numbers but got %d", got)
+	}
+	if got := mazamamaths.Sum(1, 2, 3, 4, 5); got != 15 {
+		t.Errorf("Expected 15 but got %d", got)
+	}
+	xs := []float64{0.5, 0.25, 0.125}
+	if got := mazamamaths.Sum(xs...); got != 0.875 {
+		t.Errorf("Expected 0.875 but got %g", got)
+	}
+}
+
+func FuzzAddSub(f *testing.F) {
+
+	f.Add(int64(1), int64(2))
+	f.Add(int64(math.MaxInt64), int64(1))
+	f.Fuzz(func(t *testing.T, a, b int64) {
+		// wrapping makes them inverse even when they overflow
+		if got := mazamamaths.Sub(mazamamaths.Add(a, b), b); got != a {
+			t.Errorf("Expected (%d + %d) - %d to be %d but got %d", a, b, b, a, got)
+		}
+		if mazamamaths.Add(a, b) != mazamamaths.Add(b, a) {
+			t.Errorf("Expected %d + %d to commute", a, b)
+		}
+	})
+}
+
+func FuzzMul(f *testing.F) {
+
+	f.Add(int32(6), int32(7))
+	f.Fuzz(func(t *testing.T, a, b int32) {
+		if got, want := mazamamaths.Mul(a, b), int32(int64(a)*int64(b)); got != want {
+			t.Errorf("Expected %d * %d to be %d but got %d", a, b, want, got)
+		}
+	})
+}
+
+func FuzzAbs(f *testing.F) {
+
+	f.Add(int64(-3), -2.5)
+	f.Fuzz(func(t *testing.T, i int64, x float64) {
+		if got := mazamamaths.Abs(i); got < 0 && i != math.MinInt64 {
+			t.Errorf("Expected Abs(%d) not to be negative but got %d", i, got)
+		}
+		if got, want := mazamamaths.Abs(x), math.Abs(x); got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+			t.Errorf("Expected Abs(%g) to be %g like math.Abs but got %g", x, want, got)
+		}
+	})
+}
+
+func FuzzClamp(f *testing.F) {
+
+	f.Add(5, 0, 10)
+	f.Fuzz(func(t *testing.T, x, lo, hi int) {
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		got := mazamamaths.Clamp(x, lo, hi)
+		if got < lo || got > hi {
+			t.Errorf("Expected Clamp(%d, %d, %d) within the bounds but got %d", x, lo, hi, got)
+		}
+		if x >= lo && x <= hi && got != x {
+			t.Errorf("Expected Clamp to leave %d in [%d, %d] alone but got %d", x, lo, hi, got)
+		}
+		if mazamamaths.Min(x, lo, hi) != min(x, lo, hi) || mazamamaths.Max(x, lo, hi) != max(x, lo, hi) {
+			t.Errorf("Expected Min and Max of %d, %d, %d to match the builtins", x, lo, hi)
+		}
+	})
+}
+
+func BenchmarkSum(b *testing.B) {
+
+	xs := make([]float64, 1000)
+	for i := range xs {
+		xs[i] = float64(i)
+	}
+
+	for b.Loop() {
+		mazamamaths.Sum(xs...)
+	}
+}
@@ -0,0 +1,209 @@
+package mazamamaths
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// GCD returns the greatest common divisor of a and b, which is never
+// negative, and 0 only if both are 0. Like Abs it cannot make the
+// smallest value of a signed type positive.
+func GCD[T Integer](a, b T) T {
+
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return Abs(a)
+}
+
+// LCM returns the least common multiple of a and b, 0 if either is 0.
+// Like Mul it wraps around if the result does not fit; MulChecked on
+// a/GCD(a, b) and b tells whether it does.
+func LCM[T Integer](a, b T) T {
+
+	if a == 0 || b == 0 {
+		return 0
+	}
+
+	return Abs(a / GCD(a, b) * b)
+}
+
+// mulMod returns a*b mod m for a, b < m without overflowing.
+func mulMod(a, b, m uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	_, r := bits.Div64(hi, lo, m)
+	return r
+}
+
+// addMod returns a+b mod m for a, b < m without overflowing.
+func addMod(a, b, m uint64) uint64 {
+	s := a + b
+	if s < a || s >= m {
+		s -= m
+	}
+	return s
+}
+
+func powMod(b, e, m uint64) uint64 {
+
+	r := uint64(1)
+	for b %= m; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			r = mulMod(r, b, m)
+		}
+		b = mulMod(b, b, m)
+	}
+
+	return r
+}
+
+// smallPrimes are trial divisors and the Miller–Rabin bases.
+var smallPrimes = []uint64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29, 31, 37}
+
+// IsPrime reports whether n is prime. It runs Miller–Rabin with the first
+// twelve primes as bases, which is known to decide every n < 2^64, so
+// unlike a probabilistic test it is never wrong.
+func IsPrime(n uint64) bool {
+
+	if n < 2 {
+		return false
+	}
+	for _, p := range smallPrimes {
+		if n%p == 0 {
+			return n == p
+		}
+	}
+
+	d, s := n-1, 0
+	for d%2 == 0 {
+		d /= 2
+		s++
+	}
+
+witnesses:
+	for _, a := range smallPrimes {
+		x := powMod(a, d, n)
+		if x == 1 || x == n-1 {
+			continue
+		}
+		for range s - 1 {
+			x = mulMod(x, x, n)
+			if x == n-1 {
+				continue witnesses
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// PrimeSieve returns the primes up to and including n, with the sieve of
+// Eratosthenes.
+func PrimeSieve(n int) []int {
+
+	if n < 2 {
+		return nil
+	}
+
+	composite := make([]bool, n+1)
+	for i := 2; i*i <= n; i++ {
+		if composite[i] {
+			continue
+		}
+		for j := i * i; j <= n; j += i {
+			composite[j] = true
+		}
+	}
+
+	var primes []int
+	for i := 2; i <= n; i++ {
+		if !composite[i] {
+			primes = append(primes, i)
+		}
+	}
+
+	return primes
+}
+
+// Factorize returns the prime factors of n in ascending order, each as
+// often as it divides n; none for 0 and 1. Small factors are found by
+// trial division and large ones with Pollard's rho, so a product of two
+// large primes takes milliseconds, not the minutes trial division would.
+func Factorize(n uint64) []uint64 {
+
+	if n < 2 {
+		return nil
+	}
+
+	var factors []uint64
+	for p := uint64(2); p < 1000 && p*p <= n; p++ {
+		for n%p == 0 {
+			factors = append(factors, p)
+			n /= p
+		}
+	}
+
+	stack := []uint64{n}
+	for len(stack) > 0 {
+		m := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		switch {
+		case m == 1:
+		case IsPrime(m):
+			factors = append(factors, m)
+		default:
+			d := rho(m)
+			stack = append(stack, d, m/d)
+		}
+	}
+	slices.Sort(factors)
+
+	return factors
+}
+
+// rho returns a non-trivial divisor of the odd composite n, with Brent's
+// variant of Pollard's rho.
+func rho(n uint64) uint64 {
+
+	for c := uint64(1); ; c++ {
+		f := func(x uint64) uint64 { return addMod(mulMod(x, x, n), c, n) }
+
+		const batch = 128
+		y, g, q := uint64(2), uint64(1), uint64(1)
+		var x, ys uint64
+		for r := uint64(1); g == 1; r *= 2 {
+			x = y
+			for range r {
+				y = f(y)
+			}
+			for k := uint64(0); k < r && g == 1; k += batch {
+				ys = y
+				for range min(batch, r-k) {
+					y = f(y)
+					q = mulMod(q, diff(x, y), n)
+				}
+				g = GCD(q, n)
+			}
+		}
+
+		// the batch overshot; step through it one at a time
+		if g == n {
+			for g = 1; g == 1; {
+				ys = f(ys)
+				g = GCD(diff(x, ys), n)
+			}
+		}
+		if g != n {
+			return g
+		}
+	}
+}
+
+func diff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
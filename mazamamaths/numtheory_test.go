@@ -0,0 +1,175 @@
+package mazamamaths_test
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"testing"
+
+	"pacx/mazamamaths"
+)
+
+func TestGCDLCM(t *testing.T) {
+
+	for _, tc := range []struct{ a, b, gcd, lcm int }{
+		{12, 18, 6, 36},
+		{-12, 18, 6, 36},
+		{12, -18, 6, 36},
+		{7, 13, 1, 91},
+		{0, 5, 5, 0},
+		{0, 0, 0, 0},
+	} {
+		if got := mazamamaths.GCD(tc.a, tc.b); got != tc.gcd {
+			t.Errorf("Expected GCD(%d, %d) to be %d but got %d", tc.a, tc.b, tc.gcd, got)
+		}
+		if got := mazamamaths.LCM(tc.a, tc.b); got != tc.lcm {
+			t.Errorf("Expected LCM(%d, %d) to be %d but got %d", tc.a, tc.b, tc.lcm, got)
+		}
+	}
+	if got := mazamamaths.GCD[uint64](1<<63, 1<<40); got != 1<<40 {
+		t.Errorf("Expected 2^40 but got %d", got)
+	}
+}
+
+func TestIsPrime(t *testing.T) {
+
+	primes := mazamamaths.PrimeSieve(100_000)
+	for n := uint64(0); n <= 100_000; n++ {
+		_, want := slices.BinarySearch(primes, int(n))
+		if got := mazamamaths.IsPrime(n); got != want {
+			t.Fatalf("Expected IsPrime(%d) to be %v but got %v", n, want, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		n    uint64
+		want bool
+	}{
+		{math.MaxUint64, false},
+		{18446744073709551557, true}, // the largest prime below 2^64
+		{4294967291, true},
+		{3215031751, false},          // a strong pseudoprime to bases 2, 3, 5 and 7
+		{3825123056546413051, false}, // ... and to every base up to 23
+	} {
+		if got := mazamamaths.IsPrime(tc.n); got != tc.want {
+			t.Errorf("Expected IsPrime(%d) to be %v but got %v", tc.n, tc.want, got)
+		}
+	}
+}
+
+func TestPrimeSieve(t *testing.T) {
+
+	if got := mazamamaths.PrimeSieve(30); !slices.Equal(got, []int{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}) {
+		t.Errorf("Expected the primes up to 30 but got %v", got)
+	}
+	if got := mazamamaths.PrimeSieve(1); got != nil {
+		t.Errorf("Expected no primes up to 1 but got %v", got)
+	}
+	if got, want := mazamamaths.PrimeSieve(10_000), trialDivision(10_000); !slices.Equal(got, want) {
+		t.Errorf("Expected the sieve to agree with trial division but got %d primes instead of %d", len(got), len(want))
+	}
+	if got := len(mazamamaths.PrimeSieve(1_000_000)); got != 78498 {
+		t.Errorf("Expected 78498 primes up to a million but got %d", got)
+	}
+}
+
+func checkFactors(t *testing.T, n uint64) {
+
+	t.Helper()
+
+	factors := mazamamaths.Factorize(n)
+	product := uint64(1)
+	for _, f := range factors {
+		if !mazamamaths.IsPrime(f) {
+			t.Errorf("Expected only prime factors of %d but got %d", n, f)
+		}
+		product *= f
+	}
+	if n >= 2 && product != n {
+		t.Errorf("Expected the factors %v to multiply to %d but got %d", factors, n, product)
+	}
+	if !slices.IsSorted(factors) {
+		t.Errorf("Expected the factors of %d in order but got %v", n, factors)
+	}
+}
+
+func TestFactorize(t *testing.T) {
+
+	if got := mazamamaths.Factorize(360); !slices.Equal(got, []uint64{2, 2, 2, 3, 3, 5}) {
+		t.Errorf("Expected 360 = 2·2·2·3·3·5 but got %v", got)
+	}
+	if got := mazamamaths.Factorize(1); got != nil {
+		t.Errorf("Expected no factors of 1 but got %v", got)
+	}
+
+	for _, n := range []uint64{
+		4294967291 * 4294967279, // two primes just below 2^32
+		1000003 * 1000003,
+		math.MaxUint64,
+		1 << 63,
+		18446744073709551557,
+		3825123056546413051,
+	} {
+		checkFactors(t, n)
+	}
+}
+
+func FuzzIsPrime(f *testing.F) {
+
+	f.Add(uint64(3215031751))
+	f.Fuzz(func(t *testing.T, n uint64) {
+		// ProbablyPrime(0) is exact below 2^64
+		if got, want := mazamamaths.IsPrime(n), new(big.Int).SetUint64(n).ProbablyPrime(0); got != want {
+			t.Errorf("Expected IsPrime(%d) to be %v but got %v", n, want, got)
+		}
+	})
+}
+
+func FuzzFactorize(f *testing.F) {
+
+	f.Add(uint64(360))
+	f.Fuzz(checkFactors)
+}
+
+// trialDivision is the obvious way to list the primes up to n, for the
+// sieve to beat.
+func trialDivision(n int) []int {
+
+	var primes []int
+	for i := 2; i <= n; i++ {
+		prime := true
+		for _, p := range primes {
+			if p*p > i {
+				break
+			}
+			if i%p == 0 {
+				prime = false
+				break
+			}
+		}
+		if prime {
+			primes = append(primes, i)
+		}
+	}
+
+	return primes
+}
+
+func BenchmarkPrimes(b *testing.B) {
+
+	for _, n := range []int{1_000, 100_000, 1_000_000} {
+		b.Run(fmt.Sprintf("how=sieve/n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				mazamamaths.PrimeSieve(n)
+			}
+		})
+		b.Run(fmt.Sprintf("how=trial/n=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				trialDivision(n)
+			}
+		})
+	}
+}
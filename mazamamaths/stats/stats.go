@@ -0,0 +1,220 @@
+// Package stats summarises samples: the mean and spread of a batch of
+// numbers, where they sit in order, and Welford, which keeps the mean and
+// variance of a stream without holding on to it.
+//
+// The batch functions take any number type and return float64s. None of
+// them change the slice they are given. Those that are undefined for no
+// samples return NaN.
+package stats
+
+import (
+	"math"
+	"slices"
+
+	"pacx/mazamamaths"
+)
+
+// Mean returns the arithmetic mean of xs.
+func Mean[T mazamamaths.Number](xs []T) float64 {
+	return welford(xs).Mean()
+}
+
+// Variance returns the population variance of xs, the mean squared
+// distance from the mean.
+func Variance[T mazamamaths.Number](xs []T) float64 {
+	return welford(xs).Variance()
+}
+
+// SampleVariance returns the variance of the population xs were drawn
+// from, estimated with Bessel's correction. It needs two samples.
+func SampleVariance[T mazamamaths.Number](xs []T) float64 {
+	return welford(xs).SampleVariance()
+}
+
+// StdDev returns the population standard deviation of xs.
+func StdDev[T mazamamaths.Number](xs []T) float64 {
+	return math.Sqrt(Variance(xs))
+}
+
+// SampleStdDev returns the square root of SampleVariance.
+func SampleStdDev[T mazamamaths.Number](xs []T) float64 {
+	return math.Sqrt(SampleVariance(xs))
+}
+
+func welford[T mazamamaths.Number](xs []T) *Welford {
+
+	var w Welford
+	for _, x := range xs {
+		w.Add(float64(x))
+	}
+
+	return &w
+}
+
+// Median returns the middle value of xs, or the mean of the two middle
+// values for an even count.
+func Median[T mazamamaths.Number](xs []T) float64 {
+	return Percentile(xs, 50)
+}
+
+// Percentile returns the value p percent of xs are below, with p in
+// [0, 100]. Between two samples it interpolates linearly, the way
+// spreadsheets and NumPy do by default. It sorts a copy of xs; to take
+// several percentiles of the same samples use Percentiles.
+func Percentile[T mazamamaths.Number](xs []T, p float64) float64 {
+	return Percentiles(xs, p)[0]
+}
+
+// Percentiles returns Percentile of xs for each of ps, sorting once.
+func Percentiles[T mazamamaths.Number](xs []T, ps ...float64) []float64 {
+
+	sorted := slices.Clone(xs)
+	slices.Sort(sorted)
+
+	out := make([]float64, len(ps))
+	for i, p := range ps {
+		out[i] = sortedPercentile(sorted, p)
+	}
+
+	return out
+}
+
+func sortedPercentile[T mazamamaths.Number](sorted []T, p float64) float64 {
+
+	if len(sorted) == 0 || !(p >= 0 && p <= 100) {
+		return math.NaN()
+	}
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lo := int(rank)
+	if lo == len(sorted)-1 {
+		return float64(sorted[lo])
+	}
+	frac := rank - float64(lo)
+
+	return float64(sorted[lo]) + frac*(float64(sorted[lo+1])-float64(sorted[lo]))
+}
+
+// Mode returns the values that occur most often in xs, in ascending
+// order. Every value is a mode if none repeats; none are for no samples.
+func Mode[T mazamamaths.Number](xs []T) []T {
+
+	counts := make(map[T]int, len(xs))
+	best := 0
+	for _, x := range xs {
+		counts[x]++
+		best = max(best, counts[x])
+	}
+
+	var modes []T
+	for x, n := range counts {
+		if n == best {
+			modes = append(modes, x)
+		}
+	}
+	slices.Sort(modes)
+
+	return modes
+}
+
+// Welford accumulates the count, mean, variance and range of a stream of
+// values in constant space, with Welford's online algorithm, which stays
+// accurate where summing squares would cancel out. The zero value is
+// empty and ready to use. It is not safe for concurrent use; give each
+// goroutine its own and Merge them.
+type Welford struct {
+	n        int64
+	mean     float64
+	m2       float64 // sum of squared distances from the mean
+	min, max float64
+}
+
+// Add adds x.
+func (w *Welford) Add(x float64) {
+
+	w.n++
+	if w.n == 1 {
+		w.min, w.max = x, x
+	} else {
+		w.min, w.max = min(w.min, x), max(w.max, x)
+	}
+	delta := x - w.mean
+	w.mean += delta / float64(w.n)
+	w.m2 += delta * (x - w.mean)
+}
+
+// Merge adds everything o has seen, as if each value had been added to w.
+func (w *Welford) Merge(o Welford) {
+
+	switch {
+	case o.n == 0:
+		return
+	case w.n == 0:
+		*w = o
+		return
+	}
+
+	n := w.n + o.n
+	delta := o.mean - w.mean
+	w.m2 += o.m2 + delta*delta*float64(w.n)*float64(o.n)/float64(n)
+	w.mean += delta * float64(o.n) / float64(n)
+	w.min, w.max = min(w.min, o.min), max(w.max, o.max)
+	w.n = n
+}
+
+// N returns the number of values added.
+func (w *Welford) N() int64 {
+	return w.n
+}
+
+// Mean returns the mean, or NaN before the first value.
+func (w *Welford) Mean() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.mean
+}
+
+// Variance returns the population variance, or NaN before the first
+// value.
+func (w *Welford) Variance() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.m2 / float64(w.n)
+}
+
+// SampleVariance returns the variance with Bessel's correction, or NaN
+// before the second value.
+func (w *Welford) SampleVariance() float64 {
+	if w.n < 2 {
+		return math.NaN()
+	}
+	return w.m2 / float64(w.n-1)
+}
+
+// StdDev returns the population standard deviation.
+func (w *Welford) StdDev() float64 {
+	return math.Sqrt(w.Variance())
+}
+
+// SampleStdDev returns the square root of SampleVariance.
+func (w *Welford) SampleStdDev() float64 {
+	return math.Sqrt(w.SampleVariance())
+}
+
+// Min returns the smallest value, or NaN before the first.
+func (w *Welford) Min() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.min
+}
+
+// Max returns the largest value, or NaN before the first.
+func (w *Welford) Max() float64 {
+	if w.n == 0 {
+		return math.NaN()
+	}
+	return w.max
+}
@@ -0,0 +1,154 @@
+package stats_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"pacx/mazamamaths/stats"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*max(1, math.Abs(b))
+}
+
+func TestBatch(t *testing.T) {
+
+	xs := []int{2, 4, 4, 4, 5, 5, 7, 9}
+
+	for _, tc := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"Mean", stats.Mean(xs), 5},
+		{"Variance", stats.Variance(xs), 4},
+		{"StdDev", stats.StdDev(xs), 2},
+		{"SampleVariance", stats.SampleVariance(xs), 32.0 / 7},
+		{"SampleStdDev", stats.SampleStdDev(xs), math.Sqrt(32.0 / 7)},
+		{"Median", stats.Median(xs), 4.5},
+		{"Median of odd count", stats.Median([]float64{3, 1, 2}), 2},
+	} {
+		if !near(tc.got, tc.want) {
+			t.Errorf("Expected %s to be %g but got %g", tc.name, tc.want, tc.got)
+		}
+	}
+
+	if !slices.Equal(xs, []int{2, 4, 4, 4, 5, 5, 7, 9}) {
+		t.Errorf("Expected the samples to be left alone but got %v", xs)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+
+	xs := []float64{15, 20, 35, 40, 50}
+	for _, tc := range []struct{ p, want float64 }{
+		{0, 15},
+		{25, 20},
+		{40, 29},
+		{50, 35},
+		{100, 50},
+	} {
+		if got := stats.Percentile(xs, tc.p); !near(got, tc.want) {
+			t.Errorf("Expected percentile %g to be %g but got %g", tc.p, tc.want, got)
+		}
+	}
+
+	got := stats.Percentiles([]time.Duration{3, 1, 2}, 0, 50, 100)
+	if !slices.Equal(got, []float64{1, 2, 3}) {
+		t.Errorf("Expected [1 2 3] but got %v", got)
+	}
+	if !math.IsNaN(stats.Percentile(xs, 101)) || !math.IsNaN(stats.Percentile(xs, math.NaN())) {
+		t.Error("Expected NaN for a percentile outside [0, 100] but got a number")
+	}
+}
+
+func TestMode(t *testing.T) {
+
+	if got := stats.Mode([]int{1, 2, 2, 3, 3, 4}); !slices.Equal(got, []int{2, 3}) {
+		t.Errorf("Expected modes [2 3] but got %v", got)
+	}
+	if got := stats.Mode([]int{5}); !slices.Equal(got, []int{5}) {
+		t.Errorf("Expected mode [5] but got %v", got)
+	}
+	if got := stats.Mode([]int(nil)); len(got) != 0 {
+		t.Errorf("Expected no mode for no samples but got %v", got)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+
+	var none []float64
+	for name, v := range map[string]float64{
+		"Mean":           stats.Mean(none),
+		"Variance":       stats.Variance(none),
+		"SampleVariance": stats.SampleVariance([]float64{1}),
+		"Median":         stats.Median(none),
+	} {
+		if !math.IsNaN(v) {
+			t.Errorf("Expected %s without enough samples to be NaN but got %g", name, v)
+		}
+	}
+}
+
+func TestWelford(t *testing.T) {
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	xs := make([]float64, 10_000)
+	for i := range xs {
+		// a large offset is where summing squares loses all precision
+		xs[i] = 1e9 + rng.NormFloat64()
+	}
+
+	var all, a, b stats.Welford
+	for i, x := range xs {
+		all.Add(x)
+		if i%3 == 0 {
+			a.Add(x)
+		} else {
+			b.Add(x)
+		}
+	}
+	a.Merge(b)
+
+	for _, w := range []stats.Welford{all, a} {
+		if w.N() != int64(len(xs)) {
+			t.Errorf("Expected %d values but got %d", len(xs), w.N())
+		}
+		if !near(w.Mean(), stats.Mean(xs)) {
+			t.Errorf("Expected mean %g but got %g", stats.Mean(xs), w.Mean())
+		}
+		if math.Abs(w.Variance()-1) > 0.05 {
+			t.Errorf("Expected a variance near 1 but got %g", w.Variance())
+		}
+		if w.Min() != slices.Min(xs) || w.Max() != slices.Max(xs) {
+			t.Errorf("Expected range [%g, %g] but got [%g, %g]", slices.Min(xs), slices.Max(xs), w.Min(), w.Max())
+		}
+	}
+	// merging loses a little of the precision at this offset
+	if math.Abs(a.Variance()-all.Variance()) > 1e-6 {
+		t.Errorf("Expected merged variance %g but got %g", all.Variance(), a.Variance())
+	}
+
+	var empty stats.Welford
+	empty.Merge(stats.Welford{})
+	if !math.IsNaN(empty.Mean()) || !math.IsNaN(empty.Min()) {
+		t.Error("Expected an empty accumulator to report NaN")
+	}
+}
+
+func FuzzPercentile(f *testing.F) {
+
+	f.Add(3.0, 1.0, 2.0, 50.0)
+	f.Fuzz(func(t *testing.T, a, b, c, p float64) {
+		xs := []float64{a, b, c}
+		if slices.ContainsFunc(xs, func(x float64) bool { return math.IsNaN(x) || math.IsInf(x, 0) }) || p < 0 || p > 100 {
+			return
+		}
+		got := stats.Percentile(xs, p)
+		if got < slices.Min(xs) || got > slices.Max(xs) {
+			t.Errorf("Expected percentile %g of %v within its range but got %g", p, xs, got)
+		}
+	})
+}
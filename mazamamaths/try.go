@@ -2,8 +2,12 @@ package mazamamaths
 
 import "fmt"
 
+// Add2 returns x + y. It used to return x - y; callers that relied on
+// that should call Sub.
+//
+// Deprecated: use Add.
 func Add2(x, y int) int {
-	return x - y
+	return x + y
 }
 
 // Exported function (Accessible outside the package)
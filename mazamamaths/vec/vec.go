@@ -0,0 +1,129 @@
+// Package vec has 2D and 3D vectors of float64, for positions, directions
+// and velocities such as those of a game's players:
+//
+//	pos = pos.Add(dir.Normalize().Scale(speed * dt.Seconds()))
+//
+// Vectors are small values: every method returns a new one and none
+// changes its receiver.
+package vec
+
+import "math"
+
+// Vec2 is a vector in the plane.
+type Vec2 struct {
+	X, Y float64
+}
+
+func (v Vec2) Add(w Vec2) Vec2 {
+	return Vec2{v.X + w.X, v.Y + w.Y}
+}
+
+func (v Vec2) Sub(w Vec2) Vec2 {
+	return Vec2{v.X - w.X, v.Y - w.Y}
+}
+
+// Scale returns v times s.
+func (v Vec2) Scale(s float64) Vec2 {
+	return Vec2{v.X * s, v.Y * s}
+}
+
+func (v Vec2) Dot(w Vec2) float64 {
+	return v.X*w.X + v.Y*w.Y
+}
+
+// Cross returns the z component of the cross product of v and w as 3D
+// vectors: positive if w is counterclockwise from v, negative if
+// clockwise and zero if they are parallel.
+func (v Vec2) Cross(w Vec2) float64 {
+	return v.X*w.Y - v.Y*w.X
+}
+
+func (v Vec2) Length() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+// Distance returns the length of v - w.
+func (v Vec2) Distance(w Vec2) float64 {
+	return v.Sub(w).Length()
+}
+
+// Normalize returns v scaled to length 1, or the zero vector for the zero
+// vector, which has no direction.
+func (v Vec2) Normalize() Vec2 {
+
+	l := v.Length()
+	if l == 0 {
+		return Vec2{}
+	}
+
+	return Vec2{v.X / l, v.Y / l}
+}
+
+// Lerp returns the point t of the way from v to w: v at 0, w at 1, and
+// beyond them outside [0, 1].
+func (v Vec2) Lerp(w Vec2, t float64) Vec2 {
+	return Vec2{lerp(v.X, w.X, t), lerp(v.Y, w.Y, t)}
+}
+
+// Vec3 is a vector in space.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func (v Vec3) Add(w Vec3) Vec3 {
+	return Vec3{v.X + w.X, v.Y + w.Y, v.Z + w.Z}
+}
+
+func (v Vec3) Sub(w Vec3) Vec3 {
+	return Vec3{v.X - w.X, v.Y - w.Y, v.Z - w.Z}
+}
+
+// Scale returns v times s.
+func (v Vec3) Scale(s float64) Vec3 {
+	return Vec3{v.X * s, v.Y * s, v.Z * s}
+}
+
+func (v Vec3) Dot(w Vec3) float64 {
+	return v.X*w.X + v.Y*w.Y + v.Z*w.Z
+}
+
+// Cross returns the vector perpendicular to v and w, by the right-hand
+// rule, whose length is the area of the parallelogram they span.
+func (v Vec3) Cross(w Vec3) Vec3 {
+	return Vec3{
+		v.Y*w.Z - v.Z*w.Y,
+		v.Z*w.X - v.X*w.Z,
+		v.X*w.Y - v.Y*w.X,
+	}
+}
+
+func (v Vec3) Length() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// Distance returns the length of v - w.
+func (v Vec3) Distance(w Vec3) float64 {
+	return v.Sub(w).Length()
+}
+
+// Normalize returns v scaled to length 1, or the zero vector for the zero
+// vector.
+func (v Vec3) Normalize() Vec3 {
+
+	l := v.Length()
+	if l == 0 {
+		return Vec3{}
+	}
+
+	return Vec3{v.X / l, v.Y / l, v.Z / l}
+}
+
+// Lerp returns the point t of the way from v to w.
+func (v Vec3) Lerp(w Vec3, t float64) Vec3 {
+	return Vec3{lerp(v.X, w.X, t), lerp(v.Y, w.Y, t), lerp(v.Z, w.Z, t)}
+}
+
+// lerp is exact at both ends, which a + (b-a)*t is not at t = 1.
+func lerp(a, b, t float64) float64 {
+	return a*(1-t) + b*t
+}
@@ -0,0 +1,97 @@
+package vec_test
+
+import (
+	"math"
+	"testing"
+
+	"pacx/mazamamaths/vec"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-12
+}
+
+func TestVec2(t *testing.T) {
+
+	v, w := vec.Vec2{X: 3, Y: 4}, vec.Vec2{X: 1, Y: 2}
+
+	if got := v.Add(w); got != (vec.Vec2{X: 4, Y: 6}) {
+		t.Errorf("Expected {4 6} but got %v", got)
+	}
+	if got := v.Sub(w); got != (vec.Vec2{X: 2, Y: 2}) {
+		t.Errorf("Expected {2 2} but got %v", got)
+	}
+	if got := v.Scale(2); got != (vec.Vec2{X: 6, Y: 8}) {
+		t.Errorf("Expected {6 8} but got %v", got)
+	}
+	if got := v.Dot(w); got != 11 {
+		t.Errorf("Expected 11 but got %g", got)
+	}
+	if got := v.Length(); got != 5 {
+		t.Errorf("Expected 5 but got %g", got)
+	}
+	if got := v.Distance(w); !near(got, math.Sqrt(8)) {
+		t.Errorf("Expected √8 but got %g", got)
+	}
+
+	x, y := vec.Vec2{X: 1}, vec.Vec2{Y: 1}
+	if x.Cross(y) != 1 || y.Cross(x) != -1 || x.Cross(x.Scale(3)) != 0 {
+		t.Errorf("Expected Cross to be 1, -1 and 0 but got %g, %g and %g", x.Cross(y), y.Cross(x), x.Cross(x.Scale(3)))
+	}
+
+	if n := v.Normalize(); !near(n.Length(), 1) || !near(n.X, 0.6) || !near(n.Y, 0.8) {
+		t.Errorf("Expected {0.6 0.8} but got %v", n)
+	}
+	if n := (vec.Vec2{}).Normalize(); n != (vec.Vec2{}) {
+		t.Errorf("Expected the zero vector to stay zero but got %v", n)
+	}
+
+	if got := v.Lerp(w, 0); got != v {
+		t.Errorf("Expected v at 0 but got %v", got)
+	}
+	if got := v.Lerp(w, 1); got != w {
+		t.Errorf("Expected w at 1 but got %v", got)
+	}
+	if got := v.Lerp(w, 0.5); got != (vec.Vec2{X: 2, Y: 3}) {
+		t.Errorf("Expected {2 3} halfway but got %v", got)
+	}
+}
+
+func TestVec3(t *testing.T) {
+
+	x, y, z := vec.Vec3{X: 1}, vec.Vec3{Y: 1}, vec.Vec3{Z: 1}
+
+	if got := x.Cross(y); got != z {
+		t.Errorf("Expected x × y = z but got %v", got)
+	}
+	if got := y.Cross(x); got != z.Scale(-1) {
+		t.Errorf("Expected y × x = -z but got %v", got)
+	}
+
+	v, w := vec.Vec3{X: 1, Y: 2, Z: 3}, vec.Vec3{X: 4, Y: 5, Z: 6}
+	c := v.Cross(w)
+	if c.Dot(v) != 0 || c.Dot(w) != 0 {
+		t.Errorf("Expected %v to be perpendicular to both but got dots %g and %g", c, c.Dot(v), c.Dot(w))
+	}
+	if got := v.Dot(w); got != 32 {
+		t.Errorf("Expected 32 but got %g", got)
+	}
+	if got := v.Add(w).Sub(w); got != v {
+		t.Errorf("Expected %v back but got %v", v, got)
+	}
+	if got := (vec.Vec3{X: 2, Y: 3, Z: 6}).Length(); got != 7 {
+		t.Errorf("Expected 7 but got %g", got)
+	}
+	if got := v.Distance(v); got != 0 {
+		t.Errorf("Expected 0 but got %g", got)
+	}
+	if n := w.Normalize(); !near(n.Length(), 1) {
+		t.Errorf("Expected length 1 but got %g", n.Length())
+	}
+	if n := (vec.Vec3{}).Normalize(); n != (vec.Vec3{}) {
+		t.Errorf("Expected the zero vector to stay zero but got %v", n)
+	}
+	if got := v.Lerp(w, 2); got != (vec.Vec3{X: 7, Y: 8, Z: 9}) {
+		t.Errorf("Expected {7 8 9} past w but got %v", got)
+	}
+}
@@ -0,0 +1,88 @@
+// Command metricsdemo runs a small multi-process topology: it starts a
+// collector, launches copies of itself as workers that push their counters
+// over the Unix socket, and prints what the collector merged.
+//
+//	go run ./metrics/cmd/metricsdemo
+//	curl http://localhost:8090/   # while it runs
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"pacx/metrics"
+)
+
+func main() {
+
+	worker := flag.String("worker", "", "run as a worker pushing to this socket")
+	workers := flag.Int("n", 3, "number of worker processes")
+	flag.Parse()
+
+	if *worker != "" {
+		runWorker(*worker)
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "metricsdemo")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "metrics.sock")
+
+	c, err := metrics.Listen(context.Background(), path)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer c.Close()
+
+	go http.ListenAndServe("localhost:8090", c)
+
+	self, _ := os.Executable()
+	var procs []*exec.Cmd
+	for i := 0; i < *workers; i++ {
+		cmd := exec.Command(self, "-worker", path)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Start(); err != nil {
+			fmt.Println(err)
+			return
+		}
+		procs = append(procs, cmd)
+	}
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(time.Second)
+		m := c.Merged()
+		fmt.Printf("%d sources: jobs=%d inflight=%.0f\n", len(c.Sources()), m.Counters["jobs"], m.Gauges["inflight"])
+	}
+
+	for _, p := range procs {
+		p.Process.Kill()
+		p.Wait()
+	}
+}
+
+func runWorker(path string) {
+
+	var r metrics.Registry
+	jobs, inflight := r.Counter("jobs"), r.Gauge("inflight")
+
+	go metrics.PushEvery(context.Background(), path, 200*time.Millisecond, &r, metrics.DefaultSource())
+
+	for {
+		n := rand.Intn(5)
+		inflight.Set(float64(n))
+		time.Sleep(time.Duration(n*20) * time.Millisecond)
+		jobs.Add(int64(n))
+	}
+}
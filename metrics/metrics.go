@@ -0,0 +1,142 @@
+// Package metrics keeps named counters and gauges for a process and ships
+// snapshots of them to a collector over a Unix domain socket, so several
+// processes on one machine can be watched as one.
+package metrics
+
+import (
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pacx/strings/fmtx"
+)
+
+// Counter only goes up.
+type Counter struct{ v atomic.Int64 }
+
+// Add increments the counter by n.
+func (c *Counter) Add(n int64) { c.v.Add(n) }
+
+// Value returns the current count.
+func (c *Counter) Value() int64 { return c.v.Load() }
+
+// Gauge holds the latest value of something.
+type Gauge struct{ bits atomic.Uint64 }
+
+// Set replaces the value.
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+// Snapshot is the state of a registry at one moment.
+type Snapshot struct {
+	Source   string             `json:"source"`
+	Time     time.Time          `json:"time"`
+	Counters map[string]int64   `json:"counters"`
+	Gauges   map[string]float64 `json:"gauges"`
+}
+
+// Registry hands out metrics by name. The zero value is ready to use.
+type Registry struct {
+	mu       sync.Mutex
+	counters map[string]*Counter
+	gauges   map[string]*Gauge
+}
+
+// Counter returns the counter called name, creating it on first use.
+func (r *Registry) Counter(name string) *Counter {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.counters == nil {
+		r.counters = make(map[string]*Counter)
+	}
+	c, ok := r.counters[name]
+	if !ok {
+		c = &Counter{}
+		r.counters[name] = c
+	}
+
+	return c
+}
+
+// Gauge returns the gauge called name, creating it on first use.
+func (r *Registry) Gauge(name string) *Gauge {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.gauges == nil {
+		r.gauges = make(map[string]*Gauge)
+	}
+	g, ok := r.gauges[name]
+	if !ok {
+		g = &Gauge{}
+		r.gauges[name] = g
+	}
+
+	return g
+}
+
+// Snapshot copies the current values, labelled with source.
+func (r *Registry) Snapshot(source string) Snapshot {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := Snapshot{
+		Source:   source,
+		Time:     time.Now(),
+		Counters: make(map[string]int64, len(r.counters)),
+		Gauges:   make(map[string]float64, len(r.gauges)),
+	}
+	for name, c := range r.counters {
+		s.Counters[name] = c.Value()
+	}
+	for name, g := range r.gauges {
+		s.Gauges[name] = g.Value()
+	}
+
+	return s
+}
+
+// AppendLines appends s as text: a comment line with the source and time,
+// then a "name value" line per metric, counters and then gauges, each
+// sorted by name. It is deliberately not AppendText, which encoding/json
+// would pick up in place of the JSON wire format.
+func (s Snapshot) AppendLines(b []byte) []byte {
+
+	b = append(b, "# "...)
+	b = fmtx.AppendQuote(b, s.Source)
+	b = append(b, ' ')
+	b = s.Time.AppendFormat(b, time.RFC3339)
+	b = append(b, '\n')
+
+	for _, name := range slices.Sorted(maps.Keys(s.Counters)) {
+		b = fmtx.AppendQuote(b, name)
+		b = append(b, ' ')
+		b = fmtx.AppendInt(b, s.Counters[name])
+		b = append(b, '\n')
+	}
+	for _, name := range slices.Sorted(maps.Keys(s.Gauges)) {
+		b = fmtx.AppendQuote(b, name)
+		b = append(b, ' ')
+		b = fmtx.AppendFloat(b, s.Gauges[name])
+		b = append(b, '\n')
+	}
+
+	return b
+}
+
+// DefaultSource names this process as hostname:pid.
+func DefaultSource() string {
+	host, _ := os.Hostname()
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
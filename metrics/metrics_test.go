@@ -0,0 +1,171 @@
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pacx/metrics"
+)
+
+// socketPath returns a short path; Unix socket paths are limited to about
+// a hundred bytes, which t.TempDir can exceed.
+func socketPath(t *testing.T) string {
+
+	dir, err := os.MkdirTemp("", "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "s")
+}
+
+func eventually(t *testing.T, cond func() bool) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the condition to hold within two seconds")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+
+	var r metrics.Registry
+	r.Counter("requests").Add(2)
+	r.Counter("requests").Add(3)
+	r.Gauge("queue").Set(1.5)
+
+	s := r.Snapshot("me")
+	if s.Source != "me" || s.Counters["requests"] != 5 || s.Gauges["queue"] != 1.5 {
+		t.Errorf("Expected requests=5 queue=1.5 from me but got %+v", s)
+	}
+}
+
+func TestAppendLines(t *testing.T) {
+
+	var r metrics.Registry
+	r.Counter("jobs").Add(7)
+	r.Counter("errors").Add(1)
+	r.Gauge("queue").Set(1.5)
+
+	s := r.Snapshot("host 1")
+	s.Time = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	got := s.AppendLines(nil)
+	expected := "# \"host 1\" 2024-05-01T12:00:00Z\nerrors 1\njobs 7\nqueue 1.5\n"
+	if string(got) != expected {
+		t.Errorf("Expected %q but got %q", expected, got)
+	}
+}
+
+func TestCollectorMerges(t *testing.T) {
+
+	path := socketPath(t)
+	c, err := metrics.Listen(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	for i, source := range []string{"a", "b"} {
+		var r metrics.Registry
+		r.Counter("jobs").Add(int64(10 * (i + 1)))
+		r.Gauge("workers").Set(4)
+		if err := metrics.Push(ctx, path, r.Snapshot(source)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	eventually(t, func() bool { return len(c.Sources()) == 2 })
+
+	m := c.Merged()
+	if m.Counters["jobs"] != 30 || m.Gauges["workers"] != 8 {
+		t.Errorf("Expected jobs=30 workers=8 but got %+v", m)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	var body struct {
+		Merged  metrics.Snapshot
+		Sources []metrics.Snapshot
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Merged.Counters["jobs"] != 30 || len(body.Sources) != 2 {
+		t.Errorf("Expected the HTTP view to match but got %+v", body)
+	}
+}
+
+func TestPushEveryKeepsLatest(t *testing.T) {
+
+	path := socketPath(t)
+	c, err := metrics.Listen(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var r metrics.Registry
+	jobs := r.Counter("jobs")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- metrics.PushEvery(ctx, path, 5*time.Millisecond, &r, "worker") }()
+
+	jobs.Add(1)
+	eventually(t, func() bool { return c.Merged().Counters["jobs"] == 1 })
+	jobs.Add(41)
+	eventually(t, func() bool { return c.Merged().Counters["jobs"] == 42 })
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Expected Canceled but got %v", err)
+	}
+}
+
+func TestListenReplacesStaleSocket(t *testing.T) {
+
+	path := socketPath(t)
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.SetUnlinkOnClose(false)
+	ln.Close()
+
+	c, err := metrics.Listen(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Expected a stale socket file to be replaced but got %v", err)
+	}
+	c.Close()
+}
+
+func TestListenKeepsOtherFiles(t *testing.T) {
+
+	path := socketPath(t)
+	if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if c, err := metrics.Listen(context.Background(), path); err == nil {
+		c.Close()
+		t.Fatal("Expected a regular file to be refused but got no error")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("Expected the file to be left alone but got %q, %v", data, err)
+	}
+}
@@ -0,0 +1,241 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The wire format is one JSON encoded Snapshot per line; a pusher keeps its
+// connection open and writes a line per interval.
+
+// Push sends one snapshot to the collector listening on path.
+func Push(ctx context.Context, path string, s Snapshot) error {
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetWriteDeadline(deadline)
+	}
+
+	return json.NewEncoder(conn).Encode(s)
+}
+
+// PushEvery sends a snapshot of r to the collector every interval until ctx
+// is done, redialling whenever the collector goes away. It returns
+// ctx.Err().
+func PushEvery(ctx context.Context, path string, interval time.Duration, r *Registry, source string) error {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		conn net.Conn
+		enc  *json.Encoder
+		d    net.Dialer
+	)
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		if conn == nil {
+			if c, err := d.DialContext(ctx, "unix", path); err == nil {
+				conn, enc = c, json.NewEncoder(c)
+			}
+		}
+		if enc != nil {
+			conn.SetWriteDeadline(time.Now().Add(interval))
+			if err := enc.Encode(r.Snapshot(source)); err != nil {
+				conn.Close()
+				conn, enc = nil, nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Collector receives snapshots on a Unix socket and keeps the latest one
+// per source.
+type Collector struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	sources map[string]Snapshot
+	conns   map[net.Conn]struct{}
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// Listen creates the socket at path, replacing a stale one left behind by a
+// collector that did not shut down cleanly. Anything else at path is left
+// alone and reported as an error.
+func Listen(ctx context.Context, path string) (*Collector, error) {
+
+	switch info, err := os.Lstat(path); {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case info.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("metrics: %s exists and is not a socket", path)
+	default:
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	var lc net.ListenConfig
+	ln, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Collector{
+		ln:      ln,
+		sources: make(map[string]Snapshot),
+		conns:   make(map[net.Conn]struct{}),
+	}
+	c.wg.Add(1)
+	go c.accept()
+
+	return c, nil
+}
+
+func (c *Collector) accept() {
+
+	defer c.wg.Done()
+
+	for {
+		conn, err := c.ln.Accept()
+		if err != nil {
+			return // closed
+		}
+
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.conns[conn] = struct{}{}
+		c.wg.Add(1)
+		c.mu.Unlock()
+
+		go c.read(conn)
+	}
+}
+
+func (c *Collector) read(conn net.Conn) {
+
+	defer c.wg.Done()
+	defer func() {
+		conn.Close()
+		c.mu.Lock()
+		delete(c.conns, conn)
+		c.mu.Unlock()
+	}()
+
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		var s Snapshot
+		if err := json.Unmarshal(sc.Bytes(), &s); err != nil || s.Source == "" {
+			continue // one bad line should not cost the connection
+		}
+
+		c.mu.Lock()
+		if old, ok := c.sources[s.Source]; !ok || !s.Time.Before(old.Time) {
+			c.sources[s.Source] = s
+		}
+		c.mu.Unlock()
+	}
+}
+
+// Sources returns the latest snapshot of every source, sorted by source.
+func (c *Collector) Sources() []Snapshot {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]Snapshot, 0, len(c.sources))
+	for _, s := range c.sources {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+
+	return out
+}
+
+// Merged adds up the counters and gauges of all sources into one snapshot.
+// Its Time is that of the newest source.
+func (c *Collector) Merged() Snapshot {
+
+	m := Snapshot{
+		Source:   "merged",
+		Counters: make(map[string]int64),
+		Gauges:   make(map[string]float64),
+	}
+	for _, s := range c.Sources() {
+		for name, v := range s.Counters {
+			m.Counters[name] += v
+		}
+		for name, v := range s.Gauges {
+			m.Gauges[name] += v
+		}
+		if s.Time.After(m.Time) {
+			m.Time = s.Time
+		}
+	}
+
+	return m
+}
+
+// ServeHTTP writes the merged snapshot and the per source ones as JSON.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Merged  Snapshot   `json:"merged"`
+		Sources []Snapshot `json:"sources"`
+	}{c.Merged(), c.Sources()})
+}
+
+// Close stops listening, drops the pushers' connections and removes the
+// socket.
+func (c *Collector) Close() error {
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	err := c.ln.Close()
+	for conn := range c.conns {
+		conn.Close()
+	}
+	c.mu.Unlock()
+
+	c.wg.Wait()
+
+	return err
+}
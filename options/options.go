@@ -0,0 +1,95 @@
+// Package options is the functional options pattern with generics: every
+// constructor in the repo describes its settings as a config struct with
+// defaults, exposes Option values that set one field each, and validates
+// the result in one place.
+//
+//	type config struct{ workers int }
+//
+//	func WithWorkers(n int) options.Option[config] {
+//		return options.New("WithWorkers", func(c *config) error {
+//			if n < 1 {
+//				return errors.New("must be at least 1")
+//			}
+//			c.workers = n
+//			return nil
+//		})
+//	}
+//
+//	cfg, err := options.Build(config{workers: 4}, nil, opts...)
+package options
+
+// Option sets part of a T. Its name shows up in errors and in Names.
+type Option[T any] struct {
+	name  string
+	apply func(*T) error
+}
+
+// New returns an option called name that runs apply.
+func New[T any](name string, apply func(*T) error) Option[T] {
+	return Option[T]{name: name, apply: apply}
+}
+
+// Name returns the option's name.
+func (o Option[T]) Name() string {
+	return o.name
+}
+
+// Error is returned when an option rejects its argument.
+type Error struct {
+	Option string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return "options: " + e.Option + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Apply runs opts on cfg in order and stops at the first error. Options
+// with a nil apply func are skipped.
+func Apply[T any](cfg *T, opts ...Option[T]) error {
+
+	for _, o := range opts {
+		if o.apply == nil {
+			continue
+		}
+		if err := o.apply(cfg); err != nil {
+			return &Error{Option: o.name, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// Build applies opts to a copy of defaults and then checks the combined
+// result with validate, which may be nil. Per option checks belong in the
+// option; validate is for rules that involve several fields.
+func Build[T any](defaults T, validate func(T) error, opts ...Option[T]) (T, error) {
+
+	cfg := defaults
+	if err := Apply(&cfg, opts...); err != nil {
+		return defaults, err
+	}
+	if validate != nil {
+		if err := validate(cfg); err != nil {
+			return defaults, &Error{Option: "validate", Err: err}
+		}
+	}
+
+	return cfg, nil
+}
+
+// Names returns the names of opts in order, to log or assert what a
+// component was configured with.
+func Names[T any](opts ...Option[T]) []string {
+
+	names := make([]string, len(opts))
+	for i, o := range opts {
+		names[i] = o.name
+	}
+
+	return names
+}
@@ -0,0 +1,81 @@
+package options_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"pacx/options"
+)
+
+type config struct {
+	min, max int
+	name     string
+}
+
+func withRange(min, max int) options.Option[config] {
+	return options.New("withRange", func(c *config) error {
+		if min < 0 {
+			return errors.New("min must not be negative")
+		}
+		c.min, c.max = min, max
+		return nil
+	})
+}
+
+func withName(name string) options.Option[config] {
+	return options.New("withName", func(c *config) error {
+		c.name = name
+		return nil
+	})
+}
+
+func validate(c config) error {
+	if c.max < c.min {
+		return errors.New("max below min")
+	}
+	return nil
+}
+
+func TestBuild(t *testing.T) {
+
+	defaults := config{min: 1, max: 8, name: "default"}
+
+	got, err := options.Build(defaults, validate, withName("pool"), withRange(2, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (config{min: 2, max: 4, name: "pool"}) {
+		t.Errorf("Expected options applied but got %+v", got)
+	}
+
+	// no options gives the defaults back
+	got, _ = options.Build(defaults, validate)
+	if got != defaults {
+		t.Errorf("Expected defaults but got %+v", got)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+
+	defaults := config{min: 1, max: 8}
+
+	_, err := options.Build(defaults, validate, withRange(-1, 2))
+	var oe *options.Error
+	if !errors.As(err, &oe) || oe.Option != "withRange" {
+		t.Errorf("Expected an error from withRange but got %v", err)
+	}
+
+	_, err = options.Build(defaults, validate, withRange(5, 2))
+	if !errors.As(err, &oe) || oe.Option != "validate" {
+		t.Errorf("Expected a validate error but got %v", err)
+	}
+}
+
+func TestNames(t *testing.T) {
+
+	names := options.Names(withName("x"), withRange(0, 1))
+	if !slices.Equal(names, []string{"withName", "withRange"}) {
+		t.Errorf("Expected option names in order but got %v", names)
+	}
+}
@@ -1,9 +1,6 @@
 package main
 
 import (
-	"log"
-	"os"
-	"runtime/pprof"
 	"time"
 )
 
@@ -16,21 +13,10 @@ func allocateMemory() {
 	time.Sleep(1 * time.Second) // Keep it alive briefly
 }
 
+// go run ./Profiling/profiler/cmd/profile -heap -allocs proff/memory
 func main() {
-	// Open file to save memory profile
-	f, err := os.Create("mem.prof")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer f.Close()
-
 	// Run some work to profile
 	for i := 0; i < 5; i++ {
 		allocateMemory()
 	}
-
-	// Write heap profile to file
-	if err := pprof.WriteHeapProfile(f); err != nil {
-		log.Fatal(err)
-	}
 }
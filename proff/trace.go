@@ -1,67 +1,64 @@
 package main
 
 import (
-	"log"
-	"os"
+	"context"
 	"runtime"
-	"runtime/trace"
 	"sync"
 	"time"
+
+	"pacx/Profiling/tracing"
 )
 
-func processData(id int, wg *sync.WaitGroup, ch chan<- int, mu *sync.Mutex) {
+func processData(ctx context.Context, id int, wg *sync.WaitGroup, ch chan<- int, mu *sync.Mutex) {
 	defer wg.Done()
 
 	// Simulate CPU-intensive work with memory allocation
 	data := make([]int, 0, 10000)
-	for i := 0; i < 10000; i++ {
-		data = append(data, i*i) // Dynamic growth
-		if i%1000 == 0 {
-			// Simulate contention
-			mu.Lock()
-			time.Sleep(1 * time.Millisecond) // Contention point
-			mu.Unlock()
+	tracing.Region(ctx, "compute", func() {
+		for i := 0; i < 10000; i++ {
+			data = append(data, i*i) // Dynamic growth
+			if i%1000 == 0 {
+				// Simulate contention, a region of its own so the lock
+				// waits stand out in the user regions view
+				tracing.Region(ctx, "contention", func() {
+					mu.Lock()
+					time.Sleep(1 * time.Millisecond) // Contention point
+					mu.Unlock()
+				})
+			}
 		}
-	}
+	})
 
 	// Force GC pressure with temporary allocations
-	for j := 0; j < 100; j++ {
-		tmp := make([]int, 1000)
-		_ = tmp // Prevent optimization
-	}
+	tracing.Region(ctx, "garbage", func() {
+		for j := 0; j < 100; j++ {
+			tmp := make([]int, 1000)
+			_ = tmp // Prevent optimization
+		}
+	})
 
 	// Send result
 	ch <- len(data)
 }
 
+// go run ./Profiling/profiler/cmd/profile -trace -mutex -block proff/trace
 func main() {
-	// Create trace file
-	f, err := os.Create("trace Intensive.out")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer f.Close()
-
-	// Start tracing
-	if err := trace.Start(f); err != nil {
-		log.Fatal(err)
-	}
-	defer trace.Stop()
-
 	// Configure runtime for more visibility
-	runtime.GOMAXPROCS(4)              // Limit to 4 CPUs for scheduling pressure
-	runtime.SetMutexProfileFraction(1) // Enable mutex profiling
-	runtime.SetBlockProfileRate(1)     // Enable block profiling
+	runtime.GOMAXPROCS(4) // Limit to 4 CPUs for scheduling pressure
 
 	// Simulate heavy workload
 	ch := make(chan int, 100) // Buffered channel
 	var wg sync.WaitGroup
 	mu := &sync.Mutex{}
 
+	// one task for the whole workload: go tool trace -> User-defined tasks
+	ctx, end := tracing.WithTask(context.Background(), "workload")
+	defer end()
+
 	// Launch 1000 goroutines
 	for i := 0; i < 1000; i++ {
 		wg.Add(1)
-		go processData(i, &wg, ch, mu)
+		go processData(ctx, i, &wg, ch, mu)
 	}
 
 	// Collect results
@@ -0,0 +1,129 @@
+// Package deepcopy copies values all the way down, where maps.Clone and
+// assignment stop at the first level:
+//
+//	m1 := map[string]map[string]int{"M1": {"Key1": 10}}
+//	c := maps.Clone(m1)
+//	c["M1"]["Key1"] = 10000 // changes m1 too, see map-func.go
+//
+//	c = deepcopy.Copy(m1)
+//	c["M1"]["Key1"] = 10000 // m1 is left alone
+package deepcopy
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// Copy returns a deep copy of v. Maps, slices and what pointers and
+// interfaces hold are copied, as are the exported fields of structs, at
+// every depth. A pointer, map or slice met more than once in v is copied
+// once and shared the same way in the copy, which also makes cycles safe.
+//
+// Some things are shared rather than copied: unexported struct fields,
+// which are copied as by assignment, as they may hold pointers their
+// package compares, such as a time.Time's location; map keys; channels;
+// and funcs.
+func Copy[T any](v T) T {
+
+	c := copier{seen: make(map[seenKey]reflect.Value)}
+	src := reflect.ValueOf(&v).Elem()
+	dst := reflect.New(src.Type()).Elem()
+	c.copy(dst, src)
+
+	return *dst.Addr().Interface().(*T)
+}
+
+// seenKey identifies a pointer, map or slice already copied. The type is
+// part of it since a struct and its first field share an address, and the
+// length since slices of one array may differ in it.
+type seenKey struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+	len int
+}
+
+type copier struct {
+	seen map[seenKey]reflect.Value
+}
+
+// copy sets dst, a settable zero value of src's type, to a deep copy of
+// src.
+func (c *copier) copy(dst, src reflect.Value) {
+
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		k := seenKey{src.UnsafePointer(), src.Type(), 0}
+		if p, ok := c.seen[k]; ok {
+			dst.Set(p)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		c.seen[k] = p
+		c.copy(p.Elem(), src.Elem())
+		dst.Set(p)
+
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		k := seenKey{src.UnsafePointer(), src.Type(), 0}
+		if m, ok := c.seen[k]; ok {
+			dst.Set(m)
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c.seen[k] = m
+		for it := src.MapRange(); it.Next(); {
+			v := reflect.New(src.Type().Elem()).Elem()
+			c.copy(v, it.Value())
+			m.SetMapIndex(it.Key(), v)
+		}
+		dst.Set(m)
+
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		k := seenKey{src.UnsafePointer(), src.Type(), src.Len()}
+		if s, ok := c.seen[k]; ok {
+			dst.Set(s)
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		c.seen[k] = s
+		for i := range src.Len() {
+			c.copy(s.Index(i), src.Index(i))
+		}
+		dst.Set(s)
+
+	case reflect.Array:
+		for i := range src.Len() {
+			c.copy(dst.Index(i), src.Index(i))
+		}
+
+	case reflect.Struct:
+		// the unexported fields by assignment, then the rest again deeply
+		dst.Set(src)
+		t := src.Type()
+		for i := range t.NumField() {
+			if t.Field(i).IsExported() {
+				c.copy(dst.Field(i), src.Field(i))
+			}
+		}
+
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		e := src.Elem()
+		v := reflect.New(e.Type()).Elem()
+		c.copy(v, e)
+		dst.Set(v)
+
+	default:
+		dst.Set(src)
+	}
+}
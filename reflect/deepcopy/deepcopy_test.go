@@ -0,0 +1,166 @@
+package deepcopy_test
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+
+	"pacx/reflect/deepcopy"
+)
+
+func TestNestedMaps(t *testing.T) {
+
+	m1 := map[string]map[string]int{
+		"M1": {"Key1": 10, "Key2": 100},
+		"M2": {"Key1": 100, "Key2": 1000},
+	}
+
+	shallow := maps.Clone(m1)
+	shallow["M1"]["Key1"] = 10000
+	if m1["M1"]["Key1"] != 10000 {
+		t.Fatal("Expected maps.Clone to share the inner maps")
+	}
+
+	deep := deepcopy.Copy(m1)
+	deep["M1"]["Key1"] = 1
+	deep["M2"]["New"] = 5
+	if m1["M1"]["Key1"] != 10000 || len(m1["M2"]) != 2 {
+		t.Errorf("Expected the original left alone but got %v", m1)
+	}
+	if !reflect.DeepEqual(deepcopy.Copy(m1), m1) {
+		t.Errorf("Expected the copy to equal the original")
+	}
+}
+
+type line struct {
+	SKU  string
+	Qty  *int
+	Tags []string
+}
+
+type order struct {
+	ID      int
+	Lines   []line
+	Meta    map[string]any
+	Shipped *time.Time
+	Codes   [2][]int
+	Any     any
+	secret  *int
+}
+
+func TestStruct(t *testing.T) {
+
+	qty, hidden := 3, 9
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	o := &order{
+		ID:      1,
+		Lines:   []line{{SKU: "A", Qty: &qty, Tags: []string{"x"}}},
+		Meta:    map[string]any{"notes": []any{"a", map[string]any{"b": 1}}},
+		Shipped: &at,
+		Codes:   [2][]int{{1}, {2}},
+		Any:     &line{SKU: "B"},
+		secret:  &hidden,
+	}
+
+	c := deepcopy.Copy(o)
+	if !reflect.DeepEqual(c, o) {
+		t.Fatalf("Expected an equal copy but got %+v", c)
+	}
+	if c == o {
+		t.Fatal("Expected a new pointer")
+	}
+
+	*c.Lines[0].Qty = 4
+	c.Lines[0].Tags[0] = "y"
+	c.Meta["notes"].([]any)[1].(map[string]any)["b"] = 2
+	c.Codes[0][0] = 9
+	c.Any.(*line).SKU = "C"
+	*c.Shipped = c.Shipped.Add(time.Hour)
+
+	if qty != 3 || o.Lines[0].Tags[0] != "x" || o.Meta["notes"].([]any)[1].(map[string]any)["b"] != 1 ||
+		o.Codes[0][0] != 1 || o.Any.(*line).SKU != "B" || !o.Shipped.Equal(at) {
+		t.Errorf("Expected the original left alone but got %+v", o)
+	}
+
+	// unexported fields are shared, so a time keeps its Local location
+	if c.secret != o.secret {
+		t.Error("Expected unexported fields copied by assignment")
+	}
+	if c.Shipped.Location() != time.Local {
+		t.Errorf("Expected the time's location kept but got %v", c.Shipped.Location())
+	}
+}
+
+type node struct {
+	Name string
+	Next *node
+	Kids []*node
+}
+
+func TestCycles(t *testing.T) {
+
+	a := &node{Name: "a"}
+	b := &node{Name: "b", Next: a}
+	a.Next = b
+	a.Kids = []*node{a, b}
+
+	c := deepcopy.Copy(a)
+	if c == a || c.Next == b {
+		t.Fatal("Expected new nodes")
+	}
+	if c.Next.Next != c || c.Kids[0] != c || c.Kids[1] != c.Next {
+		t.Errorf("Expected the cycle kept in the copy")
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	mc := deepcopy.Copy(m)
+	if reflect.ValueOf(mc["self"]).UnsafePointer() != reflect.ValueOf(mc).UnsafePointer() {
+		t.Errorf("Expected a map that holds itself to be copied as one")
+	}
+}
+
+func TestSharing(t *testing.T) {
+
+	shared := []int{1, 2}
+	v := struct{ A, B []int }{shared, shared}
+
+	c := deepcopy.Copy(v)
+	c.A[0] = 5
+	if c.B[0] != 5 || shared[0] != 1 {
+		t.Errorf("Expected the slice copied once and shared in the copy but got %v and %v", c, shared)
+	}
+}
+
+func TestNil(t *testing.T) {
+
+	var o order
+	if c := deepcopy.Copy(o); !reflect.DeepEqual(c, o) || c.Lines != nil || c.Meta != nil {
+		t.Errorf("Expected nils kept but got %+v", c)
+	}
+	var e error
+	if c := deepcopy.Copy(e); c != nil {
+		t.Errorf("Expected a nil interface but got %v", c)
+	}
+	if c := deepcopy.Copy([]int{}); c == nil {
+		t.Error("Expected an empty slice, not nil")
+	}
+	if c := deepcopy.Copy(42); c != 42 {
+		t.Errorf("Expected 42 but got %d", c)
+	}
+}
+
+func BenchmarkCopy(b *testing.B) {
+
+	qty := 3
+	o := order{ID: 1, Meta: map[string]any{"a": []any{1, "b"}}}
+	for range 20 {
+		o.Lines = append(o.Lines, line{SKU: "A", Qty: &qty, Tags: []string{"x", "y"}})
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		deepcopy.Copy(&o)
+	}
+}
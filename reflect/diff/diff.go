@@ -0,0 +1,266 @@
+// Package diff reports the fields in which two structs of the same type
+// differ, for test failures that say more than "not equal" and for
+// auditing what a change did:
+//
+//	changes, err := diff.Structs(before, after)
+//	for _, c := range changes {
+//		fmt.Println(c) // Status: "pending" -> "Processing"
+//	}
+//
+// jsonx.Diff does the same for JSON documents.
+package diff
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// Change is one difference. Path is in Go terms, as in Lines[2].SKU, with
+// the fields of embedded structs named as promoted, and empty for the
+// value itself. Old is nil where b has an element or key that a has not,
+// and New where a has one that b has not.
+type Change struct {
+	Path     string
+	Old, New any
+}
+
+// String is the change on one line, as in
+//
+//	Lines[0].Qty: 1 -> 2
+//	Labels["gift"]: <nil> -> "yes"
+func (c Change) String() string {
+
+	path := c.Path
+	if path == "" {
+		path = "(root)"
+	}
+
+	return fmt.Sprintf("%s: %s -> %s", path, format(c.Old), format(c.New))
+}
+
+// Structs compares a and b, structs of the same type or pointers to them,
+// and returns their differences in field order. It follows nested
+// structs, pointers, interfaces, slices, arrays and maps, slices and
+// arrays index by index and maps key by key, in key order. Unexported
+// fields are not compared, but a struct with none exported, such as a
+// time.Time, is compared whole, with its Equal method if it has one.
+func Structs(a, b any) ([]Change, error) {
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if !va.IsValid() || !vb.IsValid() || va.Type() != vb.Type() {
+		return nil, fmt.Errorf("diff: %T and %T are different types", a, b)
+	}
+	t := va.Type()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("diff: want structs, got %T", a)
+	}
+
+	d := differ{seen: make(map[seenKey]bool)}
+	d.diff(va, vb, "")
+
+	return d.changes, nil
+}
+
+// seenKey is a pair of pointers already compared, against cycles.
+type seenKey struct {
+	a, b uintptr
+	typ  reflect.Type
+}
+
+type differ struct {
+	changes []Change
+	seen    map[seenKey]bool
+}
+
+func (d *differ) add(path string, a, b reflect.Value) {
+	d.changes = append(d.changes, Change{Path: path, Old: value(a), New: value(b)})
+}
+
+func (d *differ) diff(a, b reflect.Value, path string) {
+
+	switch a.Kind() {
+	case reflect.Pointer:
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				d.add(path, a, b)
+			}
+			return
+		}
+		k := seenKey{a.Pointer(), b.Pointer(), a.Type()}
+		if a.Pointer() == b.Pointer() || d.seen[k] {
+			return
+		}
+		d.seen[k] = true
+		d.diff(a.Elem(), b.Elem(), path)
+
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() || a.Elem().Type() != b.Elem().Type() {
+			if !a.IsNil() || !b.IsNil() {
+				d.add(path, a, b)
+			}
+			return
+		}
+		d.diff(a.Elem(), b.Elem(), path)
+
+	case reflect.Struct:
+		fields := exported(a.Type())
+		if len(fields) == 0 {
+			if !equal(a, b) {
+				d.add(path, a, b)
+			}
+			return
+		}
+		for _, f := range fields {
+			p := path
+			if !f.embedded {
+				p = join(path, f.name)
+			}
+			d.diff(a.Field(f.index), b.Field(f.index), p)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := range max(a.Len(), b.Len()) {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= b.Len():
+				d.add(p, a.Index(i), reflect.Value{})
+			case i >= a.Len():
+				d.add(p, reflect.Value{}, b.Index(i))
+			default:
+				d.diff(a.Index(i), b.Index(i), p)
+			}
+		}
+
+	case reflect.Map:
+		keys := a.MapKeys()
+		for _, k := range b.MapKeys() {
+			if !a.MapIndex(k).IsValid() {
+				keys = append(keys, k)
+			}
+		}
+		slices.SortFunc(keys, compareKeys)
+		for _, k := range keys {
+			key := fmt.Sprint(k)
+			if k.Kind() == reflect.String {
+				key = strconv.Quote(k.String())
+			}
+			p := fmt.Sprintf("%s[%s]", path, key)
+			va, vb := a.MapIndex(k), b.MapIndex(k)
+			if !va.IsValid() || !vb.IsValid() {
+				d.add(p, va, vb)
+				continue
+			}
+			d.diff(va, vb, p)
+		}
+
+	default:
+		if !equal(a, b) {
+			d.add(path, a, b)
+		}
+	}
+}
+
+// compareKeys orders map keys: numbers and strings by value, anything
+// else by how fmt prints it.
+func compareKeys(a, b reflect.Value) int {
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	}
+
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+type field struct {
+	index    int
+	name     string
+	embedded bool // its fields are named as promoted
+}
+
+// exported returns the fields of struct type t that Structs compares: the
+// exported ones and the embedded structs.
+func exported(t reflect.Type) []field {
+
+	var fields []field
+	for i := range t.NumField() {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		embedded := f.Anonymous && ft.Kind() == reflect.Struct
+		if f.IsExported() || embedded {
+			fields = append(fields, field{index: i, name: f.Name, embedded: embedded})
+		}
+	}
+
+	return fields
+}
+
+// equal compares two leaves: with an Equal method if the type has one,
+// as time.Time does, else as reflect.DeepEqual does.
+func equal(a, b reflect.Value) bool {
+
+	if !a.CanInterface() {
+		return true
+	}
+	if m := a.MethodByName("Equal"); m.IsValid() && m.Type().NumIn() == 1 && m.Type().In(0) == a.Type() &&
+		m.Type().NumOut() == 1 && m.Type().Out(0).Kind() == reflect.Bool {
+		return m.Call([]reflect.Value{b})[0].Bool()
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// value returns v as an any, nil if v is missing.
+func value(v reflect.Value) any {
+
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+// format prints a value the way Go source would write it where that is
+// short: strings quoted, and pointers by what they point to.
+func format(v any) string {
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return "<nil>"
+	}
+	if s, ok := rv.Interface().(fmt.Stringer); ok && rv.Kind() != reflect.Pointer {
+		return s.String()
+	}
+	if rv.Kind() == reflect.String {
+		return strconv.Quote(rv.String())
+	}
+
+	return fmt.Sprintf("%+v", rv.Interface())
+}
+
+func join(path, name string) string {
+
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
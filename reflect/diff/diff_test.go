@@ -0,0 +1,174 @@
+package diff_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"pacx/concurrency/orders"
+	"pacx/reflect/diff"
+)
+
+type Audit struct {
+	By string
+	At time.Time
+}
+
+type line struct {
+	SKU string
+	Qty int
+}
+
+type order struct {
+	Audit
+	ID     int
+	Lines  []line
+	Ship   *line
+	Labels map[string]string
+	Codes  [2]int
+	Any    any
+	secret string
+}
+
+func sample() order {
+	return order{
+		Audit:  Audit{By: "ops", At: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+		ID:     7,
+		Lines:  []line{{"A-1", 1}, {"B-2", 2}},
+		Ship:   &line{"C-3", 3},
+		Labels: map[string]string{"gift": "yes", "rush": "no"},
+		Codes:  [2]int{1, 2},
+		Any:    1,
+		secret: "a",
+	}
+}
+
+func render(changes []diff.Change) []string {
+
+	var out []string
+	for _, c := range changes {
+		out = append(out, c.String())
+	}
+
+	return out
+}
+
+func TestStructs(t *testing.T) {
+
+	a, b := sample(), sample()
+	b.By = "bot"
+	b.At = a.At.In(time.FixedZone("IST", 5*3600+1800)) // the same instant
+	b.Lines = append(b.Lines[:1:1], line{"B-2", 5}, line{"D-4", 1})
+	b.Ship = nil
+	b.Labels = map[string]string{"gift": "no", "wrap": "yes"}
+	b.Codes[1] = 3
+	b.Any = "1"
+	b.secret = "b"
+
+	changes, err := diff.Structs(a, &b)
+	if err == nil {
+		t.Fatalf("Expected an error for a struct and a pointer but got %v", changes)
+	}
+	changes, err = diff.Structs(&a, &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		`By: "ops" -> "bot"`,
+		`Lines[1].Qty: 2 -> 5`,
+		`Lines[2]: <nil> -> {SKU:D-4 Qty:1}`,
+		`Ship: {SKU:C-3 Qty:3} -> <nil>`,
+		`Labels["gift"]: "yes" -> "no"`,
+		`Labels["rush"]: "no" -> <nil>`,
+		`Labels["wrap"]: <nil> -> "yes"`,
+		`Codes[1]: 2 -> 3`,
+		`Any: 1 -> "1"`,
+	}
+	if got := render(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%q\nbut got\n%q", want, got)
+	}
+	if changes[3].Old.(*line).SKU != "C-3" || changes[3].New.(*line) != nil {
+		t.Errorf("Expected the old and new values kept but got %#v", changes[3])
+	}
+}
+
+func TestSame(t *testing.T) {
+
+	a, b := sample(), sample()
+	b.Lines = nil
+	a.Lines = []line{}
+	changes, err := diff.Structs(a, b)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("Expected nil and empty slices to be the same but got %v, %v", changes, err)
+	}
+
+	if _, err := diff.Structs(1, 2); err == nil {
+		t.Error("Expected an error for values that are not structs")
+	}
+	if _, err := diff.Structs(nil, nil); err == nil {
+		t.Error("Expected an error for nils")
+	}
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestCycle(t *testing.T) {
+
+	a := &node{Name: "a"}
+	a.Next = &node{Name: "b", Next: a}
+	b := &node{Name: "a"}
+	b.Next = &node{Name: "c", Next: b}
+
+	changes, err := diff.Structs(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := render(changes); !reflect.DeepEqual(got, []string{`Next.Name: "b" -> "c"`}) {
+		t.Errorf("Expected one change but got %q", got)
+	}
+}
+
+func TestMapKeyOrder(t *testing.T) {
+
+	type counts struct{ M map[int]int }
+	a := counts{M: map[int]int{2: 1, 10: 1, -1: 1}}
+	b := counts{M: map[int]int{2: 2, 10: 2, -1: 2}}
+
+	changes, err := diff.Structs(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`M[-1]: 1 -> 2`, `M[2]: 1 -> 2`, `M[10]: 1 -> 2`}
+	if got := render(changes); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%q\nbut got\n%q", want, got)
+	}
+}
+
+func TestOrderStatus(t *testing.T) {
+
+	before := orders.Order{ID: 1, Status: orders.Pending}
+	after := before
+	after.Status = orders.Shipped
+
+	changes, _ := diff.Structs(before, after)
+	if len(changes) != 1 || changes[0] != (diff.Change{Path: "Status", Old: orders.Pending, New: orders.Shipped}) {
+		t.Errorf("Expected the status change but got %v", changes)
+	}
+}
+
+func BenchmarkStructs(b *testing.B) {
+
+	x, y := sample(), sample()
+	y.Lines[1].Qty = 9
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := diff.Structs(&x, &y); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+// Package dispatch calls methods by name with JSON arguments, the core of
+// an RPC server:
+//
+//	type Orders struct{ ... }
+//	func (o *Orders) Get(ctx context.Context, id int) (Order, error)
+//
+//	d := dispatch.New()
+//	d.Register("", &Orders{})
+//	out, err := d.Call(ctx, "Orders.Get", []byte(`[7]`)) // {"id":7,...}
+//
+// A method can be called if it is exported and its signature is one of
+//
+//	func(args...)
+//	func(args...) error
+//	func(args...) R
+//	func(args...) (R, error)
+//
+// where a first argument of type context.Context gets Call's context and
+// the others, and R, can be converted to and from JSON. Register checks
+// every exported method of a receiver up front, so a signature that will
+// not work is an error then and not when it is first called.
+package dispatch
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrNoMethod is returned for a name that no registered method has.
+var ErrNoMethod = errors.New("dispatch: no such method")
+
+// ArgError is an argument that does not fit its parameter.
+type ArgError struct {
+	Method string
+	Index  int // -1 for the arguments as a whole
+	Err    error
+}
+
+func (e *ArgError) Error() string {
+
+	if e.Index < 0 {
+		return fmt.Sprintf("dispatch: %s: arguments: %v", e.Method, e.Err)
+	}
+
+	return fmt.Sprintf("dispatch: %s: argument %d: %v", e.Method, e.Index, e.Err)
+}
+
+func (e *ArgError) Unwrap() error {
+	return e.Err
+}
+
+// Dispatcher holds registered receivers and calls their methods. It is
+// safe for concurrent use.
+type Dispatcher struct {
+	mu      sync.RWMutex
+	methods map[string]*method // by Receiver.Method
+}
+
+type method struct {
+	fn       reflect.Value // bound to its receiver
+	in       []reflect.Type
+	ctx      bool // the first parameter is a context.Context
+	result   bool
+	errorOut bool
+}
+
+// New returns an empty Dispatcher.
+func New() *Dispatcher {
+	return &Dispatcher{methods: make(map[string]*method)}
+}
+
+var (
+	contextType       = reflect.TypeFor[context.Context]()
+	errorType         = reflect.TypeFor[error]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+	// types that encode or decode themselves, whatever they are made of
+	marshalerTypes = []reflect.Type{
+		reflect.TypeFor[json.Marshaler](),
+		reflect.TypeFor[json.Unmarshaler](),
+		textMarshalerType,
+		reflect.TypeFor[encoding.TextUnmarshaler](),
+	}
+)
+
+// Register makes the exported methods of rcvr callable as name.Method. An
+// empty name is the name of rcvr's type. It fails, registering nothing,
+// if rcvr has no exported methods, if one cannot be called, or if name is
+// taken.
+func (d *Dispatcher) Register(name string, rcvr any) error {
+
+	v := reflect.ValueOf(rcvr)
+	if !v.IsValid() {
+		return errors.New("dispatch: nil receiver")
+	}
+	t := v.Type()
+	if name == "" {
+		name = reflect.Indirect(v).Type().Name()
+		if name == "" {
+			return fmt.Errorf("dispatch: %v has no name, give one", t)
+		}
+	}
+	if t.NumMethod() == 0 {
+		return fmt.Errorf("dispatch: %v has no exported methods", t)
+	}
+
+	methods := make(map[string]*method, t.NumMethod())
+	var errs []error
+	for i := range t.NumMethod() {
+		m := t.Method(i)
+		full := name + "." + m.Name
+		mt, err := newMethod(v.Method(i))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dispatch: %s: %w", full, err))
+			continue
+		}
+		methods[full] = mt
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for full := range d.methods {
+		if strings.HasPrefix(full, name+".") {
+			return fmt.Errorf("dispatch: %s is already registered", name)
+		}
+	}
+	maps.Copy(d.methods, methods)
+
+	return nil
+}
+
+// newMethod checks the signature of fn, a method bound to its receiver.
+func newMethod(fn reflect.Value) (*method, error) {
+
+	t := fn.Type()
+	m := &method{fn: fn}
+	if t.IsVariadic() {
+		return nil, errors.New("variadic methods are not supported")
+	}
+	for i := range t.NumIn() {
+		in := t.In(i)
+		if in == contextType {
+			if i > 0 {
+				return nil, fmt.Errorf("parameter %d: a context.Context must come first", i)
+			}
+			m.ctx = true
+			continue
+		}
+		if !jsonable(in, make(map[reflect.Type]bool)) {
+			return nil, fmt.Errorf("parameter %d of type %v cannot be JSON", i, in)
+		}
+		m.in = append(m.in, in)
+	}
+
+	switch {
+	case t.NumOut() == 0:
+	case t.NumOut() == 1 && t.Out(0) == errorType:
+		m.errorOut = true
+	case t.NumOut() == 1:
+		m.result = true
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+		m.result, m.errorOut = true, true
+	default:
+		return nil, fmt.Errorf("results %v must be at most a value and an error", t)
+	}
+	if m.result && !jsonable(t.Out(0), make(map[reflect.Type]bool)) {
+		return nil, fmt.Errorf("result of type %v cannot be JSON", t.Out(0))
+	}
+
+	return m, nil
+}
+
+// jsonable reports whether values of t can go through encoding/json.
+// checking holds the types on the way down to t, so that a type made of
+// itself, such as map[string]Tree, is not checked again inside itself.
+func jsonable(t reflect.Type, checking map[reflect.Type]bool) bool {
+
+	if checking[t] {
+		return true
+	}
+	checking[t] = true
+	defer delete(checking, t)
+
+	for _, m := range marshalerTypes {
+		if t.Implements(m) || reflect.PointerTo(t).Implements(m) {
+			return true
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return false
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return jsonable(t.Elem(), checking)
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !t.Key().Implements(textMarshalerType) {
+				return false
+			}
+		}
+		return jsonable(t.Elem(), checking)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if f.Tag.Get("json") == "-" {
+				continue
+			}
+			// encoding/json skips unexported fields but for embedded structs,
+			// whose exported fields it promotes
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if !f.IsExported() && !(f.Anonymous && ft.Kind() == reflect.Struct) {
+				continue
+			}
+			if !jsonable(f.Type, checking) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Methods returns the names of the methods that can be called, sorted.
+func (d *Dispatcher) Methods() []string {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(d.methods))
+}
+
+// Call calls the method called name, as in "Orders.Get", with args, a JSON
+// array of its arguments after any context; null or empty is no
+// arguments. It returns the method's result as JSON, null if it has none,
+// and its error as it is. A panic in the method is returned as an error.
+func (d *Dispatcher) Call(ctx context.Context, name string, args []byte) (result []byte, err error) {
+
+	d.mu.RLock()
+	m, ok := d.methods[name]
+	d.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrNoMethod, name)
+	}
+
+	var raw []json.RawMessage
+	if args := bytes.TrimSpace(args); len(args) > 0 {
+		if err := json.Unmarshal(args, &raw); err != nil {
+			return nil, &ArgError{Method: name, Index: -1, Err: err}
+		}
+	}
+	if len(raw) != len(m.in) {
+		return nil, &ArgError{Method: name, Index: -1, Err: fmt.Errorf("want %d, got %d", len(m.in), len(raw))}
+	}
+
+	in := make([]reflect.Value, 0, len(m.in)+1)
+	if m.ctx {
+		in = append(in, reflect.ValueOf(&ctx).Elem())
+	}
+	for i, t := range m.in {
+		p := reflect.New(t)
+		if err := json.Unmarshal(raw[i], p.Interface()); err != nil {
+			return nil, &ArgError{Method: name, Index: i, Err: err}
+		}
+		in = append(in, p.Elem())
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("dispatch: %s panicked: %v", name, r)
+		}
+	}()
+	out := m.fn.Call(in)
+
+	if m.errorOut {
+		if e := out[len(out)-1]; !e.IsNil() {
+			return nil, e.Interface().(error)
+		}
+	}
+	if !m.result {
+		return []byte("null"), nil
+	}
+	if result, err = json.Marshal(out[0].Interface()); err != nil {
+		return nil, fmt.Errorf("dispatch: %s: result: %w", name, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,216 @@
+package dispatch_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"pacx/concurrency/orders"
+	"pacx/reflect/dispatch"
+)
+
+var errNotFound = errors.New("no such order")
+
+// Orders is what an RPC server might serve.
+type Orders struct {
+	byID map[int]orders.Order
+}
+
+func (o *Orders) Get(ctx context.Context, id int) (orders.Order, error) {
+
+	if err := ctx.Err(); err != nil {
+		return orders.Order{}, err
+	}
+	order, ok := o.byID[id]
+	if !ok {
+		return orders.Order{}, errNotFound
+	}
+
+	return order, nil
+}
+
+func (o *Orders) Put(order orders.Order) error {
+	o.byID[order.ID] = order
+	return nil
+}
+
+func (o *Orders) Count() int {
+	return len(o.byID)
+}
+
+func (o *Orders) Reset() {
+	clear(o.byID)
+}
+
+func (o *Orders) Find(statuses []string, limit *int) []int {
+
+	var ids []int
+	for id, order := range o.byID {
+		if slices.Contains(statuses, order.Status) && (limit == nil || len(ids) < *limit) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	return ids
+}
+
+func (o *Orders) Crash() {
+	panic("boom")
+}
+
+func newDispatcher(t *testing.T) *dispatch.Dispatcher {
+
+	t.Helper()
+
+	d := dispatch.New()
+	if err := d.Register("", &Orders{byID: map[int]orders.Order{1: {ID: 1, Status: orders.Pending}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	return d
+}
+
+func TestCall(t *testing.T) {
+
+	d := newDispatcher(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		method, args, want string
+	}{
+		{"Orders.Get", `[1]`, `{"id":1,"status":"pending"}`},
+		{"Orders.Put", `[{"id":2,"status":"Shipped"}]`, `null`},
+		{"Orders.Count", ``, `2`},
+		{"Orders.Count", `null`, `2`},
+		{"Orders.Find", `[["pending","Shipped"], null]`, `[1,2]`},
+		{"Orders.Find", ` [["Shipped"], 0] `, `null`},
+		{"Orders.Reset", `[]`, `null`},
+		{"Orders.Count", `[]`, `0`},
+	}
+
+	for _, tt := range tests {
+		got, err := d.Call(ctx, tt.method, []byte(tt.args))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("Expected %s(%s) to be %s but got %s, %v", tt.method, tt.args, tt.want, got, err)
+		}
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+
+	d := newDispatcher(t)
+	ctx := context.Background()
+
+	if _, err := d.Call(ctx, "Orders.Get", []byte(`[9]`)); err != errNotFound {
+		t.Errorf("Expected the method's error as it is but got %v", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := d.Call(cancelled, "Orders.Get", []byte(`[1]`)); err != context.Canceled {
+		t.Errorf("Expected the context passed on but got %v", err)
+	}
+	if _, err := d.Call(ctx, "Orders.Delete", nil); !errors.Is(err, dispatch.ErrNoMethod) {
+		t.Errorf("Expected ErrNoMethod but got %v", err)
+	}
+	if _, err := d.Call(ctx, "Orders.Crash", nil); err == nil || !strings.Contains(err.Error(), "panicked: boom") {
+		t.Errorf("Expected the panic as an error but got %v", err)
+	}
+
+	for _, tt := range []struct {
+		args  string
+		index int
+	}{
+		{`{"id":1}`, -1},
+		{`[1, 2]`, -1},
+		{``, -1},
+		{`["one"]`, 0},
+	} {
+		_, err := d.Call(ctx, "Orders.Get", []byte(tt.args))
+		var aerr *dispatch.ArgError
+		if !errors.As(err, &aerr) || aerr.Index != tt.index || aerr.Method != "Orders.Get" {
+			t.Errorf("Expected an ArgError for argument %d of %s but got %v", tt.index, tt.args, err)
+		}
+	}
+}
+
+type bad struct{}
+
+type tree map[string]tree
+
+func (bad) Ok() string                   { return "" }
+func (bad) Chan(chan int)                {}
+func (bad) Results() (int, int)          { return 0, 0 }
+func (bad) Func() func()                 { return nil }
+func (bad) Variadic(...int)              {}
+func (bad) NotLast() (error, int)        { return nil, 0 }
+func (bad) Keys(map[[2]int]string)       {}
+func (bad) Ctx(int, context.Context)     {}
+func (bad) Fine(map[int]string) []byte   { return nil }
+func (bad) Tree(tree) []tree             { return nil }
+func (bad) Struct() struct{ C chan int } { return struct{ C chan int }{} }
+func (bad) Skipped(skipped)              {}
+
+// skipped has fields encoding/json leaves out.
+type skipped struct {
+	C chan int `json:"-"`
+	c chan int
+	N int
+}
+
+func TestRegister(t *testing.T) {
+
+	d := dispatch.New()
+	err := d.Register("bad", bad{})
+	if err == nil {
+		t.Fatal("Expected the bad signatures rejected")
+	}
+	for _, m := range []string{"Chan", "Results", "Func", "Variadic", "NotLast", "Keys", "Ctx", "Struct"} {
+		if !strings.Contains(err.Error(), "bad."+m+":") {
+			t.Errorf("Expected %s named in %v", m, err)
+		}
+	}
+	for _, m := range []string{"Ok", "Fine", "Tree", "Skipped"} {
+		if strings.Contains(err.Error(), "bad."+m+":") {
+			t.Errorf("Expected %s accepted but got %v", m, err)
+		}
+	}
+	if len(d.Methods()) != 0 {
+		t.Errorf("Expected nothing registered but got %v", d.Methods())
+	}
+
+	d = newDispatcher(t)
+	want := []string{"Orders.Count", "Orders.Crash", "Orders.Find", "Orders.Get", "Orders.Put", "Orders.Reset"}
+	if got := d.Methods(); !slices.Equal(got, want) {
+		t.Errorf("Expected %v but got %v", want, got)
+	}
+	if err := d.Register("Orders", &Orders{}); err == nil {
+		t.Error("Expected an error for a name taken")
+	}
+	if err := d.Register("", struct{}{}); err == nil {
+		t.Error("Expected an error for a receiver without a name")
+	}
+	if err := d.Register("x", 42); err == nil {
+		t.Error("Expected an error for a receiver without methods")
+	}
+	if err := d.Register("x", nil); err == nil {
+		t.Error("Expected an error for nil")
+	}
+}
+
+func BenchmarkCall(b *testing.B) {
+
+	d := dispatch.New()
+	d.Register("", &Orders{byID: map[int]orders.Order{1: {ID: 1, Status: orders.Pending}}})
+	ctx := context.Background()
+	args := []byte(`[1]`)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := d.Call(ctx, "Orders.Get", args); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,437 @@
+// Package dump prints values, not only their types as reflect/main.go's
+// checker does, one field or element per line in Go syntax:
+//
+//	dump.Print(order)
+//
+//	main.Order{
+//	  ID: 7,
+//	  Status: "pending",
+//	  Lines: []main.Line{
+//	    main.Line{
+//	      SKU: "A-1",
+//	      Qty: 2,
+//	    },
+//	  },
+//	  Created: time.Time(2026-01-02 03:04:05 +0000 UTC),
+//	}
+//
+// Values of named types that are not structs with fields to show, such as
+// a time.Time or time.Duration, print with their String method if they
+// have one. A pointer, map or slice met again within itself prints as
+// <cycle>.
+package dump
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pacx/options"
+)
+
+type config struct {
+	maxDepth   int // 0 for no limit
+	unexported bool
+	color      *bool // nil to colour terminals
+	indent     string
+}
+
+// Option configures Fprint.
+type Option = options.Option[config]
+
+// WithMaxDepth prints values nested deeper than n as Type{...}. There is
+// no limit by default.
+func WithMaxDepth(n int) Option {
+	return options.New("WithMaxDepth", func(c *config) error {
+		if n < 1 {
+			return errors.New("max depth must be at least 1")
+		}
+		c.maxDepth = n
+		return nil
+	})
+}
+
+// WithUnexported prints unexported struct fields too.
+func WithUnexported() Option {
+	return options.New("WithUnexported", func(c *config) error {
+		c.unexported = true
+		return nil
+	})
+}
+
+// WithColor turns ANSI colours on or off. By default they are on when
+// writing to a terminal, unless NO_COLOR is set or TERM is dumb.
+func WithColor(on bool) Option {
+	return options.New("WithColor", func(c *config) error {
+		c.color = &on
+		return nil
+	})
+}
+
+// WithIndent sets what each level is indented by. Defaults to two spaces.
+func WithIndent(indent string) Option {
+	return options.New("WithIndent", func(c *config) error {
+		if strings.Trim(indent, " \t") != "" {
+			return errors.New("indent must be spaces or tabs")
+		}
+		c.indent = indent
+		return nil
+	})
+}
+
+// Print writes v to standard output, followed by a newline.
+func Print(v any, opts ...Option) error {
+	return Fprint(os.Stdout, v, opts...)
+}
+
+// Fprint writes v to w, followed by a newline.
+func Fprint(w io.Writer, v any, opts ...Option) error {
+
+	cfg, err := options.Build(config{indent: "  "}, nil, opts...)
+	if err != nil {
+		return err
+	}
+	color := isTerminal(w)
+	if cfg.color != nil {
+		color = *cfg.color
+	}
+
+	bw := bufio.NewWriter(w)
+	p := printer{w: bw, cfg: cfg, color: color, path: make(map[seenKey]bool)}
+	p.value(reflect.ValueOf(v), 0)
+	bw.WriteByte('\n')
+
+	return bw.Flush()
+}
+
+// isTerminal reports whether w is a terminal colours should be used on.
+func isTerminal(w io.Writer) bool {
+
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	st, err := f.Stat()
+
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+// The colours of the parts of a value.
+const (
+	colorType    = "\x1b[36m" // cyan
+	colorString  = "\x1b[32m" // green
+	colorNumber  = "\x1b[33m" // yellow
+	colorKeyword = "\x1b[35m" // magenta: nil, true, false
+	colorNote    = "\x1b[2m"  // dim: <cycle>, {...}
+	colorReset   = "\x1b[0m"
+)
+
+// seenKey is a pointer, map or slice being printed, against cycles.
+type seenKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type printer struct {
+	w     *bufio.Writer
+	cfg   config
+	color bool
+	path  map[seenKey]bool // on the way from the root to here
+}
+
+func (p *printer) write(color, s string) {
+
+	if p.color && color != "" {
+		p.w.WriteString(color)
+		p.w.WriteString(s)
+		p.w.WriteString(colorReset)
+		return
+	}
+
+	p.w.WriteString(s)
+}
+
+func (p *printer) newline(depth int) {
+
+	p.w.WriteByte('\n')
+	for range depth {
+		p.w.WriteString(p.cfg.indent)
+	}
+}
+
+var (
+	stringerType = reflect.TypeFor[fmt.Stringer]()
+	errorType    = reflect.TypeFor[error]()
+)
+
+func (p *printer) value(v reflect.Value, depth int) {
+
+	if !v.IsValid() {
+		p.write(colorKeyword, "nil")
+		return
+	}
+	t := v.Type()
+
+	if s, ok := p.stringer(v); ok {
+		p.write(colorType, t.String())
+		p.w.WriteByte('(')
+		p.write(colorString, s)
+		p.w.WriteByte(')')
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			p.write(colorKeyword, "nil")
+			return
+		}
+		if !p.enter(v) {
+			return
+		}
+		defer p.leave(v)
+		p.w.WriteByte('&')
+		p.value(v.Elem(), depth)
+
+	case reflect.Interface:
+		p.value(v.Elem(), depth)
+
+	case reflect.Struct:
+		fields := p.fields(t)
+		p.write(colorType, t.String())
+		if len(fields) == 0 {
+			p.w.WriteString("{}")
+			return
+		}
+		if p.tooDeep(depth) {
+			return
+		}
+		p.w.WriteByte('{')
+		for _, i := range fields {
+			p.newline(depth + 1)
+			p.w.WriteString(t.Field(i).Name)
+			p.w.WriteString(": ")
+			p.value(v.Field(i), depth+1)
+			p.w.WriteByte(',')
+		}
+		p.newline(depth)
+		p.w.WriteByte('}')
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			p.write(colorKeyword, "nil")
+			return
+		}
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			if !p.enter(v) {
+				return
+			}
+			defer p.leave(v)
+		}
+		p.write(colorType, t.String())
+		if t.Elem().Kind() == reflect.Uint8 && v.Kind() == reflect.Slice {
+			p.w.WriteByte('(')
+			p.write(colorString, strconv.Quote(string(v.Bytes())))
+			p.w.WriteByte(')')
+			return
+		}
+		if v.Len() == 0 {
+			p.w.WriteString("{}")
+			return
+		}
+		if p.tooDeep(depth) {
+			return
+		}
+		p.w.WriteByte('{')
+		for i := range v.Len() {
+			p.newline(depth + 1)
+			p.value(v.Index(i), depth+1)
+			p.w.WriteByte(',')
+		}
+		p.newline(depth)
+		p.w.WriteByte('}')
+
+	case reflect.Map:
+		if v.IsNil() {
+			p.write(colorKeyword, "nil")
+			return
+		}
+		if !p.enter(v) {
+			return
+		}
+		defer p.leave(v)
+		p.write(colorType, t.String())
+		if v.Len() == 0 {
+			p.w.WriteString("{}")
+			return
+		}
+		if p.tooDeep(depth) {
+			return
+		}
+		keys := v.MapKeys()
+		slices.SortFunc(keys, compareKeys)
+		p.w.WriteByte('{')
+		for _, k := range keys {
+			p.newline(depth + 1)
+			p.value(k, depth+1)
+			p.w.WriteString(": ")
+			p.value(v.MapIndex(k), depth+1)
+			p.w.WriteByte(',')
+		}
+		p.newline(depth)
+		p.w.WriteByte('}')
+
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			p.write(colorKeyword, "nil")
+			return
+		}
+		p.write(colorType, t.String())
+		p.w.WriteByte('(')
+		if v.Kind() == reflect.Chan {
+			p.write(colorNote, fmt.Sprintf("len %d, cap %d", v.Len(), v.Cap()))
+		} else {
+			p.write(colorNumber, fmt.Sprintf("%#x", v.Pointer()))
+		}
+		p.w.WriteByte(')')
+
+	default:
+		p.basic(v)
+	}
+}
+
+// basic prints a bool, number or string, wrapped in its type if that is
+// a named one.
+func (p *printer) basic(v reflect.Value) {
+
+	var s, color string
+	switch v.Kind() {
+	case reflect.Bool:
+		s, color = strconv.FormatBool(v.Bool()), colorKeyword
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s, color = strconv.FormatInt(v.Int(), 10), colorNumber
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s, color = strconv.FormatUint(v.Uint(), 10), colorNumber
+	case reflect.Uintptr:
+		s, color = fmt.Sprintf("%#x", v.Uint()), colorNumber
+	case reflect.Float32, reflect.Float64:
+		s, color = strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), colorNumber
+	case reflect.Complex64, reflect.Complex128:
+		s, color = strconv.FormatComplex(v.Complex(), 'g', -1, v.Type().Bits()), colorNumber
+	case reflect.String:
+		s, color = strconv.Quote(v.String()), colorString
+	}
+
+	t := v.Type()
+	if t.PkgPath() == "" {
+		p.write(color, s)
+		return
+	}
+	p.write(colorType, t.String())
+	p.w.WriteByte('(')
+	p.write(color, s)
+	p.w.WriteByte(')')
+}
+
+// stringer returns what v's String or Error method says, for values that
+// would otherwise print nothing useful: of named types other than
+// containers and structs with fields to show, and pointers to structs
+// without any, as most errors are.
+func (p *printer) stringer(v reflect.Value) (string, bool) {
+
+	t := v.Type()
+	if !v.CanInterface() {
+		return "", false
+	}
+	switch {
+	case t.Kind() == reflect.Pointer:
+		if v.IsNil() || t.Elem().Kind() != reflect.Struct || len(p.fields(t.Elem())) > 0 {
+			return "", false
+		}
+	case t.Name() == "":
+		return "", false
+	case t.Kind() == reflect.Struct:
+		if len(p.fields(t)) > 0 {
+			return "", false
+		}
+	case t.Kind() == reflect.Interface, t.Kind() == reflect.Slice, t.Kind() == reflect.Array, t.Kind() == reflect.Map:
+		return "", false
+	}
+
+	switch {
+	case t.Implements(errorType):
+		return v.Interface().(error).Error(), true
+	case t.Implements(stringerType):
+		return v.Interface().(fmt.Stringer).String(), true
+	}
+
+	return "", false
+}
+
+// fields returns the indexes of the fields of struct type t to print.
+func (p *printer) fields(t reflect.Type) []int {
+
+	var fields []int
+	for i := range t.NumField() {
+		if p.cfg.unexported || t.Field(i).IsExported() {
+			fields = append(fields, i)
+		}
+	}
+
+	return fields
+}
+
+// enter marks v as being printed, or prints <cycle> and reports false if
+// it already is.
+func (p *printer) enter(v reflect.Value) bool {
+
+	k := seenKey{v.Pointer(), v.Type()}
+	if p.path[k] {
+		p.write(colorNote, "<cycle>")
+		return false
+	}
+	p.path[k] = true
+
+	return true
+}
+
+func (p *printer) leave(v reflect.Value) {
+	delete(p.path, seenKey{v.Pointer(), v.Type()})
+}
+
+// tooDeep prints {...} and reports true if the contents of a value at
+// depth are past the limit.
+func (p *printer) tooDeep(depth int) bool {
+
+	if p.cfg.maxDepth == 0 || depth < p.cfg.maxDepth {
+		return false
+	}
+	p.write(colorNote, "{...}")
+
+	return true
+}
+
+// compareKeys orders map keys: numbers and strings by value, anything
+// else by how fmt prints it.
+func compareKeys(a, b reflect.Value) int {
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	}
+
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
@@ -0,0 +1,243 @@
+package dump_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/options"
+	"pacx/reflect/dump"
+)
+
+type priority string
+
+type Audit struct {
+	By string
+}
+
+type line struct {
+	SKU string
+	Qty int
+}
+
+type order struct {
+	Audit
+	ID       int
+	Priority priority
+	Lines    []line
+	Ship     *line
+	Bill     *line
+	Labels   map[string]int
+	Any      any
+	Data     []byte
+	Created  time.Time
+	Wait     time.Duration
+	Err      error
+	Empty    []int
+	secret   string
+}
+
+func sprint(t *testing.T, v any, opts ...dump.Option) string {
+
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := dump.Fprint(&b, v, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func TestFprint(t *testing.T) {
+
+	o := &order{
+		Audit:    Audit{By: "ops"},
+		ID:       7,
+		Priority: "high",
+		Lines:    []line{{"A-1", 2}},
+		Ship:     &line{"B-2", 1},
+		Labels:   map[string]int{"b": 2, "a": 1},
+		Any:      3.5,
+		Data:     []byte("hi\n"),
+		Created:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Wait:     1500 * time.Millisecond,
+		Err:      errors.New("boom"),
+		Empty:    []int{},
+		secret:   "hidden",
+	}
+
+	want := `&dump_test.order{
+  Audit: dump_test.Audit{
+    By: "ops",
+  },
+  ID: 7,
+  Priority: dump_test.priority("high"),
+  Lines: []dump_test.line{
+    dump_test.line{
+      SKU: "A-1",
+      Qty: 2,
+    },
+  },
+  Ship: &dump_test.line{
+    SKU: "B-2",
+    Qty: 1,
+  },
+  Bill: nil,
+  Labels: map[string]int{
+    "a": 1,
+    "b": 2,
+  },
+  Any: 3.5,
+  Data: []uint8("hi\n"),
+  Created: time.Time(2026-01-02 03:04:05 +0000 UTC),
+  Wait: time.Duration(1.5s),
+  Err: *errors.errorString(boom),
+  Empty: []int{},
+}
+`
+	if got := sprint(t, o, dump.WithColor(false)); got != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, got)
+	}
+}
+
+func TestUnexported(t *testing.T) {
+
+	v := struct {
+		Name   string
+		secret string
+		at     time.Duration
+	}{"a", "b", time.Second}
+
+	if got := sprint(t, v); strings.Contains(got, "secret:") {
+		t.Errorf("Expected unexported fields left out but got\n%s", got)
+	}
+	got := sprint(t, v, dump.WithUnexported())
+	for _, want := range []string{`secret: "b",`, `at: time.Duration(1000000000),`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s but got\n%s", want, got)
+		}
+	}
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestCycle(t *testing.T) {
+
+	a := &node{Name: "a"}
+	a.Next = &node{Name: "b", Next: a}
+
+	want := `&dump_test.node{
+  Name: "a",
+  Next: &dump_test.node{
+    Name: "b",
+    Next: <cycle>,
+  },
+}
+`
+	if got := sprint(t, a); got != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, got)
+	}
+
+	m := map[string]any{}
+	m["self"] = m
+	if got := sprint(t, m); got != "map[string]interface {}{\n  \"self\": <cycle>,\n}\n" {
+		t.Errorf("Expected a map that holds itself cut short but got\n%s", got)
+	}
+
+	// the same pointer twice side by side is not a cycle
+	shared := &line{"A", 1}
+	if got := sprint(t, []*line{shared, shared}); strings.Contains(got, "cycle") {
+		t.Errorf("Expected a shared pointer printed twice but got\n%s", got)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+
+	v := map[int][]line{2: {{"B", 2}}, 10: nil, 1: {}}
+
+	want := `map[int][]dump_test.line{
+  1: []dump_test.line{},
+  2: []dump_test.line{
+    dump_test.line{...},
+  },
+  10: nil,
+}
+`
+	if got := sprint(t, v, dump.WithMaxDepth(2), dump.WithIndent("  ")); got != want {
+		t.Errorf("Expected\n%s\nbut got\n%s", want, got)
+	}
+	if got := sprint(t, v, dump.WithMaxDepth(1)); !strings.Contains(got, "2: []dump_test.line{...},") {
+		t.Errorf("Expected the lines cut short but got\n%s", got)
+	}
+}
+
+func TestColor(t *testing.T) {
+
+	got := sprint(t, line{"A", 1}, dump.WithColor(true))
+	for _, want := range []string{"\x1b[36mdump_test.line\x1b[0m", "\x1b[32m\"A\"\x1b[0m", "\x1b[33m1\x1b[0m"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in %q", want, got)
+		}
+	}
+	if got := sprint(t, line{"A", 1}); strings.Contains(got, "\x1b") {
+		t.Errorf("Expected no colours when not writing to a terminal but got %q", got)
+	}
+}
+
+func TestBasics(t *testing.T) {
+
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{nil, "nil"},
+		{true, "true"},
+		{uint8(200), "200"},
+		{-1.25, "-1.25"},
+		{complex(1, 2), "(1+2i)"},
+		{"a\tb", `"a\tb"`},
+		{[2]bool{}, "[2]bool{\n  false,\n  false,\n}"},
+		{(*int)(nil), "nil"},
+		{make(chan int, 3), "chan int(len 0, cap 3)"},
+		{struct{}{}, "struct {}{}"},
+	}
+
+	for _, tt := range tests {
+		if got := sprint(t, tt.v); got != tt.want+"\n" {
+			t.Errorf("Expected %s for %#v but got %s", tt.want, tt.v, got)
+		}
+	}
+}
+
+func TestOptions(t *testing.T) {
+
+	var oerr *options.Error
+	if err := dump.Fprint(&bytes.Buffer{}, 1, dump.WithMaxDepth(0)); !errors.As(err, &oerr) {
+		t.Errorf("Expected an options.Error but got %v", err)
+	}
+	if err := dump.Fprint(&bytes.Buffer{}, 1, dump.WithIndent("->")); err == nil {
+		t.Error("Expected an error for an indent that is not blank")
+	}
+}
+
+func BenchmarkFprint(b *testing.B) {
+
+	o := order{ID: 1, Labels: map[string]int{"a": 1, "b": 2}}
+	for range 20 {
+		o.Lines = append(o.Lines, line{"A-1", 2})
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		var buf bytes.Buffer
+		if err := dump.Fprint(&buf, &o); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"pacx/reflect/dump"
 )
 
 type Foo struct {
@@ -33,6 +35,11 @@ func main() {
 	checker(fType, 0)
 	checker(fpType, 0)
 
+	// checker only sees types; dump walks the values the same way
+	dump.Print(sl)
+	dump.Print(greetingPtr)
+	dump.Print(fp)
+
 }
 
 func checker(t reflect.Type, depth int) {
@@ -0,0 +1,457 @@
+// Package rowscan maps query results into structs, so code that reads
+// rows does not assign fields by hand:
+//
+//	type order struct {
+//		ID      int       `db:"id"`
+//		Status  string    `db:"status"`
+//		Created time.Time // the created_at column
+//		Note    *string   // nil for NULL
+//	}
+//
+//	rows, err := db.QueryContext(ctx, "SELECT * FROM orders")
+//	...
+//	defer rows.Close()
+//	orders, err := rowscan.Rows[order](rows)
+//
+// Maps does the same for rows already read into maps, as some drivers and
+// JSON documents give them.
+//
+// A column goes into the field its db tag names, or else into the field
+// whose name is the column's, ignoring case and underscores, so
+// created_at fills CreatedAt. A field tagged db:"-" is left alone, and
+// the fields of embedded structs count as the outer struct's. Columns with
+// no field are skipped.
+//
+// Values are converted to the field's type where that loses nothing: the
+// int64, float64, []byte, string, bool and time.Time values drivers return
+// into any integer, float, string or bool field they fit; strings into
+// numbers, bools and times; and anything into a field implementing
+// sql.Scanner, as sql.NullString does, by its Scan method. NULL sets a
+// field to its zero value, and a pointer field to nil.
+package rowscan
+
+import (
+	"database/sql"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pacx/options"
+)
+
+// ColumnError is a value that could not be put into its field.
+type ColumnError struct {
+	Row    int // from 0
+	Column string
+	Err    error
+}
+
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("rowscan: row %d, column %s: %v", e.Row, e.Column, e.Err)
+}
+
+func (e *ColumnError) Unwrap() error {
+	return e.Err
+}
+
+type config struct {
+	tag    string
+	strict bool
+}
+
+// Option configures Rows and Maps.
+type Option = options.Option[config]
+
+// WithTag reads column names from the given struct tag instead of db.
+func WithTag(tag string) Option {
+	return options.New("WithTag", func(c *config) error {
+		if tag == "" {
+			return errors.New("tag must not be empty")
+		}
+		c.tag = tag
+		return nil
+	})
+}
+
+// WithDisallowUnknownColumns makes a column no field takes an error rather
+// than skipping it.
+func WithDisallowUnknownColumns() Option {
+	return options.New("WithDisallowUnknownColumns", func(c *config) error {
+		c.strict = true
+		return nil
+	})
+}
+
+// Scanner is the part of *sql.Rows that Rows reads.
+type Scanner interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...any) error
+	Err() error
+}
+
+// Rows reads every remaining row of rs into a T, a struct. It leaves
+// closing rs to the caller.
+func Rows[T any](rs Scanner, opts ...Option) ([]T, error) {
+
+	cfg, err := options.Build(config{tag: "db"}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldsFor[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+	targets := make([]*field, len(cols))
+	for i, col := range cols {
+		targets[i] = fields[normalize(col)]
+		if targets[i] == nil && cfg.strict {
+			return nil, &ColumnError{Column: col, Err: errors.New("no field for it")}
+		}
+	}
+
+	var out []T
+	vals := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range dest {
+		dest[i] = &vals[i]
+	}
+	for row := 0; rs.Next(); row++ {
+		if err := rs.Scan(dest...); err != nil {
+			return out, err
+		}
+		var t T
+		v := reflect.ValueOf(&t).Elem()
+		for i, f := range targets {
+			if f == nil {
+				continue
+			}
+			if err := set(v.FieldByIndex(f.index), vals[i]); err != nil {
+				return out, &ColumnError{Row: row, Column: cols[i], Err: err}
+			}
+		}
+		out = append(out, t)
+	}
+
+	return out, rs.Err()
+}
+
+// Maps converts rows of column names to values into Ts, structs.
+func Maps[T any](rows []map[string]any, opts ...Option) ([]T, error) {
+
+	cfg, err := options.Build(config{tag: "db"}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fieldsFor[T](cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]T, len(rows))
+	for row, m := range rows {
+		v := reflect.ValueOf(&out[row]).Elem()
+		for col, val := range m {
+			f := fields[normalize(col)]
+			if f == nil {
+				if cfg.strict {
+					return nil, &ColumnError{Row: row, Column: col, Err: errors.New("no field for it")}
+				}
+				continue
+			}
+			if err := set(v.FieldByIndex(f.index), val); err != nil {
+				return nil, &ColumnError{Row: row, Column: col, Err: err}
+			}
+		}
+	}
+
+	return out, nil
+}
+
+type field struct {
+	index []int
+	depth int
+}
+
+type cacheKey struct {
+	typ reflect.Type
+	tag string
+}
+
+var fieldCache sync.Map // cacheKey to map[string]*field
+
+// fieldsFor returns the fields of T by normalized column name.
+func fieldsFor[T any](cfg config) (map[string]*field, error) {
+
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("rowscan: want a struct, got %v", t)
+	}
+	key := cacheKey{t, cfg.tag}
+	if c, ok := fieldCache.Load(key); ok {
+		return c.(map[string]*field), nil
+	}
+
+	fields := make(map[string]*field)
+	collect(t, cfg.tag, nil, fields)
+	fieldCache.Store(key, fields)
+
+	return fields, nil
+}
+
+// collect adds the fields of struct type t, under index, to fields. A
+// shallower field wins over a deeper one of the same name.
+func collect(t reflect.Type, tag string, index []int, fields map[string]*field) {
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name := sf.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		idx := append(index[:len(index):len(index)], i)
+		if sf.Anonymous && name == "" && sf.Type.Kind() == reflect.Struct {
+			collect(sf.Type, tag, idx, fields)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		key := normalize(name)
+		if f, ok := fields[key]; ok && f.depth <= len(index) {
+			continue
+		}
+		fields[key] = &field{index: idx, depth: len(index)}
+	}
+}
+
+// normalize makes column and field names comparable: created_at and
+// CreatedAt are both createdat.
+func normalize(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+var (
+	scannerType         = reflect.TypeFor[sql.Scanner]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	timeType            = reflect.TypeFor[time.Time]()
+)
+
+// timeLayouts are the ways databases write times as text.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// set puts src, a value a driver returned, into dst.
+func set(dst reflect.Value, src any) error {
+
+	if reflect.PointerTo(dst.Type()).Implements(scannerType) {
+		return dst.Addr().Interface().(sql.Scanner).Scan(src)
+	}
+	if src == nil {
+		dst.SetZero()
+		return nil
+	}
+	if dst.Kind() == reflect.Pointer {
+		p := reflect.New(dst.Type().Elem())
+		if err := set(p.Elem(), src); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+	if dst.Type() == timeType {
+		switch src := src.(type) {
+		case time.Time:
+			dst.Set(sv)
+			return nil
+		case string:
+			return setTime(dst, src)
+		case []byte:
+			return setTime(dst, string(src))
+		}
+		return fmt.Errorf("cannot put %T into %v", src, dst.Type())
+	}
+	if b, ok := src.([]byte); ok {
+		if dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8 {
+			dst.SetBytes(append([]byte(nil), b...))
+			return nil
+		}
+		src, sv = string(b), reflect.ValueOf(string(b))
+	}
+	if s, ok := src.(string); ok && reflect.PointerTo(dst.Type()).Implements(textUnmarshalerType) {
+		return dst.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		switch sv.Kind() {
+		case reflect.String:
+			dst.SetString(sv.String())
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			dst.SetString(strconv.FormatInt(sv.Int(), 10))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			dst.SetString(strconv.FormatUint(sv.Uint(), 10))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			dst.SetString(strconv.FormatFloat(sv.Float(), 'g', -1, 64))
+			return nil
+		case reflect.Bool:
+			dst.SetString(strconv.FormatBool(sv.Bool()))
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := toInt(sv)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %v", n, dst.Type())
+		}
+		dst.SetInt(n)
+		return nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := toUint(sv)
+		if err != nil {
+			return err
+		}
+		if dst.OverflowUint(n) {
+			return fmt.Errorf("%d overflows %v", n, dst.Type())
+		}
+		dst.SetUint(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch sv.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f = sv.Float()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f = float64(sv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f = float64(sv.Uint())
+		case reflect.String:
+			var err error
+			if f, err = strconv.ParseFloat(sv.String(), 64); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("cannot put %T into %v", src, dst.Type())
+		}
+		if dst.OverflowFloat(f) {
+			return fmt.Errorf("%g overflows %v", f, dst.Type())
+		}
+		dst.SetFloat(f)
+		return nil
+
+	case reflect.Bool:
+		switch sv.Kind() {
+		case reflect.Bool:
+			dst.SetBool(sv.Bool())
+			return nil
+		case reflect.String:
+			b, err := strconv.ParseBool(sv.String())
+			if err != nil {
+				return err
+			}
+			dst.SetBool(b)
+			return nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n := sv.Int(); n == 0 || n == 1 {
+				dst.SetBool(n == 1)
+				return nil
+			}
+		}
+	}
+
+	if sv.Type().AssignableTo(dst.Type()) {
+		dst.Set(sv)
+		return nil
+	}
+
+	return fmt.Errorf("cannot put %T into %v", src, dst.Type())
+}
+
+// toInt returns v as an integer if it is one, or a float or string holding
+// one.
+func toInt(v reflect.Value) (int64, error) {
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, fmt.Errorf("%d overflows int64", v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, fmt.Errorf("%g is not an integer", f)
+		}
+		return int64(f), nil
+	case reflect.String:
+		return strconv.ParseInt(strings.TrimSpace(v.String()), 10, 64)
+	}
+
+	return 0, fmt.Errorf("cannot put %v into an integer", v.Type())
+}
+
+func setTime(dst reflect.Value, s string) error {
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot parse %q as a time", s)
+}
+
+// toUint is toInt for unsigned destinations, which take values above
+// MaxInt64 but nothing negative.
+func toUint(v reflect.Value) (uint64, error) {
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, fmt.Errorf("%d is negative", v.Int())
+		}
+		return uint64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+			return 0, fmt.Errorf("%g is not an unsigned integer", f)
+		}
+		return uint64(f), nil
+	case reflect.String:
+		return strconv.ParseUint(strings.TrimSpace(v.String()), 10, 64)
+	}
+
+	return 0, fmt.Errorf("cannot put %v into an unsigned integer", v.Type())
+}
@@ -0,0 +1,214 @@
+package rowscan_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"pacx/reflect/rowscan"
+)
+
+type Audit struct {
+	CreatedAt time.Time
+	By        string `db:"created_by"`
+}
+
+type order struct {
+	Audit
+	ID       int64          `db:"id"`
+	Status   string         `db:"status"`
+	Qty      uint8          `db:"qty"`
+	Price    float64        `db:"price"`
+	Paid     bool           `db:"paid"`
+	Note     *string        `db:"note"`
+	Coupon   sql.NullString `db:"coupon"`
+	Addr     netip.Addr     `db:"addr"`
+	Data     []byte         `db:"data"`
+	Internal string         `db:"-"`
+}
+
+var created = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func TestMaps(t *testing.T) {
+
+	rows := []map[string]any{
+		{
+			"id": int64(1), "status": []byte("paid"), "qty": int64(3), "price": "9.5", "paid": int64(1),
+			"note": "gift", "coupon": "X1", "addr": "10.0.0.1", "data": []byte{1, 2},
+			"created_at": "2026-01-02 03:04:05", "CREATED_BY": "ops", "extra": 1,
+		},
+		{"id": 2.0, "status": "new", "note": nil, "coupon": nil, "paid": false, "created_at": created},
+	}
+
+	got, err := rowscan.Maps[order](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	note := "gift"
+	want := []order{
+		{
+			Audit: Audit{CreatedAt: created, By: "ops"},
+			ID:    1, Status: "paid", Qty: 3, Price: 9.5, Paid: true, Note: &note,
+			Coupon: sql.NullString{String: "X1", Valid: true}, Addr: netip.MustParseAddr("10.0.0.1"), Data: []byte{1, 2},
+		},
+		{Audit: Audit{CreatedAt: created}, ID: 2, Status: "new"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected\n%+v\nbut got\n%+v", want, got)
+	}
+
+	if _, err := rowscan.Maps[order](rows, rowscan.WithDisallowUnknownColumns()); err == nil {
+		t.Error("Expected an error for the extra column")
+	}
+}
+
+func TestMapsErrors(t *testing.T) {
+
+	tests := []map[string]any{
+		{"qty": int64(256)},
+		{"qty": int64(-1)},
+		{"id": 1.5},
+		{"id": "seven"},
+		{"paid": int64(2)},
+		{"created_at": "yesterday"},
+		{"created_at": int64(5)},
+		{"addr": "nowhere"},
+		{"status": []int{1}},
+	}
+
+	for _, m := range tests {
+		_, err := rowscan.Maps[order]([]map[string]any{{}, m})
+		var cerr *rowscan.ColumnError
+		if !errors.As(err, &cerr) || cerr.Row != 1 {
+			t.Errorf("Expected a ColumnError in row 1 for %v but got %v", m, err)
+		}
+	}
+
+	if _, err := rowscan.Maps[int](nil); err == nil {
+		t.Error("Expected an error for a type that is not a struct")
+	}
+}
+
+func TestMapsUnsigned(t *testing.T) {
+
+	type counter struct {
+		N uint64 `db:"n"`
+	}
+
+	for _, v := range []any{uint64(1 << 63), "9223372036854775808", float64(1 << 63)} {
+		got, err := rowscan.Maps[counter]([]map[string]any{{"n": v}})
+		if err != nil || got[0].N != 1<<63 {
+			t.Errorf("Expected %d from %T but got %v, %v", uint64(1<<63), v, got, err)
+		}
+	}
+	for _, v := range []any{int64(-1), "-1", -1.0, 1.5, "18446744073709551616"} {
+		if _, err := rowscan.Maps[counter]([]map[string]any{{"n": v}}); err == nil {
+			t.Errorf("Expected an error for %v but got none", v)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+
+	type item struct {
+		SKU string `json:"sku_code"`
+	}
+	got, err := rowscan.Maps[item]([]map[string]any{{"sku_code": "A"}}, rowscan.WithTag("json"))
+	if err != nil || len(got) != 1 || got[0].SKU != "A" {
+		t.Errorf("Expected the json tag used but got %v, %v", got, err)
+	}
+}
+
+func TestRows(t *testing.T) {
+
+	db := sql.OpenDB(fakeDriver{})
+	defer db.Close()
+
+	rows, err := db.QueryContext(context.Background(), "SELECT * FROM orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	got, err := rowscan.Rows[order](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 1 || got[0].Status != "paid" || !got[0].CreatedAt.Equal(created) ||
+		got[1].Note == nil || *got[1].Note != "rush" || got[0].Note != nil || !got[1].Paid {
+		t.Errorf("Expected both rows scanned but got %+v", got)
+	}
+}
+
+// fakeDriver serves one table, whatever the query, the way a SQL driver
+// returns values: int64, float64, bool, []byte, string, time.Time or nil.
+// It is its own connector, so it need not be registered.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeDriver) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (d fakeDriver) Driver() driver.Driver { return d }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("no transactions") }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                               { return nil }
+func (fakeStmt) NumInput() int                              { return 0 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, errors.New("read only") }
+
+func (fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeRows{rows: [][]driver.Value{
+		{int64(1), []byte("paid"), nil, created, int64(0)},
+		{int64(2), "new", []byte("rush"), "2026-01-02T03:04:05Z", true},
+	}}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"id", "status", "note", "created_at", "paid"}
+}
+
+func (*fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func BenchmarkMaps(b *testing.B) {
+
+	rows := make([]map[string]any, 100)
+	for i := range rows {
+		rows[i] = map[string]any{"id": int64(i), "status": []byte("paid"), "qty": int64(3), "price": 9.5, "created_at": created}
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := rowscan.Maps[order](rows); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+// Package structmap converts between structs and map[string]any without
+// going through an encoding, walking types the way reflect/main.go's
+// checker does:
+//
+//	m, err := structmap.ToMap(order, "json")
+//	err = structmap.FromMap(m, &order, "json")
+//
+// Fields are named by the given struct tag, as in `json:"id,omitempty"`,
+// or else by their Go name; "-" leaves a field out and omitempty leaves
+// out its zero value. Embedded structs without a name are flattened into
+// the outer one, as encoding/json does.
+package structmap
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// maxDepth bounds the nesting, which only a pointer cycle would reach.
+const maxDepth = 1000
+
+// FieldError is a value that could not be converted. Path is by the keys
+// of the map, as in items[2].name.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return "structmap: " + e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ToMap converts the struct v, or what v points to, into a map. Nested
+// structs become maps of their own; pointers are followed, nil ones
+// becoming nil; slices and arrays become []any, and maps with string keys
+// map[string]any. Values of other types are kept as they are, and so are
+// time.Time and other structs that implement encoding.TextMarshaler.
+func ToMap(v any, tag string) (map[string]any, error) {
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, errors.New("structmap: nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("structmap: want a struct, got %T", v)
+	}
+
+	return structToMap(rv, tag, "", 0)
+}
+
+func structToMap(v reflect.Value, tag, path string, depth int) (map[string]any, error) {
+
+	fields := fieldsOf(v.Type(), tag)
+	m := make(map[string]any, len(fields))
+	for _, f := range fields {
+		fv, err := v.FieldByIndexErr(f.index)
+		if err != nil {
+			continue // through a nil embedded pointer
+		}
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		out, err := toValue(fv, tag, join(path, f.name), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		m[f.name] = out
+	}
+
+	return m, nil
+}
+
+func toValue(v reflect.Value, tag, path string, depth int) (any, error) {
+
+	if depth > maxDepth {
+		return nil, &FieldError{Path: path, Err: errors.New("nested too deep, is there a cycle?")}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return toValue(v.Elem(), tag, path, depth+1)
+	case reflect.Struct:
+		if isLeaf(v.Type()) {
+			return v.Interface(), nil
+		}
+		return structToMap(v, tag, path, depth)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		fallthrough
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil // bytes stay bytes
+		}
+		out := make([]any, v.Len())
+		for i := range out {
+			e, err := toValue(v.Index(i), tag, fmt.Sprintf("%s[%d]", path, i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = e
+		}
+		return out, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface(), nil
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		out := make(map[string]any, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			k := it.Key().String()
+			e, err := toValue(it.Value(), tag, fmt.Sprintf("%s[%q]", path, k), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = e
+		}
+		return out, nil
+	}
+
+	return v.Interface(), nil
+}
+
+// FromMap sets the fields of the struct dst points to from m, the reverse
+// of ToMap with the same tag. Keys without a field are ignored and fields
+// without a key left as they are. Values convert as in an assignment, and
+// also between numeric types when the number fits, so the float64s of
+// json.Unmarshal set int fields; maps set structs, []any slices, and
+// strings the types that implement encoding.TextUnmarshaler.
+func FromMap(m map[string]any, dst any, tag string) error {
+
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("structmap: want a pointer to a struct, got %T", dst)
+	}
+
+	return mapToStruct(m, rv.Elem(), tag, "", 0)
+}
+
+func mapToStruct(m map[string]any, v reflect.Value, tag, path string, depth int) error {
+
+	for _, f := range fieldsOf(v.Type(), tag) {
+		in, ok := m[f.name]
+		if !ok {
+			continue
+		}
+		fv, err := fieldAlloc(v, f.index)
+		if err != nil {
+			return &FieldError{Path: join(path, f.name), Err: err}
+		}
+		if err := fromValue(in, fv, tag, join(path, f.name), depth+1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldAlloc is v.FieldByIndex that allocates nil embedded pointers on
+// the way.
+func fieldAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported %v", v.Type().Elem())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v, nil
+}
+
+func fromValue(in any, v reflect.Value, tag, path string, depth int) error {
+
+	if depth > maxDepth {
+		return &FieldError{Path: path, Err: errors.New("nested too deep")}
+	}
+	if in == nil {
+		v.SetZero()
+		return nil
+	}
+	src := reflect.ValueOf(in)
+	if src.Type().AssignableTo(v.Type()) {
+		v.Set(src)
+		return nil
+	}
+	if s, ok := in.(string); ok && v.Kind() != reflect.String && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			return &FieldError{Path: path, Err: err}
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		p := reflect.New(v.Type().Elem())
+		if err := fromValue(in, p.Elem(), tag, path, depth+1); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+
+	case reflect.Struct:
+		m, ok := in.(map[string]any)
+		if !ok || isLeaf(v.Type()) {
+			break
+		}
+		return mapToStruct(m, v, tag, path, depth)
+
+	case reflect.Slice, reflect.Array:
+		if src.Kind() != reflect.Slice && src.Kind() != reflect.Array {
+			break
+		}
+		n := src.Len()
+		if v.Kind() == reflect.Array {
+			if n != v.Len() {
+				return &FieldError{Path: path, Err: fmt.Errorf("%d elements for an array of %d", n, v.Len())}
+			}
+		} else {
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+		}
+		for i := range n {
+			if err := fromValue(src.Index(i).Interface(), v.Index(i), tag, fmt.Sprintf("%s[%d]", path, i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		if src.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String || src.Type().Key().Kind() != reflect.String {
+			break
+		}
+		out := reflect.MakeMapWithSize(v.Type(), src.Len())
+		for it := src.MapRange(); it.Next(); {
+			k := it.Key().String()
+			e := reflect.New(v.Type().Elem()).Elem()
+			if err := fromValue(it.Value().Interface(), e, tag, fmt.Sprintf("%s[%q]", path, k), depth+1); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), e)
+		}
+		v.Set(out)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if err := setNumber(src, v); err != nil {
+			return &FieldError{Path: path, Err: err}
+		}
+		return nil
+	}
+
+	if src.Kind() == v.Kind() && src.Type().ConvertibleTo(v.Type()) {
+		v.Set(src.Convert(v.Type())) // as from string to a named string
+		return nil
+	}
+
+	return &FieldError{Path: path, Err: fmt.Errorf("cannot set %v from %T", v.Type(), in)}
+}
+
+// setNumber sets the number v from the number src if it fits exactly.
+func setNumber(src, v reflect.Value) error {
+
+	var f float64
+	exact := true
+	switch src.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := src.Int()
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(n) {
+				return fmt.Errorf("%d overflows %v", n, v.Type())
+			}
+			v.SetInt(n)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n < 0 || v.OverflowUint(uint64(n)) {
+				return fmt.Errorf("%d overflows %v", n, v.Type())
+			}
+			v.SetUint(uint64(n))
+			return nil
+		}
+		f, exact = float64(n), int64(float64(n)) == n
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := src.Uint()
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n > math.MaxInt64 || v.OverflowInt(int64(n)) {
+				return fmt.Errorf("%d overflows %v", n, v.Type())
+			}
+			v.SetInt(int64(n))
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if v.OverflowUint(n) {
+				return fmt.Errorf("%d overflows %v", n, v.Type())
+			}
+			v.SetUint(n)
+			return nil
+		}
+		f, exact = float64(n), uint64(float64(n)) == n
+	case reflect.Float32, reflect.Float64:
+		f = src.Float()
+	default:
+		return fmt.Errorf("cannot set %v from %v", v.Type(), src.Type())
+	}
+
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		if !exact || v.OverflowFloat(f) {
+			return fmt.Errorf("%v does not fit %v", src, v.Type())
+		}
+		v.SetFloat(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || v.OverflowInt(int64(f)) {
+			return fmt.Errorf("%v does not fit %v", f, v.Type())
+		}
+		v.SetInt(int64(f))
+	default:
+		if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || v.OverflowUint(uint64(f)) {
+			return fmt.Errorf("%v does not fit %v", f, v.Type())
+		}
+		v.SetUint(uint64(f))
+	}
+
+	return nil
+}
+
+var (
+	textMarshalerType   = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// isLeaf reports whether a struct is kept whole rather than turned into a
+// map.
+func isLeaf(t reflect.Type) bool {
+	return t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType)
+}
+
+// field is a struct field and the key it maps to.
+type field struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+type cacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
+var fieldCache sync.Map // cacheKey to []field
+
+// fieldsOf returns the fields of struct type t named by tag, with
+// embedded structs flattened. Of fields with the same name the shallowest
+// wins; if several are equally shallow, none does. A struct embedded
+// inside itself is walked only once, as encoding/json does.
+func fieldsOf(t reflect.Type, tag string) []field {
+
+	if c, ok := fieldCache.Load(cacheKey{t, tag}); ok {
+		return c.([]field)
+	}
+
+	var all []field
+	inside := make(map[reflect.Type]bool)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		if inside[t] {
+			return
+		}
+		inside[t] = true
+		defer delete(inside, t)
+		for i := range t.NumField() {
+			f := t.Field(i)
+			value := f.Tag.Get(tag)
+			if value == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(value, ",")
+			idx := append(index[:len(index):len(index)], i)
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+				walk(ft, idx)
+				continue
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			all = append(all, field{name: name, index: idx, omitEmpty: slices.Contains(strings.Split(opts, ","), "omitempty")})
+		}
+	}
+	walk(t, nil)
+
+	fields := make([]field, 0, len(all))
+	for _, f := range all {
+		depth := len(f.index)
+		dominant, tie := true, false
+		for _, g := range all {
+			if g.name != f.name || slices.Equal(g.index, f.index) {
+				continue
+			}
+			if len(g.index) < depth {
+				dominant = false
+			}
+			if len(g.index) == depth {
+				tie = true
+			}
+		}
+		if dominant && !tie {
+			fields = append(fields, f)
+		}
+	}
+
+	fieldCache.Store(cacheKey{t, tag}, fields)
+
+	return fields
+}
+
+func join(path, name string) string {
+
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,308 @@
+package structmap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/reflect/structmap"
+)
+
+type Audit struct {
+	Created time.Time `json:"created"`
+	By      string    `json:"by,omitempty"`
+}
+
+type item struct {
+	SKU   string  `json:"sku"`
+	Qty   int     `json:"qty"`
+	Price float64 `json:"price"`
+}
+
+type address struct {
+	City string `json:"city"`
+}
+
+type order struct {
+	Audit
+	ID       int               `json:"id"`
+	Status   string            `json:"status"`
+	Items    []item            `json:"items"`
+	Ship     *address          `json:"ship,omitempty"`
+	Bill     *address          `json:"bill"`
+	Labels   map[string]string `json:"labels"`
+	Codes    [2]uint8          `json:"codes"`
+	Priority priority          `json:"priority"`
+	Note     string            `json:"-"`
+	secret   string
+}
+
+type priority string
+
+func sample() order {
+	return order{
+		Audit:    Audit{Created: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), By: "ops"},
+		ID:       7,
+		Status:   "paid",
+		Items:    []item{{"A-1", 2, 9.5}, {"B-2", 1, 20}},
+		Ship:     &address{City: "Pune"},
+		Labels:   map[string]string{"gift": "yes"},
+		Codes:    [2]uint8{4, 2},
+		Priority: "high",
+		Note:     "not mapped",
+		secret:   "hidden",
+	}
+}
+
+func TestToMap(t *testing.T) {
+
+	m, err := structmap.ToMap(sample(), "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"created": time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		"by":      "ops",
+		"id":      7,
+		"status":  "paid",
+		"items": []any{
+			map[string]any{"sku": "A-1", "qty": 2, "price": 9.5},
+			map[string]any{"sku": "B-2", "qty": 1, "price": 20.0},
+		},
+		"ship":     map[string]any{"city": "Pune"},
+		"bill":     nil,
+		"labels":   map[string]any{"gift": "yes"},
+		"codes":    [2]uint8{4, 2},
+		"priority": priority("high"),
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("Expected\n%#v\nbut got\n%#v", want, m)
+	}
+
+	o := sample()
+	o.Ship, o.By = nil, ""
+	m, _ = structmap.ToMap(&o, "json")
+	if _, ok := m["ship"]; ok {
+		t.Errorf("Expected omitempty to leave out ship but got %v", m["ship"])
+	}
+	if _, ok := m["by"]; ok {
+		t.Errorf("Expected omitempty to leave out by but got %v", m["by"])
+	}
+}
+
+func TestToMapGoNames(t *testing.T) {
+
+	m, err := structmap.ToMap(item{"A-1", 2, 9.5}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m, map[string]any{"SKU": "A-1", "Qty": 2, "Price": 9.5}) {
+		t.Errorf("Expected the Go names without a tag but got %v", m)
+	}
+
+	if _, err := structmap.ToMap(42, "json"); err == nil {
+		t.Error("Expected an error for a value that is not a struct")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+
+	in := sample()
+	m, err := structmap.ToMap(in, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out order
+	if err := structmap.FromMap(m, &out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	in.Note, in.secret = "", ""
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Expected\n%+v\nbut got\n%+v", in, out)
+	}
+}
+
+func TestFromMapJSON(t *testing.T) {
+
+	// what json.Unmarshal makes: float64 numbers, strings for times
+	data, _ := json.Marshal(sample())
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	var out order
+	if err := structmap.FromMap(m, &out, "json"); err != nil {
+		t.Fatal(err)
+	}
+	want := sample()
+	want.Note, want.secret = "", ""
+	if !reflect.DeepEqual(want, out) {
+		t.Errorf("Expected\n%+v\nbut got\n%+v", want, out)
+	}
+}
+
+func TestFromMapErrors(t *testing.T) {
+
+	tests := []struct {
+		m    map[string]any
+		path string
+	}{
+		{map[string]any{"id": 1.5}, "id"},
+		{map[string]any{"id": "7"}, "id"},
+		{map[string]any{"codes": []any{1.0, 300.0}}, "codes[1]"},
+		{map[string]any{"codes": []any{1.0}}, "codes"},
+		{map[string]any{"items": []any{map[string]any{"qty": -1.0}, map[string]any{"qty": true}}}, "items[1].qty"},
+		{map[string]any{"created": "yesterday"}, "created"},
+		{map[string]any{"labels": map[string]any{"gift": 1}}, `labels["gift"]`},
+	}
+
+	for _, tt := range tests {
+		var o order
+		err := structmap.FromMap(tt.m, &o, "json")
+		var ferr *structmap.FieldError
+		if !errors.As(err, &ferr) || ferr.Path != tt.path {
+			t.Errorf("Expected an error at %s for %v but got %v", tt.path, tt.m, err)
+		}
+	}
+
+	var o order
+	if err := structmap.FromMap(nil, o, "json"); err == nil || !strings.Contains(err.Error(), "pointer to a struct") {
+		t.Errorf("Expected an error for a struct that is not a pointer but got %v", err)
+	}
+}
+
+func TestFromMapLeavesMissing(t *testing.T) {
+
+	o := order{ID: 1, Status: "paid"}
+	if err := structmap.FromMap(map[string]any{"status": "shipped", "unknown": 1, "bill": nil}, &o, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if o.ID != 1 || o.Status != "shipped" || o.Bill != nil {
+		t.Errorf("Expected only status changed but got %+v", o)
+	}
+}
+
+func TestEmbeddedConflicts(t *testing.T) {
+
+	type A struct{ Name, Only string }
+	type B struct{ Name string }
+	type outer struct {
+		A
+		B
+		ID int
+	}
+	type shadow struct {
+		A
+		Name string
+	}
+
+	m, _ := structmap.ToMap(outer{A{"a", "x"}, B{"b"}, 1}, "")
+	if _, ok := m["Name"]; ok || m["Only"] != "x" || m["ID"] != 1 {
+		t.Errorf("Expected the equally deep Names dropped but got %v", m)
+	}
+	m, _ = structmap.ToMap(shadow{A{"a", "x"}, "outer"}, "")
+	if m["Name"] != "outer" {
+		t.Errorf("Expected the shallower Name to win but got %v", m)
+	}
+
+	type node struct {
+		*Audit
+		Next *node `json:"next"`
+	}
+	var n node
+	if err := structmap.FromMap(map[string]any{"by": "ops", "next": map[string]any{}}, &n, "json"); err != nil {
+		t.Fatal(err)
+	}
+	if n.Audit == nil || n.By != "ops" || n.Next == nil {
+		t.Errorf("Expected the embedded pointer and next allocated but got %+v", n)
+	}
+	m, _ = structmap.ToMap(node{}, "json")
+	if _, ok := m["by"]; ok || m["next"] != nil {
+		t.Errorf("Expected nothing from a nil embedded pointer but got %v", m)
+	}
+}
+
+func TestSelfEmbedded(t *testing.T) {
+
+	type self struct {
+		*self
+		X int
+	}
+	m, err := structmap.ToMap(self{X: 1}, "")
+	if err != nil || len(m) != 1 || m["X"] != 1 {
+		t.Errorf("Expected only X but got %v, %v", m, err)
+	}
+	var s self
+	if err := structmap.FromMap(map[string]any{"X": 2}, &s, ""); err != nil || s.X != 2 || s.self != nil {
+		t.Errorf("Expected X set and the embedded pointer left nil but got %+v, %v", s, err)
+	}
+}
+
+func TestCycle(t *testing.T) {
+
+	type node struct{ Next *node }
+	n := &node{}
+	n.Next = n
+	if _, err := structmap.ToMap(n, ""); err == nil {
+		t.Error("Expected an error for a cycle")
+	}
+}
+
+func BenchmarkToMap(b *testing.B) {
+
+	o := sample()
+	b.Run("how=structmap", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, err := structmap.ToMap(o, "json"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("how=json", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			data, err := json.Marshal(o)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var m map[string]any
+			if err := json.Unmarshal(data, &m); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFromMap(b *testing.B) {
+
+	m, _ := structmap.ToMap(sample(), "json")
+	b.Run("how=structmap", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			var o order
+			if err := structmap.FromMap(m, &o, "json"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("how=json", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			data, err := json.Marshal(m)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var o order
+			if err := json.Unmarshal(data, &o); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
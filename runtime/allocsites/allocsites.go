@@ -0,0 +1,377 @@
+// Package allocsites reports where a program allocates. It reads the
+// runtime's heap profile, the one go tool pprof -sample_index=alloc_space
+// shows, and turns it into records with symbolized stacks, sorted by the
+// bytes allocated.
+//
+// The profile is cumulative and sampled: one allocation per
+// runtime.MemProfileRate bytes on average is recorded, and records are
+// scaled back up the way pprof does, so they are estimates. It is also
+// published with a delay of up to two garbage collections, so a Reporter
+// interval only sees allocations the collector has caught up with. A
+// Reporter prints the top sites of each interval next to what the same
+// sites allocated the interval before, so a site that starts allocating
+// more stands out during a long soak run.
+package allocsites
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"pacx/options"
+	"pacx/sync/syncx"
+)
+
+// Frame is one call in a stack.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Record is one allocating stack.
+type Record struct {
+	Bytes     int64   // allocated, scaled by the sampling rate
+	Objects   int64   // likewise
+	PrevBytes int64   // allocated by the same stack the interval before; 0 for Snapshot
+	Stack     []Frame // innermost first
+}
+
+// Delta is the change against the previous interval.
+func (r Record) Delta() int64 {
+	return r.Bytes - r.PrevBytes
+}
+
+// Site returns the innermost frame outside the runtime and the standard
+// allocation helpers, which is usually the code that asked for the
+// memory.
+func (r Record) Site() Frame {
+
+	for _, f := range r.Stack {
+		if !strings.HasPrefix(f.Function, "runtime.") && !strings.HasPrefix(f.Function, "internal/") &&
+			!strings.HasPrefix(f.Function, "slices.") && !strings.HasPrefix(f.Function, "strings.") && !strings.HasPrefix(f.Function, "bytes.") {
+			return f
+		}
+	}
+	if len(r.Stack) > 0 {
+		return r.Stack[0]
+	}
+
+	return Frame{Function: "?"}
+}
+
+// key identifies a stack across snapshots.
+type key [32]uintptr
+
+type raw struct {
+	key     key
+	bytes   int64
+	objects int64
+	stack   []uintptr
+}
+
+// read returns the heap profile merged by stack, scaled by rate.
+func read(rate int) map[key]raw {
+
+	var records []runtime.MemProfileRecord
+	n, _ := runtime.MemProfile(nil, true)
+	for {
+		// leave room for records added in between
+		records = make([]runtime.MemProfileRecord, n+16)
+		var ok bool
+		if n, ok = runtime.MemProfile(records, true); ok {
+			records = records[:n]
+			break
+		}
+	}
+
+	out := make(map[key]raw, len(records))
+	for _, r := range records {
+		objects, bytes := scale(r.AllocObjects, r.AllocBytes, rate)
+		k := key(r.Stack0)
+		acc := out[k]
+		acc.key = k
+		acc.bytes += bytes
+		acc.objects += objects
+		acc.stack = r.Stack()
+		out[k] = acc
+	}
+
+	return out
+}
+
+// scale undoes the sampling the way pprof does: an allocation of size s
+// is sampled with probability 1-exp(-s/rate).
+func scale(count, size int64, rate int) (int64, int64) {
+
+	if count == 0 || size == 0 || rate <= 1 {
+		return count, size
+	}
+	avg := float64(size) / float64(count)
+	f := 1 / (1 - math.Exp(-avg/float64(rate)))
+
+	return int64(float64(count) * f), int64(float64(size) * f)
+}
+
+// records turns raw entries into records, most bytes first.
+func records(entries []raw, prev map[key]raw) []Record {
+
+	out := make([]Record, 0, len(entries))
+	for _, e := range entries {
+		if e.bytes <= 0 {
+			continue
+		}
+		r := Record{Bytes: e.bytes, Objects: e.objects}
+		if p, ok := prev[e.key]; ok {
+			r.PrevBytes = p.bytes
+		}
+		frames := runtime.CallersFrames(e.stack)
+		for {
+			f, more := frames.Next()
+			r.Stack = append(r.Stack, Frame{Function: f.Function, File: f.File, Line: f.Line})
+			if !more {
+				break
+			}
+		}
+		out = append(out, r)
+	}
+
+	slices.SortFunc(out, func(a, b Record) int {
+		if c := cmp.Compare(b.Bytes, a.Bytes); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Objects, a.Objects)
+	})
+
+	return out
+}
+
+// Snapshot returns every allocating stack since the program started, most
+// bytes first.
+func Snapshot() []Record {
+
+	var entries []raw
+	for _, e := range read(runtime.MemProfileRate) {
+		entries = append(entries, e)
+	}
+
+	return records(entries, nil)
+}
+
+type config struct {
+	interval time.Duration
+	top      int
+	rate     int
+	out      io.Writer
+	onReport func([]Record)
+}
+
+// Option configures a Reporter.
+type Option = options.Option[config]
+
+// WithInterval sets how often a report is made. Defaults to thirty
+// seconds.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithTop sets how many sites a report holds. Defaults to 10.
+func WithTop(n int) Option {
+	return options.New("WithTop", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one site")
+		}
+		c.top = n
+		return nil
+	})
+}
+
+// WithRate sets runtime.MemProfileRate, the average number of bytes
+// between samples. Defaults to leaving the runtime's 512KiB alone. It
+// should be set once, as early as possible: records taken at another rate
+// are scaled as if they were taken at this one.
+func WithRate(bytes int) Option {
+	return options.New("WithRate", func(c *config) error {
+		if bytes < 1 {
+			return errors.New("rate must be positive")
+		}
+		c.rate = bytes
+		return nil
+	})
+}
+
+// WithOutput sets where reports are printed. Defaults to os.Stderr; nil
+// prints nothing, for use with WithOnReport.
+func WithOutput(w io.Writer) Option {
+	return options.New("WithOutput", func(c *config) error {
+		c.out = w
+		return nil
+	})
+}
+
+// WithOnReport is called with the records of every interval that
+// allocated.
+func WithOnReport(fn func([]Record)) Option {
+	return options.New("WithOnReport", func(c *config) error {
+		c.onReport = fn
+		return nil
+	})
+}
+
+// Reporter reports the allocation sites of each interval until Close.
+type Reporter struct {
+	cfg config
+	bg  *syncx.Background
+
+	mu    sync.Mutex
+	last  map[key]raw // cumulative at the end of the previous interval
+	delta map[key]raw // what the previous interval allocated
+}
+
+// Start starts reporting.
+func Start(opts ...Option) (*Reporter, error) {
+
+	cfg, err := options.Build(config{
+		interval: 30 * time.Second,
+		top:      10,
+		out:      os.Stderr,
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.rate > 0 {
+		runtime.MemProfileRate = cfg.rate
+	} else {
+		cfg.rate = runtime.MemProfileRate
+	}
+
+	r := &Reporter{
+		cfg:  cfg,
+		last: read(cfg.rate),
+	}
+	r.bg = syncx.Go(r.loop)
+
+	return r, nil
+}
+
+func (r *Reporter) loop(quit <-chan struct{}) {
+
+	ticker := time.NewTicker(r.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			recs := r.Interval()
+			if len(recs) == 0 {
+				continue
+			}
+			if r.cfg.onReport != nil {
+				r.cfg.onReport(recs)
+			}
+			if r.cfg.out != nil {
+				Print(r.cfg.out, recs, r.cfg.top)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// Interval returns the allocations since the previous call, or since
+// Start, and starts a new interval. PrevBytes holds what each stack
+// allocated in the interval before.
+func (r *Reporter) Interval() []Record {
+
+	cur := read(r.cfg.rate)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delta := make(map[key]raw, len(cur))
+	var entries []raw
+	for k, e := range cur {
+		p := r.last[k]
+		e.bytes -= p.bytes
+		e.objects -= p.objects
+		if e.bytes > 0 {
+			delta[k] = e
+			entries = append(entries, e)
+		}
+	}
+	prev := r.delta
+	r.last, r.delta = cur, delta
+
+	return records(entries, prev)
+}
+
+// Close stops reporting.
+func (r *Reporter) Close() {
+
+	r.bg.Stop()
+}
+
+// Print writes the top n records, each with its stack.
+func Print(w io.Writer, recs []Record, n int) error {
+
+	bw := bufio.NewWriter(w)
+
+	var total int64
+	for _, r := range recs {
+		total += r.Bytes
+	}
+	fmt.Fprintf(bw, "allocations: %s over %d sites\n", size(total), len(recs))
+
+	for i, r := range recs[:min(n, len(recs))] {
+		site := r.Site()
+		fmt.Fprintf(bw, "#%d  %s in %d objects (%s%s vs previous)  %s\n", i+1, size(r.Bytes), r.Objects, sign(r.Delta()), size(abs(r.Delta())), site.Function)
+		for _, f := range r.Stack {
+			fmt.Fprintf(bw, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func size(b int64) string {
+
+	switch {
+	case b >= 1<<30:
+		return fmt.Sprintf("%.1fGiB", float64(b)/(1<<30))
+	case b >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(b)/(1<<20))
+	case b >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(b)/(1<<10))
+	}
+
+	return fmt.Sprintf("%dB", b)
+}
+
+func sign(v int64) string {
+	if v < 0 {
+		return "-"
+	}
+	return "+"
+}
+
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,91 @@
+package allocsites_test
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"pacx/runtime/allocsites"
+)
+
+var sink [][]byte
+
+//go:noinline
+func allocate(n, size int) {
+	for i := 0; i < n; i++ {
+		sink = append(sink[:0], make([]byte, size))
+	}
+}
+
+// settle runs enough collections for the heap profile to publish what was
+// allocated so far.
+func settle() {
+	runtime.GC()
+	runtime.GC()
+}
+
+func find(recs []allocsites.Record, fn string) (allocsites.Record, bool) {
+
+	for _, r := range recs {
+		if r.Site().Function == fn {
+			return r, true
+		}
+	}
+
+	return allocsites.Record{}, false
+}
+
+func TestIntervalDeltas(t *testing.T) {
+
+	r, err := allocsites.Start(
+		allocsites.WithRate(1), // every allocation
+		allocsites.WithInterval(time.Hour),
+		allocsites.WithOutput(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer func(rate int) { runtime.MemProfileRate = rate }(512 * 1024)
+
+	const site = "pacx/runtime/allocsites_test.allocate"
+
+	settle()
+	r.Interval()
+
+	// the same call both times: a stack is the same site only if its
+	// callers are the same too
+	var got []allocsites.Record
+	for _, n := range []int{100, 300} {
+		allocate(n, 1024)
+		settle()
+		r, ok := find(r.Interval(), site)
+		if !ok {
+			t.Fatalf("Expected allocate in interval %d", len(got)+1)
+		}
+		got = append(got, r)
+	}
+
+	first, second := got[0], got[1]
+	if first.Objects < 100 || first.Bytes < 100*1024 || first.PrevBytes != 0 {
+		t.Errorf("Expected 100 objects of 1KiB and nothing before but got %+v", first)
+	}
+	if second.Objects < 300 || second.Objects > 400 || second.PrevBytes != first.Bytes || second.Delta() <= 0 {
+		t.Errorf("Expected 300 objects and a positive delta against %d but got %+v", first.Bytes, second)
+	}
+
+	var buf bytes.Buffer
+	allocsites.Print(&buf, []allocsites.Record{second}, 1)
+	if !strings.Contains(buf.String(), "vs previous)  "+site) {
+		t.Errorf("Expected the site and the delta in the report but got\n%s", buf.String())
+	}
+}
+
+func TestBadOption(t *testing.T) {
+
+	if _, err := allocsites.Start(allocsites.WithTop(0)); err == nil {
+		t.Error("Expected an error for zero sites")
+	}
+}
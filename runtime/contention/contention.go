@@ -0,0 +1,349 @@
+// Package contention reports where goroutines wait for mutexes. It reads
+// the runtime's mutex profile, the one runtime.SetMutexProfileFraction
+// turns on for proff/trace.go, and turns it into records with symbolized
+// stacks and wait times, sorted by the total delay.
+//
+// The profile is cumulative since the program started and sampled: with a
+// fraction of n only one in n contention events is recorded. Records are
+// scaled back up by the fraction, so they are estimates, not exact counts.
+// A Reporter prints the top stacks of each interval rather than of the
+// whole run, so a new hot spot isn't hidden behind an old one.
+package contention
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pacx/options"
+	"pacx/sync/syncx"
+)
+
+// Frame is one call in a stack.
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// Record is one contended stack: the goroutine that held the lock, as it
+// unlocked.
+type Record struct {
+	Count int64         // contention events, scaled by the sampling fraction
+	Delay time.Duration // total time waiters spent blocked, scaled likewise
+	Stack []Frame       // innermost first
+}
+
+// Site returns the innermost frame outside sync and the runtime, which is
+// usually the code that held the lock.
+func (r Record) Site() Frame {
+
+	for _, f := range r.Stack {
+		if !strings.HasPrefix(f.Function, "sync.") && !strings.HasPrefix(f.Function, "runtime.") && !strings.HasPrefix(f.Function, "internal/") {
+			return f
+		}
+	}
+	if len(r.Stack) > 0 {
+		return r.Stack[0]
+	}
+
+	return Frame{Function: "?"}
+}
+
+// key identifies a stack across snapshots.
+type key [32]uintptr
+
+type raw struct {
+	count  int64
+	cycles int64
+	stack  []uintptr
+}
+
+// read returns the mutex profile merged by stack.
+func read() map[key]raw {
+
+	var records []runtime.BlockProfileRecord
+	n, _ := runtime.MutexProfile(nil)
+	for {
+		// leave room for records added in between
+		records = make([]runtime.BlockProfileRecord, n+16)
+		var ok bool
+		if n, ok = runtime.MutexProfile(records); ok {
+			records = records[:n]
+			break
+		}
+	}
+
+	out := make(map[key]raw, len(records))
+	for _, r := range records {
+		k := key(r.Stack0)
+		acc := out[k]
+		acc.count += r.Count
+		acc.cycles += r.Cycles
+		acc.stack = r.Stack()
+		out[k] = acc
+	}
+
+	return out
+}
+
+var (
+	cyclesOnce sync.Once
+	cyclesHz   float64
+)
+
+// cyclesPerSecond is the rate the runtime measures delays in. It is not
+// exported, but the text form of the profile prints it.
+func cyclesPerSecond() float64 {
+
+	cyclesOnce.Do(func() {
+		var buf bytes.Buffer
+		pprof.Lookup("mutex").WriteTo(&buf, 1)
+		sc := bufio.NewScanner(&buf)
+		for sc.Scan() {
+			if v, ok := strings.CutPrefix(sc.Text(), "cycles/second="); ok {
+				cyclesHz, _ = strconv.ParseFloat(v, 64)
+				break
+			}
+		}
+		if cyclesHz <= 0 {
+			cyclesHz = 1e9
+		}
+	})
+
+	return cyclesHz
+}
+
+// records turns raw entries into sorted records, scaled by fraction.
+func records(entries []raw, fraction int) []Record {
+
+	fraction = max(fraction, 1)
+	hz := cyclesPerSecond()
+
+	out := make([]Record, 0, len(entries))
+	for _, e := range entries {
+		if e.count <= 0 {
+			continue
+		}
+		r := Record{
+			Count: e.count * int64(fraction),
+			Delay: time.Duration(float64(e.cycles) / hz * float64(fraction) * float64(time.Second)),
+		}
+		frames := runtime.CallersFrames(e.stack)
+		for {
+			f, more := frames.Next()
+			r.Stack = append(r.Stack, Frame{Function: f.Function, File: f.File, Line: f.Line})
+			if !more {
+				break
+			}
+		}
+		out = append(out, r)
+	}
+
+	slices.SortFunc(out, func(a, b Record) int {
+		if c := cmp.Compare(b.Delay, a.Delay); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Count, a.Count)
+	})
+
+	return out
+}
+
+// Snapshot returns every contended stack since the program started, most
+// delay first. It is empty unless the mutex profile is enabled.
+func Snapshot() []Record {
+
+	var entries []raw
+	for _, e := range read() {
+		entries = append(entries, e)
+	}
+
+	return records(entries, runtime.SetMutexProfileFraction(-1))
+}
+
+type config struct {
+	interval time.Duration
+	top      int
+	fraction int
+	out      io.Writer
+	onReport func([]Record)
+}
+
+// Option configures a Reporter.
+type Option = options.Option[config]
+
+// WithInterval sets how often a report is made. Defaults to ten seconds.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithTop sets how many stacks a report holds. Defaults to 10.
+func WithTop(n int) Option {
+	return options.New("WithTop", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one stack")
+		}
+		c.top = n
+		return nil
+	})
+}
+
+// WithFraction sets runtime.SetMutexProfileFraction while the reporter
+// runs; Close restores the previous value. Defaults to 5, recording one in
+// five events.
+func WithFraction(n int) Option {
+	return options.New("WithFraction", func(c *config) error {
+		if n < 1 {
+			return errors.New("fraction must be positive")
+		}
+		c.fraction = n
+		return nil
+	})
+}
+
+// WithOutput sets where reports are printed. Defaults to os.Stderr; nil
+// prints nothing, for use with WithOnReport.
+func WithOutput(w io.Writer) Option {
+	return options.New("WithOutput", func(c *config) error {
+		c.out = w
+		return nil
+	})
+}
+
+// WithOnReport is called with the records of every interval that had
+// contention.
+func WithOnReport(fn func([]Record)) Option {
+	return options.New("WithOnReport", func(c *config) error {
+		c.onReport = fn
+		return nil
+	})
+}
+
+// Reporter reports the contention of each interval until Close.
+type Reporter struct {
+	cfg      config
+	previous int
+	bg       *syncx.Background
+
+	mu   sync.Mutex
+	last map[key]raw
+}
+
+// Start enables the mutex profile and starts reporting.
+func Start(opts ...Option) (*Reporter, error) {
+
+	cfg, err := options.Build(config{
+		interval: 10 * time.Second,
+		top:      10,
+		fraction: 5,
+		out:      os.Stderr,
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reporter{
+		cfg:      cfg,
+		previous: runtime.SetMutexProfileFraction(cfg.fraction),
+		last:     read(),
+	}
+	r.bg = syncx.Go(r.loop)
+
+	return r, nil
+}
+
+// loop reports until quit and then restores the previous profile
+// fraction.
+func (r *Reporter) loop(quit <-chan struct{}) {
+
+	defer runtime.SetMutexProfileFraction(r.previous)
+
+	ticker := time.NewTicker(r.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			recs := r.Interval()
+			if len(recs) == 0 {
+				continue
+			}
+			if r.cfg.onReport != nil {
+				r.cfg.onReport(recs)
+			}
+			if r.cfg.out != nil {
+				Print(r.cfg.out, recs, r.cfg.top)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// Interval returns the contention since the previous call, or since
+// Start, and starts a new interval.
+func (r *Reporter) Interval() []Record {
+
+	cur := read()
+
+	r.mu.Lock()
+	prev := r.last
+	r.last = cur
+	r.mu.Unlock()
+
+	var delta []raw
+	for k, e := range cur {
+		p := prev[k]
+		e.count -= p.count
+		e.cycles -= p.cycles
+		delta = append(delta, e)
+	}
+
+	return records(delta, r.cfg.fraction)
+}
+
+// Close stops reporting and restores the previous profile fraction.
+func (r *Reporter) Close() {
+
+	r.bg.Stop()
+}
+
+// Print writes the top n records, each with its stack.
+func Print(w io.Writer, recs []Record, n int) error {
+
+	bw := bufio.NewWriter(w)
+
+	var total time.Duration
+	for _, r := range recs {
+		total += r.Delay
+	}
+	fmt.Fprintf(bw, "mutex contention: %v waited over %d stacks\n", total.Round(time.Microsecond), len(recs))
+
+	for i, r := range recs[:min(n, len(recs))] {
+		site := r.Site()
+		fmt.Fprintf(bw, "#%d  %v in %d waits  %s\n", i+1, r.Delay.Round(time.Microsecond), r.Count, site.Function)
+		for _, f := range r.Stack {
+			fmt.Fprintf(bw, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+	}
+
+	return bw.Flush()
+}
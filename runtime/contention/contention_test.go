@@ -0,0 +1,90 @@
+package contention_test
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/runtime/contention"
+)
+
+// holdLock makes goroutines queue on a mutex held for a while each time.
+func holdLock() {
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				mu.Lock()
+				time.Sleep(time.Millisecond)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestReporterFindsHotLock(t *testing.T) {
+
+	r, err := contention.Start(
+		contention.WithFraction(1),
+		contention.WithInterval(time.Hour), // drive it with Interval instead
+		contention.WithOutput(nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	holdLock()
+
+	recs := r.Interval()
+	if len(recs) == 0 {
+		t.Fatal("Expected contention records after holding a lock across goroutines")
+	}
+	top := recs[0]
+	if top.Count == 0 || top.Delay <= 0 {
+		t.Errorf("Expected a count and a delay but got %+v", top)
+	}
+	if site := top.Site(); site.Function != "pacx/runtime/contention_test.holdLock.func1" {
+		t.Errorf("Expected the site to be the goroutine holding the lock but got %s", site.Function)
+	}
+
+	if again := r.Interval(); len(again) != 0 && again[0].Delay >= top.Delay {
+		t.Errorf("Expected the next interval to start from zero but got %+v", again[0])
+	}
+
+	var buf bytes.Buffer
+	contention.Print(&buf, recs, 1)
+	if !strings.Contains(buf.String(), "#1 ") || strings.Contains(buf.String(), "#2 ") {
+		t.Errorf("Expected exactly one record printed but got\n%s", buf.String())
+	}
+}
+
+func TestBadOption(t *testing.T) {
+
+	if _, err := contention.Start(contention.WithTop(0)); err == nil {
+		t.Error("Expected an error for zero stacks")
+	}
+}
+
+func TestCloseRestores(t *testing.T) {
+
+	defer runtime.SetMutexProfileFraction(runtime.SetMutexProfileFraction(0))
+
+	r, err := contention.Start(contention.WithInterval(time.Millisecond), contention.WithOutput(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	r.Close()
+	if got := runtime.SetMutexProfileFraction(-1); got != 0 {
+		t.Errorf("Expected the mutex fraction restored to 0 but got %d", got)
+	}
+}
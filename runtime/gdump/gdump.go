@@ -0,0 +1,273 @@
+// Package gdump writes readable dumps of all goroutines. Where
+// runtime/num.go prints a raw GoroutineProfile record per goroutine, a
+// dump groups the goroutines that are in the same state with the same
+// stack, so a thousand idle workers show up as one entry with a count and
+// the odd goroutine out stands out.
+//
+// Install adds a signal handler that writes a dump to a file, by default
+// on SIGQUIT and SIGUSR1. Taking SIGQUIT over means the program no longer
+// exits with the runtime's own dump when it gets one.
+package gdump
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pacx/options"
+	"pacx/sync/syncx"
+)
+
+// Group is a set of goroutines in the same state with the same stack.
+type Group struct {
+	State string  // wait reason, e.g. "chan receive", or "running"
+	IDs   []int64 // ascending
+	Stack string  // as printed by the runtime, argument values removed
+}
+
+// Count is the number of goroutines in the group.
+func (g Group) Count() int {
+	return len(g.IDs)
+}
+
+var (
+	header = regexp.MustCompile(`^goroutine (\d+) (?:gp=\S+ m=\S+ (?:mp=\S+ )?)?\[([^\]]+)\]:`)
+	// argument values, frame offsets and the creating goroutine differ
+	// between goroutines that are otherwise at the same place
+	args    = regexp.MustCompile(`\(.*\)$`)
+	offset  = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+	creator = regexp.MustCompile(` in goroutine \d+$`)
+)
+
+// stacks returns the text of runtime.Stack for all goroutines.
+func stacks() []byte {
+
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// parse groups the goroutines of a runtime.Stack dump.
+func parse(dump []byte) []Group {
+
+	byKey := make(map[string]*Group)
+
+	for _, block := range bytes.Split(dump, []byte("\n\n")) {
+		first, rest, _ := bytes.Cut(block, []byte("\n"))
+		m := header.FindSubmatch(first)
+		if m == nil {
+			continue
+		}
+		id, _ := strconv.ParseInt(string(m[1]), 10, 64)
+
+		// "chan receive, 2 minutes, locked to thread": how long it
+		// waited would split otherwise identical goroutines
+		state, _, _ := strings.Cut(string(m[2]), ", ")
+
+		var sb strings.Builder
+		for _, line := range strings.Split(strings.TrimRight(string(rest), "\n"), "\n") {
+			switch {
+			case strings.HasPrefix(line, "\t"):
+				line = offset.ReplaceAllString(line, "")
+			case strings.HasPrefix(line, "created by "):
+				line = creator.ReplaceAllString(line, "")
+			default:
+				line = args.ReplaceAllString(line, "(...)")
+			}
+			sb.WriteString(line)
+			sb.WriteByte('\n')
+		}
+		stack := sb.String()
+
+		key := state + "\x00" + stack
+		g, ok := byKey[key]
+		if !ok {
+			g = &Group{State: state, Stack: stack}
+			byKey[key] = g
+		}
+		g.IDs = append(g.IDs, id)
+	}
+
+	groups := make([]Group, 0, len(byKey))
+	for _, g := range byKey {
+		slices.Sort(g.IDs)
+		groups = append(groups, *g)
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		if c := cmp.Compare(b.Count(), a.Count()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.IDs[0], b.IDs[0])
+	})
+
+	return groups
+}
+
+// Groups returns the goroutines of the process grouped by state and
+// stack, largest group first.
+func Groups() []Group {
+	return parse(stacks())
+}
+
+// Write prints groups, largest first.
+func Write(w io.Writer, groups []Group) error {
+
+	bw := bufio.NewWriter(w)
+
+	total := 0
+	for _, g := range groups {
+		total += g.Count()
+	}
+	fmt.Fprintf(bw, "%d goroutines in %d groups at %s\n", total, len(groups), time.Now().Format(time.RFC3339))
+
+	for _, g := range groups {
+		fmt.Fprintf(bw, "\n%d goroutines [%s]: %s\n%s", g.Count(), g.State, ids(g.IDs), g.Stack)
+	}
+
+	return bw.Flush()
+}
+
+// Dump writes the grouped goroutines of the process to w.
+func Dump(w io.Writer) error {
+	return Write(w, Groups())
+}
+
+// ids lists up to ten ids.
+func ids(all []int64) string {
+
+	var sb strings.Builder
+	for i, id := range all {
+		if i == 10 {
+			fmt.Fprintf(&sb, " and %d more", len(all)-i)
+			break
+		}
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(strconv.FormatInt(id, 10))
+	}
+
+	return sb.String()
+}
+
+type config struct {
+	dir     string
+	signals []os.Signal
+	onDump  func(path string, err error)
+}
+
+// Option configures Install.
+type Option = options.Option[config]
+
+// WithDir sets the directory dumps are written to. Defaults to the
+// working directory.
+func WithDir(dir string) Option {
+	return options.New("WithDir", func(c *config) error {
+		if dir == "" {
+			return errors.New("empty directory")
+		}
+		c.dir = dir
+		return nil
+	})
+}
+
+// WithSignals sets the signals that trigger a dump. Defaults to SIGQUIT
+// and SIGUSR1 where they exist.
+func WithSignals(sigs ...os.Signal) Option {
+	return options.New("WithSignals", func(c *config) error {
+		if len(sigs) == 0 {
+			return errors.New("no signals")
+		}
+		c.signals = sigs
+		return nil
+	})
+}
+
+// WithOnDump is called after every dump with the file written, or the
+// error that stopped it. Defaults to a line on stderr.
+func WithOnDump(fn func(path string, err error)) Option {
+	return options.New("WithOnDump", func(c *config) error {
+		c.onDump = fn
+		return nil
+	})
+}
+
+// Install writes a dump to goroutines-<timestamp>.txt every time the
+// process gets one of the signals, until the returned stop is called.
+func Install(opts ...Option) (stop func(), err error) {
+
+	cfg, err := options.Build(config{
+		dir:     ".",
+		signals: defaultSignals,
+		onDump: func(path string, err error) {
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "gdump:", err)
+				return
+			}
+			fmt.Fprintln(os.Stderr, "gdump: goroutines written to", path)
+		},
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.signals) == 0 {
+		return nil, errors.New("gdump: no dump signals on this platform")
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, cfg.signals...)
+	bg := syncx.Go(func(quit <-chan struct{}) {
+		for {
+			select {
+			case <-ch:
+				path, err := dumpFile(cfg.dir)
+				if cfg.onDump != nil {
+					cfg.onDump(path, err)
+				}
+			case <-quit:
+				return
+			}
+		}
+	})
+
+	return func() {
+		signal.Stop(ch)
+		bg.Stop()
+	}, nil
+}
+
+func dumpFile(dir string) (string, error) {
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "goroutines-"+time.Now().Format("20060102-150405.000")+".txt")
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := Dump(f); err != nil {
+		f.Close()
+		return "", err
+	}
+
+	return path, f.Close()
+}
@@ -0,0 +1,53 @@
+package gdump_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"pacx/runtime/gdump"
+)
+
+func parked(ready *sync.WaitGroup, release chan struct{}, v int) {
+	ready.Done()
+	<-release
+	_ = v
+}
+
+func TestGroupsIdenticalStacks(t *testing.T) {
+
+	release := make(chan struct{})
+	defer close(release)
+
+	var ready sync.WaitGroup
+	ready.Add(5)
+	for i := 0; i < 5; i++ {
+		go parked(&ready, release, i) // different arguments, same place
+	}
+	ready.Wait()
+
+	var found *gdump.Group
+	for _, g := range gdump.Groups() {
+		if strings.Contains(g.Stack, "gdump_test.parked") {
+			if found != nil {
+				t.Fatalf("Expected the parked goroutines in one group but found a second:\n%s", g.Stack)
+			}
+			found = &g
+		}
+	}
+	if found == nil {
+		t.Fatal("Expected a group for the parked goroutines")
+	}
+	if found.Count() != 5 || found.State != "chan receive" {
+		t.Errorf("Expected 5 goroutines in chan receive but got %d in %q", found.Count(), found.State)
+	}
+
+	var buf bytes.Buffer
+	if err := gdump.Write(&buf, []gdump.Group{*found}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "5 goroutines [chan receive]:") {
+		t.Errorf("Expected a count header in the dump but got\n%s", buf.String())
+	}
+}
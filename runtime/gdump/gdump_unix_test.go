@@ -0,0 +1,49 @@
+//go:build unix
+
+package gdump_test
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"pacx/runtime/gdump"
+)
+
+func TestSignalWritesFile(t *testing.T) {
+
+	dir := t.TempDir()
+	dumped := make(chan string, 1)
+
+	stop, err := gdump.Install(
+		gdump.WithDir(dir),
+		gdump.WithSignals(syscall.SIGUSR1),
+		gdump.WithOnDump(func(path string, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			dumped <- path
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+
+	select {
+	case path := <-dumped:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "TestSignalWritesFile") {
+			t.Errorf("Expected the test's own goroutine in the dump but got\n%s", data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a dump after SIGUSR1")
+	}
+}
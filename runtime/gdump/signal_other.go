@@ -0,0 +1,8 @@
+//go:build !unix
+
+package gdump
+
+import "os"
+
+// no SIGQUIT or SIGUSR1; WithSignals has to pick something
+var defaultSignals []os.Signal
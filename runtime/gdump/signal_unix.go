@@ -0,0 +1,10 @@
+//go:build unix
+
+package gdump
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultSignals = []os.Signal{syscall.SIGQUIT, syscall.SIGUSR1}
@@ -0,0 +1,169 @@
+// Package leakcheck tells whether a function leaves memory behind. It runs
+// the function a few times to let caches and pools reach their working
+// size, takes a heap baseline, runs it many more times and measures again.
+// Both measurements are taken after full collections, so only memory that
+// is still reachable counts.
+//
+// Memory a function keeps on purpose shows up once; memory it leaks grows
+// with every call. The tolerance should sit between the two.
+package leakcheck
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"pacx/options"
+)
+
+// ErrLeak is returned, wrapped, when retained memory grew beyond the
+// tolerance.
+var ErrLeak = errors.New("leakcheck: retained memory grew")
+
+type config struct {
+	iterations int
+	warmup     int
+	maxBytes   int64
+	maxObjects int64
+}
+
+// Option configures Check.
+type Option = options.Option[config]
+
+// WithIterations sets how many times fn runs between the two
+// measurements. Defaults to 1000.
+func WithIterations(n int) Option {
+	return options.New("WithIterations", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one iteration")
+		}
+		c.iterations = n
+		return nil
+	})
+}
+
+// WithWarmup sets how many times fn runs before the baseline. Defaults to
+// 10.
+func WithWarmup(n int) Option {
+	return options.New("WithWarmup", func(c *config) error {
+		if n < 0 {
+			return errors.New("warmup must not be negative")
+		}
+		c.warmup = n
+		return nil
+	})
+}
+
+// WithTolerance sets how much the live heap and the number of live
+// objects may grow over all iterations. Defaults to 256 KiB and 512
+// objects.
+func WithTolerance(bytes, objects int64) Option {
+	return options.New("WithTolerance", func(c *config) error {
+		if bytes < 0 || objects < 0 {
+			return errors.New("tolerance must not be negative")
+		}
+		c.maxBytes, c.maxObjects = bytes, objects
+		return nil
+	})
+}
+
+// Report is the outcome of a check.
+type Report struct {
+	Iterations      int
+	BaselineBytes   uint64
+	FinalBytes      uint64
+	BaselineObjects uint64
+	FinalObjects    uint64
+	MaxBytes        int64
+	MaxObjects      int64
+}
+
+// Bytes is how much the live heap grew.
+func (r *Report) Bytes() int64 {
+	return int64(r.FinalBytes) - int64(r.BaselineBytes)
+}
+
+// Objects is how much the number of live objects grew.
+func (r *Report) Objects() int64 {
+	return int64(r.FinalObjects) - int64(r.BaselineObjects)
+}
+
+// Leaked reports whether either growth is beyond its tolerance.
+func (r *Report) Leaked() bool {
+	return r.Bytes() > r.MaxBytes || r.Objects() > r.MaxObjects
+}
+
+func (r *Report) String() string {
+	return fmt.Sprintf("%d iterations: heap %+d bytes (%.1f per call, tolerance %d), objects %+d (%.2f per call, tolerance %d)",
+		r.Iterations,
+		r.Bytes(), float64(r.Bytes())/float64(r.Iterations), r.MaxBytes,
+		r.Objects(), float64(r.Objects())/float64(r.Iterations), r.MaxObjects)
+}
+
+// Check runs fn and reports how much memory it left live. The error is
+// ErrLeak, wrapped with the report, if the growth is beyond the
+// tolerance; the report is returned in every case but a bad option.
+func Check(fn func(), opts ...Option) (*Report, error) {
+
+	cfg, err := options.Build(config{
+		iterations: 1000,
+		warmup:     10,
+		maxBytes:   256 << 10,
+		maxObjects: 512,
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < cfg.warmup; i++ {
+		fn()
+	}
+
+	r := &Report{Iterations: cfg.iterations, MaxBytes: cfg.maxBytes, MaxObjects: cfg.maxObjects}
+	r.BaselineBytes, r.BaselineObjects = live()
+
+	for i := 0; i < cfg.iterations; i++ {
+		fn()
+	}
+
+	r.FinalBytes, r.FinalObjects = live()
+
+	if r.Leaked() {
+		return r, fmt.Errorf("%w: %s", ErrLeak, r)
+	}
+
+	return r, nil
+}
+
+// live returns the bytes and objects still reachable. The second GC
+// collects what finalizers released during the first.
+func live() (bytes, objects uint64) {
+
+	runtime.GC()
+	runtime.GC()
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	return ms.HeapAlloc, ms.HeapObjects
+}
+
+// TB is the part of testing.TB that Verify needs.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Logf(format string, args ...any)
+}
+
+// Verify runs Check and fails the test if fn leaks.
+func Verify(t TB, fn func(), opts ...Option) {
+
+	t.Helper()
+
+	r, err := Check(fn, opts...)
+	if err != nil {
+		t.Errorf("%v", err)
+		return
+	}
+	t.Logf("leakcheck: %s", r)
+}
@@ -0,0 +1,68 @@
+package leakcheck_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pacx/concurrency/pipeline"
+	"pacx/concurrency/pool"
+	"pacx/runtime/leakcheck"
+)
+
+var retained [][]byte
+
+func TestDetectsLeak(t *testing.T) {
+
+	defer func() { retained = nil }()
+
+	r, err := leakcheck.Check(func() {
+		retained = append(retained, make([]byte, 1024))
+	}, leakcheck.WithIterations(500))
+
+	if !errors.Is(err, leakcheck.ErrLeak) {
+		t.Fatalf("Expected ErrLeak but got %v", err)
+	}
+	if r.Bytes() < 500*1024 || r.Objects() < 500 {
+		t.Errorf("Expected at least 500 KiB and 500 objects retained but got %s", r)
+	}
+}
+
+func TestGarbageIsNotALeak(t *testing.T) {
+
+	var sink []byte
+	leakcheck.Verify(t, func() {
+		sink = make([]byte, 4096)
+	})
+	_ = sink
+}
+
+func TestPoolDoesNotLeak(t *testing.T) {
+
+	leakcheck.Verify(t, func() {
+		p, err := pool.New(pool.WithWorkers(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 16; i++ {
+			p.Submit(context.Background(), func() {})
+		}
+		p.Close()
+	}, leakcheck.WithIterations(200))
+}
+
+func TestPipelineDoesNotLeak(t *testing.T) {
+
+	leakcheck.Verify(t, func() {
+		ctx := context.Background()
+		double := pipeline.Map("double", func(v int) int { return v * 2 }, pipeline.WithWorkers(4))
+		pipeline.Collect(ctx, double(ctx, pipeline.Source(ctx, []int{1, 2, 3, 4})))
+	}, leakcheck.WithIterations(200))
+}
+
+func TestBadOption(t *testing.T) {
+
+	if _, err := leakcheck.Check(func() {}, leakcheck.WithIterations(0)); err == nil {
+		t.Error("Expected an error for zero iterations")
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 	"time"
+
+	"pacx/runtime/gdump"
 )
 
 func worke(id int) {
@@ -45,4 +48,7 @@ func main() {
 
 	// Profile the goroutines
 	profileGoroutines()
+
+	// The same goroutines, grouped by stack and readable
+	gdump.Dump(os.Stdout)
 }
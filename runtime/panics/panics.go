@@ -0,0 +1,238 @@
+// Package panics counts recovered panics per component and keeps the last
+// one of each, so an operator can see that pipeline stage "square" has
+// panicked 14 times since start and what it panicked with most recently,
+// without digging through logs.
+//
+// Components recover with a deferred call to Recover, or run goroutines
+// through Go. A Registry can mirror its counts into a metrics.Registry as
+// counters named "panics.<component>", and serves its state as JSON for a
+// diagnostics endpoint.
+package panics
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+
+	"pacx/metrics"
+	"pacx/options"
+)
+
+// Panic is one recovered panic.
+type Panic struct {
+	Component string    `json:"component"`
+	Value     string    `json:"value"`
+	Stack     string    `json:"stack"`
+	Time      time.Time `json:"time"`
+}
+
+// Stats are the totals for one component.
+type Stats struct {
+	Component string    `json:"component"`
+	Count     int64     `json:"count"`
+	First     time.Time `json:"first"`
+	Last      Panic     `json:"last"`
+}
+
+type config struct {
+	metrics *metrics.Registry
+	onPanic func(Panic)
+}
+
+// Option configures a Registry.
+type Option = options.Option[config]
+
+// WithMetrics adds a counter per component to m.
+func WithMetrics(m *metrics.Registry) Option {
+	return options.New("WithMetrics", func(c *config) error {
+		c.metrics = m
+		return nil
+	})
+}
+
+// WithOnPanic is called with every panic after it is counted, for example
+// to log it.
+func WithOnPanic(fn func(Panic)) Option {
+	return options.New("WithOnPanic", func(c *config) error {
+		c.onPanic = fn
+		return nil
+	})
+}
+
+// Registry is safe for concurrent use.
+type Registry struct {
+	cfg   config
+	start time.Time
+
+	mu    sync.Mutex
+	stats map[string]*Stats
+}
+
+// Default is the registry used by the package level functions.
+var Default, _ = New()
+
+// New returns an empty registry.
+func New(opts ...Option) (*Registry, error) {
+
+	cfg, err := options.Build(config{}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{cfg: cfg, start: time.Now(), stats: make(map[string]*Stats)}, nil
+}
+
+// Record counts a panic with value v against component. stack is usually
+// debug.Stack() taken in the deferred function.
+func (r *Registry) Record(component string, v any, stack []byte) Panic {
+
+	p := Panic{Component: component, Value: fmt.Sprint(v), Stack: string(stack), Time: time.Now()}
+
+	r.mu.Lock()
+	s, ok := r.stats[component]
+	if !ok {
+		s = &Stats{Component: component, First: p.Time}
+		r.stats[component] = s
+	}
+	s.Count++
+	s.Last = p
+	r.mu.Unlock()
+
+	if r.cfg.metrics != nil {
+		r.cfg.metrics.Counter("panics." + component).Add(1)
+	}
+	if r.cfg.onPanic != nil {
+		r.cfg.onPanic(p)
+	}
+
+	return p
+}
+
+// Recover stops a panic and records it against component. It must be
+// called directly by defer:
+//
+//	defer reg.Recover("square")
+func (r *Registry) Recover(component string) {
+	if v := recover(); v != nil {
+		r.Record(component, v, debug.Stack())
+	}
+}
+
+// Go runs fn on a new goroutine; a panic in fn is recorded instead of
+// crashing the process.
+func (r *Registry) Go(component string, fn func()) {
+	go func() {
+		defer r.Recover(component)
+		fn()
+	}()
+}
+
+// Count returns how often component has panicked.
+func (r *Registry) Count(component string) int64 {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.stats[component]; ok {
+		return s.Count
+	}
+
+	return 0
+}
+
+// Last returns the most recent panic of component.
+func (r *Registry) Last(component string) (Panic, bool) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.stats[component]
+	if !ok {
+		return Panic{}, false
+	}
+
+	return s.Last, true
+}
+
+// Snapshot returns the stats of every component that panicked, most
+// panics first.
+func (r *Registry) Snapshot() []Stats {
+
+	r.mu.Lock()
+	out := make([]Stats, 0, len(r.stats))
+	for _, s := range r.stats {
+		out = append(out, *s)
+	}
+	r.mu.Unlock()
+
+	slices.SortFunc(out, func(a, b Stats) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Component, b.Component)
+	})
+
+	return out
+}
+
+// WriteTo prints a summary line per component followed by its last
+// stack, for a diagnostics bundle.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+
+	stats := r.Snapshot()
+	fmt.Fprintf(bw, "panics since %s: %d components\n", r.start.Format(time.RFC3339), len(stats))
+	for _, s := range stats {
+		fmt.Fprintf(bw, "\n%s: %d panics, last at %s: %s\n%s", s.Component, s.Count, s.Last.Time.Format(time.RFC3339), s.Last.Value, s.Last.Stack)
+	}
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Since      time.Time `json:"since"`
+		Components []Stats   `json:"components"`
+	}{r.start, r.Snapshot()})
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Recover records a panic in Default. It must be called directly by defer.
+func Recover(component string) {
+	if v := recover(); v != nil {
+		Default.Record(component, v, debug.Stack())
+	}
+}
+
+// Go runs fn on a new goroutine, recording a panic in Default.
+func Go(component string, fn func()) {
+	Default.Go(component, fn)
+}
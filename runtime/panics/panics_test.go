@@ -0,0 +1,93 @@
+package panics_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pacx/metrics"
+	"pacx/runtime/panics"
+)
+
+func TestRecordCountsPerComponent(t *testing.T) {
+
+	var m metrics.Registry
+	var seen []panics.Panic
+	r, err := panics.New(panics.WithMetrics(&m), panics.WithOnPanic(func(p panics.Panic) { seen = append(seen, p) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		func() {
+			defer r.Recover("square")
+			panic(i)
+		}()
+	}
+	func() {
+		defer r.Recover("half")
+		panic("boom")
+	}()
+
+	if r.Count("square") != 3 || r.Count("half") != 1 || r.Count("filter") != 0 {
+		t.Errorf("Expected 3, 1 and 0 panics but got %d, %d and %d", r.Count("square"), r.Count("half"), r.Count("filter"))
+	}
+	last, ok := r.Last("square")
+	if !ok || last.Value != "2" || !strings.Contains(last.Stack, "TestRecordCountsPerComponent") {
+		t.Errorf("Expected the last square panic to be 2 with its stack but got %+v", last)
+	}
+	if got := m.Snapshot("test").Counters["panics.square"]; got != 3 {
+		t.Errorf("Expected counter panics.square to be 3 but got %d", got)
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 calls of the hook but got %d", len(seen))
+	}
+
+	s := r.Snapshot()
+	if len(s) != 2 || s[0].Component != "square" {
+		t.Errorf("Expected square first of two components but got %+v", s)
+	}
+}
+
+func TestGo(t *testing.T) {
+
+	done := make(chan panics.Panic, 1)
+	r, _ := panics.New(panics.WithOnPanic(func(p panics.Panic) { done <- p }))
+
+	r.Go("worker", func() { panic("worker died") })
+
+	if p := <-done; p.Component != "worker" || p.Value != "worker died" {
+		t.Errorf("Expected the worker's panic but got %+v", p)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+
+	r, _ := panics.New()
+	func() {
+		defer r.Recover("square")
+		panic("bad input")
+	}()
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "square: 1 panics") || !strings.Contains(buf.String(), "bad input") {
+		t.Errorf("Expected the component in the report but got\n%s", buf.String())
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var body struct {
+		Components []panics.Stats
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Components) != 1 || body.Components[0].Count != 1 {
+		t.Errorf("Expected one component with one panic but got %+v", body.Components)
+	}
+}
@@ -0,0 +1,92 @@
+// Command scenario runs a workload described in a JSON file and prints
+// what each stage did.
+//
+//	go run ./runtime/scenario/cmd/scenario runtime/scenario/testdata/degraded.json
+//	go run ./runtime/scenario/cmd/scenario -check runtime/scenario/testdata/degraded.json
+//
+// Besides the built-in cpu, alloc and sleep operations it knows pipeline,
+// which squares and sums Work numbers on a fan-out like the soak command's
+// pipeline workload.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"time"
+
+	"pacx/concurrency/pipeline"
+	"pacx/runtime/scenario"
+)
+
+func main() {
+
+	check := flag.Bool("check", false, "only parse and validate the file")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: scenario [-check] file.json")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sc, err := scenario.Load(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var total time.Duration
+	for _, st := range sc.Stages {
+		total += time.Duration(st.Duration)
+	}
+	if *check {
+		fmt.Printf("%s: %d stages, %v\n", sc.Name, len(sc.Stages), total)
+		return
+	}
+
+	// Ctrl-C stops the run but still prints the stages so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("running %s for %v\n", sc.Name, total)
+	res, err := scenario.Run(ctx, sc,
+		scenario.WithOp("pipeline", pipelineOp),
+		scenario.WithOnStage(func(r scenario.StageResult) {
+			fmt.Printf("  %s: %d ops, %d errors, %d panics\n", r.Name, r.Ops, r.Errors, r.Panics)
+		}),
+	)
+	if res != nil {
+		res.Print(os.Stdout)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// pipelineOp squares and sums Work numbers on four workers.
+func pipelineOp(op scenario.Op) (scenario.OpFunc, error) {
+
+	if op.Work <= 0 {
+		return nil, errors.New("pipeline needs work > 0")
+	}
+	inputs := make([]int, op.Work)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	return func(ctx context.Context, _ *rand.Rand) error {
+		_, err := pipeline.MapReduce(ctx, inputs,
+			func(v int) int { return v * v },
+			func(acc, v int) int { return acc + v },
+			4)
+		return err
+	}, nil
+}
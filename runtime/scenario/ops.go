@@ -0,0 +1,99 @@
+package scenario
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// OpFunc runs one operation. It gets the worker's random source, which is
+// not safe to share with other goroutines.
+type OpFunc func(ctx context.Context, rng *rand.Rand) error
+
+// Builder turns an Op from a scenario file into the function its stage
+// runs. It should reject fields that make no sense for its kind.
+type Builder func(Op) (OpFunc, error)
+
+// builtin are the kinds every runner knows; WithOp adds more or replaces
+// these.
+var builtin = map[string]Builder{
+	"cpu":   cpuOp,
+	"alloc": allocOp,
+	"sleep": sleepOp,
+}
+
+// Builtin returns the builder of a built-in op kind, for running the ops
+// of scenario files outside Run.
+func Builtin(kind string) (Builder, bool) {
+	b, ok := builtin[kind]
+	return b, ok
+}
+
+// keep the results of cpu and alloc, so they are not optimised away
+var (
+	spun atomic.Uint64
+	sink atomic.Pointer[[]byte]
+)
+
+// cpuOp spins for Work iterations, like the busy loops of the profiling
+// demos.
+func cpuOp(op Op) (OpFunc, error) {
+
+	if op.Work <= 0 {
+		return nil, errors.New("cpu needs work > 0")
+	}
+
+	return func(context.Context, *rand.Rand) error {
+		x := uint64(1)
+		for i := 0; i < op.Work; i++ {
+			x = x*6364136223846793005 + 1442695040888963407
+		}
+		spun.Store(x)
+		return nil
+	}, nil
+}
+
+// allocOp allocates Work bytes of garbage.
+func allocOp(op Op) (OpFunc, error) {
+
+	if op.Work <= 0 {
+		return nil, errors.New("alloc needs work > 0")
+	}
+
+	return func(context.Context, *rand.Rand) error {
+		b := make([]byte, op.Work)
+		sink.Store(&b)
+		return nil
+	}, nil
+}
+
+// sleepOp waits Latency plus up to Jitter, like a call to a slow service.
+func sleepOp(op Op) (OpFunc, error) {
+
+	if op.Latency < 0 || op.Jitter < 0 {
+		return nil, errors.New("sleep needs latency and jitter >= 0")
+	}
+
+	return func(ctx context.Context, rng *rand.Rand) error {
+		d := time.Duration(op.Latency)
+		if op.Jitter > 0 {
+			d += time.Duration(rng.Int64N(int64(op.Jitter)))
+		}
+		return sleep(ctx, d)
+	}, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
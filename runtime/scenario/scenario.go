@@ -0,0 +1,267 @@
+// Package scenario runs workloads described in a file instead of another
+// main.go. A scenario is a list of stages; each stage repeats one
+// operation on some workers, at a rate, for a duration, with a fraction of
+// the operations failing, panicking or slowed down on purpose:
+//
+//	{
+//	  "name": "slow dependency",
+//	  "stages": [
+//	    {"name": "warm", "duration": "5s", "workers": 4, "rate": 200,
+//	     "op": {"kind": "cpu", "work": 20000}},
+//	    {"name": "degraded", "duration": "10s", "workers": 8, "rate": 500,
+//	     "op": {"kind": "sleep", "latency": "2ms", "jitter": "3ms"},
+//	     "faults": {"error": 0.05, "panic": 0.01, "slow": 0.1, "slow_by": "50ms"}}
+//	  ]
+//	}
+//
+// The built-in operations are cpu, alloc and sleep; a program can register
+// its own, such as a pipeline run, with WithOp. Files are JSON: the repo
+// has no YAML parser and does not take dependencies for one.
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"pacx/concurrency/ratelimit"
+	"pacx/options"
+	"pacx/runtime/panics"
+)
+
+// ErrInjected is the error of operations failed by Faults.Error.
+var ErrInjected = errors.New("scenario: injected failure")
+
+type config struct {
+	ops     map[string]Builder
+	onStage func(StageResult)
+}
+
+// Option configures Run.
+type Option = options.Option[config]
+
+// WithOp registers the operation kind name, or replaces a built-in one.
+func WithOp(name string, build Builder) Option {
+	return options.New("WithOp", func(c *config) error {
+		if name == "" || build == nil {
+			return errors.New("op needs a name and a builder")
+		}
+		c.ops[name] = build
+		return nil
+	})
+}
+
+// WithOnStage is called with the result of every stage as it finishes.
+func WithOnStage(fn func(StageResult)) Option {
+	return options.New("WithOnStage", func(c *config) error {
+		c.onStage = fn
+		return nil
+	})
+}
+
+// StageResult is what one stage did.
+type StageResult struct {
+	Name    string
+	Elapsed time.Duration
+	Ops     int64 // operations that returned, failed ones included
+	Errors  int64
+	Panics  int64
+	P50     time.Duration // latency of the operations that succeeded
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// Throughput is operations per second.
+func (r StageResult) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Ops) / r.Elapsed.Seconds()
+}
+
+// Result is the outcome of a scenario.
+type Result struct {
+	Name   string
+	Stages []StageResult
+}
+
+// Print writes a row per stage.
+func (r *Result) Print(w io.Writer) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "scenario %s\n", r.Name)
+	fmt.Fprintln(tw, "STAGE\tELAPSED\tOPS\tOPS/S\tERRORS\tPANICS\tP50\tP99\tMAX")
+	for _, s := range r.Stages {
+		fmt.Fprintf(tw, "%s\t%v\t%d\t%.0f\t%d\t%d\t%v\t%v\t%v\n",
+			s.Name, s.Elapsed.Round(time.Millisecond), s.Ops, s.Throughput(), s.Errors, s.Panics,
+			s.P50.Round(time.Microsecond), s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+
+	return tw.Flush()
+}
+
+// Run runs the stages of sc in order. Injected errors and panics are
+// counted, not returned; the error is ctx's if it ended the run early, or
+// one of building an operation. The result holds the stages that ran.
+func Run(ctx context.Context, sc *Scenario, opts ...Option) (*Result, error) {
+
+	cfg := config{ops: make(map[string]Builder)}
+	for name, b := range builtin {
+		cfg.ops[name] = b
+	}
+	cfg, err := options.Build(cfg, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// build every op up front, so a bad last stage fails before the first
+	// one has run for an hour
+	fns := make([]OpFunc, len(sc.Stages))
+	for i, st := range sc.Stages {
+		build, ok := cfg.ops[st.Op.Kind]
+		if !ok {
+			return nil, fmt.Errorf("scenario: stage %s: unknown op kind %q", st.Name, st.Op.Kind)
+		}
+		if fns[i], err = build(st.Op); err != nil {
+			return nil, fmt.Errorf("scenario: stage %s: %w", st.Name, err)
+		}
+	}
+
+	seed := sc.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+
+	res := &Result{Name: sc.Name}
+	for i, st := range sc.Stages {
+		r := runStage(ctx, st, fns[i], seed+uint64(i)<<32)
+		res.Stages = append(res.Stages, r)
+		if cfg.onStage != nil {
+			cfg.onStage(r)
+		}
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+	}
+
+	return res, nil
+}
+
+// reservoir is how many latencies each worker keeps for the percentiles.
+const reservoir = 4096
+
+func runStage(parent context.Context, st Stage, fn OpFunc, seed uint64) StageResult {
+
+	ctx, cancel := context.WithTimeout(parent, time.Duration(st.Duration))
+	defer cancel()
+
+	var limiter *ratelimit.Limiter
+	if st.Rate > 0 {
+		limiter = ratelimit.New(st.Rate, st.Workers)
+	}
+	reg, _ := panics.New()
+
+	var (
+		mu        sync.Mutex
+		ops, errs int64
+		latencies []time.Duration
+		wg        sync.WaitGroup
+	)
+	start := time.Now()
+
+	for w := 0; w < st.Workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rng := rand.New(rand.NewPCG(seed, uint64(w)))
+			var n, failed, timed int64
+			var kept []time.Duration
+
+			for {
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					break
+				}
+				if ctx.Err() != nil {
+					break
+				}
+
+				began := time.Now()
+				err := inject(ctx, st, fn, rng, reg)
+				if ctx.Err() != nil {
+					break // cut short by the end of the stage, not a result
+				}
+				n++
+				if err != nil {
+					failed++
+					continue
+				}
+
+				// keep a uniform sample of the latencies
+				d := time.Since(began)
+				timed++
+				if len(kept) < reservoir {
+					kept = append(kept, d)
+				} else if j := rng.Int64N(timed); j < reservoir {
+					kept[j] = d
+				}
+			}
+
+			mu.Lock()
+			ops += n
+			errs += failed
+			latencies = append(latencies, kept...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	r := StageResult{
+		Name:    st.Name,
+		Elapsed: time.Since(start),
+		Ops:     ops,
+		Errors:  errs,
+		Panics:  reg.Count(st.Name),
+	}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		r.P50 = latencies[len(latencies)/2]
+		r.P99 = latencies[len(latencies)*99/100]
+		r.Max = latencies[len(latencies)-1]
+	}
+
+	return r
+}
+
+// inject runs fn with the stage's faults. A panic, injected or not, is
+// recovered and counted in reg and makes the operation fail.
+func inject(ctx context.Context, st Stage, fn OpFunc, rng *rand.Rand, reg *panics.Registry) (err error) {
+
+	// a return overwrites err; after a panic Recover leaves it as is
+	err = errPanicked
+	defer reg.Recover(st.Name)
+
+	f := st.Faults
+	if f.Slow > 0 && rng.Float64() < f.Slow {
+		if err := sleep(ctx, time.Duration(f.SlowBy)); err != nil {
+			return err
+		}
+	}
+	switch p := rng.Float64(); {
+	case p < f.Error:
+		return ErrInjected
+	case p < f.Error+f.Panic:
+		panic("scenario: injected panic")
+	}
+
+	return fn(ctx, rng)
+}
+
+// errPanicked is what inject returns when fn panicked.
+var errPanicked = errors.New("scenario: operation panicked")
@@ -0,0 +1,135 @@
+package scenario_test
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/runtime/scenario"
+)
+
+func TestLoadExample(t *testing.T) {
+
+	sc, err := scenario.Load("testdata/degraded.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sc.Stages) != 3 || sc.Stages[2].Faults.SlowBy != scenario.Duration(50*time.Millisecond) || sc.Stages[1].Op.Kind != "alloc" {
+		t.Errorf("Expected three stages as in the file but got %+v", sc.Stages)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+
+	for name, in := range map[string]string{
+		"typo":       `{"stages": [{"duration": "1s", "op": {"kind": "cpu", "wrok": 1}}]}`,
+		"no stages":  `{"name": "x"}`,
+		"duration":   `{"stages": [{"duration": 5, "op": {"kind": "cpu"}}]}`,
+		"no kind":    `{"stages": [{"duration": "1s"}]}`,
+		"fraction":   `{"stages": [{"duration": "1s", "op": {"kind": "cpu"}, "faults": {"error": 1.5}}]}`,
+		"over 1":     `{"stages": [{"duration": "1s", "op": {"kind": "cpu"}, "faults": {"error": 0.6, "panic": 0.6}}]}`,
+		"no workers": `{"stages": [{"duration": "1s", "workers": -1, "op": {"kind": "cpu"}}]}`,
+	} {
+		if _, err := scenario.Parse(strings.NewReader(in)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestRunCountsFaults(t *testing.T) {
+
+	sc, err := scenario.Parse(strings.NewReader(`{
+		"name": "faults",
+		"seed": 7,
+		"stages": [
+			{"name": "faulty", "duration": "200ms", "workers": 4,
+			 "op": {"kind": "count"},
+			 "faults": {"error": 0.2, "panic": 0.1}}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ran atomic.Int64
+	res, err := scenario.Run(context.Background(), sc, scenario.WithOp("count", func(scenario.Op) (scenario.OpFunc, error) {
+		return func(context.Context, *rand.Rand) error {
+			ran.Add(1)
+			time.Sleep(100 * time.Microsecond)
+			return nil
+		}, nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := res.Stages[0]
+	if r.Ops < 100 {
+		t.Fatalf("Expected at least 100 operations but got %d", r.Ops)
+	}
+	if got := float64(r.Errors-r.Panics) / float64(r.Ops); got < 0.1 || got > 0.3 {
+		t.Errorf("Expected about 20%% injected errors but got %.2f", got)
+	}
+	if got := float64(r.Panics) / float64(r.Ops); got < 0.03 || got > 0.2 {
+		t.Errorf("Expected about 10%% panics but got %.2f", got)
+	}
+	// an op still running at the end of the stage ran but is not counted
+	if extra := ran.Load() - (r.Ops - r.Errors); extra < 0 || extra > 4 {
+		t.Errorf("Expected the op to run %d times, plus one per worker at most, but it ran %d", r.Ops-r.Errors, ran.Load())
+	}
+	if r.P50 <= 0 || r.P99 < r.P50 || r.Max < r.P99 {
+		t.Errorf("Expected ordered latencies but got %v, %v, %v", r.P50, r.P99, r.Max)
+	}
+}
+
+func TestRunRate(t *testing.T) {
+
+	sc, _ := scenario.Parse(strings.NewReader(`{"stages": [
+		{"duration": "500ms", "workers": 4, "rate": 100, "op": {"kind": "cpu", "work": 10}}
+	]}`))
+
+	res, err := scenario.Run(context.Background(), sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 50 in half a second plus the initial burst of 4
+	if ops := res.Stages[0].Ops; ops < 35 || ops > 60 {
+		t.Errorf("Expected about 54 operations at 100/s but got %d", ops)
+	}
+}
+
+func TestRunUnknownOp(t *testing.T) {
+
+	sc, _ := scenario.Parse(strings.NewReader(`{"stages": [
+		{"duration": "1h", "op": {"kind": "cpu", "work": 10}},
+		{"duration": "1s", "op": {"kind": "teleport"}}
+	]}`))
+
+	start := time.Now()
+	if _, err := scenario.Run(context.Background(), sc); err == nil || !strings.Contains(err.Error(), "teleport") {
+		t.Errorf("Expected an unknown kind error but got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Error("Expected the error before the first stage ran")
+	}
+}
+
+func TestRunCancel(t *testing.T) {
+
+	sc, _ := scenario.Parse(strings.NewReader(`{"stages": [
+		{"duration": "1h", "op": {"kind": "sleep", "latency": "1ms"}},
+		{"duration": "1h", "op": {"kind": "sleep", "latency": "1ms"}}
+	]}`))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	res, err := scenario.Run(ctx, sc)
+	if !errors.Is(err, context.DeadlineExceeded) || len(res.Stages) != 1 {
+		t.Errorf("Expected the run to stop in the first stage but got %v after %d stages", err, len(res.Stages))
+	}
+}
@@ -0,0 +1,133 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Scenario is a workload description: stages that run one after another.
+type Scenario struct {
+	Name   string  `json:"name"`
+	Seed   uint64  `json:"seed"` // for jitter and faults; 0 picks one
+	Stages []Stage `json:"stages"`
+}
+
+// Stage runs one kind of operation at a rate for a while.
+type Stage struct {
+	Name     string   `json:"name"`
+	Duration Duration `json:"duration"`
+	Workers  int      `json:"workers"` // goroutines; defaults to 1
+	Rate     float64  `json:"rate"`    // operations per second over all workers; 0 is unlimited
+	Op       Op       `json:"op"`
+	Faults   Faults   `json:"faults"`
+}
+
+// Op is the operation a stage repeats. Which fields apply depends on the
+// kind: cpu and alloc use Work, sleep uses Latency and Jitter.
+type Op struct {
+	Kind    string   `json:"kind"`
+	Work    int      `json:"work"`    // cpu: loop iterations; alloc: bytes
+	Latency Duration `json:"latency"` // sleep: how long
+	Jitter  Duration `json:"jitter"`  // sleep: up to this much more
+}
+
+// Faults are injected into a stage's operations, each a fraction between
+// 0 and 1 of the operations.
+type Faults struct {
+	Error  float64  `json:"error"` // fail with ErrInjected instead of running
+	Panic  float64  `json:"panic"` // panic instead of running
+	Slow   float64  `json:"slow"`  // sleep SlowBy before running
+	SlowBy Duration `json:"slow_by"`
+}
+
+// Duration is a time.Duration written as a string such as "1m30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10s\": %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+
+	return nil
+}
+
+// Parse reads a scenario in JSON. Unknown fields are errors, so a typo
+// does not silently fall back to a default.
+func Parse(r io.Reader) (*Scenario, error) {
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var sc Scenario
+	if err := dec.Decode(&sc); err != nil {
+		return nil, fmt.Errorf("scenario: %w", err)
+	}
+	if err := sc.validate(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", sc.Name, err)
+	}
+
+	return &sc, nil
+}
+
+// Load parses the scenario in the file at path.
+func Load(path string) (*Scenario, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f)
+}
+
+func (sc *Scenario) validate() error {
+
+	if len(sc.Stages) == 0 {
+		return errors.New("no stages")
+	}
+	for i := range sc.Stages {
+		st := &sc.Stages[i]
+		if st.Name == "" {
+			st.Name = fmt.Sprintf("stage%d", i+1)
+		}
+		if st.Workers == 0 {
+			st.Workers = 1
+		}
+		switch {
+		case st.Duration <= 0:
+			return fmt.Errorf("stage %s: duration must be positive", st.Name)
+		case st.Workers < 0:
+			return fmt.Errorf("stage %s: workers must not be negative", st.Name)
+		case st.Rate < 0:
+			return fmt.Errorf("stage %s: rate must not be negative", st.Name)
+		case st.Op.Kind == "":
+			return fmt.Errorf("stage %s: op needs a kind", st.Name)
+		}
+		for _, f := range []float64{st.Faults.Error, st.Faults.Panic, st.Faults.Slow} {
+			if f < 0 || f > 1 {
+				return fmt.Errorf("stage %s: fault fractions must be between 0 and 1", st.Name)
+			}
+		}
+		if st.Faults.Error+st.Faults.Panic > 1 {
+			return fmt.Errorf("stage %s: error and panic fractions add up to more than 1", st.Name)
+		}
+	}
+
+	return nil
+}
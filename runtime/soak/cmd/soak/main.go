@@ -0,0 +1,179 @@
+// Command soak runs one of the repo's workloads for a long time and fails
+// with a report if goroutines, the live heap or open files keep growing.
+//
+//	go run ./runtime/soak/cmd/soak -workload pipeline -duration 2h
+//	go run ./runtime/soak/cmd/soak -workload leak -duration 30s -interval 1s -warmup 0
+//
+// The leak workload leaks a goroutine per iteration on purpose, to see what
+// a failing run looks like. With -allocs the top allocation sites of every
+// interval go to stderr, next to what they allocated the interval before.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"time"
+
+	"pacx/Benchmarking/dataset"
+	"pacx/Benchmarking/keygen"
+	"pacx/cache"
+	"pacx/concurrency/orders"
+	"pacx/concurrency/pipeline"
+	"pacx/config"
+	"pacx/runtime/allocsites"
+	"pacx/runtime/soak"
+)
+
+var workloads = map[string]func() (func(context.Context) error, error){
+	"pipeline": pipelineWorkload,
+	"cache":    cacheWorkload,
+	"orders":   ordersWorkload,
+	"leak":     leakWorkload,
+}
+
+// settings are the flags, which can also come from SOAK_<NAME> environment
+// variables or the JSON file given with -config.
+type settings struct {
+	Workload      string        `default:"pipeline" usage:"workload to run: cache, leak, orders or pipeline"`
+	Duration      time.Duration `default:"1h" usage:"how long to run" validate:"min=1"`
+	Interval      time.Duration `default:"10s" usage:"how often to sample" validate:"min=1"`
+	Warmup        time.Duration `default:"1m" usage:"samples to ignore at the start"`
+	Workers       int           `default:"4" usage:"concurrent copies of the workload" validate:"min=1"`
+	MaxGoroutines int           `default:"10" usage:"allowed goroutine growth"`
+	MaxHeap       uint64        `default:"16777216" usage:"allowed live heap growth in bytes"`
+	MaxFDs        int           `config:"max-fds" default:"10" usage:"allowed open file growth"`
+	Allocs        int           `usage:"report this many top allocation sites every interval"`
+}
+
+func main() {
+
+	var cfg settings
+	_, err := config.Load(&cfg, os.Args[1:], config.WithName("soak"), config.WithEnvPrefix("SOAK"), config.WithFileFlag("config"))
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	build, ok := workloads[cfg.Workload]
+	if !ok {
+		names := slices.Sorted(maps.Keys(workloads))
+		fmt.Fprintf(os.Stderr, "unknown workload %q, want one of %s\n", cfg.Workload, strings.Join(names, ", "))
+		os.Exit(2)
+	}
+	workload, err := build()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Ctrl-C stops the run but still prints what was seen so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if cfg.Allocs > 0 {
+		r, err := allocsites.Start(allocsites.WithInterval(cfg.Interval), allocsites.WithTop(cfg.Allocs))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer r.Close()
+	}
+
+	fmt.Printf("soaking %s for %v\n", cfg.Workload, cfg.Duration)
+	report, err := soak.Run(ctx, workload,
+		soak.WithDuration(cfg.Duration),
+		soak.WithInterval(cfg.Interval),
+		soak.WithWarmup(cfg.Warmup),
+		soak.WithWorkers(cfg.Workers),
+		soak.WithBounds(cfg.MaxGoroutines, cfg.MaxHeap, cfg.MaxFDs),
+	)
+	if report != nil {
+		report.Print(os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// pipelineWorkload squares and sums a batch of numbers on a fan-out.
+func pipelineWorkload() (func(context.Context) error, error) {
+
+	inputs := make([]int, 1000)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	return func(ctx context.Context) error {
+		_, err := pipeline.MapReduce(ctx, inputs,
+			func(v int) int { return v * v },
+			func(acc, v int) int { return acc + v },
+			4)
+		return err
+	}, nil
+}
+
+// cacheWorkload reads and writes a bounded cache with skewed keys.
+func cacheWorkload() (func(context.Context) error, error) {
+
+	c, err := cache.New[string, []byte](cache.WithMaxCost(1<<20), cache.WithTinyLFU(10_000))
+	if err != nil {
+		return nil, err
+	}
+	keys := keygen.Zipf(1, 1.1, 100_000, 10_000)
+
+	return func(ctx context.Context) error {
+		start := rand.IntN(len(keys) - 1000)
+		for _, k := range keys[start : start+1000] {
+			key := keygen.String(int(k))
+			if _, ok := c.Get(key); !ok {
+				c.Set(key, make([]byte, 64), 64)
+			}
+		}
+		return nil
+	}, nil
+}
+
+// ordersWorkload pushes generated orders through their statuses.
+func ordersWorkload() (func(context.Context) error, error) {
+
+	d, err := dataset.Generate(dataset.WithVolume(100, 50, 100))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context) error {
+		o := d.Orders[rand.IntN(len(d.Orders))]
+		err := orders.Process(ctx, orders.Order{ID: o.ID, Status: orders.Pending}, func(orders.Order) error { return nil })
+		if ctx.Err() != nil {
+			return nil // the run is over, not a failure
+		}
+		return err
+	}, nil
+}
+
+// leakWorkload leaks one goroutine per call.
+func leakWorkload() (func(context.Context) error, error) {
+
+	never := make(chan struct{})
+
+	return func(ctx context.Context) error {
+		go func() { <-never }()
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		return nil
+	}, nil
+}
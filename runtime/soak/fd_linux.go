@@ -0,0 +1,14 @@
+package soak
+
+import "os"
+
+func openFDs() int {
+
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	// ReadDir's own descriptor is in the listing
+	return len(entries) - 1
+}
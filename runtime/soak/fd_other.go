@@ -0,0 +1,7 @@
+//go:build !linux
+
+package soak
+
+func openFDs() int {
+	return -1
+}
@@ -0,0 +1,307 @@
+// Package soak runs a workload for a long time and checks that it doesn't
+// leak. While the workload runs it samples the goroutine count, the live
+// heap and the open file descriptors, and at the end compares where each
+// one settled against where it started.
+//
+// A single high sample means nothing: heaps breathe with the GC cycle and
+// goroutines come and go with the load. What a leak does is raise the
+// floor, so the check compares the lowest value of the first quarter of
+// the samples with the lowest value of the last quarter.
+package soak
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"pacx/options"
+)
+
+// ErrDrift is returned, wrapped, when a metric grew beyond its bound.
+var ErrDrift = errors.New("soak: drift beyond bounds")
+
+// Sample is one reading of the process.
+type Sample struct {
+	At         time.Duration // since the start of the run
+	Goroutines int
+	HeapLive   uint64 // bytes of heap live after the last GC
+	FDs        int    // open file descriptors, -1 where they can't be counted
+}
+
+type config struct {
+	duration time.Duration
+	interval time.Duration
+	warmup   time.Duration
+	workers  int
+
+	maxGoroutines int
+	maxHeap       uint64
+	maxFDs        int
+
+	sample func() Sample // replaced in tests
+}
+
+// Option configures Run.
+type Option = options.Option[config]
+
+// WithDuration sets how long the workload runs. Defaults to one hour.
+func WithDuration(d time.Duration) Option {
+	return options.New("WithDuration", func(c *config) error {
+		if d <= 0 {
+			return errors.New("duration must be positive")
+		}
+		c.duration = d
+		return nil
+	})
+}
+
+// WithInterval sets how often the process is sampled. Defaults to ten
+// seconds.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithWarmup ignores the samples taken in the first d, while caches fill
+// and pools grow to their working size. Defaults to one minute.
+func WithWarmup(d time.Duration) Option {
+	return options.New("WithWarmup", func(c *config) error {
+		if d < 0 {
+			return errors.New("warmup must not be negative")
+		}
+		c.warmup = d
+		return nil
+	})
+}
+
+// WithWorkers runs the workload on n goroutines at once. Defaults to 1.
+func WithWorkers(n int) Option {
+	return options.New("WithWorkers", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one worker")
+		}
+		c.workers = n
+		return nil
+	})
+}
+
+// WithBounds sets how much each metric may grow over the run. Defaults to
+// 10 goroutines, 16 MiB of live heap and 10 file descriptors.
+func WithBounds(goroutines int, heapBytes uint64, fds int) Option {
+	return options.New("WithBounds", func(c *config) error {
+		if goroutines < 0 || fds < 0 {
+			return errors.New("bounds must not be negative")
+		}
+		c.maxGoroutines, c.maxHeap, c.maxFDs = goroutines, heapBytes, fds
+		return nil
+	})
+}
+
+func validate(c config) error {
+	if c.warmup >= c.duration {
+		return errors.New("warmup must be shorter than the duration")
+	}
+	return nil
+}
+
+// Drift is how far one metric moved over the run.
+type Drift struct {
+	Metric   string
+	Baseline int64
+	Final    int64
+	Bound    int64
+}
+
+// Growth is Final minus Baseline.
+func (d Drift) Growth() int64 {
+	return d.Final - d.Baseline
+}
+
+// Exceeded reports whether the growth is beyond the bound.
+func (d Drift) Exceeded() bool {
+	return d.Growth() > d.Bound
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	Duration   time.Duration
+	Iterations int64
+	Samples    []Sample // after the warmup
+	Drifts     []Drift
+}
+
+// Failed reports whether any metric drifted beyond its bound.
+func (r *Report) Failed() bool {
+
+	for _, d := range r.Drifts {
+		if d.Exceeded() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Print writes the drifts as a table.
+func (r *Report) Print(w io.Writer) error {
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d iterations in %v, %d samples\n", r.Iterations, r.Duration.Round(time.Second), len(r.Samples))
+	fmt.Fprintln(tw, "METRIC\tBASELINE\tFINAL\tGROWTH\tBOUND\tRESULT")
+
+	for _, d := range r.Drifts {
+		verdict := "ok"
+		if d.Exceeded() {
+			verdict = "DRIFT"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%+d\t%d\t%s\n", d.Metric, d.Baseline, d.Final, d.Growth(), d.Bound, verdict)
+	}
+
+	return tw.Flush()
+}
+
+// Run calls workload over and over until the duration is up, sampling the
+// process as it goes. The error is the workload's first error, ctx's error
+// if it ended the run early, or ErrDrift if a metric grew beyond its
+// bound; the report is returned in every case but a bad option.
+func Run(ctx context.Context, workload func(context.Context) error, opts ...Option) (*Report, error) {
+
+	cfg, err := options.Build(config{
+		duration:      time.Hour,
+		interval:      10 * time.Second,
+		warmup:        time.Minute,
+		workers:       1,
+		maxGoroutines: 10,
+		maxHeap:       16 << 20,
+		maxFDs:        10,
+		sample:        sample,
+	}, validate, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	start := time.Now()
+	var iterations atomic.Int64
+	var workErr error
+	var once sync.Once
+
+	var wg sync.WaitGroup
+	wg.Add(cfg.workers)
+	for i := 0; i < cfg.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if err := workload(ctx); err != nil {
+					if ctx.Err() == nil {
+						once.Do(func() { workErr = err; cancel() })
+					}
+					return
+				}
+				iterations.Add(1)
+			}
+		}()
+	}
+
+	var samples []Sample
+	ticker := time.NewTicker(cfg.interval)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-ticker.C:
+			if s := cfg.sample(); time.Since(start) >= cfg.warmup {
+				s.At = time.Since(start)
+				samples = append(samples, s)
+			}
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	wg.Wait()
+
+	r := &Report{
+		Duration:   time.Since(start),
+		Iterations: iterations.Load(),
+		Samples:    samples,
+		Drifts:     drifts(samples, cfg),
+	}
+
+	switch {
+	case workErr != nil:
+		return r, workErr
+	case parent.Err() != nil:
+		return r, parent.Err()
+	case r.Failed():
+		var bad []string
+		for _, d := range r.Drifts {
+			if d.Exceeded() {
+				bad = append(bad, fmt.Sprintf("%s %+d (bound %d)", d.Metric, d.Growth(), d.Bound))
+			}
+		}
+		return r, fmt.Errorf("%w: %s", ErrDrift, strings.Join(bad, ", "))
+	}
+
+	return r, nil
+}
+
+// drifts compares the floor of the first quarter of the samples with the
+// floor of the last quarter.
+func drifts(samples []Sample, cfg config) []Drift {
+
+	if len(samples) < 2 {
+		return nil
+	}
+
+	quarter := max(1, len(samples)/4)
+	first, last := samples[:quarter], samples[len(samples)-quarter:]
+
+	floor := func(in []Sample, get func(Sample) int64) int64 {
+		m := get(in[0])
+		for _, s := range in[1:] {
+			m = min(m, get(s))
+		}
+		return m
+	}
+	drift := func(metric string, bound int64, get func(Sample) int64) Drift {
+		return Drift{Metric: metric, Baseline: floor(first, get), Final: floor(last, get), Bound: bound}
+	}
+
+	out := []Drift{
+		drift("goroutines", int64(cfg.maxGoroutines), func(s Sample) int64 { return int64(s.Goroutines) }),
+		drift("heap_live_bytes", int64(cfg.maxHeap), func(s Sample) int64 { return int64(s.HeapLive) }),
+	}
+	if samples[0].FDs >= 0 {
+		out = append(out, drift("fds", int64(cfg.maxFDs), func(s Sample) int64 { return int64(s.FDs) }))
+	}
+
+	return out
+}
+
+func sample() Sample {
+
+	heap := []metrics.Sample{{Name: "/gc/heap/live:bytes"}}
+	metrics.Read(heap)
+
+	return Sample{
+		Goroutines: runtime.NumGoroutine(),
+		HeapLive:   heap[0].Value.Uint64(),
+		FDs:        openFDs(),
+	}
+}
@@ -0,0 +1,111 @@
+package soak
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDriftsCompareFloors(t *testing.T) {
+
+	cfg := config{maxGoroutines: 5, maxHeap: 1000, maxFDs: 2}
+
+	// heap spikes but settles, goroutines creep up, fds flat
+	var samples []Sample
+	for i := 0; i < 8; i++ {
+		heap := uint64(10_000)
+		if i%2 == 1 {
+			heap = 50_000
+		}
+		samples = append(samples, Sample{Goroutines: 10 + 3*i, HeapLive: heap, FDs: 7})
+	}
+
+	got := drifts(samples, cfg)
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 drifts but got %d", len(got))
+	}
+
+	byName := make(map[string]Drift)
+	for _, d := range got {
+		byName[d.Metric] = d
+	}
+	if d := byName["goroutines"]; d.Baseline != 10 || d.Final != 28 || !d.Exceeded() {
+		t.Errorf("Expected goroutines 10 -> 28 exceeded but got %+v", d)
+	}
+	if d := byName["heap_live_bytes"]; d.Growth() != 0 || d.Exceeded() {
+		t.Errorf("Expected no heap growth from spikes but got %+v", d)
+	}
+	if d := byName["fds"]; d.Exceeded() {
+		t.Errorf("Expected fds within bound but got %+v", d)
+	}
+}
+
+func TestDriftsSkipUncountedFDs(t *testing.T) {
+
+	got := drifts([]Sample{{FDs: -1}, {FDs: -1}}, config{})
+	for _, d := range got {
+		if d.Metric == "fds" {
+			t.Error("Expected no fd drift when fds can't be counted")
+		}
+	}
+}
+
+func TestRunDetectsGoroutineLeak(t *testing.T) {
+
+	release := make(chan struct{})
+	defer close(release)
+
+	r, err := Run(context.Background(), func(ctx context.Context) error {
+		go func() { <-release }() // leaked until the test ends
+		select {
+		case <-time.After(time.Millisecond):
+		case <-ctx.Done():
+		}
+		return nil
+	}, WithDuration(300*time.Millisecond), WithInterval(10*time.Millisecond), WithWarmup(0))
+
+	if !errors.Is(err, ErrDrift) {
+		t.Fatalf("Expected ErrDrift but got %v", err)
+	}
+	if !r.Failed() || r.Iterations == 0 {
+		t.Errorf("Expected a failed report with iterations but got %+v", r)
+	}
+
+	var out strings.Builder
+	r.Print(&out)
+	if !strings.Contains(out.String(), "goroutines") || !strings.Contains(out.String(), "DRIFT") {
+		t.Errorf("Expected the goroutine drift in the report but got:\n%s", out.String())
+	}
+}
+
+func TestRunSteadyWorkloadPasses(t *testing.T) {
+
+	r, err := Run(context.Background(), func(ctx context.Context) error {
+		_ = make([]byte, 1024)
+		return nil
+	}, WithDuration(200*time.Millisecond), WithInterval(10*time.Millisecond), WithWarmup(50*time.Millisecond))
+
+	if err != nil {
+		t.Fatalf("Expected a steady workload to pass but got %v", err)
+	}
+	if len(r.Samples) == 0 {
+		t.Error("Expected samples after the warmup")
+	}
+}
+
+func TestRunStopsOnWorkloadError(t *testing.T) {
+
+	boom := errors.New("boom")
+	start := time.Now()
+	_, err := Run(context.Background(), func(context.Context) error { return boom },
+		WithDuration(time.Minute), WithInterval(10*time.Millisecond), WithWarmup(0))
+
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected the workload error but got %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("Expected the run to stop at the first error")
+	}
+}
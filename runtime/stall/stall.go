@@ -0,0 +1,314 @@
+// Package stall is a watchdog for goroutines that stop making progress. It
+// samples the stacks of all goroutines, the way runtime/num.go inspects
+// them, and reports any goroutine that stays blocked on a channel, select,
+// mutex or WaitGroup with the same stack for longer than a threshold.
+//
+// Sampling only sees where a goroutine is, not whether it moved in between,
+// so a loop that wakes up and returns to the same select looks exactly like
+// one that is stuck. Goroutines that wait by design, such as idle workers
+// and servers, should be listed with WithIgnore.
+package stall
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pacx/options"
+	"pacx/sync/syncx"
+)
+
+// Stall describes one goroutine that has been blocked too long.
+type Stall struct {
+	ID      int64
+	State   string        // wait reason as printed by the runtime, e.g. "chan receive"
+	Blocked time.Duration // how long it has been seen blocked, at least
+	Stack   string
+}
+
+func (s Stall) String() string {
+	return "goroutine " + strconv.FormatInt(s.ID, 10) + " blocked on " + s.State +
+		" for " + s.Blocked.Round(time.Millisecond).String() + "\n" + s.Stack
+}
+
+// blockedStates are the wait reasons that mean waiting on another goroutine
+// rather than on IO, a timer or the scheduler.
+var blockedStates = map[string]bool{
+	"chan send":               true,
+	"chan receive":            true,
+	"chan send (nil chan)":    true,
+	"chan receive (nil chan)": true,
+	"select":                  true,
+	"select (no cases)":       true,
+	"semacquire":              true,
+	"sync.Mutex.Lock":         true,
+	"sync.RWMutex.Lock":       true,
+	"sync.RWMutex.RLock":      true,
+	"sync.Cond.Wait":          true,
+	"sync.WaitGroup.Wait":     true,
+}
+
+type config struct {
+	threshold time.Duration
+	interval  time.Duration
+	onStall   func(Stall)
+	ignore    []string
+}
+
+// Option configures a Watchdog.
+type Option = options.Option[config]
+
+// WithThreshold sets how long a goroutine may stay blocked before it is
+// reported. Defaults to ten seconds.
+func WithThreshold(d time.Duration) Option {
+	return options.New("WithThreshold", func(c *config) error {
+		if d <= 0 {
+			return errors.New("threshold must be positive")
+		}
+		c.threshold = d
+		return nil
+	})
+}
+
+// WithInterval sets how often stacks are sampled. Defaults to a tenth of
+// the threshold.
+func WithInterval(d time.Duration) Option {
+	return options.New("WithInterval", func(c *config) error {
+		if d <= 0 {
+			return errors.New("interval must be positive")
+		}
+		c.interval = d
+		return nil
+	})
+}
+
+// WithOnStall is called once for every goroutine that crosses the
+// threshold. By default stalls are logged.
+func WithOnStall(fn func(Stall)) Option {
+	return options.New("WithOnStall", func(c *config) error {
+		c.onStall = fn
+		return nil
+	})
+}
+
+// WithIgnore skips goroutines whose stack contains any of the given
+// substrings, for goroutines that are meant to wait forever.
+func WithIgnore(substrings ...string) Option {
+	return options.New("WithIgnore", func(c *config) error {
+		c.ignore = append(c.ignore, substrings...)
+		return nil
+	})
+}
+
+// sighting is a goroutine seen blocked in the same place across samples.
+type sighting struct {
+	state    string
+	stack    string
+	since    time.Time
+	reported bool
+}
+
+// Watchdog samples goroutine stacks in the background until Close.
+type Watchdog struct {
+	cfg config
+	now func() time.Time
+	bg  *syncx.Background
+
+	mu   sync.Mutex
+	seen map[int64]*sighting
+}
+
+// New starts a watchdog.
+func New(opts ...Option) (*Watchdog, error) {
+
+	cfg, err := options.Build(config{
+		threshold: 10 * time.Second,
+		// the watchdog itself and the test runner wait by design
+		ignore: []string{"pacx/runtime/stall.(*Watchdog).loop", "testing.(*T).Run", "testing.runTests", "testing.(*M)."},
+	}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.interval == 0 {
+		cfg.interval = cfg.threshold / 10
+	}
+	if cfg.onStall == nil {
+		cfg.onStall = func(s Stall) { log.Printf("stall: %s", s) }
+	}
+
+	w := &Watchdog{
+		cfg:  cfg,
+		now:  time.Now,
+		seen: make(map[int64]*sighting),
+	}
+	w.bg = syncx.Go(w.loop)
+
+	return w, nil
+}
+
+func (w *Watchdog) loop(quit <-chan struct{}) {
+
+	ticker := time.NewTicker(w.cfg.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, s := range w.sample() {
+				w.cfg.onStall(s)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// sample takes one snapshot and returns the goroutines that crossed the
+// threshold since the previous one.
+func (w *Watchdog) sample() []Stall {
+
+	now := w.now()
+	current := parse(stacks())
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var fresh []Stall
+	next := make(map[int64]*sighting, len(current))
+
+	for id, g := range current {
+		if !blockedStates[g.state] || w.ignored(g.stack) {
+			continue
+		}
+
+		s, ok := w.seen[id]
+		if !ok || s.state != g.state || s.stack != g.stack {
+			s = &sighting{state: g.state, stack: g.stack, since: now.Add(-g.waited)}
+		}
+		next[id] = s
+
+		if !s.reported && now.Sub(s.since) >= w.cfg.threshold {
+			s.reported = true
+			fresh = append(fresh, Stall{ID: id, State: s.state, Blocked: now.Sub(s.since), Stack: s.stack})
+		}
+	}
+	w.seen = next
+
+	sort.Slice(fresh, func(i, j int) bool { return fresh[i].ID < fresh[j].ID })
+
+	return fresh
+}
+
+func (w *Watchdog) ignored(stack string) bool {
+
+	for _, s := range w.cfg.ignore {
+		if strings.Contains(stack, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Stalls returns the goroutines currently blocked past the threshold, as of
+// the last sample.
+func (w *Watchdog) Stalls() []Stall {
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	var out []Stall
+	for id, s := range w.seen {
+		if s.reported {
+			out = append(out, Stall{ID: id, State: s.state, Blocked: now.Sub(s.since), Stack: s.stack})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out
+}
+
+// Close stops the watchdog.
+func (w *Watchdog) Close() {
+
+	w.bg.Stop()
+}
+
+// TB is the part of testing.TB that Watch needs.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+	Cleanup(func())
+}
+
+// Watch runs a watchdog for the rest of a test and fails the test for every
+// stall it finds.
+func Watch(t TB, opts ...Option) {
+
+	t.Helper()
+
+	opts = append([]Option{WithOnStall(func(s Stall) { t.Errorf("%s", s) })}, opts...)
+	w, err := New(opts...)
+	if err != nil {
+		t.Errorf("stall: %v", err)
+		return
+	}
+	t.Cleanup(w.Close)
+}
+
+// stacks returns the text of runtime.Stack for all goroutines.
+func stacks() []byte {
+
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+type goroutine struct {
+	state  string
+	waited time.Duration // what the runtime reports, only whole minutes
+	stack  string
+}
+
+var header = regexp.MustCompile(`^goroutine (\d+) \[([^\]]+)\]:`)
+
+// parse splits a runtime.Stack dump into goroutines keyed by id.
+func parse(dump []byte) map[int64]goroutine {
+
+	out := make(map[int64]goroutine)
+
+	for _, block := range bytes.Split(dump, []byte("\n\n")) {
+		first, rest, _ := bytes.Cut(block, []byte("\n"))
+		m := header.FindSubmatch(first)
+		if m == nil {
+			continue
+		}
+		id, _ := strconv.ParseInt(string(m[1]), 10, 64)
+
+		// "chan receive, 2 minutes, locked to thread"
+		fields := strings.Split(string(m[2]), ", ")
+		g := goroutine{state: fields[0], stack: string(rest)}
+		for _, f := range fields[1:] {
+			if mins, ok := strings.CutSuffix(f, " minutes"); ok {
+				n, _ := strconv.Atoi(mins)
+				g.waited = time.Duration(n) * time.Minute
+			}
+		}
+		out[id] = g
+	}
+
+	return out
+}
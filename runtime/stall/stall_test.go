@@ -0,0 +1,193 @@
+package stall
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+
+	dump := []byte(`goroutine 1 [running]:
+main.main()
+	/tmp/main.go:10 +0x1d
+
+goroutine 7 [chan receive, 3 minutes]:
+main.worker()
+	/tmp/main.go:20 +0x2a
+
+goroutine 9 [sync.Mutex.Lock, locked to thread]:
+sync.(*Mutex).Lock()
+`)
+
+	gs := parse(dump)
+	if len(gs) != 3 {
+		t.Fatalf("Expected 3 goroutines but got %d", len(gs))
+	}
+	if g := gs[7]; g.state != "chan receive" || g.waited != 3*time.Minute || !strings.Contains(g.stack, "main.worker") {
+		t.Errorf("Expected goroutine 7 blocked 3m in main.worker but got %+v", g)
+	}
+	if g := gs[9]; g.state != "sync.Mutex.Lock" {
+		t.Errorf("Expected sync.Mutex.Lock but got %q", g.state)
+	}
+}
+
+// newSampled returns a watchdog whose background loop effectively never
+// fires, so tests drive sample themselves on a fake clock.
+func newSampled(t *testing.T, opts ...Option) (*Watchdog, *time.Time) {
+
+	opts = append([]Option{WithInterval(time.Hour)}, opts...)
+	w, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(w.Close)
+
+	now := time.Unix(0, 0)
+	w.now = func() time.Time { return now }
+
+	return w, &now
+}
+
+// eventually polls cond until it holds or a second has passed.
+func eventually(t *testing.T, cond func() bool) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the condition to hold within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// blocked reports whether a goroutine running fn is parked in a blocked
+// state.
+func blocked(fn string) bool {
+
+	for _, g := range parse(stacks()) {
+		if blockedStates[g.state] && strings.Contains(g.stack, fn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ours(stalls []Stall) map[string]int {
+
+	states := make(map[string]int)
+	for _, s := range stalls {
+		if strings.Contains(s.Stack, "stall.waitOn") || strings.Contains(s.Stack, "stall.lockOn") {
+			states[s.State]++
+		}
+	}
+
+	return states
+}
+
+func waitOn(ch <-chan int) { <-ch }
+
+func lockOn(mu *sync.Mutex) {
+	mu.Lock()
+	mu.Unlock()
+}
+
+func TestDetectsStalls(t *testing.T) {
+
+	w, now := newSampled(t, WithThreshold(50*time.Millisecond))
+
+	never := make(chan int)
+	defer close(never)
+	go waitOn(never)
+
+	var mu sync.Mutex
+	mu.Lock()
+	defer mu.Unlock()
+	go lockOn(&mu)
+
+	eventually(t, func() bool { return blocked("stall.waitOn") && blocked("stall.lockOn") })
+
+	if got := ours(w.sample()); len(got) != 0 {
+		t.Fatalf("Expected nothing on the first sample but got %v", got)
+	}
+
+	*now = now.Add(60 * time.Millisecond)
+	if got := ours(w.sample()); got["chan receive"] != 1 || got["sync.Mutex.Lock"] != 1 {
+		t.Errorf("Expected one channel and one mutex stall but got %v", got)
+	}
+
+	*now = now.Add(60 * time.Millisecond)
+	if got := ours(w.sample()); len(got) != 0 {
+		t.Errorf("Expected each stall to be reported once but got %v", got)
+	}
+	if got := ours(w.Stalls()); len(got) != 2 {
+		t.Errorf("Expected Stalls to still list both but got %v", got)
+	}
+}
+
+func idleServer(done <-chan struct{}) { <-done }
+
+func TestShortWaitsAndIgnored(t *testing.T) {
+
+	w, now := newSampled(t, WithThreshold(100*time.Millisecond), WithIgnore("stall.idleServer"))
+
+	done := make(chan struct{})
+	defer close(done)
+	go idleServer(done)
+
+	brief := make(chan int)
+	go waitOn(brief)
+
+	eventually(t, func() bool { return blocked("stall.idleServer") && blocked("stall.waitOn") })
+	w.sample()
+
+	// unblocked before the threshold
+	close(brief)
+	eventually(t, func() bool { return !blocked("stall.waitOn") })
+
+	*now = now.Add(time.Second)
+	for _, s := range w.sample() {
+		if strings.Contains(s.Stack, "stall.waitOn") || strings.Contains(s.Stack, "stall.idleServer") {
+			t.Errorf("Expected no stall but got %s", s)
+		}
+	}
+}
+
+// fakeTB records what Watch reports.
+type fakeTB struct {
+	mu       sync.Mutex
+	errors   []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.errors = append(f.errors, format)
+}
+func (f *fakeTB) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func TestWatch(t *testing.T) {
+
+	tb := &fakeTB{}
+	Watch(tb, WithThreshold(30*time.Millisecond), WithInterval(5*time.Millisecond))
+
+	never := make(chan int)
+	defer close(never)
+	go waitOn(never)
+
+	eventually(t, func() bool {
+		tb.mu.Lock()
+		defer tb.mu.Unlock()
+		return len(tb.errors) > 0
+	})
+
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+}
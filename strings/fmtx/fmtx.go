@@ -0,0 +1,211 @@
+// Package fmtx formats the values a log line or a metrics dump is made of
+// by appending to a byte slice, where fmt.Sprintf would allocate a string
+// per value and box every argument in an interface. With a buffer from Get
+// a whole line is built without allocating once the pool is warm:
+//
+//	buf := fmtx.Get()
+//	b := append(*buf, "took="...)
+//	b = fmtx.AppendDuration(b, elapsed)
+//	b = append(b, " user="...)
+//	b = fmtx.AppendQuote(b, name)
+//	w.Write(append(b, '\n'))
+//	*buf = b
+//	fmtx.Put(buf)
+//
+// The Parse functions go the other way for the fields of CSV and NDJSON
+// lines: they read numbers straight from a []byte, without converting it
+// to a string first, and fail with a *NumError.
+package fmtx
+
+import (
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AppendInt appends v in decimal.
+func AppendInt(dst []byte, v int64) []byte {
+	return strconv.AppendInt(dst, v, 10)
+}
+
+// AppendFloat appends v in the shortest form that reads back the same,
+// like fmt's %v.
+func AppendFloat(dst []byte, v float64) []byte {
+	return strconv.AppendFloat(dst, v, 'g', -1, 64)
+}
+
+// AppendDuration appends d as d.String() would print it, such as "1.5s"
+// or "2h3m0.5s".
+func AppendDuration(dst []byte, d time.Duration) []byte {
+
+	if d == 0 {
+		return append(dst, "0s"...)
+	}
+
+	// the largest duration, "-2562047h47m16.854775808s", fits
+	var buf [32]byte
+	w := len(buf)
+
+	u := uint64(d)
+	neg := d < 0
+	if neg {
+		u = -u
+	}
+
+	if u < uint64(time.Second) {
+		// less than a second: one unit below a second, with a fraction
+		var prec int
+		w--
+		buf[w] = 's'
+		w--
+		switch {
+		case u < uint64(time.Microsecond):
+			prec = 0
+			buf[w] = 'n'
+		case u < uint64(time.Millisecond):
+			prec = 3
+			// U+00B5 'µ' micro sign, two bytes in UTF-8
+			w--
+			copy(buf[w:], "µ")
+		default:
+			prec = 6
+			buf[w] = 'm'
+		}
+		w, u = fmtFrac(buf[:w], u, prec)
+		w = fmtInt(buf[:w], u)
+	} else {
+		w--
+		buf[w] = 's'
+
+		w, u = fmtFrac(buf[:w], u, 9)
+
+		// u is now whole seconds
+		w = fmtInt(buf[:w], u%60)
+		u /= 60
+
+		if u > 0 {
+			w--
+			buf[w] = 'm'
+			w = fmtInt(buf[:w], u%60)
+			u /= 60
+
+			if u > 0 {
+				w--
+				buf[w] = 'h'
+				w = fmtInt(buf[:w], u)
+			}
+		}
+	}
+
+	if neg {
+		w--
+		buf[w] = '-'
+	}
+
+	return append(dst, buf[w:]...)
+}
+
+// fmtFrac writes the fraction of v/10^prec, without trailing zeros, into
+// the end of buf and returns where it starts and v/10^prec.
+func fmtFrac(buf []byte, v uint64, prec int) (int, uint64) {
+
+	w := len(buf)
+	nonzero := false
+	for i := 0; i < prec; i++ {
+		digit := v % 10
+		nonzero = nonzero || digit != 0
+		if nonzero {
+			w--
+			buf[w] = byte(digit) + '0'
+		}
+		v /= 10
+	}
+	if nonzero {
+		w--
+		buf[w] = '.'
+	}
+
+	return w, v
+}
+
+// fmtInt writes v into the end of buf and returns where it starts.
+func fmtInt(buf []byte, v uint64) int {
+
+	w := len(buf)
+	if v == 0 {
+		w--
+		buf[w] = '0'
+		return w
+	}
+	for v > 0 {
+		w--
+		buf[w] = byte(v%10) + '0'
+		v /= 10
+	}
+
+	return w
+}
+
+// AppendQuote appends s as a logfmt value: as is when it is a plain word,
+// and quoted with Go escapes when it is empty or holds spaces, quotes, '='
+// or anything unprintable.
+func AppendQuote(dst []byte, s string) []byte {
+
+	if !needsQuote(s) {
+		return append(dst, s...)
+	}
+
+	return strconv.AppendQuote(dst, s)
+}
+
+func needsQuote(s string) bool {
+
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || !strconv.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+
+	return false
+}
+
+var pool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// Get returns an empty buffer from a pool. Hand it back with Put once the
+// bytes are written out.
+func Get() *[]byte {
+
+	b := pool.Get().(*[]byte)
+	*b = (*b)[:0]
+
+	return b
+}
+
+// Put returns a buffer to the pool. Buffers grown past 64KiB are dropped,
+// so one huge line does not stay around for good.
+func Put(b *[]byte) {
+
+	if cap(*b) > 64<<10 {
+		return
+	}
+	pool.Put(b)
+}
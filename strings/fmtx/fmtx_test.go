@@ -0,0 +1,117 @@
+package fmtx_test
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"pacx/strings/fmtx"
+)
+
+func TestAppendDurationMatchesString(t *testing.T) {
+
+	cases := []time.Duration{
+		0, 1, 999, time.Microsecond, 1500 * time.Nanosecond, time.Millisecond + 1,
+		time.Second, 1500 * time.Millisecond, time.Minute, 61*time.Second + 5,
+		2*time.Hour + 3*time.Minute + 500*time.Millisecond, -time.Second, -1,
+		math.MaxInt64, math.MinInt64,
+	}
+	for d := time.Duration(1); d < 1e15; d = d*7 + 3 {
+		cases = append(cases, d, -d)
+	}
+
+	for _, d := range cases {
+		if got := string(fmtx.AppendDuration([]byte("x="), d)); got != "x="+d.String() {
+			t.Errorf("Expected x=%s but got %s", d, got)
+		}
+	}
+}
+
+func TestAppendQuote(t *testing.T) {
+
+	cases := map[string]string{
+		"plain":      "plain",
+		"héllo":      "héllo",
+		"":           `""`,
+		"two words":  `"two words"`,
+		"a=b":        `"a=b"`,
+		`say "hi"`:   `"say \"hi\""`,
+		"tab\there":  `"tab\there"`,
+		"bad\xffutf": `"bad\xffutf"`,
+	}
+	for in, expected := range cases {
+		if got := string(fmtx.AppendQuote(nil, in)); got != expected {
+			t.Errorf("Expected %s for %q but got %s", expected, in, got)
+		}
+	}
+}
+
+func TestNoAllocations(t *testing.T) {
+
+	buf := fmtx.Get()
+	defer fmtx.Put(buf)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		b := (*buf)[:0]
+		b = fmtx.AppendInt(b, -12345)
+		b = fmtx.AppendFloat(b, 1.25)
+		b = fmtx.AppendDuration(b, 1234567*time.Microsecond)
+		b = fmtx.AppendQuote(b, "needs quoting")
+		*buf = b
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations but got %v per run", allocs)
+	}
+}
+
+// a log line with a count, a duration and a user name, the way
+// fmt.Sprintf would build it and the way fmtx does
+
+var (
+	count   = int64(1234)
+	elapsed = 1530 * time.Millisecond
+	user    = "maulik g"
+)
+
+func BenchmarkLineSprintf(b *testing.B) {
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = fmt.Sprintf("count=%d took=%s user=%s", count, elapsed, strconv.Quote(user))
+	}
+}
+
+func BenchmarkLineAppend(b *testing.B) {
+
+	b.ReportAllocs()
+	for b.Loop() {
+		buf := fmtx.Get()
+		l := append(*buf, "count="...)
+		l = fmtx.AppendInt(l, count)
+		l = append(l, " took="...)
+		l = fmtx.AppendDuration(l, elapsed)
+		l = append(l, " user="...)
+		l = fmtx.AppendQuote(l, user)
+		*buf = l
+		fmtx.Put(buf)
+	}
+}
+
+func BenchmarkDurationString(b *testing.B) {
+
+	b.ReportAllocs()
+	for b.Loop() {
+		_ = elapsed.String()
+	}
+}
+
+func BenchmarkAppendDuration(b *testing.B) {
+
+	b.ReportAllocs()
+	var buf [32]byte
+	for b.Loop() {
+		_ = fmtx.AppendDuration(buf[:0], elapsed)
+	}
+}
@@ -0,0 +1,130 @@
+package fmtx
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"unsafe"
+)
+
+// The causes a NumError wraps, so callers can tell bad input from input
+// that is fine but too large.
+var (
+	ErrSyntax = errors.New("invalid syntax")
+	ErrRange  = errors.New("value out of range")
+)
+
+// NumError is returned by the Parse functions. Input is a copy, so it
+// stays valid after the caller reuses its buffer.
+type NumError struct {
+	Func  string // ParseUint, ParseInt or ParseFloat
+	Input string
+	Err   error // ErrSyntax or ErrRange
+}
+
+func (e *NumError) Error() string {
+	return "fmtx." + e.Func + ": parsing " + strconv.Quote(e.Input) + ": " + e.Err.Error()
+}
+
+func (e *NumError) Unwrap() error {
+	return e.Err
+}
+
+func numError(fn string, b []byte, err error) error {
+	return &NumError{Func: fn, Input: string(b), Err: err}
+}
+
+// ParseUint parses b as a decimal uint64: digits only, no sign, no
+// underscores, no base prefix. It reads b in place and does not allocate
+// unless it fails. Unlike strconv, bad syntax anywhere in b is ErrSyntax
+// even when the digits before it already overflowed.
+func ParseUint(b []byte) (uint64, error) {
+
+	if len(b) == 0 {
+		return 0, numError("ParseUint", b, ErrSyntax)
+	}
+
+	var n uint64
+	for _, c := range b {
+		d := c - '0'
+		if d > 9 {
+			return 0, numError("ParseUint", b, ErrSyntax)
+		}
+		if n > (math.MaxUint64-uint64(d))/10 {
+			// keep going so "99999999999999999999x" is a syntax error
+			for _, c := range b {
+				if c-'0' > 9 {
+					return 0, numError("ParseUint", b, ErrSyntax)
+				}
+			}
+			return math.MaxUint64, numError("ParseUint", b, ErrRange)
+		}
+		n = n*10 + uint64(d)
+	}
+
+	return n, nil
+}
+
+// ParseInt parses b as a decimal int64 with an optional sign, like
+// ParseUint otherwise. Out of range values return the nearest limit with
+// ErrRange, as strconv does.
+func ParseInt(b []byte) (int64, error) {
+
+	digits := b
+	neg := false
+	if len(b) > 0 && (b[0] == '-' || b[0] == '+') {
+		neg = b[0] == '-'
+		digits = b[1:]
+	}
+
+	u, err := ParseUint(digits)
+	if err != nil {
+		if errors.Is(err, ErrSyntax) {
+			return 0, numError("ParseInt", b, ErrSyntax)
+		}
+		u = math.MaxUint64
+	}
+
+	switch {
+	case !neg && u > math.MaxInt64:
+		return math.MaxInt64, numError("ParseInt", b, ErrRange)
+	case neg && u > -math.MinInt64:
+		return math.MinInt64, numError("ParseInt", b, ErrRange)
+	case neg:
+		return -int64(u), nil
+	}
+
+	return int64(u), nil
+}
+
+// ParseFloat parses b as a float64 in the syntax of strconv.ParseFloat,
+// which never depends on the locale: '.' is the only decimal separator
+// and there are no thousands separators. It reads b in place.
+func ParseFloat(b []byte) (float64, error) {
+
+	// strconv only keeps the string in its error, which is replaced below
+	f, err := strconv.ParseFloat(unsafe.String(unsafe.SliceData(b), len(b)), 64)
+	if err != nil {
+		if errors.Is(err, strconv.ErrRange) {
+			return f, numError("ParseFloat", b, ErrRange)
+		}
+		return 0, numError("ParseFloat", b, ErrSyntax)
+	}
+
+	return f, nil
+}
+
+// AppendFixed appends v with prec digits after the point, never in
+// exponent form. prec -1 uses as many digits as needed to read v back.
+// NaN and the infinities are written "NaN", "+Inf" and "-Inf".
+func AppendFixed(dst []byte, v float64, prec int) []byte {
+	return strconv.AppendFloat(dst, v, 'f', prec, 64)
+}
+
+// FormatFloat is AppendFixed into a new string.
+func FormatFloat(v float64, prec int) string {
+
+	var buf [32]byte
+
+	return string(AppendFixed(buf[:0], v, prec))
+}
@@ -0,0 +1,139 @@
+package fmtx_test
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"pacx/strings/fmtx"
+)
+
+func TestParseInt(t *testing.T) {
+
+	cases := []struct {
+		in   string
+		want int64
+		err  error
+	}{
+		{"0", 0, nil},
+		{"-42", -42, nil},
+		{"+7", 7, nil},
+		{"9223372036854775807", math.MaxInt64, nil},
+		{"-9223372036854775808", math.MinInt64, nil},
+		{"9223372036854775808", math.MaxInt64, fmtx.ErrRange},
+		{"-99999999999999999999", math.MinInt64, fmtx.ErrRange},
+		{"", 0, fmtx.ErrSyntax},
+		{"-", 0, fmtx.ErrSyntax},
+		{"1_000", 0, fmtx.ErrSyntax},
+		{"0x10", 0, fmtx.ErrSyntax},
+		{"99999999999999999999x", 0, fmtx.ErrSyntax},
+	}
+	for _, c := range cases {
+		got, err := fmtx.ParseInt([]byte(c.in))
+		if got != c.want || !errors.Is(err, c.err) {
+			t.Errorf("Expected %d, %v for %q but got %d, %v", c.want, c.err, c.in, got, err)
+		}
+	}
+}
+
+func TestNumErrorKeepsInput(t *testing.T) {
+
+	buf := []byte("12a")
+	_, err := fmtx.ParseUint(buf)
+	copy(buf, "xyz")
+
+	var ne *fmtx.NumError
+	if !errors.As(err, &ne) || ne.Input != "12a" || ne.Func != "ParseUint" {
+		t.Errorf("Expected a NumError for 12a but got %#v", err)
+	}
+}
+
+func TestParseWithoutAllocating(t *testing.T) {
+
+	line := []byte("18446744073709551615 -3.25")
+	allocs := testing.AllocsPerRun(100, func() {
+		fmtx.ParseUint(line[:20])
+		fmtx.ParseFloat(line[21:])
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations but got %v per run", allocs)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+
+	cases := map[string]string{
+		fmtx.FormatFloat(1e21, -1):        "1000000000000000000000",
+		fmtx.FormatFloat(0.1, 3):          "0.100",
+		fmtx.FormatFloat(-2.5, 0):         "-2",
+		fmtx.FormatFloat(math.Inf(-1), 2): "-Inf",
+		fmtx.FormatFloat(math.NaN(), 2):   "NaN",
+		fmtx.FormatFloat(1234567.891, -1): "1234567.891",
+	}
+	for got, expected := range cases {
+		if got != expected {
+			t.Errorf("Expected %s but got %s", expected, got)
+		}
+	}
+}
+
+func FuzzParseUint(f *testing.F) {
+
+	for _, s := range []string{"0", "18446744073709551615", "18446744073709551616", "+1", "", "007"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := fmtx.ParseUint([]byte(s))
+		want, werr := strconv.ParseUint(s, 10, 64)
+		// strconv stops at an overflow; ParseUint reports bad syntax after
+		// it instead, so only results and the error kind of valid numbers
+		// have to agree
+		if werr == nil && (got != want || err != nil) {
+			t.Errorf("Expected %d for %q but got %d, %v", want, s, got, err)
+		}
+		if werr != nil && err == nil {
+			t.Errorf("Expected an error for %q but got %d", s, got)
+		}
+		if errors.Is(err, fmtx.ErrRange) && (got != want || !errors.Is(werr, strconv.ErrRange)) {
+			t.Errorf("Expected %d, %v for %q but got %d, %v", want, werr, s, got, err)
+		}
+	})
+}
+
+func FuzzParseInt(f *testing.F) {
+
+	for _, s := range []string{"-9223372036854775808", "9223372036854775807", "-0", "+", "1e3"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := fmtx.ParseInt([]byte(s))
+		want, werr := strconv.ParseInt(s, 10, 64)
+		if werr == nil && (got != want || err != nil) {
+			t.Errorf("Expected %d for %q but got %d, %v", want, s, got, err)
+		}
+		if werr != nil && err == nil {
+			t.Errorf("Expected an error for %q but got %d", s, got)
+		}
+		if errors.Is(err, fmtx.ErrRange) && (got != want || !errors.Is(werr, strconv.ErrRange)) {
+			t.Errorf("Expected %d, %v for %q but got %d, %v", want, werr, s, got, err)
+		}
+	})
+}
+
+func FuzzFloatRoundTrip(f *testing.F) {
+
+	for _, v := range []float64{0, -0.5, 1e-300, 1.7976931348623157e308, 3.14159} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v float64) {
+		if math.IsNaN(v) {
+			return
+		}
+		s := fmtx.FormatFloat(v, -1)
+		got, err := fmtx.ParseFloat([]byte(s))
+		if err != nil || got != v {
+			t.Errorf("Expected %v back from %q but got %v, %v", v, s, got, err)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+// Package cmap has two concurrent maps to weigh against sync.Map, which
+// is built for keys that are written once and read many times, or that
+// disjoint goroutines own:
+//
+//   - RWMap is a map behind one sync.RWMutex, the usual first attempt.
+//     Readers run in parallel, but every write stops everybody.
+//   - Sharded splits the keys over maps with a lock each, so writes to
+//     different keys mostly don't meet.
+//
+// All three, sync.Map through SyncMap, satisfy Map. The benchmarks in the
+// tests run them on read-heavy, write-heavy and mixed workloads:
+//
+//	go test -run '^$' -bench . -count 10 ./sync/cmap > new.txt
+//	benchstat -col /impl new.txt
+package cmap
+
+import (
+	"errors"
+	"hash/maphash"
+	"math/bits"
+	"runtime"
+	"sync"
+
+	"pacx/options"
+)
+
+// Map is a map that is safe for concurrent use.
+type Map[K comparable, V any] interface {
+	Load(k K) (v V, ok bool)
+	Store(k K, v V)
+	// LoadOrStore returns the value of k if there is one, and otherwise
+	// stores v and returns it. loaded is true if there was one.
+	LoadOrStore(k K, v V) (actual V, loaded bool)
+	Delete(k K)
+	// Range calls fn for every entry until fn returns false. Like
+	// sync.Map's, it is no snapshot: fn may change the map, and may or
+	// may not see changes made meanwhile.
+	Range(fn func(k K, v V) bool)
+}
+
+// SyncMap is sync.Map with types.
+type SyncMap[K comparable, V any] struct {
+	m sync.Map
+}
+
+func (s *SyncMap[K, V]) Load(k K) (V, bool) {
+
+	v, ok := s.m.Load(k)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	return v.(V), true
+}
+
+func (s *SyncMap[K, V]) Store(k K, v V) {
+	s.m.Store(k, v)
+}
+
+func (s *SyncMap[K, V]) LoadOrStore(k K, v V) (V, bool) {
+	actual, loaded := s.m.LoadOrStore(k, v)
+	return actual.(V), loaded
+}
+
+func (s *SyncMap[K, V]) Delete(k K) {
+	s.m.Delete(k)
+}
+
+func (s *SyncMap[K, V]) Range(fn func(k K, v V) bool) {
+	s.m.Range(func(k, v any) bool { return fn(k.(K), v.(V)) })
+}
+
+// RWMap is a map guarded by a sync.RWMutex. The zero value is ready to
+// use.
+type RWMap[K comparable, V any] struct {
+	mu sync.RWMutex
+	m  map[K]V
+}
+
+func (r *RWMap[K, V]) Load(k K) (V, bool) {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	v, ok := r.m[k]
+
+	return v, ok
+}
+
+func (r *RWMap[K, V]) Store(k K, v V) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.m == nil {
+		r.m = make(map[K]V)
+	}
+	r.m[k] = v
+}
+
+func (r *RWMap[K, V]) LoadOrStore(k K, v V) (V, bool) {
+
+	// most calls find the key, and they need not wait for each other
+	if actual, ok := r.Load(k); ok {
+		return actual, true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if actual, ok := r.m[k]; ok {
+		return actual, true
+	}
+	if r.m == nil {
+		r.m = make(map[K]V)
+	}
+	r.m[k] = v
+
+	return v, false
+}
+
+func (r *RWMap[K, V]) Delete(k K) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.m, k)
+}
+
+// Range calls fn on a copy of the entries, so fn can change the map.
+func (r *RWMap[K, V]) Range(fn func(k K, v V) bool) {
+
+	r.mu.RLock()
+	entries := make([]entry[K, V], 0, len(r.m))
+	for k, v := range r.m {
+		entries = append(entries, entry[K, V]{k, v})
+	}
+	r.mu.RUnlock()
+
+	for _, e := range entries {
+		if !fn(e.k, e.v) {
+			return
+		}
+	}
+}
+
+// Len returns the number of entries.
+func (r *RWMap[K, V]) Len() int {
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.m)
+}
+
+type entry[K comparable, V any] struct {
+	k K
+	v V
+}
+
+type config struct {
+	shards int
+}
+
+// Option configures a Sharded map.
+type Option = options.Option[config]
+
+// WithShards sets the number of shards, rounded up to a power of two.
+// Defaults to four per GOMAXPROCS.
+func WithShards(n int) Option {
+	return options.New("WithShards", func(c *config) error {
+		if n < 1 {
+			return errors.New("need at least one shard")
+		}
+		c.shards = n
+		return nil
+	})
+}
+
+// shard is one lock and its keys. The padding keeps neighbouring shards
+// off each other's cache line, so locking one does not slow the next.
+type shard[K comparable, V any] struct {
+	RWMap[K, V]
+	_ [32]byte
+}
+
+// Sharded is a map split into shards by the hash of the key.
+type Sharded[K comparable, V any] struct {
+	seed   maphash.Seed
+	mask   uint64
+	shards []shard[K, V]
+}
+
+// NewSharded returns an empty sharded map.
+func NewSharded[K comparable, V any](opts ...Option) (*Sharded[K, V], error) {
+
+	cfg, err := options.Build(config{shards: 4 * runtime.GOMAXPROCS(0)}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	n := 1 << bits.Len(uint(cfg.shards-1))
+
+	return &Sharded[K, V]{
+		seed:   maphash.MakeSeed(),
+		mask:   uint64(n - 1),
+		shards: make([]shard[K, V], n),
+	}, nil
+}
+
+func (s *Sharded[K, V]) shard(k K) *shard[K, V] {
+	return &s.shards[maphash.Comparable(s.seed, k)&s.mask]
+}
+
+func (s *Sharded[K, V]) Load(k K) (V, bool) {
+	return s.shard(k).Load(k)
+}
+
+func (s *Sharded[K, V]) Store(k K, v V) {
+	s.shard(k).Store(k, v)
+}
+
+func (s *Sharded[K, V]) LoadOrStore(k K, v V) (V, bool) {
+	return s.shard(k).LoadOrStore(k, v)
+}
+
+func (s *Sharded[K, V]) Delete(k K) {
+	s.shard(k).Delete(k)
+}
+
+// Range goes through the shards one after another, each as RWMap.Range
+// does.
+func (s *Sharded[K, V]) Range(fn func(k K, v V) bool) {
+
+	more := true
+	for i := range s.shards {
+		s.shards[i].Range(func(k K, v V) bool {
+			more = fn(k, v)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+// Len returns the number of entries. Under concurrent writes it is only
+// a rough figure, since the shards are counted one at a time.
+func (s *Sharded[K, V]) Len() int {
+
+	n := 0
+	for i := range s.shards {
+		n += s.shards[i].Len()
+	}
+
+	return n
+}
+
+// Shards returns the number of shards.
+func (s *Sharded[K, V]) Shards() int {
+	return len(s.shards)
+}
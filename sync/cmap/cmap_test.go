@@ -0,0 +1,177 @@
+package cmap_test
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"pacx/Benchmarking/compare"
+	"pacx/sync/cmap"
+)
+
+func impls(t testing.TB) map[string]func() cmap.Map[int, int] {
+
+	return map[string]func() cmap.Map[int, int]{
+		"sync":    func() cmap.Map[int, int] { return &cmap.SyncMap[int, int]{} },
+		"rwmutex": func() cmap.Map[int, int] { return &cmap.RWMap[int, int]{} },
+		"sharded": func() cmap.Map[int, int] {
+			s, err := cmap.NewSharded[int, int](cmap.WithShards(8))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+}
+
+func TestMaps(t *testing.T) {
+
+	for name, build := range impls(t) {
+		m := build()
+
+		if _, ok := m.Load(1); ok {
+			t.Errorf("%s: Expected no value in an empty map but got one", name)
+		}
+		m.Store(1, 10)
+		if v, ok := m.Load(1); !ok || v != 10 {
+			t.Errorf("%s: Expected 10 but got %d, %v", name, v, ok)
+		}
+		if v, loaded := m.LoadOrStore(1, 11); !loaded || v != 10 {
+			t.Errorf("%s: Expected LoadOrStore to find 10 but got %d, %v", name, v, loaded)
+		}
+		if v, loaded := m.LoadOrStore(2, 20); loaded || v != 20 {
+			t.Errorf("%s: Expected LoadOrStore to store 20 but got %d, %v", name, v, loaded)
+		}
+		m.Delete(1)
+		if _, ok := m.Load(1); ok {
+			t.Errorf("%s: Expected 1 to be deleted but it is there", name)
+		}
+
+		for i := range 100 {
+			m.Store(i, i)
+		}
+		sum, seen := 0, 0
+		m.Range(func(k, v int) bool {
+			sum += v
+			seen++
+			m.Delete(k) // allowed during Range
+			return true
+		})
+		if sum != 4950 || seen != 100 {
+			t.Errorf("%s: Expected Range to see 100 entries summing to 4950 but got %d summing to %d", name, seen, sum)
+		}
+		seen = 0
+		m.Store(1, 1)
+		m.Store(2, 2)
+		m.Range(func(k, v int) bool {
+			seen++
+			return false
+		})
+		if seen != 1 {
+			t.Errorf("%s: Expected Range to stop after one entry but got %d", name, seen)
+		}
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+
+	for name, build := range impls(t) {
+		m := build()
+
+		var wg sync.WaitGroup
+		var stored atomic.Int64
+		for g := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 1000 {
+					if _, loaded := m.LoadOrStore(i%100, g); !loaded {
+						stored.Add(1)
+					}
+					m.Load(i)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if stored.Load() != 100 {
+			t.Errorf("%s: Expected each of 100 keys to be stored once but got %d stores", name, stored.Load())
+		}
+	}
+}
+
+func TestSharded(t *testing.T) {
+
+	s, err := cmap.NewSharded[string, int](cmap.WithShards(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Shards() != 8 {
+		t.Errorf("Expected 5 shards to round up to 8 but got %d", s.Shards())
+	}
+	for i := range 1000 {
+		s.Store(fmt.Sprint(i), i)
+	}
+	if s.Len() != 1000 {
+		t.Errorf("Expected 1000 entries but got %d", s.Len())
+	}
+
+	if _, err := cmap.NewSharded[int, int](cmap.WithShards(0)); err == nil {
+		t.Error("Expected zero shards to be refused but got no error")
+	}
+}
+
+// keys is the key space of the benchmarks, stored before they start.
+const keys = 1 << 16
+
+// mix is one op per iteration on a random key: a Load reads percent of
+// the time, a Store otherwise. It runs GOMAXPROCS*par goroutines.
+func mix(reads, par int) func(*testing.B, cmap.Map[int, int]) {
+	return func(b *testing.B, m cmap.Map[int, int]) {
+
+		for k := range keys {
+			m.Store(k, k)
+		}
+		b.ResetTimer()
+
+		var seeds atomic.Uint64
+		b.SetParallelism(par)
+		b.RunParallel(func(pb *testing.PB) {
+			rng := rand.New(rand.NewPCG(seeds.Add(1), 0))
+			for pb.Next() {
+				k := rng.IntN(keys)
+				if rng.IntN(100) < reads {
+					m.Load(k)
+				} else {
+					m.Store(k, k)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMaps(b *testing.B) {
+
+	h := compare.New[cmap.Map[int, int]]()
+	all := impls(b)
+	for _, name := range []string{"sync", "rwmutex", "sharded"} {
+		h.Add("impl="+name, all[name])
+	}
+	for _, w := range []struct {
+		name  string
+		reads int
+	}{
+		{"read-heavy", 90},
+		{"mixed", 50},
+		{"write-heavy", 10},
+	} {
+		for _, par := range []int{1, 4, 16} {
+			h.Workload(fmt.Sprintf("mix=%s/goroutines=%d", w.name, par*runtime.GOMAXPROCS(0)), mix(w.reads, par))
+		}
+	}
+
+	h.Benchmark(b)
+}
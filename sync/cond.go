@@ -1,62 +1,88 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
+
+	"pacx/sync/syncx"
 )
 
 var (
 	queue    []int // buffer for the store the item
 	mu       sync.Mutex
-	cond     = sync.NewCond(&mu) // cond for mutex
-	capacity = 5                 // buffer size
+	cond     = syncx.NewCond(&mu) // cond for mutex, can be cancelled unlike sync.Cond
+	capacity = 5                  // buffer size
 )
 
 func main() {
 
-	go producer(1)
-	go producer(2)
+	// if anyone is still waiting after this, give up instead of hanging
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+
+	go producer(ctx, &wg, 1)
+	go producer(ctx, &wg, 2)
 
-	go consumer(3)
-	go consumer(4)
+	go consumer(ctx, &wg, 3)
+	go consumer(ctx, &wg, 4)
 
-	time.Sleep(time.Second * 5)
+	wg.Wait()
 }
 
-func producer(id int) {
+func producer(ctx context.Context, wg *sync.WaitGroup, id int) {
+
+	defer wg.Done()
 
 	for i := 1; i <= 3; i++ {
 
 		mu.Lock()
-		if len(queue) == capacity {
+		// for, not if: by the time we wake up another producer may have
+		// filled the slot again
+		for len(queue) == capacity {
 			fmt.Printf("The producer %d cant add the item %d\n", id, i)
-			cond.Wait() // waiting for the signal now
+			if err := cond.WaitContext(ctx); err != nil { // waiting for the signal now
+				mu.Unlock()
+				fmt.Printf("The producer %d gave up: %v\n", id, err)
+				return
+			}
 		}
 
 		queue = append(queue, i)
 		fmt.Printf("The Produer %d addeed the the item %d\n", id, i)
-		cond.Signal() // telling that i have done the change
+		// Broadcast, not Signal: a signal could wake the other producer
+		// and leave the consumers sleeping forever
+		cond.Broadcast()
 		mu.Unlock()
 		time.Sleep(time.Millisecond * 500)
 	}
 }
 
-func consumer(id int) {
+func consumer(ctx context.Context, wg *sync.WaitGroup, id int) {
+
+	defer wg.Done()
 
 	for i := 1; i <= 3; i++ {
 
 		mu.Lock()
-		if len(queue) == 0 {
+		for len(queue) == 0 {
 			fmt.Printf("The consumer %d is saying no item now\n", id)
-			cond.Wait() // wait for the signal that resorces job are added
+			if err := cond.WaitContext(ctx); err != nil { // wait for the signal that resorces job are added
+				mu.Unlock()
+				fmt.Printf("The consumer %d gave up: %v\n", id, err)
+				return
+			}
 		}
 
 		item := queue[0]
 		queue = queue[1:]
 
 		fmt.Printf("The consumer %d eated item %d\n", id, item)
-		cond.Signal() // telling that i have changed
+		cond.Broadcast() // telling that i have changed
 		mu.Unlock()
 		time.Sleep(time.Millisecond * 700)
 	}
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"sync"
+
+	"pacx/sync/syncx"
 )
 
 func main() {
@@ -30,4 +32,16 @@ func main() {
 	wg.Wait()
 	fmt.Printf("Count is : %d\n", cnt)
 
+	// OnceValue does the same but also hands back the result (and error)
+	// to every caller
+	config := syncx.OnceValue(func() (string, error) {
+		fmt.Println("loading config once")
+		return "debug=true", nil
+	})
+
+	for i := 0; i < 3; i++ {
+		c, err := config()
+		fmt.Println(c, err)
+	}
+
 }
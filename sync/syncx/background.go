@@ -0,0 +1,31 @@
+package syncx
+
+import "sync"
+
+// Background is a goroutine that runs until Stop, the lifecycle of the
+// monitors and reporters that work on a ticker until they are closed.
+type Background struct {
+	quit chan struct{}
+	done chan struct{}
+	stop sync.Once
+}
+
+// Go runs fn in a new goroutine. fn should return once quit is closed.
+func Go(fn func(quit <-chan struct{})) *Background {
+
+	b := &Background{quit: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(b.done)
+		fn(b.quit)
+	}()
+
+	return b
+}
+
+// Stop closes quit and waits for fn to return. It is safe to call any
+// number of times from any goroutine, and every call waits.
+func (b *Background) Stop() {
+
+	b.stop.Do(func() { close(b.quit) })
+	<-b.done
+}
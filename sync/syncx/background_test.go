@@ -0,0 +1,53 @@
+package syncx_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"pacx/sync/syncx"
+)
+
+func TestBackgroundStop(t *testing.T) {
+
+	var exited atomic.Int32
+	b := syncx.Go(func(quit <-chan struct{}) {
+		<-quit
+		time.Sleep(time.Millisecond) // cleaning up takes a while
+		exited.Add(1)
+	})
+
+	b.Stop()
+	if exited.Load() != 1 {
+		t.Fatal("Expected Stop to wait for the goroutine to return")
+	}
+	b.Stop()
+	if exited.Load() != 1 {
+		t.Error("Expected a second Stop to do nothing")
+	}
+}
+
+func TestBackgroundConcurrentStop(t *testing.T) {
+
+	for i := 0; i < 20; i++ {
+		var exited atomic.Bool
+		b := syncx.Go(func(quit <-chan struct{}) {
+			<-quit
+			exited.Store(true)
+		})
+
+		var wg sync.WaitGroup
+		wg.Add(8)
+		for j := 0; j < 8; j++ {
+			go func() {
+				defer wg.Done()
+				b.Stop()
+				if !exited.Load() {
+					t.Error("Expected every Stop to wait for the goroutine")
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
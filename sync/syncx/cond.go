@@ -0,0 +1,59 @@
+package syncx
+
+import (
+	"context"
+	"sync"
+)
+
+// Cond is a condition variable whose waits can be cancelled. It only offers
+// Broadcast: waking everybody and having each waiter recheck its condition
+// in a loop is what makes a condition variable correct, and it avoids the
+// lost wakeups Signal causes when the woken goroutine waits for something
+// else.
+type Cond struct {
+	// L is held while checking the condition and while changing it.
+	L sync.Locker
+
+	mu sync.Mutex
+	ch chan struct{} // closed by the next Broadcast
+}
+
+// NewCond returns a Cond guarded by l.
+func NewCond(l sync.Locker) *Cond {
+	return &Cond{L: l}
+}
+
+// WaitContext unlocks L, waits for a Broadcast or for ctx to be done, and
+// locks L again before returning. Like sync.Cond.Wait it has to be called
+// with L held and in a loop that rechecks the condition.
+func (c *Cond) WaitContext(ctx context.Context) error {
+
+	c.mu.Lock()
+	if c.ch == nil {
+		c.ch = make(chan struct{})
+	}
+	ch := c.ch
+	c.mu.Unlock()
+
+	c.L.Unlock()
+	defer c.L.Lock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Broadcast wakes every goroutine waiting on c. L does not have to be held.
+func (c *Cond) Broadcast() {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ch != nil {
+		close(c.ch)
+		c.ch = nil
+	}
+}
@@ -0,0 +1,67 @@
+package syncx_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/sync/syncx"
+)
+
+func TestCondBroadcast(t *testing.T) {
+
+	const waiters = 5
+
+	var (
+		mu    sync.Mutex
+		ready bool
+		wg    sync.WaitGroup
+	)
+	cond := syncx.NewCond(&mu)
+
+	wg.Add(waiters)
+	for i := 0; i < waiters; i++ {
+		go func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			for !ready {
+				if err := cond.WaitContext(context.Background()); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	ready = true
+	mu.Unlock()
+	cond.Broadcast()
+
+	if !syncx.WaitTimeout(&wg, time.Second) {
+		t.Fatal("Expected every waiter to wake up")
+	}
+}
+
+func TestCondWaitContextCancel(t *testing.T) {
+
+	var mu sync.Mutex
+	cond := syncx.NewCond(&mu)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	mu.Lock()
+	err := cond.WaitContext(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+	// L is held again after the wait, cancelled or not
+	if mu.TryLock() {
+		t.Error("Expected the lock to be held after WaitContext")
+	}
+	mu.Unlock()
+}
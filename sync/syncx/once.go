@@ -0,0 +1,99 @@
+package syncx
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+// OnceValue returns a function that calls fn the first time and returns
+// the same value and error on every call after that. If fn panics, every
+// call panics with the same *PanicError.
+func OnceValue[T any](fn func() (T, error)) func() (T, error) {
+
+	var (
+		once sync.Once
+		v    T
+		err  error
+		p    *PanicError
+	)
+
+	return func() (T, error) {
+		once.Do(func() {
+			defer func() {
+				if r := recover(); r != nil {
+					p = &PanicError{Value: r, Stack: debug.Stack()}
+				}
+			}()
+			v, err = fn()
+		})
+		if p != nil {
+			panic(p)
+		}
+		return v, err
+	}
+}
+
+// OnceFunc returns a function that calls fn only the first time. Panics are
+// handled as in OnceValue.
+func OnceFunc(fn func()) func() {
+
+	f := OnceValue(func() (struct{}, error) {
+		fn()
+		return struct{}{}, nil
+	})
+
+	return func() { f() }
+}
+
+// Lazy holds a value that is computed on first use. Unlike OnceValue a
+// failed computation is not kept: the next Get tries again, so a transient
+// error during initialization does not stick forever. A panic does stick,
+// as it usually means a bug rather than bad luck.
+type Lazy[T any] struct {
+	mu   sync.Mutex
+	fn   func() (T, error)
+	done bool
+	v    T
+	p    *PanicError
+}
+
+// NewLazy returns a Lazy computing its value with fn.
+func NewLazy[T any](fn func() (T, error)) *Lazy[T] {
+	return &Lazy[T]{fn: fn}
+}
+
+// Get returns the value, computing it if no earlier Get succeeded.
+// Concurrent callers wait for the one computing it.
+func (l *Lazy[T]) Get() (T, error) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.p != nil {
+		panic(l.p)
+	}
+	if l.done {
+		return l.v, nil
+	}
+
+	v, err := l.call()
+	if err != nil {
+		return v, err
+	}
+	l.v, l.done = v, true
+
+	return v, nil
+}
+
+// call runs fn, turning a panic into a sticky *PanicError. mu must be held.
+func (l *Lazy[T]) call() (v T, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			l.p = &PanicError{Value: r, Stack: debug.Stack()}
+			panic(l.p)
+		}
+	}()
+
+	return l.fn()
+}
@@ -0,0 +1,134 @@
+package syncx_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"pacx/sync/syncx"
+)
+
+func TestOnceValueConcurrent(t *testing.T) {
+
+	var calls atomic.Int32
+	get := syncx.OnceValue(func() (int, error) {
+		calls.Add(1)
+		return 42, nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(50)
+	for i := 0; i < 50; i++ {
+		go func() {
+			defer wg.Done()
+			if v, err := get(); v != 42 || err != nil {
+				t.Errorf("Expected 42, nil but got %d, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected fn to run once but ran %d times", got)
+	}
+}
+
+func TestOnceValueKeepsError(t *testing.T) {
+
+	errInit := errors.New("init failed")
+	var calls int
+	get := syncx.OnceValue(func() (string, error) {
+		calls++
+		return "", errInit
+	})
+
+	get()
+	if _, err := get(); err != errInit || calls != 1 {
+		t.Errorf("Expected the cached %v after one call but got %v after %d", errInit, err, calls)
+	}
+}
+
+func catch(fn func()) (p any) {
+	defer func() { p = recover() }()
+	fn()
+	return nil
+}
+
+func TestOncePanicSticks(t *testing.T) {
+
+	var calls int
+	f := syncx.OnceFunc(func() {
+		calls++
+		panic("boom")
+	})
+
+	first, second := catch(f), catch(f)
+	if first == nil || first != second {
+		t.Errorf("Expected the same panic twice but got %v and %v", first, second)
+	}
+	if pe, ok := first.(*syncx.PanicError); !ok || pe.Value != "boom" {
+		t.Errorf("Expected a *PanicError with boom but got %v", first)
+	}
+	if calls != 1 {
+		t.Errorf("Expected fn to run once but ran %d times", calls)
+	}
+}
+
+func TestLazyRetriesErrors(t *testing.T) {
+
+	var calls int
+	l := syncx.NewLazy(func() (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("not yet")
+		}
+		return calls, nil
+	})
+
+	l.Get()
+	l.Get()
+	v, err := l.Get()
+	if v != 3 || err != nil {
+		t.Fatalf("Expected 3, nil on the third try but got %d, %v", v, err)
+	}
+	if v, _ := l.Get(); v != 3 || calls != 3 {
+		t.Errorf("Expected the value to be kept but got %d after %d calls", v, calls)
+	}
+}
+
+func TestLazyConcurrent(t *testing.T) {
+
+	var calls atomic.Int32
+	l := syncx.NewLazy(func() ([]int, error) {
+		calls.Add(1)
+		return []int{1, 2, 3}, nil
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(50)
+	for i := 0; i < 50; i++ {
+		go func() {
+			defer wg.Done()
+			if v, err := l.Get(); len(v) != 3 || err != nil {
+				t.Errorf("Expected [1 2 3] but got %v, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected fn to run once but ran %d times", got)
+	}
+}
+
+func TestLazyPanicSticks(t *testing.T) {
+
+	l := syncx.NewLazy(func() (int, error) { panic("bad config") })
+
+	first := catch(func() { l.Get() })
+	second := catch(func() { l.Get() })
+	if first == nil || first != second {
+		t.Errorf("Expected the same panic twice but got %v and %v", first, second)
+	}
+}
@@ -0,0 +1,105 @@
+// Package syncx adds the failure handling that sync leaves to the caller:
+// bounded waits on a WaitGroup, a group that collects errors and panics
+// from the goroutines it runs, and a background goroutine that any number
+// of callers can stop.
+package syncx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// WaitContext waits for wg until ctx is done. On cancellation the waiting
+// goroutine is left behind until wg reaches zero.
+func WaitContext(ctx context.Context, wg *sync.WaitGroup) error {
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitTimeout waits for wg for at most d and reports whether it finished.
+func WaitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	return WaitContext(ctx, wg) == nil
+}
+
+// PanicError is a panic recovered from a goroutine started by ErrWaitGroup
+// or from a function run by OnceValue, OnceFunc or Lazy.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("syncx: panic: %v\n\n%s", p.Value, p.Stack)
+}
+
+// ErrWaitGroup is a WaitGroup whose goroutines return errors. Unlike
+// errgroup it keeps every error, not just the first, and turns panics into
+// *PanicError values instead of crashing the program. The zero value is
+// ready to use.
+type ErrWaitGroup struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// Go runs fn in a new goroutine.
+func (g *ErrWaitGroup) Go(fn func() error) {
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if r := recover(); r != nil {
+				g.add(&PanicError{Value: r, Stack: debug.Stack()})
+			}
+		}()
+
+		if err := fn(); err != nil {
+			g.add(err)
+		}
+	}()
+}
+
+func (g *ErrWaitGroup) add(err error) {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.errs = append(g.errs, err)
+}
+
+// Wait waits for every goroutine and returns their errors joined, or nil.
+func (g *ErrWaitGroup) Wait() error {
+	return g.WaitContext(context.Background())
+}
+
+// WaitContext is Wait bounded by ctx. If ctx ends first the result is
+// ctx.Err() joined with the errors collected so far.
+func (g *ErrWaitGroup) WaitContext(ctx context.Context) error {
+
+	err := WaitContext(ctx, &g.wg)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return errors.Join(append([]error{err}, g.errs...)...)
+}
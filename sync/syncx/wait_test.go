@@ -0,0 +1,82 @@
+package syncx_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"pacx/sync/syncx"
+)
+
+func TestWaitTimeout(t *testing.T) {
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	release := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		<-release
+	}()
+
+	if syncx.WaitTimeout(&wg, 10*time.Millisecond) {
+		t.Error("Expected the wait to time out")
+	}
+
+	close(release)
+	if !syncx.WaitTimeout(&wg, time.Second) {
+		t.Error("Expected the wait to finish")
+	}
+}
+
+func TestErrWaitGroup(t *testing.T) {
+
+	var g syncx.ErrWaitGroup
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+
+	g.Go(func() error { return errA })
+	g.Go(func() error { return nil })
+	g.Go(func() error { return errB })
+	g.Go(func() error { panic("c blew up") })
+
+	err := g.Wait()
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("Expected both errors but got %v", err)
+	}
+
+	var pe *syncx.PanicError
+	if !errors.As(err, &pe) || pe.Value != "c blew up" {
+		t.Errorf("Expected the panic to be collected but got %v", err)
+	}
+}
+
+func TestErrWaitGroupEmpty(t *testing.T) {
+
+	var g syncx.ErrWaitGroup
+	g.Go(func() error { return nil })
+
+	if err := g.Wait(); err != nil {
+		t.Errorf("Expected nil but got %v", err)
+	}
+}
+
+func TestErrWaitGroupWaitContext(t *testing.T) {
+
+	var g syncx.ErrWaitGroup
+	release := make(chan struct{})
+	defer close(release)
+
+	g.Go(func() error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := g.WaitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected DeadlineExceeded but got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package tune
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"pacx/cache"
+	"pacx/concurrency/pool"
+	"pacx/concurrency/ratelimit"
+)
+
+// Int is a knob for an integer in [lo, hi].
+func Int(name, help string, lo, hi int64, get func() int64, set func(int64) error) Knob {
+	return Knob{
+		Name: name,
+		Help: help,
+		Get:  func() string { return strconv.FormatInt(get(), 10) },
+		Set: func(s string) error {
+			v, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return fmt.Errorf("not an integer: %q", s)
+			}
+			if v < lo || v > hi {
+				return fmt.Errorf("%d outside [%d, %d]", v, lo, hi)
+			}
+			return set(v)
+		},
+	}
+}
+
+// Float is a knob for a number in [lo, hi].
+func Float(name, help string, lo, hi float64, get func() float64, set func(float64) error) Knob {
+	return Knob{
+		Name: name,
+		Help: help,
+		Get:  func() string { return strconv.FormatFloat(get(), 'g', -1, 64) },
+		Set: func(s string) error {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil {
+				return fmt.Errorf("not a number: %q", s)
+			}
+			if !(v >= lo && v <= hi) { // also rejects NaN
+				return fmt.Errorf("%g outside [%g, %g]", v, lo, hi)
+			}
+			return set(v)
+		},
+	}
+}
+
+// AtomicInt is Int backed by an atomic.Int64, for settings the program
+// reads on its hot path.
+func AtomicInt(name, help string, lo, hi int64, v *atomic.Int64) Knob {
+	return Int(name, help, lo, hi, v.Load, func(n int64) error {
+		v.Store(n)
+		return nil
+	})
+}
+
+// GCPercent is debug.SetGCPercent. -1 turns the collector off.
+func GCPercent() Knob {
+	return Int("gc_percent", "GOGC: heap growth in percent that triggers a collection, -1 for off", -1, 10000,
+		func() int64 {
+			s := []metrics.Sample{{Name: "/gc/gogc:percent"}}
+			metrics.Read(s)
+			return int64(s[0].Value.Uint64())
+		},
+		func(v int64) error {
+			debug.SetGCPercent(int(v))
+			return nil
+		})
+}
+
+// LogLevel sets v to a level such as "debug", "info", "warn", "error" or
+// "info+2".
+func LogLevel(v *slog.LevelVar) Knob {
+	return Knob{
+		Name: "log_level",
+		Help: "minimum level logged: debug, info, warn or error",
+		Get:  func() string { return strings.ToLower(v.Level().String()) },
+		Set: func(s string) error {
+			var l slog.Level
+			if err := l.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("not a log level: %q", s)
+			}
+			v.Set(l)
+			return nil
+		},
+	}
+}
+
+// RateLimit sets the refill rate of l, in events per second.
+func RateLimit(name string, l *ratelimit.Limiter) Knob {
+	return Float(name, "events per second", 0, 1e9, l.Rate, func(r float64) error {
+		l.SetRate(r)
+		return nil
+	})
+}
+
+// CacheCapacity sets the maximum total cost of c. Shrinking it evicts at
+// once.
+func CacheCapacity[K comparable, V any](name string, c *cache.Cache[K, V]) Knob {
+	return Int(name, "maximum total cost of the entries", 1, 1<<40, c.MaxCost, c.SetMaxCost)
+}
+
+// PoolBounds sets the worker range of p, written as "min..max".
+func PoolBounds(name string, p *pool.ScalingPool) Knob {
+	return Knob{
+		Name: name,
+		Help: "worker range as min..max",
+		Get: func() string {
+			lo, hi := p.Bounds()
+			return fmt.Sprintf("%d..%d", lo, hi)
+		},
+		Set: func(s string) error {
+			a, b, ok := strings.Cut(s, "..")
+			lo, err1 := strconv.Atoi(a)
+			hi, err2 := strconv.Atoi(b)
+			if !ok || err1 != nil || err2 != nil {
+				return fmt.Errorf("want min..max, got %q", s)
+			}
+			if hi > 4096 {
+				return fmt.Errorf("%d workers is too many", hi)
+			}
+			return p.SetBounds(lo, hi)
+		},
+	}
+}
@@ -0,0 +1,285 @@
+// Package tune exposes a process's live settings, such as pool sizes, rate
+// limits, cache capacity, the log level and the GC percent, so they can be
+// read and changed while it runs. Each setting is a Knob that parses and
+// validates its own values; a Registry holds the knobs, logs every change
+// with who made it and serves them over HTTP:
+//
+//	GET  /            all knobs and their values
+//	GET  /{name}      one value
+//	PUT  /{name}      set from the request body, or ?value=
+//	GET  /?history=1  the changes made so far
+//
+// The handler has no authentication of its own; mount it behind whatever
+// guards the program's other admin endpoints.
+package tune
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"pacx/options"
+)
+
+// ErrUnknown is returned for a knob that was not registered.
+var ErrUnknown = errors.New("tune: unknown knob")
+
+// Knob is one live setting. Set gets the raw text of the new value and
+// must reject it, leaving the setting alone, if it does not parse or is
+// out of range.
+type Knob struct {
+	Name string
+	Help string
+	Get  func() string
+	Set  func(string) error
+}
+
+// Change is one entry of the audit log.
+type Change struct {
+	Time time.Time `json:"time"`
+	Knob string    `json:"knob"`
+	Old  string    `json:"old"`
+	New  string    `json:"new"`
+	By   string    `json:"by"`
+}
+
+type config struct {
+	logger  *log.Logger
+	history int
+}
+
+// Option configures a Registry.
+type Option = options.Option[config]
+
+// WithLogger sets where changes are logged. Defaults to log.Default().
+func WithLogger(l *log.Logger) Option {
+	return options.New("WithLogger", func(c *config) error {
+		if l == nil {
+			return errors.New("nil logger")
+		}
+		c.logger = l
+		return nil
+	})
+}
+
+// WithHistory sets how many changes History keeps. Defaults to 100.
+func WithHistory(n int) Option {
+	return options.New("WithHistory", func(c *config) error {
+		if n < 0 {
+			return errors.New("history must not be negative")
+		}
+		c.history = n
+		return nil
+	})
+}
+
+// Registry is safe for concurrent use. Changes to one knob are applied
+// one at a time, so the audit log shows them in the order they took
+// effect.
+type Registry struct {
+	cfg config
+
+	mu      sync.Mutex
+	knobs   map[string]*entry
+	changes []Change
+}
+
+// entry is a registered knob. Its lock serialises changes to the knob, so
+// a slow Set holds up neither the other knobs nor the registry.
+type entry struct {
+	Knob
+	mu sync.Mutex
+}
+
+// New returns an empty registry.
+func New(opts ...Option) (*Registry, error) {
+
+	cfg, err := options.Build(config{logger: log.Default(), history: 100}, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Registry{cfg: cfg, knobs: make(map[string]*entry)}, nil
+}
+
+// Register adds knobs. Names must be unique and usable in a URL path.
+func (r *Registry) Register(knobs ...Knob) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range knobs {
+		if k.Name == "" || strings.ContainsAny(k.Name, "/?# ") {
+			return fmt.Errorf("tune: bad knob name %q", k.Name)
+		}
+		if k.Get == nil || k.Set == nil {
+			return fmt.Errorf("tune: knob %s needs Get and Set", k.Name)
+		}
+		if _, ok := r.knobs[k.Name]; ok {
+			return fmt.Errorf("tune: knob %s registered twice", k.Name)
+		}
+		r.knobs[k.Name] = &entry{Knob: k}
+	}
+
+	return nil
+}
+
+func (r *Registry) knob(name string) (*entry, error) {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.knobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknown, name)
+	}
+
+	return k, nil
+}
+
+// Get returns the current value of a knob.
+func (r *Registry) Get(name string) (string, error) {
+
+	k, err := r.knob(name)
+	if err != nil {
+		return "", err
+	}
+
+	return k.Get(), nil
+}
+
+// Set changes a knob and logs the change as made by by.
+func (r *Registry) Set(name, value, by string) error {
+
+	k, err := r.knob(name)
+	if err != nil {
+		return err
+	}
+
+	// hold the knob's lock across Get and Set so concurrent changes are
+	// logged with the right old value
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	old := k.Get()
+	if err := k.Set(value); err != nil {
+		r.cfg.logger.Printf("tune: %s rejected %s=%q: %v", by, name, value, err)
+		return fmt.Errorf("tune: %s: %w", name, err)
+	}
+
+	c := Change{Time: time.Now(), Knob: name, Old: old, New: k.Get(), By: by}
+	r.cfg.logger.Printf("tune: %s set %s from %q to %q", c.By, c.Knob, c.Old, c.New)
+	r.mu.Lock()
+	if r.cfg.history > 0 {
+		if len(r.changes) == r.cfg.history {
+			r.changes = slices.Delete(r.changes, 0, 1)
+		}
+		r.changes = append(r.changes, c)
+	}
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Values returns every knob's current value.
+func (r *Registry) Values() map[string]string {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]string, len(r.knobs))
+	for name, k := range r.knobs {
+		out[name] = k.Get()
+	}
+
+	return out
+}
+
+// History returns the logged changes, oldest first.
+func (r *Registry) History() []Change {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.changes)
+}
+
+type knobView struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Help  string `json:"help,omitempty"`
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+
+	name := strings.Trim(req.URL.Path, "/")
+
+	switch {
+	case req.Method == http.MethodGet && name == "" && req.URL.Query().Has("history"):
+		writeJSON(w, r.History())
+
+	case req.Method == http.MethodGet && name == "":
+		r.mu.Lock()
+		views := make([]knobView, 0, len(r.knobs))
+		for _, k := range r.knobs {
+			views = append(views, knobView{Name: k.Name, Value: k.Get(), Help: k.Help})
+		}
+		r.mu.Unlock()
+		slices.SortFunc(views, func(a, b knobView) int { return strings.Compare(a.Name, b.Name) })
+		writeJSON(w, views)
+
+	case req.Method == http.MethodGet:
+		v, err := r.Get(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, knobView{Name: name, Value: v})
+
+	case (req.Method == http.MethodPut || req.Method == http.MethodPost) && name != "":
+		value := req.URL.Query().Get("value")
+		if !req.URL.Query().Has("value") {
+			body, err := io.ReadAll(io.LimitReader(req.Body, 4096))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			value = strings.TrimSpace(string(body))
+		}
+		if err := r.Set(name, value, who(req)); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrUnknown) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		v, _ := r.Get(name)
+		writeJSON(w, knobView{Name: name, Value: v})
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// who names the client for the audit log: the basic auth user if there is
+// one, and the remote address.
+func who(req *http.Request) string {
+	if user, _, ok := req.BasicAuth(); ok {
+		return user + "@" + req.RemoteAddr
+	}
+	return req.RemoteAddr
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,157 @@
+package tune_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"pacx/cache"
+	"pacx/concurrency/ratelimit"
+	"pacx/tune"
+)
+
+func newRegistry(t *testing.T, logs *bytes.Buffer, knobs ...tune.Knob) *tune.Registry {
+
+	r, err := tune.New(tune.WithLogger(log.New(logs, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(knobs...); err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestSetValidatesAndAudits(t *testing.T) {
+
+	var workers atomic.Int64
+	workers.Store(4)
+
+	var logs bytes.Buffer
+	r := newRegistry(t, &logs, tune.AtomicInt("workers", "", 1, 64, &workers))
+
+	if err := r.Set("workers", "8", "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if workers.Load() != 8 {
+		t.Errorf("Expected 8 workers but got %d", workers.Load())
+	}
+	if err := r.Set("workers", "100", "ops"); err == nil || workers.Load() != 8 {
+		t.Errorf("Expected an out of range value to be rejected but got %v with %d workers", err, workers.Load())
+	}
+	if err := r.Set("nope", "1", "ops"); !errors.Is(err, tune.ErrUnknown) {
+		t.Errorf("Expected ErrUnknown but got %v", err)
+	}
+
+	h := r.History()
+	if len(h) != 1 || h[0].Old != "4" || h[0].New != "8" || h[0].By != "ops" {
+		t.Errorf("Expected one change 4 -> 8 by ops but got %+v", h)
+	}
+	if !strings.Contains(logs.String(), `ops set workers from "4" to "8"`) || !strings.Contains(logs.String(), "rejected") {
+		t.Errorf("Expected the change and the rejection in the log but got\n%s", logs.String())
+	}
+}
+
+func TestBuiltinKnobs(t *testing.T) {
+
+	var level slog.LevelVar
+	lim := ratelimit.New(10, 1)
+	c, _ := cache.New[string, int](cache.WithMaxCost(10))
+
+	var logs bytes.Buffer
+	r := newRegistry(t, &logs,
+		tune.LogLevel(&level),
+		tune.RateLimit("api_rate", lim),
+		tune.CacheCapacity("cache_cost", c),
+	)
+
+	for _, set := range [][2]string{{"log_level", "debug"}, {"api_rate", "2.5"}, {"cache_cost", "3"}} {
+		if err := r.Set(set[0], set[1], "test"); err != nil {
+			t.Fatalf("Expected %s=%s to be accepted but got %v", set[0], set[1], err)
+		}
+	}
+	if level.Level() != slog.LevelDebug || lim.Rate() != 2.5 || c.MaxCost() != 3 {
+		t.Errorf("Expected debug, 2.5 and 3 but got %v, %v and %d", level.Level(), lim.Rate(), c.MaxCost())
+	}
+	for _, bad := range [][2]string{{"log_level", "loud"}, {"api_rate", "-1"}, {"cache_cost", "0"}} {
+		if err := r.Set(bad[0], bad[1], "test"); err == nil {
+			t.Errorf("Expected %s=%s to be rejected", bad[0], bad[1])
+		}
+	}
+}
+
+func TestHTTP(t *testing.T) {
+
+	var n atomic.Int64
+	var logs bytes.Buffer
+	r := newRegistry(t, &logs, tune.AtomicInt("n", "a number", 0, 10, &n))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("PUT", "/n", strings.NewReader("7\n")))
+	if rec.Code != 200 || n.Load() != 7 {
+		t.Fatalf("Expected PUT to set 7 but got %d, %d: %s", rec.Code, n.Load(), rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/n?value=11", nil))
+	if rec.Code != 400 {
+		t.Errorf("Expected 400 for an invalid value but got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+	if rec.Code != 404 {
+		t.Errorf("Expected 404 for an unknown knob but got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	var all []struct{ Name, Value, Help string }
+	if err := json.NewDecoder(rec.Body).Decode(&all); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Value != "7" || all[0].Help != "a number" {
+		t.Errorf("Expected the knob listed with value 7 but got %+v", all)
+	}
+}
+
+func TestSlowSet(t *testing.T) {
+
+	var fast atomic.Int64
+	entered, release := make(chan struct{}), make(chan struct{})
+	slow := tune.Int("slow", "", 0, 10, func() int64 { return 0 }, func(int64) error {
+		close(entered)
+		<-release
+		return nil
+	})
+
+	var logs bytes.Buffer
+	r := newRegistry(t, &logs, slow, tune.AtomicInt("fast", "", 0, 10, &fast))
+
+	done := make(chan error)
+	go func() { done <- r.Set("slow", "1", "ops") }()
+	<-entered
+
+	// a knob stuck in Set holds up nothing but itself
+	if err := r.Set("fast", "3", "ops"); err != nil || fast.Load() != 3 {
+		t.Errorf("Expected fast set to 3 but got %d, %v", fast.Load(), err)
+	}
+	if v := r.Values(); v["fast"] != "3" {
+		t.Errorf("Expected the values read but got %v", v)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if h := r.History(); len(h) != 2 || h[0].Knob != "fast" || h[1].Knob != "slow" {
+		t.Errorf("Expected both changes in the order they took effect but got %+v", h)
+	}
+}
@@ -0,0 +1,327 @@
+// Package validate checks structs against the rules in their validate
+// tags:
+//
+//	type Order struct {
+//		ID     int      `validate:"min=1"`
+//		Email  string   `validate:"required,regexp=^[^@]+@[^@]+$"`
+//		Lines  []Line   `validate:"min=1,max=100"`
+//	}
+//
+//	if err := validate.Struct(o); err != nil { ... }
+//
+// The rules are
+//
+//	required    the value is not its zero value
+//	min=n       numbers at least n; strings, slices and maps at least n long
+//	max=n       at most n, likewise
+//	regexp=re   strings must match re; as it may hold commas, it goes last
+//
+// and a field tagged validate:"-" is not looked at, nor is anything in it.
+// jsonx.UnmarshalStrict checks documents against the same tags.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The rule violations a FieldError wraps.
+var (
+	ErrRequired   = errors.New("is required")
+	ErrOutOfRange = errors.New("out of range")
+	ErrPattern    = errors.New("does not match")
+)
+
+// FieldError is one rule one field breaks. Path is in Go terms, as in
+// Lines[2].SKU, with the fields of embedded structs named as promoted.
+type FieldError struct {
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is every rule Struct found broken, sorted by path.
+type Errors []*FieldError
+
+func (es Errors) Error() string {
+
+	var b strings.Builder
+	b.WriteString("validate: ")
+	for i, e := range es {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(e.Error())
+	}
+
+	return b.String()
+}
+
+func (es Errors) Unwrap() []error {
+
+	errs := make([]error, len(es))
+	for i, e := range es {
+		errs[i] = e
+	}
+
+	return errs
+}
+
+// Struct checks the struct v, or what v points to, and the structs nested
+// in it, in pointers, slices, arrays and maps too. Every broken rule is
+// reported, as an Errors. A malformed tag is a plain error.
+func Struct(v any) error {
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: want a struct, got %T", v)
+	}
+
+	c := checker{seen: make(map[seenKey]bool)}
+	if err := c.check(rv, ""); err != nil {
+		return err
+	}
+	if len(c.errs) > 0 {
+		slices.SortStableFunc(c.errs, func(a, b *FieldError) int { return strings.Compare(a.Path, b.Path) })
+		return c.errs
+	}
+
+	return nil
+}
+
+type seenKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+type checker struct {
+	errs Errors
+	seen map[seenKey]bool // pointers followed, against cycles
+}
+
+func (c *checker) check(v reflect.Value, path string) error {
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Pointer {
+			k := seenKey{v.Pointer(), v.Type()}
+			if c.seen[k] {
+				return nil
+			}
+			c.seen[k] = true
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields, err := fieldsOf(v.Type())
+		if err != nil {
+			return err
+		}
+		for _, f := range fields {
+			fv := v.Field(f.index)
+			p := path
+			if !f.embedded {
+				p = join(path, f.name)
+			}
+			if f.rule.Required && fv.IsZero() {
+				c.errs = append(c.errs, &FieldError{Path: p, Err: ErrRequired})
+				continue
+			}
+			if err := f.rule.Check(fv); err != nil {
+				c.errs = append(c.errs, &FieldError{Path: p, Err: err})
+			}
+			if err := c.check(fv, p); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := c.check(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		keys := v.MapKeys()
+		slices.SortFunc(keys, func(a, b reflect.Value) int {
+			return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+		})
+		for _, k := range keys {
+			key := fmt.Sprint(k)
+			if k.Kind() == reflect.String {
+				key = strconv.Quote(k.String())
+			}
+			if err := c.check(v.MapIndex(k), fmt.Sprintf("%s[%s]", path, key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+type field struct {
+	index    int
+	name     string
+	embedded bool // its fields are named as promoted
+	rule     Rule
+}
+
+var fieldCache sync.Map // reflect.Type to []field or error
+
+// fieldsOf returns the fields of struct type t that Struct looks at: the
+// exported ones and the embedded structs.
+func fieldsOf(t reflect.Type) ([]field, error) {
+
+	if c, ok := fieldCache.Load(t); ok {
+		if err, ok := c.(error); ok {
+			return nil, err
+		}
+		return c.([]field), nil
+	}
+
+	var fields []field
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("validate")
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		embedded := f.Anonymous && ft.Kind() == reflect.Struct
+		if tag == "-" || !f.IsExported() && !embedded {
+			continue
+		}
+		r, err := ParseRule(tag)
+		if err != nil {
+			err = fmt.Errorf("validate: field %s of %v: %w", f.Name, t, err)
+			fieldCache.Store(t, err)
+			return nil, err
+		}
+		fields = append(fields, field{index: i, name: f.Name, embedded: embedded, rule: r})
+	}
+	fieldCache.Store(t, fields)
+
+	return fields, nil
+}
+
+// Rule is a parsed validate tag.
+type Rule struct {
+	Required bool
+	Min, Max *float64
+	Pattern  *regexp.Regexp
+}
+
+// ParseRule parses a validate tag, as in "required,min=1,regexp=^a".
+func ParseRule(tag string) (Rule, error) {
+
+	var r Rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "regexp=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			r.Required = true
+		case "min", "max":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return r, fmt.Errorf("bad %s %q", key, value)
+			}
+			if key == "min" {
+				r.Min = &n
+			} else {
+				r.Max = &n
+			}
+		case "regexp":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return r, err
+			}
+			r.Pattern = re
+		default:
+			return r, fmt.Errorf("unknown rule %q", key)
+		}
+	}
+
+	return r, nil
+}
+
+// Check checks v, or what it points to, against the bounds and the
+// pattern. Required is left to the caller, as what counts as missing
+// depends on where the value came from: Struct takes it as the zero
+// value, jsonx.UnmarshalStrict as an absent or null key.
+func (r Rule) Check(v reflect.Value) error {
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	var n float64
+	var what string
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = v.Float()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n, what = float64(v.Len()), "length "
+	default:
+		return nil
+	}
+	// NaN compares false against any bound, so it would pass them all
+	if math.IsNaN(n) && (r.Min != nil || r.Max != nil) {
+		return fmt.Errorf("%w: NaN is outside any range", ErrOutOfRange)
+	}
+	if r.Min != nil && n < *r.Min {
+		return fmt.Errorf("%w: %s%g is under the min %g", ErrOutOfRange, what, n, *r.Min)
+	}
+	if r.Max != nil && n > *r.Max {
+		return fmt.Errorf("%w: %s%g is over the max %g", ErrOutOfRange, what, n, *r.Max)
+	}
+	if r.Pattern != nil && v.Kind() == reflect.String && !r.Pattern.MatchString(v.String()) {
+		return fmt.Errorf("%w %s", ErrPattern, r.Pattern)
+	}
+
+	return nil
+}
+
+func join(path, name string) string {
+
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,210 @@
+package validate_test
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pacx/validate"
+)
+
+type Audit struct {
+	By string `validate:"required"`
+}
+
+type line struct {
+	SKU string `validate:"required,regexp=^[A-Z]+-[0-9]+$"`
+	Qty int    `validate:"min=1,max=100"`
+}
+
+type order struct {
+	Audit
+	ID     int              `validate:"min=1"`
+	Email  string           `validate:"regexp=^[^@]+@[^@]+$"`
+	Lines  []line           `validate:"min=1,max=3"`
+	Ship   *line            `validate:"required"`
+	Labels map[string]*line `validate:"max=2"`
+	Note   string           `validate:"-"`
+	secret string
+}
+
+func valid() order {
+	return order{
+		Audit:  Audit{By: "ops"},
+		ID:     7,
+		Email:  "a@b.c",
+		Lines:  []line{{"A-1", 2}},
+		Ship:   &line{"B-2", 1},
+		Labels: map[string]*line{"gift": {"C-3", 1}},
+	}
+}
+
+func paths(err error) []string {
+
+	var errs validate.Errors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	var ps []string
+	for _, e := range errs {
+		ps = append(ps, e.Path)
+	}
+
+	return ps
+}
+
+func TestValid(t *testing.T) {
+
+	o := valid()
+	if err := validate.Struct(o); err != nil {
+		t.Errorf("Expected no error but got %v", err)
+	}
+	if err := validate.Struct(&o); err != nil {
+		t.Errorf("Expected no error through a pointer but got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+
+	o := valid()
+	o.By = ""
+	o.ID = 0
+	o.Email = "nobody"
+	o.Lines = append(o.Lines, line{"bad", 0}, line{"", 101})
+	o.Labels["x"] = &line{"D-4", -1}
+
+	err := validate.Struct(o)
+	want := []string{"By", "Email", "ID", "Labels[\"x\"].Qty", "Lines[1].Qty", "Lines[1].SKU", "Lines[2].Qty", "Lines[2].SKU"}
+	if got := paths(err); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Expected errors at\n%v\nbut got\n%v", want, err)
+	}
+
+	var errs validate.Errors
+	errors.As(err, &errs)
+	for _, tt := range []struct {
+		i   int
+		err error
+	}{{0, validate.ErrRequired}, {1, validate.ErrPattern}, {2, validate.ErrOutOfRange}, {5, validate.ErrPattern}, {7, validate.ErrRequired}} {
+		if !errors.Is(errs[tt.i], tt.err) {
+			t.Errorf("Expected %v at %s but got %v", tt.err, errs[tt.i].Path, errs[tt.i].Err)
+		}
+	}
+	if !errors.Is(err, validate.ErrRequired) {
+		t.Error("Expected Errors to unwrap to its field errors")
+	}
+	if !strings.HasPrefix(err.Error(), "validate: By: is required; Email: does not match") {
+		t.Errorf("Expected the errors joined but got %q", err)
+	}
+}
+
+func TestLengths(t *testing.T) {
+
+	o := valid()
+	o.Lines = nil
+	o.Ship = nil
+	o.Labels = map[string]*line{"a": nil, "b": nil, "c": nil}
+
+	want := []string{"Labels", "Lines", "Ship"}
+	if got := paths(validate.Struct(o)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected errors at %v but got %v", want, got)
+	}
+}
+
+func TestNaN(t *testing.T) {
+
+	if err := validate.Struct(struct {
+		F float64 `validate:"max=1"`
+	}{math.NaN()}); !errors.Is(err, validate.ErrOutOfRange) {
+		t.Errorf("Expected %v for NaN but got %v", validate.ErrOutOfRange, err)
+	}
+	if err := validate.Struct(struct{ F float64 }{math.NaN()}); err != nil {
+		t.Errorf("Expected NaN without a range to pass but got %v", err)
+	}
+}
+
+func TestSkipped(t *testing.T) {
+
+	type bad struct {
+		N int `validate:"min=x"`
+	}
+	type outer struct {
+		Note   bad `validate:"-"`
+		secret bad
+	}
+	if err := validate.Struct(outer{}); err != nil {
+		t.Errorf("Expected skipped and unexported fields left alone but got %v", err)
+	}
+}
+
+func TestBadTag(t *testing.T) {
+
+	tests := []any{
+		struct {
+			N int `validate:"min=x"`
+		}{},
+		struct {
+			S string `validate:"regexp=("`
+		}{},
+		struct {
+			S string `validate:"requird"`
+		}{},
+		42,
+	}
+
+	for _, v := range tests {
+		err := validate.Struct(v)
+		var errs validate.Errors
+		if err == nil || errors.As(err, &errs) {
+			t.Errorf("Expected a plain error for %T but got %v", v, err)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+
+	r, err := validate.ParseRule("required,min=2,regexp=^a,b$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Required || *r.Min != 2 || r.Max != nil || r.Pattern.String() != "^a,b$" {
+		t.Errorf("Expected the regexp to keep its comma but got %+v", r)
+	}
+	if err := r.Check(reflect.ValueOf("a,b")); err != nil {
+		t.Errorf("Expected a match but got %v", err)
+	}
+	if err := r.Check(reflect.ValueOf("a")); !errors.Is(err, validate.ErrOutOfRange) {
+		t.Errorf("Expected %v but got %v", validate.ErrOutOfRange, err)
+	}
+}
+
+type node struct {
+	Name string `validate:"required"`
+	Next *node
+}
+
+func TestCycle(t *testing.T) {
+
+	a := &node{Name: "a"}
+	b := &node{Next: a}
+	a.Next = b
+
+	want := []string{"Next.Name"}
+	if got := paths(validate.Struct(a)); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected errors at %v but got %v", want, got)
+	}
+}
+
+func BenchmarkStruct(b *testing.B) {
+
+	o := valid()
+	o.Lines = append(o.Lines, line{"A-1", 2}, line{"B-2", 3})
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := validate.Struct(&o); err != nil {
+			b.Fatal(err)
+		}
+	}
+}